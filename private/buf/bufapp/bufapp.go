@@ -16,8 +16,14 @@ package bufapp
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulemirror"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/cert/certclient"
 )
@@ -30,16 +36,105 @@ type ExternalConfig struct {
 
 	Version string                             `json:"version,omitempty" yaml:"version,omitempty"`
 	TLS     certclient.ExternalClientTLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Mirrors []ExternalMirrorConfig             `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+	Remotes []ExternalRemoteConfig             `json:"remotes,omitempty" yaml:"remotes,omitempty"`
+	// DigestVerification is one of "strict", "warn", or "off". The default is "strict".
+	DigestVerification string                         `json:"digest_verification,omitempty" yaml:"digest_verification,omitempty"`
+	Proxies            []ExternalProxyConfig          `json:"proxies,omitempty" yaml:"proxies,omitempty"`
+	Aliases            map[string]ExternalAliasConfig `json:"aliases,omitempty" yaml:"aliases,omitempty"`
 }
 
 // IsEmpty returns true if the externalConfig is empty.
 func (e ExternalConfig) IsEmpty() bool {
-	return e.Version == "" && e.TLS.IsEmpty()
+	return e.Version == "" && e.TLS.IsEmpty() && len(e.Mirrors) == 0 && len(e.Remotes) == 0 && e.DigestVerification == "" && len(e.Proxies) == 0 && len(e.Aliases) == 0
+}
+
+// ExternalMirrorConfig is an external mirror config.
+//
+// Modules with the prefix are downloaded from the remotes in order.
+type ExternalMirrorConfig struct {
+	Prefix  string   `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Remotes []string `json:"remotes,omitempty" yaml:"remotes,omitempty"`
+}
+
+// ExternalRemoteConfig is an external remote config.
+//
+// Modules in the registry with the name are read from the URL instead of the BSR API.
+// Only file:// URLs are supported.
+type ExternalRemoteConfig struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL  string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// ExternalProxyConfig is an external proxy config.
+//
+// Requests to hosts matching the host pattern are sent through the proxy at the URL,
+// instead of the proxy from the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment variables.
+// The host is either a hostname or a wildcard such as "*.example.com". The URL has the scheme
+// http, https, socks5, or socks5h, or is "direct" to not use a proxy.
+type ExternalProxyConfig struct {
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	URL  string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// ExternalAliasConfig is an external alias config.
+//
+// An alias expands to one or more argument lists that are run in order, such as
+// [["lint"], ["breaking", "--against", ".git#branch=main"]]. A single argument list,
+// such as ["registry", "login"], may also be given.
+type ExternalAliasConfig [][]string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface. This is done to accept a
+// single argument list.
+func (e *ExternalAliasConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return e.unmarshalWith(unmarshal)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. This is done to accept a
+// single argument list.
+func (e *ExternalAliasConfig) UnmarshalJSON(data []byte) error {
+	unmarshal := func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	}
+	return e.unmarshalWith(unmarshal)
+}
+
+// unmarshalWith is used to unmarshal into json/yaml. See https://abhinavg.net/posts/flexible-yaml for details.
+func (e *ExternalAliasConfig) unmarshalWith(unmarshal func(interface{}) error) error {
+	var args []string
+	if err := unmarshal(&args); err == nil {
+		*e = ExternalAliasConfig{args}
+		return nil
+	}
+	var argLists [][]string
+	if err := unmarshal(&argLists); err != nil {
+		return err
+	}
+	*e = argLists
+	return nil
+}
+
+// ProxyConfig is a proxy config.
+type ProxyConfig struct {
+	// HostPattern is a hostname, or a wildcard such as "*.example.com".
+	HostPattern string
+	// URL is the URL of the proxy. Nil if requests are sent directly.
+	URL *url.URL
 }
 
 // Config is a config.
 type Config struct {
-	TLS *tls.Config
+	TLS     *tls.Config
+	Mirrors []bufmodulemirror.Mirror
+	// RemoteToDirPath maps registry hostnames to the directories of file remotes.
+	RemoteToDirPath map[string]string
+	// DigestVerification is the policy for downloaded modules that do not match their digests.
+	DigestVerification bufmodule.DigestVerification
+	// Proxies are the per-host proxies, in order of precedence.
+	Proxies []*ProxyConfig
+	// AliasToArgLists maps the names of user-defined command aliases to the argument lists
+	// they expand to.
+	AliasToArgLists map[string][][]string
 }
 
 // NewConfig returns a new Config for the ExternalConfig.
@@ -54,7 +149,120 @@ func NewConfig(
 	if err != nil {
 		return nil, err
 	}
+	mirrors := make([]bufmodulemirror.Mirror, 0, len(externalConfig.Mirrors))
+	for _, externalMirrorConfig := range externalConfig.Mirrors {
+		mirror, err := bufmodulemirror.NewMirror(externalMirrorConfig.Prefix, externalMirrorConfig.Remotes)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	remoteToDirPath := make(map[string]string, len(externalConfig.Remotes))
+	for _, externalRemoteConfig := range externalConfig.Remotes {
+		dirPath, err := getDirPathForExternalRemoteConfig(externalRemoteConfig)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+		if _, ok := remoteToDirPath[externalRemoteConfig.Name]; ok {
+			return nil, fmt.Errorf("buf configuration at %q: duplicate remote %q", container.ConfigDirPath(), externalRemoteConfig.Name)
+		}
+		remoteToDirPath[externalRemoteConfig.Name] = dirPath
+	}
+	digestVerification := bufmodule.DigestVerificationStrict
+	if externalConfig.DigestVerification != "" {
+		digestVerification, err = bufmodule.ParseDigestVerification(externalConfig.DigestVerification)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+	}
+	proxies := make([]*ProxyConfig, 0, len(externalConfig.Proxies))
+	for _, externalProxyConfig := range externalConfig.Proxies {
+		proxy, err := newProxyConfig(externalProxyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+		proxies = append(proxies, proxy)
+	}
+	aliasToArgLists := make(map[string][][]string, len(externalConfig.Aliases))
+	for alias, externalAliasConfig := range externalConfig.Aliases {
+		if err := validateExternalAliasConfig(alias, externalAliasConfig); err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+		aliasToArgLists[alias] = externalAliasConfig
+	}
 	return &Config{
-		TLS: tlsConfig,
+		TLS:                tlsConfig,
+		Mirrors:            mirrors,
+		RemoteToDirPath:    remoteToDirPath,
+		DigestVerification: digestVerification,
+		Proxies:            proxies,
+		AliasToArgLists:    aliasToArgLists,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+func getDirPathForExternalRemoteConfig(externalRemoteConfig ExternalRemoteConfig) (string, error) {
+	if externalRemoteConfig.Name == "" || strings.Contains(externalRemoteConfig.Name, "/") {
+		return "", fmt.Errorf("invalid remote name %q: must be a registry hostname", externalRemoteConfig.Name)
+	}
+	remoteURL, err := url.Parse(externalRemoteConfig.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL for remote %q: %w", externalRemoteConfig.Name, err)
+	}
+	if remoteURL.Scheme != "file" {
+		return "", fmt.Errorf("invalid URL %q for remote %q: only file:// URLs are supported", externalRemoteConfig.URL, externalRemoteConfig.Name)
+	}
+	if (remoteURL.Host != "" && remoteURL.Host != "localhost") || remoteURL.Path == "" {
+		return "", fmt.Errorf("invalid URL %q for remote %q: must be of the form file:///path/to/registry", externalRemoteConfig.URL, externalRemoteConfig.Name)
+	}
+	return filepath.FromSlash(remoteURL.Path), nil
+}
+
+func newProxyConfig(externalProxyConfig ExternalProxyConfig) (*ProxyConfig, error) {
+	hostPattern := externalProxyConfig.Host
+	hostname := strings.TrimPrefix(hostPattern, "*.")
+	if hostPattern == "" || strings.ContainsAny(hostname, "*/:") {
+		return nil, fmt.Errorf("invalid proxy host %q: must be a hostname or a wildcard such as *.example.com", hostPattern)
+	}
+	if externalProxyConfig.URL == "direct" {
+		return &ProxyConfig{
+			HostPattern: hostPattern,
+		}, nil
+	}
+	proxyURL, err := url.Parse(externalProxyConfig.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL for host %q: %w", hostPattern, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf(
+			"invalid proxy URL %q for host %q: must have the scheme http, https, socks5, or socks5h, or be \"direct\"",
+			externalProxyConfig.URL,
+			hostPattern,
+		)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q for host %q: must have a host", externalProxyConfig.URL, hostPattern)
+	}
+	return &ProxyConfig{
+		HostPattern: hostPattern,
+		URL:         proxyURL,
 	}, nil
 }
+
+func validateExternalAliasConfig(alias string, externalAliasConfig ExternalAliasConfig) error {
+	if alias == "" || strings.HasPrefix(alias, "-") || strings.ContainsAny(alias, " \t/\\") {
+		return fmt.Errorf("invalid alias name %q", alias)
+	}
+	if len(externalAliasConfig) == 0 {
+		return fmt.Errorf("alias %q must have at least one command", alias)
+	}
+	for _, args := range externalAliasConfig {
+		if len(args) == 0 {
+			return fmt.Errorf("alias %q has an empty command", alias)
+		}
+	}
+	return nil
+}
@@ -15,12 +15,119 @@
 package bufapp
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExternalConfigIsEmpty(t *testing.T) {
 	t.Parallel()
 	assert.True(t, ExternalConfig{}.IsEmpty())
 }
+
+func TestGetDirPathForExternalRemoteConfig(t *testing.T) {
+	t.Parallel()
+	dirPath, err := getDirPathForExternalRemoteConfig(
+		ExternalRemoteConfig{
+			Name: "buf.local",
+			URL:  "file:///srv/bufregistry",
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.FromSlash("/srv/bufregistry"), dirPath)
+	_, err = getDirPathForExternalRemoteConfig(
+		ExternalRemoteConfig{
+			Name: "buf.local",
+			URL:  "https://example.com/bufregistry",
+		},
+	)
+	assert.Error(t, err)
+	_, err = getDirPathForExternalRemoteConfig(
+		ExternalRemoteConfig{
+			Name: "buf.local/foo",
+			URL:  "file:///srv/bufregistry",
+		},
+	)
+	assert.Error(t, err)
+	_, err = getDirPathForExternalRemoteConfig(
+		ExternalRemoteConfig{
+			Name: "buf.local",
+			URL:  "file://example.com/srv/bufregistry",
+		},
+	)
+	assert.Error(t, err)
+}
+
+func TestNewProxyConfig(t *testing.T) {
+	t.Parallel()
+	proxyConfig, err := newProxyConfig(
+		ExternalProxyConfig{
+			Host: "*.example.com",
+			URL:  "socks5://proxy.example.com:1080",
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "*.example.com", proxyConfig.HostPattern)
+	assert.Equal(t, "socks5://proxy.example.com:1080", proxyConfig.URL.String())
+	proxyConfig, err = newProxyConfig(
+		ExternalProxyConfig{
+			Host: "buf.example.com",
+			URL:  "direct",
+		},
+	)
+	require.NoError(t, err)
+	assert.Nil(t, proxyConfig.URL)
+	_, err = newProxyConfig(
+		ExternalProxyConfig{
+			Host: "buf.example.com",
+			URL:  "ftp://proxy.example.com",
+		},
+	)
+	assert.Error(t, err)
+	_, err = newProxyConfig(
+		ExternalProxyConfig{
+			Host: "buf.*.com",
+			URL:  "http://proxy.example.com",
+		},
+	)
+	assert.Error(t, err)
+	_, err = newProxyConfig(
+		ExternalProxyConfig{
+			URL: "http://proxy.example.com",
+		},
+	)
+	assert.Error(t, err)
+}
+
+func TestExternalAliasConfig(t *testing.T) {
+	t.Parallel()
+	var externalConfig ExternalConfig
+	require.NoError(
+		t,
+		encoding.UnmarshalYAMLStrict(
+			[]byte(`version: v1
+aliases:
+  check: [["lint"], ["breaking", "--against", ".git#branch=main"]]
+  whoami: ["registry", "whoami"]
+`),
+			&externalConfig,
+		),
+	)
+	assert.Equal(
+		t,
+		map[string]ExternalAliasConfig{
+			"check":  {{"lint"}, {"breaking", "--against", ".git#branch=main"}},
+			"whoami": {{"registry", "whoami"}},
+		},
+		externalConfig.Aliases,
+	)
+	for alias, externalAliasConfig := range externalConfig.Aliases {
+		assert.NoError(t, validateExternalAliasConfig(alias, externalAliasConfig))
+	}
+	assert.Error(t, validateExternalAliasConfig("--check", ExternalAliasConfig{{"lint"}}))
+	assert.Error(t, validateExternalAliasConfig("check", ExternalAliasConfig{}))
+	assert.Error(t, validateExternalAliasConfig("check", ExternalAliasConfig{{}}))
+}
@@ -0,0 +1,52 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"github.com/bufbuild/buf/private/buf/bufapp"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+)
+
+// NewExpandAliasFunc returns a new appcmd.ExpandAliasFunc for the aliases section of the
+// configuration file within the config directory.
+//
+// For example, the following runs buf lint and then buf breaking for "buf check":
+//
+//	version: v1
+//	aliases:
+//	  check: [["lint"], ["breaking", "--against", ".git#branch=main"]]
+func NewExpandAliasFunc(appName string) appcmd.ExpandAliasFunc {
+	return func(container app.Container, name string) ([][]string, error) {
+		nameContainer, err := appext.NewNameContainer(container, appName)
+		if err != nil {
+			return nil, err
+		}
+		externalConfig := bufapp.ExternalConfig{}
+		if err := appext.ReadConfig(nameContainer, &externalConfig); err != nil {
+			return nil, err
+		}
+		if len(externalConfig.Aliases) == 0 {
+			// Avoid validating the rest of the configuration for every unknown command.
+			return nil, nil
+		}
+		config, err := bufapp.NewConfig(nameContainer, externalConfig)
+		if err != nil {
+			return nil, err
+		}
+		return config.AliasToArgLists[name], nil
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandAliasFunc(t *testing.T) {
+	t.Parallel()
+	configDirPath := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(configDirPath, "config.yaml"),
+			[]byte(`version: v1
+aliases:
+  check: [["lint"], ["breaking", "--against", ".git#branch=main"]]
+  whoami: ["registry", "whoami"]
+`),
+			0600,
+		),
+	)
+	container := app.NewContainer(map[string]string{"BUF_CONFIG_DIR": configDirPath}, nil, nil, nil, "buf")
+	expandAlias := NewExpandAliasFunc("buf")
+	argLists, err := expandAlias(container, "check")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"lint"}, {"breaking", "--against", ".git#branch=main"}}, argLists)
+	argLists, err = expandAlias(container, "whoami")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"registry", "whoami"}}, argLists)
+	argLists, err = expandAlias(container, "foo")
+	require.NoError(t, err)
+	assert.Nil(t, argLists)
+	// No configuration file.
+	argLists, err = expandAlias(app.NewContainer(map[string]string{"BUF_CONFIG_DIR": t.TempDir()}, nil, nil, nil, "buf"), "check")
+	require.NoError(t, err)
+	assert.Nil(t, argLists)
+}
@@ -0,0 +1,68 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+)
+
+// GetBufGenYAMLFileForDirPath reads the buf.gen.yaml file at the directory path.
+//
+// Returns an error that fulfills fs.ErrNotExist if the file does not exist.
+func GetBufGenYAMLFileForDirPath(
+	ctx context.Context,
+	dirPath string,
+) (bufconfig.BufGenYAMLFile, error) {
+	bucket, err := newOSReadWriteBucketWithSymlinks(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return bufconfig.GetBufGenYAMLFileForPrefix(ctx, bucket, ".")
+}
+
+// PutBufGenYAMLFileForDirPath writes the buf.gen.yaml file to the directory path.
+func PutBufGenYAMLFileForDirPath(
+	ctx context.Context,
+	dirPath string,
+	bufGenYAMLFile bufconfig.BufGenYAMLFile,
+) error {
+	bucket, err := newOSReadWriteBucketWithSymlinks(dirPath)
+	if err != nil {
+		return err
+	}
+	return bufconfig.PutBufGenYAMLFileForPrefix(ctx, bucket, ".", bufGenYAMLFile)
+}
+
+// BufGenYAMLFileExistsForDirPath returns true if the buf.gen.yaml file exists at the dir path.
+func BufGenYAMLFileExistsForDirPath(
+	ctx context.Context,
+	dirPath string,
+) (bool, error) {
+	bucket, err := newOSReadWriteBucketWithSymlinks(dirPath)
+	if err != nil {
+		return false, err
+	}
+	if _, err := bufconfig.GetBufGenYAMLFileVersionForPrefix(ctx, bucket, "."); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
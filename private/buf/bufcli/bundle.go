@@ -0,0 +1,92 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebundle"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
+)
+
+const fromBundleFlagName = "from-bundle"
+
+// BindFromBundle binds the global --from-bundle flag.
+//
+// The flag is applied to the Container with NewFromBundleInterceptor.
+func BindFromBundle(flagSet *pflag.FlagSet, fromBundle *string) {
+	flagSet.StringVar(
+		fromBundle,
+		fromBundleFlagName,
+		"",
+		fmt.Sprintf(
+			`Read dependencies from a bundle created with "buf dep download" before the cache or the BSR. Can also be set with %s`,
+			dependencyBundleEnvKey,
+		),
+	)
+}
+
+// NewFromBundleInterceptor returns a new Interceptor that sets dependencyBundleEnvKey on the
+// Container if fromBundle is set, so that the --from-bundle flag applies to all commands.
+func NewFromBundleInterceptor(fromBundle *string) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if *fromBundle == "" {
+				return next(ctx, container)
+			}
+			fromBundleContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					dependencyBundleEnvKey: *fromBundle,
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, fromBundleContainer)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+// newBundleModuleDataProviderIfConfigured wraps the delegate with a bundle if
+// dependencyBundleEnvKey is set, otherwise the delegate is returned.
+//
+// The bundle is consulted after the cache, and ModuleDatas read from the bundle are put
+// to the cache, so that later invocations do not need the bundle.
+func newBundleModuleDataProviderIfConfigured(
+	container appext.Container,
+	delegate bufmodule.ModuleDataProvider,
+) (bufmodule.ModuleDataProvider, error) {
+	bundlePath := container.Env(dependencyBundleEnvKey)
+	if bundlePath == "" {
+		return delegate, nil
+	}
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read dependency bundle: %w", err)
+	}
+	return bufmodulebundle.NewModuleDataProvider(
+		container.Logger(),
+		bytes.NewReader(data),
+		delegate,
+	), nil
+}
@@ -20,18 +20,33 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufwkt/bufwktstore"
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulefs"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulemirror"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleproxy"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storagezstd"
+)
+
+const (
+	// cacheLockTimeout is the timeout for acquiring a lock within the cache.
+	//
+	// This is much longer than filelock.DefaultLockTimeout, as other buf processes
+	// sharing the cache, such as parallel CI jobs, may be writing large modules.
+	cacheLockTimeout = time.Minute
 )
 
 var (
@@ -52,6 +67,8 @@ var (
 		v3CacheCommitsRelDirPath,
 		v3CacheWKTRelDirPath,
 		v3CacheModuleLockRelDirPath,
+		v3CacheStatsRelDirPath,
+		v3CacheLSPDepsRelDirPath,
 	}
 
 	// v1CacheModuleDataRelDirPath is the relative path to the cache directory where module data
@@ -103,11 +120,26 @@ var (
 	//
 	// Normalized.
 	v3CacheModuleLockRelDirPath = normalpath.Join("v3", "modulelocks")
+	// v3CacheStatsRelDirPath is the relative path to the cache directory where cache hit and miss
+	// counters are stored. These are reset whenever the cache is cleared.
+	//
+	// Normalized.
+	v3CacheStatsRelDirPath = normalpath.Join("v3", "stats")
 	// v3CacheWasmRuntimeRelDirPath is the relative path to the Wasm runtime cache directory in its newest iteration.
 	// This directory is used to store the Wasm runtime cache. This is an implementation specific cache and opaque outside of the runtime.
 	//
 	// Normalized.
 	v3CacheWasmRuntimeRelDirPath = normalpath.Join("v3", "wasmruntime")
+	// v3CacheCurlReflectionRelDirPath is the relative path to the cache directory where buf curl
+	// stores descriptors downloaded with server reflection.
+	//
+	// Normalized.
+	v3CacheCurlReflectionRelDirPath = normalpath.Join("v3", "curlreflection")
+	// v3CacheLSPDepsRelDirPath is the relative path to the cache directory where the language
+	// server writes the files of remote dependencies, so that editors can open them.
+	//
+	// Normalized.
+	v3CacheLSPDepsRelDirPath = normalpath.Join("v3", "lspdeps")
 )
 
 // NewModuleDataProvider returns a new ModuleDataProvider while creating the
@@ -153,6 +185,26 @@ func CreateWasmRuntimeCacheDir(container appext.Container) (string, error) {
 	return fullCacheDirPath, nil
 }
 
+// CreateCurlReflectionCacheDir creates the cache directory for buf curl server
+// reflection results, and returns the full path to it.
+func CreateCurlReflectionCacheDir(container appext.Container) (string, error) {
+	if err := createCacheDir(container.CacheDirPath(), v3CacheCurlReflectionRelDirPath); err != nil {
+		return "", err
+	}
+	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheCurlReflectionRelDirPath)
+	return fullCacheDirPath, nil
+}
+
+// CreateLSPDepsCacheDir creates the cache directory for the files of remote dependencies
+// written by the language server, and returns the full path to it.
+func CreateLSPDepsCacheDir(container appext.Container) (string, error) {
+	if err := createCacheDir(container.CacheDirPath(), v3CacheLSPDepsRelDirPath); err != nil {
+		return "", err
+	}
+	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheLSPDepsRelDirPath)
+	return fullCacheDirPath, nil
+}
+
 // NewWKTStore returns a new bufwktstore.Store while creating the required cache directories.
 func NewWKTStore(container appext.Container) (bufwktstore.Store, error) {
 	if err := createCacheDir(container.CacheDirPath(), v3CacheWKTRelDirPath); err != nil {
@@ -180,35 +232,265 @@ func newModuleDataProvider(
 		return nil, err
 	}
 	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheModuleRelDirPath)
-	delegateModuleDataProvider := bufmoduleapi.NewModuleDataProvider(
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	downloadConcurrency, err := app.EnvInt(container, downloadConcurrencyEnvKey, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", downloadConcurrencyEnvKey, err)
+	}
+	observer := newLoggingModuleDataProviderObserver(container.Logger())
+	var delegateModuleDataProvider bufmodule.ModuleDataProvider = offlineModuleDataProvider{}
+	if !offline {
+		delegateModuleDataProvider, err = newMirrorModuleDataProviderIfConfigured(
+			container,
+			bufmoduleapi.NewModuleDataProvider(
+				container.Logger(),
+				clientProvider,
+				newGraphProvider(container, clientProvider),
+				// A value of 0 keeps the default.
+				bufmoduleapi.ModuleDataProviderWithDownloadConcurrency(downloadConcurrency),
+				bufmoduleapi.ModuleDataProviderWithObserver(observer),
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+		delegateModuleDataProvider, err = newModuleProxyModuleDataProviderIfConfigured(
+			container,
+			delegateModuleDataProvider,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fileRemoteProviders, err := newFileRemoteProviders(container)
+	if err != nil {
+		return nil, err
+	}
+	delegateModuleDataProvider = bufmodulefs.NewModuleDataProvider(delegateModuleDataProvider, fileRemoteProviders)
+	digestVerification, err := getDigestVerification(container)
+	if err != nil {
+		return nil, err
+	}
+	// The DigestVerification is applied at every layer that reads ModuleDatas, as every
+	// layer that puts ModuleDatas to a store reads their content.
+	delegateModuleDataProvider = bufmodule.NewDigestVerificationModuleDataProvider(
 		container.Logger(),
-		clientProvider,
-		newGraphProvider(container, clientProvider),
+		delegateModuleDataProvider,
+		digestVerification,
 	)
-	// No symlinks.
-	storageosProvider := storageos.NewProvider()
-	cacheBucket, err := storageosProvider.NewReadWriteBucket(fullCacheDirPath)
+	delegateModuleDataProvider, err = newBundleModuleDataProviderIfConfigured(
+		container,
+		delegateModuleDataProvider,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delegateModuleDataProvider = bufmodule.NewDigestVerificationModuleDataProvider(
+		container.Logger(),
+		delegateModuleDataProvider,
+		digestVerification,
+	)
+	cacheBucket, err := newCompressedCacheBucket(container, fullCacheDirPath)
 	if err != nil {
 		return nil, err
 	}
 	if err := createCacheDir(container.CacheDirPath(), v3CacheModuleLockRelDirPath); err != nil {
 		return nil, err
 	}
-	filelocker, err := filelock.NewLocker(normalpath.Join(container.CacheDirPath(), v3CacheModuleLockRelDirPath))
+	filelocker, err := filelock.NewLocker(
+		normalpath.Join(container.CacheDirPath(), v3CacheModuleLockRelDirPath),
+		filelock.LockerWithLockTimeout(cacheLockTimeout),
+	)
 	if err != nil {
 		return nil, err
 	}
-	return bufmodulecache.NewModuleDataProvider(
-		container.Logger(),
-		delegateModuleDataProvider,
+	var moduleDataStoreOptions []bufmodulestore.ModuleDataStoreOption
+	if digestVerification == bufmodule.DigestVerificationStrict {
+		// Files are read from the cache again after the Digest of a ModuleData is
+		// verified, verify each file as it is read.
+		moduleDataStoreOptions = append(
+			moduleDataStoreOptions,
+			bufmodulestore.ModuleDataStoreWithFileDigestVerification(),
+		)
+	}
+	// Hits and misses are counted at the local store, so that modules read from the
+	// remote cache are counted as misses of the local cache.
+	localModuleDataStore, err := newCountingModuleDataStore(
+		container,
 		bufmodulestore.NewModuleDataStore(
 			container.Logger(),
 			cacheBucket,
 			filelocker,
+			moduleDataStoreOptions...,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	moduleDataStore, err := newRemoteModuleDataStoreIfConfigured(container, localModuleDataStore)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewDigestVerificationModuleDataProvider(
+		container.Logger(),
+		bufmodulecache.NewModuleDataProvider(
+			container.Logger(),
+			delegateModuleDataProvider,
+			moduleDataStore,
+			bufmodulecache.ModuleDataProviderWithObserver(observer),
 		),
+		digestVerification,
 	), nil
 }
 
+// getDigestVerification gets the DigestVerification from digestVerificationEnvKey if set,
+// otherwise from the buf configuration.
+func getDigestVerification(container appext.Container) (bufmodule.DigestVerification, error) {
+	if value := container.Env(digestVerificationEnvKey); value != "" {
+		digestVerification, err := bufmodule.ParseDigestVerification(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", digestVerificationEnvKey, err)
+		}
+		return digestVerification, nil
+	}
+	config, err := newConfig(container)
+	if err != nil {
+		return 0, err
+	}
+	return config.DigestVerification, nil
+}
+
+// newRemoteModuleDataStoreIfConfigured wraps the local ModuleDataStore with a remote
+// cache if remoteCacheURLEnvKey is set, otherwise the local ModuleDataStore is returned.
+func newRemoteModuleDataStoreIfConfigured(
+	container appext.Container,
+	localModuleDataStore bufmodulestore.ModuleDataStore,
+) (bufmodulestore.ModuleDataStore, error) {
+	remoteCacheURL := container.Env(remoteCacheURLEnvKey)
+	if remoteCacheURL == "" {
+		return localModuleDataStore, nil
+	}
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		// The remote cache is network access as well.
+		return localModuleDataStore, nil
+	}
+	cacheBackend, err := newCacheBackend(container, remoteCacheURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", remoteCacheURLEnvKey, err)
+	}
+	var remoteModuleDataStoreOptions []bufmodulestore.RemoteModuleDataStoreOption
+	remoteCacheWrite, err := app.EnvBool(container, remoteCacheWriteEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", remoteCacheWriteEnvKey, err)
+	}
+	if remoteCacheWrite {
+		remoteModuleDataStoreOptions = append(
+			remoteModuleDataStoreOptions,
+			bufmodulestore.RemoteModuleDataStoreWithWrite(),
+		)
+	}
+	return bufmodulestore.NewRemoteModuleDataStore(
+		container.Logger(),
+		localModuleDataStore,
+		cacheBackend,
+		remoteModuleDataStoreOptions...,
+	), nil
+}
+
+// newCacheBackend returns a new CacheBackend for the remote cache URL, which is either
+// an object storage URL or the base URL of an HTTP cache.
+func newCacheBackend(
+	container appext.Container,
+	remoteCacheURL string,
+) (bufmodulestore.CacheBackend, error) {
+	bucket, ok, err := NewObjectStorageBucket(container, remoteCacheURL)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return bufmodulestore.NewBucketCacheBackend(bucket), nil
+	}
+	var httpCacheBackendOptions []bufmodulestore.HTTPCacheBackendOption
+	if remoteCacheToken := container.Env(remoteCacheTokenEnvKey); remoteCacheToken != "" {
+		httpCacheBackendOptions = append(
+			httpCacheBackendOptions,
+			bufmodulestore.HTTPCacheBackendWithHeader("Authorization", "Bearer "+remoteCacheToken),
+		)
+	}
+	httpClient, err := NewHTTPClient(container)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodulestore.NewHTTPCacheBackend(
+		httpClient,
+		remoteCacheURL,
+		httpCacheBackendOptions...,
+	)
+}
+
+// newMirrorModuleDataProviderIfConfigured downloads modules from the mirrors in the
+// configuration file if any are configured, otherwise the direct ModuleDataProvider
+// is returned.
+func newMirrorModuleDataProviderIfConfigured(
+	container appext.Container,
+	directModuleDataProvider bufmodule.ModuleDataProvider,
+) (bufmodule.ModuleDataProvider, error) {
+	config, err := newConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Mirrors) == 0 {
+		return directModuleDataProvider, nil
+	}
+	return bufmodulemirror.NewModuleDataProvider(
+		container.Logger(),
+		directModuleDataProvider,
+		config.Mirrors,
+	)
+}
+
+// newModuleProxyModuleDataProviderIfConfigured routes module downloads through the
+// module proxies in moduleProxyEnvKey if set, otherwise the direct ModuleDataProvider
+// is returned.
+func newModuleProxyModuleDataProviderIfConfigured(
+	container appext.Container,
+	directModuleDataProvider bufmodule.ModuleDataProvider,
+) (bufmodule.ModuleDataProvider, error) {
+	moduleProxy := container.Env(moduleProxyEnvKey)
+	if moduleProxy == "" {
+		return directModuleDataProvider, nil
+	}
+	var moduleDataProviderOptions []bufmoduleproxy.ModuleDataProviderOption
+	if moduleProxyToken := container.Env(moduleProxyTokenEnvKey); moduleProxyToken != "" {
+		moduleDataProviderOptions = append(
+			moduleDataProviderOptions,
+			bufmoduleproxy.ModuleDataProviderWithHeader("Authorization", "Bearer "+moduleProxyToken),
+		)
+	}
+	httpClient, err := NewHTTPClient(container)
+	if err != nil {
+		return nil, err
+	}
+	moduleDataProvider, err := bufmoduleproxy.NewModuleDataProvider(
+		container.Logger(),
+		httpClient,
+		moduleProxy,
+		directModuleDataProvider,
+		moduleDataProviderOptions...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", moduleProxyEnvKey, err)
+	}
+	return moduleDataProvider, nil
+}
+
 func newCommitProvider(
 	container appext.Container,
 	clientProvider bufapi.ClientProvider,
@@ -217,10 +499,20 @@ func newCommitProvider(
 		return nil, err
 	}
 	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheCommitsRelDirPath)
-	delegateReader := bufmoduleapi.NewCommitProvider(container.Logger(), clientProvider)
-	// No symlinks.
-	storageosProvider := storageos.NewProvider()
-	cacheBucket, err := storageosProvider.NewReadWriteBucket(fullCacheDirPath)
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	var delegateReader bufmodule.CommitProvider = offlineCommitProvider{}
+	if !offline {
+		delegateReader = bufmoduleapi.NewCommitProvider(container.Logger(), clientProvider)
+	}
+	fileRemoteProviders, err := newFileRemoteProviders(container)
+	if err != nil {
+		return nil, err
+	}
+	delegateReader = bufmodulefs.NewCommitProvider(delegateReader, fileRemoteProviders)
+	cacheBucket, err := newCompressedCacheBucket(container, fullCacheDirPath)
 	if err != nil {
 		return nil, err
 	}
@@ -234,6 +526,30 @@ func newCommitProvider(
 	), nil
 }
 
+// newCompressedCacheBucket returns a new bucket for the cache directory at fullCacheDirPath
+// that serves both compressed and uncompressed objects.
+//
+// New objects are only written zstd-compressed if cacheCompressionEnvKey is set to true.
+// Compression is off by default as files in the cache are then not readable by editors,
+// which breaks navigating to dependencies from the LSP.
+func newCompressedCacheBucket(container appext.Container, fullCacheDirPath string) (storage.ReadWriteBucket, error) {
+	cacheCompression, err := app.EnvBool(container, cacheCompressionEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cacheCompressionEnvKey, err)
+	}
+	// No symlinks.
+	storageosProvider := storageos.NewProvider()
+	cacheBucket, err := storageosProvider.NewReadWriteBucket(fullCacheDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var options []storagezstd.ReadWriteBucketOption
+	if !cacheCompression {
+		options = append(options, storagezstd.ReadWriteBucketWithUncompressedWrites())
+	}
+	return storagezstd.NewReadWriteBucket(cacheBucket, options...), nil
+}
+
 func createCacheDir(baseCacheDirPath string, relDirPath string) error {
 	baseCacheDirPath = normalpath.Unnormalize(baseCacheDirPath)
 	relDirPath = normalpath.Unnormalize(relDirPath)
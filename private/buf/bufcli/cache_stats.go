@@ -0,0 +1,321 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"go.uber.org/multierr"
+)
+
+const (
+	cacheStatsCountersFileName     = "counters.json"
+	cacheStatsCountersLockFileName = "counters.json.lock"
+	// cacheModuleDataFileName is the file written last for every module in the module cache.
+	//
+	// Its presence indicates that the module was completely written.
+	cacheModuleDataFileName = "module.yaml"
+	// cacheModuleDirDepth is the depth of module directories within the module cache,
+	// that is "digestType/registry/owner/name/dashlessCommitID".
+	cacheModuleDirDepth = 5
+)
+
+// CacheStats are statistics about the module cache.
+type CacheStats struct {
+	// Remotes are the per-remote statistics, sorted by remote.
+	Remotes []*CacheRemoteStats `json:"remotes,omitempty"`
+	// NumModules is the total number of modules in the cache.
+	NumModules int `json:"num_modules"`
+	// NumBlobs is the total number of files stored for all modules in the cache.
+	NumBlobs int `json:"num_blobs"`
+	// TotalBytes is the total size of the module cache on disk.
+	TotalBytes int64 `json:"total_bytes"`
+	// Hits is the number of modules read from the cache since the cache was last cleared.
+	Hits int64 `json:"hits"`
+	// Misses is the number of modules not found in the cache since the cache was last cleared.
+	Misses int64 `json:"misses"`
+	// LargestModules are the largest modules in the cache, sorted by size descending.
+	LargestModules []*CacheModuleStats `json:"largest_modules,omitempty"`
+}
+
+// CacheRemoteStats are statistics about a single remote within the module cache.
+type CacheRemoteStats struct {
+	Remote     string `json:"remote"`
+	NumModules int    `json:"num_modules"`
+	NumBlobs   int    `json:"num_blobs"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+// CacheModuleStats are statistics about a single module commit within the module cache.
+type CacheModuleStats struct {
+	Name       string `json:"name"`
+	Commit     string `json:"commit"`
+	DigestType string `json:"digest_type"`
+	NumBlobs   int    `json:"num_blobs"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+// GetCacheStats gets the statistics for the module cache.
+//
+// Only the largestModulesLimit largest modules are returned in LargestModules.
+func GetCacheStats(
+	ctx context.Context,
+	container appext.Container,
+	largestModulesLimit int,
+) (*CacheStats, error) {
+	cacheStats := &CacheStats{}
+	cacheModuleStats, err := getAllCacheModuleStats(
+		filepath.Join(
+			container.CacheDirPath(),
+			normalpath.Unnormalize(v3CacheModuleRelDirPath),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	remoteToCacheRemoteStats := make(map[string]*CacheRemoteStats)
+	for _, oneCacheModuleStats := range cacheModuleStats {
+		remote, _, _ := strings.Cut(oneCacheModuleStats.Name, "/")
+		cacheRemoteStats, ok := remoteToCacheRemoteStats[remote]
+		if !ok {
+			cacheRemoteStats = &CacheRemoteStats{
+				Remote: remote,
+			}
+			remoteToCacheRemoteStats[remote] = cacheRemoteStats
+			cacheStats.Remotes = append(cacheStats.Remotes, cacheRemoteStats)
+		}
+		cacheRemoteStats.NumModules++
+		cacheRemoteStats.NumBlobs += oneCacheModuleStats.NumBlobs
+		cacheRemoteStats.TotalBytes += oneCacheModuleStats.TotalBytes
+		cacheStats.NumModules++
+		cacheStats.NumBlobs += oneCacheModuleStats.NumBlobs
+		cacheStats.TotalBytes += oneCacheModuleStats.TotalBytes
+	}
+	sort.Slice(
+		cacheStats.Remotes,
+		func(i int, j int) bool {
+			return cacheStats.Remotes[i].Remote < cacheStats.Remotes[j].Remote
+		},
+	)
+	sort.SliceStable(
+		cacheModuleStats,
+		func(i int, j int) bool {
+			return cacheModuleStats[i].TotalBytes > cacheModuleStats[j].TotalBytes
+		},
+	)
+	if largestModulesLimit >= 0 && len(cacheModuleStats) > largestModulesLimit {
+		cacheModuleStats = cacheModuleStats[:largestModulesLimit]
+	}
+	cacheStats.LargestModules = cacheModuleStats
+	cacheCounters, err := readCacheCounters(
+		filepath.Join(
+			container.CacheDirPath(),
+			normalpath.Unnormalize(v3CacheStatsRelDirPath),
+			cacheStatsCountersFileName,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cacheStats.Hits = cacheCounters.Hits
+	cacheStats.Misses = cacheCounters.Misses
+	return cacheStats, nil
+}
+
+// *** PRIVATE ***
+
+// cacheCounters are the hit and miss counters persisted in the cache directory.
+type cacheCounters struct {
+	Hits   int64 `json:"hits,omitempty"`
+	Misses int64 `json:"misses,omitempty"`
+}
+
+// countingModuleDataStore is a ModuleDataStore that records cache hits and misses
+// across invocations.
+type countingModuleDataStore struct {
+	bufmodulestore.ModuleDataStore
+
+	logger       *slog.Logger
+	locker       filelock.Locker
+	countersPath string
+}
+
+func newCountingModuleDataStore(
+	container appext.Container,
+	delegate bufmodulestore.ModuleDataStore,
+) (*countingModuleDataStore, error) {
+	if err := createCacheDir(container.CacheDirPath(), v3CacheStatsRelDirPath); err != nil {
+		return nil, err
+	}
+	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheStatsRelDirPath)
+	locker, err := filelock.NewLocker(fullCacheDirPath)
+	if err != nil {
+		return nil, err
+	}
+	return &countingModuleDataStore{
+		ModuleDataStore: delegate,
+		logger:          container.Logger(),
+		locker:          locker,
+		countersPath:    filepath.Join(normalpath.Unnormalize(fullCacheDirPath), cacheStatsCountersFileName),
+	}, nil
+}
+
+func (c *countingModuleDataStore) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, []bufmodule.ModuleKey, error) {
+	foundModuleDatas, notFoundModuleKeys, err := c.ModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Statistics are best-effort, we never want to fail a command because we could not
+	// record a cache hit.
+	if err := c.addCounters(ctx, int64(len(foundModuleDatas)), int64(len(notFoundModuleKeys))); err != nil {
+		c.logger.DebugContext(ctx, "failed to record cache statistics", slogext.ErrorAttr(err))
+	}
+	return foundModuleDatas, notFoundModuleKeys, nil
+}
+
+func (c *countingModuleDataStore) addCounters(ctx context.Context, hits int64, misses int64) (retErr error) {
+	if hits == 0 && misses == 0 {
+		return nil
+	}
+	unlocker, err := c.locker.Lock(ctx, cacheStatsCountersLockFileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	cacheCounters, err := readCacheCounters(c.countersPath)
+	if err != nil {
+		return err
+	}
+	cacheCounters.Hits += hits
+	cacheCounters.Misses += misses
+	data, err := json.Marshal(cacheCounters)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename so that readers never see a partial file.
+	tempPath := c.countersPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, c.countersPath)
+}
+
+// readCacheCounters reads the counters at the path.
+//
+// Returns empty counters if the file does not exist or is corrupted.
+func readCacheCounters(path string) (*cacheCounters, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &cacheCounters{}, nil
+		}
+		return nil, err
+	}
+	counters := &cacheCounters{}
+	if err := json.Unmarshal(data, counters); err != nil {
+		// Corrupted counters are reset rather than failing.
+		return &cacheCounters{}, nil
+	}
+	return counters, nil
+}
+
+// getAllCacheModuleStats walks the module cache directory and returns the statistics for
+// every module that was completely written to the cache.
+func getAllCacheModuleStats(moduleCacheDirPath string) ([]*CacheModuleStats, error) {
+	keyToCacheModuleStats := make(map[string]*CacheModuleStats)
+	complete := make(map[string]bool)
+	if err := filepath.WalkDir(
+		moduleCacheDirPath,
+		func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && path == moduleCacheDirPath {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !dirEntry.Type().IsRegular() {
+				return nil
+			}
+			relPath, err := filepath.Rel(moduleCacheDirPath, path)
+			if err != nil {
+				return err
+			}
+			components := strings.Split(normalpath.Normalize(relPath), "/")
+			if len(components) <= cacheModuleDirDepth {
+				// Lock files, tarballs, and anything else that is not within a module directory.
+				return nil
+			}
+			fileInfo, err := dirEntry.Info()
+			if err != nil {
+				return err
+			}
+			key := strings.Join(components[:cacheModuleDirDepth], "/")
+			oneCacheModuleStats, ok := keyToCacheModuleStats[key]
+			if !ok {
+				oneCacheModuleStats = &CacheModuleStats{
+					DigestType: components[0],
+					Name:       strings.Join(components[1:4], "/"),
+					Commit:     components[4],
+				}
+				keyToCacheModuleStats[key] = oneCacheModuleStats
+			}
+			if len(components) == cacheModuleDirDepth+1 && components[cacheModuleDirDepth] == cacheModuleDataFileName {
+				complete[key] = true
+			} else {
+				oneCacheModuleStats.NumBlobs++
+			}
+			oneCacheModuleStats.TotalBytes += fileInfo.Size()
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	completeCacheModuleStats := make([]*CacheModuleStats, 0, len(complete))
+	for key, oneCacheModuleStats := range keyToCacheModuleStats {
+		if complete[key] {
+			completeCacheModuleStats = append(completeCacheModuleStats, oneCacheModuleStats)
+		}
+	}
+	// Sort for determinism, callers may sort again.
+	sort.Slice(
+		completeCacheModuleStats,
+		func(i int, j int) bool {
+			if completeCacheModuleStats[i].Name != completeCacheModuleStats[j].Name {
+				return completeCacheModuleStats[i].Name < completeCacheModuleStats[j].Name
+			}
+			return completeCacheModuleStats[i].Commit < completeCacheModuleStats[j].Commit
+		},
+	)
+	return completeCacheModuleStats, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingModuleDataStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container := testNewCacheContainer(t)
+	moduleKeys, moduleDatas := testGetCacheModuleKeysAndModuleDatas(t, ctx)
+	countingModuleDataStore, err := newCountingModuleDataStore(
+		container,
+		bufmodulestore.NewModuleDataStore(
+			container.Logger(),
+			storagemem.NewReadWriteBucket(),
+			filelock.NewNopLocker(),
+		),
+	)
+	require.NoError(t, err)
+
+	_, notFoundModuleKeys, err := countingModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, notFoundModuleKeys, 2)
+	testRequireCacheCounters(t, container, 0, 2)
+
+	require.NoError(t, countingModuleDataStore.PutModuleDatas(ctx, moduleDatas[:1]))
+	foundModuleDatas, notFoundModuleKeys, err := countingModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 1)
+	require.Len(t, notFoundModuleKeys, 1)
+	testRequireCacheCounters(t, container, 1, 3)
+
+	// Empty lookups do not touch the counters.
+	_, _, err = countingModuleDataStore.GetModuleDatasForModuleKeys(ctx, nil)
+	require.NoError(t, err)
+	testRequireCacheCounters(t, container, 1, 3)
+}
+
+func TestReadCacheCountersCorrupted(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), cacheStatsCountersFileName)
+	counters, err := readCacheCounters(path)
+	require.NoError(t, err)
+	assert.Equal(t, &cacheCounters{}, counters)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	counters, err = readCacheCounters(path)
+	require.NoError(t, err)
+	assert.Equal(t, &cacheCounters{}, counters)
+}
+
+func TestGetAllCacheModuleStats(t *testing.T) {
+	t.Parallel()
+	moduleCacheDirPath := t.TempDir()
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/buf.build/foo/a/1234/module.yaml", "1234")
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/buf.build/foo/a/1234/files/a.proto", "12")
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/buf.build/foo/a/1234/files/b/b.proto", "123")
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/example.com/bar/b/5678/module.yaml", "1")
+	// Modules without module.yaml were not completely written and are ignored.
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/buf.build/foo/c/9012/files/c.proto", "12345")
+	// Files outside of module directories are ignored.
+	testWriteCacheFile(t, moduleCacheDirPath, "b5/buf.build/foo/a/1234.lock", "")
+
+	cacheModuleStats, err := getAllCacheModuleStats(moduleCacheDirPath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*CacheModuleStats{
+			{
+				Name:       "buf.build/foo/a",
+				Commit:     "1234",
+				DigestType: "b5",
+				NumBlobs:   2,
+				TotalBytes: 9,
+			},
+			{
+				Name:       "example.com/bar/b",
+				Commit:     "5678",
+				DigestType: "b5",
+				TotalBytes: 1,
+			},
+		},
+		cacheModuleStats,
+	)
+
+	cacheModuleStats, err = getAllCacheModuleStats(filepath.Join(moduleCacheDirPath, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, cacheModuleStats)
+}
+
+func testNewCacheContainer(t *testing.T) appext.Container {
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			map[string]string{
+				"BUF_CACHE_DIR": t.TempDir(),
+			},
+			nil,
+			nil,
+			nil,
+		),
+		"buf",
+	)
+	require.NoError(t, err)
+	return appext.NewContainer(nameContainer, slogtestext.NewLogger(t))
+}
+
+func testGetCacheModuleKeysAndModuleDatas(t *testing.T, ctx context.Context) ([]bufmodule.ModuleKey, []bufmodule.ModuleData) {
+	omniProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod1",
+			PathToData: map[string][]byte{
+				"mod1.proto": []byte(`syntax = "proto3"; package mod1;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod2",
+			PathToData: map[string][]byte{
+				"mod2.proto": []byte(`syntax = "proto3"; package mod2;`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	var moduleRefs []bufmodule.ModuleRef
+	for _, name := range []string{"mod1", "mod2"} {
+		moduleRef, err := bufmodule.NewModuleRef("buf.build", "foo", name, "")
+		require.NoError(t, err)
+		moduleRefs = append(moduleRefs, moduleRef)
+	}
+	moduleKeys, err := omniProvider.GetModuleKeysForModuleRefs(ctx, moduleRefs, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	moduleDatas, err := omniProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	return moduleKeys, moduleDatas
+}
+
+func testRequireCacheCounters(t *testing.T, container appext.Container, expectedHits int64, expectedMisses int64) {
+	cacheStats, err := GetCacheStats(context.Background(), container, 0)
+	require.NoError(t, err)
+	assert.Equal(t, expectedHits, cacheStats.Hits)
+	assert.Equal(t, expectedMisses, cacheStats.Misses)
+}
+
+func testWriteCacheFile(t *testing.T, dirPath string, relPath string, data string) {
+	path := filepath.Join(dirPath, filepath.FromSlash(relPath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
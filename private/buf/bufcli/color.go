@@ -0,0 +1,94 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/termstyle"
+	"github.com/spf13/pflag"
+)
+
+const colorFlagName = "color"
+
+// BindColor binds the global --color flag.
+//
+// The flag is applied to the Container with NewColorInterceptor.
+func BindColor(flagSet *pflag.FlagSet, color *string) {
+	flagSet.StringVar(
+		color,
+		colorFlagName,
+		"",
+		fmt.Sprintf(
+			`Whether to color the output. Must be one of %s. Defaults to auto, which colors the output if it is a terminal and %s is not set. Can also be set with %s`,
+			strings.Join(termstyle.AllColorModeStrings, ","),
+			termstyle.NoColorEnvKey,
+			colorEnvKey,
+		),
+	)
+}
+
+// NewColorInterceptor returns a new Interceptor that sets colorEnvKey on the
+// Container if color is set, so that NewStyler reflects the --color flag.
+func NewColorInterceptor(color *string) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if *color == "" {
+				return next(ctx, container)
+			}
+			if _, err := termstyle.ParseColorMode(*color); err != nil {
+				return appcmd.NewInvalidArgumentErrorf("--%s: %v", colorFlagName, err)
+			}
+			colorContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					colorEnvKey: *color,
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, colorContainer)
+		}
+	}
+}
+
+// NewStyler returns a new Styler for output written to the writer.
+//
+// The output is styled according to --color or colorEnvKey.
+func NewStyler(container app.EnvContainer, writer io.Writer) (termstyle.Styler, error) {
+	colorMode, err := getColorMode(container)
+	if err != nil {
+		return nil, err
+	}
+	return termstyle.NewStylerForWriter(colorMode, container, writer), nil
+}
+
+// *** PRIVATE ***
+
+// getColorMode returns the ColorMode set by --color or colorEnvKey.
+func getColorMode(container app.EnvContainer) (termstyle.ColorMode, error) {
+	colorMode, err := termstyle.ParseColorMode(container.Env(colorEnvKey))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", colorEnvKey, err)
+	}
+	return colorMode, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// NewInputCompletionFunc returns a new appcmd.CompletionFunc that completes inputs
+// such as the value of --against.
+//
+// If the value being completed is a local git repository followed by "#", such as
+// ".git#", the branches and tags of the repository are completed. Otherwise, the names
+// of the modules in the module cache are completed, and the shell falls back to
+// completing files if there are no matching modules.
+func NewInputCompletionFunc(builder appext.SubCommandBuilder) appcmd.CompletionFunc {
+	return newCompletionFunc(
+		builder,
+		func(ctx context.Context, container appext.Container, toComplete string) ([]string, error) {
+			if gitDirPath, _, ok := strings.Cut(toComplete, "#"); ok {
+				return completeGitRefs(ctx, container, gitDirPath, toComplete)
+			}
+			return completeCachedModuleNames(container, toComplete)
+		},
+	)
+}
+
+// NewTypeCompletionFunc returns a new appcmd.CompletionFunc that completes the fully-qualified
+// names of the types in the input, such as the value of --type.
+//
+// The input is the first positional argument, or the current directory if no argument
+// was given. If messagesOnly is set, only message names are completed, otherwise message,
+// enum, and service names are completed.
+func NewTypeCompletionFunc(builder appext.SubCommandBuilder, messagesOnly bool) appcmd.CompletionFunc {
+	return newCompletionFunc(
+		builder,
+		func(ctx context.Context, container appext.Container, toComplete string) ([]string, error) {
+			input, err := GetInputValue(container, "", ".")
+			if err != nil {
+				return nil, err
+			}
+			controller, err := NewController(container)
+			if err != nil {
+				return nil, err
+			}
+			image, err := controller.GetImage(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			var typeNames []string
+			for _, imageFile := range image.Files() {
+				fileDescriptorProto := imageFile.FileDescriptorProto()
+				typeNames = appendTypeNames(
+					typeNames,
+					fileDescriptorProto.GetPackage(),
+					fileDescriptorProto.GetMessageType(),
+					fileDescriptorProto.GetEnumType(),
+					messagesOnly,
+				)
+				if !messagesOnly {
+					for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+						typeNames = append(typeNames, joinTypeName(fileDescriptorProto.GetPackage(), serviceDescriptorProto.GetName()))
+					}
+				}
+			}
+			return filterCompletions(typeNames, toComplete), nil
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+func newCompletionFunc(
+	builder appext.SubCommandBuilder,
+	f func(context.Context, appext.Container, string) ([]string, error),
+) appcmd.CompletionFunc {
+	return func(ctx context.Context, container app.Container, toComplete string) ([]string, error) {
+		var completions []string
+		if err := builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				var err error
+				completions, err = f(ctx, container, toComplete)
+				return err
+			},
+		)(ctx, container); err != nil {
+			return nil, err
+		}
+		return completions, nil
+	}
+}
+
+func completeGitRefs(
+	ctx context.Context,
+	container appext.Container,
+	gitDirPath string,
+	toComplete string,
+) ([]string, error) {
+	branches, tags, err := git.ListBranchesAndTags(ctx, command.NewRunner(), container, gitDirPath)
+	if err != nil {
+		// The path is not a git repository, there is nothing to complete.
+		return nil, nil
+	}
+	completions := make([]string, 0, len(branches)+len(tags))
+	for _, branch := range branches {
+		completions = append(completions, gitDirPath+"#branch="+branch)
+	}
+	for _, tag := range tags {
+		completions = append(completions, gitDirPath+"#tag="+tag)
+	}
+	return filterCompletions(completions, toComplete), nil
+}
+
+func completeCachedModuleNames(container appext.Container, toComplete string) ([]string, error) {
+	cacheModuleStats, err := getAllCacheModuleStats(
+		filepath.Join(
+			container.CacheDirPath(),
+			normalpath.Unnormalize(v3CacheModuleRelDirPath),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	moduleNames := make([]string, 0, len(cacheModuleStats))
+	for _, oneCacheModuleStats := range cacheModuleStats {
+		moduleNames = append(moduleNames, oneCacheModuleStats.Name)
+	}
+	return filterCompletions(moduleNames, toComplete), nil
+}
+
+func appendTypeNames(
+	typeNames []string,
+	prefix string,
+	descriptorProtos []*descriptorpb.DescriptorProto,
+	enumDescriptorProtos []*descriptorpb.EnumDescriptorProto,
+	messagesOnly bool,
+) []string {
+	for _, descriptorProto := range descriptorProtos {
+		typeName := joinTypeName(prefix, descriptorProto.GetName())
+		if !descriptorProto.GetOptions().GetMapEntry() {
+			typeNames = append(typeNames, typeName)
+		}
+		typeNames = appendTypeNames(
+			typeNames,
+			typeName,
+			descriptorProto.GetNestedType(),
+			descriptorProto.GetEnumType(),
+			messagesOnly,
+		)
+	}
+	if !messagesOnly {
+		for _, enumDescriptorProto := range enumDescriptorProtos {
+			typeNames = append(typeNames, joinTypeName(prefix, enumDescriptorProto.GetName()))
+		}
+	}
+	return typeNames
+}
+
+func joinTypeName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// filterCompletions returns the sorted, deduplicated completions that start with toComplete.
+func filterCompletions(completions []string, toComplete string) []string {
+	var filtered []string
+	seen := make(map[string]struct{})
+	for _, completion := range completions {
+		if _, ok := seen[completion]; ok || !strings.HasPrefix(completion, toComplete) {
+			continue
+		}
+		seen[completion] = struct{}{}
+		filtered = append(filtered, completion)
+	}
+	sort.Strings(filtered)
+	return filtered
+}
@@ -15,30 +15,41 @@
 package bufcli
 
 import (
+	"fmt"
+
 	"connectrpc.com/connect"
 	otelconnect "connectrpc.com/otelconnect"
 	"github.com/bufbuild/buf/private/buf/bufapp"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/bufpkg/buftransport"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
-	"github.com/bufbuild/buf/private/pkg/netrc"
-	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
 )
 
 // NewConnectClientConfig creates a new connect.ClientConfig which uses a token reader to look
-// up the token in the container or in netrc based on the address of each individual client.
+// up the token in the container, in the SSO credentials, or in netrc based on the address of
+// each individual client.
 // It is then set in the header of all outgoing requests from clients created using this config.
 func NewConnectClientConfig(container appext.Container) (*connectclient.Config, error) {
 	envTokenProvider, err := bufconnect.NewTokenProviderFromContainer(container)
 	if err != nil {
 		return nil, err
 	}
-	netrcTokenProvider := bufconnect.NewNetrcTokenProvider(container, netrc.GetMachineForName)
+	config, err := newConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(container, config)
+	if err != nil {
+		return nil, err
+	}
+	ssoTokenProvider := newSSOTokenProvider(container, httpClient)
+	netrcTokenProvider := bufconnect.NewNetrcTokenProvider(container, newCachedGetMachineForName(container))
 	return newConnectClientConfigWithOptions(
 		container,
 		connectclient.WithAuthInterceptorProvider(
-			bufconnect.NewAuthorizationInterceptorProvider(envTokenProvider, netrcTokenProvider),
+			bufconnect.NewAuthorizationInterceptorProvider(envTokenProvider, ssoTokenProvider, netrcTokenProvider),
 		),
 	)
 }
@@ -68,7 +79,34 @@ func newConnectClientConfigWithOptions(container appext.Container, opts ...conne
 	if err != nil {
 		return nil, err
 	}
-	client := httpclient.NewClient(config.TLS)
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	rpcMaxAttempts, err := app.EnvInt(container, rpcMaxAttemptsEnvKey, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", rpcMaxAttemptsEnvKey, err)
+	}
+	interceptors := []connect.Interceptor{
+		bufconnect.NewAugmentedConnectErrorInterceptor(),
+		bufconnect.NewSetCLIVersionInterceptor(Version),
+		bufconnect.NewCLIWarningInterceptor(container),
+		otelconnectInterceptor,
+		// Innermost, so that every attempt is traced, and server warnings are only logged once.
+		bufconnect.NewRetryInterceptor(
+			container.Logger(),
+			// A value of 0 keeps the default.
+			bufconnect.RetryInterceptorWithMaxAttempts(rpcMaxAttempts),
+		),
+	}
+	if offline {
+		// Outermost, so that no other interceptor runs.
+		interceptors = append([]connect.Interceptor{offlineInterceptor{}}, interceptors...)
+	}
+	client, err := newHTTPClient(container, config)
+	if err != nil {
+		return nil, err
+	}
 	options := []connectclient.ConfigOption{
 		connectclient.WithAddressMapper(func(address string) string {
 			if config.TLS == nil {
@@ -76,14 +114,7 @@ func newConnectClientConfigWithOptions(container appext.Container, opts ...conne
 			}
 			return buftransport.PrependHTTPS(address)
 		}),
-		connectclient.WithInterceptors(
-			[]connect.Interceptor{
-				bufconnect.NewAugmentedConnectErrorInterceptor(),
-				bufconnect.NewSetCLIVersionInterceptor(Version),
-				bufconnect.NewCLIWarningInterceptor(container),
-				otelconnectInterceptor,
-			},
-		),
+		connectclient.WithInterceptors(interceptors),
 	}
 	return connectclient.NewConfig(client, append(options, opts...)...), nil
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulefs"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
@@ -32,6 +33,12 @@ func NewController(
 			bufctl.WithCopyToInMemory(),
 		)
 	}
+	colorMode, err := getColorMode(container)
+	if err != nil {
+		return nil, err
+	}
+	// Prepended, so that an explicit option from the command takes precedence.
+	options = append([]bufctl.ControllerOption{bufctl.WithColorMode(colorMode)}, options...)
 	clientConfig, err := NewConnectClientConfig(container)
 	if err != nil {
 		return nil, err
@@ -49,16 +56,23 @@ func NewController(
 	if err != nil {
 		return nil, err
 	}
+	httpClient, err := NewHTTPClient(container)
+	if err != nil {
+		return nil, err
+	}
+	fileRemoteProviders, err := newFileRemoteProviders(container)
+	if err != nil {
+		return nil, err
+	}
 	return bufctl.NewController(
 		container.Logger(),
 		container,
-		newGraphProvider(container, clientProvider),
-		bufmoduleapi.NewModuleKeyProvider(container.Logger(), clientProvider),
+		bufmodulefs.NewGraphProvider(newGraphProvider(container, clientProvider), fileRemoteProviders),
+		bufmodulefs.NewModuleKeyProvider(bufmoduleapi.NewModuleKeyProvider(container.Logger(), clientProvider), fileRemoteProviders),
 		moduleDataProvider,
 		commitProvider,
 		wktStore,
-		// TODO FUTURE: Delete defaultHTTPClient and use the one from newConfig
-		defaultHTTPClient,
+		httpClient,
 		defaultHTTPAuthenticator,
 		defaultGitClonerOptions,
 		options...,
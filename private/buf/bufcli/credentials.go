@@ -0,0 +1,519 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/keyring"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+)
+
+const (
+	credentialStoreEnvKey = "BUF_CREDENTIAL_STORE"
+
+	credentialStoreAuto     = ""
+	credentialStoreKeychain = "keychain"
+	credentialStoreNetrc    = "netrc"
+
+	// keyringService is the service that registry credentials are stored under in the
+	// OS keychain. The account is the name of the remote.
+	keyringService = "buf"
+	// keyringIndexFileName is the name of the file within the config directory that lists
+	// the remotes with credentials in the OS keychain, as credential stores cannot be listed
+	// portably. The OS keychain is only read for remotes in this file, unless
+	// BUF_CREDENTIAL_STORE is set to keychain.
+	keyringIndexFileName = "keychain.json"
+	// keyringTimeout is the maximum time for a single operation on the OS keychain, which
+	// may run a separate process that waits for the keychain to be unlocked.
+	keyringTimeout = 5 * time.Second
+)
+
+// GetMachineForName returns the registry credentials for the remote with the given name.
+//
+// The OS keychain is checked first if buf registry login saved the credentials for the
+// remote there, followed by the .netrc file. Returns nil if no credentials are found.
+func GetMachineForName(ctx context.Context, container appext.NameContainer, name string) (netrc.Machine, error) {
+	credentialStore, err := newCredentialStore(container)
+	if err != nil {
+		return nil, err
+	}
+	return credentialStore.getMachineForName(ctx, name)
+}
+
+// PutMachine saves the registry credentials for a remote.
+//
+// The credentials are saved to the OS keychain if it is available, and to the .netrc file
+// otherwise. Set BUF_CREDENTIAL_STORE to "keychain" or "netrc" to require a specific store.
+//
+// Returns a description of where the credentials were saved, suitable for printing.
+func PutMachine(ctx context.Context, container appext.NameContainer, machine netrc.Machine) (string, error) {
+	credentialStore, err := newCredentialStore(container)
+	if err != nil {
+		return "", err
+	}
+	return credentialStore.putMachine(ctx, machine)
+}
+
+// DeleteMachineForName deletes the registry credentials for the remote with the given name
+// from both the OS keychain and the .netrc file.
+//
+// Returns true if any credentials were deleted.
+func DeleteMachineForName(ctx context.Context, container appext.NameContainer, name string) (bool, error) {
+	credentialStore, err := newCredentialStore(container)
+	if err != nil {
+		return false, err
+	}
+	return credentialStore.deleteMachineForName(ctx, name)
+}
+
+// GetTokenForRemote returns the Buf API token that commands send to the remote.
+//
+// Tokens are read in the same order as for requests to the BSR: from BUF_TOKEN, from
+// the credentials of buf registry login --sso, and then from the credentials stored
+// by buf registry login. Returns an error if there is no token for the remote.
+func GetTokenForRemote(ctx context.Context, container appext.Container, remote string) (string, error) {
+	envTokenProvider, err := bufconnect.NewTokenProviderFromContainer(container)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", bufconnect.TokenEnvKey, err)
+	}
+	config, err := newConfig(container)
+	if err != nil {
+		return "", err
+	}
+	httpClient, err := newHTTPClient(container, config)
+	if err != nil {
+		return "", err
+	}
+	for _, tokenProvider := range []bufconnect.TokenProvider{
+		envTokenProvider,
+		newSSOTokenProvider(container, httpClient),
+	} {
+		if token := tokenProvider.RemoteToken(remote); token != "" {
+			return token, nil
+		}
+	}
+	machine, err := GetMachineForName(ctx, container, remote)
+	if err != nil {
+		return "", err
+	}
+	if machine != nil && machine.Password() != "" {
+		return machine.Password(), nil
+	}
+	return "", fmt.Errorf(
+		"no Buf API token for %s, run buf registry login %s or set %s",
+		remote,
+		remote,
+		bufconnect.TokenEnvKey,
+	)
+}
+
+// CredentialsSource is where the credentials for a remote are configured.
+type CredentialsSource string
+
+const (
+	// CredentialsSourceEnv is the BUF_TOKEN environment variable.
+	CredentialsSourceEnv CredentialsSource = "env"
+	// CredentialsSourceSSO is the credentials from buf registry login --sso.
+	CredentialsSourceSSO CredentialsSource = "sso"
+	// CredentialsSourceKeychain is the OS keychain.
+	CredentialsSourceKeychain CredentialsSource = "keychain"
+	// CredentialsSourceNetrc is the .netrc file.
+	CredentialsSourceNetrc CredentialsSource = "netrc"
+)
+
+// RemoteCredentials are the credentials used for a remote.
+type RemoteCredentials struct {
+	// Remote is the name of the remote.
+	Remote string
+	// Source is where the credentials are configured.
+	//
+	// If credentials are configured in multiple sources, this is the source that takes
+	// precedence, in the order env, sso, keychain, netrc.
+	Source CredentialsSource
+	// Login is the login saved with the token. May be empty.
+	Login string
+	// Expiry is when the token expires. Zero if unknown or if the token does not expire.
+	Expiry time.Time
+}
+
+// GetRemoteCredentials returns the credentials used for every remote that has credentials
+// configured, sorted by remote.
+//
+// If BUF_TOKEN is set to a single token, it is used for every remote, and the default
+// remote is always included.
+func GetRemoteCredentials(ctx context.Context, container appext.NameContainer) ([]*RemoteCredentials, error) {
+	return getRemoteCredentials(ctx, container)
+}
+
+// GetRemoteCredentialsForRemote returns the credentials used for the remote.
+//
+// Returns nil if there are no credentials for the remote.
+func GetRemoteCredentialsForRemote(ctx context.Context, container appext.NameContainer, remote string) (*RemoteCredentials, error) {
+	remoteCredentialsList, err := getRemoteCredentials(ctx, container, remote)
+	if err != nil {
+		return nil, err
+	}
+	for _, remoteCredentials := range remoteCredentialsList {
+		if remoteCredentials.Remote == remote {
+			return remoteCredentials, nil
+		}
+	}
+	return nil, nil
+}
+
+// *** PRIVATE ***
+
+// getRemoteCredentials returns the credentials for every remote that has credentials
+// configured, and for the extra remotes if they have credentials.
+func getRemoteCredentials(ctx context.Context, container appext.NameContainer, extraRemotes ...string) ([]*RemoteCredentials, error) {
+	envRemotes, envAllRemotes, err := getEnvTokenRemotes(container)
+	if err != nil {
+		return nil, err
+	}
+	remoteToSSOCredentials, err := readSSOCredentialsFile(GetSSOCredentialsFilePath(container))
+	if err != nil {
+		return nil, err
+	}
+	credentialStore, err := newCredentialStore(container)
+	if err != nil {
+		return nil, err
+	}
+	keyringNames, err := credentialStore.getKeyringNames()
+	if err != nil {
+		return nil, err
+	}
+	netrcMachines, err := netrc.GetMachines(container)
+	if err != nil {
+		return nil, err
+	}
+	remotes := slices.Concat(extraRemotes, envRemotes, keyringNames)
+	for remote := range remoteToSSOCredentials {
+		remotes = append(remotes, remote)
+	}
+	for _, netrcMachine := range netrcMachines {
+		// Skip the default machine and the legacy entries for the Go module proxy.
+		if netrcMachine.Name() != "" && !strings.HasPrefix(netrcMachine.Name(), "go.") {
+			remotes = append(remotes, netrcMachine.Name())
+		}
+	}
+	if envAllRemotes {
+		remotes = append(remotes, GetDefaultRemote(container))
+	}
+	slices.Sort(remotes)
+	remotes = slices.Compact(remotes)
+	var remoteCredentialsList []*RemoteCredentials
+	for _, remote := range remotes {
+		remoteCredentials := &RemoteCredentials{
+			Remote: remote,
+		}
+		if ssoCredentials, ok := remoteToSSOCredentials[remote]; ok {
+			remoteCredentials.Source = CredentialsSourceSSO
+			remoteCredentials.Expiry = ssoCredentials.Expiry
+		}
+		if envAllRemotes || slices.Contains(envRemotes, remote) {
+			remoteCredentials.Source = CredentialsSourceEnv
+			remoteCredentials.Expiry = time.Time{}
+		}
+		if remoteCredentials.Source == "" {
+			machine, err := credentialStore.getMachineForName(ctx, remote)
+			if err != nil {
+				return nil, err
+			}
+			if machine == nil {
+				continue
+			}
+			remoteCredentials.Source = CredentialsSourceNetrc
+			if slices.Contains(keyringNames, remote) {
+				remoteCredentials.Source = CredentialsSourceKeychain
+			}
+			remoteCredentials.Login = machine.Login()
+		}
+		remoteCredentialsList = append(remoteCredentialsList, remoteCredentials)
+	}
+	return remoteCredentialsList, nil
+}
+
+type credentialStore struct {
+	envContainer app.EnvContainer
+	// indexFilePath is the path to the file that lists the remotes with credentials in
+	// the OS keychain.
+	indexFilePath string
+	// keyring is nil if the OS keychain is not used.
+	keyring keyring.Keyring
+	// required is true if the OS keychain is always read, and errors from the keyring
+	// should not fall back to the .netrc file.
+	required bool
+}
+
+func newCredentialStore(container appext.NameContainer) (*credentialStore, error) {
+	credentialStore := &credentialStore{
+		envContainer:  container,
+		indexFilePath: filepath.Join(container.ConfigDirPath(), keyringIndexFileName),
+	}
+	switch value := container.Env(credentialStoreEnvKey); value {
+	case credentialStoreNetrc:
+		return credentialStore, nil
+	case credentialStoreAuto, credentialStoreKeychain:
+		osKeyring, err := keyring.NewOSKeyring(container, command.NewRunner())
+		if err != nil {
+			if value == credentialStoreKeychain {
+				return nil, fmt.Errorf("%s=%s: %w", credentialStoreEnvKey, credentialStoreKeychain, err)
+			}
+			// Fall back to the .netrc file.
+			return credentialStore, nil
+		}
+		credentialStore.keyring = osKeyring
+		credentialStore.required = value == credentialStoreKeychain
+		return credentialStore, nil
+	default:
+		return nil, fmt.Errorf(
+			"invalid value for %s: %q, must be one of %q, %q",
+			credentialStoreEnvKey,
+			value,
+			credentialStoreKeychain,
+			credentialStoreNetrc,
+		)
+	}
+}
+
+func (c *credentialStore) getMachineForName(ctx context.Context, name string) (netrc.Machine, error) {
+	if c.keyring != nil {
+		// Only read the OS keychain for remotes that buf registry login saved there, so
+		// that commands never wait on a keychain that was not used.
+		keyringNames, err := c.getKeyringNames()
+		if err != nil && c.required {
+			return nil, err
+		}
+		if c.required || slices.Contains(keyringNames, name) {
+			machine, err := c.getMachineForNameFromKeyring(ctx, name)
+			switch {
+			case err == nil:
+				return machine, nil
+			case c.required && !errors.Is(err, fs.ErrNotExist):
+				return nil, err
+			}
+			// Fall back to the .netrc file.
+		}
+	}
+	return netrc.GetMachineForName(c.envContainer, name)
+}
+
+func (c *credentialStore) getMachineForNameFromKeyring(ctx context.Context, name string) (netrc.Machine, error) {
+	ctx, cancel := context.WithTimeout(ctx, keyringTimeout)
+	defer cancel()
+	secret, err := c.keyring.Get(ctx, keyringService, name)
+	if err != nil {
+		return nil, err
+	}
+	return newMachineForKeyringSecret(name, secret)
+}
+
+func (c *credentialStore) putMachine(ctx context.Context, machine netrc.Machine) (string, error) {
+	if c.keyring != nil {
+		secret, err := json.Marshal(
+			&keyringSecret{
+				Login:    machine.Login(),
+				Password: machine.Password(),
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+		err = c.keyringSet(ctx, machine.Name(), string(secret))
+		if err == nil {
+			if err := c.updateKeyringIndex(machine.Name(), true); err != nil {
+				return "", err
+			}
+			// Remove any stale credentials so that the .netrc file does not shadow the keychain
+			// for tools that only read the .netrc file.
+			if _, err := netrc.DeleteMachineForName(c.envContainer, machine.Name()); err != nil {
+				return "", err
+			}
+			return "the OS keychain", nil
+		}
+		if c.required {
+			return "", err
+		}
+	}
+	if err := netrc.PutMachines(c.envContainer, machine); err != nil {
+		return "", err
+	}
+	return netrc.GetFilePath(c.envContainer)
+}
+
+func (c *credentialStore) deleteMachineForName(ctx context.Context, name string) (bool, error) {
+	var keyringModified bool
+	if c.keyring != nil {
+		err := c.keyringDelete(ctx, name)
+		switch {
+		case err == nil:
+			keyringModified = true
+		case c.required && !errors.Is(err, fs.ErrNotExist):
+			return false, err
+		}
+		// The credentials are gone from the OS keychain, or were never there, or the
+		// keychain could not be read, in which case the .netrc file is used from now on.
+		if err := c.updateKeyringIndex(name, false); err != nil {
+			return false, err
+		}
+	}
+	netrcModified, err := netrc.DeleteMachineForName(c.envContainer, name)
+	if err != nil {
+		return false, err
+	}
+	return keyringModified || netrcModified, nil
+}
+
+func (c *credentialStore) keyringSet(ctx context.Context, name string, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, keyringTimeout)
+	defer cancel()
+	return c.keyring.Set(ctx, keyringService, name, secret)
+}
+
+func (c *credentialStore) keyringDelete(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, keyringTimeout)
+	defer cancel()
+	return c.keyring.Delete(ctx, keyringService, name)
+}
+
+// getKeyringNames returns the names of all remotes with credentials in the OS keychain.
+func (c *credentialStore) getKeyringNames() ([]string, error) {
+	if c.keyring == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.indexFilePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", c.indexFilePath, err)
+	}
+	return names, nil
+}
+
+// updateKeyringIndex adds or removes the name from the index of remotes with
+// credentials in the OS keychain.
+func (c *credentialStore) updateKeyringIndex(name string, add bool) error {
+	names, err := c.getKeyringNames()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(names, name) == add {
+		return nil
+	}
+	names = slices.DeleteFunc(names, func(existingName string) bool {
+		return existingName == name
+	})
+	if add {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		if err := os.Remove(c.indexFilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	slices.Sort(names)
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.indexFilePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.indexFilePath, data, 0600)
+}
+
+// keyringSecret is the secret stored in the OS keychain for a remote.
+type keyringSecret struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func newMachineForKeyringSecret(name string, secret string) (netrc.Machine, error) {
+	var keyringSecret keyringSecret
+	if err := json.Unmarshal([]byte(secret), &keyringSecret); err != nil {
+		return nil, fmt.Errorf("invalid credentials for %s in the OS keychain: %w", name, err)
+	}
+	return netrc.NewMachine(name, keyringSecret.Login, keyringSecret.Password), nil
+}
+
+// newCachedGetMachineForName returns a function for bufconnect.NewNetrcTokenProvider
+// that only looks up the credentials for each name once.
+//
+// Looking up credentials in the OS keychain may run a separate process, and token
+// providers look up credentials for every request.
+func newCachedGetMachineForName(container appext.NameContainer) func(app.EnvContainer, string) (netrc.Machine, error) {
+	var lock sync.Mutex
+	nameToMachine := make(map[string]netrc.Machine)
+	return func(_ app.EnvContainer, name string) (netrc.Machine, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if machine, ok := nameToMachine[name]; ok {
+			return machine, nil
+		}
+		// Token providers do not have the context of the request. Operations on the OS
+		// keychain are bounded by keyringTimeout.
+		machine, err := GetMachineForName(context.Background(), container, name)
+		if err != nil {
+			return nil, err
+		}
+		nameToMachine[name] = machine
+		return machine, nil
+	}
+}
+
+// getEnvTokenRemotes returns the remotes that BUF_TOKEN has a token for.
+//
+// Returns true if BUF_TOKEN has a single token that is used for all remotes.
+func getEnvTokenRemotes(envContainer app.EnvContainer) ([]string, bool, error) {
+	value := envContainer.Env(bufconnect.TokenEnvKey)
+	if value == "" {
+		return nil, false, nil
+	}
+	// Validate the value the same way that it is parsed for requests.
+	if _, err := bufconnect.NewTokenProviderFromContainer(envContainer); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %w", bufconnect.TokenEnvKey, err)
+	}
+	if !strings.Contains(value, "@") {
+		return nil, true, nil
+	}
+	var remotes []string
+	for _, token := range strings.Split(value, ",") {
+		if _, remote, ok := strings.Cut(token, "@"); ok {
+			remotes = append(remotes, remote)
+		}
+	}
+	return remotes, false, nil
+}
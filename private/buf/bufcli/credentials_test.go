@@ -0,0 +1,270 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialStoreKeyring(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	envContainer := app.NewEnvContainer(map[string]string{"HOME": t.TempDir()})
+	require.NoError(t, netrc.PutMachines(envContainer, netrc.NewMachine("buf.build", "old", "oldtoken")))
+	testKeyring := newTestKeyring()
+	credentialStore := &credentialStore{
+		envContainer:  envContainer,
+		indexFilePath: filepath.Join(t.TempDir(), keyringIndexFileName),
+		keyring:       testKeyring,
+	}
+
+	// The OS keychain is not read for remotes that were not saved there.
+	machine, err := credentialStore.getMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "oldtoken", machine.Password())
+	assert.Zero(t, testKeyring.numGets)
+
+	location, err := credentialStore.putMachine(ctx, netrc.NewMachine("buf.build", "user", "token"))
+	require.NoError(t, err)
+	assert.Equal(t, "the OS keychain", location)
+	// The stale .netrc entry is removed.
+	machine, err = netrc.GetMachineForName(envContainer, "buf.build")
+	require.NoError(t, err)
+	assert.Nil(t, machine)
+	machine, err = credentialStore.getMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "user", machine.Login())
+	assert.Equal(t, "token", machine.Password())
+	assert.Equal(t, 1, testKeyring.numGets)
+	keyringNames, err := credentialStore.getKeyringNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"buf.build"}, keyringNames)
+
+	deleted, err := credentialStore.deleteMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	keyringNames, err = credentialStore.getKeyringNames()
+	require.NoError(t, err)
+	assert.Empty(t, keyringNames)
+	assert.NoFileExists(t, credentialStore.indexFilePath)
+	assert.Empty(t, testKeyring.secrets)
+	deleted, err = credentialStore.deleteMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	machine, err = credentialStore.getMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.Nil(t, machine)
+}
+
+func TestCredentialStoreKeyringFallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	envContainer := app.NewEnvContainer(map[string]string{"HOME": t.TempDir()})
+	testKeyring := newTestKeyring()
+	credentialStore := &credentialStore{
+		envContainer:  envContainer,
+		indexFilePath: filepath.Join(t.TempDir(), keyringIndexFileName),
+		keyring:       testKeyring,
+	}
+	_, err := credentialStore.putMachine(ctx, netrc.NewMachine("buf.example.com", "user", "keyringtoken"))
+	require.NoError(t, err)
+	testKeyring.err = errors.New("keychain is locked")
+
+	location, err := credentialStore.putMachine(ctx, netrc.NewMachine("buf.build", "user", "token"))
+	require.NoError(t, err)
+	netrcFilePath, err := netrc.GetFilePath(envContainer)
+	require.NoError(t, err)
+	assert.Equal(t, netrcFilePath, location)
+	machine, err := credentialStore.getMachineForName(ctx, "buf.build")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "token", machine.Password())
+	// Credentials that were saved to the OS keychain fall back to the .netrc file if the
+	// keychain cannot be read.
+	require.NoError(t, netrc.PutMachines(envContainer, netrc.NewMachine("buf.example.com", "user", "netrctoken")))
+	machine, err = credentialStore.getMachineForName(ctx, "buf.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "netrctoken", machine.Password())
+
+	credentialStore.required = true
+	_, err = credentialStore.putMachine(ctx, netrc.NewMachine("buf.build", "user", "token"))
+	assert.ErrorIs(t, err, testKeyring.err)
+	_, err = credentialStore.getMachineForName(ctx, "buf.build")
+	assert.ErrorIs(t, err, testKeyring.err)
+}
+
+func TestNewCredentialStore(t *testing.T) {
+	t.Parallel()
+	credentialStore, err := newCredentialStore(newTestNameContainer(t, map[string]string{credentialStoreEnvKey: credentialStoreNetrc}))
+	require.NoError(t, err)
+	assert.Nil(t, credentialStore.keyring)
+	_, err = newCredentialStore(newTestNameContainer(t, map[string]string{credentialStoreEnvKey: "file"}))
+	assert.Error(t, err)
+}
+
+type testKeyring struct {
+	secrets map[string]string
+	err     error
+	numGets int
+}
+
+func newTestKeyring() *testKeyring {
+	return &testKeyring{
+		secrets: make(map[string]string),
+	}
+}
+
+func (k *testKeyring) Get(ctx context.Context, service string, account string) (string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return "", errors.New("no deadline")
+	}
+	k.numGets++
+	if k.err != nil {
+		return "", k.err
+	}
+	secret, ok := k.secrets[service+":"+account]
+	if !ok {
+		return "", fs.ErrNotExist
+	}
+	return secret, nil
+}
+
+func (k *testKeyring) Set(ctx context.Context, service string, account string, secret string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+	if k.err != nil {
+		return k.err
+	}
+	k.secrets[service+":"+account] = secret
+	return nil
+}
+
+func (k *testKeyring) Delete(ctx context.Context, service string, account string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+	if k.err != nil {
+		return k.err
+	}
+	if _, ok := k.secrets[service+":"+account]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(k.secrets, service+":"+account)
+	return nil
+}
+
+func TestGetRemoteCredentials(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	homeDirPath := t.TempDir()
+	container := newTestNameContainer(
+		t,
+		map[string]string{
+			"HOME":                 homeDirPath,
+			credentialStoreEnvKey:  credentialStoreNetrc,
+			bufconnect.TokenEnvKey: "envtoken@a.example.com",
+			"BUF_DEFAULT_REMOTE":   "d.example.com",
+			"XDG_CONFIG_HOME":      filepath.Join(homeDirPath, "config"),
+		},
+	)
+	require.NoError(
+		t,
+		netrc.PutMachines(
+			container,
+			netrc.NewMachine("b.example.com", "user", "token"),
+			netrc.NewMachine("go.b.example.com", "user", "token"),
+			netrc.NewMachine("c.example.com", "user", "token"),
+		),
+	)
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(
+		t,
+		PutSSOCredentials(
+			ctx,
+			container,
+			"c.example.com",
+			&SSOCredentials{AccessToken: "access", Expiry: expiry},
+		),
+	)
+	remoteCredentialsList, err := GetRemoteCredentials(ctx, container)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*RemoteCredentials{
+			{Remote: "a.example.com", Source: CredentialsSourceEnv},
+			{Remote: "b.example.com", Source: CredentialsSourceNetrc, Login: "user"},
+			{Remote: "c.example.com", Source: CredentialsSourceSSO, Expiry: expiry},
+		},
+		remoteCredentialsList,
+	)
+	remoteCredentials, err := GetRemoteCredentialsForRemote(ctx, container, "d.example.com")
+	require.NoError(t, err)
+	assert.Nil(t, remoteCredentials)
+}
+
+func TestGetTokenForRemote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	homeDirPath := t.TempDir()
+	env := map[string]string{
+		"HOME":                homeDirPath,
+		credentialStoreEnvKey: credentialStoreNetrc,
+		"XDG_CONFIG_HOME":     filepath.Join(homeDirPath, "config"),
+	}
+	container := appext.NewContainer(newTestNameContainer(t, env), slogtestext.NewLogger(t))
+	require.NoError(t, netrc.PutMachines(container, netrc.NewMachine("a.example.com", "user", "netrctoken")))
+	require.NoError(
+		t,
+		PutSSOCredentials(
+			ctx,
+			container,
+			"b.example.com",
+			&SSOCredentials{AccessToken: "ssotoken", Expiry: time.Now().Add(time.Hour)},
+		),
+	)
+
+	token, err := GetTokenForRemote(ctx, container, "a.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "netrctoken", token)
+	token, err = GetTokenForRemote(ctx, container, "b.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ssotoken", token)
+	_, err = GetTokenForRemote(ctx, container, "c.example.com")
+	assert.EqualError(t, err, "no Buf API token for c.example.com, run buf registry login c.example.com or set BUF_TOKEN")
+
+	// BUF_TOKEN takes precedence over stored credentials.
+	env[bufconnect.TokenEnvKey] = "envtoken@a.example.com"
+	container = appext.NewContainer(newTestNameContainer(t, env), slogtestext.NewLogger(t))
+	token, err = GetTokenForRemote(ctx, container, "a.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "envtoken", token)
+}
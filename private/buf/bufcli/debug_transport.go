@@ -0,0 +1,76 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
+)
+
+const debugTransportFlagName = "debug-transport"
+
+// BindDebugTransport binds the global --debug-transport flag.
+//
+// The flag is applied to the Container with NewDebugTransportInterceptor.
+func BindDebugTransport(flagSet *pflag.FlagSet, debugTransport *bool) {
+	flagSet.BoolVar(
+		debugTransport,
+		debugTransportFlagName,
+		false,
+		fmt.Sprintf(
+			`Log every HTTP request and response to the BSR, remote caches, and module proxies to stderr, with credentials redacted. Can also be set with %s=1`,
+			debugTransportEnvKey,
+		),
+	)
+}
+
+// NewDebugTransportInterceptor returns a new Interceptor that sets debugTransportEnvKey
+// on the Container if debugTransport is set, so that HTTP clients created from the
+// Container log every request and response.
+func NewDebugTransportInterceptor(debugTransport *bool) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if !*debugTransport {
+				return next(ctx, container)
+			}
+			debugTransportContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					debugTransportEnvKey: "1",
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, debugTransportContainer)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+// isDebugTransport returns true if HTTP requests should be logged, either by
+// --debug-transport or debugTransportEnvKey.
+func isDebugTransport(container app.EnvContainer) (bool, error) {
+	debugTransport, err := app.EnvBool(container, debugTransportEnvKey, false)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", debugTransportEnvKey, err)
+	}
+	return debugTransport, nil
+}
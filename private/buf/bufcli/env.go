@@ -16,7 +16,6 @@ package bufcli
 
 import (
 	"context"
-	"net/http"
 
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/git"
@@ -29,6 +28,53 @@ const (
 	inputSSHKeyFileEnvKey         = "BUF_INPUT_SSH_KEY_FILE"
 	inputSSHKnownHostsFilesEnvKey = "BUF_INPUT_SSH_KNOWN_HOSTS_FILES"
 
+	remoteCacheURLEnvKey   = "BUF_REMOTE_CACHE_URL"
+	remoteCacheWriteEnvKey = "BUF_REMOTE_CACHE_WRITE"
+	remoteCacheTokenEnvKey = "BUF_REMOTE_CACHE_TOKEN"
+
+	// These are the standard variables read by the AWS and Google Cloud SDKs and CLIs.
+	awsAccessKeyIDEnvKey         = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKeyEnvKey     = "AWS_SECRET_ACCESS_KEY"
+	awsSessionTokenEnvKey        = "AWS_SESSION_TOKEN"
+	awsRegionEnvKey              = "AWS_REGION"
+	awsDefaultRegionEnvKey       = "AWS_DEFAULT_REGION"
+	awsEndpointURLS3EnvKey       = "AWS_ENDPOINT_URL_S3"
+	awsEndpointURLEnvKey         = "AWS_ENDPOINT_URL"
+	googleOAuthAccessTokenEnvKey = "GOOGLE_OAUTH_ACCESS_TOKEN"
+	storageEmulatorHostEnvKey    = "STORAGE_EMULATOR_HOST"
+
+	moduleProxyEnvKey      = "BUF_MODULE_PROXY"
+	moduleProxyTokenEnvKey = "BUF_MODULE_PROXY_TOKEN"
+
+	offlineEnvKey = "BUF_OFFLINE"
+
+	noSandboxEnvKey = "BUF_NO_SANDBOX"
+
+	pluginConcurrencyEnvKey = "BUF_PLUGIN_CONCURRENCY"
+	pluginOutputLimitEnvKey = "BUF_PLUGIN_OUTPUT_LIMIT"
+
+	debugTransportEnvKey = "BUF_DEBUG_TRANSPORT"
+
+	colorEnvKey = "BUF_COLOR"
+
+	externalCommandTokenEnvKey = "BUF_EXTERNAL_COMMAND_TOKEN"
+
+	profileEnvKey       = "BUF_PROFILE"
+	defaultRemoteEnvKey = "BUF_DEFAULT_REMOTE"
+	// These are read by appext.NameContainer.
+	configDirEnvKey = "BUF_CONFIG_DIR"
+	cacheDirEnvKey  = "BUF_CACHE_DIR"
+	dataDirEnvKey   = "BUF_DATA_DIR"
+
+	cacheCompressionEnvKey = "BUF_CACHE_COMPRESSION"
+
+	dependencyBundleEnvKey = "BUF_DEPENDENCY_BUNDLE"
+
+	digestVerificationEnvKey = "BUF_DIGEST_VERIFICATION"
+
+	downloadConcurrencyEnvKey = "BUF_DOWNLOAD_CONCURRENCY"
+	rpcMaxAttemptsEnvKey      = "BUF_RPC_MAX_ATTEMPTS"
+
 	alphaSuppressWarningsEnvKey = "BUF_ALPHA_SUPPRESS_WARNINGS"
 	betaSuppressWarningsEnvKey  = "BUF_BETA_SUPPRESS_WARNINGS"
 
@@ -47,9 +93,6 @@ const (
 )
 
 var (
-	// defaultHTTPClient is the client we use for HTTP requests.
-	// Timeout should be set through context for calls to ImageConfigReader, not through http.Client
-	defaultHTTPClient = &http.Client{}
 	// defaultHTTPAuthenticator is the default authenticator
 	// used for HTTP requests.
 	defaultHTTPAuthenticator = httpauth.NewMultiAuthenticator(
@@ -0,0 +1,159 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+const (
+	// externalCommandsDirName is the name of the directory within the config directory
+	// that is searched for external commands before PATH.
+	externalCommandsDirName = "commands"
+
+	// These are set for external commands, in addition to the config, cache, and data
+	// directories, the default remote, and the token if enabled.
+	externalBinaryEnvKey  = "BUF_BINARY"
+	externalVersionEnvKey = "BUF_VERSION"
+)
+
+// NewExternalFunc returns a new appcmd.ExternalFunc that runs external commands.
+//
+// The external command for "buf foo" is an executable named "buf-foo", in the commands
+// directory within the config directory, or on PATH. The external command is run with
+// the environment of the buf invocation, plus the config, cache, and data directories,
+// and the default remote, so that it can share the module cache.
+//
+// The token for the default remote is only passed if externalCommandTokenEnvKey is set
+// to true, as any executable on PATH can be run as an external command. Otherwise, the
+// token environment variable is removed from the environment of the external command.
+//
+// The external command name must come before any flags, so --profile is passed to the
+// external command as an argument, and only the profile selected with profileEnvKey is
+// applied to the environment.
+func NewExternalFunc(appName string) appcmd.ExternalFunc {
+	return func(ctx context.Context, container app.Container, name string, args []string) (bool, error) {
+		nameContainer, err := appext.NewNameContainer(container, appName)
+		if err != nil {
+			return false, err
+		}
+		filePath, err := findExternalCommand(nameContainer, appName+"-"+name)
+		if err != nil || filePath == "" {
+			return false, err
+		}
+		env, err := getExternalCommandEnv(ctx, nameContainer, appName)
+		if err != nil {
+			return true, err
+		}
+		if err := command.NewRunner().Run(
+			ctx,
+			filePath,
+			command.RunWithArgs(args...),
+			command.RunWithEnv(env),
+			command.RunWithStdin(container.Stdin()),
+			command.RunWithStdout(container.Stdout()),
+			command.RunWithStderr(container.Stderr()),
+		); err != nil {
+			execExitError := &exec.ExitError{}
+			if errors.As(err, &execExitError) && execExitError.ExitCode() > 0 {
+				// The external command is responsible for printing its own errors.
+				return true, app.NewError(execExitError.ExitCode(), "")
+			}
+			return true, err
+		}
+		return true, nil
+	}
+}
+
+// *** PRIVATE ***
+
+// findExternalCommand returns the path to the executable with the given file name.
+//
+// Returns empty string if there is no such executable.
+func findExternalCommand(container appext.NameContainer, fileName string) (string, error) {
+	dirPaths := []string{filepath.Join(container.ConfigDirPath(), externalCommandsDirName)}
+	dirPaths = append(dirPaths, filepath.SplitList(container.Env("PATH"))...)
+	for _, dirPath := range dirPaths {
+		if dirPath == "" {
+			continue
+		}
+		// exec.LookPath checks that the file is executable, and adds PATHEXT
+		// extensions on Windows.
+		filePath, err := exec.LookPath(filepath.Join(dirPath, fileName))
+		if err != nil {
+			continue
+		}
+		return filepath.Abs(filePath)
+	}
+	return "", nil
+}
+
+func getExternalCommandEnv(ctx context.Context, container appext.NameContainer, appName string) (map[string]string, error) {
+	env := app.EnvironMap(container)
+	if profileName := container.Env(profileEnvKey); profileName != "" {
+		overrides, err := getProfileEnvOverrides(container, profileName)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range overrides {
+			if value == "" {
+				delete(env, key)
+			} else {
+				env[key] = value
+			}
+		}
+	}
+	// Resolve the directories and the default remote with the profile applied.
+	envContainer, err := appext.NewNameContainer(app.NewContainer(env, nil, nil, nil), appName)
+	if err != nil {
+		return nil, err
+	}
+	env[configDirEnvKey] = envContainer.ConfigDirPath()
+	env[cacheDirEnvKey] = envContainer.CacheDirPath()
+	env[dataDirEnvKey] = envContainer.DataDirPath()
+	defaultRemote := GetDefaultRemote(envContainer)
+	env[defaultRemoteEnvKey] = defaultRemote
+	passToken, err := app.EnvBool(envContainer, externalCommandTokenEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", externalCommandTokenEnvKey, err)
+	}
+	if !passToken {
+		delete(env, bufconnect.TokenEnvKey)
+	} else if env[bufconnect.TokenEnvKey] == "" {
+		machine, err := GetMachineForName(ctx, envContainer, defaultRemote)
+		if err != nil {
+			return nil, err
+		}
+		if machine != nil && machine.Password() != "" {
+			env[bufconnect.TokenEnvKey] = machine.Password() + "@" + defaultRemote
+		}
+	}
+	if binaryPath, err := os.Executable(); err == nil {
+		env[externalBinaryEnvKey] = binaryPath
+	}
+	env[externalVersionEnvKey] = Version
+	return env, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalFunc(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("external command test uses a shell script")
+	}
+	configDirPath := t.TempDir()
+	pathDirPath := t.TempDir()
+	homeDirPath := t.TempDir()
+	writeExecutable(
+		t,
+		filepath.Join(pathDirPath, "buf-foo"),
+		`#!/bin/sh
+echo "$@"
+echo "$BUF_CACHE_DIR"
+echo "$BUF_DEFAULT_REMOTE"
+exit 3
+`,
+	)
+	// The commands directory takes precedence over PATH.
+	writeExecutable(
+		t,
+		filepath.Join(configDirPath, externalCommandsDirName, "buf-bar"),
+		"#!/bin/sh\necho commands\n",
+	)
+	writeExecutable(t, filepath.Join(pathDirPath, "buf-bar"), "#!/bin/sh\necho path\n")
+	writeExecutable(t, filepath.Join(pathDirPath, "buf-token"), "#!/bin/sh\necho \"${BUF_TOKEN:-none}\"\n")
+	externalFunc := NewExternalFunc("buf")
+	runWithEnv := func(env map[string]string, name string, args ...string) (string, bool, error) {
+		stdout := bytes.NewBuffer(nil)
+		containerEnv := map[string]string{
+			"HOME":              homeDirPath,
+			"PATH":              pathDirPath,
+			configDirEnvKey:     configDirPath,
+			cacheDirEnvKey:      "/cache",
+			defaultRemoteEnvKey: "buf.example.com",
+		}
+		for key, value := range env {
+			containerEnv[key] = value
+		}
+		container := app.NewContainer(
+			containerEnv,
+			nil,
+			stdout,
+			nil,
+			"buf",
+		)
+		ok, err := externalFunc(context.Background(), container, name, args)
+		return stdout.String(), ok, err
+	}
+	run := func(name string, args ...string) (string, bool, error) {
+		return runWithEnv(nil, name, args...)
+	}
+
+	stdout, ok, err := run("foo", "one", "--two")
+	assert.True(t, ok)
+	assert.Equal(t, 3, app.GetExitCode(err))
+	assert.Equal(t, "one --two\n/cache\nbuf.example.com\n", stdout)
+
+	stdout, ok, err = run("bar")
+	assert.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "commands", strings.TrimSpace(stdout))
+
+	_, ok, err = run("baz")
+	assert.False(t, ok)
+	require.NoError(t, err)
+
+	// The token is only passed if explicitly enabled.
+	stdout, ok, err = runWithEnv(map[string]string{"BUF_TOKEN": "secret"}, "token")
+	assert.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "none", strings.TrimSpace(stdout))
+	stdout, ok, err = runWithEnv(
+		map[string]string{
+			"BUF_TOKEN":                "secret",
+			externalCommandTokenEnvKey: "true",
+		},
+		"token",
+	)
+	assert.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", strings.TrimSpace(stdout))
+}
+
+func writeExecutable(t *testing.T, filePath string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0755))
+}
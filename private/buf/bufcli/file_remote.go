@@ -0,0 +1,50 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"sort"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulefs"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+)
+
+// newFileRemoteProviders returns the Providers for the file remotes in the buf configuration.
+//
+// Modules in the registries of file remotes are read from disk instead of the BSR API,
+// including when offline.
+func newFileRemoteProviders(container appext.Container) ([]bufmodulefs.Provider, error) {
+	config, err := newConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	remotes := make([]string, 0, len(config.RemoteToDirPath))
+	for remote := range config.RemoteToDirPath {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	// No symlinks.
+	storageosProvider := storageos.NewProvider()
+	providers := make([]bufmodulefs.Provider, 0, len(remotes))
+	for _, remote := range remotes {
+		bucket, err := storageosProvider.NewReadWriteBucket(config.RemoteToDirPath[remote])
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, bufmodulefs.NewProvider(container.Logger(), remote, bucket))
+	}
+	return providers, nil
+}
@@ -126,7 +126,7 @@ func BindExcludePaths(
 		excludePathsAddr,
 		excludePathsFlagName,
 		nil,
-		`Exclude specific files or directories, e.g. "proto/a/a.proto", "proto/a"
+		`Exclude specific files or directories, e.g. "proto/a/a.proto", "proto/a", or globs, e.g. "**/internal"
 If specified multiple times, the union is taken`,
 	)
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulefs"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
@@ -27,9 +28,18 @@ func NewGraphProvider(container appext.Container) (bufmodule.GraphProvider, erro
 	if err != nil {
 		return nil, err
 	}
-	return newGraphProvider(container, bufapi.NewClientProvider(clientConfig)), nil
+	fileRemoteProviders, err := newFileRemoteProviders(container)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodulefs.NewGraphProvider(
+		newGraphProvider(container, bufapi.NewClientProvider(clientConfig)),
+		fileRemoteProviders,
+	), nil
 }
 
+// newGraphProvider returns a new GraphProvider for the BSR API only.
+
 func newGraphProvider(
 	container appext.Container,
 	clientProvider bufapi.ClientProvider,
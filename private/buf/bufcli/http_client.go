@@ -0,0 +1,55 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/buf/bufapp"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
+)
+
+// NewHTTPClient returns a new HTTP client with the TLS and proxy configuration of the container.
+//
+// Proxies are read from the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment variables of
+// the container, and may be overridden per host in the proxies section of the configuration
+// file. All clients for the BSR, remote caches, and module proxies use this configuration.
+func NewHTTPClient(container appext.Container) (*http.Client, error) {
+	config, err := newConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	return newHTTPClient(container, config)
+}
+
+// *** PRIVATE ***
+
+func newHTTPClient(container appext.Container, config *bufapp.Config) (*http.Client, error) {
+	options := []httpclient.ClientOption{
+		httpclient.ClientWithProxyFromEnvContainer(container),
+	}
+	for _, proxy := range config.Proxies {
+		options = append(options, httpclient.ClientWithHostProxy(proxy.HostPattern, proxy.URL))
+	}
+	debugTransport, err := isDebugTransport(container)
+	if err != nil {
+		return nil, err
+	}
+	if debugTransport {
+		options = append(options, httpclient.ClientWithDebugLogger(container.Logger()))
+	}
+	return httpclient.NewClient(config.TLS, options...), nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+)
+
+// GetLoginStatuses returns the login status for each of the RemoteCredentials.
+//
+// The credentials are checked against each remote. Credentials that cannot be checked
+// do not result in an error, instead the error is set on the returned LoginStatus.
+func GetLoginStatuses(
+	ctx context.Context,
+	container appext.Container,
+	remoteCredentialsList []*RemoteCredentials,
+) ([]*bufprint.LoginStatus, error) {
+	clientConfig, err := NewConnectClientConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	loginStatuses := make([]*bufprint.LoginStatus, 0, len(remoteCredentialsList))
+	for _, remoteCredentials := range remoteCredentialsList {
+		loginStatus := &bufprint.LoginStatus{
+			Remote: remoteCredentials.Remote,
+			Source: string(remoteCredentials.Source),
+		}
+		authnService := connectclient.Make(clientConfig, remoteCredentials.Remote, registryv1alpha1connect.NewAuthnServiceClient)
+		response, err := authnService.GetCurrentUser(ctx, connect.NewRequest(&registryv1alpha1.GetCurrentUserRequest{}))
+		switch {
+		case err != nil:
+			if connectErr := new(connect.Error); errors.As(err, &connectErr) && connectErr.Code() == connect.CodeUnauthenticated {
+				loginStatus.Error = "invalid or expired token"
+			} else {
+				loginStatus.Error = err.Error()
+			}
+		case response.Msg.User == nil:
+			loginStatus.Error = "no user found for token"
+		default:
+			loginStatus.Username = response.Msg.User.Username
+		}
+		loginStatuses = append(loginStatuses, loginStatus)
+	}
+	// Checking the credentials may have refreshed SSO credentials, so the expiry is
+	// read afterwards.
+	remoteToSSOCredentials, err := readSSOCredentialsFile(GetSSOCredentialsFilePath(container))
+	if err != nil {
+		return nil, err
+	}
+	for i, remoteCredentials := range remoteCredentialsList {
+		expiry := remoteCredentials.Expiry
+		if ssoCredentials, ok := remoteToSSOCredentials[remoteCredentials.Remote]; ok && remoteCredentials.Source == CredentialsSourceSSO {
+			expiry = ssoCredentials.Expiry
+		}
+		if !expiry.IsZero() {
+			loginStatuses[i].Expiry = &expiry
+		}
+	}
+	return loginStatuses, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+// *** PRIVATE ***
+
+// loggingModuleDataProviderObserver is a bufmodule.ModuleDataProviderObserver that logs
+// all operations at debug level, so that slow dependency resolution can be diagnosed
+// with --debug.
+type loggingModuleDataProviderObserver struct {
+	logger *slog.Logger
+}
+
+func newLoggingModuleDataProviderObserver(logger *slog.Logger) *loggingModuleDataProviderObserver {
+	return &loggingModuleDataProviderObserver{
+		logger: logger,
+	}
+}
+
+func (o *loggingModuleDataProviderObserver) CacheLookup(
+	ctx context.Context,
+	hitModuleKeys []bufmodule.ModuleKey,
+	missedModuleKeys []bufmodule.ModuleKey,
+	duration time.Duration,
+) {
+	o.logger.DebugContext(
+		ctx,
+		"module cache lookup",
+		slog.Int("hits", len(hitModuleKeys)),
+		slog.Int("misses", len(missedModuleKeys)),
+		slog.Any("missedModuleKeys", slicesext.Map(missedModuleKeys, bufmodule.ModuleKey.String)),
+		slog.Duration("duration", duration),
+	)
+}
+
+func (o *loggingModuleDataProviderObserver) DownloadStart(
+	ctx context.Context,
+	registry string,
+	commitIDs []uuid.UUID,
+) {
+	o.logger.DebugContext(
+		ctx,
+		"module download start",
+		slog.String("registry", registry),
+		slog.Any("commitIDs", slicesext.Map(commitIDs, uuidutil.ToDashless)),
+	)
+}
+
+func (o *loggingModuleDataProviderObserver) DownloadFinish(
+	ctx context.Context,
+	registry string,
+	commitIDs []uuid.UUID,
+	numBytes int64,
+	duration time.Duration,
+	err error,
+) {
+	attrs := []any{
+		slog.String("registry", registry),
+		slog.Int("numCommits", len(commitIDs)),
+		slog.Int64("bytes", numBytes),
+		slog.Duration("duration", duration),
+	}
+	if err != nil {
+		attrs = append(attrs, slogext.ErrorAttr(err))
+	}
+	o.logger.DebugContext(ctx, "module download finish", attrs...)
+}
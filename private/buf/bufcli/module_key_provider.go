@@ -18,6 +18,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulefs"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
@@ -27,10 +28,17 @@ func NewModuleKeyProvider(container appext.Container) (bufmodule.ModuleKeyProvid
 	if err != nil {
 		return nil, err
 	}
-	return bufmoduleapi.NewModuleKeyProvider(
-		container.Logger(),
-		bufapi.NewClientProvider(
-			clientConfig,
+	fileRemoteProviders, err := newFileRemoteProviders(container)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodulefs.NewModuleKeyProvider(
+		bufmoduleapi.NewModuleKeyProvider(
+			container.Logger(),
+			bufapi.NewClientProvider(
+				clientConfig,
+			),
 		),
+		fileRemoteProviders,
 	), nil
 }
@@ -0,0 +1,162 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagegcs"
+	"github.com/bufbuild/buf/private/pkg/storage/storages3"
+)
+
+const (
+	s3Scheme  = "s3"
+	gcsScheme = "gs"
+)
+
+// IsObjectStorageURL returns true if the value is an s3:// or gs:// URL.
+func IsObjectStorageURL(value string) bool {
+	return strings.HasPrefix(value, s3Scheme+"://") || strings.HasPrefix(value, gcsScheme+"://")
+}
+
+// NewObjectStorageBucket returns a new ReadWriteBucket for the s3:// or gs:// URL, scoped
+// to the path of the URL. Returns false if the value is not an object storage URL.
+//
+// S3 credentials, region, and endpoint are read from the standard AWS environment
+// variables. Cloud Storage requests are authenticated with the access token in
+// GOOGLE_OAUTH_ACCESS_TOKEN. The credential chains of the AWS and Google Cloud SDKs,
+// such as shared credentials files, profiles, and instance or workload identities, are
+// not used, and an error is returned if no credentials are set. Each object is uploaded
+// with a single request, without multipart or resumable uploads, so objects are limited
+// to 5 GiB.
+func NewObjectStorageBucket(container appext.Container, value string) (storage.ReadWriteBucket, bool, error) {
+	if !IsObjectStorageURL(value) {
+		return nil, false, nil
+	}
+	parsedURL, err := url.Parse(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid object storage URL %q: %w", value, err)
+	}
+	if parsedURL.Host == "" {
+		return nil, false, fmt.Errorf("invalid object storage URL %q: no bucket", value)
+	}
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, false, err
+	}
+	if offline {
+		return nil, false, fmt.Errorf("cannot access %s in offline mode", value)
+	}
+	httpClient, err := NewHTTPClient(container)
+	if err != nil {
+		return nil, false, err
+	}
+	prefix := strings.Trim(parsedURL.Path, "/")
+	var readWriteBucket storage.ReadWriteBucket
+	switch parsedURL.Scheme {
+	case s3Scheme:
+		readWriteBucket, err = newS3Bucket(container, httpClient, parsedURL.Host, prefix)
+	case gcsScheme:
+		readWriteBucket, err = newGCSBucket(container, httpClient, parsedURL.Host, prefix)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return readWriteBucket, true, nil
+}
+
+// *** PRIVATE ***
+
+func newS3Bucket(
+	container app.EnvContainer,
+	httpClient *http.Client,
+	bucketName string,
+	prefix string,
+) (storage.ReadWriteBucket, error) {
+	options := []storages3.ReadWriteBucketOption{
+		storages3.ReadWriteBucketWithPrefix(prefix),
+		storages3.ReadWriteBucketWithRegion(getFirstEnv(container, awsRegionEnvKey, awsDefaultRegionEnvKey)),
+		storages3.ReadWriteBucketWithEndpoint(getFirstEnv(container, awsEndpointURLS3EnvKey, awsEndpointURLEnvKey)),
+	}
+	accessKeyID := container.Env(awsAccessKeyIDEnvKey)
+	secretAccessKey := container.Env(awsSecretAccessKeyEnvKey)
+	switch {
+	case accessKeyID != "" && secretAccessKey != "":
+		options = append(
+			options,
+			storages3.ReadWriteBucketWithCredentials(
+				storages3.Credentials{
+					AccessKeyID:     accessKeyID,
+					SecretAccessKey: secretAccessKey,
+					SessionToken:    container.Env(awsSessionTokenEnvKey),
+				},
+			),
+		)
+	case accessKeyID != "" || secretAccessKey != "":
+		return nil, errors.New(awsAccessKeyIDEnvKey + " and " + awsSecretAccessKeyEnvKey + " must be set together")
+	default:
+		return nil, fmt.Errorf(
+			"no S3 credentials found for s3://%s: set %s and %s, shared credentials files and profiles are not supported",
+			bucketName,
+			awsAccessKeyIDEnvKey,
+			awsSecretAccessKeyEnvKey,
+		)
+	}
+	return storages3.NewReadWriteBucket(httpClient, bucketName, options...)
+}
+
+func newGCSBucket(
+	container app.EnvContainer,
+	httpClient *http.Client,
+	bucketName string,
+	prefix string,
+) (storage.ReadWriteBucket, error) {
+	token := container.Env(googleOAuthAccessTokenEnvKey)
+	options := []storagegcs.ReadWriteBucketOption{
+		storagegcs.ReadWriteBucketWithPrefix(prefix),
+		storagegcs.ReadWriteBucketWithToken(token),
+	}
+	if emulatorHost := container.Env(storageEmulatorHostEnvKey); emulatorHost != "" {
+		if !strings.Contains(emulatorHost, "://") {
+			emulatorHost = "http://" + emulatorHost
+		}
+		options = append(options, storagegcs.ReadWriteBucketWithEndpoint(emulatorHost))
+	} else if token == "" {
+		// Only the emulator can be used without credentials.
+		return nil, fmt.Errorf(
+			"no Cloud Storage credentials found for gs://%s: set %s, such as to the output of gcloud auth print-access-token, application default credentials are not supported",
+			bucketName,
+			googleOAuthAccessTokenEnvKey,
+		)
+	}
+	return storagegcs.NewReadWriteBucket(httpClient, bucketName, options...)
+}
+
+// getFirstEnv returns the value of the first of the environment variables that is set.
+func getFirstEnv(container app.EnvContainer, keys ...string) string {
+	for _, key := range keys {
+		if value := container.Env(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,222 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/spf13/pflag"
+)
+
+const offlineFlagName = "offline"
+
+// BindOffline binds the global --offline flag.
+//
+// The flag is applied to the Container with NewOfflineInterceptor.
+func BindOffline(flagSet *pflag.FlagSet, offline *bool) {
+	flagSet.BoolVar(
+		offline,
+		offlineFlagName,
+		false,
+		fmt.Sprintf(
+			`Forbid all network access. Modules are only read from the cache. Can also be set with %s=1`,
+			offlineEnvKey,
+		),
+	)
+}
+
+// NewOfflineInterceptor returns a new Interceptor that sets offlineEnvKey on the
+// Container if offline is set, so that IsOffline reflects the --offline flag.
+func NewOfflineInterceptor(offline *bool) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if !*offline {
+				return next(ctx, container)
+			}
+			offlineContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					offlineEnvKey: "1",
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, offlineContainer)
+		}
+	}
+}
+
+// IsOffline returns true if network access is forbidden, either by --offline or offlineEnvKey.
+func IsOffline(container app.EnvContainer) (bool, error) {
+	offline, err := app.EnvBool(container, offlineEnvKey, false)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", offlineEnvKey, err)
+	}
+	return offline, nil
+}
+
+// *** PRIVATE ***
+
+// errOffline is returned for any network access attempted while offline.
+var errOffline = fmt.Errorf("network access is disabled by --%s or %s", offlineFlagName, offlineEnvKey)
+
+func newContainerWithEnvOverrides(
+	container appext.Container,
+	overrides map[string]string,
+) (appext.Container, error) {
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			app.EnvironMap(app.NewEnvContainerWithOverrides(container, overrides)),
+			container.Stdin(),
+			container.Stdout(),
+			container.Stderr(),
+			app.Args(container)...,
+		),
+		container.AppName(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return appext.NewContainer(nameContainer, container.Logger()), nil
+}
+
+// offlineInterceptor fails every RPC before any network access occurs.
+//
+// This is the backstop for offline mode, so that any code path that reaches the
+// network fails fast instead of hanging on an unreachable remote.
+type offlineInterceptor struct{}
+
+func (offlineInterceptor) WrapUnary(connect.UnaryFunc) connect.UnaryFunc {
+	return func(_ context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return nil, newOfflineConnectError(request.Spec().Procedure)
+	}
+}
+
+func (offlineInterceptor) WrapStreamingClient(connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(_ context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &offlineStreamingClientConn{
+			spec: spec,
+			err:  newOfflineConnectError(spec.Procedure),
+		}
+	}
+}
+
+func (offlineInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func newOfflineConnectError(procedure string) error {
+	return connect.NewError(connect.CodeUnavailable, fmt.Errorf("%w: cannot call %s", errOffline, procedure))
+}
+
+type offlineStreamingClientConn struct {
+	spec connect.Spec
+	err  error
+}
+
+func (c *offlineStreamingClientConn) Spec() connect.Spec {
+	return c.spec
+}
+
+func (*offlineStreamingClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (c *offlineStreamingClientConn) Send(any) error {
+	return c.err
+}
+
+func (*offlineStreamingClientConn) RequestHeader() http.Header {
+	return http.Header{}
+}
+
+func (*offlineStreamingClientConn) CloseRequest() error {
+	return nil
+}
+
+func (c *offlineStreamingClientConn) Receive(any) error {
+	return c.err
+}
+
+func (*offlineStreamingClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (*offlineStreamingClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (*offlineStreamingClientConn) CloseResponse() error {
+	return nil
+}
+
+// offlineModuleDataProvider is the delegate ModuleDataProvider used while offline.
+//
+// It is only called for ModuleKeys that were not found in the cache.
+type offlineModuleDataProvider struct{}
+
+func (offlineModuleDataProvider) GetModuleDatasForModuleKeys(
+	_ context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	return nil, newModuleKeysNotInCacheError(moduleKeys)
+}
+
+// offlineCommitProvider is the delegate CommitProvider used while offline.
+//
+// It is only called for keys that were not found in the cache.
+type offlineCommitProvider struct{}
+
+func (offlineCommitProvider) GetCommitsForModuleKeys(
+	_ context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	return nil, newModuleKeysNotInCacheError(moduleKeys)
+}
+
+func (offlineCommitProvider) GetCommitsForCommitKeys(
+	_ context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	pins := make([]string, len(commitKeys))
+	for i, commitKey := range commitKeys {
+		pins[i] = commitKey.Registry() + ":" + uuidutil.ToDashless(commitKey.CommitID())
+	}
+	return nil, newPinsNotInCacheError(pins)
+}
+
+func newModuleKeysNotInCacheError(moduleKeys []bufmodule.ModuleKey) error {
+	pins := make([]string, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		pins[i] = moduleKey.String()
+	}
+	return newPinsNotInCacheError(pins)
+}
+
+func newPinsNotInCacheError(pins []string) error {
+	if len(pins) == 1 {
+		return fmt.Errorf("%s not in cache: %w", pins[0], errOffline)
+	}
+	return fmt.Errorf("not in cache: %s: %w", strings.Join(pins, ", "), errOffline)
+}
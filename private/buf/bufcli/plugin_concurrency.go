@@ -0,0 +1,83 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/spf13/pflag"
+)
+
+const pluginConcurrencyFlagName = "plugin-concurrency"
+
+// BindPluginConcurrency binds the global --plugin-concurrency flag.
+//
+// The flag is applied to the Container with NewPluginConcurrencyInterceptor.
+func BindPluginConcurrency(flagSet *pflag.FlagSet, pluginConcurrency *int) {
+	flagSet.IntVar(
+		pluginConcurrency,
+		pluginConcurrencyFlagName,
+		0,
+		fmt.Sprintf(
+			`The maximum number of local plugin processes that run concurrently across generate, lint, and breaking. Defaults to the number of CPUs. Can also be set with %s`,
+			pluginConcurrencyEnvKey,
+		),
+	)
+}
+
+// NewPluginConcurrencyInterceptor returns a new Interceptor that sets pluginConcurrencyEnvKey
+// on the Container if pluginConcurrency is set, so that NewPluginRunner reflects the
+// --plugin-concurrency flag.
+func NewPluginConcurrencyInterceptor(pluginConcurrency *int) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if *pluginConcurrency == 0 {
+				return next(ctx, container)
+			}
+			if *pluginConcurrency < 0 {
+				return appcmd.NewInvalidArgumentErrorf("--%s must be positive", pluginConcurrencyFlagName)
+			}
+			pluginConcurrencyContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					pluginConcurrencyEnvKey: strconv.Itoa(*pluginConcurrency),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, pluginConcurrencyContainer)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+func getPluginConcurrency(envContainer app.EnvContainer) (int, error) {
+	pluginConcurrency, err := app.EnvInt(envContainer, pluginConcurrencyEnvKey, thread.Parallelism())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", pluginConcurrencyEnvKey, err)
+	}
+	if pluginConcurrency < 1 {
+		return 0, fmt.Errorf("%s: must be positive, got %d", pluginConcurrencyEnvKey, pluginConcurrency)
+	}
+	return pluginConcurrency, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPluginConcurrency(t *testing.T) {
+	t.Parallel()
+	pluginConcurrency, err := getPluginConcurrency(app.NewEnvContainer(nil))
+	require.NoError(t, err)
+	assert.Equal(t, thread.Parallelism(), pluginConcurrency)
+	pluginConcurrency, err = getPluginConcurrency(
+		app.NewEnvContainer(
+			map[string]string{
+				pluginConcurrencyEnvKey: "2",
+			},
+		),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pluginConcurrency)
+	for _, value := range []string{"0", "-1", "foo"} {
+		_, err = getPluginConcurrency(
+			app.NewEnvContainer(
+				map[string]string{
+					pluginConcurrencyEnvKey: value,
+				},
+			),
+		)
+		assert.Error(t, err, value)
+	}
+}
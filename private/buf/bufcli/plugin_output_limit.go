@@ -0,0 +1,64 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/pluginrpcutil"
+)
+
+// defaultPluginOutputLimit is the default maximum number of bytes a plugin can write
+// to stdout for a single invocation.
+//
+// This is well above the size of any reasonable plugin response, and is only meant to
+// stop a misbehaving plugin from exhausting memory.
+const defaultPluginOutputLimit int64 = 1 << 30
+
+// GetPluginOutputLimit returns the maximum number of bytes a local plugin can write
+// to stdout for a single invocation.
+//
+// This is set with pluginOutputLimitEnvKey, and defaults to 1 GiB. A value of 0 means
+// no limit.
+func GetPluginOutputLimit(envContainer app.EnvContainer) (int64, error) {
+	value := envContainer.Env(pluginOutputLimitEnvKey)
+	if value == "" {
+		return defaultPluginOutputLimit, nil
+	}
+	pluginOutputLimit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", pluginOutputLimitEnvKey, err)
+	}
+	if pluginOutputLimit < 0 {
+		return 0, fmt.Errorf("%s: must not be negative, got %d", pluginOutputLimitEnvKey, pluginOutputLimit)
+	}
+	return pluginOutputLimit, nil
+}
+
+// NewPluginRunnerInterceptors returns the pluginrpcutil.RunnerInterceptors applied to
+// every invocation of a check plugin.
+func NewPluginRunnerInterceptors(container appext.Container) ([]pluginrpcutil.RunnerInterceptor, error) {
+	pluginOutputLimit, err := GetPluginOutputLimit(container)
+	if err != nil {
+		return nil, err
+	}
+	return []pluginrpcutil.RunnerInterceptor{
+		pluginrpcutil.NewLoggingRunnerInterceptor(container.Logger()),
+		pluginrpcutil.NewOutputLimitRunnerInterceptor(pluginOutputLimit),
+	}, nil
+}
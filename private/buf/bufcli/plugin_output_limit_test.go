@@ -0,0 +1,54 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPluginOutputLimit(t *testing.T) {
+	t.Parallel()
+	pluginOutputLimit, err := GetPluginOutputLimit(app.NewEnvContainer(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultPluginOutputLimit, pluginOutputLimit)
+	for value, expected := range map[string]int64{
+		"0":       0,
+		"1048576": 1048576,
+	} {
+		pluginOutputLimit, err := GetPluginOutputLimit(
+			app.NewEnvContainer(
+				map[string]string{
+					pluginOutputLimitEnvKey: value,
+				},
+			),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, expected, pluginOutputLimit)
+	}
+	for _, value := range []string{"-1", "1GB"} {
+		_, err := GetPluginOutputLimit(
+			app.NewEnvContainer(
+				map[string]string{
+					pluginOutputLimitEnvKey: value,
+				},
+			),
+		)
+		assert.Error(t, err, value)
+	}
+}
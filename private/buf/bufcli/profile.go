@@ -0,0 +1,189 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/netext"
+	"github.com/spf13/pflag"
+)
+
+const (
+	profileFlagName = "profile"
+	// profilesFileName is the name of the file within the config directory that contains
+	// the named profiles.
+	profilesFileName = "profiles.yaml"
+	profilesVersion  = "v1"
+)
+
+// BindProfile binds the global --profile flag.
+//
+// The flag is applied to the Container with NewProfileInterceptor.
+func BindProfile(flagSet *pflag.FlagSet, profile *string) {
+	flagSet.StringVar(
+		profile,
+		profileFlagName,
+		"",
+		fmt.Sprintf(
+			`The name of the profile in %s within the configuration directory to use for the default remote, token, proxy, and cache directory. Can also be set with %s`,
+			profilesFileName,
+			profileEnvKey,
+		),
+	)
+}
+
+// NewProfileInterceptor returns a new Interceptor that applies the selected profile
+// to the Container.
+//
+// The profile is selected with --profile, or profileEnvKey if the flag is not set. A
+// profile sets the environment variables that correspond to its values, overriding any
+// values already present in the environment.
+func NewProfileInterceptor(profile *string) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			profileName := *profile
+			if profileName == "" {
+				profileName = container.Env(profileEnvKey)
+			}
+			if profileName == "" {
+				return next(ctx, container)
+			}
+			overrides, err := getProfileEnvOverrides(container, profileName)
+			if err != nil {
+				return err
+			}
+			profileContainer, err := newContainerWithEnvOverrides(container, overrides)
+			if err != nil {
+				return err
+			}
+			return next(ctx, profileContainer)
+		}
+	}
+}
+
+// GetDefaultRemote returns the remote to use when a command is not given one.
+//
+// This is the remote of the selected profile if it sets one, otherwise bufconnect.DefaultRemote.
+func GetDefaultRemote(container app.EnvContainer) string {
+	if remote := container.Env(defaultRemoteEnvKey); remote != "" {
+		return remote
+	}
+	return bufconnect.DefaultRemote
+}
+
+// *** PRIVATE ***
+
+type externalProfiles struct {
+	Version  string                     `json:"version,omitempty" yaml:"version,omitempty"`
+	Profiles map[string]externalProfile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+type externalProfile struct {
+	// Remote is the default remote, used by commands such as buf registry login when no
+	// remote is given.
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	// TokenEnv is the name of an environment variable that contains the token.
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	// TokenFile is the path to a file that contains the token.
+	TokenFile string `json:"token_file,omitempty" yaml:"token_file,omitempty"`
+	// Proxy is the URL of the proxy to use for all HTTP and HTTPS requests.
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// CacheDir is the cache directory.
+	CacheDir string `json:"cache_dir,omitempty" yaml:"cache_dir,omitempty"`
+}
+
+// getProfileEnvOverrides reads the profile with the given name and returns the
+// environment variables it sets.
+func getProfileEnvOverrides(container appext.NameContainer, profileName string) (map[string]string, error) {
+	profilesFilePath := filepath.Join(container.ConfigDirPath(), profilesFileName)
+	data, err := os.ReadFile(profilesFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("profile %q selected but %s does not exist", profileName, profilesFilePath)
+		}
+		return nil, err
+	}
+	var externalProfiles externalProfiles
+	if err := encoding.UnmarshalYAMLStrict(data, &externalProfiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles file at %s: %w", profilesFilePath, err)
+	}
+	if externalProfiles.Version != profilesVersion {
+		return nil, fmt.Errorf("profiles file at %s must declare 'version: %s'", profilesFilePath, profilesVersion)
+	}
+	externalProfile, ok := externalProfiles.Profiles[profileName]
+	if !ok {
+		profileNames := make([]string, 0, len(externalProfiles.Profiles))
+		for name := range externalProfiles.Profiles {
+			profileNames = append(profileNames, name)
+		}
+		sort.Strings(profileNames)
+		return nil, fmt.Errorf("profile %q not found in %s, available profiles are: [%s]", profileName, profilesFilePath, strings.Join(profileNames, ", "))
+	}
+	overrides, err := getEnvOverridesForExternalProfile(container, externalProfile)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q in %s: %w", profileName, profilesFilePath, err)
+	}
+	return overrides, nil
+}
+
+func getEnvOverridesForExternalProfile(container app.EnvContainer, externalProfile externalProfile) (map[string]string, error) {
+	overrides := make(map[string]string)
+	if externalProfile.Remote != "" {
+		if _, err := netext.ValidateHostname(externalProfile.Remote); err != nil {
+			return nil, fmt.Errorf("invalid remote: %w", err)
+		}
+		overrides[defaultRemoteEnvKey] = externalProfile.Remote
+	}
+	switch {
+	case externalProfile.TokenEnv != "" && externalProfile.TokenFile != "":
+		return nil, errors.New("cannot set both token_env and token_file")
+	case externalProfile.TokenEnv != "":
+		token := container.Env(externalProfile.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("token_env is set to %s but %s is not set", externalProfile.TokenEnv, externalProfile.TokenEnv)
+		}
+		overrides[bufconnect.TokenEnvKey] = token
+	case externalProfile.TokenFile != "":
+		data, err := os.ReadFile(externalProfile.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read token_file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return nil, fmt.Errorf("token_file %s is empty", externalProfile.TokenFile)
+		}
+		overrides[bufconnect.TokenEnvKey] = token
+	}
+	if externalProfile.Proxy != "" {
+		for _, proxyEnvKey := range []string{"HTTPS_PROXY", "HTTP_PROXY", "https_proxy", "http_proxy"} {
+			overrides[proxyEnvKey] = externalProfile.Proxy
+		}
+	}
+	if externalProfile.CacheDir != "" {
+		overrides[cacheDirEnvKey] = externalProfile.CacheDir
+	}
+	return overrides, nil
+}
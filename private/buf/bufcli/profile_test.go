@@ -0,0 +1,107 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProfileEnvOverrides(t *testing.T) {
+	t.Parallel()
+	configDirPath := t.TempDir()
+	tokenFilePath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFilePath, []byte("file-token\n"), 0600))
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(configDirPath, profilesFileName),
+			[]byte(`version: v1
+profiles:
+  work:
+    remote: buf.example.com
+    token_env: EXAMPLE_TOKEN
+    proxy: http://proxy.example.com:3128
+    cache_dir: /tmp/example-cache
+  personal:
+    token_file: `+tokenFilePath+`
+  invalid:
+    token_env: EXAMPLE_TOKEN
+    token_file: `+tokenFilePath+`
+`),
+			0600,
+		),
+	)
+	container := newTestNameContainer(
+		t,
+		map[string]string{
+			"BUF_CONFIG_DIR": configDirPath,
+			"EXAMPLE_TOKEN":  "env-token",
+		},
+	)
+
+	overrides, err := getProfileEnvOverrides(container, "work")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		map[string]string{
+			defaultRemoteEnvKey: "buf.example.com",
+			"BUF_TOKEN":         "env-token",
+			"HTTPS_PROXY":       "http://proxy.example.com:3128",
+			"HTTP_PROXY":        "http://proxy.example.com:3128",
+			"https_proxy":       "http://proxy.example.com:3128",
+			"http_proxy":        "http://proxy.example.com:3128",
+			cacheDirEnvKey:      "/tmp/example-cache",
+		},
+		overrides,
+	)
+	overrides, err = getProfileEnvOverrides(container, "personal")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"BUF_TOKEN": "file-token"}, overrides)
+
+	_, err = getProfileEnvOverrides(container, "invalid")
+	require.ErrorContains(t, err, "cannot set both token_env and token_file")
+	_, err = getProfileEnvOverrides(container, "unknown")
+	require.ErrorContains(t, err, `profile "unknown" not found`)
+	require.ErrorContains(t, err, "[invalid, personal, work]")
+}
+
+func TestGetProfileEnvOverridesNoProfilesFile(t *testing.T) {
+	t.Parallel()
+	container := newTestNameContainer(t, map[string]string{"BUF_CONFIG_DIR": t.TempDir()})
+	_, err := getProfileEnvOverrides(container, "work")
+	require.ErrorContains(t, err, "does not exist")
+}
+
+func TestGetDefaultRemote(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "buf.build", GetDefaultRemote(app.NewEnvContainer(nil)))
+	require.Equal(
+		t,
+		"buf.example.com",
+		GetDefaultRemote(app.NewEnvContainer(map[string]string{defaultRemoteEnvKey: "buf.example.com"})),
+	)
+}
+
+func newTestNameContainer(t *testing.T, env map[string]string) appext.NameContainer {
+	nameContainer, err := appext.NewNameContainer(app.NewContainer(env, nil, nil, nil), "buf")
+	require.NoError(t, err)
+	return nameContainer
+}
@@ -43,6 +43,35 @@ func PromptUserForPassword(container app.Container, prompt string) (string, erro
 	return promptUser(container, prompt, true)
 }
 
+// PromptUserWithDefault reads a line from Stdin, prompting the user with the prompt first.
+// The defaultValue is returned if the user provides an empty response.
+// ErrNotATTY is returned if the input containers Stdin is not a terminal.
+func PromptUserWithDefault(container app.Container, prompt string, defaultValue string) (string, error) {
+	if !IsStdinTerminal(container) {
+		return "", ErrNotATTY
+	}
+	if _, err := fmt.Fprint(container.Stdout(), prompt); err != nil {
+		return "", syserror.Wrap(err)
+	}
+	value, err := readLine(container)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(value) == "" {
+		return defaultValue, nil
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// IsStdinTerminal returns true if the containers Stdin is a terminal.
+//
+// Commands that read answers from Stdin themselves use this to fail before doing
+// any work if they cannot prompt the user.
+func IsStdinTerminal(container app.StdinContainer) bool {
+	file, ok := container.Stdin().(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
 // PromptUserForDelete is used to receive user confirmation that a specific
 // entity should be deleted. If the user's answer does not match the expected
 // answer, an error is returned.
@@ -105,18 +134,11 @@ func promptUser(container app.Container, prompt string, isPassword bool) (string
 			}
 			value = string(data)
 		} else {
-			scanner := bufio.NewScanner(container.Stdin())
-			if !scanner.Scan() {
-				// scanner.Err() returns nil on EOF.
-				if err := scanner.Err(); err != nil {
-					return "", syserror.Wrap(err)
-				}
-				return "", io.EOF
-			}
-			value = scanner.Text()
-			if err := scanner.Err(); err != nil {
-				return "", syserror.Wrap(err)
+			line, err := readLine(container)
+			if err != nil {
+				return "", err
 			}
+			value = line
 		}
 		if len(strings.TrimSpace(value)) != 0 {
 			// We want to preserve spaces in user input, so we only apply
@@ -136,3 +158,19 @@ func promptUser(container app.Container, prompt string, isPassword bool) (string
 	}
 	return "", NewTooManyEmptyAnswersError(userPromptAttempts)
 }
+
+// readLine reads a line from Stdin.
+func readLine(container app.StdinContainer) (string, error) {
+	scanner := bufio.NewScanner(container.Stdin())
+	if !scanner.Scan() {
+		// scanner.Err() returns nil on EOF.
+		if err := scanner.Err(); err != nil {
+			return "", syserror.Wrap(err)
+		}
+		return "", io.EOF
+	}
+	if err := scanner.Err(); err != nil {
+		return "", syserror.Wrap(err)
+	}
+	return scanner.Text(), nil
+}
@@ -0,0 +1,342 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	resultFormatFlagName = "format"
+
+	resultFormatText = "text"
+	resultFormatJSON = "json"
+
+	resultStatusSuccess = "success"
+	resultStatusFailure = "failure"
+)
+
+// AllResultFormatStrings are all the values for the global --format flag.
+var AllResultFormatStrings = []string{
+	resultFormatText,
+	resultFormatJSON,
+}
+
+// BindResultFormat binds the global --format flag.
+//
+// The flag is applied with NewResultFormatInterceptor. Commands that define their own
+// --format flag shadow the global flag.
+func BindResultFormat(flagSet *pflag.FlagSet, resultFormat *string) {
+	flagSet.StringVar(
+		resultFormat,
+		resultFormatFlagName,
+		resultFormatText,
+		fmt.Sprintf(
+			`The format of the result of the command. Must be one of %s. If json, a single JSON object with the status, warnings, data, and error of the command is printed to stdout, and human-readable output is printed to stderr. Commands that stream their output do not support json. Commands that have their own --format flag use that flag instead`,
+			stringutil.SliceToString(AllResultFormatStrings),
+		),
+	)
+}
+
+// SetResult sets the structured result of the command, which is used as the data of the
+// JSON result for --format=json.
+//
+// If a result is set, all output the command prints to stdout is printed to stderr instead.
+// This is a no-op if --format=json is not set.
+func SetResult(ctx context.Context, result any) error {
+	resultRecorder, ok := ctx.Value(resultRecorderContextKey{}).(*resultRecorder)
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	resultRecorder.setResult(data)
+	return nil
+}
+
+// SetFileAnnotationSetResult sets the FileAnnotations of the FileAnnotationSet as the
+// structured result of the command, in the same shape as --error-format=json.
+//
+// This is a no-op if --format=json is not set.
+func SetFileAnnotationSetResult(ctx context.Context, fileAnnotationSet bufanalysis.FileAnnotationSet) error {
+	if _, ok := ctx.Value(resultRecorderContextKey{}).(*resultRecorder); !ok {
+		return nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := bufanalysis.PrintFileAnnotationSet(buffer, fileAnnotationSet, "json"); err != nil {
+		return err
+	}
+	fileAnnotations := make([]json.RawMessage, 0, len(fileAnnotationSet.FileAnnotations()))
+	decoder := json.NewDecoder(buffer)
+	for {
+		var fileAnnotation json.RawMessage
+		if err := decoder.Decode(&fileAnnotation); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		fileAnnotations = append(fileAnnotations, fileAnnotation)
+	}
+	return SetResult(ctx, fileAnnotations)
+}
+
+// ValidateResultFormatNotStreaming returns an error if --format=json is set.
+//
+// This should be called by commands that stream their output, as their output cannot be
+// wrapped in a single JSON result.
+func ValidateResultFormatNotStreaming(ctx context.Context) error {
+	if _, ok := ctx.Value(resultRecorderContextKey{}).(*resultRecorder); !ok {
+		return nil
+	}
+	return fmt.Errorf("--%s=%s is not supported by commands that stream their output", resultFormatFlagName, resultFormatJSON)
+}
+
+// NewResultFormatInterceptor returns a new Interceptor that wraps the result of every
+// command in a JSON envelope if resultFormat is json.
+//
+// The envelope has the following shape:
+//
+//	{
+//	  "status": "success" | "failure",
+//	  "warnings": ["..."],
+//	  "data": ...,
+//	  "error": {"code": 1, "error_code": "failure", "message": "..."}
+//	}
+//
+// The code is the exit code of the command, and the error_code is the machine-readable
+// class of the failure, such as config_error or lint_violation. See bufctl.GetErrorCode.
+//
+// The data is the result set by the command with SetResult. If no result was set, and
+// the output the command would have printed to stdout is JSON, or a sequence of JSON
+// values such as JSON lines, the output is embedded as JSON. All other output is
+// human-readable, and is printed to stderr instead, along with log output and error
+// messages. Warnings are the messages of all warnings logged by the command.
+//
+// This should be the first Interceptor, so that it observes the errors returned to the user.
+func NewResultFormatInterceptor(resultFormat *string) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			switch resultFormat := strings.ToLower(strings.TrimSpace(*resultFormat)); resultFormat {
+			case "", resultFormatText:
+				return next(ctx, container)
+			case resultFormatJSON:
+			default:
+				return fmt.Errorf("--%s: unknown format %q, must be one of %s", resultFormatFlagName, resultFormat, stringutil.SliceToString(AllResultFormatStrings))
+			}
+			stdout := bytes.NewBuffer(nil)
+			warningRecorder := &warningRecorder{}
+			resultContainer, err := newResultContainer(container, stdout, warningRecorder)
+			if err != nil {
+				return err
+			}
+			resultRecorder := &resultRecorder{}
+			runErr := next(context.WithValue(ctx, resultRecorderContextKey{}, resultRecorder), resultContainer)
+			resultData, isHumanReadable := resultRecorder.getResult(), true
+			if resultData == nil {
+				resultData, isHumanReadable = getResultData(stdout.Bytes())
+			}
+			if isHumanReadable {
+				if _, err := container.Stderr().Write(stdout.Bytes()); err != nil {
+					return errors.Join(runErr, err)
+				}
+			}
+			data, err := json.Marshal(newResultEnvelope(resultData, warningRecorder.getWarnings(), runErr))
+			if err != nil {
+				return errors.Join(runErr, err)
+			}
+			if _, err := fmt.Fprintln(container.Stdout(), string(data)); err != nil {
+				return errors.Join(runErr, err)
+			}
+			return runErr
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+type resultEnvelope struct {
+	Status   string          `json:"status"`
+	Warnings []string        `json:"warnings,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    *resultError    `json:"error,omitempty"`
+}
+
+type resultError struct {
+	Code      int    `json:"code"`
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message,omitempty"`
+}
+
+type resultRecorderContextKey struct{}
+
+type resultRecorder struct {
+	result json.RawMessage
+	lock   sync.Mutex
+}
+
+func (r *resultRecorder) setResult(result json.RawMessage) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.result = result
+}
+
+func (r *resultRecorder) getResult() json.RawMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.result
+}
+
+func newResultEnvelope(data json.RawMessage, warnings []string, err error) *resultEnvelope {
+	resultEnvelope := &resultEnvelope{
+		Status:   resultStatusSuccess,
+		Warnings: warnings,
+		Data:     data,
+	}
+	if err != nil {
+		resultEnvelope.Status = resultStatusFailure
+		resultEnvelope.Error = &resultError{
+			Code:      app.GetExitCode(err),
+			ErrorCode: bufctl.GetErrorCode(err),
+			Message:   err.Error(),
+		}
+	}
+	return resultEnvelope
+}
+
+// getResultData returns the JSON to embed in the envelope for the output of a command.
+//
+// Returns true if the output is not JSON, in which case the output is human-readable
+// and no data is returned.
+func getResultData(stdout []byte) (json.RawMessage, bool) {
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil, false
+	}
+	if json.Valid(stdout) {
+		return bytes.TrimSpace(stdout), false
+	}
+	// Commands such as buf lint --error-format=json print one JSON value per line.
+	var values []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			values = nil
+			break
+		}
+		values = append(values, value)
+	}
+	if len(values) > 0 {
+		if data, err := json.Marshal(values); err == nil {
+			return data, false
+		}
+	}
+	return nil, true
+}
+
+func newResultContainer(
+	container appext.Container,
+	stdout io.Writer,
+	warningRecorder *warningRecorder,
+) (appext.Container, error) {
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			app.EnvironMap(container),
+			container.Stdin(),
+			stdout,
+			container.Stderr(),
+			app.Args(container)...,
+		),
+		container.AppName(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return appext.NewContainer(
+		nameContainer,
+		slog.New(
+			&warningRecordingHandler{
+				Handler:         container.Logger().Handler(),
+				warningRecorder: warningRecorder,
+			},
+		),
+	), nil
+}
+
+type warningRecorder struct {
+	warnings []string
+	lock     sync.Mutex
+}
+
+func (w *warningRecorder) addWarning(warning string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.warnings = append(w.warnings, warning)
+}
+
+func (w *warningRecorder) getWarnings() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.warnings
+}
+
+// warningRecordingHandler is a slog.Handler that records the messages of all warnings
+// before passing them to the delegate Handler.
+type warningRecordingHandler struct {
+	slog.Handler
+
+	warningRecorder *warningRecorder
+}
+
+func (h *warningRecordingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelWarn && record.Level < slog.LevelError {
+		h.warningRecorder.addWarning(record.Message)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *warningRecordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &warningRecordingHandler{
+		Handler:         h.Handler.WithAttrs(attrs),
+		warningRecorder: h.warningRecorder,
+	}
+}
+
+func (h *warningRecordingHandler) WithGroup(name string) slog.Handler {
+	return &warningRecordingHandler{
+		Handler:         h.Handler.WithGroup(name),
+		warningRecorder: h.warningRecorder,
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResultData(t *testing.T) {
+	t.Parallel()
+	testGetResultData(t, "", ``, false)
+	testGetResultData(t, "\n", ``, false)
+	testGetResultData(t, `{"a":1}`+"\n", `{"a":1}`, false)
+	testGetResultData(t, `{"a":1}`+"\n"+`{"b":2}`+"\n", `[{"a":1},{"b":2}]`, false)
+	testGetResultData(t, "a.proto\nb.proto\n", ``, true)
+	testGetResultData(t, `{"a":1}`+"\nfoo\n", ``, true)
+}
+
+func TestResultFormatInterceptor(t *testing.T) {
+	t.Parallel()
+	testResultFormatInterceptor(
+		t,
+		func(ctx context.Context, container appext.Container) error {
+			_, err := container.Stdout().Write([]byte(`{"a":1}` + "\n"))
+			return err
+		},
+		`{"status":"success","data":{"a":1}}`,
+		"",
+	)
+	testResultFormatInterceptor(
+		t,
+		func(ctx context.Context, container appext.Container) error {
+			_, err := container.Stdout().Write([]byte("a.proto\n"))
+			return err
+		},
+		`{"status":"success"}`,
+		"a.proto\n",
+	)
+	testResultFormatInterceptor(
+		t,
+		func(ctx context.Context, container appext.Container) error {
+			if _, err := container.Stdout().Write([]byte("a.proto:1:1:foo\n")); err != nil {
+				return err
+			}
+			if err := SetFileAnnotationSetResult(
+				ctx,
+				bufanalysis.NewFileAnnotationSet(
+					bufanalysis.NewFileAnnotation(nil, 0, 0, 0, 0, "FOO", "foo", ""),
+				),
+			); err != nil {
+				return err
+			}
+			return bufctl.ErrLintViolation
+		},
+		`{"status":"failure","data":[{"start_line":1,"start_column":1,"end_line":1,"end_column":1,"type":"FOO","message":"foo"}],"error":{"code":100,"error_code":"lint_violation"}}`,
+		"a.proto:1:1:foo\n",
+	)
+	testResultFormatInterceptor(
+		t,
+		func(ctx context.Context, container appext.Container) error {
+			return ValidateResultFormatNotStreaming(ctx)
+		},
+		`{"status":"failure","error":{"code":1,"error_code":"failure","message":"--format=json is not supported by commands that stream their output"}}`,
+		"",
+	)
+}
+
+func TestResultFormatInterceptorText(t *testing.T) {
+	t.Parallel()
+	resultFormat := "text"
+	stdout := bytes.NewBuffer(nil)
+	err := NewResultFormatInterceptor(&resultFormat)(
+		func(ctx context.Context, container appext.Container) error {
+			if err := SetResult(ctx, "foo"); err != nil {
+				return err
+			}
+			if err := ValidateResultFormatNotStreaming(ctx); err != nil {
+				return err
+			}
+			_, err := container.Stdout().Write([]byte("a.proto\n"))
+			return err
+		},
+	)(context.Background(), testNewResultFormatContainer(t, stdout, bytes.NewBuffer(nil)))
+	require.NoError(t, err)
+	require.Equal(t, "a.proto\n", stdout.String())
+}
+
+func TestNewResultEnvelope(t *testing.T) {
+	t.Parallel()
+	data, err := json.Marshal(newResultEnvelope(json.RawMessage(`{"a":1}`), []string{"foo"}, nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"success","warnings":["foo"],"data":{"a":1}}`, string(data))
+	data, err = json.Marshal(newResultEnvelope(nil, nil, app.NewError(100, "")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"failure","error":{"code":100,"error_code":"file_annotation"}}`, string(data))
+	data, err = json.Marshal(newResultEnvelope(nil, nil, errors.New("foo")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"failure","error":{"code":1,"error_code":"failure","message":"foo"}}`, string(data))
+	data, err = json.Marshal(newResultEnvelope(nil, nil, bufctl.ErrLintViolation))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"failure","error":{"code":100,"error_code":"lint_violation"}}`, string(data))
+	data, err = json.Marshal(newResultEnvelope(nil, nil, bufctl.ErrBreakingViolation))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"failure","error":{"code":100,"error_code":"breaking_violation"}}`, string(data))
+	data, err = json.Marshal(newResultEnvelope(nil, nil, app.NewError(bufctl.ExitCodeAuthFailure, "foo")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"failure","error":{"code":4,"error_code":"auth_failure","message":"foo"}}`, string(data))
+}
+
+func TestWarningRecordingHandler(t *testing.T) {
+	t.Parallel()
+	warningRecorder := &warningRecorder{}
+	logger := slog.New(
+		&warningRecordingHandler{
+			Handler:         slogtestext.NewLogger(t).Handler(),
+			warningRecorder: warningRecorder,
+		},
+	).With(slog.String("key", "value"))
+	ctx := context.Background()
+	logger.InfoContext(ctx, "info")
+	logger.WarnContext(ctx, "warn1")
+	logger.ErrorContext(ctx, "error")
+	logger.WithGroup("group").WarnContext(ctx, "warn2")
+	require.Equal(t, []string{"warn1", "warn2"}, warningRecorder.getWarnings())
+}
+
+func testGetResultData(t *testing.T, stdout string, expected string, expectedIsHumanReadable bool) {
+	data, isHumanReadable := getResultData([]byte(stdout))
+	require.Equal(t, expected, string(data))
+	require.Equal(t, expectedIsHumanReadable, isHumanReadable)
+}
+
+func testResultFormatInterceptor(
+	t *testing.T,
+	run func(context.Context, appext.Container) error,
+	expectedStdout string,
+	expectedStderr string,
+) {
+	resultFormat := "json"
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	_ = NewResultFormatInterceptor(&resultFormat)(run)(
+		context.Background(),
+		testNewResultFormatContainer(t, stdout, stderr),
+	)
+	require.JSONEq(t, expectedStdout, stdout.String())
+	require.Equal(t, expectedStderr, stderr.String())
+}
+
+func testNewResultFormatContainer(t *testing.T, stdout *bytes.Buffer, stderr *bytes.Buffer) appext.Container {
+	nameContainer, err := appext.NewNameContainer(app.NewContainer(nil, nil, stdout, stderr), "buf")
+	require.NoError(t, err)
+	return appext.NewContainer(nameContainer, slogtestext.NewLogger(t))
+}
@@ -0,0 +1,107 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/spf13/pflag"
+)
+
+const noSandboxFlagName = "no-sandbox"
+
+// BindNoSandbox binds the global --no-sandbox flag.
+//
+// The flag is applied to the Container with NewNoSandboxInterceptor.
+func BindNoSandbox(flagSet *pflag.FlagSet, noSandbox *bool) {
+	flagSet.BoolVar(
+		noSandbox,
+		noSandboxFlagName,
+		false,
+		fmt.Sprintf(
+			`Run local plugins without a sandbox. By default, local plugins are run without network access and cannot write to the filesystem outside of the temporary directory, if supported on this platform. Can also be set with %s=1`,
+			noSandboxEnvKey,
+		),
+	)
+}
+
+// NewNoSandboxInterceptor returns a new Interceptor that sets noSandboxEnvKey on the
+// Container if noSandbox is set, so that NewPluginRunner reflects the --no-sandbox flag.
+func NewNoSandboxInterceptor(noSandbox *bool) appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			if !*noSandbox {
+				return next(ctx, container)
+			}
+			noSandboxContainer, err := newContainerWithEnvOverrides(
+				container,
+				map[string]string{
+					noSandboxEnvKey: "1",
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return next(ctx, noSandboxContainer)
+		}
+	}
+}
+
+// NewPluginRunner returns a new command.Runner for running local plugins.
+//
+// Plugins are run in a sandbox, unless disabled by --no-sandbox or noSandboxEnvKey, or
+// sandboxing is not supported on this platform. If the sandbox is supported but cannot
+// be created, a warning is logged and plugins are run without a sandbox. The number of plugin processes that run
+// concurrently is capped by --plugin-concurrency or pluginConcurrencyEnvKey.
+func NewPluginRunner(container appext.Container) (command.Runner, error) {
+	pluginConcurrency, err := getPluginConcurrency(container)
+	if err != nil {
+		return nil, err
+	}
+	runnerOptions := []command.RunnerOption{
+		command.RunnerWithParallelism(pluginConcurrency),
+	}
+	noSandbox, err := app.EnvBool(container, noSandboxEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", noSandboxEnvKey, err)
+	}
+	if noSandbox {
+		return command.NewRunner(runnerOptions...), nil
+	}
+	if err := command.CheckSandboxSupported(); err != nil {
+		if errors.Is(err, command.ErrSandboxNotSupported) {
+			container.Logger().Debug(fmt.Sprintf("local plugins are not sandboxed: %v", err))
+		} else {
+			// The sandbox is supported but could not be created, for example within a container
+			// that does not permit creating namespaces. Fall back to running plugins unsandboxed
+			// rather than failing every plugin invocation.
+			container.Logger().Warn(
+				fmt.Sprintf(
+					"Local plugins are not sandboxed: %v. Use --%s or set %s=1 to disable sandboxing and suppress this warning.",
+					err,
+					noSandboxFlagName,
+					noSandboxEnvKey,
+				),
+			)
+		}
+		return command.NewRunner(runnerOptions...), nil
+	}
+	return command.NewRunner(append(runnerOptions, command.RunnerWithSandbox())...), nil
+}
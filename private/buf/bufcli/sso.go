@@ -0,0 +1,323 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/oauth2"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"go.uber.org/multierr"
+)
+
+// SSOCredentialsFileName is the name of the file within the config directory that
+// contains the credentials from buf registry login --sso.
+const SSOCredentialsFileName = "sso.json"
+
+const (
+	ssoCredentialsVersion = "v1"
+
+	// ssoRefreshMargin is how long before an access token expires that it is refreshed.
+	ssoRefreshMargin  = time.Minute
+	ssoRefreshTimeout = 30 * time.Second
+)
+
+// SSOCredentials are the credentials for a remote obtained with the device authorization
+// flow of an OpenID Connect provider.
+type SSOCredentials struct {
+	// ClientID is the client that the tokens were issued to.
+	ClientID string `json:"client_id"`
+	// ClientSecret is the client secret. May be empty.
+	ClientSecret string `json:"client_secret,omitempty"`
+	// TokenEndpoint is the URL to refresh the access token at.
+	TokenEndpoint string `json:"token_endpoint"`
+	// AccessToken is the token that is sent to the remote.
+	AccessToken string `json:"access_token"`
+	// RefreshToken is used to get a new access token once it expires. May be empty.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Expiry is when the access token expires. Zero if the access token does not expire.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// NewSSOCredentials returns new SSOCredentials for the token response.
+func NewSSOCredentials(
+	clientID string,
+	clientSecret string,
+	tokenEndpoint string,
+	tokenResponse *oauth2.DeviceAccessTokenResponse,
+) *SSOCredentials {
+	ssoCredentials := &SSOCredentials{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		TokenEndpoint: tokenEndpoint,
+	}
+	ssoCredentials.update(tokenResponse, time.Now())
+	return ssoCredentials
+}
+
+// GetSSOCredentialsFilePath returns the path to the file that contains SSO credentials.
+func GetSSOCredentialsFilePath(container appext.NameContainer) string {
+	return filepath.Join(container.ConfigDirPath(), SSOCredentialsFileName)
+}
+
+// PutSSOCredentials saves the SSOCredentials for the remote, replacing any existing
+// credentials for the remote.
+func PutSSOCredentials(
+	ctx context.Context,
+	container appext.NameContainer,
+	remote string,
+	ssoCredentials *SSOCredentials,
+) error {
+	return updateSSOCredentialsFile(
+		ctx,
+		GetSSOCredentialsFilePath(container),
+		func(remoteToSSOCredentials map[string]*SSOCredentials) (bool, error) {
+			remoteToSSOCredentials[remote] = ssoCredentials
+			return true, nil
+		},
+	)
+}
+
+// DeleteSSOCredentials deletes the SSOCredentials for the remote.
+//
+// Returns true if there were credentials for the remote.
+func DeleteSSOCredentials(
+	ctx context.Context,
+	container appext.NameContainer,
+	remote string,
+) (bool, error) {
+	var deleted bool
+	if err := updateSSOCredentialsFile(
+		ctx,
+		GetSSOCredentialsFilePath(container),
+		func(remoteToSSOCredentials map[string]*SSOCredentials) (bool, error) {
+			_, deleted = remoteToSSOCredentials[remote]
+			delete(remoteToSSOCredentials, remote)
+			return deleted, nil
+		},
+	); err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
+
+// *** PRIVATE ***
+
+type externalSSOCredentialsFile struct {
+	Version string                     `json:"version"`
+	Remotes map[string]*SSOCredentials `json:"remotes,omitempty"`
+}
+
+func (c *SSOCredentials) update(tokenResponse *oauth2.DeviceAccessTokenResponse, now time.Time) {
+	c.AccessToken = tokenResponse.AccessToken
+	// A refresh token response may omit the refresh token, in which case the
+	// existing refresh token remains valid.
+	if tokenResponse.RefreshToken != "" {
+		c.RefreshToken = tokenResponse.RefreshToken
+	}
+	c.Expiry = time.Time{}
+	if tokenResponse.ExpiresIn > 0 {
+		c.Expiry = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second).UTC()
+	}
+}
+
+// needsRefresh returns true if the access token has expired or is about to expire.
+func (c *SSOCredentials) needsRefresh(now time.Time) bool {
+	return !c.Expiry.IsZero() && !now.Add(ssoRefreshMargin).Before(c.Expiry)
+}
+
+// ssoTokenProvider is a bufconnect.TokenProvider for the credentials from
+// buf registry login --sso, that refreshes access tokens as they expire.
+type ssoTokenProvider struct {
+	logger     *slog.Logger
+	filePath   string
+	httpClient *http.Client
+
+	// remoteToToken caches refreshed tokens for the lifetime of the process.
+	remoteToToken map[string]string
+	lock          sync.Mutex
+}
+
+func newSSOTokenProvider(
+	container appext.Container,
+	httpClient *http.Client,
+) *ssoTokenProvider {
+	return &ssoTokenProvider{
+		logger:        container.Logger(),
+		filePath:      GetSSOCredentialsFilePath(container),
+		httpClient:    httpClient,
+		remoteToToken: make(map[string]string),
+	}
+}
+
+func (p *ssoTokenProvider) RemoteToken(address string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if token, ok := p.remoteToToken[address]; ok {
+		return token
+	}
+	token, err := p.getToken(address)
+	if err != nil {
+		p.logger.Warn(
+			"failed to refresh SSO credentials, run buf registry login --sso to log in again",
+			slog.String("remote", address),
+			slogext.ErrorAttr(err),
+		)
+	}
+	p.remoteToToken[address] = token
+	return token
+}
+
+func (*ssoTokenProvider) IsFromEnvVar() bool {
+	return false
+}
+
+func (p *ssoTokenProvider) getToken(remote string) (string, error) {
+	remoteToSSOCredentials, err := readSSOCredentialsFile(p.filePath)
+	if err != nil {
+		return "", err
+	}
+	ssoCredentials, ok := remoteToSSOCredentials[remote]
+	if !ok {
+		return "", nil
+	}
+	if !ssoCredentials.needsRefresh(time.Now()) {
+		return ssoCredentials.AccessToken, nil
+	}
+	if ssoCredentials.RefreshToken == "" {
+		return "", errors.New("access token expired")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ssoRefreshTimeout)
+	defer cancel()
+	var token string
+	if err := updateSSOCredentialsFile(
+		ctx,
+		p.filePath,
+		func(remoteToSSOCredentials map[string]*SSOCredentials) (bool, error) {
+			ssoCredentials, ok := remoteToSSOCredentials[remote]
+			if !ok {
+				return false, nil
+			}
+			// Another process may have refreshed the token while we waited for the lock.
+			now := time.Now()
+			if !ssoCredentials.needsRefresh(now) {
+				token = ssoCredentials.AccessToken
+				return false, nil
+			}
+			tokenResponse, err := oauth2.NewClient(
+				"",
+				p.httpClient,
+				oauth2.ClientWithServerMetadata(&oauth2.ServerMetadata{TokenEndpoint: ssoCredentials.TokenEndpoint}),
+			).RefreshAccessToken(
+				ctx,
+				&oauth2.RefreshTokenRequest{
+					ClientID:     ssoCredentials.ClientID,
+					ClientSecret: ssoCredentials.ClientSecret,
+					RefreshToken: ssoCredentials.RefreshToken,
+					GrantType:    oauth2.RefreshTokenGrantType,
+				},
+			)
+			if err != nil {
+				return false, err
+			}
+			ssoCredentials.update(tokenResponse, now)
+			token = ssoCredentials.AccessToken
+			return true, nil
+		},
+	); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// updateSSOCredentialsFile reads the credentials file, calls f, and writes the
+// credentials file if f returns true, while holding a lock on the file.
+func updateSSOCredentialsFile(
+	ctx context.Context,
+	filePath string,
+	f func(map[string]*SSOCredentials) (bool, error),
+) (retErr error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	unlocker, err := filelock.Lock(ctx, filePath+".lock")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	remoteToSSOCredentials, err := readSSOCredentialsFile(filePath)
+	if err != nil {
+		return err
+	}
+	modified, err := f(remoteToSSOCredentials)
+	if err != nil || !modified {
+		return err
+	}
+	data, err := json.MarshalIndent(
+		&externalSSOCredentialsFile{
+			Version: ssoCredentialsVersion,
+			Remotes: remoteToSSOCredentials,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return err
+	}
+	// Write atomically, so that concurrent readers never see a partial file.
+	tempFilePath := filePath + ".tmp"
+	if err := os.WriteFile(tempFilePath, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, filePath)
+}
+
+// readSSOCredentialsFile reads the credentials file.
+//
+// Returns an empty map if the file does not exist.
+func readSSOCredentialsFile(filePath string) (map[string]*SSOCredentials, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return make(map[string]*SSOCredentials), nil
+		}
+		return nil, err
+	}
+	var externalFile externalSSOCredentialsFile
+	if err := json.Unmarshal(data, &externalFile); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", filePath, err)
+	}
+	if externalFile.Version != ssoCredentialsVersion {
+		return nil, fmt.Errorf("%s has unknown version %q", filePath, externalFile.Version)
+	}
+	if externalFile.Remotes == nil {
+		externalFile.Remotes = make(map[string]*SSOCredentials)
+	}
+	return externalFile.Remotes, nil
+}
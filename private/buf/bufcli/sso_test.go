@@ -0,0 +1,117 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/oauth2"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSOCredentialsNeedsRefresh(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	ssoCredentials := &SSOCredentials{}
+	ssoCredentials.update(&oauth2.DeviceAccessTokenResponse{AccessToken: "a", RefreshToken: "r"}, now)
+	assert.False(t, ssoCredentials.needsRefresh(now.Add(time.Hour)))
+	ssoCredentials.update(&oauth2.DeviceAccessTokenResponse{AccessToken: "b", ExpiresIn: 3600}, now)
+	assert.Equal(t, "b", ssoCredentials.AccessToken)
+	// The refresh token is kept if the response does not have one.
+	assert.Equal(t, "r", ssoCredentials.RefreshToken)
+	assert.False(t, ssoCredentials.needsRefresh(now))
+	assert.True(t, ssoCredentials.needsRefresh(now.Add(time.Hour-ssoRefreshMargin)))
+	assert.True(t, ssoCredentials.needsRefresh(now.Add(2*time.Hour)))
+}
+
+func TestSSOTokenProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var numRefreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, request.ParseForm())
+		assert.Equal(t, "client", request.Form.Get("client_id"))
+		assert.Equal(t, "refresh1", request.Form.Get("refresh_token"))
+		assert.Equal(t, oauth2.RefreshTokenGrantType, request.Form.Get("grant_type"))
+		numRefreshes.Add(1)
+		writer.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(writer).Encode(&oauth2.DeviceAccessTokenResponse{
+			AccessToken:  "access2",
+			ExpiresIn:    3600,
+			RefreshToken: "refresh2",
+		}))
+	}))
+	t.Cleanup(server.Close)
+	filePath := filepath.Join(t.TempDir(), SSOCredentialsFileName)
+	putSSOCredentials := func(remote string, ssoCredentials *SSOCredentials) {
+		require.NoError(t, updateSSOCredentialsFile(ctx, filePath, func(remoteToSSOCredentials map[string]*SSOCredentials) (bool, error) {
+			remoteToSSOCredentials[remote] = ssoCredentials
+			return true, nil
+		}))
+	}
+	newTestSSOTokenProvider := func() *ssoTokenProvider {
+		return &ssoTokenProvider{
+			logger:        slogtestext.NewLogger(t),
+			filePath:      filePath,
+			httpClient:    server.Client(),
+			remoteToToken: make(map[string]string),
+		}
+	}
+	putSSOCredentials("valid.example.com", &SSOCredentials{
+		ClientID:      "client",
+		TokenEndpoint: server.URL,
+		AccessToken:   "access1",
+		RefreshToken:  "refresh1",
+		Expiry:        time.Now().Add(time.Hour),
+	})
+	putSSOCredentials("expired.example.com", &SSOCredentials{
+		ClientID:      "client",
+		TokenEndpoint: server.URL,
+		AccessToken:   "access1",
+		RefreshToken:  "refresh1",
+		Expiry:        time.Now().Add(-time.Hour),
+	})
+	putSSOCredentials("norefresh.example.com", &SSOCredentials{
+		ClientID:      "client",
+		TokenEndpoint: server.URL,
+		AccessToken:   "access1",
+		Expiry:        time.Now().Add(-time.Hour),
+	})
+
+	tokenProvider := newTestSSOTokenProvider()
+	assert.Equal(t, "access1", tokenProvider.RemoteToken("valid.example.com"))
+	assert.Equal(t, "access2", tokenProvider.RemoteToken("expired.example.com"))
+	assert.Equal(t, "access2", tokenProvider.RemoteToken("expired.example.com"))
+	assert.Equal(t, "", tokenProvider.RemoteToken("norefresh.example.com"))
+	assert.Equal(t, "", tokenProvider.RemoteToken("unknown.example.com"))
+	assert.Equal(t, int32(1), numRefreshes.Load())
+
+	// The refreshed credentials were saved.
+	remoteToSSOCredentials, err := readSSOCredentialsFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "access2", remoteToSSOCredentials["expired.example.com"].AccessToken)
+	assert.Equal(t, "refresh2", remoteToSSOCredentials["expired.example.com"].RefreshToken)
+	assert.Equal(t, "access2", newTestSSOTokenProvider().RemoteToken("expired.example.com"))
+	assert.Equal(t, int32(1), numRefreshes.Load())
+}
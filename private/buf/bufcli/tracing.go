@@ -0,0 +1,144 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/tracing"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// These are the standard OpenTelemetry environment variables.
+	//
+	// https://opentelemetry.io/docs/specs/otel/protocol/exporter/
+	otelSDKDisabledEnvKey                = "OTEL_SDK_DISABLED"
+	otelServiceNameEnvKey                = "OTEL_SERVICE_NAME"
+	otelExporterOTLPEndpointEnvKey       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	otelExporterOTLPHeadersEnvKey        = "OTEL_EXPORTER_OTLP_HEADERS"
+	otelExporterOTLPTracesEndpointEnvKey = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	otelExporterOTLPTracesHeadersEnvKey  = "OTEL_EXPORTER_OTLP_TRACES_HEADERS"
+
+	tracesExportTimeout = 10 * time.Second
+)
+
+// NewTracingInterceptor returns a new Interceptor that exports a trace of the command
+// to an OTLP/HTTP collector.
+//
+// Tracing is opt-in, and is enabled by setting OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT. Only the JSON encoding of OTLP/HTTP is supported.
+// A failure to export the trace is logged, and does not fail the command.
+func NewTracingInterceptor() appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) (retErr error) {
+			tracerProvider, err := newTracerProviderIfConfigured(container)
+			if err != nil {
+				return err
+			}
+			if tracerProvider == nil {
+				return next(ctx, container)
+			}
+			otel.SetTracerProvider(tracerProvider)
+			otel.SetTextMapPropagator(propagation.TraceContext{})
+			defer func() {
+				// Export even if the command was canceled or timed out, this is when
+				// a trace is most useful.
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tracesExportTimeout)
+				defer cancel()
+				if err := tracerProvider.Shutdown(ctx); err != nil {
+					container.Logger().WarnContext(ctx, "failed to export trace", slogext.ErrorAttr(err))
+				}
+			}()
+			ctx, span := tracing.Start(ctx, container.AppName())
+			defer func() { tracing.End(span, retErr) }()
+			container.Logger().DebugContext(ctx, "tracing", slog.String("traceID", span.SpanContext().TraceID().String()))
+			return next(ctx, container)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+func newTracerProviderIfConfigured(container appext.Container) (tracing.TracerProvider, error) {
+	disabled, err := app.EnvBool(container, otelSDKDisabledEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", otelSDKDisabledEnvKey, err)
+	}
+	if disabled {
+		return nil, nil
+	}
+	endpoint := container.Env(otelExporterOTLPTracesEndpointEnvKey)
+	if endpoint == "" {
+		if baseEndpoint := container.Env(otelExporterOTLPEndpointEnvKey); baseEndpoint != "" {
+			endpoint = strings.TrimSuffix(baseEndpoint, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+	options := []tracing.OTLPTracerProviderOption{
+		tracing.OTLPTracerProviderWithServiceName(container.AppName()),
+	}
+	if serviceName := container.Env(otelServiceNameEnvKey); serviceName != "" {
+		options = append(options, tracing.OTLPTracerProviderWithServiceName(serviceName))
+	}
+	for _, headersEnvKey := range []string{otelExporterOTLPHeadersEnvKey, otelExporterOTLPTracesHeadersEnvKey} {
+		headers, err := parseOTLPHeaders(container.Env(headersEnvKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", headersEnvKey, err)
+		}
+		for _, header := range headers {
+			options = append(options, tracing.OTLPTracerProviderWithHeader(header[0], header[1]))
+		}
+	}
+	return tracing.NewOTLPTracerProvider(
+		httpclient.NewClient(nil, httpclient.ClientWithProxyFromEnvContainer(container)),
+		endpoint,
+		options...,
+	), nil
+}
+
+// parseOTLPHeaders parses a list of headers of the form "key1=value1,key2=value2",
+// where the values are URL-encoded.
+func parseOTLPHeaders(value string) ([][2]string, error) {
+	var headers [][2]string
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("header %q must be of the form key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", key, err)
+		}
+		headers = append(headers, [2]string{key, value})
+	}
+	return headers, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOTLPHeaders(t *testing.T) {
+	t.Parallel()
+	headers, err := parseOTLPHeaders("")
+	require.NoError(t, err)
+	assert.Empty(t, headers)
+	headers, err = parseOTLPHeaders("api-key=secret, x-team = a%20b ,")
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{{"api-key", "secret"}, {"x-team", "a b"}}, headers)
+	_, err = parseOTLPHeaders("api-key")
+	require.Error(t, err)
+	_, err = parseOTLPHeaders("=secret")
+	require.Error(t, err)
+	_, err = parseOTLPHeaders("api-key=%zz")
+	require.Error(t, err)
+}
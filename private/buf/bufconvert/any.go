@@ -0,0 +1,327 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// UnknownAnyError says to fail if a google.protobuf.Any holds a type that
+	// cannot be resolved.
+	UnknownAnyError UnknownAny = iota + 1
+	// UnknownAnySkip says to leave out any google.protobuf.Any that holds a type
+	// that cannot be resolved.
+	UnknownAnySkip
+	// UnknownAnyPassthrough says to write any google.protobuf.Any that holds a type
+	// that cannot be resolved with its type URL and its value as opaque bytes.
+	UnknownAnyPassthrough
+
+	anyFullName           protoreflect.FullName    = "google.protobuf.Any"
+	anyTypeURLFieldNumber protoreflect.FieldNumber = 1
+	anyValueFieldNumber   protoreflect.FieldNumber = 2
+)
+
+var (
+	// AllUnknownAnyStrings are all string values for UnknownAny.
+	AllUnknownAnyStrings = []string{
+		"error",
+		"skip",
+		"passthrough",
+	}
+
+	unknownAnyToString = map[UnknownAny]string{
+		UnknownAnyError:       "error",
+		UnknownAnySkip:        "skip",
+		UnknownAnyPassthrough: "passthrough",
+	}
+	stringToUnknownAny = map[string]UnknownAny{
+		"error":       UnknownAnyError,
+		"skip":        UnknownAnySkip,
+		"passthrough": UnknownAnyPassthrough,
+	}
+)
+
+// UnknownAny is how to handle a google.protobuf.Any that holds a type that
+// cannot be resolved.
+type UnknownAny int
+
+// String implements fmt.Stringer.
+func (u UnknownAny) String() string {
+	s, ok := unknownAnyToString[u]
+	if !ok {
+		return strconv.Itoa(int(u))
+	}
+	return s
+}
+
+// ParseUnknownAny parses the UnknownAny.
+//
+// The empty string is a parse error.
+func ParseUnknownAny(s string) (UnknownAny, error) {
+	u, ok := stringToUnknownAny[strings.ToLower(strings.TrimSpace(s))]
+	if ok {
+		return u, nil
+	}
+	return 0, fmt.Errorf("unknown UnknownAny: %q", s)
+}
+
+// ResolveAnys checks that every google.protobuf.Any within the message holds a
+// type that the resolver can resolve, including within the contents of other
+// Anys, and handles those that do not according to unknownAny.
+//
+// The JSON, YAML, and text marshalers expand the contents of an Any using the
+// type named by its type URL, so they cannot write an Any whose type is not known.
+// The message should be marshaled with the returned resolver. The given message
+// is never modified.
+//
+// With UnknownAnyPassthrough, the returned resolver resolves each unknown type URL
+// to google.protobuf.BytesValue, so that the Any is written with its type URL and
+// a "value" holding its original bytes, as is done for well-known types.
+func ResolveAnys(
+	message proto.Message,
+	resolver protoencoding.Resolver,
+	unknownAny UnknownAny,
+) (proto.Message, protoencoding.Resolver, error) {
+	if unknownAny != UnknownAnyError {
+		message = proto.Clone(message)
+	}
+	anyResolver := &anyResolver{
+		resolver:   resolver,
+		unknownAny: unknownAny,
+	}
+	remove, _, err := anyResolver.resolveInMessage(message.ProtoReflect(), "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if remove {
+		// The message itself is an Any that holds an unknown type.
+		message = message.ProtoReflect().Type().New().Interface()
+	}
+	if len(anyResolver.passthroughTypeURLs) > 0 {
+		resolver = &passthroughAnyResolver{
+			Resolver:            resolver,
+			passthroughTypeURLs: anyResolver.passthroughTypeURLs,
+		}
+	}
+	return message, resolver, nil
+}
+
+// *** PRIVATE ***
+
+type anyResolver struct {
+	resolver            protoencoding.Resolver
+	unknownAny          UnknownAny
+	passthroughTypeURLs map[string]struct{}
+}
+
+// resolveInMessage resolves the Anys within the message.
+//
+// Returns whether the message is an Any that should be removed, and whether the
+// message was modified.
+func (a *anyResolver) resolveInMessage(message protoreflect.Message, path string) (bool, bool, error) {
+	if message.Descriptor().FullName() == anyFullName {
+		return a.resolveAny(message, path)
+	}
+	var fieldDescriptors []protoreflect.FieldDescriptor
+	message.Range(func(fieldDescriptor protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fieldDescriptors = append(fieldDescriptors, fieldDescriptor)
+		return true
+	})
+	var modified bool
+	for _, fieldDescriptor := range fieldDescriptors {
+		fieldModified, err := a.resolveInField(message, fieldDescriptor, joinPath(path, fieldDescriptor))
+		if err != nil {
+			return false, false, err
+		}
+		modified = modified || fieldModified
+	}
+	return false, modified, nil
+}
+
+func (a *anyResolver) resolveInField(
+	message protoreflect.Message,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	path string,
+) (bool, error) {
+	value := message.Get(fieldDescriptor)
+	if fieldDescriptor.IsMap() {
+		if !isMessageKind(fieldDescriptor.MapValue().Kind()) {
+			return false, nil
+		}
+		mapValue := value.Map()
+		var keys []protoreflect.MapKey
+		mapValue.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, key)
+			return true
+		})
+		var modified bool
+		for _, key := range keys {
+			remove, valueModified, err := a.resolveInMessage(mapValue.Get(key).Message(), path+"["+formatMapKey(key)+"]")
+			if err != nil {
+				return false, err
+			}
+			if remove {
+				mapValue.Clear(key)
+			}
+			modified = modified || valueModified
+		}
+		return modified, nil
+	}
+	if !isMessageKind(fieldDescriptor.Kind()) {
+		return false, nil
+	}
+	if fieldDescriptor.IsList() {
+		list := value.List()
+		var kept []protoreflect.Value
+		var modified bool
+		for i := 0; i < list.Len(); i++ {
+			remove, elementModified, err := a.resolveInMessage(list.Get(i).Message(), path+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return false, err
+			}
+			if !remove {
+				kept = append(kept, list.Get(i))
+			}
+			modified = modified || elementModified
+		}
+		if len(kept) < list.Len() {
+			list.Truncate(0)
+			for _, element := range kept {
+				list.Append(element)
+			}
+		}
+		return modified, nil
+	}
+	remove, modified, err := a.resolveInMessage(value.Message(), path)
+	if err != nil {
+		return false, err
+	}
+	if remove {
+		message.Clear(fieldDescriptor)
+	}
+	return modified, nil
+}
+
+func (a *anyResolver) resolveAny(message protoreflect.Message, path string) (bool, bool, error) {
+	fields := message.Descriptor().Fields()
+	typeURLFieldDescriptor := fields.ByNumber(anyTypeURLFieldNumber)
+	valueFieldDescriptor := fields.ByNumber(anyValueFieldNumber)
+	if typeURLFieldDescriptor == nil || valueFieldDescriptor == nil {
+		return false, false, newPathError(path, fmt.Errorf("%s is missing its type_url or value field", anyFullName))
+	}
+	typeURL := message.Get(typeURLFieldDescriptor).String()
+	if typeURL == "" {
+		return false, false, nil
+	}
+	value := message.Get(valueFieldDescriptor).Bytes()
+	messageType, err := a.resolver.FindMessageByURL(typeURL)
+	if err != nil {
+		if !errors.Is(err, protoregistry.NotFound) {
+			return false, false, newPathError(path, err)
+		}
+		switch a.unknownAny {
+		case UnknownAnySkip:
+			return true, true, nil
+		case UnknownAnyPassthrough:
+			data, err := proto.Marshal(wrapperspb.Bytes(value))
+			if err != nil {
+				return false, false, newPathError(path, err)
+			}
+			message.Set(valueFieldDescriptor, protoreflect.ValueOfBytes(data))
+			if a.passthroughTypeURLs == nil {
+				a.passthroughTypeURLs = make(map[string]struct{})
+			}
+			a.passthroughTypeURLs[typeURL] = struct{}{}
+			return false, true, nil
+		default:
+			return false, false, newPathError(path, fmt.Errorf("unable to resolve %s type %q", anyFullName, typeURL))
+		}
+	}
+	contents := messageType.New()
+	if err := (proto.UnmarshalOptions{
+		Resolver:     a.resolver,
+		AllowPartial: true,
+	}).Unmarshal(value, contents.Interface()); err != nil {
+		return false, false, newPathError(path, fmt.Errorf("unable to unmarshal %s of type %q: %w", anyFullName, typeURL, err))
+	}
+	remove, modified, err := a.resolveInMessage(contents, path)
+	if err != nil || remove || !modified {
+		return remove, modified, err
+	}
+	data, err := proto.MarshalOptions{
+		AllowPartial:  true,
+		Deterministic: true,
+	}.Marshal(contents.Interface())
+	if err != nil {
+		return false, false, newPathError(path, err)
+	}
+	message.Set(valueFieldDescriptor, protoreflect.ValueOfBytes(data))
+	return false, true, nil
+}
+
+// passthroughAnyResolver resolves the type URLs of Anys written with
+// UnknownAnyPassthrough to google.protobuf.BytesValue.
+type passthroughAnyResolver struct {
+	protoencoding.Resolver
+
+	passthroughTypeURLs map[string]struct{}
+}
+
+func (p *passthroughAnyResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if _, ok := p.passthroughTypeURLs[url]; ok {
+		return (&wrapperspb.BytesValue{}).ProtoReflect().Type(), nil
+	}
+	return p.Resolver.FindMessageByURL(url)
+}
+
+func isMessageKind(kind protoreflect.Kind) bool {
+	return kind == protoreflect.MessageKind || kind == protoreflect.GroupKind
+}
+
+// joinPath appends the field to the path, in the same form accepted by SelectPath.
+func joinPath(path string, fieldDescriptor protoreflect.FieldDescriptor) string {
+	name := string(fieldDescriptor.Name())
+	if fieldDescriptor.IsExtension() {
+		name = "[" + string(fieldDescriptor.FullName()) + "]"
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func formatMapKey(key protoreflect.MapKey) string {
+	if s, ok := key.Interface().(string); ok {
+		return strconv.Quote(s)
+	}
+	return key.String()
+}
+
+func newPathError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
@@ -0,0 +1,140 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestResolveAnys(t *testing.T) {
+	t.Parallel()
+	resolver, err := protoencoding.NewResolver(
+		protodesc.ToFileDescriptorProto(anypb.File_google_protobuf_any_proto),
+		protodesc.ToFileDescriptorProto(sourcecontextpb.File_google_protobuf_source_context_proto),
+		protodesc.ToFileDescriptorProto(typepb.File_google_protobuf_type_proto),
+	)
+	require.NoError(t, err)
+	inner, err := anypb.New(
+		&typepb.Type{
+			Name: "inner",
+			Options: []*typepb.Option{
+				{
+					Name: "nested",
+					Value: &anypb.Any{
+						TypeUrl: "type.googleapis.com/foo.Unknown",
+						Value:   []byte{0x08, 0x01},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	message := &typepb.Type{
+		Name: "outer",
+		Options: []*typepb.Option{
+			{
+				Name:  "known",
+				Value: inner,
+			},
+			{
+				Name: "unknown",
+				Value: &anypb.Any{
+					TypeUrl: "type.googleapis.com/foo.Unknown",
+					Value:   []byte{0x08, 0x02},
+				},
+			},
+		},
+	}
+	original := proto.Clone(message)
+
+	_, _, err = ResolveAnys(message, resolver, UnknownAnyError)
+	assert.EqualError(t, err, `options[0].value.options[0].value: unable to resolve google.protobuf.Any type "type.googleapis.com/foo.Unknown"`)
+	testResolveAnysJSON(
+		t,
+		message,
+		resolver,
+		UnknownAnySkip,
+		`{
+			"name": "outer",
+			"options": [
+				{"name": "known", "value": {"@type": "type.googleapis.com/google.protobuf.Type", "name": "inner", "options": [{"name": "nested"}]}},
+				{"name": "unknown"}
+			]
+		}`,
+	)
+	testResolveAnysJSON(
+		t,
+		message,
+		resolver,
+		UnknownAnyPassthrough,
+		`{
+			"name": "outer",
+			"options": [
+				{"name": "known", "value": {"@type": "type.googleapis.com/google.protobuf.Type", "name": "inner", "options": [{"name": "nested", "value": {"@type": "type.googleapis.com/foo.Unknown", "value": "CAE="}}]}},
+				{"name": "unknown", "value": {"@type": "type.googleapis.com/foo.Unknown", "value": "CAI="}}
+			]
+		}`,
+	)
+	assert.True(t, proto.Equal(original, message))
+
+	// Anys that hold known types are left alone.
+	known, err := anypb.New(&typepb.Type{Name: "known"})
+	require.NoError(t, err)
+	resolvedMessage, resolvedResolver, err := ResolveAnys(known, resolver, UnknownAnyPassthrough)
+	require.NoError(t, err)
+	assert.Equal(t, resolver, resolvedResolver)
+	assert.True(t, proto.Equal(known, resolvedMessage))
+	// The message itself can be an Any.
+	resolvedMessage, _, err = ResolveAnys(message.GetOptions()[1].GetValue(), resolver, UnknownAnySkip)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&anypb.Any{}, resolvedMessage))
+	_, _, err = ResolveAnys(&descriptorpb.FileDescriptorProto{}, resolver, UnknownAnyError)
+	assert.NoError(t, err)
+}
+
+func TestParseUnknownAny(t *testing.T) {
+	t.Parallel()
+	for _, s := range AllUnknownAnyStrings {
+		unknownAny, err := ParseUnknownAny(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, unknownAny.String())
+	}
+	_, err := ParseUnknownAny("")
+	assert.Error(t, err)
+}
+
+func testResolveAnysJSON(
+	t *testing.T,
+	message proto.Message,
+	resolver protoencoding.Resolver,
+	unknownAny UnknownAny,
+	expectedJSON string,
+) {
+	resolvedMessage, resolvedResolver, err := ResolveAnys(message, resolver, unknownAny)
+	require.NoError(t, err)
+	data, err := protoencoding.NewJSONMarshaler(resolvedResolver).Marshal(resolvedMessage)
+	require.NoError(t, err)
+	assert.JSONEq(t, expectedJSON, string(data))
+}
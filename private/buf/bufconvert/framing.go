@@ -0,0 +1,331 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FramingVarint says that each record is prefixed with its size as a varint,
+	// as done by the protodelim package.
+	FramingVarint Framing = iota + 1
+	// FramingConnect says that each record is wrapped in a Connect or gRPC
+	// envelope: a flags byte followed by the size as a big-endian uint32.
+	FramingConnect
+	// FramingFixed32 says that each record is prefixed with its size as a
+	// big-endian uint32, as is common for dumps of Kafka topics and other logs.
+	FramingFixed32
+	// FramingRecordIO says that each record is prefixed with its size as a
+	// decimal number followed by a newline, as in the RecordIO format used
+	// by Mesos and others.
+	FramingRecordIO
+)
+
+const (
+	// connectFlagCompressed is set on envelopes whose payload is compressed.
+	connectFlagCompressed = 0b00000001
+	// connectFlagEndStream is set on the final envelope of a Connect stream,
+	// whose payload is the JSON end-of-stream message and not a record.
+	connectFlagEndStream = 0b00000010
+	// maxRecordSize is the maximum size of a single record.
+	//
+	// This matches the maximum size of an encoded Protobuf message.
+	maxRecordSize = math.MaxInt32
+)
+
+var (
+	// AllFramingStrings are all string values for Framing.
+	AllFramingStrings = []string{
+		"varint",
+		"connect",
+		"fixed32",
+		"recordio",
+	}
+
+	framingToString = map[Framing]string{
+		FramingVarint:   "varint",
+		FramingConnect:  "connect",
+		FramingFixed32:  "fixed32",
+		FramingRecordIO: "recordio",
+	}
+	stringToFraming = map[string]Framing{
+		"varint":   FramingVarint,
+		"connect":  FramingConnect,
+		"fixed32":  FramingFixed32,
+		"recordio": FramingRecordIO,
+	}
+)
+
+// Framing is how records are delimited within a binary stream.
+type Framing int
+
+// String implements fmt.Stringer.
+func (f Framing) String() string {
+	s, ok := framingToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFraming parses the Framing.
+//
+// The empty string is a parse error.
+func ParseFraming(s string) (Framing, error) {
+	f, ok := stringToFraming[strings.ToLower(strings.TrimSpace(s))]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown Framing: %q", s)
+}
+
+// RecordReader reads records from a stream one at a time.
+type RecordReader interface {
+	// ReadRecord reads the next record.
+	//
+	// Returns io.EOF if the stream ended cleanly between records.
+	ReadRecord() ([]byte, error)
+}
+
+// NewRecordReader returns a new RecordReader that reads records delimited
+// with the given Framing.
+func NewRecordReader(reader io.Reader, framing Framing) (RecordReader, error) {
+	prefixCodec, ok := framingToPrefixCodec[framing]
+	if !ok {
+		return nil, fmt.Errorf("unknown Framing: %v", framing)
+	}
+	return newPrefixRecordReader(reader, prefixCodec), nil
+}
+
+// NewJSONRecordReader returns a new RecordReader that reads a stream of
+// JSON values, such as newline-delimited JSON.
+func NewJSONRecordReader(reader io.Reader) RecordReader {
+	return newJSONRecordReader(reader)
+}
+
+// RecordWriter writes records to a stream one at a time.
+type RecordWriter interface {
+	// WriteRecord writes the record.
+	WriteRecord(data []byte) error
+}
+
+// NewRecordWriter returns a new RecordWriter that writes records delimited
+// with the given Framing.
+func NewRecordWriter(writer io.Writer, framing Framing) (RecordWriter, error) {
+	prefixCodec, ok := framingToPrefixCodec[framing]
+	if !ok {
+		return nil, fmt.Errorf("unknown Framing: %v", framing)
+	}
+	return newPrefixRecordWriter(writer, prefixCodec), nil
+}
+
+// NewLineRecordWriter returns a new RecordWriter that writes each record
+// followed by a newline.
+//
+// The records must not contain newlines themselves.
+func NewLineRecordWriter(writer io.Writer) RecordWriter {
+	return newLineRecordWriter(writer)
+}
+
+// *** PRIVATE ***
+
+// prefixCodec reads and writes the prefix that delimits each record for a Framing.
+type prefixCodec struct {
+	// readPrefix reads the prefix of the next record and returns the size of the record.
+	//
+	// Returns io.EOF if no bytes were read. If endStream is true, the record ends the
+	// stream and is not returned.
+	readPrefix func(reader *bufio.Reader) (size uint64, endStream bool, err error)
+	// appendPrefix appends the prefix of a record of the given size to dst.
+	appendPrefix func(dst []byte, size int) []byte
+}
+
+var framingToPrefixCodec = map[Framing]prefixCodec{
+	FramingVarint: {
+		readPrefix: func(reader *bufio.Reader) (uint64, bool, error) {
+			// ReadUvarint only returns io.EOF if no bytes were read.
+			size, err := binary.ReadUvarint(reader)
+			return size, false, err
+		},
+		appendPrefix: func(dst []byte, size int) []byte {
+			return binary.AppendUvarint(dst, uint64(size))
+		},
+	},
+	FramingConnect: {
+		readPrefix: func(reader *bufio.Reader) (uint64, bool, error) {
+			var prefix [5]byte
+			if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+				// ReadFull only returns io.EOF if no bytes were read.
+				return 0, false, err
+			}
+			flags := prefix[0]
+			if flags&connectFlagCompressed != 0 {
+				return 0, false, errors.New("compressed envelopes are not supported")
+			}
+			return uint64(binary.BigEndian.Uint32(prefix[1:])), flags&connectFlagEndStream != 0, nil
+		},
+		appendPrefix: func(dst []byte, size int) []byte {
+			return binary.BigEndian.AppendUint32(append(dst, 0), uint32(size))
+		},
+	},
+	FramingFixed32: {
+		readPrefix: func(reader *bufio.Reader) (uint64, bool, error) {
+			var prefix [4]byte
+			if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+				// ReadFull only returns io.EOF if no bytes were read.
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(prefix[:])), false, nil
+		},
+		appendPrefix: func(dst []byte, size int) []byte {
+			return binary.BigEndian.AppendUint32(dst, uint32(size))
+		},
+	},
+	FramingRecordIO: {
+		readPrefix: func(reader *bufio.Reader) (uint64, bool, error) {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				if errors.Is(err, io.EOF) && line != "" {
+					// We read part of a size, the stream was truncated.
+					return 0, false, io.ErrUnexpectedEOF
+				}
+				return 0, false, err
+			}
+			size, err := strconv.ParseUint(strings.TrimSuffix(line, "\n"), 10, 64)
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid record size %q", strings.TrimSuffix(line, "\n"))
+			}
+			return size, false, nil
+		},
+		appendPrefix: func(dst []byte, size int) []byte {
+			return append(strconv.AppendInt(dst, int64(size), 10), '\n')
+		},
+	},
+}
+
+type prefixRecordReader struct {
+	reader      *bufio.Reader
+	prefixCodec prefixCodec
+}
+
+func newPrefixRecordReader(reader io.Reader, prefixCodec prefixCodec) *prefixRecordReader {
+	return &prefixRecordReader{
+		reader:      bufio.NewReader(reader),
+		prefixCodec: prefixCodec,
+	}
+}
+
+func (r *prefixRecordReader) ReadRecord() ([]byte, error) {
+	size, endStream, err := r.prefixCodec.readPrefix(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readRecord(r.reader, size)
+	if err != nil {
+		return nil, err
+	}
+	if endStream {
+		// The end-of-stream message carries trailers and errors, not a record.
+		// Nothing may follow it.
+		if _, err := r.reader.ReadByte(); err != io.EOF {
+			return nil, errors.New("data found after end-of-stream envelope")
+		}
+		return nil, io.EOF
+	}
+	return data, nil
+}
+
+type jsonRecordReader struct {
+	decoder *json.Decoder
+}
+
+func newJSONRecordReader(reader io.Reader) *jsonRecordReader {
+	return &jsonRecordReader{
+		decoder: json.NewDecoder(reader),
+	}
+}
+
+func (r *jsonRecordReader) ReadRecord() ([]byte, error) {
+	var data json.RawMessage
+	if err := r.decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+type prefixRecordWriter struct {
+	writer      io.Writer
+	prefixCodec prefixCodec
+}
+
+func newPrefixRecordWriter(writer io.Writer, prefixCodec prefixCodec) *prefixRecordWriter {
+	return &prefixRecordWriter{
+		writer:      writer,
+		prefixCodec: prefixCodec,
+	}
+}
+
+func (w *prefixRecordWriter) WriteRecord(data []byte) error {
+	if len(data) > maxRecordSize {
+		return fmt.Errorf("record of size %d exceeds maximum size %d", len(data), maxRecordSize)
+	}
+	if _, err := w.writer.Write(w.prefixCodec.appendPrefix(nil, len(data))); err != nil {
+		return err
+	}
+	_, err := w.writer.Write(data)
+	return err
+}
+
+type lineRecordWriter struct {
+	writer io.Writer
+}
+
+func newLineRecordWriter(writer io.Writer) *lineRecordWriter {
+	return &lineRecordWriter{
+		writer: writer,
+	}
+}
+
+func (w *lineRecordWriter) WriteRecord(data []byte) error {
+	if _, err := w.writer.Write(data); err != nil {
+		return err
+	}
+	_, err := w.writer.Write([]byte{'\n'})
+	return err
+}
+
+func readRecord(reader io.Reader, size uint64) ([]byte, error) {
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record of size %d exceeds maximum size %d", size, maxRecordSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			// We read a size but no data, the stream was truncated.
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
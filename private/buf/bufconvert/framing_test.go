@@ -0,0 +1,100 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramingRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, framing := range []Framing{FramingVarint, FramingConnect, FramingFixed32, FramingRecordIO} {
+		framing := framing
+		t.Run(framing.String(), func(t *testing.T) {
+			t.Parallel()
+			records := [][]byte{
+				[]byte("foo"),
+				{},
+				bytes.Repeat([]byte("b"), 300),
+			}
+			buffer := bytes.NewBuffer(nil)
+			recordWriter, err := NewRecordWriter(buffer, framing)
+			require.NoError(t, err)
+			for _, record := range records {
+				require.NoError(t, recordWriter.WriteRecord(record))
+			}
+			recordReader, err := NewRecordReader(buffer, framing)
+			require.NoError(t, err)
+			for _, record := range records {
+				data, err := recordReader.ReadRecord()
+				require.NoError(t, err)
+				assert.Equal(t, record, data)
+			}
+			_, err = recordReader.ReadRecord()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestFramingTruncated(t *testing.T) {
+	t.Parallel()
+	recordReader, err := NewRecordReader(bytes.NewReader([]byte{0x05, 'a', 'b'}), FramingVarint)
+	require.NoError(t, err)
+	_, err = recordReader.ReadRecord()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	recordReader, err = NewRecordReader(bytes.NewReader([]byte{0x00, 0x00}), FramingConnect)
+	require.NoError(t, err)
+	_, err = recordReader.ReadRecord()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	recordReader, err = NewRecordReader(bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x03, 'a'}), FramingFixed32)
+	require.NoError(t, err)
+	_, err = recordReader.ReadRecord()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	recordReader, err = NewRecordReader(bytes.NewReader([]byte("12")), FramingRecordIO)
+	require.NoError(t, err)
+	_, err = recordReader.ReadRecord()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestFramingRecordIOInvalidSize(t *testing.T) {
+	t.Parallel()
+	recordReader, err := NewRecordReader(bytes.NewReader([]byte("3\nabcx\nd")), FramingRecordIO)
+	require.NoError(t, err)
+	record, err := recordReader.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abc"), record)
+	_, err = recordReader.ReadRecord()
+	assert.EqualError(t, err, `invalid record size "x"`)
+}
+
+func TestFramingConnectEndStream(t *testing.T) {
+	t.Parallel()
+	data := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x01, 'a',
+		0x02, 0x00, 0x00, 0x00, 0x02, '{', '}',
+	}
+	recordReader, err := NewRecordReader(bytes.NewReader(data), FramingConnect)
+	require.NoError(t, err)
+	record, err := recordReader.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), record)
+	_, err = recordReader.ReadRecord()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,353 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Selection is a value within a message addressed by a path.
+type Selection interface {
+	// Message returns the selected value if it is a message.
+	Message() (proto.Message, bool)
+	// MarshalJSONValue marshals the selected value as JSON.
+	//
+	// newJSONMarshaler must return a JSON marshaler with the given options added.
+	MarshalJSONValue(newJSONMarshaler func(...protoencoding.JSONMarshalerOption) protoencoding.Marshaler) ([]byte, error)
+
+	isSelection()
+}
+
+// SelectPath selects the value addressed by the path within the message.
+//
+// A path is a sequence of field names separated by dots. A repeated field may be
+// followed by a zero-based index in brackets, and a map field may be followed by a
+// key in brackets, for example "foo.bar[2].baz" or `labels["env"]`. Fields may be
+// named by either their Protobuf or JSON names.
+func SelectPath(message proto.Message, path string) (Selection, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	current := message.ProtoReflect()
+	for i, segment := range segments {
+		selection, err := selectSegment(current, segment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatPath(segments[:i+1]), err)
+		}
+		if i == len(segments)-1 {
+			return selection, nil
+		}
+		selectedMessage, ok := selection.Message()
+		if !ok {
+			return nil, fmt.Errorf("%s: not a message", formatPath(segments[:i+1]))
+		}
+		current = selectedMessage.ProtoReflect()
+	}
+	// parsePath never returns an empty path.
+	return nil, errors.New("empty path")
+}
+
+// *** PRIVATE ***
+
+type pathSegment struct {
+	name string
+	// key is the contents of the brackets following the name, if any.
+	key    string
+	hasKey bool
+}
+
+func (p pathSegment) String() string {
+	if p.hasKey {
+		return p.name + "[" + p.key + "]"
+	}
+	return p.name
+}
+
+type selection struct {
+	parent     protoreflect.Message
+	field      protoreflect.FieldDescriptor
+	value      protoreflect.Value
+	listIndex  bool
+	mapKey     protoreflect.MapKey
+	isMapValue bool
+}
+
+func (s *selection) Message() (proto.Message, bool) {
+	var isMessage bool
+	switch {
+	case s.isMapValue:
+		isMessage = s.field.MapValue().Message() != nil
+	case s.field.IsMap():
+		isMessage = false
+	case s.field.IsList():
+		isMessage = s.listIndex && s.field.Message() != nil
+	default:
+		isMessage = s.field.Message() != nil
+	}
+	if !isMessage {
+		return nil, false
+	}
+	message := s.value.Message()
+	if !message.IsValid() {
+		// The field is unset, select an empty message instead of a nil one.
+		message = message.Type().New()
+	}
+	return message.Interface(), true
+}
+
+func (s *selection) MarshalJSONValue(newJSONMarshaler func(...protoencoding.JSONMarshalerOption) protoencoding.Marshaler) ([]byte, error) {
+	// protojson can only marshal messages, so we marshal a message of the parent type
+	// with only the selected value set, and pull the value back out of the result.
+	container := s.parent.Type().New()
+	switch {
+	case s.listIndex:
+		container.Mutable(s.field).List().Append(s.value)
+	case s.isMapValue:
+		container.Mutable(s.field).Map().Set(s.mapKey, s.value)
+	case s.field.IsList():
+		list := s.value.List()
+		containerList := container.Mutable(s.field).List()
+		for i := 0; i < list.Len(); i++ {
+			containerList.Append(list.Get(i))
+		}
+	case s.field.IsMap():
+		containerMap := container.Mutable(s.field).Map()
+		s.value.Map().Range(
+			func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				containerMap.Set(key, value)
+				return true
+			},
+		)
+	case s.parent.Has(s.field):
+		container.Set(s.field, s.value)
+	}
+	value, ok, err := marshalJSONField(newJSONMarshaler(), container, s.field)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// The selected value is the default for its field, so protojson left it out.
+		// We only emit unpopulated fields now so that the defaults of fields nested
+		// within a populated value are not printed.
+		value, ok, err = marshalJSONField(
+			newJSONMarshaler(protoencoding.JSONMarshalerWithEmitUnpopulated()),
+			container,
+			s.field,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("field %q missing from JSON output", s.field.FullName())
+		}
+	}
+	switch {
+	case s.listIndex:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return nil, err
+		}
+		if len(elements) != 1 {
+			return nil, fmt.Errorf("expected 1 element for field %q but got %d", s.field.FullName(), len(elements))
+		}
+		return elements[0], nil
+	case s.isMapValue:
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(value, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			return entry, nil
+		}
+		return nil, fmt.Errorf("expected 1 entry for field %q but got 0", s.field.FullName())
+	default:
+		return value, nil
+	}
+}
+
+func (*selection) isSelection() {}
+
+func marshalJSONField(
+	marshaler protoencoding.Marshaler,
+	message protoreflect.Message,
+	field protoreflect.FieldDescriptor,
+) (json.RawMessage, bool, error) {
+	data, err := marshaler.Marshal(message.Interface())
+	if err != nil {
+		return nil, false, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, err
+	}
+	value, ok := fields[field.JSONName()]
+	if !ok {
+		value, ok = fields[string(field.Name())]
+	}
+	return value, ok, nil
+}
+
+func selectSegment(message protoreflect.Message, segment pathSegment) (*selection, error) {
+	fields := message.Descriptor().Fields()
+	field := fields.ByName(protoreflect.Name(segment.name))
+	if field == nil {
+		field = fields.ByJSONName(segment.name)
+	}
+	if field == nil {
+		return nil, fmt.Errorf("no field %q in message %q", segment.name, message.Descriptor().FullName())
+	}
+	value := message.Get(field)
+	if !segment.hasKey {
+		return &selection{
+			parent: message,
+			field:  field,
+			value:  value,
+		}, nil
+	}
+	switch {
+	case field.IsList():
+		index, err := strconv.Atoi(segment.key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", segment.key)
+		}
+		list := value.List()
+		if index < 0 || index >= list.Len() {
+			return nil, fmt.Errorf("index %d out of range for list of length %d", index, list.Len())
+		}
+		return &selection{
+			parent:    message,
+			field:     field,
+			value:     list.Get(index),
+			listIndex: true,
+		}, nil
+	case field.IsMap():
+		mapKey, err := parseMapKey(field.MapKey(), segment.key)
+		if err != nil {
+			return nil, err
+		}
+		mapValue := value.Map().Get(mapKey)
+		if !mapValue.IsValid() {
+			return nil, fmt.Errorf("no entry with key %s", segment.key)
+		}
+		return &selection{
+			parent:     message,
+			field:      field,
+			value:      mapValue,
+			mapKey:     mapKey,
+			isMapValue: true,
+		}, nil
+	default:
+		return nil, fmt.Errorf("field %q is not a repeated or map field", field.FullName())
+	}
+}
+
+func parseMapKey(keyField protoreflect.FieldDescriptor, key string) (protoreflect.MapKey, error) {
+	var value protoreflect.Value
+	switch keyField.Kind() {
+	case protoreflect.StringKind:
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		value = protoreflect.ValueOfString(key)
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid bool key %q", key)
+		}
+		value = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(key, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid int32 key %q", key)
+		}
+		value = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid int64 key %q", key)
+		}
+		value = protoreflect.ValueOfInt64(i)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		u, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid uint32 key %q", key)
+		}
+		value = protoreflect.ValueOfUint32(uint32(u))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		u, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid uint64 key %q", key)
+		}
+		value = protoreflect.ValueOfUint64(u)
+	default:
+		return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %v", keyField.Kind())
+	}
+	return value.MapKey(), nil
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+	var segments []pathSegment
+	for remaining := path; ; {
+		// Map keys may contain dots, so we cannot just split on them.
+		end := strings.IndexAny(remaining, ".[")
+		if end < 0 {
+			end = len(remaining)
+		}
+		segment := pathSegment{
+			name: remaining[:end],
+		}
+		if segment.name == "" {
+			return nil, fmt.Errorf("invalid path %q: empty field name", path)
+		}
+		remaining = remaining[end:]
+		if strings.HasPrefix(remaining, "[") {
+			closing := strings.IndexByte(remaining, ']')
+			if closing < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed bracket", path)
+			}
+			segment.key = remaining[1:closing]
+			segment.hasKey = true
+			remaining = remaining[closing+1:]
+		}
+		segments = append(segments, segment)
+		if remaining == "" {
+			return segments, nil
+		}
+		if !strings.HasPrefix(remaining, ".") {
+			return nil, fmt.Errorf("invalid path %q: expected %q after %q", path, ".", segment)
+		}
+		remaining = remaining[1:]
+	}
+}
+
+func formatPath(segments []pathSegment) string {
+	strs := make([]string, len(segments))
+	for i, segment := range segments {
+		strs[i] = segment.String()
+	}
+	return strings.Join(strs, ".")
+}
@@ -0,0 +1,95 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconvert
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSelectPath(t *testing.T) {
+	t.Parallel()
+	message := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("foo.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("one"),
+						Number: proto.Int32(1),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+					},
+					{
+						Name:   proto.String("two"),
+						Number: proto.Int32(2),
+					},
+				},
+			},
+		},
+	}
+	testSelectPathJSON(t, message, "name", `"foo.proto"`)
+	testSelectPathJSON(t, message, "message_type[0].field[0].type", `"TYPE_INT64"`)
+	testSelectPathJSON(t, message, "messageType[0].field[1].number", `2`)
+	// Unset fields select their default value.
+	testSelectPathJSON(t, message, "message_type[0].field[1].type", `null`)
+	testSelectPathJSON(t, message, "dependency", `[]`)
+	testSelectPathJSON(t, message, "message_type[0].field[1].json_name", `null`)
+
+	selection, err := SelectPath(message, "message_type[0].field[1]")
+	require.NoError(t, err)
+	selectedMessage, ok := selection.Message()
+	require.True(t, ok)
+	assert.True(t, proto.Equal(message.GetMessageType()[0].GetField()[1], selectedMessage))
+	selection, err = SelectPath(message, "options")
+	require.NoError(t, err)
+	selectedMessage, ok = selection.Message()
+	require.True(t, ok)
+	assert.True(t, proto.Equal(&descriptorpb.FileOptions{}, selectedMessage))
+	selection, err = SelectPath(message, "message_type")
+	require.NoError(t, err)
+	_, ok = selection.Message()
+	assert.False(t, ok)
+
+	_, err = SelectPath(message, "message_type[1]")
+	assert.EqualError(t, err, "message_type[1]: index 1 out of range for list of length 1")
+	_, err = SelectPath(message, "message_type.name")
+	assert.EqualError(t, err, "message_type: not a message")
+	_, err = SelectPath(message, "name[0]")
+	assert.EqualError(t, err, `name[0]: field "google.protobuf.FileDescriptorProto.name" is not a repeated or map field`)
+	_, err = SelectPath(message, "nope")
+	assert.EqualError(t, err, `nope: no field "nope" in message "google.protobuf.FileDescriptorProto"`)
+	_, err = SelectPath(message, "message_type[0")
+	assert.EqualError(t, err, `invalid path "message_type[0": unclosed bracket`)
+	_, err = SelectPath(message, "message_type..name")
+	assert.EqualError(t, err, `invalid path "message_type..name": empty field name`)
+}
+
+func testSelectPathJSON(t *testing.T, message proto.Message, path string, expected string) {
+	selection, err := SelectPath(message, path)
+	require.NoError(t, err)
+	data, err := selection.MarshalJSONValue(
+		func(options ...protoencoding.JSONMarshalerOption) protoencoding.Marshaler {
+			return protoencoding.NewJSONMarshaler(nil, options...)
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data), path)
+}
@@ -15,16 +15,49 @@
 package bufctl
 
 import (
+	"errors"
+
 	"github.com/bufbuild/buf/private/pkg/app"
 )
 
+// The exit codes below are stable, and can be used by CI pipelines to branch on the class
+// of a failure. Any other failure exits with 1.
 const (
+	// ExitCodeConfigError is the exit code used when a configuration file could not be read.
+	ExitCodeConfigError = 2
+	// ExitCodeNetworkFailure is the exit code used when a remote could not be reached or rate limited us.
+	ExitCodeNetworkFailure = 3
+	// ExitCodeAuthFailure is the exit code used when the user is not authenticated for a remote.
+	ExitCodeAuthFailure = 4
+	// ExitCodeInternalError is the exit code used when we hit a bug in buf.
+	ExitCodeInternalError = 5
 	// ExitCodeFileAnnotation is the exit code used when we print file annotations.
 	//
 	// We use a different exit code to be able to distinguish user-parsable errors from system errors.
 	//
 	// TODO FUTURE: Rename to something like "ExitCodeCompileError" as we use this for ImportNotExistErrors as well.
 	ExitCodeFileAnnotation = 100
+	// ExitCodeLintViolation is the exit code used when buf lint finds violations.
+	//
+	// This is the same as ExitCodeFileAnnotation for backwards compatibility.
+	ExitCodeLintViolation = ExitCodeFileAnnotation
+	// ExitCodeBreakingViolation is the exit code used when buf breaking finds violations.
+	//
+	// This is the same as ExitCodeFileAnnotation for backwards compatibility. Use the
+	// error code in the JSON output to distinguish breaking violations.
+	ExitCodeBreakingViolation = ExitCodeFileAnnotation
+)
+
+// The error codes below are attached to errors in the JSON output of --format=json.
+const (
+	ErrorCodeFailure           = "failure"
+	ErrorCodeConfigError       = "config_error"
+	ErrorCodeNetworkFailure    = "network_failure"
+	ErrorCodeAuthFailure       = "auth_failure"
+	ErrorCodeInternalError     = "internal_error"
+	ErrorCodeFileAnnotation    = "file_annotation"
+	ErrorCodeLintViolation     = "lint_violation"
+	ErrorCodeBreakingViolation = "breaking_violation"
 )
 
 var (
@@ -36,4 +69,36 @@ var (
 	//
 	// We also exit with 100 to be able to distinguish user-parsable errors from system errors.
 	ErrFileAnnotation = app.NewError(ExitCodeFileAnnotation, "")
+	// ErrLintViolation is used when buf lint prints violations.
+	ErrLintViolation = app.NewError(ExitCodeLintViolation, "")
+	// ErrBreakingViolation is used when buf breaking prints violations.
+	ErrBreakingViolation = app.NewError(ExitCodeBreakingViolation, "")
 )
+
+// GetErrorCode returns the machine-readable error code for the error.
+//
+// If err == nil, this returns the empty string.
+func GetErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrLintViolation):
+		return ErrorCodeLintViolation
+	case errors.Is(err, ErrBreakingViolation):
+		return ErrorCodeBreakingViolation
+	}
+	switch app.GetExitCode(err) {
+	case ExitCodeConfigError:
+		return ErrorCodeConfigError
+	case ExitCodeNetworkFailure:
+		return ErrorCodeNetworkFailure
+	case ExitCodeAuthFailure:
+		return ErrorCodeAuthFailure
+	case ExitCodeInternalError:
+		return ErrorCodeInternalError
+	case ExitCodeFileAnnotation:
+		return ErrorCodeFileAnnotation
+	default:
+		return ErrorCodeFailure
+	}
+}
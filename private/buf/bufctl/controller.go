@@ -15,6 +15,7 @@
 package bufctl
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"sort"
 
 	"buf.build/go/protoyaml"
+	"github.com/bufbuild/buf/private/buf/bufconvert"
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/buf/bufwkt/bufwktstore"
 	"github.com/bufbuild/buf/private/buf/bufworkspace"
@@ -47,6 +49,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 	"github.com/bufbuild/protovalidate-go"
 	"go.uber.org/multierr"
 	"google.golang.org/protobuf/proto"
@@ -128,6 +131,28 @@ type Controller interface {
 		defaultMessageEncoding buffetch.MessageEncoding,
 		options ...FunctionOption,
 	) error
+	// StreamMessages reads a stream of messages of the given type from messageInput and
+	// writes each message to messageOutput as soon as it is read, so that the stream
+	// never has to fit in memory.
+	//
+	// Binary messages are delimited with inputFraming when read and outputFraming
+	// when written, and JSON messages are newline-delimited. Other encodings cannot
+	// be streamed.
+	//
+	// If message validation is enabled, the stream stops at the first invalid message,
+	// and the violations are returned with the index of the message.
+	StreamMessages(
+		ctx context.Context,
+		schemaImage bufimage.Image,
+		messageInput string,
+		messageOutput string,
+		typeName string,
+		inputFraming bufconvert.Framing,
+		outputFraming bufconvert.Framing,
+		defaultInputMessageEncoding buffetch.MessageEncoding,
+		defaultOutputMessageEncoding func(buffetch.MessageEncoding) (buffetch.MessageEncoding, error),
+		options ...FunctionOption,
+	) error
 }
 
 func NewController(
@@ -176,6 +201,7 @@ type controller struct {
 	disableSymlinks           bool
 	fileAnnotationErrorFormat string
 	fileAnnotationsToStdout   bool
+	colorMode                 termstyle.ColorMode
 	copyToInMemory            bool
 
 	commandRunner               command.Runner
@@ -540,7 +566,7 @@ func (c *controller) PutImage(
 	if messageRef.IsNull() {
 		return nil
 	}
-	marshaler, err := newProtoencodingMarshaler(image, messageRef)
+	marshaler, err := newProtoencodingMarshaler(image.Resolver(), messageRef)
 	if err != nil {
 		return err
 	}
@@ -585,15 +611,7 @@ func (c *controller) GetMessage(
 	for _, option := range options {
 		option(functionOptions)
 	}
-	// Must be messageRefParser NOT c.buffetchRefParser as a NewMessageRefParser
-	// defaults to a defaultMessageEncoding and not dir.
-	messageRefParser := buffetch.NewMessageRefParser(
-		c.logger,
-		buffetch.MessageRefParserWithDefaultMessageEncoding(
-			defaultMessageEncoding,
-		),
-	)
-	messageRef, err := messageRefParser.GetMessageRef(ctx, messageInput)
+	messageRef, err := c.getMessageRef(ctx, messageInput, defaultMessageEncoding)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -609,25 +627,12 @@ func (c *controller) GetMessage(
 			return nil, 0, err
 		}
 	}
-	var unmarshaler protoencoding.Unmarshaler
-	switch messageEncoding {
-	case buffetch.MessageEncodingBinpb:
-		unmarshaler = protoencoding.NewWireUnmarshaler(schemaImage.Resolver())
-	case buffetch.MessageEncodingJSON:
-		unmarshaler = protoencoding.NewJSONUnmarshaler(schemaImage.Resolver())
-	case buffetch.MessageEncodingTxtpb:
-		unmarshaler = protoencoding.NewTxtpbUnmarshaler(schemaImage.Resolver())
-	case buffetch.MessageEncodingYAML:
-		unmarshaler = protoencoding.NewYAMLUnmarshaler(
-			schemaImage.Resolver(),
-			protoencoding.YAMLUnmarshalerWithPath(messageRef.Path()),
-			// This will pretty print validation errors.
-			protoencoding.YAMLUnmarshalerWithValidator(validator),
-		)
+	unmarshaler, err := newProtoencodingUnmarshaler(schemaImage.Resolver(), messageRef, validator)
+	if err != nil {
+		return nil, 0, err
+	}
+	if messageEncoding == buffetch.MessageEncodingYAML {
 		validator = nil // Validation errors are handled by the unmarshaler.
-	default:
-		// This is a system error.
-		return nil, 0, syserror.Newf("unknown MessageEncoding: %v", messageEncoding)
 	}
 	readCloser, err := c.buffetchReader.GetMessageFile(ctx, c.container, messageRef)
 	if err != nil {
@@ -668,6 +673,131 @@ func (c *controller) PutMessage(
 	for _, option := range options {
 		option(functionOptions)
 	}
+	messageRef, err := c.getMessageRef(ctx, messageOutput, defaultMessageEncoding)
+	if err != nil {
+		return err
+	}
+	if messageRef.IsNull() {
+		return nil
+	}
+	data, err := marshalMessage(schemaImage, messageRef, message, functionOptions)
+	if err != nil {
+		return err
+	}
+	writeCloser, err := c.buffetchWriter.PutMessageFile(ctx, c.container, messageRef)
+	if err != nil {
+		return err
+	}
+	_, err = writeCloser.Write(data)
+	return multierr.Append(err, writeCloser.Close())
+}
+
+func (c *controller) StreamMessages(
+	ctx context.Context,
+	schemaImage bufimage.Image,
+	messageInput string,
+	messageOutput string,
+	typeName string,
+	inputFraming bufconvert.Framing,
+	outputFraming bufconvert.Framing,
+	defaultInputMessageEncoding buffetch.MessageEncoding,
+	defaultOutputMessageEncoding func(buffetch.MessageEncoding) (buffetch.MessageEncoding, error),
+	options ...FunctionOption,
+) (retErr error) {
+	defer c.handleFileAnnotationSetRetError(&retErr)
+	functionOptions := newFunctionOptions(c)
+	for _, option := range options {
+		option(functionOptions)
+	}
+	inputMessageRef, outputMessageRef, err := c.getStreamMessageRefs(
+		ctx,
+		messageInput,
+		messageOutput,
+		defaultInputMessageEncoding,
+		defaultOutputMessageEncoding,
+	)
+	if err != nil {
+		return err
+	}
+	if inputMessageRef == nil {
+		return nil
+	}
+	// Validation errors are reported per record rather than by the unmarshaler.
+	unmarshaler, err := newProtoencodingUnmarshaler(schemaImage.Resolver(), inputMessageRef, nil)
+	if err != nil {
+		return err
+	}
+	var validator protoyaml.Validator
+	if functionOptions.messageValidation {
+		validator, err = protovalidate.New()
+		if err != nil {
+			return err
+		}
+	}
+	readCloser, err := c.buffetchReader.GetMessageFile(ctx, c.container, inputMessageRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	recordReader, err := newRecordReader(readCloser, inputMessageRef, inputFraming)
+	if err != nil {
+		return err
+	}
+	var writeCloser io.WriteCloser = ioext.NopWriteCloser(io.Discard)
+	if !outputMessageRef.IsNull() {
+		writeCloser, err = c.buffetchWriter.PutMessageFile(ctx, c.container, outputMessageRef)
+		if err != nil {
+			return err
+		}
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	// Buffer the output so that small records do not each cost a write.
+	bufferedWriter := bufio.NewWriter(writeCloser)
+	recordWriter, err := newRecordWriter(bufferedWriter, outputMessageRef, outputFraming)
+	if err != nil {
+		return err
+	}
+	for index := 0; ; index++ {
+		data, err := recordReader.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		message, err := bufreflect.NewMessage(ctx, schemaImage, typeName)
+		if err != nil {
+			return err
+		}
+		if err := unmarshaler.Unmarshal(data, message); err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		if validator != nil {
+			if err := validator.Validate(message); err != nil {
+				return fmt.Errorf("record %d: %w", index, err)
+			}
+		}
+		data, err = marshalMessage(schemaImage, outputMessageRef, message, functionOptions)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		if err := recordWriter.WriteRecord(data); err != nil {
+			return err
+		}
+	}
+	return bufferedWriter.Flush()
+}
+
+// getMessageRef parses the MessageRef for a message input or output.
+func (c *controller) getMessageRef(
+	ctx context.Context,
+	value string,
+	defaultMessageEncoding buffetch.MessageEncoding,
+) (buffetch.MessageRef, error) {
 	// Must be messageRefParser NOT c.buffetchRefParser as a NewMessageRefParser
 	// defaults to a defaultMessageEncoding and not dir.
 	messageRefParser := buffetch.NewMessageRefParser(
@@ -676,27 +806,42 @@ func (c *controller) PutMessage(
 			defaultMessageEncoding,
 		),
 	)
-	messageRef, err := messageRefParser.GetMessageRef(ctx, messageOutput)
+	return messageRefParser.GetMessageRef(ctx, value)
+}
+
+// getStreamMessageRefs parses the MessageRefs for the input and output of StreamMessages,
+// and checks that both of their MessageEncodings can be streamed.
+//
+// Returns nil MessageRefs if the input is null, in which case there is nothing to stream.
+func (c *controller) getStreamMessageRefs(
+	ctx context.Context,
+	messageInput string,
+	messageOutput string,
+	defaultInputMessageEncoding buffetch.MessageEncoding,
+	defaultOutputMessageEncoding func(buffetch.MessageEncoding) (buffetch.MessageEncoding, error),
+) (buffetch.MessageRef, buffetch.MessageRef, error) {
+	inputMessageRef, err := c.getMessageRef(ctx, messageInput, defaultInputMessageEncoding)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if messageRef.IsNull() {
-		return nil
+	if inputMessageRef.IsNull() {
+		return nil, nil, nil
 	}
-	marshaler, err := newProtoencodingMarshaler(schemaImage, messageRef)
+	outputMessageEncoding, err := defaultOutputMessageEncoding(inputMessageRef.MessageEncoding())
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	data, err := marshaler.Marshal(message)
+	outputMessageRef, err := c.getMessageRef(ctx, messageOutput, outputMessageEncoding)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	writeCloser, err := c.buffetchWriter.PutMessageFile(ctx, c.container, messageRef)
-	if err != nil {
-		return err
+	if err := validateStreamMessageEncoding(messageInput, inputMessageRef); err != nil {
+		return nil, nil, err
 	}
-	_, err = writeCloser.Write(data)
-	return multierr.Append(err, writeCloser.Close())
+	if err := validateStreamMessageEncoding(messageOutput, outputMessageRef); err != nil {
+		return nil, nil, err
+	}
+	return inputMessageRef, outputMessageRef, nil
 }
 
 func (c *controller) getImage(
@@ -1170,6 +1315,9 @@ func (c *controller) handleFileAnnotationSetRetError(retErrAddr *error) {
 			writer,
 			fileAnnotationSet,
 			c.fileAnnotationErrorFormat,
+			bufanalysis.PrintFileAnnotationSetWithStyler(
+				termstyle.NewStylerForWriter(c.colorMode, c.container, writer),
+			),
 		); err != nil {
 			*retErrAddr = err
 			return
@@ -1260,27 +1408,148 @@ func newStorageosProvider(disableSymlinks bool) storageos.Provider {
 }
 
 func newProtoencodingMarshaler(
-	image bufimage.Image,
+	resolver protoencoding.Resolver,
 	messageRef buffetch.MessageRef,
+	jsonMarshalerOptions ...protoencoding.JSONMarshalerOption,
 ) (protoencoding.Marshaler, error) {
 	switch messageEncoding := messageRef.MessageEncoding(); messageEncoding {
 	case buffetch.MessageEncodingBinpb:
 		return protoencoding.NewWireMarshaler(), nil
 	case buffetch.MessageEncodingJSON:
-		return newJSONMarshaler(image.Resolver(), messageRef), nil
+		return newJSONMarshaler(resolver, messageRef, jsonMarshalerOptions...), nil
+	case buffetch.MessageEncodingTxtpb:
+		return protoencoding.NewTxtpbMarshaler(resolver), nil
+	case buffetch.MessageEncodingYAML:
+		return newYAMLMarshaler(resolver, messageRef), nil
+	default:
+		// This is a system error.
+		return nil, syserror.Newf("unknown MessageEncoding: %v", messageEncoding)
+	}
+}
+
+// newProtoencodingUnmarshaler returns the Unmarshaler for the MessageEncoding of the MessageRef.
+//
+// The validator is only used by the YAML Unmarshaler, so that validation errors are printed
+// with their position in the file. It may be nil.
+func newProtoencodingUnmarshaler(
+	resolver protoencoding.Resolver,
+	messageRef buffetch.MessageRef,
+	validator protoyaml.Validator,
+) (protoencoding.Unmarshaler, error) {
+	switch messageEncoding := messageRef.MessageEncoding(); messageEncoding {
+	case buffetch.MessageEncodingBinpb:
+		return protoencoding.NewWireUnmarshaler(resolver), nil
+	case buffetch.MessageEncodingJSON:
+		return protoencoding.NewJSONUnmarshaler(resolver), nil
 	case buffetch.MessageEncodingTxtpb:
-		return protoencoding.NewTxtpbMarshaler(image.Resolver()), nil
+		return protoencoding.NewTxtpbUnmarshaler(resolver), nil
 	case buffetch.MessageEncodingYAML:
-		return newYAMLMarshaler(image.Resolver(), messageRef), nil
+		return protoencoding.NewYAMLUnmarshaler(
+			resolver,
+			protoencoding.YAMLUnmarshalerWithPath(messageRef.Path()),
+			// This will pretty print validation errors.
+			protoencoding.YAMLUnmarshalerWithValidator(validator),
+		), nil
 	default:
 		// This is a system error.
 		return nil, syserror.Newf("unknown MessageEncoding: %v", messageEncoding)
 	}
 }
 
+// validateStreamMessageEncoding returns an error if messages with the MessageEncoding of
+// the MessageRef parsed from value cannot be streamed.
+func validateStreamMessageEncoding(value string, messageRef buffetch.MessageRef) error {
+	switch messageRef.MessageEncoding() {
+	case buffetch.MessageEncodingBinpb, buffetch.MessageEncodingJSON:
+		return nil
+	default:
+		return fmt.Errorf("%q: only binpb and json messages can be streamed", value)
+	}
+}
+
+// newRecordReader returns the RecordReader for a stream of messages with the MessageEncoding
+// of the MessageRef. Binary messages are delimited with the Framing, and JSON messages are
+// read one value at a time.
+func newRecordReader(
+	reader io.Reader,
+	messageRef buffetch.MessageRef,
+	framing bufconvert.Framing,
+) (bufconvert.RecordReader, error) {
+	if messageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		return bufconvert.NewJSONRecordReader(reader), nil
+	}
+	return bufconvert.NewRecordReader(reader, framing)
+}
+
+// newRecordWriter returns the RecordWriter for a stream of messages with the MessageEncoding
+// of the MessageRef. Binary messages are delimited with the Framing, and JSON messages are
+// newline-delimited.
+func newRecordWriter(
+	writer io.Writer,
+	messageRef buffetch.MessageRef,
+	framing bufconvert.Framing,
+) (bufconvert.RecordWriter, error) {
+	if messageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		return bufconvert.NewLineRecordWriter(writer), nil
+	}
+	return bufconvert.NewRecordWriter(writer, framing)
+}
+
+// marshalMessage marshals the message, or the value at messageSelectPath within
+// the message if set, with the MessageEncoding of the MessageRef.
+func marshalMessage(
+	schemaImage bufimage.Image,
+	messageRef buffetch.MessageRef,
+	message proto.Message,
+	functionOptions *functionOptions,
+) ([]byte, error) {
+	if functionOptions.messageJSONCanonical && messageRef.MessageEncoding() != buffetch.MessageEncodingJSON {
+		return nil, errors.New("canonical output is only supported for json")
+	}
+	var jsonMarshalerOptions []protoencoding.JSONMarshalerOption
+	if functionOptions.messageJSONCanonical {
+		jsonMarshalerOptions = append(jsonMarshalerOptions, protoencoding.JSONMarshalerWithCanonical())
+	}
+	resolver := schemaImage.Resolver()
+	// The binary encoding does not look inside of Anys, so they only need to be
+	// resolved for the other encodings.
+	if messageRef.MessageEncoding() != buffetch.MessageEncodingBinpb {
+		var err error
+		message, resolver, err = bufconvert.ResolveAnys(message, resolver, functionOptions.messageUnknownAny)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if messageSelectPath := functionOptions.messageSelectPath; messageSelectPath != "" {
+		selection, err := bufconvert.SelectPath(message, messageSelectPath)
+		if err != nil {
+			return nil, err
+		}
+		selectedMessage, ok := selection.Message()
+		if !ok {
+			// Only JSON can represent a value that is not a message on its own.
+			if messageRef.MessageEncoding() != buffetch.MessageEncodingJSON {
+				return nil, fmt.Errorf("%q does not select a message and can only be written as json", messageSelectPath)
+			}
+			return selection.MarshalJSONValue(
+				func(options ...protoencoding.JSONMarshalerOption) protoencoding.Marshaler {
+					return newJSONMarshaler(resolver, messageRef, append(jsonMarshalerOptions, options...)...)
+				},
+			)
+		}
+		message = selectedMessage
+	}
+	marshaler, err := newProtoencodingMarshaler(resolver, messageRef, jsonMarshalerOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return marshaler.Marshal(message)
+}
+
 func newJSONMarshaler(
 	resolver protoencoding.Resolver,
 	messageRef buffetch.MessageRef,
+	options ...protoencoding.JSONMarshalerOption,
 ) protoencoding.Marshaler {
 	jsonMarshalerOptions := []protoencoding.JSONMarshalerOption{
 		//protoencoding.JSONMarshalerWithIndent(),
@@ -1297,6 +1566,7 @@ func newJSONMarshaler(
 			protoencoding.JSONMarshalerWithUseEnumNumbers(),
 		)
 	}
+	jsonMarshalerOptions = append(jsonMarshalerOptions, options...)
 	return protoencoding.NewJSONMarshaler(resolver, jsonMarshalerOptions...)
 }
 
@@ -15,7 +15,9 @@
 package bufctl
 
 import (
+	"github.com/bufbuild/buf/private/buf/bufconvert"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 )
 
 type ControllerOption func(*controller)
@@ -38,6 +40,12 @@ func WithFileAnnotationsToStdout() ControllerOption {
 	}
 }
 
+func WithColorMode(colorMode termstyle.ColorMode) ControllerOption {
+	return func(controller *controller) {
+		controller.colorMode = colorMode
+	}
+}
+
 func WithCopyToInMemory() ControllerOption {
 	return func(controller *controller) {
 		controller.copyToInMemory = true
@@ -124,6 +132,36 @@ func WithMessageValidation() FunctionOption {
 	}
 }
 
+// WithMessageSelectPath returns a new FunctionOption that says to only write the
+// value at the given path within the message, as parsed by bufconvert.SelectPath.
+func WithMessageSelectPath(messageSelectPath string) FunctionOption {
+	return func(functionOptions *functionOptions) {
+		functionOptions.messageSelectPath = messageSelectPath
+	}
+}
+
+// WithMessageUnknownAny returns a new FunctionOption that says how to handle a
+// google.protobuf.Any holding a type that cannot be resolved when writing a message
+// as JSON, YAML, or text, as done by bufconvert.ResolveAnys.
+//
+// The default is bufconvert.UnknownAnyError.
+func WithMessageUnknownAny(unknownAny bufconvert.UnknownAny) FunctionOption {
+	return func(functionOptions *functionOptions) {
+		functionOptions.messageUnknownAny = unknownAny
+	}
+}
+
+// WithMessageJSONCanonical returns a new FunctionOption that says to write a
+// message as JSON in the canonical form of RFC 8785, with object keys sorted and
+// numbers in a fixed format, so that the output is byte-for-byte stable.
+//
+// Writing a message with any other encoding is an error.
+func WithMessageJSONCanonical(messageJSONCanonical bool) FunctionOption {
+	return func(functionOptions *functionOptions) {
+		functionOptions.messageJSONCanonical = messageJSONCanonical
+	}
+}
+
 // *** PRIVATE ***
 
 type functionOptions struct {
@@ -138,11 +176,15 @@ type functionOptions struct {
 	configOverride                  string
 	ignoreAndDisallowV1BufWorkYAMLs bool
 	messageValidation               bool
+	messageSelectPath               string
+	messageUnknownAny               bufconvert.UnknownAny
+	messageJSONCanonical            bool
 }
 
 func newFunctionOptions(controller *controller) *functionOptions {
 	return &functionOptions{
-		copyToInMemory: controller.copyToInMemory,
+		copyToInMemory:    controller.copyToInMemory,
+		messageUnknownAny: bufconvert.UnknownAnyError,
 	}
 }
 
@@ -0,0 +1,120 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+)
+
+// BatchRequest is a single RPC in a batch request file.
+type BatchRequest struct {
+	// Name is the name of the RPC, used when reporting its result.
+	Name string
+	// URL is the URL of the method to invoke.
+	URL string
+	// Headers are the request headers, in the same form as the values of
+	// the --header flag.
+	Headers []string
+	// Data is the request data, in the same form as the value of the --data flag.
+	Data string
+}
+
+// ParseBatchRequests parses the contents of a batch request file.
+//
+// The file is YAML or JSON, and lists the RPCs to invoke:
+//
+//	requests:
+//	  - name: say hello
+//	    url: https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say
+//	    headers:
+//	      - "Custom-Header: value"
+//	    data:
+//	      sentence: hello
+//	  - url: https://demo.connectrpc.com
+//	    method: connectrpc.eliza.v1.ElizaService/Say
+//	    data: '{"sentence": "goodbye"}'
+//
+// If a method is given, the URL is the base URL of the server. The data is
+// either a string, which is used as-is, or an object, which is converted to
+// JSON. The name defaults to the URL of the method.
+func ParseBatchRequests(data []byte) ([]BatchRequest, error) {
+	var externalBatchFile externalBatchFile
+	if err := encoding.UnmarshalJSONOrYAMLStrict(data, &externalBatchFile); err != nil {
+		return nil, err
+	}
+	if len(externalBatchFile.Requests) == 0 {
+		return nil, errors.New("no requests")
+	}
+	batchRequests := make([]BatchRequest, len(externalBatchFile.Requests))
+	for i, externalBatchRequest := range externalBatchFile.Requests {
+		batchRequest, err := newBatchRequest(externalBatchRequest)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+		batchRequests[i] = batchRequest
+	}
+	return batchRequests, nil
+}
+
+// *** PRIVATE ***
+
+type externalBatchFile struct {
+	Requests []externalBatchRequest `json:"requests,omitempty" yaml:"requests,omitempty"`
+}
+
+type externalBatchRequest struct {
+	Name    string      `json:"name,omitempty" yaml:"name,omitempty"`
+	URL     string      `json:"url,omitempty" yaml:"url,omitempty"`
+	Method  string      `json:"method,omitempty" yaml:"method,omitempty"`
+	Headers []string    `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Data    interface{} `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+func newBatchRequest(externalBatchRequest externalBatchRequest) (BatchRequest, error) {
+	url := externalBatchRequest.URL
+	if url == "" {
+		return BatchRequest{}, errors.New("url is required")
+	}
+	if externalBatchRequest.Method != "" {
+		url = strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(externalBatchRequest.Method, "/")
+	}
+	var data string
+	switch externalData := externalBatchRequest.Data.(type) {
+	case nil:
+	case string:
+		data = externalData
+	default:
+		dataBytes, err := json.Marshal(externalData)
+		if err != nil {
+			return BatchRequest{}, fmt.Errorf("invalid data: %w", err)
+		}
+		data = string(dataBytes)
+	}
+	name := externalBatchRequest.Name
+	if name == "" {
+		name = url
+	}
+	return BatchRequest{
+		Name:    name,
+		URL:     url,
+		Headers: externalBatchRequest.Headers,
+		Data:    data,
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchRequests(t *testing.T) {
+	t.Parallel()
+	batchRequests, err := ParseBatchRequests([]byte(`
+requests:
+  - name: say hello
+    url: https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say
+    headers:
+      - "Custom-Header: value"
+    data:
+      sentence: hello
+  - url: https://demo.connectrpc.com/
+    method: connectrpc.eliza.v1.ElizaService/Say
+    data: '{"sentence": "goodbye"}'
+  - url: https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say
+`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]BatchRequest{
+			{
+				Name:    "say hello",
+				URL:     "https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say",
+				Headers: []string{"Custom-Header: value"},
+				Data:    `{"sentence":"hello"}`,
+			},
+			{
+				Name: "https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say",
+				URL:  "https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say",
+				Data: `{"sentence": "goodbye"}`,
+			},
+			{
+				Name: "https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say",
+				URL:  "https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say",
+			},
+		},
+		batchRequests,
+	)
+}
+
+func TestParseBatchRequestsJSON(t *testing.T) {
+	t.Parallel()
+	batchRequests, err := ParseBatchRequests([]byte(`{"requests": [{"url": "http://localhost:8080/foo.v1.FooService/Bar", "data": {"ids": [1, 2]}}]}`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]BatchRequest{
+			{
+				Name: "http://localhost:8080/foo.v1.FooService/Bar",
+				URL:  "http://localhost:8080/foo.v1.FooService/Bar",
+				Data: `{"ids":[1,2]}`,
+			},
+		},
+		batchRequests,
+	)
+}
+
+func TestParseBatchRequestsError(t *testing.T) {
+	t.Parallel()
+	_, err := ParseBatchRequests([]byte(`requests: []`))
+	assert.EqualError(t, err, "no requests")
+	_, err = ParseBatchRequests([]byte("requests:\n  - name: foo\n"))
+	assert.EqualError(t, err, "request 1: url is required")
+	_, err = ParseBatchRequests([]byte("requests:\n  - url: foo\n    unknown: bar\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+const (
+	grpcWebContentTypePrefix     = "application/grpc-web"
+	grpcWebTextContentTypePrefix = "application/grpc-web-text"
+)
+
+// NewGRPCWebTextHTTPClient returns a new HTTP client that translates gRPC-Web
+// requests and responses that use binary framing, which is what the Connect
+// client library produces, to and from the base64 text framing used by
+// "application/grpc-web-text" endpoints.
+//
+// Request bodies are base64-encoded as a single stream. Response bodies may
+// be a concatenation of separately padded base64 chunks, which is how most
+// servers encode each frame of a streaming response.
+//
+// Requests that are not gRPC-Web requests are passed through unchanged.
+func NewGRPCWebTextHTTPClient(client connect.HTTPClient) connect.HTTPClient {
+	return &grpcWebTextClient{client: client}
+}
+
+type grpcWebTextClient struct {
+	client connect.HTTPClient
+}
+
+func (g *grpcWebTextClient) Do(req *http.Request) (*http.Response, error) {
+	contentType := req.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, grpcWebContentTypePrefix) {
+		return g.client.Do(req)
+	}
+	req.Header.Set("Content-Type", grpcWebTextContentTypePrefix+strings.TrimPrefix(contentType, grpcWebContentTypePrefix))
+	req.Header.Set("Accept", grpcWebTextContentTypePrefix+strings.TrimPrefix(contentType, grpcWebContentTypePrefix))
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = newBase64EncodingReader(req.Body)
+		if req.ContentLength > 0 {
+			req.ContentLength = int64(base64.StdEncoding.EncodedLen(int(req.ContentLength)))
+		}
+		// The original body can not be replayed through the encoder.
+		req.GetBody = nil
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	responseContentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(responseContentType, grpcWebTextContentTypePrefix) {
+		resp.Header.Set("Content-Type", grpcWebContentTypePrefix+strings.TrimPrefix(responseContentType, grpcWebTextContentTypePrefix))
+		resp.Body = newBase64DecodingReader(resp.Body)
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// base64EncodingReader base64-encodes the data read from the delegate.
+type base64EncodingReader struct {
+	delegate io.ReadCloser
+	// pending is the encoded data that has not been read yet.
+	pending bytes.Buffer
+	// remainder is the unencoded data that is not a multiple of three bytes,
+	// and therefore can not be encoded until more data is read.
+	remainder []byte
+	eof       bool
+}
+
+func newBase64EncodingReader(delegate io.ReadCloser) *base64EncodingReader {
+	return &base64EncodingReader{delegate: delegate}
+}
+
+func (b *base64EncodingReader) Read(data []byte) (int, error) {
+	for b.pending.Len() == 0 {
+		if b.eof {
+			return 0, io.EOF
+		}
+		buffer := make([]byte, len(data))
+		n, err := b.delegate.Read(buffer)
+		b.remainder = append(b.remainder, buffer[:n]...)
+		if err != nil {
+			if err != io.EOF {
+				return 0, err
+			}
+			b.eof = true
+			b.pending.WriteString(base64.StdEncoding.EncodeToString(b.remainder))
+			b.remainder = nil
+			continue
+		}
+		// Only encode complete groups of three bytes so that no padding is
+		// written until the end of the body.
+		complete := len(b.remainder) - len(b.remainder)%3
+		b.pending.WriteString(base64.StdEncoding.EncodeToString(b.remainder[:complete]))
+		b.remainder = b.remainder[complete:]
+	}
+	return b.pending.Read(data)
+}
+
+func (b *base64EncodingReader) Close() error {
+	return b.delegate.Close()
+}
+
+// base64DecodingReader base64-decodes the data read from the delegate.
+//
+// Unlike base64.NewDecoder, padding may occur at the end of any group of four
+// characters, not only at the end of the data.
+type base64DecodingReader struct {
+	delegate io.ReadCloser
+	// pending is the decoded data that has not been read yet.
+	pending bytes.Buffer
+	// remainder is the encoded data that is not a multiple of four characters,
+	// and therefore can not be decoded until more data is read.
+	remainder []byte
+	eof       bool
+}
+
+func newBase64DecodingReader(delegate io.ReadCloser) *base64DecodingReader {
+	return &base64DecodingReader{delegate: delegate}
+}
+
+func (b *base64DecodingReader) Read(data []byte) (int, error) {
+	for b.pending.Len() == 0 {
+		if b.eof {
+			if len(b.remainder) > 0 {
+				return 0, base64.CorruptInputError(0)
+			}
+			return 0, io.EOF
+		}
+		buffer := make([]byte, max(len(data), 4))
+		n, err := b.delegate.Read(buffer)
+		for _, c := range buffer[:n] {
+			// Ignore line breaks and other whitespace, as base64.StdEncoding does.
+			if c != '\r' && c != '\n' && c != ' ' && c != '\t' {
+				b.remainder = append(b.remainder, c)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return 0, err
+			}
+			b.eof = true
+		}
+		var decoded [3]byte
+		for len(b.remainder) >= 4 {
+			n, err := base64.StdEncoding.Decode(decoded[:], b.remainder[:4])
+			if err != nil {
+				return 0, err
+			}
+			b.pending.Write(decoded[:n])
+			b.remainder = b.remainder[4:]
+		}
+	}
+	return b.pending.Read(data)
+}
+
+func (b *base64DecodingReader) Close() error {
+	return b.delegate.Close()
+}
@@ -0,0 +1,97 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase64EncodingReader(t *testing.T) {
+	t.Parallel()
+	for _, size := range []int{0, 1, 2, 3, 4, 100, 1001} {
+		data := bytes.Repeat([]byte{0xfb, 0x01, 0x7e}, size)[:size]
+		// Read one byte at a time to make sure padding is only written at the end.
+		encoded, err := io.ReadAll(newBase64EncodingReader(io.NopCloser(iotest.OneByteReader(bytes.NewReader(data)))))
+		require.NoError(t, err)
+		require.Equal(t, base64.StdEncoding.EncodeToString(data), string(encoded))
+	}
+}
+
+func TestBase64DecodingReader(t *testing.T) {
+	t.Parallel()
+	// Each chunk is padded separately, as servers do for each frame.
+	encoded := base64.StdEncoding.EncodeToString([]byte("a")) +
+		base64.StdEncoding.EncodeToString([]byte("bc")) +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString([]byte("def"))
+	decoded, err := io.ReadAll(newBase64DecodingReader(io.NopCloser(iotest.HalfReader(strings.NewReader(encoded)))))
+	require.NoError(t, err)
+	require.Equal(t, "abcdef", string(decoded))
+
+	_, err = io.ReadAll(newBase64DecodingReader(io.NopCloser(strings.NewReader("YWJj!!!!"))))
+	require.Error(t, err)
+	_, err = io.ReadAll(newBase64DecodingReader(io.NopCloser(strings.NewReader("YWJjZ"))))
+	require.Error(t, err)
+}
+
+func TestGRPCWebTextHTTPClient(t *testing.T) {
+	t.Parallel()
+	var requestContentType string
+	var requestBody []byte
+	client := NewGRPCWebTextHTTPClient(
+		testHTTPClientFunc(
+			func(req *http.Request) (*http.Response, error) {
+				requestContentType = req.Header.Get("Content-Type")
+				var err error
+				requestBody, err = io.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				header := make(http.Header)
+				header.Set("Content-Type", "application/grpc-web-text+proto")
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(base64.StdEncoding.EncodeToString([]byte("response")))),
+				}, nil
+			},
+		),
+	)
+	request, err := http.NewRequest(http.MethodPost, "http://localhost/foo.v1.FooService/Bar", strings.NewReader("request"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/grpc-web+proto")
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	require.Equal(t, "application/grpc-web-text+proto", requestContentType)
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("request")), string(requestBody))
+	require.Equal(t, "application/grpc-web+proto", response.Header.Get("Content-Type"))
+	responseBody, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "response", string(responseBody))
+}
+
+type testHTTPClientFunc func(*http.Request) (*http.Response, error)
+
+func (f testHTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
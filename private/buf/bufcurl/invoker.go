@@ -23,13 +23,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/bufbuild/buf/private/pkg/verbose"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
@@ -77,6 +81,19 @@ type invoker struct {
 	output       io.Writer
 	errOutput    io.Writer
 	printer      verbose.Printer
+	noHalfClose  bool
+
+	outputFormat     OutputFormat
+	includeHeaders   bool
+	trailersOnly     bool
+	retryPolicy      RetryPolicy
+	enumsAsInts      bool
+	bytesAsBase64URL bool
+	jsonIndent       string
+	jsonCanonical    bool
+	timings          bool
+	// start is the time the current invocation started, used for timings.
+	start time.Time
 }
 
 // NewInvoker creates a new invoker for invoking the method described by the
@@ -84,12 +101,12 @@ type invoker struct {
 // in JSON format. The given resolver is used to resolve Any messages and
 // extensions that appear in the input or output. Other parameters are used
 // to create a Connect client, for issuing the RPC.
-func NewInvoker(container appext.Container, verbosePrinter verbose.Printer, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, url string, out io.Writer) Invoker {
+func NewInvoker(container appext.Container, verbosePrinter verbose.Printer, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, url string, out io.Writer, options ...InvokerOption) Invoker {
 	opts = append(opts, connect.WithCodec(protoCodec{}))
 	// TODO: could also provide custom compressor implementations that could give us
 	//  optics into when request and response messages are compressed (which could be
 	//  useful to include in verbose output).
-	return &invoker{
+	invoker := &invoker{
 		md:           md,
 		res:          res,
 		emitDefaults: emitDefaults,
@@ -97,6 +114,119 @@ func NewInvoker(container appext.Container, verbosePrinter verbose.Printer, md p
 		printer:      verbosePrinter,
 		errOutput:    container.Stderr(),
 		client:       connect.NewClient[dynamicpb.Message, deferredMessage](httpClient, url, opts...),
+		outputFormat: OutputFormatJSON,
+		jsonIndent:   "  ",
+	}
+	for _, option := range options {
+		option(invoker)
+	}
+	return invoker
+}
+
+// InvokerOption is an option for a new Invoker.
+type InvokerOption func(*invoker)
+
+// InvokerWithNoHalfClose returns a new InvokerOption that keeps the request stream
+// of a bidirectional streaming RPC open after all request messages have been sent,
+// until the server closes the response stream.
+//
+// The default is to half-close the request stream as soon as the input data is
+// exhausted, which some servers interpret as the end of the conversation.
+func InvokerWithNoHalfClose() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.noHalfClose = true
+	}
+}
+
+// InvokerWithOutputFormat returns a new InvokerOption that sets the format
+// that response messages are printed in.
+//
+// The default is OutputFormatJSON.
+func InvokerWithOutputFormat(outputFormat OutputFormat) InvokerOption {
+	return func(invoker *invoker) {
+		invoker.outputFormat = outputFormat
+	}
+}
+
+// InvokerWithEnumsAsInts returns a new InvokerOption that prints enum values
+// in JSON response messages as numbers instead of names.
+func InvokerWithEnumsAsInts() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.enumsAsInts = true
+	}
+}
+
+// InvokerWithBytesAsBase64URL returns a new InvokerOption that prints the values
+// of bytes fields in JSON response messages with the URL-safe base64 alphabet.
+//
+// The default is the standard base64 alphabet. Both are accepted when parsing
+// the JSON form of a message.
+func InvokerWithBytesAsBase64URL() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.bytesAsBase64URL = true
+	}
+}
+
+// InvokerWithJSONIndent returns a new InvokerOption that indents JSON response
+// messages with the given number of spaces. If the number is zero, each
+// response message is printed on a single line.
+//
+// The default is 2.
+func InvokerWithJSONIndent(spaces int) InvokerOption {
+	return func(invoker *invoker) {
+		invoker.jsonIndent = strings.Repeat(" ", spaces)
+	}
+}
+
+// InvokerWithJSONCanonical returns a new InvokerOption that prints JSON response
+// messages in the canonical form of RFC 8785, with object keys sorted and numbers
+// in a fixed format, so that the same message is always printed as the same bytes.
+// Each response message is printed on a single line.
+func InvokerWithJSONCanonical() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.jsonCanonical = true
+	}
+}
+
+// InvokerWithTimings returns a new InvokerOption that prints the time at which
+// each response message of a server-streaming or bidirectional-streaming RPC
+// was received, relative to the start of the RPC, to stderr.
+func InvokerWithTimings() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.timings = true
+	}
+}
+
+// InvokerWithIncludeHeaders returns a new InvokerOption that prints the response
+// headers before the response messages, and the response trailers after the
+// response messages.
+//
+// Headers and trailers are printed as "name: value" lines, and the headers are
+// followed by a blank line.
+func InvokerWithIncludeHeaders() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.includeHeaders = true
+	}
+}
+
+// InvokerWithRetryPolicy returns a new InvokerOption that retries failed RPCs
+// according to the RetryPolicy.
+//
+// Client-streaming and bidirectional-streaming RPCs are never retried, and
+// server-streaming RPCs are only retried if no response message was received.
+func InvokerWithRetryPolicy(retryPolicy RetryPolicy) InvokerOption {
+	return func(invoker *invoker) {
+		invoker.retryPolicy = retryPolicy
+	}
+}
+
+// InvokerWithTrailersOnly returns a new InvokerOption that prints the response
+// trailers instead of the response messages.
+//
+// Trailers are printed as "name: value" lines.
+func InvokerWithTrailersOnly() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.trailersOnly = true
 	}
 }
 
@@ -105,6 +235,63 @@ func (inv *invoker) Invoke(ctx context.Context, dataSource string, data io.Reade
 	// request's user-agent header(s) get overwritten by protocol, so we stash them in the
 	// context so that underlying transport can restore them
 	ctx = withUserAgent(ctx, headers)
+	inv.start = time.Now()
+	var err error
+	if inv.retryPolicy.MaxRetries > 0 && !inv.md.IsStreamingClient() {
+		err = inv.invokeWithRetries(ctx, dataSource, data, headers)
+	} else {
+		err = inv.invoke(ctx, dataSource, data, headers)
+	}
+	var connErr *connect.Error
+	if errors.As(err, &connErr) {
+		return inv.handleErrorResponse(connErr)
+	}
+	return err
+}
+
+func (inv *invoker) invokeWithRetries(ctx context.Context, dataSource string, data io.Reader, headers http.Header) error {
+	// The request data is buffered so that it can be sent again. Client-streaming
+	// RPCs are never retried, so this is at most a single request message.
+	var dataBytes []byte
+	if data != nil {
+		var err error
+		dataBytes, err = io.ReadAll(data)
+		if err != nil {
+			return ErrorHasFilename(err, dataSource)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		var attemptData io.Reader
+		if data != nil {
+			attemptData = bytes.NewReader(dataBytes)
+		}
+		output := &trackingWriter{Writer: inv.output}
+		attemptInvoker := *inv
+		attemptInvoker.output = output
+		err := attemptInvoker.invoke(ctx, dataSource, attemptData, headers)
+		if err == nil {
+			return nil
+		}
+		// Once a response message has been printed, retrying would print it again.
+		if output.wrote {
+			return err
+		}
+		backoff, ok := inv.retryPolicy.getBackoff(err, attempt)
+		if !ok {
+			return err
+		}
+		inv.printer.Printf("* RPC failed, retrying in %v (retry %d of %d): %v", backoff, attempt+1, inv.retryPolicy.MaxRetries, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (inv *invoker) invoke(ctx context.Context, dataSource string, data io.Reader, headers http.Header) error {
 	switch {
 	case inv.md.IsStreamingServer() && inv.md.IsStreamingClient():
 		return inv.handleBidiStream(ctx, dataSource, data, headers)
@@ -135,14 +322,9 @@ func (inv *invoker) handleUnary(ctx context.Context, dataSource string, data io.
 	}
 	resp, err := inv.client.CallUnary(ctx, req)
 	if err != nil {
-		var connErr *connect.Error
-		if !errors.As(err, &connErr) {
-			return err
-		}
-		err := inv.handleErrorResponse(connErr)
 		return err
 	}
-	return inv.handleResponse(resp.Msg.data, nil)
+	return inv.handleUnaryResponse(resp)
 }
 
 func (inv *invoker) handleClientStream(ctx context.Context, dataSource string, data io.Reader, headers http.Header) (retErr error) {
@@ -152,14 +334,6 @@ func (inv *invoker) handleClientStream(ctx context.Context, dataSource string, d
 	for k, v := range headers {
 		stream.RequestHeader()[k] = v
 	}
-	defer func() {
-		if retErr != nil {
-			var connErr *connect.Error
-			if errors.As(retErr, &connErr) {
-				retErr = inv.handleErrorResponse(connErr)
-			}
-		}
-	}()
 	if err, isStreamError := inv.handleStreamRequest(provider, msg, stream); err != nil {
 		if isStreamError {
 			_, recvErr := stream.CloseAndReceive()
@@ -175,7 +349,7 @@ func (inv *invoker) handleClientStream(ctx context.Context, dataSource string, d
 	if err != nil {
 		return err
 	}
-	return inv.handleResponse(resp.Msg.data, nil)
+	return inv.handleUnaryResponse(resp)
 }
 
 func (inv *invoker) handleServerStream(ctx context.Context, dataSource string, data io.Reader, headers http.Header) (retErr error) {
@@ -194,14 +368,6 @@ func (inv *invoker) handleServerStream(ctx context.Context, dataSource string, d
 	for k, v := range headers {
 		req.Header()[k] = v
 	}
-	defer func() {
-		if retErr != nil {
-			var connErr *connect.Error
-			if errors.As(retErr, &connErr) {
-				retErr = inv.handleErrorResponse(connErr)
-			}
-		}
-	}()
 
 	stream, err := inv.client.CallServerStream(ctx, req)
 	if err != nil {
@@ -219,15 +385,6 @@ func (inv *invoker) handleBidiStream(ctx context.Context, dataSource string, dat
 		stream.RequestHeader()[k] = v
 	}
 
-	defer func() {
-		if retErr != nil {
-			var connErr *connect.Error
-			if errors.As(retErr, &connErr) {
-				retErr = inv.handleErrorResponse(connErr)
-			}
-		}
-	}()
-
 	var recvErr error
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -260,6 +417,15 @@ func (inv *invoker) handleBidiStream(ctx context.Context, dataSource string, dat
 	if err != nil {
 		return err
 	}
+	if inv.noHalfClose {
+		inv.printer.Printf("* Finished sending request messages, waiting for server to close stream")
+		// Once the server closes the response stream, the context is cancelled, so
+		// the error from closing the request stream is not interesting. Any error
+		// from the server is reported via recvErr.
+		wg.Wait()
+		_ = stream.CloseRequest()
+		return nil
+	}
 	return stream.CloseRequest()
 }
 
@@ -274,32 +440,91 @@ func isCancelled(err error) bool {
 	return false
 }
 
+func (inv *invoker) handleUnaryResponse(resp *connect.Response[deferredMessage]) error {
+	if err := inv.handleResponseHeaders(resp.Header()); err != nil {
+		return err
+	}
+	if err := inv.handleResponse(resp.Msg.data, nil); err != nil {
+		return err
+	}
+	return inv.handleResponseTrailers(resp.Trailer())
+}
+
+func (inv *invoker) handleResponseHeaders(headers http.Header) error {
+	if !inv.includeHeaders {
+		return nil
+	}
+	if err := writeMetadata(inv.output, headers); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(inv.output)
+	return err
+}
+
+func (inv *invoker) handleResponseTrailers(trailers http.Header) error {
+	if !inv.includeHeaders && !inv.trailersOnly {
+		return nil
+	}
+	return writeMetadata(inv.output, trailers)
+}
+
 func (inv *invoker) handleResponse(data []byte, msg *dynamicpb.Message) error {
+	if inv.trailersOnly {
+		return nil
+	}
+	if inv.outputFormat == OutputFormatBinpb {
+		// The raw bytes are written as received, without unmarshalling, so that
+		// the output is exactly what the server sent.
+		if inv.md.IsStreamingServer() {
+			if _, err := inv.output.Write(protowire.AppendVarint(nil, uint64(len(data)))); err != nil {
+				return err
+			}
+		}
+		_, err := inv.output.Write(data)
+		return err
+	}
 	if msg == nil {
 		msg = dynamicpb.NewMessage(inv.md.Output())
 	}
 	if err := protoencoding.NewWireUnmarshaler(inv.res).Unmarshal(data, msg); err != nil {
 		return err
 	}
-	jsonMarshalerOptions := []protoencoding.JSONMarshalerOption{
-		protoencoding.JSONMarshalerWithIndent(),
-	}
+	var jsonMarshalerOptions []protoencoding.JSONMarshalerOption
 	if inv.emitDefaults {
 		jsonMarshalerOptions = append(
 			jsonMarshalerOptions,
 			protoencoding.JSONMarshalerWithEmitUnpopulated(),
 		)
 	}
+	if inv.enumsAsInts {
+		jsonMarshalerOptions = append(
+			jsonMarshalerOptions,
+			protoencoding.JSONMarshalerWithUseEnumNumbers(),
+		)
+	}
 	unrecognized := countUnrecognized(msg.ProtoReflect())
 	if unrecognized > 0 {
 		inv.printer.Printf("Response message (%s) contained %d bytes of unrecognized fields.",
 			msg.ProtoReflect().Descriptor().FullName(), unrecognized)
 	}
-	outputBytes, err := protoencoding.NewJSONMarshaler(inv.res, jsonMarshalerOptions...).Marshal(msg)
+	var marshaler protoencoding.Marshaler
+	switch inv.outputFormat {
+	case OutputFormatText:
+		marshaler = protoencoding.NewTxtpbMarshaler(inv.res)
+	default:
+		marshaler = protoencoding.NewJSONMarshaler(inv.res, jsonMarshalerOptions...)
+	}
+	outputBytes, err := marshaler.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintf(inv.output, "%s\n", outputBytes)
+	if inv.outputFormat == OutputFormatJSON {
+		outputBytes, err = formatJSON(outputBytes, msg.ProtoReflect().Descriptor(), inv.res, inv.jsonIndent, inv.jsonCanonical, inv.bytesAsBase64URL)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(inv.output, "%s\n", bytes.TrimSuffix(outputBytes, []byte("\n")))
 	return err
 }
 
@@ -310,6 +535,8 @@ type clientStream interface {
 type serverStream interface {
 	Receive() (*deferredMessage, error)
 	CloseResponse() error
+	ResponseHeader() http.Header
+	ResponseTrailer() http.Header
 }
 
 type serverStreamAdapter struct {
@@ -331,6 +558,14 @@ func (ssa *serverStreamAdapter) CloseResponse() error {
 	return ssa.stream.Close()
 }
 
+func (ssa *serverStreamAdapter) ResponseHeader() http.Header {
+	return ssa.stream.ResponseHeader()
+}
+
+func (ssa *serverStreamAdapter) ResponseTrailer() http.Header {
+	return ssa.stream.ResponseTrailer()
+}
+
 func (inv *invoker) handleStreamRequest(provider messageProvider, msg *dynamicpb.Message, stream clientStream) (error, bool) {
 	for {
 		if err := provider.next(msg); errors.Is(err, io.EOF) {
@@ -353,13 +588,28 @@ func (inv *invoker) handleStreamResponse(stream serverStream) (retError error) {
 		}
 	}()
 	msg := dynamicpb.NewMessage(inv.md.Output())
-	for {
+	for messageNumber := 1; ; messageNumber++ {
 		responseMsg, err := stream.Receive()
+		if messageNumber == 1 && (err == nil || errors.Is(err, io.EOF)) {
+			// The response headers are not necessarily available until the
+			// first call to Receive returns.
+			if err := inv.handleResponseHeaders(stream.ResponseHeader()); err != nil {
+				return err
+			}
+		}
 		if errors.Is(err, io.EOF) {
-			return nil
+			return inv.handleResponseTrailers(stream.ResponseTrailer())
 		} else if err != nil {
 			return err
 		}
+		if inv.timings {
+			_, _ = fmt.Fprintf(
+				inv.errOutput,
+				"* Received response message #%d at +%v\n",
+				messageNumber,
+				time.Since(inv.start).Round(time.Microsecond),
+			)
+		}
 		if err := inv.handleResponse(responseMsg.data, msg); err != nil {
 			return err
 		}
@@ -380,6 +630,11 @@ func (inv *invoker) handleErrorResponse(connErr *connect.Error) error {
 	}
 	req.Header.Add("content-type", "application/json")
 
+	if inv.includeHeaders || inv.trailersOnly {
+		if err := writeMetadata(inv.output, connErr.Meta()); err != nil {
+			return err
+		}
+	}
 	w := connect.NewErrorWriter()
 	responseWriter := httptest.NewRecorder()
 	err := w.Write(responseWriter, req, connErr)
@@ -482,3 +737,32 @@ func countUnrecognized(msg protoreflect.Message) int {
 func isMessageKind(k protoreflect.Kind) bool {
 	return k == protoreflect.MessageKind || k == protoreflect.GroupKind
 }
+
+// writeMetadata writes the metadata as "name: value" lines, sorted by name.
+func writeMetadata(writer io.Writer, metadata http.Header) error {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range metadata[key] {
+			if _, err := fmt.Fprintf(writer, "%s: %s\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trackingWriter is an io.Writer that records whether anything was written.
+type trackingWriter struct {
+	io.Writer
+
+	wrote bool
+}
+
+func (t *trackingWriter) Write(data []byte) (int, error) {
+	t.wrote = true
+	return t.Writer.Write(data)
+}
@@ -15,7 +15,9 @@
 package bufcurl
 
 import (
+	"bytes"
 	"context"
+	"net/http"
 	"os"
 	"testing"
 
@@ -71,3 +73,19 @@ func TestCountUnrecognized(t *testing.T) {
 	unrecognized := countUnrecognized(msg)
 	assert.Equal(t, expectedUnrecognized, unrecognized)
 }
+
+func TestWriteMetadata(t *testing.T) {
+	t.Parallel()
+	metadata := http.Header{}
+	metadata.Add("Grpc-Status", "0")
+	metadata.Add("Content-Type", "application/grpc")
+	metadata.Add("X-Multi", "a")
+	metadata.Add("X-Multi", "b")
+	var buffer bytes.Buffer
+	require.NoError(t, writeMetadata(&buffer, metadata))
+	assert.Equal(
+		t,
+		"Content-Type: application/grpc\nGrpc-Status: 0\nX-Multi: a\nX-Multi: b\n",
+		buffer.String(),
+	)
+}
@@ -0,0 +1,315 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// formatJSON reformats the JSON produced by protojson for a message of the
+// given type.
+//
+// The JSON is indented with the given indent, or compacted onto a single line
+// if the indent is empty. protojson does not produce stable whitespace, so the
+// JSON is always reformatted. If canonical is true, the indent is ignored and
+// the JSON is written in the canonical form of RFC 8785 instead. If
+// bytesAsBase64URL is true, the values of bytes fields are re-encoded with the
+// URL-safe base64 alphabet.
+func formatJSON(
+	data []byte,
+	md protoreflect.MessageDescriptor,
+	res protoencoding.Resolver,
+	indent string,
+	canonical bool,
+	bytesAsBase64URL bool,
+) ([]byte, error) {
+	if bytesAsBase64URL {
+		rewriter := &base64URLRewriter{
+			data:    data,
+			decoder: json.NewDecoder(bytes.NewReader(data)),
+			res:     res,
+		}
+		rewriter.decoder.UseNumber()
+		if err := rewriter.rewriteMessage(md); err != nil {
+			return nil, err
+		}
+		data = rewriter.buffer.Bytes()
+	}
+	if canonical {
+		return encoding.CanonicalizeJSON(data)
+	}
+	var buffer bytes.Buffer
+	if indent == "" {
+		if err := json.Compact(&buffer, data); err != nil {
+			return nil, err
+		}
+	} else if err := json.Indent(&buffer, data, "", indent); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// base64URLRewriter rewrites the JSON form of a message, re-encoding the
+// values of bytes fields with the URL-safe base64 alphabet.
+//
+// The JSON is read and written token by token, using the descriptors to
+// determine which values are bytes. Values that cannot contain a bytes
+// field, such as those of unknown fields or of well-known types with a
+// special JSON form, are copied as-is.
+type base64URLRewriter struct {
+	data    []byte
+	decoder *json.Decoder
+	res     protoencoding.Resolver
+	buffer  bytes.Buffer
+}
+
+func (r *base64URLRewriter) rewriteMessage(md protoreflect.MessageDescriptor) error {
+	switch md.FullName() {
+	case "google.protobuf.BytesValue":
+		return r.rewriteBytes()
+	case "google.protobuf.Any":
+		return r.rewriteAny()
+	}
+	if hasSpecialJSONForm(md) {
+		return r.copyValue()
+	}
+	return r.rewriteObject(func(key string) func() error {
+		fd := findJSONField(md, key, r.res)
+		if fd == nil {
+			return r.copyValue
+		}
+		return func() error { return r.rewriteField(fd) }
+	})
+}
+
+func (r *base64URLRewriter) rewriteAny() error {
+	var md protoreflect.MessageDescriptor
+	return r.rewriteObject(func(key string) func() error {
+		switch {
+		case key == "@type":
+			return func() error {
+				token, err := r.decoder.Token()
+				if err != nil {
+					return err
+				}
+				typeURL, ok := token.(string)
+				if !ok {
+					return fmt.Errorf("invalid @type: %v", token)
+				}
+				if messageType, err := r.res.FindMessageByURL(typeURL); err == nil {
+					md = messageType.Descriptor()
+				}
+				return r.writeToken(token)
+			}
+		case md == nil:
+			// protojson writes @type first, so the type is unknown.
+			return r.copyValue
+		case hasSpecialJSONForm(md) || md.FullName() == "google.protobuf.BytesValue":
+			if key != "value" {
+				return r.copyValue
+			}
+			return func() error { return r.rewriteMessage(md) }
+		default:
+			fd := findJSONField(md, key, r.res)
+			if fd == nil {
+				return r.copyValue
+			}
+			return func() error { return r.rewriteField(fd) }
+		}
+	})
+}
+
+func (r *base64URLRewriter) rewriteField(fd protoreflect.FieldDescriptor) error {
+	switch {
+	case fd.IsMap():
+		return r.rewriteObject(func(string) func() error {
+			return func() error { return r.rewriteSingular(fd.MapValue()) }
+		})
+	case fd.IsList():
+		if isNull, err := r.copyIfNull(); isNull || err != nil {
+			return err
+		}
+		if err := r.expectDelim('['); err != nil {
+			return err
+		}
+		for i := 0; r.decoder.More(); i++ {
+			if i > 0 {
+				r.buffer.WriteByte(',')
+			}
+			if err := r.rewriteSingular(fd); err != nil {
+				return err
+			}
+		}
+		return r.expectDelim(']')
+	default:
+		return r.rewriteSingular(fd)
+	}
+}
+
+func (r *base64URLRewriter) rewriteSingular(fd protoreflect.FieldDescriptor) error {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return r.rewriteBytes()
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return r.rewriteMessage(fd.Message())
+	default:
+		return r.copyValue()
+	}
+}
+
+func (r *base64URLRewriter) rewriteBytes() error {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return err
+	}
+	value, ok := token.(string)
+	if !ok {
+		return r.writeToken(token)
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("invalid bytes value %q: %w", value, err)
+	}
+	return r.writeToken(base64.URLEncoding.EncodeToString(data))
+}
+
+// rewriteObject rewrites a JSON object, calling getRewriteValue with each
+// key to get the function that rewrites the value for the key.
+func (r *base64URLRewriter) rewriteObject(getRewriteValue func(key string) func() error) error {
+	if isNull, err := r.copyIfNull(); isNull || err != nil {
+		return err
+	}
+	if err := r.expectDelim('{'); err != nil {
+		return err
+	}
+	for i := 0; r.decoder.More(); i++ {
+		if i > 0 {
+			r.buffer.WriteByte(',')
+		}
+		token, err := r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("invalid object key: %v", token)
+		}
+		if err := r.writeToken(key); err != nil {
+			return err
+		}
+		r.buffer.WriteByte(':')
+		if err := getRewriteValue(key)(); err != nil {
+			return err
+		}
+	}
+	return r.expectDelim('}')
+}
+
+// copyValue copies the next JSON value as-is.
+func (r *base64URLRewriter) copyValue() error {
+	var value json.RawMessage
+	if err := r.decoder.Decode(&value); err != nil {
+		return err
+	}
+	_, err := r.buffer.Write(value)
+	return err
+}
+
+// copyIfNull copies the next JSON value if it is null.
+func (r *base64URLRewriter) copyIfNull() (bool, error) {
+	// The decoder does not support peeking at the next token, so this
+	// looks at the input past the last token, skipping any separators.
+	remaining := bytes.TrimLeft(r.data[r.decoder.InputOffset():], " \t\r\n,:")
+	if !bytes.HasPrefix(remaining, []byte("null")) {
+		return false, nil
+	}
+	return true, r.copyValue()
+}
+
+func (r *base64URLRewriter) expectDelim(delim json.Delim) error {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	r.buffer.WriteString(delim.String())
+	return nil
+}
+
+func (r *base64URLRewriter) writeToken(token json.Token) error {
+	switch token := token.(type) {
+	case json.Number:
+		r.buffer.WriteString(token.String())
+		return nil
+	case nil:
+		r.buffer.WriteString("null")
+		return nil
+	default:
+		encoder := json.NewEncoder(&r.buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(token); err != nil {
+			return err
+		}
+		// Encode writes a trailing newline.
+		r.buffer.Truncate(r.buffer.Len() - 1)
+		return nil
+	}
+}
+
+// hasSpecialJSONForm returns true if the message is a well-known type that
+// protojson does not write as a JSON object of its fields.
+func hasSpecialJSONForm(md protoreflect.MessageDescriptor) bool {
+	if md.FullName().Parent() != "google.protobuf" {
+		return false
+	}
+	switch md.Name() {
+	case "Duration", "Timestamp", "FieldMask", "Struct", "Value", "ListValue",
+		"DoubleValue", "FloatValue", "Int64Value", "UInt64Value", "Int32Value",
+		"UInt32Value", "BoolValue", "StringValue", "BytesValue":
+		return true
+	default:
+		return false
+	}
+}
+
+// findJSONField returns the field of the message with the given JSON key,
+// or nil if there is no such field.
+func findJSONField(
+	md protoreflect.MessageDescriptor,
+	key string,
+	res protoencoding.Resolver,
+) protoreflect.FieldDescriptor {
+	if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+		extensionType, err := res.FindExtensionByName(protoreflect.FullName(key[1 : len(key)-1]))
+		if err != nil {
+			return nil
+		}
+		return extensionType.TypeDescriptor()
+	}
+	if fd := md.Fields().ByJSONName(key); fd != nil {
+		return fd
+	}
+	return md.Fields().ByTextName(key)
+}
@@ -0,0 +1,131 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestFormatJSON(t *testing.T) {
+	t.Parallel()
+	anyFile := protodesc.ToFileDescriptorProto(anypb.File_google_protobuf_any_proto)
+	wrappersFile := protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto)
+	testFile := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{anyFile.GetName(), wrappersFile.GetName()},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Test"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newTestField("data", 1, descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", false),
+					newTestField("list", 2, descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", true),
+					newTestField("map", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Test.MapEntry", true),
+					newTestField("wrapper", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.BytesValue", false),
+					newTestField("any", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Any", false),
+					newTestField("child", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Test", false),
+					newTestField("text", 7, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("MapEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							newTestField("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+							newTestField("value", 2, descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", false),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+		},
+	}
+	res, err := protoencoding.NewResolver(anyFile, wrappersFile, testFile)
+	require.NoError(t, err)
+	md, err := res.FindDescriptorByName("test.Test")
+	require.NoError(t, err)
+	messageDescriptor := md.(protoreflect.MessageDescriptor)
+	msg := dynamicpb.NewMessage(messageDescriptor)
+	require.NoError(t, protoencoding.NewJSONUnmarshaler(res).Unmarshal([]byte(`{
+		"data": "+/8=",
+		"list": ["+/8=", ""],
+		"map": {"a": "+/8="},
+		"wrapper": "+/8=",
+		"any": {"@type": "type.googleapis.com/google.protobuf.BytesValue", "value": "+/8="},
+		"child": {"data": "+/8=", "text": "+/8="},
+		"text": "+/8=<>"
+	}`), msg))
+	data, err := protoencoding.NewJSONMarshaler(res).Marshal(msg)
+	require.NoError(t, err)
+
+	formatted, err := formatJSON(data, messageDescriptor, res, "", false, false)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"data":"+/8=","list":["+/8=",""],"map":{"a":"+/8="},"wrapper":"+/8=","any":{"@type":"type.googleapis.com/google.protobuf.BytesValue","value":"+/8="},"child":{"data":"+/8=","text":"+/8="},"text":"+/8=<>"}`,
+		string(formatted),
+	)
+	formatted, err = formatJSON(data, messageDescriptor, res, "", false, true)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"data":"-_8=","list":["-_8=",""],"map":{"a":"-_8="},"wrapper":"-_8=","any":{"@type":"type.googleapis.com/google.protobuf.BytesValue","value":"-_8="},"child":{"data":"-_8=","text":"+/8="},"text":"+/8=<>"}`,
+		string(formatted),
+	)
+	formatted, err = formatJSON([]byte(`{"data":"+/8="}`), messageDescriptor, res, "  ", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"data\": \"-_8=\"\n}", string(formatted))
+	formatted, err = formatJSON(data, messageDescriptor, res, "  ", true, true)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"any":{"@type":"type.googleapis.com/google.protobuf.BytesValue","value":"-_8="},"child":{"data":"-_8=","text":"+/8="},"data":"-_8=","list":["-_8=",""],"map":{"a":"-_8="},"text":"+/8=<>","wrapper":"-_8="}`,
+		string(formatted),
+	)
+}
+
+func newTestField(
+	name string,
+	number int32,
+	fieldType descriptorpb.FieldDescriptorProto_Type,
+	typeName string,
+	repeated bool,
+) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     fieldType.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	if repeated {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	return field
+}
@@ -0,0 +1,78 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OutputFormatJSON represents that response messages are printed as JSON.
+	OutputFormatJSON OutputFormat = iota + 1
+	// OutputFormatBinpb represents that response messages are printed as
+	// the raw binary Protobuf bytes received from the server.
+	//
+	// For server-streaming and bidirectional-streaming RPCs, each message
+	// is prefixed with its size as a varint, as done by the protodelim package.
+	OutputFormatBinpb
+	// OutputFormatText represents that response messages are printed in the
+	// Protobuf text format.
+	OutputFormatText
+)
+
+var (
+	// AllOutputFormatStrings are all string values for OutputFormat.
+	AllOutputFormatStrings = []string{
+		"json",
+		"binpb",
+		"text",
+	}
+
+	outputFormatToString = map[OutputFormat]string{
+		OutputFormatJSON:  "json",
+		OutputFormatBinpb: "binpb",
+		OutputFormatText:  "text",
+	}
+	stringToOutputFormat = map[string]OutputFormat{
+		"json":  OutputFormatJSON,
+		"binpb": OutputFormatBinpb,
+		"text":  OutputFormatText,
+	}
+)
+
+// OutputFormat is the format that response messages are printed in.
+type OutputFormat int
+
+// String implements fmt.Stringer.
+func (o OutputFormat) String() string {
+	s, ok := outputFormatToString[o]
+	if !ok {
+		return strconv.Itoa(int(o))
+	}
+	return s
+}
+
+// ParseOutputFormat parses the OutputFormat.
+//
+// The empty string is a parse error.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	o, ok := stringToOutputFormat[strings.ToLower(strings.TrimSpace(s))]
+	if ok {
+		return o, nil
+	}
+	return 0, fmt.Errorf("unknown OutputFormat: %q", s)
+}
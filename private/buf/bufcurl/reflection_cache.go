@@ -0,0 +1,184 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultReflectionCacheTTL is the default time that cached server reflection
+// results are used for.
+const DefaultReflectionCacheTTL = time.Hour
+
+// ReflectionCache is an on-disk cache of the descriptors that were downloaded
+// with server reflection, so that repeated invocations of the same service on
+// the same server do not need to use server reflection.
+//
+// Entries are keyed by a hash of the server's base URL and the service name,
+// and contain the file that defines the service and all of its imports.
+type ReflectionCache interface {
+	// GetResolver returns a Resolver for the cached descriptors for the service
+	// on the server at the base URL.
+	//
+	// Returns nil if there are no cached descriptors, or the cached descriptors
+	// have expired.
+	GetResolver(baseURL string, service string) (Resolver, error)
+	// Put caches the descriptors for the service on the server at the base URL.
+	Put(baseURL string, serviceDescriptor protoreflect.ServiceDescriptor) error
+}
+
+// NewReflectionCache returns a new ReflectionCache that stores entries in the
+// directory, and uses entries for the given TTL.
+func NewReflectionCache(dirPath string, ttl time.Duration, printer verbose.Printer) ReflectionCache {
+	return newReflectionCache(dirPath, ttl, printer)
+}
+
+// *** PRIVATE ***
+
+type reflectionCache struct {
+	dirPath string
+	ttl     time.Duration
+	printer verbose.Printer
+}
+
+func newReflectionCache(dirPath string, ttl time.Duration, printer verbose.Printer) *reflectionCache {
+	return &reflectionCache{
+		dirPath: dirPath,
+		ttl:     ttl,
+		printer: printer,
+	}
+}
+
+func (r *reflectionCache) GetResolver(baseURL string, service string) (Resolver, error) {
+	filePath := r.getFilePath(baseURL, service)
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if age := time.Since(fileInfo.ModTime()); age > r.ttl {
+		r.printer.Printf("* Cached server reflection results for %s have expired", service)
+		return nil, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, fileDescriptorSet); err != nil {
+		// A corrupted entry is treated as a cache miss, and is overwritten
+		// once the descriptors are downloaded again.
+		r.printer.Printf("* Ignoring corrupted server reflection cache entry for %s: %v", service, err)
+		return nil, nil
+	}
+	resolver, err := protoencoding.NewResolver(fileDescriptorSet.GetFile()...)
+	if err != nil {
+		r.printer.Printf("* Ignoring invalid server reflection cache entry for %s: %v", service, err)
+		return nil, nil
+	}
+	r.printer.Printf("* Using cached server reflection results for %s", service)
+	return &fileDescriptorSetResolver{
+		Resolver:          resolver,
+		fileDescriptorSet: fileDescriptorSet,
+	}, nil
+}
+
+func (r *reflectionCache) Put(baseURL string, serviceDescriptor protoreflect.ServiceDescriptor) error {
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	addFileAndImports(fileDescriptorSet, serviceDescriptor.ParentFile(), make(map[string]struct{}))
+	data, err := protoencoding.NewWireMarshaler().Marshal(fileDescriptorSet)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.dirPath, 0755); err != nil {
+		return err
+	}
+	filePath := r.getFilePath(baseURL, string(serviceDescriptor.FullName()))
+	// Write to a temporary file and rename so that readers never see a partial file.
+	tempFile, err := os.CreateTemp(r.dirPath, filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	return os.Rename(tempFile.Name(), filePath)
+}
+
+func (r *reflectionCache) getFilePath(baseURL string, service string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSuffix(baseURL, "/") + "\x00" + service))
+	return filepath.Join(r.dirPath, hex.EncodeToString(hash[:])+".binpb")
+}
+
+// addFileAndImports adds the file and all of its imports to the FileDescriptorSet,
+// with imports before the files that import them.
+func addFileAndImports(
+	fileDescriptorSet *descriptorpb.FileDescriptorSet,
+	fileDescriptor protoreflect.FileDescriptor,
+	seen map[string]struct{},
+) {
+	if _, ok := seen[fileDescriptor.Path()]; ok {
+		return
+	}
+	seen[fileDescriptor.Path()] = struct{}{}
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if imports.Get(i).IsPlaceholder() {
+			continue
+		}
+		addFileAndImports(fileDescriptorSet, imports.Get(i).FileDescriptor, seen)
+	}
+	fileDescriptorSet.File = append(fileDescriptorSet.File, protodesc.ToFileDescriptorProto(fileDescriptor))
+}
+
+type fileDescriptorSetResolver struct {
+	protoencoding.Resolver
+	fileDescriptorSet *descriptorpb.FileDescriptorSet
+}
+
+func (f *fileDescriptorSetResolver) ListServices() ([]protoreflect.FullName, error) {
+	var names []protoreflect.FullName
+	for _, fileDescriptor := range f.fileDescriptorSet.GetFile() {
+		for _, service := range fileDescriptor.GetService() {
+			if fileDescriptor.GetPackage() != "" {
+				names = append(names, protoreflect.FullName(fileDescriptor.GetPackage()+"."+service.GetName()))
+			} else {
+				names = append(names, protoreflect.FullName(service.GetName()))
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+	"time"
+
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestReflectionCache(t *testing.T) {
+	t.Parallel()
+	serviceDescriptor := reflectionv1.File_grpc_reflection_v1_reflection_proto.Services().Get(0)
+	serviceName := string(serviceDescriptor.FullName())
+	reflectionCache := NewReflectionCache(t.TempDir(), time.Hour, verbose.NopPrinter)
+
+	resolver, err := reflectionCache.GetResolver("https://example.com", serviceName)
+	require.NoError(t, err)
+	require.Nil(t, resolver)
+
+	require.NoError(t, reflectionCache.Put("https://example.com/", serviceDescriptor))
+	resolver, err = reflectionCache.GetResolver("https://example.com", serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, resolver)
+	descriptor, err := resolver.FindDescriptorByName(serviceDescriptor.FullName())
+	require.NoError(t, err)
+	require.Equal(t, serviceDescriptor.FullName(), descriptor.FullName())
+	serviceNames, err := resolver.ListServices()
+	require.NoError(t, err)
+	require.Equal(t, []protoreflect.FullName{serviceDescriptor.FullName()}, serviceNames)
+
+	// Entries are per server.
+	resolver, err = reflectionCache.GetResolver("https://other.example.com", serviceName)
+	require.NoError(t, err)
+	require.Nil(t, resolver)
+}
+
+func TestReflectionCacheExpired(t *testing.T) {
+	t.Parallel()
+	serviceDescriptor := reflectionv1.File_grpc_reflection_v1_reflection_proto.Services().Get(0)
+	reflectionCache := NewReflectionCache(t.TempDir(), -time.Second, verbose.NopPrinter)
+	require.NoError(t, reflectionCache.Put("https://example.com", serviceDescriptor))
+	resolver, err := reflectionCache.GetResolver("https://example.com", string(serviceDescriptor.FullName()))
+	require.NoError(t, err)
+	require.Nil(t, resolver)
+}
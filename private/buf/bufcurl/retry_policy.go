@@ -0,0 +1,73 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// retryPushbackHeader is the metadata key that gRPC servers use to tell clients
+// how long to wait before retrying, or that they should not retry at all.
+const retryPushbackHeader = "grpc-retry-pushback-ms"
+
+// DefaultRetryableCodes are the codes of errors that are retried by default.
+var DefaultRetryableCodes = []connect.Code{
+	connect.CodeUnavailable,
+}
+
+// RetryPolicy is a policy for retrying failed RPCs.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times to retry a failed RPC.
+	//
+	// If zero, failed RPCs are not retried.
+	MaxRetries int
+	// InitialBackoff is the time to wait before the first retry. The time
+	// to wait doubles for each subsequent retry.
+	//
+	// If the server sends retry pushback metadata, the time from the
+	// metadata is used instead.
+	InitialBackoff time.Duration
+	// RetryableCodes are the codes of errors that are retried.
+	RetryableCodes []connect.Code
+}
+
+// getBackoff returns the time to wait before retrying after the given error,
+// where attempt is the zero-based number of the attempt that failed.
+//
+// Returns false if the RPC should not be retried.
+func (r RetryPolicy) getBackoff(err error, attempt int) (time.Duration, bool) {
+	if attempt >= r.MaxRetries {
+		return 0, false
+	}
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || !slices.Contains(r.RetryableCodes, connectErr.Code()) {
+		return 0, false
+	}
+	if pushbackValues := connectErr.Meta().Values(retryPushbackHeader); len(pushbackValues) > 0 {
+		// A pushback that is negative or can not be parsed means that the
+		// server does not want the client to retry.
+		pushbackMillis, err := strconv.ParseInt(pushbackValues[0], 10, 64)
+		if err != nil || pushbackMillis < 0 {
+			return 0, false
+		}
+		return time.Duration(pushbackMillis) * time.Millisecond, true
+	}
+	return r.InitialBackoff << attempt, true
+}
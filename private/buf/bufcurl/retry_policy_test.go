@@ -0,0 +1,56 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyGetBackoff(t *testing.T) {
+	t.Parallel()
+	retryPolicy := RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		RetryableCodes: []connect.Code{connect.CodeUnavailable, connect.CodeResourceExhausted},
+	}
+	unavailableErr := connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+	backoff, ok := retryPolicy.getBackoff(unavailableErr, 0)
+	require.True(t, ok)
+	require.Equal(t, time.Second, backoff)
+	backoff, ok = retryPolicy.getBackoff(unavailableErr, 2)
+	require.True(t, ok)
+	require.Equal(t, 4*time.Second, backoff)
+	_, ok = retryPolicy.getBackoff(unavailableErr, 3)
+	require.False(t, ok)
+
+	_, ok = retryPolicy.getBackoff(connect.NewError(connect.CodeInternal, errors.New("internal")), 0)
+	require.False(t, ok)
+	_, ok = retryPolicy.getBackoff(errors.New("not a connect error"), 0)
+	require.False(t, ok)
+
+	pushbackErr := connect.NewError(connect.CodeResourceExhausted, errors.New("resource exhausted"))
+	pushbackErr.Meta().Set(retryPushbackHeader, "250")
+	backoff, ok = retryPolicy.getBackoff(pushbackErr, 1)
+	require.True(t, ok)
+	require.Equal(t, 250*time.Millisecond, backoff)
+	pushbackErr.Meta().Set(retryPushbackHeader, "-1")
+	_, ok = retryPolicy.getBackoff(pushbackErr, 1)
+	require.False(t, ok)
+}
@@ -0,0 +1,73 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownSchemaInputExtensions are the file extensions that buf detects
+// the format of when an input is given without an explicit format.
+var knownSchemaInputExtensions = map[string]struct{}{
+	".bin":   {},
+	".binpb": {},
+	".git":   {},
+	".gz":    {},
+	".json":  {},
+	".proto": {},
+	".tar":   {},
+	".tgz":   {},
+	".txtpb": {},
+	".yaml":  {},
+	".zip":   {},
+	".zst":   {},
+}
+
+// SchemaInput returns the input to use to load the schema given with
+// the --schema flag of buf curl.
+//
+// Compiled descriptors are often written to files with extensions that buf
+// does not recognize, such as the ".pb", ".desc", or ".protoset" files produced by
+// "protoc --descriptor_set_out". If the schema is a regular file with such an
+// extension, the format is detected from the file contents: a file that is
+// valid JSON is a JSON-encoded FileDescriptorSet or image, and any other file
+// is a binary-encoded FileDescriptorSet or image. Otherwise, including for
+// directories, modules, and inputs with an explicit format, the schema is
+// returned unchanged.
+func SchemaInput(schema string) (string, error) {
+	if schema == "-" || strings.Contains(schema, "#") {
+		return schema, nil
+	}
+	if _, ok := knownSchemaInputExtensions[filepath.Ext(schema)]; ok {
+		return schema, nil
+	}
+	fileInfo, err := os.Stat(schema)
+	if err != nil || !fileInfo.Mode().IsRegular() {
+		// Not a local file, so this is a module or an input that
+		// will fail to resolve with a more appropriate error.
+		return schema, nil
+	}
+	data, err := os.ReadFile(schema)
+	if err != nil {
+		return "", ErrorHasFilename(err, schema)
+	}
+	if json.Valid(data) {
+		return schema + "#format=json", nil
+	}
+	return schema + "#format=binpb", nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestSchemaInput(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto),
+		},
+	}
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	binaryFilePath := filepath.Join(dirPath, "descriptors.protoset")
+	require.NoError(t, os.WriteFile(binaryFilePath, data, 0600))
+	jsonFilePath := filepath.Join(dirPath, "descriptors.desc")
+	require.NoError(t, os.WriteFile(jsonFilePath, []byte(` {"file": [{"name": "foo.proto"}]}`), 0600))
+	knownFilePath := filepath.Join(dirPath, "image.binpb")
+	require.NoError(t, os.WriteFile(knownFilePath, data, 0600))
+
+	testCases := []struct {
+		name     string
+		schema   string
+		expected string
+	}{
+		{
+			name:     "binary",
+			schema:   binaryFilePath,
+			expected: binaryFilePath + "#format=binpb",
+		},
+		{
+			name:     "json",
+			schema:   jsonFilePath,
+			expected: jsonFilePath + "#format=json",
+		},
+		{
+			name:     "known_extension",
+			schema:   knownFilePath,
+			expected: knownFilePath,
+		},
+		{
+			name:     "explicit_format",
+			schema:   binaryFilePath + "#format=json",
+			expected: binaryFilePath + "#format=json",
+		},
+		{
+			name:     "directory",
+			schema:   dirPath,
+			expected: dirPath,
+		},
+		{
+			name:     "module",
+			schema:   "buf.build/connectrpc/eliza",
+			expected: "buf.build/connectrpc/eliza",
+		},
+		{
+			name:     "stdin",
+			schema:   "-",
+			expected: "-",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			schema, err := SchemaInput(testCase.schema)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, schema)
+		})
+	}
+}
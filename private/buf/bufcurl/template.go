@@ -0,0 +1,114 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ExpandTemplate replaces "${NAME}" placeholders in the data with the value
+// of the variable NAME.
+//
+// Values are looked up in vars first, and then with lookupEnv if it is not nil.
+// Values are inserted verbatim, so string values that are used as JSON strings
+// must be placed within quotes in the template, such as "${NAME}".
+//
+// A placeholder can be escaped as "$${NAME}", which is replaced with "${NAME}".
+// It is an error for a placeholder to refer to a variable that is not defined.
+func ExpandTemplate(
+	data []byte,
+	vars map[string]string,
+	lookupEnv func(string) (string, bool),
+) ([]byte, error) {
+	if !bytes.Contains(data, []byte("${")) {
+		return data, nil
+	}
+	var buffer bytes.Buffer
+	for len(data) > 0 {
+		index := bytes.Index(data, []byte("${"))
+		if index < 0 {
+			buffer.Write(data)
+			break
+		}
+		if index > 0 && data[index-1] == '$' {
+			// Escaped placeholder, write everything up to the escape, and then
+			// the placeholder start without the escape.
+			buffer.Write(data[:index-1])
+			buffer.WriteString("${")
+			data = data[index+2:]
+			continue
+		}
+		buffer.Write(data[:index])
+		end := bytes.IndexByte(data[index:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder %q", truncate(string(data[index:]), 20))
+		}
+		name := string(data[index+2 : index+end])
+		if !isValidTemplateVarName(name) {
+			return nil, fmt.Errorf("invalid placeholder %q: variable names must start with a letter or underscore and contain only letters, digits, and underscores", string(data[index:index+end+1]))
+		}
+		value, ok := vars[name]
+		if !ok && lookupEnv != nil {
+			value, ok = lookupEnv(name)
+		}
+		if !ok {
+			return nil, fmt.Errorf("variable %q used in placeholder is not defined", name)
+		}
+		buffer.WriteString(value)
+		data = data[index+end+1:]
+	}
+	return buffer.Bytes(), nil
+}
+
+// ParseTemplateVars parses "name=value" strings into a map of variables
+// for ExpandTemplate.
+func ParseTemplateVars(nameValues []string) (map[string]string, error) {
+	vars := make(map[string]string, len(nameValues))
+	for _, nameValue := range nameValues {
+		name, value, ok := strings.Cut(nameValue, "=")
+		if !ok {
+			return nil, fmt.Errorf("variable %q must have the form name=value", nameValue)
+		}
+		if !isValidTemplateVarName(name) {
+			return nil, fmt.Errorf("invalid variable name %q: variable names must start with a letter or underscore and contain only letters, digits, and underscores", name)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+func isValidTemplateVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
@@ -0,0 +1,76 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	t.Parallel()
+	vars := map[string]string{
+		"NAME":  "foo",
+		"COUNT": "3",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		if name == "ENV_NAME" {
+			return "from-env", true
+		}
+		return "", false
+	}
+	testExpandTemplate(t, `{"name": "foo"}`, `{"name": "foo"}`, vars, lookupEnv)
+	testExpandTemplate(t, `{"name": "${NAME}", "count": ${COUNT}}`, `{"name": "foo", "count": 3}`, vars, lookupEnv)
+	testExpandTemplate(t, `{"name": "${ENV_NAME}"}`, `{"name": "from-env"}`, vars, lookupEnv)
+	testExpandTemplate(t, `{"name": "$${NAME}", "other": "$NAME"}`, `{"name": "${NAME}", "other": "$NAME"}`, vars, lookupEnv)
+	testExpandTemplateFail(t, `{"name": "${MISSING}"}`, vars, lookupEnv)
+	testExpandTemplateFail(t, `{"name": "${ENV_NAME}"}`, vars, nil)
+	testExpandTemplateFail(t, `{"name": "${NAME"}`, vars, lookupEnv)
+	testExpandTemplateFail(t, `{"name": "${1NAME}"}`, vars, lookupEnv)
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	t.Parallel()
+	vars, err := ParseTemplateVars([]string{"NAME=foo", "EMPTY=", "EQUALS=a=b"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"NAME": "foo", "EMPTY": "", "EQUALS": "a=b"}, vars)
+	_, err = ParseTemplateVars([]string{"NAME"})
+	require.Error(t, err)
+	_, err = ParseTemplateVars([]string{"NA-ME=foo"})
+	require.Error(t, err)
+}
+
+func testExpandTemplate(
+	t *testing.T,
+	template string,
+	expected string,
+	vars map[string]string,
+	lookupEnv func(string) (string, bool),
+) {
+	actual, err := ExpandTemplate([]byte(template), vars, lookupEnv)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(actual))
+}
+
+func testExpandTemplateFail(
+	t *testing.T,
+	template string,
+	vars map[string]string,
+	lookupEnv func(string) (string, bool),
+) {
+	_, err := ExpandTemplate([]byte(template), vars, lookupEnv)
+	require.Error(t, err)
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// NewTimingHTTPClient returns a new HTTP client that writes a breakdown of
+// the time taken by each HTTP request to the given writer, when the response
+// body is complete.
+//
+// The DNS lookup, TCP connect, and TLS handshake times are the durations of
+// each phase, and are omitted if the phase did not occur, such as when an
+// existing connection is reused. The time to first byte and total times are
+// measured from the start of the request.
+func NewTimingHTTPClient(delegate connect.HTTPClient, writer io.Writer) connect.HTTPClient {
+	return &timingClient{delegate: delegate, writer: writer}
+}
+
+// *** PRIVATE ***
+
+type timingClient struct {
+	delegate connect.HTTPClient
+	writer   io.Writer
+	// lock serializes writes so that the timings of concurrent
+	// requests are not interleaved.
+	lock sync.Mutex
+}
+
+func (t *timingClient) Do(req *http.Request) (*http.Response, error) {
+	timings := newRequestTimings()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timings.clientTrace()))
+	resp, err := t.delegate.Do(req)
+	timings.gotResponse()
+	if err != nil || resp.Body == nil {
+		timings.done()
+		t.write(req, timings)
+		return resp, err
+	}
+	resp.Body = &verboseReader{
+		ReadCloser: resp.Body,
+		whenDone: func(error) {
+			timings.done()
+			t.write(req, timings)
+		},
+	}
+	return resp, nil
+}
+
+func (t *timingClient) write(req *http.Request, timings *requestTimings) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	_, _ = fmt.Fprintf(t.writer, "* Timings for %s %s\n", req.Method, req.URL.Path)
+	for _, line := range timings.lines() {
+		_, _ = fmt.Fprintf(t.writer, "*   %-20s%v\n", line.name+":", line.duration)
+	}
+}
+
+type requestTimings struct {
+	lock                 sync.Mutex
+	start                time.Time
+	dnsStart             time.Time
+	dnsDone              time.Time
+	connectStart         time.Time
+	connectDone          time.Time
+	tlsHandshakeStart    time.Time
+	tlsHandshakeDone     time.Time
+	gotFirstResponseByte time.Time
+	end                  time.Time
+}
+
+type timingLine struct {
+	name     string
+	duration time.Duration
+}
+
+func newRequestTimings() *requestTimings {
+	return &requestTimings{start: time.Now()}
+}
+
+func (r *requestTimings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.set(&r.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.set(&r.dnsDone)
+		},
+		ConnectStart: func(string, string) {
+			// With multiple addresses, connections may be attempted in
+			// parallel, so only the first start is recorded.
+			r.setIfZero(&r.connectStart)
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			if err == nil {
+				r.setIfZero(&r.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			r.set(&r.tlsHandshakeStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.set(&r.tlsHandshakeDone)
+		},
+		GotFirstResponseByte: func() {
+			r.set(&r.gotFirstResponseByte)
+		},
+	}
+}
+
+// gotResponse records the time the response headers were received as the
+// time to first byte, for transports that do not support tracing.
+func (r *requestTimings) gotResponse() {
+	r.setIfZero(&r.gotFirstResponseByte)
+}
+
+func (r *requestTimings) done() {
+	r.set(&r.end)
+}
+
+func (r *requestTimings) lines() []timingLine {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var lines []timingLine
+	if !r.dnsStart.IsZero() && !r.dnsDone.IsZero() {
+		lines = append(lines, timingLine{name: "DNS lookup", duration: roundTiming(r.dnsDone.Sub(r.dnsStart))})
+	}
+	if !r.connectStart.IsZero() && !r.connectDone.IsZero() {
+		lines = append(lines, timingLine{name: "TCP connect", duration: roundTiming(r.connectDone.Sub(r.connectStart))})
+	}
+	if !r.tlsHandshakeStart.IsZero() && !r.tlsHandshakeDone.IsZero() {
+		lines = append(lines, timingLine{name: "TLS handshake", duration: roundTiming(r.tlsHandshakeDone.Sub(r.tlsHandshakeStart))})
+	}
+	if !r.gotFirstResponseByte.IsZero() {
+		lines = append(lines, timingLine{name: "Time to first byte", duration: roundTiming(r.gotFirstResponseByte.Sub(r.start))})
+	}
+	lines = append(lines, timingLine{name: "Total", duration: roundTiming(r.end.Sub(r.start))})
+	return lines
+}
+
+func (r *requestTimings) set(t *time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	*t = time.Now()
+}
+
+func (r *requestTimings) setIfZero(t *time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+func roundTiming(duration time.Duration) time.Duration {
+	return duration.Round(time.Microsecond)
+}
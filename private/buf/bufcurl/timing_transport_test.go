@@ -0,0 +1,63 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimingHTTPClient(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, _ *http.Request) {
+		_, _ = responseWriter.Write([]byte("hello"))
+	}))
+	t.Cleanup(server.Close)
+	var output bytes.Buffer
+	client := NewTimingHTTPClient(server.Client(), &output)
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/foo.v1.FooService/Bar", nil)
+		require.NoError(t, err)
+		response, err := client.Do(request)
+		require.NoError(t, err)
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		assert.Equal(t, "hello", string(data))
+	}
+	timings := strings.Split(output.String(), "* Timings for POST /foo.v1.FooService/Bar\n")
+	require.Len(t, timings, 3)
+	assert.Empty(t, timings[0])
+	// The first request establishes the connection.
+	assert.Equal(t, []string{"TCP connect", "TLS handshake", "Time to first byte", "Total"}, timingNames(timings[1]))
+	// The second request reuses the connection.
+	assert.Equal(t, []string{"Time to first byte", "Total"}, timingNames(timings[2]))
+}
+
+func timingNames(timings string) []string {
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(timings), "\n") {
+		name, _, _ := strings.Cut(strings.TrimPrefix(line, "*   "), ":")
+		names = append(names, name)
+	}
+	return names
+}
@@ -17,18 +17,30 @@ package bufcurl
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/bufbuild/buf/private/pkg/verbose"
+	"golang.org/x/crypto/pkcs12"
 )
 
 // TLSSettings contains settings related to creating a TLS client.
 type TLSSettings struct {
 	// Filenames for a private key, certificate, and CA certificate pool.
 	KeyFile, CertFile, CACertFile string
+	// If true, CertFile is a PKCS#12 bundle that contains both the
+	// certificate and the private key, and KeyFile is not used.
+	CertFileIsPKCS12 bool
+	// The password for a PKCS#12 CertFile, if any.
+	CertPassword string
 	// Override server name, for SNI.
 	ServerName string
 	// If true, the server's certificate is not verified.
@@ -134,16 +146,8 @@ func MakeVerboseTLSConfig(settings *TLSSettings, authority string, printer verbo
 		conf.RootCAs.AppendCertsFromPEM(caCert)
 	}
 
-	if settings.KeyFile != "" && settings.CertFile != "" {
-		cert, err := os.ReadFile(settings.CertFile)
-		if err != nil {
-			return nil, ErrorHasFilename(err, settings.CertFile)
-		}
-		key, err := os.ReadFile(settings.KeyFile)
-		if err != nil {
-			return nil, ErrorHasFilename(err, settings.KeyFile)
-		}
-		certPair, err := tls.X509KeyPair(cert, key)
+	if settings.CertFile != "" && (settings.KeyFile != "" || settings.CertFileIsPKCS12) {
+		certPair, err := loadClientCertificate(settings)
 		if err != nil {
 			return nil, err
 		}
@@ -163,3 +167,114 @@ func MakeVerboseTLSConfig(settings *TLSSettings, authority string, printer verbo
 
 	return &conf, nil
 }
+
+// NewClientCertificateErrorRoundTripper returns a new http.RoundTripper that
+// describes errors caused by the server rejecting or requiring a client
+// certificate during the TLS handshake.
+//
+// Without this, such errors are reported as a bare TLS alert such as "remote
+// error: tls: bad certificate", which is easy to confuse with an authorization
+// failure reported by the server after the connection is established.
+func NewClientCertificateErrorRoundTripper(delegate http.RoundTripper, hasClientCertificate bool) http.RoundTripper {
+	return &clientCertificateErrorRoundTripper{
+		delegate:             delegate,
+		hasClientCertificate: hasClientCertificate,
+	}
+}
+
+type clientCertificateErrorRoundTripper struct {
+	delegate             http.RoundTripper
+	hasClientCertificate bool
+}
+
+func (c *clientCertificateErrorRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := c.delegate.RoundTrip(request)
+	if err != nil {
+		return nil, describeClientCertificateError(err, c.hasClientCertificate)
+	}
+	return response, nil
+}
+
+// describeClientCertificateError returns an error that describes err if it is a
+// TLS alert sent by the server that indicates a problem with the client certificate.
+//
+// Otherwise, err is returned unchanged.
+func describeClientCertificateError(err error, hasClientCertificate bool) error {
+	var opError *net.OpError
+	// Alerts received from the peer are reported by crypto/tls as a *net.OpError
+	// with an Op of "remote error", wrapping an unexported alert type.
+	if !errors.As(err, &opError) || opError.Op != "remote error" || opError.Err == nil {
+		return err
+	}
+	switch alert := opError.Err.Error(); {
+	case !hasClientCertificate && slices.Contains(clientCertificateMissingAlerts, alert):
+		return fmt.Errorf("TLS handshake failed: the server requires a client certificate: %w", err)
+	case hasClientCertificate && slices.Contains(clientCertificateRejectedAlerts, alert):
+		return fmt.Errorf("TLS handshake failed: the server rejected the client certificate: %w", err)
+	default:
+		return err
+	}
+}
+
+var (
+	// clientCertificateMissingAlerts are the TLS alerts that servers send when
+	// a client certificate is required but not provided.
+	clientCertificateMissingAlerts = []string{
+		"tls: certificate required",
+		"tls: bad certificate",
+		"tls: handshake failure",
+	}
+	// clientCertificateRejectedAlerts are the TLS alerts that servers send when
+	// the client certificate provided is not accepted.
+	clientCertificateRejectedAlerts = []string{
+		"tls: bad certificate",
+		"tls: unsupported certificate",
+		"tls: revoked certificate",
+		"tls: expired certificate",
+		"tls: unknown certificate",
+		"tls: unknown certificate authority",
+		"tls: access denied",
+		"tls: handshake failure",
+	}
+)
+
+// loadClientCertificate loads the client certificate and private key from
+// the files in the TLSSettings.
+func loadClientCertificate(settings *TLSSettings) (tls.Certificate, error) {
+	cert, err := os.ReadFile(settings.CertFile)
+	if err != nil {
+		return tls.Certificate{}, ErrorHasFilename(err, settings.CertFile)
+	}
+	if settings.CertFileIsPKCS12 {
+		// The PKCS#12 bundle is converted to PEM so that certificate chains
+		// are supported, which pkcs12.Decode does not do.
+		pemBlocks, err := pkcs12.ToPEM(cert, settings.CertPassword)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not decode PKCS#12 file %s: %w", settings.CertFile, err)
+		}
+		// The leaf certificate must come first. Only the leaf certificate and
+		// the private key have a localKeyId attribute.
+		sort.SliceStable(
+			pemBlocks,
+			func(i int, j int) bool {
+				_, iHasLocalKeyID := pemBlocks[i].Headers["localKeyId"]
+				_, jHasLocalKeyID := pemBlocks[j].Headers["localKeyId"]
+				return iHasLocalKeyID && !jHasLocalKeyID
+			},
+		)
+		var pemData []byte
+		for _, pemBlock := range pemBlocks {
+			pemData = append(pemData, pem.EncodeToMemory(pemBlock)...)
+		}
+		certPair, err := tls.X509KeyPair(pemData, pemData)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 file %s: %w", settings.CertFile, err)
+		}
+		return certPair, nil
+	}
+	key, err := os.ReadFile(settings.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, ErrorHasFilename(err, settings.KeyFile)
+	}
+	return tls.X509KeyPair(cert, key)
+}
@@ -0,0 +1,43 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeClientCertificateError(t *testing.T) {
+	t.Parallel()
+	badCertificateErr := fmt.Errorf("Post: %w", &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")})
+	certificateRequiredErr := &net.OpError{Op: "remote error", Err: errors.New("tls: certificate required")}
+	otherErr := errors.New("connection refused")
+
+	err := describeClientCertificateError(badCertificateErr, true)
+	require.ErrorIs(t, err, badCertificateErr)
+	require.ErrorContains(t, err, "the server rejected the client certificate")
+	err = describeClientCertificateError(badCertificateErr, false)
+	require.ErrorIs(t, err, badCertificateErr)
+	require.ErrorContains(t, err, "the server requires a client certificate")
+	err = describeClientCertificateError(certificateRequiredErr, false)
+	require.ErrorContains(t, err, "the server requires a client certificate")
+	// A server that requires a certificate never rejects a certificate with this alert.
+	require.Equal(t, certificateRequiredErr, describeClientCertificateError(certificateRequiredErr, true))
+	require.Equal(t, otherErr, describeClientCertificateError(otherErr, true))
+}
@@ -0,0 +1,316 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdocs renders documentation for the packages, messages, enums,
+// and services of an image from their comments and options.
+package bufdocs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+const (
+	// FormatMarkdown is the Markdown format.
+	FormatMarkdown Format = iota + 1
+	// FormatHTML is the HTML format.
+	FormatHTML
+	// FormatJSON is the JSON format.
+	//
+	// This is the structure that templates are executed with, and can be used
+	// to render documentation with other tools.
+	FormatJSON
+)
+
+var (
+	// AllFormatStrings are all string values for Format.
+	AllFormatStrings = []string{
+		"markdown",
+		"html",
+		"json",
+	}
+
+	formatToString = map[Format]string{
+		FormatMarkdown: "markdown",
+		FormatHTML:     "html",
+		FormatJSON:     "json",
+	}
+	stringToFormat = map[string]Format{
+		"markdown": FormatMarkdown,
+		"html":     FormatHTML,
+		"json":     FormatJSON,
+	}
+)
+
+// Format is a documentation format.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	s, ok := formatToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFormat parses the Format.
+//
+// The empty string is a parse error.
+func ParseFormat(s string) (Format, error) {
+	f, ok := stringToFormat[strings.ToLower(strings.TrimSpace(s))]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown Format: %q", s)
+}
+
+// Docs is the documentation of an image.
+//
+// The fields of Docs and of the types it contains are available to templates
+// under their Go names, and are printed by FormatJSON under their JSON names.
+type Docs struct {
+	// Packages are the packages of the non-import files of the image, sorted
+	// by name.
+	Packages []*Package `json:"packages"`
+}
+
+// Package is the documentation of a package.
+type Package struct {
+	// Name is the name of the package, which is empty for files without a
+	// package.
+	Name string `json:"name"`
+	// Anchor is the anchor that links to the package.
+	Anchor string `json:"anchor"`
+	// Description is the comments on the package statements of the files
+	// of the package.
+	Description string `json:"description,omitempty"`
+	// Files are the paths of the files of the package.
+	Files []string `json:"files"`
+	// Messages are the messages of the package, including nested messages,
+	// which follow the message they are nested in.
+	Messages []*Message `json:"messages,omitempty"`
+	// Enums are the enums of the package, including nested enums.
+	Enums []*Enum `json:"enums,omitempty"`
+	// Services are the services of the package.
+	Services []*Service `json:"services,omitempty"`
+	// Extensions are the extensions defined in the package, including those
+	// defined within messages.
+	Extensions []*Field `json:"extensions,omitempty"`
+}
+
+// Message is the documentation of a message.
+type Message struct {
+	// Name is the name of the message within its package, such as
+	// "Outer.Inner" for nested messages.
+	Name        string    `json:"name"`
+	FullName    string    `json:"fullName"`
+	Anchor      string    `json:"anchor"`
+	Description string    `json:"description,omitempty"`
+	File        string    `json:"file"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+	Options     []*Option `json:"options,omitempty"`
+	Fields      []*Field  `json:"fields,omitempty"`
+}
+
+// Field is the documentation of a field or extension.
+type Field struct {
+	Name   string `json:"name"`
+	Number int32  `json:"number"`
+	// Label is "repeated", "optional", or "required" if the field has that
+	// label in the source, and empty otherwise.
+	Label string `json:"label,omitempty"`
+	// Type is the name of a scalar type, the full name of a message or enum,
+	// or "map<K, V>" for map fields.
+	Type string `json:"type"`
+	// TypeAnchor is the anchor that links to the message or enum of the field,
+	// or to the value of a map field. This is empty for scalar types and for
+	// types that are not documented, such as those defined in imports.
+	TypeAnchor string `json:"typeAnchor,omitempty"`
+	// Oneof is the name of the oneof the field is in, if any.
+	Oneof string `json:"oneof,omitempty"`
+	// Extendee is the full name of the message an extension extends, and is
+	// empty for fields.
+	Extendee string `json:"extendee,omitempty"`
+	// ExtendeeAnchor is the anchor that links to the extended message, if it
+	// is documented.
+	ExtendeeAnchor string `json:"extendeeAnchor,omitempty"`
+	// DefaultValue is the explicit default value of the field, if any.
+	DefaultValue string    `json:"defaultValue,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Deprecated   bool      `json:"deprecated,omitempty"`
+	Options      []*Option `json:"options,omitempty"`
+}
+
+// Enum is the documentation of an enum.
+type Enum struct {
+	// Name is the name of the enum within its package, such as "Outer.Enum"
+	// for nested enums.
+	Name        string       `json:"name"`
+	FullName    string       `json:"fullName"`
+	Anchor      string       `json:"anchor"`
+	Description string       `json:"description,omitempty"`
+	File        string       `json:"file"`
+	Deprecated  bool         `json:"deprecated,omitempty"`
+	Options     []*Option    `json:"options,omitempty"`
+	Values      []*EnumValue `json:"values"`
+}
+
+// EnumValue is the documentation of an enum value.
+type EnumValue struct {
+	Name        string    `json:"name"`
+	Number      int32     `json:"number"`
+	Description string    `json:"description,omitempty"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+	Options     []*Option `json:"options,omitempty"`
+}
+
+// Service is the documentation of a service.
+type Service struct {
+	Name        string    `json:"name"`
+	FullName    string    `json:"fullName"`
+	Anchor      string    `json:"anchor"`
+	Description string    `json:"description,omitempty"`
+	File        string    `json:"file"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+	Options     []*Option `json:"options,omitempty"`
+	Methods     []*Method `json:"methods,omitempty"`
+}
+
+// Method is the documentation of a method.
+type Method struct {
+	Name               string    `json:"name"`
+	RequestType        string    `json:"requestType"`
+	RequestTypeAnchor  string    `json:"requestTypeAnchor,omitempty"`
+	RequestStreaming   bool      `json:"requestStreaming,omitempty"`
+	ResponseType       string    `json:"responseType"`
+	ResponseTypeAnchor string    `json:"responseTypeAnchor,omitempty"`
+	ResponseStreaming  bool      `json:"responseStreaming,omitempty"`
+	Description        string    `json:"description,omitempty"`
+	Deprecated         bool      `json:"deprecated,omitempty"`
+	Options            []*Option `json:"options,omitempty"`
+}
+
+// Option is an option set on an element.
+//
+// The deprecated option is not included, as it is documented by the
+// Deprecated field of each element.
+type Option struct {
+	// Name is the name of the option, which is in parentheses for custom
+	// options, as in the source.
+	Name string `json:"name"`
+	// Value is the value of the option. Messages are formatted as JSON.
+	Value string `json:"value"`
+}
+
+// NewDocs returns the documentation of the non-import files of the image.
+//
+// Imports are used to resolve the custom options set by the files, but are
+// not documented, and types defined in imports are not linked to.
+func NewDocs(image bufimage.Image) (*Docs, error) {
+	return newDocsBuilder(image).build()
+}
+
+// DefaultTemplate returns the template that is used to render the format
+// if no template is given.
+//
+// Returns an error for FormatJSON, which is not rendered with a template.
+func DefaultTemplate(format Format) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate, nil
+	case FormatHTML:
+		return htmlTemplate, nil
+	case FormatJSON:
+		return "", errors.New("the json format is not rendered with a template")
+	default:
+		return "", fmt.Errorf("unknown Format: %v", format)
+	}
+}
+
+// Render writes the documentation in the format.
+func Render(writer io.Writer, format Format, docs *Docs, options ...RenderOption) error {
+	renderOptions := newRenderOptions()
+	for _, option := range options {
+		option(renderOptions)
+	}
+	if format == FormatJSON {
+		if renderOptions.template != "" {
+			return errors.New("a template cannot be used with the json format")
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(docs)
+	}
+	templateText := renderOptions.template
+	if templateText == "" {
+		var err error
+		templateText, err = DefaultTemplate(format)
+		if err != nil {
+			return err
+		}
+	}
+	switch format {
+	case FormatMarkdown:
+		tmpl, err := texttemplate.New(format.String()).Funcs(markdownFuncMap).Parse(templateText)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(writer, docs)
+	case FormatHTML:
+		// html/template escapes the documentation, as comments are not trusted to
+		// be valid HTML.
+		tmpl, err := htmltemplate.New(format.String()).Parse(templateText)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(writer, docs)
+	default:
+		return fmt.Errorf("unknown Format: %v", format)
+	}
+}
+
+// RenderOption is an option for Render.
+type RenderOption func(*renderOptions)
+
+// RenderWithTemplate returns a new RenderOption that renders the documentation
+// with the given template instead of the default template of the format.
+//
+// Templates for FormatMarkdown are text/template templates, and templates for
+// FormatHTML are html/template templates. Both are executed with the *Docs.
+// Templates for FormatMarkdown can call markdownCell, which formats text for a
+// cell of a Markdown table.
+func RenderWithTemplate(template string) RenderOption {
+	return func(renderOptions *renderOptions) {
+		renderOptions.template = template
+	}
+}
+
+// *** PRIVATE ***
+
+type renderOptions struct {
+	template string
+}
+
+func newRenderOptions() *renderOptions {
+	return &renderOptions{}
+}
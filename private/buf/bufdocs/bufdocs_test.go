@@ -0,0 +1,187 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	for _, s := range AllFormatStrings {
+		format, err := ParseFormat(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, format.String())
+	}
+	_, err := ParseFormat("")
+	assert.Error(t, err)
+}
+
+func TestMarkdownCell(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", markdownCell(""))
+	assert.Equal(t, `a \| b`, markdownCell("a | b"))
+	assert.Equal(t, "one two<br><br>three", markdownCell("one\ntwo\n\n\n\nthree\n"))
+}
+
+func TestNewDocs(t *testing.T) {
+	t.Parallel()
+	// A file without a package, with a map field whose values are of a documented
+	// type, and a field of a type that is not documented.
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"b.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("A"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("values"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".A.ValuesEntry"),
+						JsonName: proto.String("values"),
+					},
+					{
+						Name:     proto.String("b"),
+						Number:   proto.Int32(2),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".B"),
+						JsonName: proto.String("b"),
+						Options: &descriptorpb.FieldOptions{
+							Deprecated: proto.Bool(true),
+							Packed:     proto.Bool(false),
+						},
+					},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("ValuesEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     proto.String("key"),
+								Number:   proto.Int32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+								JsonName: proto.String("key"),
+							},
+							{
+								Name:     proto.String("value"),
+								Number:   proto.Int32(2),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+								TypeName: proto.String(".A"),
+								JsonName: proto.String("value"),
+							},
+						},
+						Options: &descriptorpb.MessageOptions{
+							MapEntry: proto.Bool(true),
+						},
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{
+					Path:            []int32{4, 0},
+					Span:            []int32{0, 0, 1},
+					LeadingComments: proto.String(" A is a message.\n\n Really.\n"),
+				},
+			},
+		},
+	}
+	importFile := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("b.proto"),
+		Syntax: proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("B"),
+			},
+		},
+	}
+	importImageFile, err := bufimage.NewImageFile(importFile, nil, uuid.UUID{}, "b.proto", "b.proto", true, false, nil)
+	require.NoError(t, err)
+	imageFile, err := bufimage.NewImageFile(file, nil, uuid.UUID{}, "a.proto", "a.proto", false, false, nil)
+	require.NoError(t, err)
+	image, err := bufimage.NewImage([]bufimage.ImageFile{importImageFile, imageFile})
+	require.NoError(t, err)
+
+	docs, err := NewDocs(image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Docs{
+			Packages: []*Package{
+				{
+					Name:   "",
+					Anchor: "package-",
+					Files:  []string{"a.proto"},
+					Messages: []*Message{
+						{
+							Name:        "A",
+							FullName:    "A",
+							Anchor:      "A",
+							Description: "A is a message.\n\nReally.",
+							File:        "a.proto",
+							Fields: []*Field{
+								{
+									Name:       "values",
+									Number:     1,
+									Type:       "map<string, A>",
+									TypeAnchor: "A",
+								},
+								{
+									Name:       "b",
+									Number:     2,
+									Type:       "B",
+									Deprecated: true,
+									Options: []*Option{
+										{
+											Name:  "packed",
+											Value: "false",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		docs,
+	)
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, Render(buffer, FormatMarkdown, docs, RenderWithTemplate(`{{range .Packages}}{{range .Messages}}{{range .Fields}}{{.Name}}:{{.Type}};{{end}}{{end}}{{end}}`)))
+	assert.Equal(t, "values:map<string, A>;b:B;", buffer.String())
+	buffer.Reset()
+	require.NoError(t, Render(buffer, FormatHTML, docs, RenderWithTemplate(`{{range .Packages}}{{range .Messages}}{{range .Fields}}{{.Type}};{{end}}{{end}}{{end}}`)))
+	assert.Equal(t, "map&lt;string, A&gt;;B;", buffer.String())
+	assert.Error(t, Render(buffer, FormatJSON, docs, RenderWithTemplate("{{.}}")))
+	_, err = DefaultTemplate(FormatJSON)
+	assert.Error(t, err)
+}
@@ -0,0 +1,484 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// packageFieldNumber is the number of the package field of
+	// google.protobuf.FileDescriptorProto, which is the source path of the
+	// package statement of a file.
+	packageFieldNumber = 2
+	// deprecatedOptionName is the name of the deprecated option, which every
+	// options message that has it defines with this name.
+	deprecatedOptionName = "deprecated"
+)
+
+type docsBuilder struct {
+	image    bufimage.Image
+	resolver protoencoding.Resolver
+	// optionMarshaler formats options whose values are messages.
+	optionMarshaler protoencoding.Marshaler
+	// fullNameToAnchor contains the anchors of all documented messages and enums.
+	fullNameToAnchor map[protoreflect.FullName]string
+}
+
+func newDocsBuilder(image bufimage.Image) *docsBuilder {
+	resolver := image.Resolver()
+	return &docsBuilder{
+		image:    image,
+		resolver: resolver,
+		// The canonical form is used so that the same options are always
+		// documented the same way.
+		optionMarshaler:  protoencoding.NewJSONMarshaler(resolver, protoencoding.JSONMarshalerWithCanonical()),
+		fullNameToAnchor: make(map[protoreflect.FullName]string),
+	}
+}
+
+func (b *docsBuilder) build() (*Docs, error) {
+	var fileDescriptors []protoreflect.FileDescriptor
+	for _, imageFile := range b.image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := b.resolver.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		fileDescriptors = append(fileDescriptors, fileDescriptor)
+	}
+	slices.SortFunc(
+		fileDescriptors,
+		func(one protoreflect.FileDescriptor, two protoreflect.FileDescriptor) int {
+			return strings.Compare(one.Path(), two.Path())
+		},
+	)
+	// All anchors are needed before any element is documented, as fields may
+	// refer to types defined later or in other files.
+	for _, fileDescriptor := range fileDescriptors {
+		b.addAnchors(fileDescriptor.Messages(), fileDescriptor.Enums())
+	}
+	docs := &Docs{}
+	nameToPackage := make(map[string]*Package)
+	for _, fileDescriptor := range fileDescriptors {
+		packageName := string(fileDescriptor.Package())
+		pkg, ok := nameToPackage[packageName]
+		if !ok {
+			pkg = &Package{
+				Name:   packageName,
+				Anchor: "package-" + packageName,
+			}
+			nameToPackage[packageName] = pkg
+			docs.Packages = append(docs.Packages, pkg)
+		}
+		pkg.Files = append(pkg.Files, fileDescriptor.Path())
+		packageDescription := getDescription(
+			fileDescriptor.SourceLocations().ByPath(protoreflect.SourcePath{packageFieldNumber}),
+		)
+		if packageDescription != "" {
+			if pkg.Description != "" {
+				pkg.Description += "\n\n"
+			}
+			pkg.Description += packageDescription
+		}
+		if err := b.addMessages(pkg, fileDescriptor.Messages()); err != nil {
+			return nil, err
+		}
+		if err := b.addEnums(pkg, fileDescriptor.Enums()); err != nil {
+			return nil, err
+		}
+		if err := b.addExtensions(pkg, fileDescriptor.Extensions()); err != nil {
+			return nil, err
+		}
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			service, err := b.newService(pkg, services.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			pkg.Services = append(pkg.Services, service)
+		}
+	}
+	slices.SortFunc(
+		docs.Packages,
+		func(one *Package, two *Package) int {
+			return strings.Compare(one.Name, two.Name)
+		},
+	)
+	return docs, nil
+}
+
+func (b *docsBuilder) addAnchors(messages protoreflect.MessageDescriptors, enums protoreflect.EnumDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		if message.IsMapEntry() {
+			continue
+		}
+		b.fullNameToAnchor[message.FullName()] = string(message.FullName())
+		b.addAnchors(message.Messages(), message.Enums())
+	}
+	for i := 0; i < enums.Len(); i++ {
+		b.fullNameToAnchor[enums.Get(i).FullName()] = string(enums.Get(i).FullName())
+	}
+}
+
+func (b *docsBuilder) addMessages(pkg *Package, messages protoreflect.MessageDescriptors) error {
+	for i := 0; i < messages.Len(); i++ {
+		messageDescriptor := messages.Get(i)
+		// Map entries are documented as the type of their map field.
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		options, deprecated, err := b.getOptions(messageDescriptor)
+		if err != nil {
+			return err
+		}
+		message := &Message{
+			Name:        nameInPackage(pkg, messageDescriptor.FullName()),
+			FullName:    string(messageDescriptor.FullName()),
+			Anchor:      b.fullNameToAnchor[messageDescriptor.FullName()],
+			Description: getDescriptorDescription(messageDescriptor),
+			File:        messageDescriptor.ParentFile().Path(),
+			Deprecated:  deprecated,
+			Options:     options,
+		}
+		fields := messageDescriptor.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			field, err := b.newField(pkg, fields.Get(j))
+			if err != nil {
+				return err
+			}
+			message.Fields = append(message.Fields, field)
+		}
+		pkg.Messages = append(pkg.Messages, message)
+		if err := b.addMessages(pkg, messageDescriptor.Messages()); err != nil {
+			return err
+		}
+		if err := b.addEnums(pkg, messageDescriptor.Enums()); err != nil {
+			return err
+		}
+		if err := b.addExtensions(pkg, messageDescriptor.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *docsBuilder) addEnums(pkg *Package, enums protoreflect.EnumDescriptors) error {
+	for i := 0; i < enums.Len(); i++ {
+		enumDescriptor := enums.Get(i)
+		options, deprecated, err := b.getOptions(enumDescriptor)
+		if err != nil {
+			return err
+		}
+		enum := &Enum{
+			Name:        nameInPackage(pkg, enumDescriptor.FullName()),
+			FullName:    string(enumDescriptor.FullName()),
+			Anchor:      b.fullNameToAnchor[enumDescriptor.FullName()],
+			Description: getDescriptorDescription(enumDescriptor),
+			File:        enumDescriptor.ParentFile().Path(),
+			Deprecated:  deprecated,
+			Options:     options,
+			Values:      []*EnumValue{},
+		}
+		values := enumDescriptor.Values()
+		for j := 0; j < values.Len(); j++ {
+			valueDescriptor := values.Get(j)
+			options, deprecated, err := b.getOptions(valueDescriptor)
+			if err != nil {
+				return err
+			}
+			enum.Values = append(
+				enum.Values,
+				&EnumValue{
+					Name:        string(valueDescriptor.Name()),
+					Number:      int32(valueDescriptor.Number()),
+					Description: getDescriptorDescription(valueDescriptor),
+					Deprecated:  deprecated,
+					Options:     options,
+				},
+			)
+		}
+		pkg.Enums = append(pkg.Enums, enum)
+	}
+	return nil
+}
+
+func (b *docsBuilder) addExtensions(pkg *Package, extensions protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < extensions.Len(); i++ {
+		extension, err := b.newField(pkg, extensions.Get(i))
+		if err != nil {
+			return err
+		}
+		pkg.Extensions = append(pkg.Extensions, extension)
+	}
+	return nil
+}
+
+func (b *docsBuilder) newField(pkg *Package, fieldDescriptor protoreflect.FieldDescriptor) (*Field, error) {
+	options, deprecated, err := b.getOptions(fieldDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{
+		Name:        string(fieldDescriptor.Name()),
+		Number:      int32(fieldDescriptor.Number()),
+		Description: getDescriptorDescription(fieldDescriptor),
+		Deprecated:  deprecated,
+		Options:     options,
+	}
+	switch {
+	case fieldDescriptor.IsMap():
+		field.Type = "map<" + getTypeName(fieldDescriptor.MapKey()) + ", " + getTypeName(fieldDescriptor.MapValue()) + ">"
+		field.TypeAnchor = b.getTypeAnchor(fieldDescriptor.MapValue())
+	case fieldDescriptor.Cardinality() == protoreflect.Repeated:
+		field.Label = "repeated"
+	case fieldDescriptor.Cardinality() == protoreflect.Required:
+		field.Label = "required"
+	case fieldDescriptor.HasOptionalKeyword():
+		field.Label = "optional"
+	}
+	if field.Type == "" {
+		field.Type = getTypeName(fieldDescriptor)
+		field.TypeAnchor = b.getTypeAnchor(fieldDescriptor)
+	}
+	if oneof := fieldDescriptor.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		field.Oneof = string(oneof.Name())
+	}
+	if fieldDescriptor.IsExtension() {
+		field.Name = nameInPackage(pkg, fieldDescriptor.FullName())
+		field.Extendee = string(fieldDescriptor.ContainingMessage().FullName())
+		field.ExtendeeAnchor = b.fullNameToAnchor[fieldDescriptor.ContainingMessage().FullName()]
+	}
+	if fieldDescriptor.HasDefault() {
+		field.DefaultValue = b.formatSingularValue(fieldDescriptor, fieldDescriptor.Default())
+	}
+	return field, nil
+}
+
+func (b *docsBuilder) newService(pkg *Package, serviceDescriptor protoreflect.ServiceDescriptor) (*Service, error) {
+	options, deprecated, err := b.getOptions(serviceDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	service := &Service{
+		Name:        nameInPackage(pkg, serviceDescriptor.FullName()),
+		FullName:    string(serviceDescriptor.FullName()),
+		Anchor:      string(serviceDescriptor.FullName()),
+		Description: getDescriptorDescription(serviceDescriptor),
+		File:        serviceDescriptor.ParentFile().Path(),
+		Deprecated:  deprecated,
+		Options:     options,
+	}
+	methods := serviceDescriptor.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		methodDescriptor := methods.Get(i)
+		options, deprecated, err := b.getOptions(methodDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		service.Methods = append(
+			service.Methods,
+			&Method{
+				Name:               string(methodDescriptor.Name()),
+				RequestType:        string(methodDescriptor.Input().FullName()),
+				RequestTypeAnchor:  b.fullNameToAnchor[methodDescriptor.Input().FullName()],
+				RequestStreaming:   methodDescriptor.IsStreamingClient(),
+				ResponseType:       string(methodDescriptor.Output().FullName()),
+				ResponseTypeAnchor: b.fullNameToAnchor[methodDescriptor.Output().FullName()],
+				ResponseStreaming:  methodDescriptor.IsStreamingServer(),
+				Description:        getDescriptorDescription(methodDescriptor),
+				Deprecated:         deprecated,
+				Options:            options,
+			},
+		)
+	}
+	return service, nil
+}
+
+// getOptions returns the options set on the descriptor, other than the
+// deprecated option, and whether the descriptor is deprecated.
+func (b *docsBuilder) getOptions(descriptor protoreflect.Descriptor) ([]*Option, bool, error) {
+	options := descriptor.Options()
+	// Descriptors without options return a typed nil message.
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return nil, false, nil
+	}
+	var deprecated bool
+	if deprecatedOptions, ok := options.(interface{ GetDeprecated() bool }); ok {
+		deprecated = deprecatedOptions.GetDeprecated()
+	}
+	// Custom options are unrecognized fields until they are parsed with a resolver
+	// that knows their extensions. The options are shared with the descriptor, so
+	// they are cloned before being parsed.
+	options = proto.Clone(options)
+	if err := protoencoding.ReparseExtensions(b.resolver, options.ProtoReflect()); err != nil {
+		return nil, false, err
+	}
+	var optionFields []protoreflect.FieldDescriptor
+	options.ProtoReflect().Range(
+		func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if field.IsExtension() || field.Name() != deprecatedOptionName {
+				optionFields = append(optionFields, field)
+			}
+			return true
+		},
+	)
+	// Standard options are first, in the order of their numbers, followed by
+	// custom options in the order of their names.
+	slices.SortFunc(
+		optionFields,
+		func(one protoreflect.FieldDescriptor, two protoreflect.FieldDescriptor) int {
+			if one.IsExtension() != two.IsExtension() {
+				if two.IsExtension() {
+					return -1
+				}
+				return 1
+			}
+			if one.IsExtension() {
+				return strings.Compare(string(one.FullName()), string(two.FullName()))
+			}
+			return int(one.Number()) - int(two.Number())
+		},
+	)
+	var result []*Option
+	for _, optionField := range optionFields {
+		name := string(optionField.Name())
+		if optionField.IsExtension() {
+			name = "(" + string(optionField.FullName()) + ")"
+		}
+		value, err := b.formatValue(optionField, options.ProtoReflect().Get(optionField))
+		if err != nil {
+			return nil, false, err
+		}
+		result = append(result, &Option{Name: name, Value: value})
+	}
+	return result, deprecated, nil
+}
+
+func (b *docsBuilder) formatValue(field protoreflect.FieldDescriptor, value protoreflect.Value) (string, error) {
+	if field.IsList() {
+		list := value.List()
+		elements := make([]string, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			element, err := b.formatSingularMessageOrValue(field, list.Get(i))
+			if err != nil {
+				return "", err
+			}
+			elements[i] = element
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+	}
+	return b.formatSingularMessageOrValue(field, value)
+}
+
+func (b *docsBuilder) formatSingularMessageOrValue(field protoreflect.FieldDescriptor, value protoreflect.Value) (string, error) {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, err := b.optionMarshaler.Marshal(value.Message().Interface())
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	default:
+		return b.formatSingularValue(field, value), nil
+	}
+}
+
+// formatSingularValue formats a value that is not a message as it would be
+// written in the source.
+func (*docsBuilder) formatSingularValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(value.Bytes()))
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	default:
+		return value.String()
+	}
+}
+
+// getTypeAnchor returns the anchor of the message or enum of the field, if it
+// is documented.
+func (b *docsBuilder) getTypeAnchor(fieldDescriptor protoreflect.FieldDescriptor) string {
+	switch {
+	case fieldDescriptor.Message() != nil:
+		return b.fullNameToAnchor[fieldDescriptor.Message().FullName()]
+	case fieldDescriptor.Enum() != nil:
+		return b.fullNameToAnchor[fieldDescriptor.Enum().FullName()]
+	default:
+		return ""
+	}
+}
+
+// getTypeName returns the full name of the message or enum of the field, or
+// the name of its scalar type.
+func getTypeName(fieldDescriptor protoreflect.FieldDescriptor) string {
+	switch {
+	case fieldDescriptor.Message() != nil:
+		return string(fieldDescriptor.Message().FullName())
+	case fieldDescriptor.Enum() != nil:
+		return string(fieldDescriptor.Enum().FullName())
+	default:
+		return fieldDescriptor.Kind().String()
+	}
+}
+
+// nameInPackage returns the full name without the package name.
+func nameInPackage(pkg *Package, fullName protoreflect.FullName) string {
+	if pkg.Name == "" {
+		return string(fullName)
+	}
+	return strings.TrimPrefix(string(fullName), pkg.Name+".")
+}
+
+func getDescriptorDescription(descriptor protoreflect.Descriptor) string {
+	return getDescription(descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor))
+}
+
+// getDescription returns the leading comments of the source location, or its
+// trailing comments if it has no leading comments.
+//
+// Comments have the space that usually follows the comment marker removed from
+// each line, and leading and trailing blank lines removed.
+func getDescription(sourceLocation protoreflect.SourceLocation) string {
+	comments := sourceLocation.LeadingComments
+	if strings.TrimSpace(comments) == "" {
+		comments = sourceLocation.TrailingComments
+	}
+	lines := strings.Split(comments, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(line, " \t\r"), " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
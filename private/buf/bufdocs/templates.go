@@ -0,0 +1,274 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	markdownTemplate = `{{- define "packageName"}}{{if .Name}}{{.Name}}{{else}}(no package){{end}}{{end -}}
+{{- define "deprecated"}}{{if .Deprecated}}**Deprecated.** {{end}}{{end -}}
+{{- define "options"}}{{with .}}
+
+Options:
+{{range .}}
+- ` + "`" + `{{.Name}} = {{.Value}}` + "`" + `
+{{- end}}{{end}}{{end -}}
+{{- define "optionsCell"}}{{range .}} ` + "`" + `{{.Name}} = {{markdownCell .Value}}` + "`" + `{{end}}{{end -}}
+{{- define "typeLink"}}{{if .TypeAnchor}}[` + "`" + `{{.Type}}` + "`" + `](#{{.TypeAnchor}}){{else}}` + "`" + `{{.Type}}` + "`" + `{{end}}{{end -}}
+# Protobuf Documentation
+
+## Table of Contents
+{{range .Packages}}
+- [{{template "packageName" .}}](#{{.Anchor}})
+{{- range .Messages}}
+  - [{{.Name}}](#{{.Anchor}})
+{{- end}}
+{{- range .Enums}}
+  - [{{.Name}}](#{{.Anchor}})
+{{- end}}
+{{- range .Services}}
+  - [{{.Name}}](#{{.Anchor}})
+{{- end}}
+{{- end}}
+{{- range .Packages}}
+
+<a name="{{.Anchor}}"></a>
+
+## {{template "packageName" .}}
+{{- with .Description}}
+
+{{.}}
+{{- end}}
+
+Files:
+{{range .Files}}
+- ` + "`" + `{{.}}` + "`" + `
+{{- end}}
+{{- range .Messages}}
+
+<a name="{{.Anchor}}"></a>
+
+### {{.Name}}
+{{- if .Deprecated}}
+
+**Deprecated.**
+{{- end}}
+{{- with .Description}}
+
+{{.}}
+{{- end}}
+{{- template "options" .Options}}
+{{- with .Fields}}
+
+| Field | Number | Type | Description |
+| ----- | ------ | ---- | ----------- |
+{{- range .}}
+| {{.Name}} | {{.Number}} | {{with .Label}}{{.}} {{end}}{{template "typeLink" .}} | {{template "deprecated" .}}{{markdownCell .Description}}{{with .Oneof}} Oneof ` + "`" + `{{.}}` + "`" + `.{{end}}{{with .DefaultValue}} Default ` + "`" + `{{markdownCell .}}` + "`" + `.{{end}}{{template "optionsCell" .Options}} |
+{{- end}}
+{{- end}}
+{{- end}}
+{{- range .Enums}}
+
+<a name="{{.Anchor}}"></a>
+
+### {{.Name}}
+{{- if .Deprecated}}
+
+**Deprecated.**
+{{- end}}
+{{- with .Description}}
+
+{{.}}
+{{- end}}
+{{- template "options" .Options}}
+
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+{{- range .Values}}
+| {{.Name}} | {{.Number}} | {{template "deprecated" .}}{{markdownCell .Description}}{{template "optionsCell" .Options}} |
+{{- end}}
+{{- end}}
+{{- range .Services}}
+
+<a name="{{.Anchor}}"></a>
+
+### {{.Name}}
+{{- if .Deprecated}}
+
+**Deprecated.**
+{{- end}}
+{{- with .Description}}
+
+{{.}}
+{{- end}}
+{{- template "options" .Options}}
+{{- with .Methods}}
+
+| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+{{- range .}}
+| {{.Name}} | {{if .RequestStreaming}}stream {{end}}{{if .RequestTypeAnchor}}[` + "`" + `{{.RequestType}}` + "`" + `](#{{.RequestTypeAnchor}}){{else}}` + "`" + `{{.RequestType}}` + "`" + `{{end}} | {{if .ResponseStreaming}}stream {{end}}{{if .ResponseTypeAnchor}}[` + "`" + `{{.ResponseType}}` + "`" + `](#{{.ResponseTypeAnchor}}){{else}}` + "`" + `{{.ResponseType}}` + "`" + `{{end}} | {{template "deprecated" .}}{{markdownCell .Description}}{{template "optionsCell" .Options}} |
+{{- end}}
+{{- end}}
+{{- end}}
+{{- with .Extensions}}
+
+### Extensions
+
+| Extension | Number | Type | Extends | Description |
+| --------- | ------ | ---- | ------- | ----------- |
+{{- range .}}
+| {{.Name}} | {{.Number}} | {{with .Label}}{{.}} {{end}}{{template "typeLink" .}} | {{if .ExtendeeAnchor}}[` + "`" + `{{.Extendee}}` + "`" + `](#{{.ExtendeeAnchor}}){{else}}` + "`" + `{{.Extendee}}` + "`" + `{{end}} | {{template "deprecated" .}}{{markdownCell .Description}}{{template "optionsCell" .Options}} |
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+	htmlTemplate = `{{- define "packageName"}}{{if .Name}}{{.Name}}{{else}}(no package){{end}}{{end -}}
+{{- define "header"}}
+{{- if .Deprecated}}
+<p class="deprecated">Deprecated.</p>
+{{- end}}
+{{- with .Description}}
+<p class="description">{{.}}</p>
+{{- end}}
+{{- with .Options}}
+<ul class="options">
+{{- range .}}
+<li><code>{{.Name}} = {{.Value}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end -}}
+{{- define "descriptionCell"}}
+{{- if .Deprecated}}<span class="deprecated">Deprecated.</span> {{end}}
+{{- with .Description}}<span class="description">{{.}}</span>{{end}}
+{{- range .Options}} <code>{{.Name}} = {{.Value}}</code>{{end}}
+{{- end -}}
+{{- define "typeLink"}}{{if .TypeAnchor}}<a href="#{{.TypeAnchor}}"><code>{{.Type}}</code></a>{{else}}<code>{{.Type}}</code>{{end}}{{end -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Protobuf Documentation</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 1000px; padding: 1em; }
+table { border-collapse: collapse; margin: 1em 0; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+.description { white-space: pre-line; }
+.deprecated { color: #b00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Protobuf Documentation</h1>
+<h2>Table of Contents</h2>
+<ul>
+{{- range .Packages}}
+<li><a href="#{{.Anchor}}">{{template "packageName" .}}</a>
+<ul>
+{{- range .Messages}}
+<li><a href="#{{.Anchor}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Enums}}
+<li><a href="#{{.Anchor}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Services}}
+<li><a href="#{{.Anchor}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+{{- range .Packages}}
+<h2 id="{{.Anchor}}">{{template "packageName" .}}</h2>
+{{- with .Description}}
+<p class="description">{{.}}</p>
+{{- end}}
+<p>Files:</p>
+<ul>
+{{- range .Files}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- range .Messages}}
+<h3 id="{{.Anchor}}">{{.Name}}</h3>
+{{- template "header" .}}
+{{- with .Fields}}
+<table>
+<tr><th>Field</th><th>Number</th><th>Type</th><th>Description</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{with .Label}}{{.}} {{end}}{{template "typeLink" .}}</td><td>{{template "descriptionCell" .}}{{with .Oneof}} Oneof <code>{{.}}</code>.{{end}}{{with .DefaultValue}} Default <code>{{.}}</code>.{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- range .Enums}}
+<h3 id="{{.Anchor}}">{{.Name}}</h3>
+{{- template "header" .}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{- range .Values}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{template "descriptionCell" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Services}}
+<h3 id="{{.Anchor}}">{{.Name}}</h3>
+{{- template "header" .}}
+{{- with .Methods}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{if .RequestStreaming}}stream {{end}}{{if .RequestTypeAnchor}}<a href="#{{.RequestTypeAnchor}}"><code>{{.RequestType}}</code></a>{{else}}<code>{{.RequestType}}</code>{{end}}</td><td>{{if .ResponseStreaming}}stream {{end}}{{if .ResponseTypeAnchor}}<a href="#{{.ResponseTypeAnchor}}"><code>{{.ResponseType}}</code></a>{{else}}<code>{{.ResponseType}}</code>{{end}}</td><td>{{template "descriptionCell" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- with .Extensions}}
+<h3>Extensions</h3>
+<table>
+<tr><th>Extension</th><th>Number</th><th>Type</th><th>Extends</th><th>Description</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{with .Label}}{{.}} {{end}}{{template "typeLink" .}}</td><td>{{if .ExtendeeAnchor}}<a href="#{{.ExtendeeAnchor}}"><code>{{.Extendee}}</code></a>{{else}}<code>{{.Extendee}}</code>{{end}}</td><td>{{template "descriptionCell" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`
+)
+
+var markdownFuncMap = texttemplate.FuncMap{
+	"markdownCell": markdownCell,
+}
+
+// markdownCell formats text for a cell of a Markdown table, which must be on
+// a single line and cannot contain unescaped pipes.
+//
+// Lines within a paragraph are joined with spaces, and paragraphs are separated
+// with line breaks.
+func markdownCell(s string) string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "|", `\|`), "\n\n") {
+		if paragraph := strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return strings.Join(paragraphs, "<br><br>")
+}
@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 
 	"github.com/bufbuild/buf/private/buf/buftarget"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/git"
@@ -868,29 +869,57 @@ func fsRootAndFSRelPathForPath(path string) (string, string, error) {
 //
 // This is going to take away other intermediate errors unfortunately.
 func attemptToFixOSRootBucketPathErrors(fsRoot string, err error) error {
+	var decodeError *bufconfig.DecodeError
+	if errors.As(err, &decodeError) {
+		relPath, ok, fixErr := attemptToFixOSRootBucketPath(fsRoot, decodeError.Path)
+		if fixErr != nil {
+			return fixErr
+		}
+		if ok {
+			// Making a copy just to be super-safe.
+			return &bufconfig.DecodeError{
+				Path: relPath,
+				Err:  decodeError.Err,
+			}
+		}
+		return err
+	}
 	var pathError *fs.PathError
 	if errors.As(err, &pathError) {
-		pwd, err := osext.Getwd()
-		if err != nil {
-			return err
+		relPath, ok, fixErr := attemptToFixOSRootBucketPath(fsRoot, pathError.Path)
+		if fixErr != nil {
+			return fixErr
 		}
-		pwd = normalpath.Normalize(pwd)
-		if normalpath.EqualsOrContainsPath(pwd, normalpath.Join(fsRoot, pathError.Path), normalpath.Absolute) {
-			relPath, err := normalpath.Rel(pwd, normalpath.Join(fsRoot, pathError.Path))
-			// Just ignore if this errors and do nothing.
-			if err == nil {
-				// Making a copy just to be super-safe.
-				return &fs.PathError{
-					Op:   pathError.Op,
-					Path: relPath,
-					Err:  pathError.Err,
-				}
+		if ok {
+			// Making a copy just to be super-safe.
+			return &fs.PathError{
+				Op:   pathError.Op,
+				Path: relPath,
+				Err:  pathError.Err,
 			}
 		}
 	}
 	return err
 }
 
+// attemptToFixOSRootBucketPath returns the path relative to the current working
+// directory if the path within fsRoot is contained within the current working directory.
+func attemptToFixOSRootBucketPath(fsRoot string, path string) (string, bool, error) {
+	pwd, err := osext.Getwd()
+	if err != nil {
+		return "", false, err
+	}
+	pwd = normalpath.Normalize(pwd)
+	if normalpath.EqualsOrContainsPath(pwd, normalpath.Join(fsRoot, path), normalpath.Absolute) {
+		relPath, err := normalpath.Rel(pwd, normalpath.Join(fsRoot, path))
+		// Just ignore if this errors and do nothing.
+		if err == nil {
+			return relPath, true, nil
+		}
+	}
+	return "", false, nil
+}
+
 func validatePaths(
 	inputSubDirPath string,
 	targetPaths []string,
@@ -28,6 +28,8 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type refParser struct {
@@ -506,7 +508,9 @@ func (a *refParser) getParsedRef(
 	ctx context.Context,
 	value string,
 	allowedFormats []string,
-) (internal.ParsedRef, error) {
+) (_ internal.ParsedRef, retErr error) {
+	ctx, span := tracing.Start(ctx, "buffetch.parse_ref", attribute.String("buf.input", value))
+	defer func() { tracing.End(span, retErr) }()
 	parsedRef, err := a.fetchRefParser.GetParsedRef(
 		ctx,
 		value,
@@ -523,7 +527,9 @@ func (a *refParser) getParsedRefForInputConfig(
 	ctx context.Context,
 	inputConfig bufconfig.InputConfig,
 	allowedFormats []string,
-) (internal.ParsedRef, error) {
+) (_ internal.ParsedRef, retErr error) {
+	ctx, span := tracing.Start(ctx, "buffetch.parse_ref")
+	defer func() { tracing.End(span, retErr) }()
 	parsedRef, err := a.fetchRefParser.GetParsedRefForInputConfig(
 		ctx,
 		inputConfig,
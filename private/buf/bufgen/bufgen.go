@@ -25,6 +25,7 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin/bufprotopluginos"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
@@ -123,6 +124,33 @@ func GenerateWithDeleteOuts(deleteOuts bool) GenerateOption {
 	}
 }
 
+// GenerateWithOutBucketFunc returns a new GenerateOption that writes the outputs for
+// which the OutBucketFunc returns a bucket to that bucket, such as an object storage
+// bucket, instead of to the OS filesystem.
+func GenerateWithOutBucketFunc(outBucketFunc bufprotopluginos.OutBucketFunc) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.outBucketFunc = outBucketFunc
+	}
+}
+
+// GenerateWithPluginLogDirPath returns a new GenerateOption that appends the full
+// stderr of each local plugin to a file named after the plugin in the given directory.
+func GenerateWithPluginLogDirPath(pluginLogDirPath string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.pluginLogDirPath = pluginLogDirPath
+	}
+}
+
+// GenerateWithPluginOutputLimit returns a new GenerateOption that fails generation if
+// a local plugin writes more than pluginOutputLimit bytes to stdout for a single request.
+//
+// The default is no limit.
+func GenerateWithPluginOutputLimit(pluginOutputLimit int64) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.pluginOutputLimit = pluginOutputLimit
+	}
+}
+
 // GenerateWithIncludeImportsOverride is a strict override on whether imports are
 // generated. This overrides IncludeImports from the GeneratePluginConfig.
 //
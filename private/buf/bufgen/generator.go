@@ -20,12 +20,14 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"strings"
 
 	connect "connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufprotopluginexec"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
 	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin/bufprotopluginos"
 	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin"
@@ -35,9 +37,12 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/buf/private/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/multierr"
 	"google.golang.org/protobuf/types/pluginpb"
 )
@@ -110,6 +115,7 @@ func (g *generator) Generate(
 			ctx,
 			generateOptions.baseOutDirPath,
 			config.GeneratePluginConfigs(),
+			generateOptions.outBucketFunc,
 		); err != nil {
 			return err
 		}
@@ -123,6 +129,9 @@ func (g *generator) Generate(
 			config.GeneratePluginConfigs(),
 			generateOptions.includeImportsOverride,
 			generateOptions.includeWellKnownTypesOverride,
+			generateOptions.outBucketFunc,
+			generateOptions.pluginLogDirPath,
+			generateOptions.pluginOutputLimit,
 		); err != nil {
 			return err
 		}
@@ -134,17 +143,18 @@ func (g *generator) deleteOuts(
 	ctx context.Context,
 	baseOutDir string,
 	pluginConfigs []bufconfig.GeneratePluginConfig,
+	outBucketFunc bufprotopluginos.OutBucketFunc,
 ) error {
-	return bufprotopluginos.NewCleaner(g.storageosProvider).DeleteOuts(
+	var cleanerOptions []bufprotopluginos.CleanerOption
+	if outBucketFunc != nil {
+		cleanerOptions = append(cleanerOptions, bufprotopluginos.CleanerWithOutBucketFunc(outBucketFunc))
+	}
+	return bufprotopluginos.NewCleaner(g.storageosProvider, cleanerOptions...).DeleteOuts(
 		ctx,
 		slicesext.Map(
 			pluginConfigs,
 			func(pluginConfig bufconfig.GeneratePluginConfig) string {
-				out := pluginConfig.Out()
-				if baseOutDir != "" && baseOutDir != "." {
-					return filepath.Join(baseOutDir, out)
-				}
-				return out
+				return getPluginOut(baseOutDir, pluginConfig.Out())
 			},
 		),
 	)
@@ -158,6 +168,9 @@ func (g *generator) generateCode(
 	pluginConfigs []bufconfig.GeneratePluginConfig,
 	includeImportsOverride *bool,
 	includeWellKnownTypesOverride *bool,
+	outBucketFunc bufprotopluginos.OutBucketFunc,
+	pluginLogDirPath string,
+	pluginOutputLimit int64,
 ) error {
 	responses, err := g.execPlugins(
 		ctx,
@@ -166,21 +179,26 @@ func (g *generator) generateCode(
 		inputImage,
 		includeImportsOverride,
 		includeWellKnownTypesOverride,
+		pluginLogDirPath,
+		pluginOutputLimit,
 	)
 	if err != nil {
 		return err
 	}
 	// Apply the CodeGeneratorResponses in the order they were specified.
+	responseWriterOptions := []bufprotopluginos.ResponseWriterOption{
+		bufprotopluginos.ResponseWriterWithCreateOutDirIfNotExists(),
+	}
+	if outBucketFunc != nil {
+		responseWriterOptions = append(responseWriterOptions, bufprotopluginos.ResponseWriterWithOutBucketFunc(outBucketFunc))
+	}
 	responseWriter := bufprotopluginos.NewResponseWriter(
 		g.logger,
 		g.storageosProvider,
-		bufprotopluginos.ResponseWriterWithCreateOutDirIfNotExists(),
+		responseWriterOptions...,
 	)
 	for i, pluginConfig := range pluginConfigs {
-		out := pluginConfig.Out()
-		if baseOutDir != "" && baseOutDir != "." {
-			out = filepath.Join(baseOutDir, out)
-		}
+		out := getPluginOut(baseOutDir, pluginConfig.Out())
 		response := responses[i]
 		if response == nil {
 			return fmt.Errorf("failed to get plugin response for %s", pluginConfig.Name())
@@ -206,6 +224,8 @@ func (g *generator) execPlugins(
 	image bufimage.Image,
 	includeImportsOverride *bool,
 	includeWellKnownTypesOverride *bool,
+	pluginLogDirPath string,
+	pluginOutputLimit int64,
 ) ([]*pluginpb.CodeGeneratorResponse, error) {
 	imageProvider := newImageProvider(image)
 	// Collect all of the plugin jobs so that they can be executed in parallel.
@@ -245,6 +265,8 @@ func (g *generator) execPlugins(
 					currentPluginConfig,
 					includeImports,
 					includeWellKnownTypes,
+					pluginLogDirPath,
+					pluginOutputLimit,
 				)
 				if err != nil {
 					return err
@@ -317,7 +339,11 @@ func (g *generator) execLocalPlugin(
 	pluginConfig bufconfig.GeneratePluginConfig,
 	includeImports bool,
 	includeWellKnownTypes bool,
-) (*pluginpb.CodeGeneratorResponse, error) {
+	pluginLogDirPath string,
+	pluginOutputLimit int64,
+) (_ *pluginpb.CodeGeneratorResponse, retErr error) {
+	ctx, span := tracing.Start(ctx, "bufgen.plugin", attribute.String("buf.plugin.name", pluginConfig.Name()))
+	defer func() { tracing.End(span, retErr) }()
 	pluginImages, err := imageProvider.GetImages(Strategy(pluginConfig.Strategy()))
 	if err != nil {
 		return nil, err
@@ -332,13 +358,25 @@ func (g *generator) execLocalPlugin(
 	if err != nil {
 		return nil, err
 	}
+	pluginPath, err := resolveLocalPluginPath(container, pluginConfig)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", pluginConfig.Name(), err)
+	}
+	pluginexecGenerateOptions := []bufprotopluginexec.GenerateOption{
+		bufprotopluginexec.GenerateWithPluginPath(pluginPath...),
+		bufprotopluginexec.GenerateWithProtocPath(pluginConfig.ProtocPath()...),
+		bufprotopluginexec.GenerateWithPluginLogDirPath(pluginLogDirPath),
+		bufprotopluginexec.GenerateWithOutputLimit(pluginOutputLimit),
+	}
+	if pluginConfig.Persistent() {
+		pluginexecGenerateOptions = append(pluginexecGenerateOptions, bufprotopluginexec.GenerateWithPersistent())
+	}
 	response, err := g.pluginexecGenerator.Generate(
 		ctx,
 		container,
 		pluginConfig.Name(),
 		requests,
-		bufprotopluginexec.GenerateWithPluginPath(pluginConfig.Path()...),
-		bufprotopluginexec.GenerateWithProtocPath(pluginConfig.ProtocPath()...),
+		pluginexecGenerateOptions...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("plugin %s: %v", pluginConfig.Name(), err)
@@ -346,6 +384,49 @@ func (g *generator) execLocalPlugin(
 	return response, nil
 }
 
+// resolveLocalPluginPath resolves the path of a local plugin against the plugin
+// manifests in the plugin directories specified by bufpluginmanifest.DirsEnvKey.
+//
+// If there is no manifest for the plugin, the path of the plugin config is returned
+// as-is, and the plugin is looked up on $PATH.
+func resolveLocalPluginPath(
+	container app.EnvContainer,
+	pluginConfig bufconfig.GeneratePluginConfig,
+) ([]string, error) {
+	pluginPath := pluginConfig.Path()
+	var reference string
+	switch pluginConfig.Type() {
+	case bufconfig.GeneratePluginConfigTypeLocal:
+		if len(pluginPath) == 0 {
+			return pluginPath, nil
+		}
+		reference = pluginPath[0]
+	case bufconfig.GeneratePluginConfigTypeLocalOrProtocBuiltin:
+		if len(pluginPath) > 0 {
+			reference = pluginPath[0]
+		} else {
+			reference = "protoc-gen-" + pluginConfig.Name()
+		}
+	default:
+		return pluginPath, nil
+	}
+	resolver, err := bufpluginmanifest.NewResolverForEnv(container)
+	if err != nil {
+		return nil, err
+	}
+	binaryPath, ok, err := resolver.Resolve(reference, bufpluginmanifest.CapabilityGenerate)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return pluginPath, nil
+	}
+	if len(pluginPath) == 0 {
+		return []string{binaryPath}, nil
+	}
+	return append([]string{binaryPath}, pluginPath[1:]...), nil
+}
+
 type remotePluginExecArgs struct {
 	Index        int
 	PluginConfig bufconfig.GeneratePluginConfig
@@ -364,7 +445,18 @@ func (g *generator) execRemotePluginsV2(
 	pluginConfigs []*remotePluginExecArgs,
 	includeImportsOverride *bool,
 	includeWellKnownTypesOverride *bool,
-) ([]*remotePluginExecutionResult, error) {
+) (_ []*remotePluginExecutionResult, retErr error) {
+	pluginNames := make([]string, len(pluginConfigs))
+	for i, pluginConfig := range pluginConfigs {
+		pluginNames[i] = pluginConfig.PluginConfig.Name()
+	}
+	ctx, span := tracing.Start(
+		ctx,
+		"bufgen.remote_plugins",
+		attribute.String("buf.remote", remote),
+		attribute.StringSlice("buf.plugin.names", pluginNames),
+	)
+	defer func() { tracing.End(span, retErr) }()
 	requests := make([]*registryv1alpha1.PluginGenerationRequest, len(pluginConfigs))
 	for i, pluginConfig := range pluginConfigs {
 		includeImports := pluginConfig.PluginConfig.IncludeImports()
@@ -480,11 +572,28 @@ func validateResponses(
 	return nil
 }
 
+// getPluginOut returns the out of the plugin within the base output directory.
+//
+// The base output directory may be a URL such as s3://bucket/path, which filepath.Join
+// would mangle.
+func getPluginOut(baseOutDir string, out string) string {
+	if baseOutDir == "" || baseOutDir == "." {
+		return out
+	}
+	if strings.Contains(baseOutDir, "://") {
+		return strings.TrimSuffix(baseOutDir, "/") + "/" + normalpath.Normalize(out)
+	}
+	return filepath.Join(baseOutDir, out)
+}
+
 type generateOptions struct {
 	baseOutDirPath                string
 	deleteOuts                    *bool
 	includeImportsOverride        *bool
 	includeWellKnownTypesOverride *bool
+	outBucketFunc                 bufprotopluginos.OutBucketFunc
+	pluginLogDirPath              string
+	pluginOutputLimit             int64
 }
 
 func newGenerateOptions() *generateOptions {
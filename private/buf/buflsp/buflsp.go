@@ -21,11 +21,14 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"sync"
 	"sync/atomic"
 
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/buf/private/pkg/storage"
@@ -35,12 +38,25 @@ import (
 	"go.uber.org/zap"
 )
 
+// configFileNames are the names of the Buf configuration files that determine the
+// modules and dependencies of a workspace.
+var configFileNames = []string{
+	bufconfig.DefaultBufYAMLFileName,
+	bufconfig.DefaultBufLockFileName,
+	bufconfig.DefaultBufWorkYAMLFileName,
+}
+
 // Serve spawns a new LSP server, listening on the given stream.
 //
+// depsDirPath is a directory that files of remote dependencies are written to, so that
+// the client can open them, such as for go-to-definition. If empty, files of remote
+// dependencies cannot be opened by the client.
+//
 // Returns a context for managing the server.
 func Serve(
 	ctx context.Context,
 	wktBucket storage.ReadBucket,
+	depsDirPath string,
 	container appext.Container,
 	controller bufctl.Controller,
 	checkClient bufcheck.Client,
@@ -70,8 +86,10 @@ func Serve(
 		checkClient: checkClient,
 		rootBucket:  bucket,
 		wktBucket:   wktBucket,
+		depsDirPath: depsDirPath,
 	}
 	lsp.fileManager = newFileManager(lsp)
+	lsp.symbolIndex = newSymbolIndex()
 	off := protocol.TraceOff
 	lsp.traceValue.Store(&off)
 
@@ -97,8 +115,11 @@ type lsp struct {
 	checkClient bufcheck.Client
 	rootBucket  storage.ReadBucket
 	fileManager *fileManager
+	symbolIndex *symbolIndex
 
 	wktBucket storage.ReadBucket
+	// depsDirPath is the directory that files of remote dependencies are written to.
+	depsDirPath string
 
 	lock sync.Mutex
 
@@ -126,6 +147,42 @@ func (l *lsp) init(_ context.Context, params *protocol.InitializeParams) error {
 	return nil
 }
 
+// canWatchFiles returns whether the client supports registering files to watch, i.e.
+// whether we will receive [protocol.MethodWorkspaceDidChangeWatchedFiles] notifications.
+func (l *lsp) canWatchFiles() bool {
+	params := l.initParams.Load()
+	return params != nil &&
+		params.Capabilities.Workspace != nil &&
+		params.Capabilities.Workspace.DidChangeWatchedFiles != nil &&
+		params.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration
+}
+
+// watchConfigFiles asks the client to notify us when the Buf configuration files change.
+func (l *lsp) watchConfigFiles(ctx context.Context) {
+	var watchers []protocol.FileSystemWatcher
+	for _, fileName := range configFileNames {
+		watchers = append(watchers, protocol.FileSystemWatcher{GlobPattern: "**/" + fileName})
+	}
+	if err := l.client.RegisterCapability(ctx, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{
+			{
+				ID:     protocol.MethodWorkspaceDidChangeWatchedFiles,
+				Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+				RegisterOptions: &protocol.DidChangeWatchedFilesRegistrationOptions{
+					Watchers: watchers,
+				},
+			},
+		},
+	}); err != nil {
+		l.logger.Warn("could not watch Buf configuration files", slogext.ErrorAttr(err))
+	}
+}
+
+// isConfigFile returns whether the file at the given path is a Buf configuration file.
+func isConfigFile(path string) bool {
+	return slices.Contains(configFileNames, filepath.Base(path))
+}
+
 // newHandler constructs an RPC handler that wraps the default one from jsonrpc2. This allows us
 // to inject debug logging, tracing, and timeouts to requests.
 func (l *lsp) newHandler() jsonrpc2.Handler {
@@ -0,0 +1,265 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buflsp"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/wasm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+// testPublicImportFiles are the files of a workspace where c.proto refers to Foo, which
+// is defined in a.proto, through the public import of a.proto in b.proto.
+var testPublicImportFiles = map[string]string{
+	"buf.yaml": "version: v2\n",
+	"a.proto": `syntax = "proto3";
+
+package acme.v1;
+
+message Foo {}
+`,
+	"b.proto": `syntax = "proto3";
+
+package acme.v1;
+
+import public "a.proto";
+`,
+	"c.proto": `syntax = "proto3";
+
+package acme.v1;
+
+import "b.proto";
+
+message Bar {
+  Foo foo = 1;
+  acme.v1.Foo other_foo = 2;
+}
+`,
+	"d.proto": `syntax = "proto3";
+
+package acme.v1;
+
+message Baz {}
+`,
+}
+
+func TestReferencesPublicImport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dirPath := testWriteFiles(t, testPublicImportFiles)
+	server := testNewServer(t, ctx)
+	testOpenFile(t, ctx, server, dirPath, "a.proto")
+
+	locations, err := server.References(
+		ctx,
+		&protocol.ReferenceParams{
+			TextDocumentPositionParams: testPosition(t, dirPath, "a.proto", "Foo"),
+			Context: protocol.ReferenceContext{
+				IncludeDeclaration: true,
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"a.proto:5:9",
+			"c.proto:8:3",
+			"c.proto:9:11",
+		},
+		testLocationStrings(dirPath, locations),
+	)
+
+	// References are also found from a file that only sees Foo through the public import.
+	testOpenFile(t, ctx, server, dirPath, "c.proto")
+	locations, err = server.References(
+		ctx,
+		&protocol.ReferenceParams{
+			TextDocumentPositionParams: testPosition(t, dirPath, "c.proto", "Foo foo"),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"c.proto:8:3",
+			"c.proto:9:11",
+		},
+		testLocationStrings(dirPath, locations),
+	)
+}
+
+func TestRenamePublicImport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dirPath := testWriteFiles(t, testPublicImportFiles)
+	server := testNewServer(t, ctx)
+	testOpenFile(t, ctx, server, dirPath, "c.proto")
+
+	workspaceEdit, err := server.Rename(
+		ctx,
+		&protocol.RenameParams{
+			TextDocumentPositionParams: testPosition(t, dirPath, "c.proto", "Foo foo"),
+			NewName:                    "Qux",
+		},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, workspaceEdit)
+	var edits []string
+	for uri, textEdits := range workspaceEdit.Changes {
+		for _, textEdit := range textEdits {
+			assert.Equal(t, "Qux", textEdit.NewText)
+			edits = append(edits, testLocationString(dirPath, protocol.Location{URI: uri, Range: textEdit.Range}))
+		}
+	}
+	assert.ElementsMatch(
+		t,
+		[]string{
+			"a.proto:5:9",
+			"c.proto:8:3",
+			"c.proto:9:11",
+		},
+		edits,
+	)
+}
+
+// testNewServer starts a new LSP server, and returns a client for it.
+func testNewServer(t *testing.T, ctx context.Context) protocol.Server {
+	cacheDirPath := t.TempDir()
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			map[string]string{
+				"BUF_CACHE_DIR": cacheDirPath,
+			},
+			nil,
+			bytes.NewBuffer(nil),
+			bytes.NewBuffer(nil),
+		),
+		"buf",
+	)
+	require.NoError(t, err)
+	container := appext.NewContainer(nameContainer, slogtestext.NewLogger(t))
+	wktStore, err := bufcli.NewWKTStore(container)
+	require.NoError(t, err)
+	wktBucket, err := wktStore.GetBucket(ctx)
+	require.NoError(t, err)
+	controller, err := bufcli.NewController(container)
+	require.NoError(t, err)
+	checkClient, err := bufcheck.NewClient(
+		container.Logger(),
+		bufcheck.NewRunnerProvider(command.NewRunner(), wasm.UnimplementedRuntime),
+	)
+	require.NoError(t, err)
+
+	serverPipe, clientPipe := net.Pipe()
+	serverConn, err := buflsp.Serve(ctx, wktBucket, "", container, controller, checkClient, jsonrpc2.NewStream(serverPipe))
+	require.NoError(t, err)
+	clientConn := jsonrpc2.NewConn(jsonrpc2.NewStream(clientPipe))
+	// The client ignores diagnostics and progress sent by the server.
+	clientConn.Go(ctx, func(ctx context.Context, reply jsonrpc2.Replier, _ jsonrpc2.Request) error {
+		return reply(ctx, nil, nil)
+	})
+	t.Cleanup(func() {
+		_ = clientConn.Close()
+		_ = serverConn.Close()
+	})
+
+	server := protocol.ServerDispatcher(clientConn, zap.NewNop())
+	_, err = server.Initialize(ctx, &protocol.InitializeParams{})
+	require.NoError(t, err)
+	return server
+}
+
+func testWriteFiles(t *testing.T, pathToData map[string]string) string {
+	dirPath := t.TempDir()
+	for path, data := range pathToData {
+		require.NoError(t, os.WriteFile(filepath.Join(dirPath, path), []byte(data), 0600))
+	}
+	return dirPath
+}
+
+func testOpenFile(t *testing.T, ctx context.Context, server protocol.Server, dirPath string, path string) {
+	data, err := os.ReadFile(filepath.Join(dirPath, path))
+	require.NoError(t, err)
+	require.NoError(
+		t,
+		server.DidOpen(
+			ctx,
+			&protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{
+					URI:        protocol.DocumentURI("file://" + filepath.Join(dirPath, path)),
+					LanguageID: "protobuf",
+					Version:    1,
+					Text:       string(data),
+				},
+			},
+		),
+	)
+}
+
+// testPosition returns the position of the first occurrence of substring in the file at path.
+func testPosition(t *testing.T, dirPath string, path string, substring string) protocol.TextDocumentPositionParams {
+	data, err := os.ReadFile(filepath.Join(dirPath, path))
+	require.NoError(t, err)
+	for i, line := range strings.Split(string(data), "\n") {
+		if character := strings.Index(line, substring); character >= 0 {
+			return protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{
+					URI: protocol.DocumentURI("file://" + filepath.Join(dirPath, path)),
+				},
+				Position: protocol.Position{
+					Line:      uint32(i),
+					Character: uint32(character),
+				},
+			}
+		}
+	}
+	require.FailNow(t, "substring not found", "%q in %q", substring, path)
+	return protocol.TextDocumentPositionParams{}
+}
+
+func testLocationStrings(dirPath string, locations []protocol.Location) []string {
+	locationStrings := make([]string, len(locations))
+	for i, location := range locations {
+		locationStrings[i] = testLocationString(dirPath, location)
+	}
+	return locationStrings
+}
+
+// testLocationString returns the path relative to dirPath, and the 1-based line and
+// column of the start of location.
+func testLocationString(dirPath string, location protocol.Location) string {
+	path, _ := filepath.Rel(dirPath, location.URI.Filename())
+	return fmt.Sprintf("%s:%d:%d", path, location.Range.Start.Line+1, location.Range.Start.Character+1)
+}
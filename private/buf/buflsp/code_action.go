@@ -0,0 +1,143 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements code actions that fix lint violations.
+
+package buflsp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"go.lsp.dev/protocol"
+)
+
+// lintFix is a fix for violations of a lint rule.
+type lintFix struct {
+	// The title of the code action shown to the user.
+	title string
+	// Computes the edits that fix the violation reported in diagnostic. Returns nil if
+	// this violation cannot be fixed automatically.
+	fix func(ctx context.Context, f *file, diagnostic protocol.Diagnostic) (*protocol.WorkspaceEdit, error)
+}
+
+// lintFixes maps lint rule IDs to fixes for violations of them.
+var lintFixes = map[string]lintFix{
+	"PACKAGE_VERSION_SUFFIX": {
+		title: "Add package version suffix",
+		fix:   fixPackageVersionSuffix,
+	},
+	"ENUM_VALUE_PREFIX": {
+		title: "Prefix enum value",
+		fix: newRenameFix(func(path []string) string {
+			// Enum values are nested under their enum in the symbol table.
+			if len(path) < 2 {
+				return ""
+			}
+			return stringutil.ToUpperSnakeCase(path[len(path)-2]) + "_" + path[len(path)-1]
+		}),
+	},
+	"ENUM_VALUE_UPPER_SNAKE_CASE": {
+		title: "Rename enum value to UPPER_SNAKE_CASE",
+		fix: newRenameFix(func(path []string) string {
+			return stringutil.ToUpperSnakeCase(path[len(path)-1])
+		}),
+	},
+	"FIELD_LOWER_SNAKE_CASE": {
+		title: "Rename field to lower_snake_case",
+		fix: newRenameFix(func(path []string) string {
+			return stringutil.ToLowerSnakeCase(path[len(path)-1])
+		}),
+	},
+}
+
+// CodeActions returns the quick fixes for the given diagnostics, which the client
+// got from this file.
+func (f *file) CodeActions(ctx context.Context, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Source != "buf lint" {
+			continue
+		}
+		ruleID, _ := diagnostic.Code.(string)
+		lintFix, ok := lintFixes[ruleID]
+		if !ok {
+			continue
+		}
+
+		edit, err := lintFix.fix(ctx, f, diagnostic)
+		if err != nil {
+			f.lsp.logger.Warn(fmt.Sprintf("could not fix %s in %s: %s", ruleID, f.uri, err))
+			continue
+		}
+		if edit == nil {
+			continue
+		}
+
+		actions = append(actions, protocol.CodeAction{
+			Title:       lintFix.title,
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			IsPreferred: true,
+			Edit:        edit,
+		})
+	}
+	return actions
+}
+
+// fixPackageVersionSuffix appends a v1 version to the package of f.
+func fixPackageVersionSuffix(_ context.Context, f *file, _ protocol.Diagnostic) (*protocol.WorkspaceEdit, error) {
+	if f.packageNode == nil {
+		return nil, nil
+	}
+
+	end := infoToRange(f.fileNode.NodeInfo(f.packageNode.Name)).End
+	return &protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			f.uri: {{
+				Range:   protocol.Range{Start: end, End: end},
+				NewText: ".v1",
+			}},
+		},
+	}, nil
+}
+
+// newRenameFix returns a fix that renames the definition a diagnostic is reported on,
+// along with all references to it. newName computes the new name from the path of the
+// definition.
+func newRenameFix(
+	newName func(path []string) string,
+) func(context.Context, *file, protocol.Diagnostic) (*protocol.WorkspaceEdit, error) {
+	return func(ctx context.Context, f *file, diagnostic protocol.Diagnostic) (*protocol.WorkspaceEdit, error) {
+		symbol := f.SymbolAt(ctx, diagnostic.Range.Start)
+		if symbol == nil {
+			return nil, nil
+		}
+		def, ok := symbol.kind.(*definition)
+		if !ok {
+			return nil, nil
+		}
+		name := newName(def.path)
+		if name == "" || name == def.path[len(def.path)-1] {
+			return nil, nil
+		}
+
+		target, err := symbol.RenameTarget(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return f.lsp.renameSymbol(ctx, target, name)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file defines how files of remote dependencies are made available to the client.
+
+package buflsp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+	"go.uber.org/multierr"
+)
+
+// materializeFileInfo returns a bufmodule.FileInfo for the file with a local path that
+// the client can open, writing the file to the dependency directory if needed.
+//
+// Files of remote dependencies only exist in the module cache, which is content-addressed,
+// so they have no local path of their own. Without a local path, go-to-definition into a
+// dependency would result in "file not found" on the client. We write each such file to
+// <depsDirPath>/<module full name>/<commit ID>/<path>, which never changes once written,
+// as the contents of a commit are immutable.
+//
+// Returns the fileInfo unchanged if it already has a local path, or if it cannot be
+// materialized.
+func (l *lsp) materializeFileInfo(ctx context.Context, fileInfo bufmodule.FileInfo) (bufmodule.FileInfo, error) {
+	if fileInfo.LocalPath() != "" || l.depsDirPath == "" {
+		return fileInfo, nil
+	}
+	module := fileInfo.Module()
+	moduleFullName := module.ModuleFullName()
+	commitID := module.CommitID()
+	if moduleFullName == nil || commitID == uuid.Nil {
+		return fileInfo, nil
+	}
+	localPath := filepath.Join(
+		l.depsDirPath,
+		filepath.FromSlash(moduleFullName.String()),
+		uuidutil.ToDashless(commitID),
+		filepath.FromSlash(fileInfo.Path()),
+	)
+	if _, err := os.Stat(localPath); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err := writeModuleFile(ctx, module, fileInfo.Path(), localPath); err != nil {
+			return nil, err
+		}
+	}
+	return materializedFileInfo{FileInfo: fileInfo, localPath: localPath}, nil
+}
+
+// writeModuleFile writes the file at path in the module to localPath.
+//
+// The file is written to a temporary file first and then renamed, so that a concurrent
+// reader never observes a partially-written file.
+func writeModuleFile(ctx context.Context, module bufmodule.Module, path string, localPath string) (retErr error) {
+	moduleFile, err := module.GetFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleFile.Close())
+	}()
+	dirPath := filepath.Dir(localPath)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(dirPath, filepath.Base(localPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempFilePath := tempFile.Name()
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, os.Remove(tempFilePath))
+		}
+	}()
+	if _, err := io.Copy(tempFile, moduleFile); err != nil {
+		return multierr.Append(err, tempFile.Close())
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, localPath)
+}
+
+// materializedFileInfo is a bufmodule.FileInfo for a file of a remote dependency that
+// has been written to the dependency directory.
+type materializedFileInfo struct {
+	bufmodule.FileInfo
+
+	localPath string
+}
+
+func (m materializedFileInfo) LocalPath() string {
+	return m.localPath
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file defines the document symbol outline of a file.
+
+package buflsp
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/protocompile/ast"
+	"go.lsp.dev/protocol"
+)
+
+// DocumentSymbols returns the outline of this file, as a tree of document symbols.
+//
+// This is computed from the AST alone, so it is available even if the file does not
+// compile.
+func (f *file) DocumentSymbols() []protocol.DocumentSymbol {
+	if f.fileNode == nil {
+		return nil
+	}
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range f.fileNode.Decls {
+		switch decl := decl.(type) {
+		case *ast.PackageNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindPackage, "", nil)
+		case *ast.MessageNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindStruct, "", f.messageDocumentSymbols(decl.Decls))
+		case *ast.EnumNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindEnum, "", f.enumDocumentSymbols(decl))
+		case *ast.ServiceNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindInterface, "", f.serviceDocumentSymbols(decl))
+		case *ast.ExtendNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Extendee, protocol.SymbolKindNamespace, "extend", f.extendDocumentSymbols(decl))
+		}
+	}
+	return symbols
+}
+
+func (f *file) messageDocumentSymbols(decls []ast.MessageElement) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.FieldNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, fieldDetail(decl), nil)
+		case *ast.MapFieldNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, mapFieldDetail(decl), nil)
+		case *ast.GroupNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, "group", f.messageDocumentSymbols(decl.Decls))
+		case *ast.OneofNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, "oneof", f.oneofDocumentSymbols(decl))
+		case *ast.MessageNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindStruct, "", f.messageDocumentSymbols(decl.Decls))
+		case *ast.EnumNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindEnum, "", f.enumDocumentSymbols(decl))
+		case *ast.ExtendNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Extendee, protocol.SymbolKindNamespace, "extend", f.extendDocumentSymbols(decl))
+		}
+	}
+	return symbols
+}
+
+func (f *file) oneofDocumentSymbols(oneof *ast.OneofNode) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range oneof.Decls {
+		switch decl := decl.(type) {
+		case *ast.FieldNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, fieldDetail(decl), nil)
+		case *ast.GroupNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, "group", f.messageDocumentSymbols(decl.Decls))
+		}
+	}
+	return symbols
+}
+
+func (f *file) enumDocumentSymbols(enum *ast.EnumNode) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range enum.Decls {
+		if value, ok := decl.(*ast.EnumValueNode); ok {
+			symbols = appendDocumentSymbol(f, symbols, value, value.Name, protocol.SymbolKindEnumMember, "", nil)
+		}
+	}
+	return symbols
+}
+
+func (f *file) serviceDocumentSymbols(service *ast.ServiceNode) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range service.Decls {
+		if rpc, ok := decl.(*ast.RPCNode); ok {
+			symbols = appendDocumentSymbol(f, symbols, rpc, rpc.Name, protocol.SymbolKindMethod, rpcDetail(rpc), nil)
+		}
+	}
+	return symbols
+}
+
+func (f *file) extendDocumentSymbols(extend *ast.ExtendNode) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for _, decl := range extend.Decls {
+		switch decl := decl.(type) {
+		case *ast.FieldNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, fieldDetail(decl), nil)
+		case *ast.GroupNode:
+			symbols = appendDocumentSymbol(f, symbols, decl, decl.Name, protocol.SymbolKindField, "group", f.messageDocumentSymbols(decl.Decls))
+		}
+	}
+	return symbols
+}
+
+// appendDocumentSymbol appends a document symbol for the node to symbols.
+//
+// Nodes without a name, which the parser produces when recovering from syntax
+// errors, are skipped, as the protocol requires a non-empty name.
+func appendDocumentSymbol(
+	f *file,
+	symbols []protocol.DocumentSymbol,
+	node ast.Node,
+	name ast.IdentValueNode,
+	kind protocol.SymbolKind,
+	detail string,
+	children []protocol.DocumentSymbol,
+) []protocol.DocumentSymbol {
+	if node == nil || name == nil {
+		return symbols
+	}
+	if identNode, ok := name.(*ast.IdentNode); ok && identNode == nil {
+		return symbols
+	}
+	nameString := string(name.AsIdentifier())
+	if nameString == "" {
+		return symbols
+	}
+	return append(
+		symbols,
+		protocol.DocumentSymbol{
+			Name:           nameString,
+			Detail:         detail,
+			Kind:           kind,
+			Range:          infoToRange(f.fileNode.NodeInfo(node)),
+			SelectionRange: infoToRange(f.fileNode.NodeInfo(name)),
+			Children:       children,
+		},
+	)
+}
+
+func fieldDetail(field *ast.FieldNode) string {
+	if field.FldType == nil {
+		return ""
+	}
+	fieldType := string(field.FldType.AsIdentifier())
+	if field.Label.KeywordNode != nil {
+		return field.Label.Val + " " + fieldType
+	}
+	return fieldType
+}
+
+func mapFieldDetail(mapField *ast.MapFieldNode) string {
+	if mapField.MapType == nil || mapField.MapType.KeyType == nil || mapField.MapType.ValueType == nil {
+		return ""
+	}
+	return fmt.Sprintf("map<%s, %s>", mapField.MapType.KeyType.Val, mapField.MapType.ValueType.AsIdentifier())
+}
+
+func rpcDetail(rpc *ast.RPCNode) string {
+	if rpc.Input == nil || rpc.Output == nil || rpc.Input.MessageType == nil || rpc.Output.MessageType == nil {
+		return ""
+	}
+	return fmt.Sprintf("(%s) returns (%s)", rpcTypeDetail(rpc.Input), rpcTypeDetail(rpc.Output))
+}
+
+func rpcTypeDetail(rpcType *ast.RPCTypeNode) string {
+	if rpcType.Stream != nil {
+		return "stream " + string(rpcType.MessageType.AsIdentifier())
+	}
+	return string(rpcType.MessageType.AsIdentifier())
+}
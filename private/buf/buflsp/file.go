@@ -32,6 +32,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/ioext"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/protocompile"
@@ -74,6 +75,8 @@ type file struct {
 	importToFile map[string]*file
 	symbols      []*symbol
 	image        bufimage.Image
+	// The linked descriptors for this file, which are used to resolve option values.
+	linkerFile linker.File
 }
 
 // IsWKT returns whether this file corresponds to a well-known type.
@@ -110,17 +113,40 @@ func (f *file) Package() []string {
 func (f *file) Reset(ctx context.Context) {
 	f.lsp.logger.Debug(fmt.Sprintf("resetting file %v", f.uri))
 
+	f.resetWorkspace()
+	f.resetAST(ctx)
+}
+
+// resetWorkspace clears the workspace, module, and importable files found for this file.
+//
+// These are expensive to compute, so they are only discarded when the file is reset or
+// the Buf configuration files of the workspace change.
+func (f *file) resetWorkspace() {
+	f.workspace = nil
+	f.module = nil
+	f.importablePathToObject = nil
+}
+
+// resetAST clears all bookkeeping information derived from this file's text, and closes
+// the files it imports.
+//
+// Unlike [file.Reset], this keeps the workspace, module, and importable files that
+// were found for this file, since an edit almost never changes them.
+func (f *file) resetAST(ctx context.Context) {
+	for _, imported := range f.importToFile {
+		// A file that imports itself, or descriptor.proto, did not open itself.
+		if imported != f {
+			imported.Close(ctx)
+		}
+	}
+
 	f.fileNode = nil
 	f.packageNode = nil
 	f.diagnostics = nil
-	f.importablePathToObject = nil
 	f.importToFile = nil
 	f.symbols = nil
 	f.image = nil
-
-	for _, imported := range f.importToFile {
-		imported.Close(ctx)
-	}
+	f.linkerFile = nil
 }
 
 // Close marks a file as closed.
@@ -161,8 +187,10 @@ func (f *file) ReadFromDisk(ctx context.Context) (err error) {
 
 // Update updates the contents of this file with the given text received from
 // the LSP client.
+//
+// Only state derived from the file's text is discarded; see [file.resetAST].
 func (f *file) Update(ctx context.Context, version int32, text string) {
-	f.Reset(ctx)
+	f.resetAST(ctx)
 
 	f.lsp.logger.Info(fmt.Sprintf("new file version: %v, %v -> %v", f.uri, f.version, version))
 	f.version = version
@@ -206,6 +234,56 @@ func (f *file) Refresh(ctx context.Context) {
 	f.PublishDiagnostics(ctx)
 }
 
+// RefreshDependents rebuilds the images and diagnostics of all files open in the
+// editor that depend on this file, so that e.g. a type deleted from this file shows
+// up as an error in the files that use it.
+//
+// Only the dependents themselves are rebuilt: their workspaces and importable files
+// are reused, and the rest of the workspace is read through the in-memory overlay
+// of editor files in [file.BuildImage].
+func (f *file) RefreshDependents(ctx context.Context) {
+	if f.objectInfo == nil {
+		return
+	}
+
+	var dependents []*file
+	f.Manager().uriToFile.Range(func(_ protocol.URI, other *file) bool {
+		if other != f && other.IsOpenInEditor() && other.DependsOn(f) {
+			dependents = append(dependents, other)
+		}
+		return true
+	})
+
+	for _, dependent := range dependents {
+		f.lsp.logger.Debug(fmt.Sprintf("rebuilding %v, which depends on %v", dependent.uri, f.uri))
+
+		dependent.resetAST(ctx)
+		dependent.RefreshAST(ctx)
+		dependent.IndexImports(ctx)
+		dependent.BuildImage(ctx)
+		dependent.RunLints(ctx)
+		dependent.IndexSymbols(ctx)
+		dependent.PublishDiagnostics(ctx)
+	}
+}
+
+// DependsOn returns whether this file imports other, directly or transitively.
+//
+// This is computed from the most recently built image, so it is only accurate if
+// this file has been refreshed at least once.
+func (f *file) DependsOn(other *file) bool {
+	for _, imported := range f.importToFile {
+		if imported == other {
+			return true
+		}
+	}
+
+	if f.image == nil || other.objectInfo == nil {
+		return false
+	}
+	return f.image.GetFile(other.objectInfo.Path()) != nil
+}
+
 // RefreshAST reparses the file and generates diagnostics if necessary.
 //
 // Returns whether a reparse was necessary.
@@ -274,7 +352,14 @@ func (f *file) PublishDiagnostics(ctx context.Context) {
 }
 
 // FindModule finds the Buf module for this file.
+//
+// The workspace is only loaded once; it is discarded by [file.Reset], and when the
+// Buf configuration files change; see [fileManager.ResetWorkspaces].
 func (f *file) FindModule(ctx context.Context) {
+	if f.workspace != nil {
+		return
+	}
+
 	workspace, err := f.lsp.controller.GetWorkspace(ctx, f.uri.Filename())
 	if err != nil {
 		f.lsp.logger.Warn("could not load workspace", slog.String("uri", string(f.uri)), slogext.ErrorAttr(err))
@@ -297,6 +382,7 @@ func (f *file) FindModule(ctx context.Context) {
 	}
 	if module == nil {
 		f.lsp.logger.Warn(fmt.Sprintf("could not find module for %q", f.uri))
+		return
 	}
 
 	// Determine if this is the WKT module. We do so by checking if this module contains
@@ -318,14 +404,21 @@ func (f *file) IndexImports(ctx context.Context) {
 		return
 	}
 
-	importable, err := findImportable(ctx, f.uri, f.lsp)
-	if err != nil {
-		f.lsp.logger.Warn(fmt.Sprintf("could not compute importable files for %s: %s", f.uri, err))
-		if f.importablePathToObject == nil {
-			return
+	// Computing the importable files requires loading the workspace, so we only do
+	// it when this file has never been indexed, or when an import cannot be found in
+	// the files we already know about (e.g., because it was just created).
+	importable := f.importablePathToObject
+	if importable == nil || f.hasUnresolvedImports(importable) {
+		computed, err := findImportable(ctx, f.uri, f.lsp)
+		if err != nil {
+			f.lsp.logger.Warn(fmt.Sprintf("could not compute importable files for %s: %s", f.uri, err))
+			if importable == nil {
+				return
+			}
+		} else {
+			importable = computed
+			f.importablePathToObject = computed
 		}
-	} else if f.importablePathToObject == nil {
-		f.importablePathToObject = importable
 	}
 
 	// Find the FileInfo for this path. The crazy thing is that it may appear in importable
@@ -347,48 +440,15 @@ func (f *file) IndexImports(ctx context.Context) {
 			continue
 		}
 
-		// If this is an external file, it will be in the cache and therefore
-		// finding imports via lsp.findImportable() will not work correctly:
-		// the bucket for the workspace found for a dependency will have
-		// truncated paths, and those workspace files will appear to be
-		// local rather than external.
-		//
-		// Thus, we search for name and all of its path suffixes. This is not
-		// ideal but is our only option in this case.
-		var fileInfo storage.ObjectInfo
-		var pathWasTruncated bool
-		name := node.Name.AsString()
-		for {
-			fileInfo, ok = importable[name]
-			if ok {
-				break
-			}
-
-			idx := strings.Index(name, "/")
-			if idx == -1 {
-				break
-			}
-
-			name = name[idx+1:]
-			pathWasTruncated = true
-		}
-		if fileInfo == nil {
-			f.lsp.logger.Warn(fmt.Sprintf("could not find URI for import %q", node.Name.AsString()))
-			continue
-		}
-		if pathWasTruncated && !strings.HasSuffix(fileInfo.LocalPath(), node.Name.AsString()) {
-			// Verify that the file we found, with a potentially too-short path, does in fact have
-			// the "correct" full path as a prefix. E.g., suppose we import a/b/c.proto. We find
-			// c.proto in importable. Now, we look at the full local path, which we expect to be of
-			// the form /home/blah/.cache/blah/a/b/c.proto or similar. If it does not contain
-			// a/b/c.proto as a suffix, we didn't find our file.
-			f.lsp.logger.Warn(fmt.Sprintf("could not find URI for import %q, but found same-suffix path %q", node.Name.AsString(), fileInfo.LocalPath()))
+		fileInfo, err := findImport(importable, node.Name.AsString())
+		if err != nil {
+			f.lsp.logger.Warn(err.Error())
 			continue
 		}
 
 		f.lsp.logger.Debug(
 			"mapped import -> path",
-			slog.String("import", name),
+			slog.String("import", node.Name.AsString()),
 			slog.String("path", fileInfo.LocalPath()),
 		)
 
@@ -416,7 +476,6 @@ func (f *file) IndexImports(ctx context.Context) {
 		}
 	}
 
-	// FIXME: This algorithm is not correct: it does not account for `import public`.
 	fileImports := f.importToFile
 
 	for _, file := range fileImports {
@@ -430,10 +489,118 @@ func (f *file) IndexImports(ctx context.Context) {
 		// index symbols in the import's imports, otherwise we will recursively
 		// index the universe and that would be quite slow.
 		file.RefreshAST(ctx)
+		if len(file.publicImportPaths()) > 0 {
+			// The exception is the files that the import imports publicly, since
+			// their symbols are visible in this file; see [file.visibleImports].
+			// This recurses only through public imports.
+			if file.importablePathToObject == nil {
+				file.importablePathToObject = importable
+			}
+			file.IndexImports(ctx)
+		}
 		file.IndexSymbols(ctx)
 	}
 }
 
+// publicImportPaths returns the paths of the files this file imports with `import public`.
+func (f *file) publicImportPaths() []string {
+	if f.fileNode == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, decl := range f.fileNode.Decls {
+		if node, ok := decl.(*ast.ImportNode); ok && node.Public != nil {
+			paths = append(paths, node.Name.AsString())
+		}
+	}
+	return paths
+}
+
+// visibleImports returns the imported files whose symbols are visible in this file: its
+// direct imports, and the files those import with `import public`, transitively.
+func (f *file) visibleImports() []*file {
+	var visible []*file
+	seen := make(map[*file]struct{})
+	queue := slicesext.MapValuesToSlice(f.importToFile)
+	for len(queue) > 0 {
+		imported := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[imported]; ok {
+			continue
+		}
+		seen[imported] = struct{}{}
+		visible = append(visible, imported)
+
+		for _, path := range imported.publicImportPaths() {
+			if publicImport, ok := imported.importToFile[path]; ok {
+				queue = append(queue, publicImport)
+			}
+		}
+	}
+	return visible
+}
+
+// hasUnresolvedImports returns whether any of the imports in this file's AST cannot
+// be found in importable.
+func (f *file) hasUnresolvedImports(importable map[string]storage.ObjectInfo) bool {
+	if _, ok := importable[descriptorPath]; !ok {
+		return true
+	}
+	for _, decl := range f.fileNode.Decls {
+		node, ok := decl.(*ast.ImportNode)
+		if !ok {
+			continue
+		}
+		if _, err := findImport(importable, node.Name.AsString()); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findImport finds the file for the given import path in importable.
+func findImport(importable map[string]storage.ObjectInfo, importPath string) (storage.ObjectInfo, error) {
+	// If this is an external file, it will be in the cache and therefore
+	// finding imports via lsp.findImportable() will not work correctly:
+	// the bucket for the workspace found for a dependency will have
+	// truncated paths, and those workspace files will appear to be
+	// local rather than external.
+	//
+	// Thus, we search for name and all of its path suffixes. This is not
+	// ideal but is our only option in this case.
+	var fileInfo storage.ObjectInfo
+	var pathWasTruncated bool
+	name := importPath
+	for {
+		var ok bool
+		fileInfo, ok = importable[name]
+		if ok {
+			break
+		}
+
+		idx := strings.Index(name, "/")
+		if idx == -1 {
+			break
+		}
+
+		name = name[idx+1:]
+		pathWasTruncated = true
+	}
+	if fileInfo == nil {
+		return nil, fmt.Errorf("could not find URI for import %q", importPath)
+	}
+	if pathWasTruncated && !strings.HasSuffix(fileInfo.LocalPath(), importPath) {
+		// Verify that the file we found, with a potentially too-short path, does in fact have
+		// the "correct" full path as a prefix. E.g., suppose we import a/b/c.proto. We find
+		// c.proto in importable. Now, we look at the full local path, which we expect to be of
+		// the form /home/blah/.cache/blah/a/b/c.proto or similar. If it does not contain
+		// a/b/c.proto as a suffix, we didn't find our file.
+		return nil, fmt.Errorf("could not find URI for import %q, but found same-suffix path %q", importPath, fileInfo.LocalPath())
+	}
+	return fileInfo, nil
+}
+
 // BuildImage builds a Buf Image for this file. This does not use the controller to build
 // the image, because we need delicate control over the input files: namely, for the case
 // when we depend on a file that has been opened and modified in the editor.
@@ -481,6 +648,7 @@ func (f *file) BuildImage(ctx context.Context) {
 	if compiled[0] == nil {
 		return
 	}
+	f.linkerFile = compiled[0]
 
 	var imageFiles []bufimage.ImageFile
 	seen := map[string]bool{}
@@ -714,7 +882,13 @@ func findImportable(
 				return nil
 			}
 
-			imports[fileInfo.Path()] = fileInfo
+			materializedFileInfo, err := lsp.materializeFileInfo(ctx, fileInfo)
+			if err != nil {
+				// Not fatal: the file is still importable, it just cannot be opened by the client.
+				lsp.logger.Warn("could not materialize dependency file", slog.String("path", fileInfo.Path()), slogext.ErrorAttr(err))
+				materializedFileInfo = fileInfo
+			}
+			imports[fileInfo.Path()] = materializedFileInfo
 
 			return nil
 		})
@@ -18,16 +18,26 @@ package buflsp
 
 import (
 	"context"
+	"time"
 
 	"github.com/bufbuild/buf/private/pkg/refcount"
 	"go.lsp.dev/protocol"
 )
 
+// refreshDependentsDelay is how long to wait after the last edit before rebuilding
+// the files that depend on the edited files, so that we do not rebuild them on every
+// keystroke.
+const refreshDependentsDelay = 250 * time.Millisecond
+
 // fileManager tracks all files the LSP is currently handling, whether read from disk or opened
 // by the editor.
 type fileManager struct {
 	lsp       *lsp
 	uriToFile refcount.Map[protocol.URI, file]
+
+	// These are protected by lsp.lock.
+	refreshDependentsTimer   *time.Timer
+	pendingRefreshDependents map[*file]struct{}
 }
 
 // newFiles creates a new file manager.
@@ -62,3 +72,55 @@ func (fm *fileManager) Close(ctx context.Context, uri protocol.URI) {
 		deleted.Reset(ctx)
 	}
 }
+
+// ScheduleRefreshDependents calls [file.RefreshDependents] for the given file in the
+// background, once no file has been edited for refreshDependentsDelay.
+//
+// This must be called with lsp.lock held, i.e. from a request handler.
+func (fm *fileManager) ScheduleRefreshDependents(edited *file) {
+	if fm.pendingRefreshDependents == nil {
+		fm.pendingRefreshDependents = make(map[*file]struct{})
+	}
+	fm.pendingRefreshDependents[edited] = struct{}{}
+
+	if fm.refreshDependentsTimer != nil {
+		fm.refreshDependentsTimer.Stop()
+	}
+	fm.refreshDependentsTimer = time.AfterFunc(refreshDependentsDelay, fm.refreshPendingDependents)
+}
+
+// refreshPendingDependents refreshes the dependents of all files passed to
+// ScheduleRefreshDependents since the last call.
+func (fm *fileManager) refreshPendingDependents() {
+	fm.lsp.lock.Lock()
+	defer fm.lsp.lock.Unlock()
+
+	pending := fm.pendingRefreshDependents
+	fm.pendingRefreshDependents = nil
+	for file := range pending {
+		// The file may have been closed since the refresh was scheduled.
+		if fm.Get(file.uri) != file {
+			continue
+		}
+		file.RefreshDependents(context.Background())
+	}
+}
+
+// ResetWorkspaces discards the workspaces found for all files, and refreshes the files
+// open in the editor. This is called when the Buf configuration files change, since these
+// determine the modules and dependencies of the workspace.
+func (fm *fileManager) ResetWorkspaces(ctx context.Context) {
+	var openFiles []*file
+	fm.uriToFile.Range(func(_ protocol.URI, file *file) bool {
+		file.resetWorkspace()
+		if file.IsOpenInEditor() {
+			openFiles = append(openFiles, file)
+		}
+		return true
+	})
+
+	for _, file := range openFiles {
+		file.resetAST(ctx)
+		file.Refresh(ctx)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements formatting of files open in the editor.
+
+package buflsp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufformat"
+	"github.com/bufbuild/buf/private/pkg/diff/diffmyers"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+	"go.lsp.dev/protocol"
+)
+
+// Format formats this file's current text, including any unsaved changes, and returns
+// the edits that must be applied to it to make it formatted.
+//
+// If lines is not nil, only edits that touch the given range of lines are returned.
+//
+// Files that do not parse cleanly are not formatted, since formatting a partial AST
+// would throw away whatever the parser could not make sense of.
+func (f *file) Format(lines *protocol.Range) ([]protocol.TextEdit, error) {
+	fileNode, err := parser.Parse(f.uri.Filename(), strings.NewReader(f.text), reporter.NewHandler(nil))
+	if err != nil {
+		f.lsp.logger.Debug("not formatting file with syntax errors", slog.String("uri", string(f.uri)), slogext.ErrorAttr(err))
+		return nil, nil
+	}
+
+	var out strings.Builder
+	if err := bufformat.FormatFileNode(&out, fileNode); err != nil {
+		return nil, err
+	}
+
+	edits := diffToTextEdits(f.text, out.String())
+	if lines == nil {
+		return edits, nil
+	}
+
+	// Keep only the edits that overlap the requested lines. An edit that only inserts
+	// text has an empty range, which we treat as covering the line it is inserted before.
+	var filtered []protocol.TextEdit
+	for _, edit := range edits {
+		if edit.Range.Start.Line <= lines.End.Line && edit.Range.End.Line >= lines.Start.Line {
+			filtered = append(filtered, edit)
+		}
+	}
+	return filtered, nil
+}
+
+// formatOnSave returns whether the client asked for files to be formatted when they
+// are saved, by setting "formatOnSave" to true in its initialization options.
+func (l *lsp) formatOnSave() bool {
+	params := l.initParams.Load()
+	if params == nil || params.InitializationOptions == nil {
+		return false
+	}
+
+	// InitializationOptions is whatever JSON the client sent, decoded into an any, so the
+	// simplest way to pick it apart is to round-trip it through JSON.
+	data, err := json.Marshal(params.InitializationOptions)
+	if err != nil {
+		return false
+	}
+	var options struct {
+		FormatOnSave bool `json:"formatOnSave"`
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return false
+	}
+	return options.FormatOnSave
+}
+
+// diffToTextEdits computes a minimal set of whole-line edits that transform from into to.
+//
+// Editors preserve the cursor position and folding state outside of edited regions,
+// so this is much less disruptive than replacing the whole file.
+func diffToTextEdits(from, to string) []protocol.TextEdit {
+	fromLines := splitLines(from)
+	toLines := splitLines(to)
+
+	// Each run of adjacent inserts and deletes becomes a single edit, which replaces
+	// the deleted lines with the inserted ones.
+	var textEdits []protocol.TextEdit
+	var newText strings.Builder
+	var start, end int
+	flush := func() {
+		textEdits = append(textEdits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(start)},
+				End:   protocol.Position{Line: uint32(end)},
+			},
+			NewText: newText.String(),
+		})
+		newText.Reset()
+	}
+	edits := diffmyers.Diff(fromLines, toLines)
+	for i, edit := range edits {
+		if i == 0 {
+			start, end = edit.FromPosition, edit.FromPosition
+		} else if edit.FromPosition > end {
+			flush()
+			start, end = edit.FromPosition, edit.FromPosition
+		}
+		switch edit.Kind {
+		case diffmyers.EditKindDelete:
+			end = edit.FromPosition + 1
+		case diffmyers.EditKindInsert:
+			newText.Write(toLines[edit.ToPosition])
+		}
+	}
+	if len(edits) > 0 {
+		flush()
+	}
+	return textEdits
+}
+
+// splitLines splits text into lines, keeping the trailing newline of each line.
+func splitLines(text string) [][]byte {
+	var lines [][]byte
+	for len(text) > 0 {
+		idx := strings.IndexByte(text, '\n') + 1
+		if idx == 0 {
+			idx = len(text)
+		}
+		lines = append(lines, []byte(text[:idx]))
+		text = text[idx:]
+	}
+	return lines
+}
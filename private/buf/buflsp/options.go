@@ -0,0 +1,118 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements rendering of the options set on a definition.
+
+package buflsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/protocompile/linker"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// formatOptions renders the options set on the descriptor named name as option
+// declarations, one per line. Custom options, such as google.api.http or validation
+// rules, are resolved against the files visible from linkerFile.
+//
+// Returns the empty string if name cannot be found or has no options set.
+func formatOptions(linkerFile linker.File, name protoreflect.FullName) string {
+	resolver := linker.ResolverFromFile(linkerFile)
+	descriptor, err := resolver.FindDescriptorByName(name)
+	if err != nil {
+		return ""
+	}
+	options := descriptor.Options()
+	if options == nil {
+		return ""
+	}
+
+	// Custom options may be stored as unknown fields, so we round-trip the options
+	// through the wire format with a resolver that knows about the extensions visible
+	// from this file.
+	data, err := proto.Marshal(options)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	resolved := options.ProtoReflect().New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, resolved); err != nil {
+		return ""
+	}
+
+	type option struct {
+		field protoreflect.FieldDescriptor
+		value protoreflect.Value
+	}
+	var set []option
+	resolved.ProtoReflect().Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		set = append(set, option{field, value})
+		return true
+	})
+	slices.SortFunc(set, func(a, b option) int {
+		return int(a.field.Number()) - int(b.field.Number())
+	})
+
+	var out strings.Builder
+	for _, option := range set {
+		optionName := string(option.field.Name())
+		if option.field.IsExtension() {
+			optionName = "(" + string(option.field.FullName()) + ")"
+		}
+		if option.field.IsList() {
+			list := option.value.List()
+			for i := 0; i < list.Len(); i++ {
+				fmt.Fprintf(&out, "option %s = %s;\n", optionName, formatOptionValue(option.field, list.Get(i)))
+			}
+			continue
+		}
+		fmt.Fprintf(&out, "option %s = %s;\n", optionName, formatOptionValue(option.field, option.value))
+	}
+	return out.String()
+}
+
+// formatOptionValue renders a single value of field in Protobuf syntax.
+func formatOptionValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		text := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Format(value.Message().Interface())
+		if text == "" {
+			return "{}"
+		}
+		var out strings.Builder
+		out.WriteString("{\n")
+		for _, line := range strings.SplitAfter(text, "\n") {
+			if line != "" {
+				out.WriteString("  " + line)
+			}
+		}
+		out.WriteString("}")
+		return out.String()
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return fmt.Sprint(value.Enum())
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", value.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", value.Bytes())
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements finding the references to a symbol across a workspace.
+
+package buflsp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"go.lsp.dev/protocol"
+)
+
+// symbolReference is an occurrence of the name of a definition.
+type symbolReference struct {
+	uri protocol.URI
+	// The range of the part of the name that names the definition.
+	rng protocol.Range
+	// Whether this is the definition itself, rather than a reference to it.
+	isDefinition bool
+}
+
+// findReferences finds every occurrence of def's name in the files of origin's workspace,
+// including in option values and extensions, in file order.
+func (l *lsp) findReferences(ctx context.Context, origin *file, def *symbol) ([]symbolReference, error) {
+	// The workspace is only known for files that were opened by the editor.
+	if origin.workspace == nil {
+		origin.FindModule(ctx)
+	}
+	workspace := origin.workspace
+	if workspace == nil {
+		return nil, fmt.Errorf("could not find workspace for %q", origin.uri.Filename())
+	}
+
+	var paths []string
+	for _, module := range workspace.Modules() {
+		if !module.IsLocal() {
+			continue
+		}
+		if err := module.WalkFileInfos(ctx, func(fileInfo bufmodule.FileInfo) error {
+			if fileInfo.LocalPath() != "" {
+				paths = append(paths, fileInfo.LocalPath())
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// References can only appear in files that can see def's file, so we only need to
+	// index those. Files that are not open in the editor are opened for the duration
+	// of the search.
+	defPath := def.kind.(*definition).path
+	var references []symbolReference
+	for _, path := range paths {
+		fileReferences, err := l.findReferencesInFile(ctx, origin, path, def.file, defPath)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, fileReferences...)
+	}
+	return references, nil
+}
+
+// findReferencesInFile finds every occurrence of the name of the definition at defPath
+// in defFile in the file at path.
+func (l *lsp) findReferencesInFile(
+	ctx context.Context,
+	origin *file,
+	path string,
+	defFile *file,
+	defPath []string,
+) ([]symbolReference, error) {
+	file := l.fileManager.Open(ctx, protocol.URI("file://"+path))
+	defer file.Close(ctx)
+
+	if err := file.ReadFromDisk(ctx); err != nil {
+		return nil, err
+	}
+	// A file can only refer to def if it spells out its name, so we can skip indexing
+	// most of the files of the workspace without parsing them.
+	if file != defFile && !strings.Contains(file.text, defPath[len(defPath)-1]) {
+		return nil, nil
+	}
+	file.RefreshAST(ctx)
+	if file.importablePathToObject == nil {
+		// All of these files are in the same workspace, so they can import the same files.
+		file.importablePathToObject = origin.importablePathToObject
+	}
+	file.IndexImports(ctx)
+	if file != defFile && !slices.Contains(file.visibleImports(), defFile) {
+		return nil, nil
+	}
+	if file.symbols == nil || !file.IsOpenInEditor() {
+		// Files that were only indexed as imports of other files have unresolved
+		// cross-file references, so they need to be indexed again.
+		file.IndexSymbols(ctx)
+	}
+
+	// Finding the definition file's symbols may have reindexed it, so look def up again.
+	def := findDefinition(defFile, defPath)
+	if def == nil {
+		return nil, fmt.Errorf("could not find %q in %q", defPath, defFile.uri.Filename())
+	}
+
+	var references []symbolReference
+	for _, symbol := range file.symbols {
+		if rng, ok := symbol.RenameRange(def); ok {
+			_, isDefinition := symbol.kind.(*definition)
+			references = append(references, symbolReference{
+				uri:          file.uri,
+				rng:          rng,
+				isDefinition: isDefinition,
+			})
+		}
+	}
+	return references, nil
+}
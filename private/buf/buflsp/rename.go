@@ -0,0 +1,129 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements renaming of symbols across a workspace.
+
+package buflsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/protocompile/ast"
+	"go.lsp.dev/protocol"
+)
+
+// identPattern matches a valid Protobuf identifier.
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RenameTarget returns the definition that renaming s would rename.
+//
+// Returns an error if s cannot be renamed.
+func (s *symbol) RenameTarget(ctx context.Context) (*symbol, error) {
+	def, node := s.Definition(ctx)
+	if def == nil {
+		return nil, errors.New("cannot rename a symbol whose definition could not be found")
+	}
+
+	switch node.(type) {
+	case *ast.MessageNode, *ast.EnumNode, *ast.EnumValueNode,
+		*ast.FieldNode, *ast.MapFieldNode, *ast.OneofNode,
+		*ast.ServiceNode, *ast.RPCNode:
+	default:
+		return nil, errors.New("only messages, enums, enum values, fields, oneofs, services, and methods can be renamed")
+	}
+
+	if !def.file.IsLocal() {
+		return nil, fmt.Errorf("cannot rename %q, because it is not defined in this workspace", def.file.uri.Filename())
+	}
+	return def, nil
+}
+
+// RenameRange returns the range of the part of s's name that must be replaced when
+// renaming def.
+//
+// Returns false if s does not refer to def. Note that a reference may refer to def
+// without spelling out its name, e.g. the reference Baz in message Foo refers to
+// Foo.Baz, but renaming Foo does not touch it.
+func (s *symbol) RenameRange(def *symbol) (protocol.Range, bool) {
+	defPath := def.kind.(*definition).path
+
+	switch kind := s.kind.(type) {
+	case *definition:
+		if s.file == def.file && slices.Equal(kind.path, defPath) {
+			return s.Range(), true
+		}
+
+	case *reference:
+		if kind.file != def.file {
+			return protocol.Range{}, false
+		}
+		rest, ok := slicesext.TrimPrefix(kind.path, defPath)
+		if !ok {
+			return protocol.Range{}, false
+		}
+
+		// Find which component of the name spells out def's name. It is followed by
+		// exactly the components in rest.
+		var components []*ast.IdentNode
+		switch name := s.name.(type) {
+		case *ast.IdentNode:
+			components = []*ast.IdentNode{name}
+		case *ast.CompoundIdentNode:
+			components = name.Components
+		}
+		idx := len(components) - len(rest) - 1
+		if idx < 0 || components[idx].Val != defPath[len(defPath)-1] {
+			return protocol.Range{}, false
+		}
+		return infoToRange(s.file.fileNode.NodeInfo(components[idx])), true
+	}
+
+	return protocol.Range{}, false
+}
+
+// renameSymbol computes the edits needed to rename def to newName in every file of the
+// workspace def is defined in.
+func (l *lsp) renameSymbol(ctx context.Context, def *symbol, newName string) (*protocol.WorkspaceEdit, error) {
+	if !identPattern.MatchString(newName) {
+		return nil, fmt.Errorf("%q is not a valid Protobuf identifier", newName)
+	}
+
+	references, err := l.findReferences(ctx, def.file, def)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	for _, reference := range references {
+		changes[reference.uri] = append(changes[reference.uri], protocol.TextEdit{
+			Range:   reference.rng,
+			NewText: newName,
+		})
+	}
+	return &protocol.WorkspaceEdit{Changes: changes}, nil
+}
+
+// findDefinition finds the symbol defining path in file.
+func findDefinition(file *file, path []string) *symbol {
+	for _, symbol := range file.symbols {
+		if def, ok := symbol.kind.(*definition); ok && slices.Equal(def.path, path) {
+			return symbol
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements semantic highlighting of files.
+
+package buflsp
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bufbuild/protocompile/ast"
+)
+
+const (
+	semanticTypeType = iota
+	semanticTypeStruct
+	semanticTypeVariable
+	semanticTypeEnum
+	semanticTypeEnumMember
+	semanticTypeInterface
+	semanticTypeMethod
+	semanticTypeDecorator
+	semanticTypeKeyword
+	semanticTypeString
+	semanticTypeNumber
+	semanticTypeProperty
+	semanticTypeNamespace
+)
+
+const (
+	semanticModifierDeclaration = 1 << iota
+	semanticModifierDefaultLibrary
+)
+
+var (
+	// These slices must match the order of the indices in the above const blocks.
+	semanticTypeLegend = []string{
+		"type", "struct", "variable", "enum",
+		"enumMember", "interface", "method", "decorator",
+		"keyword", "string", "number", "property",
+		"namespace",
+	}
+	semanticModifierLegend = []string{
+		"declaration", "defaultLibrary",
+	}
+)
+
+// semanticToken is a single classified range of a file.
+type semanticToken struct {
+	info      ast.NodeInfo
+	typ       uint32
+	modifiers uint32
+}
+
+// SemanticTokens classifies the contents of this file for syntax highlighting, and
+// returns them in the encoding described in the LSP specification.
+//
+// Names are classified using the symbol table, so e.g. a reference to an enum is
+// highlighted as an enum; everything else is classified from the AST.
+func (f *file) SemanticTokens(ctx context.Context) []uint32 {
+	if f.fileNode == nil {
+		return nil
+	}
+
+	var tokens []semanticToken
+	for _, symbol := range f.symbols {
+		if token, ok := symbol.semanticToken(ctx); ok {
+			tokens = append(tokens, token)
+		}
+	}
+
+	// NOTE: Errors are only returned by the walk if our callback returns one.
+	_ = ast.Walk(f.fileNode, &ast.SimpleVisitor{}, ast.WithBefore(func(node ast.Node) error {
+		add := func(node ast.Node, typ uint32) {
+			tokens = append(tokens, semanticToken{info: f.fileNode.NodeInfo(node), typ: typ})
+		}
+		// addValue adds a token for the identifier-valued literals, which are
+		// otherwise indistinguishable from enum values.
+		addValue := func(value ast.ValueNode) {
+			if ident, ok := value.(*ast.IdentNode); ok {
+				switch ident.Val {
+				case "true", "false", "inf", "nan":
+					add(ident, semanticTypeKeyword)
+				}
+			}
+		}
+
+		switch node := node.(type) {
+		case *ast.PackageNode:
+			add(node.Name, semanticTypeNamespace)
+		case *ast.OptionNode:
+			addValue(node.Val)
+		case *ast.MessageFieldNode:
+			addValue(node.Val)
+		case *ast.ArrayLiteralNode:
+			for _, element := range node.Elements {
+				addValue(element)
+			}
+		case *ast.SpecialFloatLiteralNode, *ast.NegativeIntLiteralNode, *ast.SignedFloatLiteralNode,
+			*ast.UintLiteralNode, *ast.FloatLiteralNode:
+			add(node, semanticTypeNumber)
+		case *ast.StringLiteralNode:
+			add(node, semanticTypeString)
+		case *ast.KeywordNode:
+			add(node, semanticTypeKeyword)
+		}
+		return nil
+	}))
+
+	// Tokens must be sent in order and may not overlap. Where they do, e.g. for the
+	// minus sign of a negative number and the number itself, the earliest and longest
+	// token wins. Symbols were added first, so they win ties.
+	slices.SortStableFunc(tokens, func(a, b semanticToken) int {
+		if diff := a.info.Start().Offset - b.info.Start().Offset; diff != 0 {
+			return diff
+		}
+		return b.info.End().Offset - a.info.End().Offset
+	})
+
+	// This fairly painful encoding is described in detail here:
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_semanticTokens
+	var (
+		encoded           []uint32
+		prevLine, prevCol uint32
+		prevEnd           int
+	)
+	for _, token := range tokens {
+		start, end := token.info.Start(), token.info.End()
+		if start.Offset < prevEnd || start.Line != end.Line {
+			// Overlapping or multi-line tokens cannot be represented without extra
+			// client capabilities, and no token we produce should be multi-line.
+			continue
+		}
+
+		line := uint32(start.Line - 1)
+		col := uint32(start.Col - 1)
+		deltaCol := col
+		if line == prevLine {
+			deltaCol -= prevCol
+		}
+
+		encoded = append(encoded, line-prevLine, deltaCol, uint32(end.Col-start.Col), token.typ, token.modifiers)
+		prevLine, prevCol, prevEnd = line, col, end.Offset
+	}
+	return encoded
+}
+
+// semanticToken classifies this symbol for syntax highlighting.
+//
+// Returns false if this symbol should not be highlighted as a name, e.g. because
+// it could not be resolved.
+func (s *symbol) semanticToken(ctx context.Context) (semanticToken, bool) {
+	token := semanticToken{info: s.info}
+
+	if s.isOption {
+		token.typ = semanticTypeDecorator
+		return token, true
+	}
+
+	if _, ok := s.kind.(*builtin); ok {
+		token.typ = semanticTypeType
+		token.modifiers = semanticModifierDefaultLibrary
+		return token, true
+	}
+
+	def, defNode := s.Definition(ctx)
+	if def == nil {
+		return token, false
+	}
+	switch defNode.(type) {
+	case *ast.MessageNode, *ast.GroupNode:
+		token.typ = semanticTypeStruct
+	case *ast.FieldNode, *ast.MapFieldNode:
+		token.typ = semanticTypeProperty
+	case *ast.OneofNode:
+		token.typ = semanticTypeVariable
+	case *ast.EnumNode:
+		token.typ = semanticTypeEnum
+	case *ast.EnumValueNode:
+		token.typ = semanticTypeEnumMember
+	case *ast.ServiceNode:
+		token.typ = semanticTypeInterface
+	case *ast.RPCNode:
+		token.typ = semanticTypeMethod
+	default:
+		return token, false
+	}
+
+	if def == s {
+		token.modifiers |= semanticModifierDeclaration
+	}
+	if def.file.IsWKT() {
+		token.modifiers |= semanticModifierDefaultLibrary
+	}
+	return token, true
+}
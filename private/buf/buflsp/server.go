@@ -19,35 +19,15 @@ package buflsp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime/debug"
+	"slices"
 	"strings"
 
-	"github.com/bufbuild/buf/private/buf/bufformat"
-	"github.com/bufbuild/protocompile/ast"
 	"go.lsp.dev/protocol"
 )
 
-const (
-	semanticTypeType = iota
-	semanticTypeStruct
-	semanticTypeVariable
-	semanticTypeEnum
-	semanticTypeEnumMember
-	semanticTypeInterface
-	semanticTypeMethod
-	semanticTypeDecorator
-)
-
-var (
-	// These slices must match the order of the indices in the above const block.
-	semanticTypeLegend = []string{
-		"type", "struct", "variable", "enum",
-		"enumMember", "interface", "method", "decorator",
-	}
-	semanticModifierLegend = []string{}
-)
-
 // server is an implementation of protocol.Server.
 //
 // This is a separate type from buflsp.lsp so that the dozens of handler methods for this
@@ -110,12 +90,24 @@ func (s *server) Initialize(
 				// usually get especially huge, so this simplifies our logic without
 				// necessarily making the LSP slow.
 				Change: protocol.TextDocumentSyncKindFull,
+				// Only ask to be notified of saves if we're going to format them.
+				WillSaveWaitUntil: s.formatOnSave(),
+			},
+			CodeActionProvider: &protocol.CodeActionOptions{
+				CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix},
 			},
 			DefinitionProvider: &protocol.DefinitionOptions{
 				WorkDoneProgressOptions: protocol.WorkDoneProgressOptions{WorkDoneProgress: true},
 			},
-			DocumentFormattingProvider: true,
-			HoverProvider:              true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentSymbolProvider:          true,
+			HoverProvider:                   true,
+			ReferencesProvider:              true,
+			RenameProvider: &protocol.RenameOptions{
+				PrepareProvider: true,
+			},
+			WorkspaceSymbolProvider: true,
 			SemanticTokensProvider: &SemanticTokensOptions{
 				WorkDoneProgressOptions: protocol.WorkDoneProgressOptions{WorkDoneProgress: true},
 				Legend: SematicTokensLegend{
@@ -135,6 +127,11 @@ func (s *server) Initialized(
 	ctx context.Context,
 	params *protocol.InitializedParams,
 ) error {
+	if s.canWatchFiles() {
+		// Registering is a request to the client, which we must not wait on while
+		// handling a notification.
+		go s.watchConfigFiles(context.WithoutCancel(ctx))
+	}
 	return nil
 }
 
@@ -171,6 +168,9 @@ func (s *server) DidOpen(
 	params *protocol.DidOpenTextDocumentParams,
 ) error {
 	file := s.fileManager.Open(ctx, params.TextDocument.URI)
+	// Opening a file is a good time to pick up any changes to the workspace
+	// configuration, so we discard everything we know about it.
+	file.Reset(ctx)
 	file.Update(ctx, params.TextDocument.Version, params.TextDocument.Text)
 	file.Refresh(context.WithoutCancel(ctx))
 	return nil
@@ -190,6 +190,25 @@ func (s *server) DidChange(
 
 	file.Update(ctx, params.TextDocument.Version, params.ContentChanges[0].Text)
 	file.Refresh(context.WithoutCancel(ctx))
+	// Files that depend on this one are rebuilt in the background once the user stops
+	// typing, so that this request does not wait on them.
+	s.fileManager.ScheduleRefreshDependents(file)
+	return nil
+}
+
+// DidChangeWatchedFiles is called whenever files watched by the client change. We watch
+// the Buf configuration files, so that the workspaces of open files are reloaded when the
+// modules or dependencies of the workspace change.
+func (s *server) DidChangeWatchedFiles(
+	ctx context.Context,
+	params *protocol.DidChangeWatchedFilesParams,
+) error {
+	for _, change := range params.Changes {
+		if isConfigFile(change.URI.Filename()) {
+			s.fileManager.ResetWorkspaces(context.WithoutCancel(ctx))
+			return nil
+		}
+	}
 	return nil
 }
 
@@ -206,21 +225,34 @@ func (s *server) Formatting(
 
 	// Currently we have no way to honor any of the parameters.
 	_ = params
-	if file.fileNode == nil {
-		return nil, nil
+	return file.Format(nil)
+}
+
+// RangeFormatting is called whenever the user requests formatting of a selection.
+func (s *server) RangeFormatting(
+	ctx context.Context,
+	params *protocol.DocumentRangeFormattingParams,
+) ([]protocol.TextEdit, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, fmt.Errorf("received update for file that was not open: %q", params.TextDocument.URI)
 	}
 
-	var out strings.Builder
-	if err := bufformat.FormatFileNode(&out, file.fileNode); err != nil {
-		return nil, err
+	return file.Format(&params.Range)
+}
+
+// WillSaveWaitUntil is called before a file is saved, and allows us to edit it before
+// it is written. We use this to implement format-on-save.
+func (s *server) WillSaveWaitUntil(
+	ctx context.Context,
+	params *protocol.WillSaveTextDocumentParams,
+) ([]protocol.TextEdit, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil || !s.formatOnSave() || params.Reason != protocol.TextDocumentSaveReasonManual {
+		return nil, nil
 	}
 
-	return []protocol.TextEdit{
-		{
-			Range:   infoToRange(file.fileNode.NodeInfo(file.fileNode)),
-			NewText: out.String(),
-		},
-	}, nil
+	return file.Format(nil)
 }
 
 // DidOpen is called whenever the client opens a document. This is our signal to parse
@@ -304,81 +336,160 @@ func (s *server) Definition(
 	return nil, nil
 }
 
-// SemanticTokensFull is called to render semantic token information on the client.
-func (s *server) SemanticTokensFull(
+// Symbols is called to search for symbols across the whole workspace.
+func (s *server) Symbols(
 	ctx context.Context,
-	params *protocol.SemanticTokensParams,
-) (*protocol.SemanticTokens, error) {
+	params *protocol.WorkspaceSymbolParams,
+) ([]protocol.SymbolInformation, error) {
+	progress := newProgressFromClient(s.lsp, &params.WorkDoneProgressParams)
+	progress.Begin(ctx, "Indexing Symbols")
+	defer progress.Done(ctx)
+
+	return s.WorkspaceSymbols(ctx, params.Query)
+}
+
+// CodeAction is called to find the actions available for a range of a file, such as
+// quick fixes for the diagnostics in that range.
+func (s *server) CodeAction(
+	ctx context.Context,
+	params *protocol.CodeActionParams,
+) ([]protocol.CodeAction, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, nil
+	}
+
+	// We only provide quick fixes.
+	if len(params.Context.Only) > 0 && !slices.Contains(params.Context.Only, protocol.QuickFix) {
+		return nil, nil
+	}
+	return file.CodeActions(ctx, params.Context.Diagnostics), nil
+}
+
+// PrepareRename is called to check whether the symbol under the cursor can be renamed,
+// and to find the range of text that the user is renaming.
+func (s *server) PrepareRename(
+	ctx context.Context,
+	params *protocol.PrepareRenameParams,
+) (*protocol.Range, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, nil
+	}
+
+	symbol := file.SymbolAt(ctx, params.Position)
+	if symbol == nil {
+		return nil, nil
+	}
+	def, err := symbol.RenameTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rng, ok := symbol.RenameRange(def)
+	if !ok {
+		return nil, errors.New("cannot rename a symbol that is only referred to implicitly here")
+	}
+	return &rng, nil
+}
+
+// Rename is called to rename the symbol under the cursor, along with every reference
+// to it in the workspace.
+func (s *server) Rename(
+	ctx context.Context,
+	params *protocol.RenameParams,
+) (*protocol.WorkspaceEdit, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, nil
+	}
+
+	symbol := file.SymbolAt(ctx, params.Position)
+	if symbol == nil {
+		return nil, nil
+	}
+	def, err := symbol.RenameTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.renameSymbol(ctx, def, params.NewName)
+}
+
+// References is called to find all references to the symbol under the cursor.
+func (s *server) References(
+	ctx context.Context,
+	params *protocol.ReferenceParams,
+) ([]protocol.Location, error) {
 	file := s.fileManager.Get(params.TextDocument.URI)
 	if file == nil {
 		return nil, nil
 	}
 
 	progress := newProgressFromClient(s.lsp, &params.WorkDoneProgressParams)
-	progress.Begin(ctx, "Processing Tokens")
+	progress.Begin(ctx, "Searching")
 	defer progress.Done(ctx)
 
-	var (
-		encoded           []uint32
-		prevLine, prevCol uint32
-	)
-	for i, symbol := range file.symbols {
-		progress.Report(ctx, fmt.Sprintf("%d/%d", i+1, len(file.symbols)), float64(i)/float64(len(file.symbols)))
-
-		var semanticType uint32
+	symbol := file.SymbolAt(ctx, params.Position)
+	if symbol == nil {
+		return nil, nil
+	}
+	def, _ := symbol.Definition(ctx)
+	if def == nil {
+		return nil, nil
+	}
 
-		if symbol.isOption {
-			semanticType = semanticTypeDecorator
-		} else if def, defNode := symbol.Definition(ctx); def != nil {
-			switch defNode.(type) {
-			case *ast.FileNode:
+	references, err := s.findReferences(ctx, file, def)
+	if err != nil {
+		return nil, err
+	}
+	var locations []protocol.Location
+	var foundDefinition bool
+	for _, reference := range references {
+		if reference.isDefinition {
+			foundDefinition = true
+			if !params.Context.IncludeDeclaration {
 				continue
-			case *ast.MessageNode, *ast.GroupNode:
-				semanticType = semanticTypeStruct
-			case *ast.FieldNode, *ast.MapFieldNode, *ast.OneofNode:
-				semanticType = semanticTypeVariable
-			case *ast.EnumNode:
-				semanticType = semanticTypeEnum
-			case *ast.EnumValueNode:
-				semanticType = semanticTypeEnumMember
-			case *ast.ServiceNode:
-				semanticType = semanticTypeInterface
-			case *ast.RPCNode:
-				semanticType = semanticTypeMethod
 			}
-		} else if _, ok := symbol.kind.(*builtin); ok {
-			semanticType = semanticTypeType
-		} else {
-			continue
 		}
+		locations = append(locations, protocol.Location{URI: reference.uri, Range: reference.rng})
+	}
+	if params.Context.IncludeDeclaration && !foundDefinition {
+		// The definition is outside of the workspace, e.g. in a dependency.
+		locations = append([]protocol.Location{{URI: def.file.uri, Range: def.Range()}}, locations...)
+	}
+	return locations, nil
+}
 
-		// This fairly painful encoding is described in detail here:
-		// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_semanticTokens
-		start, end := symbol.info.Start(), symbol.info.End()
-		for i := start.Line; i <= end.Line; i++ {
-			newLine := uint32(i - 1)
-			var newCol uint32
-			if i == start.Line {
-				newCol = uint32(start.Col - 1)
-				if prevLine == newLine {
-					newCol -= prevCol
-				}
-			}
+// DocumentSymbol is called to render the outline of a file on the client.
+func (s *server) DocumentSymbol(
+	ctx context.Context,
+	params *protocol.DocumentSymbolParams,
+) ([]interface{}, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, nil
+	}
 
-			symbolLen := uint32(end.Col - 1)
-			if i == start.Line {
-				symbolLen -= uint32(start.Col - 1)
-			}
+	symbols := file.DocumentSymbols()
+	result := make([]interface{}, len(symbols))
+	for i, symbol := range symbols {
+		result[i] = symbol
+	}
+	return result, nil
+}
 
-			encoded = append(encoded, newLine-prevLine, newCol, symbolLen, semanticType, 0)
-			prevLine = newLine
-			if i == start.Line {
-				prevCol = uint32(start.Col - 1)
-			} else {
-				prevCol = 0
-			}
-		}
+// SemanticTokensFull is called to render semantic token information on the client.
+func (s *server) SemanticTokensFull(
+	ctx context.Context,
+	params *protocol.SemanticTokensParams,
+) (*protocol.SemanticTokens, error) {
+	file := s.fileManager.Get(params.TextDocument.URI)
+	if file == nil {
+		return nil, nil
 	}
 
-	return &protocol.SemanticTokens{Data: encoded}, nil
+	progress := newProgressFromClient(s.lsp, &params.WorkDoneProgressParams)
+	progress.Begin(ctx, "Processing Tokens")
+	defer progress.Done(ctx)
+
+	return &protocol.SemanticTokens{Data: file.SemanticTokens(ctx)}, nil
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/protocompile/ast"
 	"go.lsp.dev/protocol"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // symbol represents a named symbol inside of a buflsp.file
@@ -215,7 +216,7 @@ func (s *symbol) ResolveCrossFile(ctx context.Context) {
 			// actually validate if the dependent symbol exists, because that will happen for us
 			// when we go to hover over the symbol.
 			ref, ok = ty.kind.(*reference)
-			if !ok || ty.file == nil {
+			if !ok || ref.file == nil {
 				s.file.lsp.logger.DebugContext(
 					ctx,
 					"dependent symbol's field type didn't resolve to a reference",
@@ -227,7 +228,7 @@ func (s *symbol) ResolveCrossFile(ctx context.Context) {
 			}
 
 			// Done.
-			kind.file = def.file
+			kind.file = ref.file
 			kind.path = append(slicesext.Copy(ref.path), components...)
 			return
 		}
@@ -292,7 +293,7 @@ func (s *symbol) ResolveCrossFile(ctx context.Context) {
 			return
 		}
 
-		for _, imported := range s.file.importToFile {
+		for _, imported := range s.file.visibleImports() {
 			// If necessary, refresh the file. Note that this cannot hit
 			// cycles, because fileNode will become non-nil after calling
 			// Refresh but before calling IndexSymbols.
@@ -520,6 +521,21 @@ func (s *symbol) FormatDocs(ctx context.Context) string {
 		fmt.Fprintln(&tooltip, "<missing docs>")
 	}
 
+	// Finally, show the options set on the definition, with custom options resolved.
+	fullName := strings.Join(path, ".")
+	if pkgNode := def.file.packageNode; pkgNode != nil {
+		fullName = string(pkgNode.Name.AsIdentifier()) + "." + fullName
+	}
+	for _, file := range []*file{s.file, def.file} {
+		if file.linkerFile == nil {
+			continue
+		}
+		if options := formatOptions(file.linkerFile, protoreflect.FullName(fullName)); options != "" {
+			fmt.Fprintf(&tooltip, "\n```proto\n%s```\n", options)
+			break
+		}
+	}
+
 	return tooltip.String()
 }
 
@@ -683,7 +699,49 @@ func (w *symbolWalker) Walk(node, parent ast.Node) {
 			next.isOption = true
 		}
 
-		// TODO: node.Val
+		if len(w.symbols) > 0 {
+			w.walkOptionValue(node.Val, w.symbols[len(w.symbols)-1])
+		}
+	}
+}
+
+// walkOptionValue generates symbols for the field names and enum values that appear
+// in the value of an option. These are resolved against the type of typeOf, which is
+// the symbol for the field the value is being assigned to.
+func (w *symbolWalker) walkOptionValue(value ast.ValueNode, typeOf *symbol) {
+	switch value := value.(type) {
+	case *ast.IdentNode:
+		switch value.Val {
+		case "true", "false", "inf", "nan":
+			// These are literals, not enum values.
+			return
+		}
+		symbol := w.newSymbol(value)
+		symbol.kind = &reference{seeTypeOf: typeOf}
+
+	case *ast.ArrayLiteralNode:
+		for _, element := range value.Elements {
+			w.walkOptionValue(element, typeOf)
+		}
+
+	case *ast.MessageLiteralNode:
+		for _, field := range value.Elements {
+			var next *symbol
+			switch {
+			case field.Name.IsAnyTypeReference():
+				// The type named here is not the type of a field, so we cannot resolve
+				// the fields of the value against it.
+				w.newRef(field.Name.Name)
+				continue
+			case field.Name.IsExtension():
+				next = w.newRef(field.Name.Name)
+				next.isOption = true
+			default:
+				next = w.newSymbol(field.Name.Name)
+				next.kind = &reference{seeTypeOf: typeOf}
+			}
+			w.walkOptionValue(field.Val, next)
+		}
 	}
 }
 
@@ -0,0 +1,435 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements the workspace-wide symbol index.
+
+package buflsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+	"github.com/google/uuid"
+	"go.lsp.dev/protocol"
+	"go.uber.org/multierr"
+)
+
+// maxWorkspaceSymbols is the maximum number of symbols returned for a single query.
+const maxWorkspaceSymbols = 256
+
+// symbolIndex is an index of the symbols defined in every file of the open workspaces,
+// including the files of their remote dependencies.
+//
+// The index is updated incrementally: a local file is only reparsed if it was changed
+// since it was last indexed. The files of a remote dependency never change, so the
+// symbols of each dependency commit are also persisted next to its files in the
+// dependency directory, and are not recomputed across restarts of the server.
+type symbolIndex struct {
+	// The indexed files, by local path.
+	files map[string]*indexedFile
+	// The symbols of remote dependencies, by the directory their files were written to,
+	// and then by path within the module.
+	dependencies map[string]map[string][]indexedSymbol
+}
+
+// indexedFile is a file in the symbol index.
+type indexedFile struct {
+	// An opaque version of the file's contents. If it changes, the file is reindexed.
+	version string
+	symbols []indexedSymbol
+}
+
+// indexedSymbol is a symbol in the symbol index.
+type indexedSymbol struct {
+	// The fully qualified name of the symbol.
+	Name string `json:"name"`
+	// The fully qualified name of the symbol this symbol is nested in, if any.
+	ContainerName string              `json:"container_name,omitempty"`
+	Kind          protocol.SymbolKind `json:"kind"`
+	Range         protocol.Range      `json:"range"`
+}
+
+// newSymbolIndex creates a new, empty symbol index.
+func newSymbolIndex() *symbolIndex {
+	return &symbolIndex{
+		files:        make(map[string]*indexedFile),
+		dependencies: make(map[string]map[string][]indexedSymbol),
+	}
+}
+
+// WorkspaceSymbols returns the symbols in the open workspaces whose fully qualified names
+// fuzzily match query, best match first.
+func (l *lsp) WorkspaceSymbols(ctx context.Context, query string) ([]protocol.SymbolInformation, error) {
+	if err := l.updateSymbolIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		symbol protocol.SymbolInformation
+		score  int
+	}
+	var matches []match
+	for localPath, indexed := range l.symbolIndex.files {
+		for _, symbol := range indexed.symbols {
+			score, ok := fuzzyMatch(query, symbol.Name)
+			if !ok {
+				continue
+			}
+			matches = append(matches, match{
+				symbol: protocol.SymbolInformation{
+					Name:          symbol.Name,
+					Kind:          symbol.Kind,
+					ContainerName: symbol.ContainerName,
+					Location: protocol.Location{
+						URI:   protocol.URI("file://" + localPath),
+						Range: symbol.Range,
+					},
+				},
+				score: score,
+			})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if len(a.symbol.Name) != len(b.symbol.Name) {
+			return len(a.symbol.Name) - len(b.symbol.Name)
+		}
+		return strings.Compare(a.symbol.Name, b.symbol.Name)
+	})
+	if len(matches) > maxWorkspaceSymbols {
+		matches = matches[:maxWorkspaceSymbols]
+	}
+
+	symbols := make([]protocol.SymbolInformation, len(matches))
+	for i, match := range matches {
+		symbols[i] = match.symbol
+	}
+	return symbols, nil
+}
+
+// updateSymbolIndex brings the symbol index up to date with the files of the open
+// workspaces. Files that are no longer in any workspace are dropped from the index.
+func (l *lsp) updateSymbolIndex(ctx context.Context) error {
+	workspaces, err := l.openWorkspaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]*indexedFile)
+	for _, workspace := range workspaces {
+		for _, module := range workspace.Modules() {
+			if !module.IsLocal() {
+				if err := l.indexDependency(ctx, module, files); err != nil {
+					l.logger.Warn(fmt.Sprintf("could not index symbols of %s: %s", module.OpaqueID(), err))
+				}
+				continue
+			}
+			if err := module.WalkFileInfos(ctx, func(fileInfo bufmodule.FileInfo) error {
+				if localPath := fileInfo.LocalPath(); localPath != "" {
+					if indexed := l.indexLocalFile(localPath); indexed != nil {
+						files[localPath] = indexed
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	l.symbolIndex.files = files
+	return nil
+}
+
+// openWorkspaces returns the workspaces of the files open in the editor, or if there
+// are none, the workspace at the root the client was initialized with.
+func (l *lsp) openWorkspaces(ctx context.Context) ([]bufworkspace.Workspace, error) {
+	var workspaces []bufworkspace.Workspace
+	l.fileManager.uriToFile.Range(func(_ protocol.URI, file *file) bool {
+		if file.workspace != nil && !slices.Contains(workspaces, file.workspace) {
+			workspaces = append(workspaces, file.workspace)
+		}
+		return true
+	})
+	if len(workspaces) > 0 {
+		return workspaces, nil
+	}
+
+	params := l.initParams.Load()
+	var rootURI protocol.URI
+	switch {
+	case len(params.WorkspaceFolders) > 0:
+		rootURI = protocol.URI(params.WorkspaceFolders[0].URI)
+	case params.RootURI != "":
+		rootURI = params.RootURI
+	default:
+		return nil, nil
+	}
+	workspace, err := l.controller.GetWorkspace(ctx, rootURI.Filename())
+	if err != nil {
+		return nil, err
+	}
+	return []bufworkspace.Workspace{workspace}, nil
+}
+
+// indexLocalFile returns the index entry for the local file at localPath, reusing
+// the existing entry if the file has not changed.
+//
+// Returns nil if the file cannot be read.
+func (l *lsp) indexLocalFile(localPath string) *indexedFile {
+	// Files open in the editor may have unsaved changes, so we index the editor's
+	// contents instead of the file on disk.
+	var version, text string
+	if file := l.fileManager.Get(protocol.URI("file://" + localPath)); file != nil && file.IsOpenInEditor() {
+		version = fmt.Sprintf("editor:%d", file.version)
+		text = file.text
+	} else {
+		fileInfo, err := os.Stat(localPath)
+		if err != nil {
+			return nil
+		}
+		version = fmt.Sprintf("disk:%d:%d", fileInfo.ModTime().UnixNano(), fileInfo.Size())
+	}
+	if indexed := l.symbolIndex.files[localPath]; indexed != nil && indexed.version == version {
+		return indexed
+	}
+
+	if strings.HasPrefix(version, "disk:") {
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return nil
+		}
+		text = string(data)
+	}
+	return &indexedFile{
+		version: version,
+		symbols: indexSymbols(localPath, text),
+	}
+}
+
+// indexDependency adds the files of a remote dependency to files.
+//
+// The files are written to the dependency directory so that the client can open them,
+// and their symbols are persisted next to them.
+func (l *lsp) indexDependency(ctx context.Context, module bufmodule.Module, files map[string]*indexedFile) error {
+	moduleFullName := module.ModuleFullName()
+	commitID := module.CommitID()
+	if l.depsDirPath == "" || moduleFullName == nil || commitID == uuid.Nil {
+		// There would be no file for the client to open.
+		return nil
+	}
+	commitDirPath := filepath.Join(
+		l.depsDirPath,
+		filepath.FromSlash(moduleFullName.String()),
+		uuidutil.ToDashless(commitID),
+	)
+	indexPath := commitDirPath + ".symbols.json"
+
+	pathToSymbols, ok := l.symbolIndex.dependencies[commitDirPath]
+	if !ok {
+		var err error
+		pathToSymbols, err = readDependencyIndex(indexPath)
+		if err != nil {
+			pathToSymbols = make(map[string][]indexedSymbol)
+			if err := module.WalkFileInfos(ctx, func(fileInfo bufmodule.FileInfo) error {
+				materialized, err := l.materializeFileInfo(ctx, fileInfo)
+				if err != nil {
+					return err
+				}
+				data, err := os.ReadFile(materialized.LocalPath())
+				if err != nil {
+					return err
+				}
+				pathToSymbols[fileInfo.Path()] = indexSymbols(materialized.LocalPath(), string(data))
+				return nil
+			}); err != nil {
+				return err
+			}
+			if err := writeDependencyIndex(indexPath, pathToSymbols); err != nil {
+				l.logger.Warn(fmt.Sprintf("could not persist symbol index %s: %s", indexPath, err))
+			}
+		}
+		l.symbolIndex.dependencies[commitDirPath] = pathToSymbols
+	}
+
+	version := uuidutil.ToDashless(commitID)
+	for path, symbols := range pathToSymbols {
+		files[filepath.Join(commitDirPath, filepath.FromSlash(path))] = &indexedFile{
+			version: version,
+			symbols: symbols,
+		}
+	}
+	return nil
+}
+
+// readDependencyIndex reads a persisted dependency index.
+func readDependencyIndex(indexPath string) (map[string][]indexedSymbol, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	var pathToSymbols map[string][]indexedSymbol
+	if err := json.Unmarshal(data, &pathToSymbols); err != nil {
+		return nil, err
+	}
+	return pathToSymbols, nil
+}
+
+// writeDependencyIndex persists a dependency index.
+//
+// Like the files of the dependency, the index is written to a temporary file first
+// and then renamed, so that a concurrent reader never observes a partial index.
+func writeDependencyIndex(indexPath string, pathToSymbols map[string][]indexedSymbol) error {
+	data, err := json.Marshal(pathToSymbols)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(indexPath), filepath.Base(indexPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		return multierr.Combine(err, tempFile.Close(), os.Remove(tempFile.Name()))
+	}
+	if err := tempFile.Close(); err != nil {
+		return multierr.Append(err, os.Remove(tempFile.Name()))
+	}
+	if err := os.Rename(tempFile.Name(), indexPath); err != nil {
+		return multierr.Append(err, os.Remove(tempFile.Name()))
+	}
+	return nil
+}
+
+// indexSymbols returns the symbols defined in the given file.
+//
+// This only requires parsing the file, so symbols are indexed even for files with
+// errors.
+func indexSymbols(path string, text string) []indexedSymbol {
+	// Ignore all errors; the parser recovers from them as best as it can.
+	handler := reporter.NewHandler(reporter.NewReporter(
+		func(reporter.ErrorWithPos) error { return nil },
+		nil,
+	))
+	fileNode, _ := parser.Parse(path, strings.NewReader(text), handler)
+	if fileNode == nil {
+		return nil
+	}
+
+	// The document outline already has all of the information we need, we just need to
+	// flatten it and qualify the names.
+	documentSymbols := (&file{fileNode: fileNode}).DocumentSymbols()
+	var pkg string
+	for _, documentSymbol := range documentSymbols {
+		if documentSymbol.Kind == protocol.SymbolKindPackage {
+			pkg = documentSymbol.Name
+		}
+	}
+
+	var symbols []indexedSymbol
+	var visit func(documentSymbols []protocol.DocumentSymbol, scope string, container string)
+	visit = func(documentSymbols []protocol.DocumentSymbol, scope string, container string) {
+		for _, documentSymbol := range documentSymbols {
+			switch {
+			case documentSymbol.Kind == protocol.SymbolKindPackage:
+				continue
+			case documentSymbol.Kind == protocol.SymbolKindNamespace:
+				// Extensions are scoped to the block the extend appears in, not to
+				// the extended message.
+				visit(documentSymbol.Children, scope, container)
+				continue
+			}
+
+			name := documentSymbol.Name
+			if scope != "" {
+				name = scope + "." + name
+			}
+			symbols = append(symbols, indexedSymbol{
+				Name:          name,
+				ContainerName: container,
+				Kind:          documentSymbol.Kind,
+				Range:         documentSymbol.SelectionRange,
+			})
+
+			// Enum values and the fields of a oneof are scoped to the enclosing scope.
+			childScope := name
+			if documentSymbol.Kind == protocol.SymbolKindEnum || documentSymbol.Detail == "oneof" {
+				childScope = scope
+			}
+			visit(documentSymbol.Children, childScope, name)
+		}
+	}
+	visit(documentSymbols, pkg, "")
+	return symbols
+}
+
+// fuzzyMatch returns whether the characters of query appear in candidate in order,
+// ignoring case, and if so, a score for how good the match is.
+//
+// Matches of consecutive characters, matches at the start of a word, and matches in
+// the last component of candidate score higher. An empty query matches everything.
+func fuzzyMatch(query string, candidate string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	queryRunes := []rune(strings.ToLower(query))
+	lastDot := strings.LastIndexByte(candidate, '.')
+
+	var score, queryIndex int
+	var previous rune
+	previousMatched := false
+	for i, r := range candidate {
+		if queryIndex == len(queryRunes) {
+			break
+		}
+		matched := unicode.ToLower(r) == queryRunes[queryIndex]
+		if matched {
+			score++
+			if previousMatched {
+				score += 5
+			}
+			if i == 0 || previous == '.' || previous == '_' || (unicode.IsUpper(r) && unicode.IsLower(previous)) {
+				score += 3
+			}
+			if i > lastDot {
+				score += 2
+			}
+			queryIndex++
+		}
+		previous = r
+		previousMatched = matched
+	}
+	if queryIndex < len(queryRunes) {
+		return 0, false
+	}
+	if strings.EqualFold(candidate[lastDot+1:], query) {
+		score += 20
+	}
+	return score, true
+}
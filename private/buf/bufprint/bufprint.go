@@ -27,6 +27,7 @@ import (
 	modulev1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/module/v1"
 	ownerv1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/owner/v1"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/bufbuild/buf/private/pkg/protostat"
@@ -215,12 +216,47 @@ func NewLabelEntity(label *modulev1.Label, moduleFullName bufmodule.ModuleFullNa
 }
 
 // NewCommitEntity returns a new commit entity to print.
-func NewCommitEntity(commit *modulev1.Commit, moduleFullName bufmodule.ModuleFullName) Entity {
-	return outputCommit{
+func NewCommitEntity(
+	commit *modulev1.Commit,
+	moduleFullName bufmodule.ModuleFullName,
+	options ...CommitEntityOption,
+) Entity {
+	outputCommit := outputCommit{
 		Commit:         commit.Id,
 		CreateTime:     commit.CreateTime.AsTime(),
 		moduleFullName: moduleFullName,
 	}
+	// The digest is informational, we do not fail printing if the registry returned
+	// a digest type we do not know about.
+	if commit.Digest != nil {
+		if digest, err := bufmoduleapi.V1ProtoToDigest(commit.Digest); err == nil {
+			outputCommit.Digest = digest.String()
+		}
+	}
+	for _, option := range options {
+		option(&outputCommit)
+	}
+	return outputCommit
+}
+
+// CommitEntityOption is an option for a new commit entity.
+type CommitEntityOption func(*outputCommit)
+
+// CommitEntityWithAuthor returns a new CommitEntityOption that sets the author of the commit.
+//
+// This is typically the name of the user that created the commit.
+func CommitEntityWithAuthor(author string) CommitEntityOption {
+	return func(outputCommit *outputCommit) {
+		outputCommit.Author = author
+	}
+}
+
+// CommitEntityWithLabels returns a new CommitEntityOption that sets the names of the
+// labels that point to the commit.
+func CommitEntityWithLabels(labels ...string) CommitEntityOption {
+	return func(outputCommit *outputCommit) {
+		outputCommit.Labels = labels
+	}
 }
 
 // NewModuleEntity returns a new module entity to print.
@@ -362,6 +398,11 @@ func getFieldNamesAndValuesForInfo(entity any) ([]string, []string, error) {
 				continue
 			}
 			fieldValues = append(fieldValues, t)
+		case []string:
+			if omitEmpty && len(t) == 0 {
+				continue
+			}
+			fieldValues = append(fieldValues, strings.Join(t, ", "))
 		case *time.Time:
 			if omitEmpty && t == nil {
 				continue
@@ -422,6 +463,9 @@ func (l outputLabel) fullName() string {
 type outputCommit struct {
 	Commit     string    `json:"commit,omitempty" bufprint:"Commit"`
 	CreateTime time.Time `json:"create_time,omitempty" bufprint:"Create Time"`
+	Digest     string    `json:"digest,omitempty" bufprint:"Digest,omitempty"`
+	Author     string    `json:"author,omitempty" bufprint:"Author,omitempty"`
+	Labels     []string  `json:"labels,omitempty" bufprint:"Labels,omitempty"`
 
 	moduleFullName bufmodule.ModuleFullName
 }
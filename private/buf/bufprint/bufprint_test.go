@@ -0,0 +1,104 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprint
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	modulev1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/module/v1"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPrintCommitEntity(t *testing.T) {
+	t.Parallel()
+	moduleFullName, err := bufmodule.NewModuleFullName("buf.build", "acme", "weather")
+	require.NoError(t, err)
+	digestValue := bytes.Repeat([]byte{0xab}, 64)
+	digestString := "b5:" + hex.EncodeToString(digestValue)
+	commit := &modulev1.Commit{
+		Id:         "0123456789abcdef0123456789abcdef",
+		CreateTime: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		Digest: &modulev1.Digest{
+			Type:  modulev1.DigestType_DIGEST_TYPE_B5,
+			Value: digestValue,
+		},
+	}
+
+	testPrintEntity(
+		t,
+		NewCommitEntity(commit, moduleFullName),
+		strings.Join(
+			[]string{
+				"Commit                            Create Time           Digest",
+				"0123456789abcdef0123456789abcdef  2024-01-02T03:04:05Z  " + digestString,
+				"",
+			},
+			"\n",
+		),
+		`{"commit":"0123456789abcdef0123456789abcdef","create_time":"2024-01-02T03:04:05Z","digest":"`+digestString+`"}`+"\n",
+	)
+	testPrintEntity(
+		t,
+		NewCommitEntity(
+			commit,
+			moduleFullName,
+			CommitEntityWithAuthor("bufbot"),
+			CommitEntityWithLabels("main", "v1"),
+		),
+		strings.Join(
+			[]string{
+				"Commit                            Create Time           Digest" + strings.Repeat(" ", len(digestString)-len("Digest")) + "  Author  Labels",
+				"0123456789abcdef0123456789abcdef  2024-01-02T03:04:05Z  " + digestString + "  bufbot  main, v1",
+				"",
+			},
+			"\n",
+		),
+		`{"commit":"0123456789abcdef0123456789abcdef","create_time":"2024-01-02T03:04:05Z","digest":"`+digestString+`","author":"bufbot","labels":["main","v1"]}`+"\n",
+	)
+	// Unknown digest types are not printed rather than failing.
+	commit.Digest = &modulev1.Digest{
+		Type:  modulev1.DigestType_DIGEST_TYPE_UNSPECIFIED,
+		Value: digestValue,
+	}
+	testPrintEntity(
+		t,
+		NewCommitEntity(commit, moduleFullName, CommitEntityWithLabels()),
+		strings.Join(
+			[]string{
+				"Commit                            Create Time",
+				"0123456789abcdef0123456789abcdef  2024-01-02T03:04:05Z",
+				"",
+			},
+			"\n",
+		),
+		`{"commit":"0123456789abcdef0123456789abcdef","create_time":"2024-01-02T03:04:05Z"}`+"\n",
+	)
+}
+
+func testPrintEntity(t *testing.T, entity Entity, expectedText string, expectedJSON string) {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintEntity(buffer, FormatText, entity))
+	assert.Equal(t, expectedText, buffer.String())
+	buffer.Reset()
+	require.NoError(t, PrintEntity(buffer, FormatJSON, entity))
+	assert.Equal(t, expectedJSON, buffer.String())
+}
@@ -0,0 +1,93 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprint
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/syserror"
+)
+
+// LoginStatus is the login status for a remote.
+type LoginStatus struct {
+	// Remote is the name of the remote.
+	Remote string `json:"remote"`
+	// Username is the user that the credentials authenticate as. Empty if the
+	// credentials could not be checked.
+	Username string `json:"username,omitempty"`
+	// Source is where the credentials are configured.
+	Source string `json:"source"`
+	// Expiry is when the credentials expire. Nil if unknown or if the credentials
+	// do not expire.
+	Expiry *time.Time `json:"expiry,omitempty"`
+	// Error is why the credentials could not be checked. Empty if the credentials are valid.
+	Error string `json:"error,omitempty"`
+}
+
+// PrintLoginStatuses prints the login statuses.
+//
+// If format is FormatText, this prints the statuses in a table.
+// If format is FormatJSON, this prints each status as a JSON object on its own line.
+func PrintLoginStatuses(writer io.Writer, format Format, loginStatuses ...*LoginStatus) error {
+	switch format {
+	case FormatText:
+		if len(loginStatuses) == 0 {
+			return nil
+		}
+		return WithTabWriter(
+			writer,
+			[]string{
+				"Remote",
+				"User",
+				"Source",
+				"Expire time",
+				"Status",
+			},
+			func(tabWriter TabWriter) error {
+				for _, loginStatus := range loginStatuses {
+					var expiry string
+					if loginStatus.Expiry != nil {
+						expiry = loginStatus.Expiry.Format(time.RFC3339)
+					}
+					status := "ok"
+					if loginStatus.Error != "" {
+						status = loginStatus.Error
+					}
+					if err := tabWriter.Write(
+						loginStatus.Remote,
+						loginStatus.Username,
+						loginStatus.Source,
+						expiry,
+						status,
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		)
+	case FormatJSON:
+		for _, loginStatus := range loginStatuses {
+			if err := json.NewEncoder(writer).Encode(loginStatus); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return syserror.Newf("unknown format: %s", format)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strconv"
 
 	"github.com/bufbuild/buf/private/pkg/protostat"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 )
 
 type statsPrinter struct {
@@ -37,7 +38,7 @@ func newStatsPrinter(writer io.Writer) *statsPrinter {
 func (p *statsPrinter) PrintStats(ctx context.Context, format Format, stats *protostat.Stats) error {
 	switch format {
 	case FormatText:
-		return WithTabWriter(
+		if err := WithTabWriter(
 			p.writer,
 			[]string{
 				"Files",
@@ -49,7 +50,7 @@ func (p *statsPrinter) PrintStats(ctx context.Context, format Format, stats *pro
 				"Extensions",
 				"Services",
 				"Methods",
-				"Files With Errors",
+				"Deprecated",
 			},
 			func(tabWriter TabWriter) error {
 				return tabWriter.Write(
@@ -62,13 +63,42 @@ func (p *statsPrinter) PrintStats(ctx context.Context, format Format, stats *pro
 					strconv.Itoa(stats.NumExtensions),
 					strconv.Itoa(stats.NumServices),
 					strconv.Itoa(stats.NumMethods),
-					strconv.Itoa(stats.NumFilesWithSyntaxErrors),
+					strconv.Itoa(stats.NumDeprecated),
 				)
 			},
-		)
+		); err != nil {
+			return err
+		}
+		if err := p.printCounts([]string{"Field Type", "Fields"}, stats.NumFieldsByType); err != nil {
+			return err
+		}
+		return p.printCounts([]string{"Extension", "Uses"}, stats.NumExtensionUses)
 	case FormatJSON:
 		return json.NewEncoder(p.writer).Encode(stats)
 	default:
 		return fmt.Errorf("unknown format: %v", format)
 	}
 }
+
+// printCounts prints the counts sorted by key, after a blank line separating them
+// from the previous table. Nothing is printed if there are no counts.
+func (p *statsPrinter) printCounts(header []string, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(p.writer); err != nil {
+		return err
+	}
+	return WithTabWriter(
+		p.writer,
+		header,
+		func(tabWriter TabWriter) error {
+			for _, key := range slicesext.MapKeysToSortedSlice(counts) {
+				if err := tabWriter.Write(key, strconv.Itoa(counts[key])); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/protoplugin"
+	"go.uber.org/multierr"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -34,6 +35,8 @@ type binaryHandler struct {
 	runner     command.Runner
 	pluginPath string
 	pluginArgs []string
+	// outputLimit is the maximum size of stdout, or 0 for no limit.
+	outputLimit int64
 }
 
 func newBinaryHandler(
@@ -41,12 +44,14 @@ func newBinaryHandler(
 	runner command.Runner,
 	pluginPath string,
 	pluginArgs []string,
+	outputLimit int64,
 ) *binaryHandler {
 	return &binaryHandler{
-		logger:     logger,
-		runner:     runner,
-		pluginPath: pluginPath,
-		pluginArgs: pluginArgs,
+		logger:      logger,
+		runner:      runner,
+		pluginPath:  pluginPath,
+		pluginArgs:  pluginArgs,
+		outputLimit: outputLimit,
 	}
 }
 
@@ -63,11 +68,20 @@ func (h *binaryHandler) Handle(
 		return err
 	}
 	responseBuffer := bytes.NewBuffer(nil)
+	var stdout io.Writer = responseBuffer
+	var limitedStdout ioext.LimitedWriter
+	if h.outputLimit > 0 {
+		limitedStdout = ioext.NewLimitedWriter(responseBuffer, h.outputLimit)
+		stdout = limitedStdout
+	}
 	stderrWriteCloser := newStderrWriteCloser(pluginEnv.Stderr, h.pluginPath)
+	defer func() {
+		retErr = multierr.Append(retErr, stderrWriteCloser.Close())
+	}()
 	runOptions := []command.RunOption{
 		command.RunWithEnviron(pluginEnv.Environ),
 		command.RunWithStdin(bytes.NewReader(requestData)),
-		command.RunWithStdout(responseBuffer),
+		command.RunWithStdout(stdout),
 		command.RunWithStderr(stderrWriteCloser),
 	}
 	if len(h.pluginArgs) > 0 {
@@ -80,6 +94,9 @@ func (h *binaryHandler) Handle(
 	); err != nil {
 		return err
 	}
+	if limitedStdout != nil && limitedStdout.Exceeded() {
+		return newOutputLimitError(h.pluginPath, limitedStdout.Size(), h.outputLimit)
+	}
 	response := &pluginpb.CodeGeneratorResponse{}
 	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(responseBuffer.Bytes(), response); err != nil {
 		return err
@@ -92,15 +109,27 @@ func (h *binaryHandler) Handle(
 	return nil
 }
 
+// newStderrWriteCloser returns a new io.WriteCloser for the stderr of a single process.
+//
+// If the delegate is a *pluginStderr, lines are attributed to the plugin. The returned
+// io.WriteCloser must be closed after the process exits.
 func newStderrWriteCloser(delegate io.Writer, pluginPath string) io.WriteCloser {
+	stderrWriteCloser := ioext.NopWriteCloser(delegate)
+	if pluginStderr, ok := delegate.(*pluginStderr); ok {
+		stderrWriteCloser = newProcessStderrWriteCloser(pluginStderr)
+	}
 	switch filepath.Base(pluginPath) {
 	case "protoc-gen-swift":
 		// https://github.com/bufbuild/buf/issues/1736
 		// Swallowing specific stderr message for protoc-gen-swift as protoc-gen-swift, see issue.
 		// This is all disgusting code but it's simple and it works.
 		// We did not document if pluginPath is normalized or not, so
-		return newProtocGenSwiftStderrWriteCloser(delegate)
+		protocGenSwiftStderrWriteCloser := newProtocGenSwiftStderrWriteCloser(stderrWriteCloser)
+		return ioext.CompositeWriteCloser(
+			protocGenSwiftStderrWriteCloser,
+			ioext.ChainCloser(protocGenSwiftStderrWriteCloser, stderrWriteCloser),
+		)
 	default:
-		return ioext.NopWriteCloser(delegate)
+		return stderrWriteCloser
 	}
 }
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/pkg/app"
@@ -48,6 +49,14 @@ const (
 	defaultSuffixVersion = ""
 )
 
+// PersistentEnvKey is the environment variable set to "1" for plugins run with
+// GenerateWithPersistent.
+//
+// A plugin run with this set reads a sequence of CodeGeneratorRequests from stdin, each
+// prefixed by its varint-encoded length, and writes a CodeGeneratorResponse for each
+// request to stdout in the same format. The plugin should exit once stdin is closed.
+const PersistentEnvKey = "BUF_PLUGIN_PERSISTENT"
+
 var (
 	// DefaultVersion represents the default version to use as compiler version for codegen requests.
 	DefaultVersion = newVersion(
@@ -102,6 +111,39 @@ func GenerateWithProtocPath(protocPath ...string) GenerateOption {
 	}
 }
 
+// GenerateWithPersistent returns a new GenerateOption that keeps plugin processes alive
+// across CodeGeneratorRequests, instead of running a new process for each request.
+//
+// The plugin must be a binary plugin that supports the protocol described on PersistentEnvKey.
+func GenerateWithPersistent() GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.persistent = true
+	}
+}
+
+// GenerateWithPluginLogDirPath returns a new GenerateOption that appends the full stderr
+// of the plugin to a file named after the plugin in the given directory.
+//
+// The stderr of the plugin is always written to the stderr of the container with each
+// line prefixed by the plugin name, and the end of the stderr is attached to any error
+// returned for the plugin.
+func GenerateWithPluginLogDirPath(pluginLogDirPath string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.pluginLogDirPath = pluginLogDirPath
+	}
+}
+
+// GenerateWithOutputLimit returns a new GenerateOption that fails generation if the
+// plugin writes more than outputLimit bytes to stdout for a single CodeGeneratorRequest.
+//
+// Output past the limit is discarded instead of being buffered in memory. The default
+// is no limit.
+func GenerateWithOutputLimit(outputLimit int64) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.outputLimit = outputLimit
+	}
+}
+
 // NewHandler returns a new Handler based on the plugin name and optional path.
 //
 // protocPath and pluginPath are optional.
@@ -126,11 +168,17 @@ func NewHandler(
 	// Initialize binary plugin handler when path is specified with optional args. Return
 	// on error as something is wrong with the supplied pluginPath option.
 	if len(handlerOptions.pluginPath) > 0 {
-		return NewBinaryHandler(logger, runner, handlerOptions.pluginPath[0], handlerOptions.pluginPath[1:])
+		return newBinaryHandlerForPath(
+			logger,
+			runner,
+			handlerOptions.pluginPath[0],
+			handlerOptions.pluginPath[1:],
+			handlerOptions.outputLimit,
+		)
 	}
 
 	// Initialize binary plugin handler based on plugin name.
-	if handler, err := NewBinaryHandler(logger, runner, "protoc-gen-"+pluginName, nil); err == nil {
+	if handler, err := newBinaryHandlerForPath(logger, runner, "protoc-gen-"+pluginName, nil, handlerOptions.outputLimit); err == nil {
 		return handler, nil
 	}
 
@@ -178,25 +226,52 @@ func HandlerWithPluginPath(pluginPath ...string) HandlerOption {
 	}
 }
 
+// HandlerWithOutputLimit returns a new HandlerOption that fails a binary plugin if it
+// writes more than outputLimit bytes to stdout.
+//
+// The default is no limit.
+func HandlerWithOutputLimit(outputLimit int64) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.outputLimit = outputLimit
+	}
+}
+
 // NewBinaryHandler returns a new Handler that invokes the specific plugin
 // specified by pluginPath.
 func NewBinaryHandler(logger *slog.Logger, runner command.Runner, pluginPath string, pluginArgs []string) (protoplugin.Handler, error) {
-	pluginPath, err := unsafeLookPath(pluginPath)
-	if err != nil {
-		return nil, err
-	}
-	return newBinaryHandler(logger, runner, pluginPath, pluginArgs), nil
+	return newBinaryHandlerForPath(logger, runner, pluginPath, pluginArgs, 0)
 }
 
 type handlerOptions struct {
-	pluginPath []string
-	protocPath []string
+	pluginPath  []string
+	protocPath  []string
+	outputLimit int64
 }
 
 func newHandlerOptions() *handlerOptions {
 	return &handlerOptions{}
 }
 
+func newBinaryHandlerForPath(
+	logger *slog.Logger,
+	runner command.Runner,
+	pluginPath string,
+	pluginArgs []string,
+	outputLimit int64,
+) (protoplugin.Handler, error) {
+	pluginPath, err := unsafeLookPath(pluginPath)
+	if err != nil {
+		return nil, err
+	}
+	return newBinaryHandler(logger, runner, pluginPath, pluginArgs, outputLimit), nil
+}
+
+// newOutputLimitError returns a new error for a plugin that wrote more than the
+// output limit to stdout.
+func newOutputLimitError(pluginPath string, size int64, outputLimit int64) error {
+	return fmt.Errorf("plugin %s produced %d bytes, limit %d", filepath.Base(pluginPath), size, outputLimit)
+}
+
 // unsafeLookPath is a wrapper around exec.LookPath that restores the original
 // pre-Go 1.19 behavior of resolving queries that would use relative PATH
 // entries. We consider it acceptable for the use case of locating plugins.
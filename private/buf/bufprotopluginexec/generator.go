@@ -22,6 +22,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"go.uber.org/multierr"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -54,9 +55,42 @@ func (g *generator) Generate(
 	for _, option := range options {
 		option(generateOptions)
 	}
+	pluginStderr, err := newPluginStderr(pluginName, container.Stderr(), generateOptions.pluginLogDirPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, pluginStderr.Close())
+	}()
+	container = newEnvStderrContainer(container, pluginStderr)
+	response, err := g.generate(ctx, container, pluginName, requests, generateOptions)
+	if err != nil {
+		return nil, pluginStderr.WrapError(err)
+	}
+	return response, nil
+}
+
+func (g *generator) generate(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	pluginName string,
+	requests []*pluginpb.CodeGeneratorRequest,
+	generateOptions *generateOptions,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	if generateOptions.persistent {
+		return g.generatePersistent(
+			ctx,
+			container,
+			pluginName,
+			generateOptions.pluginPath,
+			generateOptions.outputLimit,
+			requests,
+		)
+	}
 	handlerOptions := []HandlerOption{
 		HandlerWithPluginPath(generateOptions.pluginPath...),
 		HandlerWithProtocPath(generateOptions.protocPath...),
+		HandlerWithOutputLimit(generateOptions.outputLimit),
 	}
 	handler, err := NewHandler(
 		g.logger,
@@ -79,8 +113,11 @@ func (g *generator) Generate(
 }
 
 type generateOptions struct {
-	pluginPath []string
-	protocPath []string
+	pluginPath       []string
+	protocPath       []string
+	persistent       bool
+	pluginLogDirPath string
+	outputLimit      int64
 }
 
 func newGenerateOptions() *generateOptions {
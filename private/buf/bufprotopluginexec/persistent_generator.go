@@ -0,0 +1,229 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprotopluginexec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/protoplugin"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// generatePersistent generates with a set of persistent plugin processes.
+//
+// Up to thread.Parallelism() processes are started, each of which is fed requests
+// sequentially until there are no requests left. A process is only started once
+// there is a request for it to handle, and is only stopped once all requests have
+// been handled. Since a process never waits on another process to start, this
+// does not deadlock with the concurrency limit of the command.Runner.
+func (g *generator) generatePersistent(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	pluginName string,
+	pluginPath []string,
+	outputLimit int64,
+	requests []*pluginpb.CodeGeneratorRequest,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	if len(pluginPath) == 0 {
+		pluginPath = []string{"protoc-gen-" + pluginName}
+	}
+	binaryPath, err := unsafeLookPath(pluginPath[0])
+	if err != nil {
+		return nil, err
+	}
+	defer slogext.DebugProfile(g.logger, slog.String("plugin", filepath.Base(binaryPath)), slog.Bool("persistent", true))()
+
+	responseWriter := protoplugin.NewResponseWriter(
+		protoplugin.ResponseWriterWithLenientValidation(
+			func(err error) {
+				_, _ = fmt.Fprintln(container.Stderr(), err.Error())
+			},
+		),
+	)
+	requestC := make(chan *pluginpb.CodeGeneratorRequest, len(requests))
+	for _, request := range requests {
+		requestC <- request
+	}
+	close(requestC)
+	jobs := make([]func(context.Context) error, min(len(requests), thread.Parallelism()))
+	for i := range jobs {
+		jobs[i] = func(ctx context.Context) (retErr error) {
+			var process *persistentProcess
+			defer func() {
+				if process != nil {
+					retErr = multierr.Append(retErr, process.Close(ctx))
+				}
+			}()
+			for request := range requestC {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if _, err := protoplugin.NewRequest(request); err != nil {
+					return err
+				}
+				if process == nil {
+					process, err = startPersistentProcess(
+						g.runner,
+						binaryPath,
+						pluginPath[1:],
+						app.Environ(container),
+						container.Stderr(),
+						outputLimit,
+					)
+					if err != nil {
+						return handlePotentialTooManyFilesError(err)
+					}
+				}
+				response, err := process.Generate(request)
+				if err != nil {
+					return err
+				}
+				responseWriter.AddCodeGeneratorResponseFiles(response.GetFile()...)
+				responseWriter.AddError(response.GetError())
+				responseWriter.SetSupportedFeatures(response.GetSupportedFeatures())
+				responseWriter.SetMinimumEdition(response.GetMinimumEdition())
+				responseWriter.SetMaximumEdition(response.GetMaximumEdition())
+			}
+			return nil
+		}
+	}
+	if err := thread.Parallelize(ctx, jobs, thread.ParallelizeWithCancelOnFailure()); err != nil {
+		return nil, err
+	}
+	response, err := responseWriter.ToCodeGeneratorResponse()
+	if err != nil {
+		return nil, err
+	}
+	if errString := response.GetError(); errString != "" {
+		return nil, errors.New(errString)
+	}
+	return response, nil
+}
+
+// persistentProcess is a plugin process that handles multiple CodeGeneratorRequests.
+//
+// Requests are written to the stdin of the process, and responses are read from the
+// stdout of the process, each prefixed by its varint-encoded length.
+type persistentProcess struct {
+	process           command.Process
+	stdinWriter       *os.File
+	stdoutFile        *os.File
+	stdout            *bufio.Reader
+	stderrWriteCloser io.WriteCloser
+	pluginPath        string
+	// outputLimit is the maximum size of a single response, or 0 for no limit.
+	outputLimit int64
+}
+
+// startPersistentProcess starts a new persistentProcess.
+//
+// We use *os.Files for stdin and stdout, as opposed to io.Pipes, so that the exec
+// package passes the file descriptors to the process directly. With io.Pipes, the exec
+// package copies in goroutines, and a process that exits early would leave us blocked
+// on a read or write that is never completed.
+func startPersistentProcess(
+	runner command.Runner,
+	pluginPath string,
+	pluginArgs []string,
+	environ []string,
+	stderr io.Writer,
+	outputLimit int64,
+) (_ *persistentProcess, retErr error) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, multierr.Append(err, multierr.Combine(stdinReader.Close(), stdinWriter.Close()))
+	}
+	// The child has its own copies of these after Start, so we always close ours.
+	defer func() {
+		retErr = multierr.Combine(retErr, stdinReader.Close(), stdoutWriter.Close())
+	}()
+	stderrWriteCloser := newStderrWriteCloser(stderr, pluginPath)
+	startOptions := []command.StartOption{
+		command.StartWithEnviron(append(environ, PersistentEnvKey+"=1")),
+		command.StartWithStdin(stdinReader),
+		command.StartWithStdout(stdoutWriter),
+		command.StartWithStderr(stderrWriteCloser),
+	}
+	if len(pluginArgs) > 0 {
+		startOptions = append(startOptions, command.StartWithArgs(pluginArgs...))
+	}
+	process, err := runner.Start(pluginPath, startOptions...)
+	if err != nil {
+		return nil, multierr.Append(err, multierr.Combine(stdinWriter.Close(), stdoutReader.Close()))
+	}
+	return &persistentProcess{
+		process:           process,
+		stdinWriter:       stdinWriter,
+		stdoutFile:        stdoutReader,
+		stdout:            bufio.NewReader(stdoutReader),
+		stderrWriteCloser: stderrWriteCloser,
+		pluginPath:        pluginPath,
+		outputLimit:       outputLimit,
+	}, nil
+}
+
+// Generate writes the request to the process and reads back its response.
+func (p *persistentProcess) Generate(request *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	if _, err := protodelim.MarshalTo(p.stdinWriter, request); err != nil {
+		return nil, fmt.Errorf("could not write request to persistent plugin: %w", err)
+	}
+	response := &pluginpb.CodeGeneratorResponse{}
+	unmarshalOptions := protodelim.UnmarshalOptions{}
+	if p.outputLimit > 0 {
+		unmarshalOptions.MaxSize = p.outputLimit
+	}
+	if err := unmarshalOptions.UnmarshalFrom(p.stdout, response); err != nil {
+		sizeTooLargeError := &protodelim.SizeTooLargeError{}
+		if errors.As(err, &sizeTooLargeError) {
+			return nil, newOutputLimitError(p.pluginPath, int64(sizeTooLargeError.Size), p.outputLimit)
+		}
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("could not read response from persistent plugin: %w", err)
+	}
+	return response, nil
+}
+
+// Close closes the stdin and stdout of the process, and waits for the process to exit.
+//
+// We close stdout before waiting, so that a process that is blocked writing a response
+// we did not read, such as one over the output limit, does not block us from exiting.
+// The process is killed if the context is done first.
+func (p *persistentProcess) Close(ctx context.Context) error {
+	return multierr.Combine(
+		p.stdinWriter.Close(),
+		p.stdoutFile.Close(),
+		p.process.Wait(ctx),
+		p.stderrWriteCloser.Close(),
+	)
+}
@@ -0,0 +1,223 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprotopluginexec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+)
+
+// stderrTailSize is the number of bytes at the end of the stderr of a plugin
+// that are attached to an error returned for the plugin.
+const stderrTailSize = 8 * 1024
+
+// pluginStderr captures the stderr of all processes run for a single plugin.
+//
+// Each line is written to the delegate prefixed with the plugin name, so that the
+// output of plugins run in parallel can be attributed. If a log file is set, the
+// unprefixed output is also written to the log file. The last stderrTailSize bytes
+// are kept so that they can be attached to an error.
+//
+// Use newStderrWriteCloser to get a writer for a single process, so that partial
+// lines from different processes are not interleaved.
+type pluginStderr struct {
+	prefix   []byte
+	delegate io.Writer
+	logFile  *os.File
+
+	lock sync.Mutex
+	tail []byte
+	// truncated is true if the tail no longer contains the beginning of the stderr.
+	truncated bool
+}
+
+// newPluginStderr returns a new pluginStderr.
+//
+// If logDirPath is not empty, the output is appended to a file in this directory
+// named after the plugin.
+func newPluginStderr(pluginName string, delegate io.Writer, logDirPath string) (*pluginStderr, error) {
+	pluginStderr := &pluginStderr{
+		prefix:   []byte(pluginName + ": "),
+		delegate: delegate,
+	}
+	if logDirPath != "" {
+		if err := os.MkdirAll(logDirPath, 0755); err != nil {
+			return nil, err
+		}
+		logFile, err := os.OpenFile(
+			filepath.Join(logDirPath, getLogFileName(pluginName)),
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+			0644,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pluginStderr.logFile = logFile
+	}
+	return pluginStderr, nil
+}
+
+// Write writes the data as one or more complete lines.
+func (s *pluginStderr) Write(data []byte) (int, error) {
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := s.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WrapError attaches the tail of the stderr to the error, if any stderr was written.
+func (s *pluginStderr) WrapError(err error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil || len(s.tail) == 0 {
+		return err
+	}
+	return &stderrError{
+		err:       err,
+		tail:      string(s.tail),
+		truncated: s.truncated,
+	}
+}
+
+// Close closes the log file, if any.
+func (s *pluginStderr) Close() error {
+	if s.logFile == nil {
+		return nil
+	}
+	return s.logFile.Close()
+}
+
+func (s *pluginStderr) writeLine(line []byte) error {
+	if line[len(line)-1] != '\n' {
+		line = append(line[:len(line):len(line)], '\n')
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tail = append(s.tail, line...)
+	if len(s.tail) > stderrTailSize {
+		s.tail = s.tail[len(s.tail)-stderrTailSize:]
+		// Start the tail on a line boundary if possible.
+		if index := bytes.IndexByte(s.tail, '\n'); index >= 0 && index < len(s.tail)-1 {
+			s.tail = s.tail[index+1:]
+		}
+		s.truncated = true
+	}
+	if s.logFile != nil {
+		if _, err := s.logFile.Write(line); err != nil {
+			return err
+		}
+	}
+	_, err := s.delegate.Write(append(append([]byte{}, s.prefix...), line...))
+	return err
+}
+
+// processStderrWriteCloser buffers partial lines written by a single process, and
+// writes complete lines to the pluginStderr.
+type processStderrWriteCloser struct {
+	pluginStderr *pluginStderr
+	buffer       []byte
+}
+
+func newProcessStderrWriteCloser(pluginStderr *pluginStderr) *processStderrWriteCloser {
+	return &processStderrWriteCloser{
+		pluginStderr: pluginStderr,
+	}
+}
+
+func (p *processStderrWriteCloser) Write(data []byte) (int, error) {
+	p.buffer = append(p.buffer, data...)
+	for {
+		index := bytes.IndexByte(p.buffer, '\n')
+		if index < 0 {
+			return len(data), nil
+		}
+		if err := p.pluginStderr.writeLine(p.buffer[:index+1]); err != nil {
+			return 0, err
+		}
+		p.buffer = p.buffer[index+1:]
+	}
+}
+
+func (p *processStderrWriteCloser) Close() error {
+	if len(p.buffer) == 0 {
+		return nil
+	}
+	buffer := p.buffer
+	p.buffer = nil
+	return p.pluginStderr.writeLine(buffer)
+}
+
+type stderrError struct {
+	err       error
+	tail      string
+	truncated bool
+}
+
+func (e *stderrError) Error() string {
+	var builder strings.Builder
+	_, _ = builder.WriteString(e.err.Error())
+	if e.truncated {
+		_, _ = fmt.Fprintf(&builder, "\n\nlast %d bytes of plugin stderr:\n", len(e.tail))
+	} else {
+		_, _ = builder.WriteString("\n\nplugin stderr:\n")
+	}
+	_, _ = builder.WriteString(strings.TrimSuffix(e.tail, "\n"))
+	return builder.String()
+}
+
+func (e *stderrError) Unwrap() error {
+	return e.err
+}
+
+type envStderrContainer struct {
+	app.EnvContainer
+	app.StderrContainer
+}
+
+func newEnvStderrContainer(envContainer app.EnvContainer, stderr io.Writer) *envStderrContainer {
+	return &envStderrContainer{
+		EnvContainer:    envContainer,
+		StderrContainer: app.NewStderrContainer(stderr),
+	}
+}
+
+// getLogFileName returns the name of the log file for the plugin name.
+//
+// Plugin names may be paths or contain spaces for local plugins with arguments.
+func getLogFileName(pluginName string) string {
+	return strings.Map(
+		func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			default:
+				return '_'
+			}
+		},
+		pluginName,
+	) + ".log"
+}
@@ -0,0 +1,91 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprotopluginexec
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginStderr(t *testing.T) {
+	t.Parallel()
+	stderr := bytes.NewBuffer(nil)
+	logDirPath := t.TempDir()
+	pluginStderr, err := newPluginStderr("protoc-gen-foo", stderr, logDirPath)
+	require.NoError(t, err)
+	first := newStderrWriteCloser(pluginStderr, "protoc-gen-foo")
+	second := newStderrWriteCloser(pluginStderr, "protoc-gen-foo")
+	_, err = first.Write([]byte("hello "))
+	require.NoError(t, err)
+	_, err = second.Write([]byte("other\n"))
+	require.NoError(t, err)
+	_, err = first.Write([]byte("world\nunterminated"))
+	require.NoError(t, err)
+	require.NoError(t, first.Close())
+	require.NoError(t, second.Close())
+	require.NoError(t, pluginStderr.Close())
+	assert.Equal(
+		t,
+		"protoc-gen-foo: other\nprotoc-gen-foo: hello world\nprotoc-gen-foo: unterminated\n",
+		stderr.String(),
+	)
+	data, err := os.ReadFile(filepath.Join(logDirPath, "protoc-gen-foo.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "other\nhello world\nunterminated\n", string(data))
+
+	err = pluginStderr.WrapError(errors.New("exit status 1"))
+	assert.Equal(t, "exit status 1\n\nplugin stderr:\nother\nhello world\nunterminated", err.Error())
+	assert.Equal(t, "exit status 1", errors.Unwrap(err).Error())
+}
+
+func TestPluginStderrTail(t *testing.T) {
+	t.Parallel()
+	pluginStderr, err := newPluginStderr("protoc-gen-foo", bytes.NewBuffer(nil), "")
+	require.NoError(t, err)
+	line := strings.Repeat("a", 99) + "\n"
+	for i := 0; i < 2*stderrTailSize/len(line); i++ {
+		_, err := pluginStderr.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	_, err = pluginStderr.Write([]byte("last\n"))
+	require.NoError(t, err)
+	var stderrError *stderrError
+	require.ErrorAs(t, pluginStderr.WrapError(errors.New("exit status 1")), &stderrError)
+	assert.True(t, stderrError.truncated)
+	assert.LessOrEqual(t, len(stderrError.tail), stderrTailSize)
+	assert.True(t, strings.HasPrefix(stderrError.tail, line))
+	assert.True(t, strings.HasSuffix(stderrError.tail, "\nlast\n"))
+}
+
+func TestPluginStderrNoOutput(t *testing.T) {
+	t.Parallel()
+	pluginStderr, err := newPluginStderr("protoc-gen-foo", bytes.NewBuffer(nil), "")
+	require.NoError(t, err)
+	err = errors.New("exit status 1")
+	assert.Equal(t, err, pluginStderr.WrapError(err))
+}
+
+func TestGetLogFileName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "protoc-gen-go.log", getLogFileName("protoc-gen-go"))
+	assert.Equal(t, "go_run_._cmd_protoc-gen-foo.log", getLogFileName("go run ./cmd/protoc-gen-foo"))
+}
@@ -149,13 +149,17 @@ func (h *protocProxyHandler) Handle(
 	if descriptorFilePath != "" && descriptorFilePath == app.DevStdinFilePath {
 		stdin = bytes.NewReader(fileDescriptorSetData)
 	}
+	stderrWriteCloser := newStderrWriteCloser(pluginEnv.Stderr, h.protocPath)
+	defer func() {
+		retErr = multierr.Append(retErr, stderrWriteCloser.Close())
+	}()
 	if err := h.runner.Run(
 		ctx,
 		h.protocPath,
 		command.RunWithArgs(args...),
 		command.RunWithEnviron(pluginEnv.Environ),
 		command.RunWithStdin(stdin),
-		command.RunWithStderr(pluginEnv.Stderr),
+		command.RunWithStderr(stderrWriteCloser),
 	); err != nil {
 		// TODO: strip binary path as well?
 		// We don't know if this is a system error or plugin error, so we assume system error
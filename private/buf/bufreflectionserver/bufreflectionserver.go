@@ -0,0 +1,49 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufreflectionserver serves the gRPC server reflection API for an Image.
+package bufreflectionserver
+
+import (
+	"log/slog"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+const (
+	// V1Procedure is the procedure of the grpc.reflection.v1 API.
+	V1Procedure = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	// V1AlphaProcedure is the procedure of the grpc.reflection.v1alpha API.
+	//
+	// The v1alpha messages are identical to the v1 messages on the wire.
+	V1AlphaProcedure = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// NewHandler returns a new http.Handler that serves the gRPC server reflection
+// API, both v1 and v1alpha, for the services and types in the given Image.
+//
+// The handler must be served over HTTP/2 for gRPC clients to use it.
+func NewHandler(logger *slog.Logger, image bufimage.Image) http.Handler {
+	server := newServer(logger, image)
+	mux := http.NewServeMux()
+	for _, procedure := range []string{V1Procedure, V1AlphaProcedure} {
+		mux.Handle(
+			procedure,
+			connect.NewBidiStreamHandler(procedure, server.ServerReflectionInfo),
+		)
+	}
+	return mux
+}
@@ -0,0 +1,200 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufreflectionserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestServerReflectionInfo(t *testing.T) {
+	t.Parallel()
+	for _, procedure := range []string{V1Procedure, V1AlphaProcedure} {
+		procedure := procedure
+		t.Run(procedure, func(t *testing.T) {
+			t.Parallel()
+			testServerReflectionInfo(t, procedure)
+		})
+	}
+}
+
+func testServerReflectionInfo(t *testing.T, procedure string) {
+	httpServer := httptest.NewUnstartedServer(NewHandler(slogtestext.NewLogger(t), newTestImage(t)))
+	httpServer.EnableHTTP2 = true
+	httpServer.StartTLS()
+	t.Cleanup(httpServer.Close)
+	client := connect.NewClient[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse](
+		httpServer.Client(),
+		httpServer.URL+procedure,
+		connect.WithGRPC(),
+	)
+	stream := client.CallBidiStream(context.Background())
+	t.Cleanup(func() {
+		_ = stream.CloseRequest()
+		_ = stream.CloseResponse()
+	})
+	send := func(request *reflectionv1.ServerReflectionRequest) *reflectionv1.ServerReflectionResponse {
+		require.NoError(t, stream.Send(request))
+		response, err := stream.Receive()
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(request, response.GetOriginalRequest()))
+		return response
+	}
+
+	response := send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+	})
+	require.Len(t, response.GetListServicesResponse().GetService(), 1)
+	assert.Equal(t, "foo.v1.FooService", response.GetListServicesResponse().GetService()[0].GetName())
+
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "foo.v1.FooService.Get",
+		},
+	})
+	assert.Equal(t, []string{"foo/v1/foo.proto", "google/protobuf/timestamp.proto"}, fileNames(t, response))
+	// Dependencies are only sent once per stream.
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{
+			FileByFilename: "foo/v1/foo.proto",
+		},
+	})
+	assert.Equal(t, []string{"foo/v1/foo.proto"}, fileNames(t, response))
+
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingExtension{
+			FileContainingExtension: &reflectionv1.ExtensionRequest{
+				ContainingType:  "foo.v1.Foo",
+				ExtensionNumber: 100,
+			},
+		},
+	})
+	assert.Equal(t, []string{"foo/v1/foo.proto"}, fileNames(t, response))
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType{
+			AllExtensionNumbersOfType: "foo.v1.Foo",
+		},
+	})
+	assert.Equal(t, "foo.v1.Foo", response.GetAllExtensionNumbersResponse().GetBaseTypeName())
+	assert.Equal(t, []int32{100}, response.GetAllExtensionNumbersResponse().GetExtensionNumber())
+
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "foo.v1.Bar",
+		},
+	})
+	assert.Equal(t, int32(connect.CodeNotFound), response.GetErrorResponse().GetErrorCode())
+	assert.Equal(t, "symbol not found: foo.v1.Bar", response.GetErrorResponse().GetErrorMessage())
+	response = send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{
+			FileByFilename: "bar.proto",
+		},
+	})
+	assert.Equal(t, int32(connect.CodeNotFound), response.GetErrorResponse().GetErrorCode())
+}
+
+func fileNames(t *testing.T, response *reflectionv1.ServerReflectionResponse) []string {
+	var names []string
+	for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, proto.Unmarshal(data, fileDescriptorProto))
+		names = append(names, fileDescriptorProto.GetName())
+	}
+	return names
+}
+
+func newTestImage(t *testing.T) bufimage.Image {
+	timestampFileDescriptorProto := protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto)
+	fooFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("foo/v1/foo.proto"),
+		Package:    proto.String("foo.v1"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("time"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".google.protobuf.Timestamp"),
+					},
+				},
+				ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{
+					{
+						Start: proto.Int32(100),
+						End:   proto.Int32(200),
+					},
+				},
+			},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("priority"),
+				Number:   proto.Int32(100),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				Extendee: proto.String(".foo.v1.Foo"),
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Get"),
+						InputType:  proto.String(".foo.v1.Foo"),
+						OutputType: proto.String(".foo.v1.Foo"),
+					},
+				},
+			},
+		},
+	}
+	var imageFiles []bufimage.ImageFile
+	for _, fileDescriptorProto := range []*descriptorpb.FileDescriptorProto{
+		timestampFileDescriptorProto,
+		fooFileDescriptorProto,
+	} {
+		imageFile, err := bufimage.NewImageFile(
+			fileDescriptorProto,
+			nil,
+			uuid.UUID{},
+			fileDescriptorProto.GetName(),
+			fileDescriptorProto.GetName(),
+			fileDescriptorProto == timestampFileDescriptorProto,
+			false,
+			nil,
+		)
+		require.NoError(t, err)
+		imageFiles = append(imageFiles, imageFile)
+	}
+	image, err := bufimage.NewImage(imageFiles)
+	require.NoError(t, err)
+	return image
+}
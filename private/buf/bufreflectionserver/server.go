@@ -0,0 +1,256 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufreflectionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type server struct {
+	logger *slog.Logger
+	image  bufimage.Image
+}
+
+func newServer(logger *slog.Logger, image bufimage.Image) *server {
+	return &server{
+		logger: logger,
+		image:  image,
+	}
+}
+
+func (s *server) ServerReflectionInfo(
+	ctx context.Context,
+	stream *connect.BidiStream[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse],
+) error {
+	// Like grpc-go, we only send each dependency once per stream, as the client
+	// is expected to cache the files it has already received.
+	sentFilePaths := make(map[string]struct{})
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		response := &reflectionv1.ServerReflectionResponse{
+			ValidHost:       request.GetHost(),
+			OriginalRequest: request,
+		}
+		if err := s.setMessageResponse(response, request, sentFilePaths); err != nil {
+			s.logger.DebugContext(ctx, "reflection_error", slog.String("error", err.Error()))
+			response.MessageResponse = &reflectionv1.ServerReflectionResponse_ErrorResponse{
+				ErrorResponse: &reflectionv1.ErrorResponse{
+					ErrorCode:    int32(connect.CodeOf(err)),
+					ErrorMessage: errorMessage(err),
+				},
+			}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) setMessageResponse(
+	response *reflectionv1.ServerReflectionResponse,
+	request *reflectionv1.ServerReflectionRequest,
+	sentFilePaths map[string]struct{},
+) error {
+	switch messageRequest := request.GetMessageRequest().(type) {
+	case *reflectionv1.ServerReflectionRequest_FileByFilename:
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(messageRequest.FileByFilename, sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_FileContainingSymbol:
+		descriptor, err := s.image.Resolver().FindDescriptorByName(protoreflect.FullName(messageRequest.FileContainingSymbol))
+		if err != nil {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("symbol not found: %s", messageRequest.FileContainingSymbol))
+		}
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(descriptor.ParentFile().Path(), sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_FileContainingExtension:
+		extensionRequest := messageRequest.FileContainingExtension
+		extensionType, err := s.image.Resolver().FindExtensionByNumber(
+			protoreflect.FullName(extensionRequest.GetContainingType()),
+			protoreflect.FieldNumber(extensionRequest.GetExtensionNumber()),
+		)
+		if err != nil {
+			return connect.NewError(
+				connect.CodeNotFound,
+				fmt.Errorf("extension not found: %s(%d)", extensionRequest.GetContainingType(), extensionRequest.GetExtensionNumber()),
+			)
+		}
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(extensionType.TypeDescriptor().ParentFile().Path(), sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType:
+		extensionNumberResponse, err := s.getExtensionNumberResponse(messageRequest.AllExtensionNumbersOfType)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = extensionNumberResponse
+	case *reflectionv1.ServerReflectionRequest_ListServices:
+		response.MessageResponse = s.getListServicesResponse()
+	default:
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown message request: %T", messageRequest))
+	}
+	return nil
+}
+
+// getFileDescriptorResponse returns the file at the path, followed by all of its
+// transitive dependencies that have not been sent on the stream yet.
+func (s *server) getFileDescriptorResponse(
+	path string,
+	sentFilePaths map[string]struct{},
+) (*reflectionv1.ServerReflectionResponse_FileDescriptorResponse, error) {
+	imageFile := s.image.GetFile(path)
+	if imageFile == nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("file not found: %s", path))
+	}
+	var fileDescriptorProtos [][]byte
+	queue := []bufimage.ImageFile{imageFile}
+	seenFilePaths := map[string]struct{}{
+		path: {},
+	}
+	for len(queue) > 0 {
+		imageFile := queue[0]
+		queue = queue[1:]
+		data, err := proto.Marshal(imageFile.FileDescriptorProto())
+		if err != nil {
+			return nil, err
+		}
+		fileDescriptorProtos = append(fileDescriptorProtos, data)
+		sentFilePaths[imageFile.Path()] = struct{}{}
+		for _, dependency := range imageFile.FileDescriptorProto().GetDependency() {
+			if _, ok := seenFilePaths[dependency]; ok {
+				continue
+			}
+			seenFilePaths[dependency] = struct{}{}
+			if _, ok := sentFilePaths[dependency]; ok {
+				continue
+			}
+			dependencyImageFile := s.image.GetFile(dependency)
+			if dependencyImageFile == nil {
+				// The image was built without imports, there is nothing to send.
+				continue
+			}
+			queue = append(queue, dependencyImageFile)
+		}
+	}
+	return &reflectionv1.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &reflectionv1.FileDescriptorResponse{
+			FileDescriptorProto: fileDescriptorProtos,
+		},
+	}, nil
+}
+
+func (s *server) getExtensionNumberResponse(
+	typeName string,
+) (*reflectionv1.ServerReflectionResponse_AllExtensionNumbersResponse, error) {
+	descriptor, err := s.image.Resolver().FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("type not found: %s", typeName))
+	}
+	if _, ok := descriptor.(protoreflect.MessageDescriptor); !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("not a message type: %s", typeName))
+	}
+	var extensionNumbers []int32
+	for _, imageFile := range s.image.Files() {
+		fileDescriptor, err := s.image.Resolver().FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		rangeExtensions(fileDescriptor, func(extensionDescriptor protoreflect.ExtensionDescriptor) {
+			if extensionDescriptor.ContainingMessage().FullName() == descriptor.FullName() {
+				extensionNumbers = append(extensionNumbers, int32(extensionDescriptor.Number()))
+			}
+		})
+	}
+	return &reflectionv1.ServerReflectionResponse_AllExtensionNumbersResponse{
+		AllExtensionNumbersResponse: &reflectionv1.ExtensionNumberResponse{
+			BaseTypeName:    typeName,
+			ExtensionNumber: extensionNumbers,
+		},
+	}, nil
+}
+
+func (s *server) getListServicesResponse() *reflectionv1.ServerReflectionResponse_ListServicesResponse {
+	var serviceResponses []*reflectionv1.ServiceResponse
+	for _, imageFile := range s.image.Files() {
+		// Services in imports are not part of the input, so we do not serve them.
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptorProto := imageFile.FileDescriptorProto()
+		for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+			name := serviceDescriptorProto.GetName()
+			if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+				name = pkg + "." + name
+			}
+			serviceResponses = append(
+				serviceResponses,
+				&reflectionv1.ServiceResponse{
+					Name: name,
+				},
+			)
+		}
+	}
+	return &reflectionv1.ServerReflectionResponse_ListServicesResponse{
+		ListServicesResponse: &reflectionv1.ListServiceResponse{
+			Service: serviceResponses,
+		},
+	}
+}
+
+type extensionContainer interface {
+	Extensions() protoreflect.ExtensionDescriptors
+	Messages() protoreflect.MessageDescriptors
+}
+
+func rangeExtensions(container extensionContainer, f func(protoreflect.ExtensionDescriptor)) {
+	extensions := container.Extensions()
+	for i := 0; i < extensions.Len(); i++ {
+		f(extensions.Get(i))
+	}
+	messages := container.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		rangeExtensions(messages.Get(i), f)
+	}
+}
+
+func errorMessage(err error) string {
+	if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+		return connectErr.Message()
+	}
+	return err.Error()
+}
@@ -17,6 +17,7 @@ package buftarget
 import (
 	"context"
 	"log/slog"
+	"strings"
 
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
@@ -120,6 +121,10 @@ func newBucketTargeting(
 			mappedTargetPaths[i] = mappedTargetPath
 		}
 		for i, targetExcludePath := range targetExcludePaths {
+			if targetExcludePath == "**" || strings.HasPrefix(targetExcludePath, "**/") {
+				// Globs that are not anchored to a directory apply as-is.
+				continue
+			}
 			mappedTargetExcludePath, err := normalpath.Rel(controllingWorkspace.Path(), targetExcludePath)
 			if err != nil {
 				return nil, err
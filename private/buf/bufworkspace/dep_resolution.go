@@ -0,0 +1,67 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufworkspace
+
+import (
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+)
+
+// DepResolutionsForWorkspace gets the reasons each remote dependency of the Workspace was
+// resolved, keyed by the ModuleFullName string of the dependency.
+//
+// A dependency is direct if a local Module in the Workspace depends on it. A dependency is
+// required by every other remote dependency that directly depends on it. The result is
+// suitable for UpdateBufLockFileWithDepResolutions.
+func DepResolutionsForWorkspace(workspace Workspace) (map[string]bufconfig.BufLockFileDepResolution, error) {
+	remoteDeps, err := bufmodule.RemoteDepsForModuleSet(workspace)
+	if err != nil {
+		return nil, err
+	}
+	moduleFullNameStringToRequiredBy := make(map[string][]bufmodule.ModuleFullName)
+	for _, remoteDep := range remoteDeps {
+		remoteDepModuleFullName := remoteDep.ModuleFullName()
+		if remoteDepModuleFullName == nil {
+			return nil, syserror.Newf("ModuleFullName nil on remote Module dependency %q", remoteDep.OpaqueID())
+		}
+		moduleDeps, err := remoteDep.ModuleDeps()
+		if err != nil {
+			return nil, err
+		}
+		for _, moduleDep := range moduleDeps {
+			if !moduleDep.IsDirect() || moduleDep.IsLocal() {
+				continue
+			}
+			moduleDepFullName := moduleDep.ModuleFullName()
+			if moduleDepFullName == nil {
+				return nil, syserror.Newf("ModuleFullName nil on Module dependency %q", moduleDep.OpaqueID())
+			}
+			moduleFullNameStringToRequiredBy[moduleDepFullName.String()] = append(
+				moduleFullNameStringToRequiredBy[moduleDepFullName.String()],
+				remoteDepModuleFullName,
+			)
+		}
+	}
+	moduleFullNameStringToDepResolution := make(map[string]bufconfig.BufLockFileDepResolution, len(remoteDeps))
+	for _, remoteDep := range remoteDeps {
+		moduleFullNameString := remoteDep.ModuleFullName().String()
+		moduleFullNameStringToDepResolution[moduleFullNameString] = bufconfig.NewBufLockFileDepResolution(
+			remoteDep.IsDirect(),
+			moduleFullNameStringToRequiredBy[moduleFullNameString],
+		)
+	}
+	return moduleFullNameStringToDepResolution, nil
+}
@@ -95,10 +95,21 @@ func MalformedDepsForWorkspace(workspace Workspace) ([]MalformedDep, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Dependencies replaced with another module are used if the replacement is used.
+	moduleFullNameStringToReplacementModuleFullNameString := make(map[string]string)
+	for _, replaceConfig := range workspace.ReplaceConfigs() {
+		if moduleRef := replaceConfig.ModuleRef(); moduleRef != nil {
+			moduleFullNameStringToReplacementModuleFullNameString[replaceConfig.ModuleFullName().String()] = moduleRef.ModuleFullName().String()
+		}
+	}
 	var malformedDeps []MalformedDep
 	for moduleFullNameString, configuredDepModuleRef := range moduleFullNameStringToConfiguredDepModuleRef {
-		_, isLocalModule := localModuleFullNameStringMap[moduleFullNameString]
-		_, isRemoteDep := moduleFullNameStringToRemoteDep[moduleFullNameString]
+		usedModuleFullNameString := moduleFullNameString
+		if replacementModuleFullNameString, ok := moduleFullNameStringToReplacementModuleFullNameString[moduleFullNameString]; ok {
+			usedModuleFullNameString = replacementModuleFullNameString
+		}
+		_, isLocalModule := localModuleFullNameStringMap[usedModuleFullNameString]
+		_, isRemoteDep := moduleFullNameStringToRemoteDep[usedModuleFullNameString]
 		if !isRemoteDep && !isLocalModule {
 			// The module was in buf.yaml deps, but was not in the remote dep list after
 			// adding all ModuleKeys and transitive dependency ModuleKeys. It is also not
@@ -16,6 +16,7 @@ package bufworkspace
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/buftarget"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
@@ -61,6 +62,7 @@ func newModuleTargeting(
 
 	var moduleTargetPaths []string
 	var moduleTargetExcludePaths []string
+	var unanchoredGlobExcludePaths []string
 	var moduleProtoFileTargetPath string
 	var includePackageFiles bool
 	if config.protoFileTargetPath != "" {
@@ -124,6 +126,12 @@ func newModuleTargeting(
 					// This really should be allowed - how else do you exclude from a workspace?
 					return nil, fmt.Errorf("module %q was specified with --exclude-path, this flag cannot be used to specify module directories", targetExcludePath)
 				}
+				if isUnanchoredGlob(targetExcludePath) {
+					// Globs such as "**/internal" apply within every module, and are matched
+					// against paths relative to the roots as well.
+					unanchoredGlobExcludePaths = append(unanchoredGlobExcludePaths, targetExcludePath)
+					continue
+				}
 				if normalpath.ContainsPath(moduleDirPath, targetExcludePath, normalpath.Relative) {
 					moduleTargetExcludePath, err := normalpath.Rel(moduleDirPath, targetExcludePath)
 					if err != nil {
@@ -151,6 +159,7 @@ func newModuleTargeting(
 		if err != nil {
 			return nil, err
 		}
+		moduleTargetExcludePaths = append(moduleTargetExcludePaths, unanchoredGlobExcludePaths...)
 	}
 	return &moduleTargeting{
 		moduleDirPath:             moduleDirPath,
@@ -162,6 +171,12 @@ func newModuleTargeting(
 	}, nil
 }
 
+// isUnanchoredGlob returns true if the path is a glob pattern that starts with "**",
+// and therefore is not anchored to any directory.
+func isUnanchoredGlob(path string) bool {
+	return path == "**" || strings.HasPrefix(path, "**/")
+}
+
 func applyRootsToTargetPath(roots []string, path string, pathType normalpath.PathType) (string, error) {
 	var matchingRoots []string
 	for _, root := range roots {
@@ -0,0 +1,217 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const (
+	// VendorDirName is the name of the directory that dependencies are vendored to.
+	//
+	// The vendor directory lives next to the v2 buf.yaml and buf.lock.
+	VendorDirName = "vendor"
+
+	// vendorModulesFileName is the name of the file within the vendor directory that lists
+	// the vendored modules.
+	//
+	// A directory is only treated as a vendor directory if this file is present, so that
+	// existing directories named vendor are not affected.
+	vendorModulesFileName = "modules.yaml"
+	vendorModulesVersion  = "v1"
+)
+
+// *** PRIVATE ***
+
+var errReadOnlyVendorDir = errors.New("vendor directory is read-only, use buf dep vendor to update it")
+
+// externalVendorModules is the content of the vendorModulesFileName file.
+//
+// This is purely informational, to make reviewing changes to vendored modules easier.
+// The vendored modules themselves are read by ModuleKey from the buf.lock.
+type externalVendorModules struct {
+	Version string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	Modules []externalVendorModule `json:"modules,omitempty" yaml:"modules,omitempty"`
+}
+
+type externalVendorModule struct {
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// putVendorDir replaces the vendor directory within the bucket with the ModuleDatas.
+func putVendorDir(
+	ctx context.Context,
+	bucket storage.ReadWriteBucket,
+	vendorDirPath string,
+	moduleDatas []bufmodule.ModuleData,
+) error {
+	if err := bucket.DeleteAll(ctx, vendorDirPath); err != nil {
+		return err
+	}
+	vendorBucket := storage.MapReadWriteBucket(bucket, storage.MapOnPrefix(vendorDirPath))
+	if err := newVendorModuleDataStore(vendorBucket).PutModuleDatas(ctx, moduleDatas); err != nil {
+		return err
+	}
+	externalVendorModules := externalVendorModules{
+		Version: vendorModulesVersion,
+	}
+	for _, moduleData := range moduleDatas {
+		moduleKey := moduleData.ModuleKey()
+		digest, err := moduleKey.Digest()
+		if err != nil {
+			return err
+		}
+		externalVendorModules.Modules = append(
+			externalVendorModules.Modules,
+			externalVendorModule{
+				Name:   moduleKey.ModuleFullName().String(),
+				Commit: uuidutil.ToDashless(moduleKey.CommitID()),
+				Digest: digest.String(),
+			},
+		)
+	}
+	data, err := encoding.MarshalYAML(&externalVendorModules)
+	if err != nil {
+		return err
+	}
+	return storage.PutPath(
+		ctx,
+		vendorBucket,
+		vendorModulesFileName,
+		append([]byte("# Generated by buf. DO NOT EDIT.\n"), data...),
+	)
+}
+
+// isVendorDir returns true if the directory at the path is a vendor directory.
+func isVendorDir(ctx context.Context, bucket storage.ReadBucket, vendorDirPath string) (bool, error) {
+	return storage.Exists(ctx, bucket, normalpath.Join(vendorDirPath, vendorModulesFileName))
+}
+
+func newVendorModuleDataStore(vendorBucket storage.ReadWriteBucket) bufmodulestore.ModuleDataStore {
+	return bufmodulestore.NewModuleDataStore(
+		// The store only logs at debug level.
+		slogext.NopLogger,
+		vendorBucket,
+		// Vendor directories are only written by explicit user commands.
+		filelock.NewNopLocker(),
+	)
+}
+
+// vendorModuleDataProvider is a ModuleDataProvider that prefers ModuleDatas in a vendor
+// directory, and uses the delegate for any ModuleKeys that are not vendored.
+type vendorModuleDataProvider struct {
+	logger   *slog.Logger
+	delegate bufmodule.ModuleDataProvider
+	store    bufmodulestore.ModuleDataStore
+}
+
+func newVendorModuleDataProvider(
+	logger *slog.Logger,
+	delegate bufmodule.ModuleDataProvider,
+	bucket storage.ReadBucket,
+	vendorDirPath string,
+) *vendorModuleDataProvider {
+	return &vendorModuleDataProvider{
+		logger:   logger,
+		delegate: delegate,
+		store: newVendorModuleDataStore(
+			&readOnlyBucket{
+				ReadBucket: storage.MapReadBucket(bucket, storage.MapOnPrefix(vendorDirPath)),
+			},
+		),
+	}
+}
+
+func (p *vendorModuleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	foundModuleDatas, notFoundModuleKeys, err := p.store.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(notFoundModuleKeys) > 0 {
+		p.logger.DebugContext(
+			ctx,
+			"modules not vendored",
+			slog.Any("moduleKeys", slicesext.Map(notFoundModuleKeys, bufmodule.ModuleKey.String)),
+		)
+		delegateModuleDatas, err := p.delegate.GetModuleDatasForModuleKeys(ctx, notFoundModuleKeys)
+		if err != nil {
+			return nil, err
+		}
+		foundModuleDatas = append(foundModuleDatas, delegateModuleDatas...)
+	}
+	// Restore the order of the input ModuleKeys.
+	commitIDToModuleData, err := slicesext.ToUniqueValuesMapError(
+		foundModuleDatas,
+		func(moduleData bufmodule.ModuleData) (uuid.UUID, error) {
+			return moduleData.ModuleKey().CommitID(), nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return slicesext.MapError(
+		moduleKeys,
+		func(moduleKey bufmodule.ModuleKey) (bufmodule.ModuleData, error) {
+			moduleData, ok := commitIDToModuleData[moduleKey.CommitID()]
+			if !ok {
+				// This should never happen.
+				return nil, fmt.Errorf("no ModuleData returned for %s", moduleKey.String())
+			}
+			return moduleData, nil
+		},
+	)
+}
+
+// readOnlyBucket is a ReadWriteBucket that errors on all writes.
+//
+// The vendor directory is never written to when reading a workspace.
+type readOnlyBucket struct {
+	storage.ReadBucket
+}
+
+func (*readOnlyBucket) Put(context.Context, string, ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	return nil, errReadOnlyVendorDir
+}
+
+func (*readOnlyBucket) Delete(context.Context, string) error {
+	return errReadOnlyVendorDir
+}
+
+func (*readOnlyBucket) DeleteAll(context.Context, string) error {
+	return errReadOnlyVendorDir
+}
+
+func (*readOnlyBucket) SetExternalAndLocalPathsSupported() bool {
+	return false
+}
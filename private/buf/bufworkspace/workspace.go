@@ -84,6 +84,14 @@ type Workspace interface {
 	//
 	// Sorted.
 	ConfiguredDepModuleRefs() []bufmodule.ModuleRef
+	// ReplaceConfigs returns the replace directives for the dependencies of the Workspace.
+	//
+	// Dependencies replaced with a path are already within the Workspace as non-target
+	// local Modules. Dependencies replaced with a ModuleRef are resolved to the replacement
+	// by buf dep update, and the replacement is what is in the buf.lock.
+	//
+	// Only set for Workspaces created from v2 buf.yamls. Sorted by ModuleFullName.
+	ReplaceConfigs() []bufconfig.ReplaceConfig
 
 	// IsV2 signifies if this module was created from a v2 buf.yaml.
 	//
@@ -105,6 +113,7 @@ type workspace struct {
 	opaqueIDToBreakingConfig map[string]bufconfig.BreakingConfig
 	pluginConfigs            []bufconfig.PluginConfig
 	configuredDepModuleRefs  []bufmodule.ModuleRef
+	replaceConfigs           []bufconfig.ReplaceConfig
 
 	// If true, the workspace was created from v2 buf.yamls.
 	// If false, the workspace was created from defaults, or v1beta1/v1 buf.yamls.
@@ -117,6 +126,7 @@ func newWorkspace(
 	opaqueIDToBreakingConfig map[string]bufconfig.BreakingConfig,
 	pluginConfigs []bufconfig.PluginConfig,
 	configuredDepModuleRefs []bufmodule.ModuleRef,
+	replaceConfigs []bufconfig.ReplaceConfig,
 	isV2 bool,
 ) *workspace {
 	return &workspace{
@@ -125,6 +135,7 @@ func newWorkspace(
 		opaqueIDToBreakingConfig: opaqueIDToBreakingConfig,
 		pluginConfigs:            pluginConfigs,
 		configuredDepModuleRefs:  configuredDepModuleRefs,
+		replaceConfigs:           replaceConfigs,
 		isV2:                     isV2,
 	}
 }
@@ -145,6 +156,10 @@ func (w *workspace) ConfiguredDepModuleRefs() []bufmodule.ModuleRef {
 	return slicesext.Copy(w.configuredDepModuleRefs)
 }
 
+func (w *workspace) ReplaceConfigs() []bufconfig.ReplaceConfig {
+	return slicesext.Copy(w.replaceConfigs)
+}
+
 func (w *workspace) IsV2() bool {
 	return w.isV2
 }
@@ -21,6 +21,7 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 )
@@ -42,7 +43,7 @@ type WorkspaceDepManager interface {
 	// the given ModuleKeys.
 	//
 	// If a buf.lock does not exist, one will be created.
-	UpdateBufLockFile(ctx context.Context, depModuleKeys []bufmodule.ModuleKey) error
+	UpdateBufLockFile(ctx context.Context, depModuleKeys []bufmodule.ModuleKey, options ...UpdateBufLockFileOption) error
 	// ConfiguredDepModuleRefs returns the configured dependencies of the Workspace as ModuleRefs.
 	//
 	// These come from buf.yaml files.
@@ -55,10 +56,43 @@ type WorkspaceDepManager interface {
 	//
 	// Sorted.
 	ConfiguredDepModuleRefs(ctx context.Context) ([]bufmodule.ModuleRef, error)
+	// ReplaceConfigs returns the replace directives for the dependencies of the Workspace.
+	//
+	// These come from v2 buf.yaml files. Sorted by ModuleFullName.
+	ReplaceConfigs(ctx context.Context) ([]bufconfig.ReplaceConfig, error)
+	// RemoveConfiguredDeps removes the configured dependencies with the given ModuleFullNames
+	// from the buf.yaml that backs the Workspace.
+	//
+	// Comments and the order of the remaining dependencies in the buf.yaml are preserved.
+	RemoveConfiguredDeps(ctx context.Context, moduleFullNames []bufmodule.ModuleFullName) error
+	// UpdateVendorDir replaces the vendor directory next to the buf.lock with the
+	// given ModuleDatas.
+	//
+	// When a vendor directory is present, Workspaces read dependencies from the vendor
+	// directory instead of the cache or the BSR.
+	//
+	// This is only supported for Workspaces backed by a v2 buf.yaml.
+	UpdateVendorDir(ctx context.Context, depModuleDatas []bufmodule.ModuleData) error
 
 	isWorkspaceDepManager()
 }
 
+// UpdateBufLockFileOption is an option for UpdateBufLockFile.
+type UpdateBufLockFileOption func(*updateBufLockFileOptions)
+
+// UpdateBufLockFileWithDepResolutions returns a new UpdateBufLockFileOption that records
+// why each dependency was resolved into the buf.lock file.
+//
+// The resolutions are typically computed with DepResolutionsForWorkspace. Resolutions
+// are only recorded for Workspaces backed by a v2 buf.yaml, and are ignored otherwise.
+func UpdateBufLockFileWithDepResolutions(
+	moduleFullNameStringToDepResolution map[string]bufconfig.BufLockFileDepResolution,
+) UpdateBufLockFileOption {
+	return func(updateBufLockFileOptions *updateBufLockFileOptions) {
+		updateBufLockFileOptions.moduleFullNameStringToDepResolution = moduleFullNameStringToDepResolution
+	}
+}
+
 // *** PRIVATE ***
 
 type workspaceDepManager struct {
@@ -93,6 +127,31 @@ func newWorkspaceDepManager(
 }
 
 func (w *workspaceDepManager) ConfiguredDepModuleRefs(ctx context.Context) ([]bufmodule.ModuleRef, error) {
+	bufYAMLFile, err := w.getBufYAMLFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bufYAMLFile == nil {
+		return nil, nil
+	}
+	return bufYAMLFile.ConfiguredDepModuleRefs(), nil
+}
+
+func (w *workspaceDepManager) ReplaceConfigs(ctx context.Context) ([]bufconfig.ReplaceConfig, error) {
+	bufYAMLFile, err := w.getBufYAMLFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bufYAMLFile == nil {
+		return nil, nil
+	}
+	return bufYAMLFile.ReplaceConfigs(), nil
+}
+
+// getBufYAMLFile gets the buf.yaml that backs the Workspace.
+//
+// Returns nil if there is no buf.yaml.
+func (w *workspaceDepManager) getBufYAMLFile(ctx context.Context) (bufconfig.BufYAMLFile, error) {
 	bufYAMLFile, err := bufconfig.GetBufYAMLFileForPrefix(ctx, w.bucket, w.targetSubDirPath)
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
@@ -114,7 +173,14 @@ func (w *workspaceDepManager) ConfiguredDepModuleRefs(ctx context.Context) ([]bu
 	default:
 		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
 	}
-	return bufYAMLFile.ConfiguredDepModuleRefs(), nil
+	return bufYAMLFile, nil
+}
+
+func (w *workspaceDepManager) RemoveConfiguredDeps(ctx context.Context, moduleFullNames []bufmodule.ModuleFullName) error {
+	if len(moduleFullNames) == 0 {
+		return nil
+	}
+	return bufconfig.RemoveBufYAMLFileDepsForPrefix(ctx, w.bucket, w.targetSubDirPath, moduleFullNames)
 }
 
 func (w *workspaceDepManager) BufLockFileDigestType() bufmodule.DigestType {
@@ -135,11 +201,23 @@ func (w *workspaceDepManager) ExistingBufLockFileDepModuleKeys(ctx context.Conte
 	return bufLockFile.DepModuleKeys(), nil
 }
 
-func (w *workspaceDepManager) UpdateBufLockFile(ctx context.Context, depModuleKeys []bufmodule.ModuleKey) error {
+func (w *workspaceDepManager) UpdateBufLockFile(
+	ctx context.Context,
+	depModuleKeys []bufmodule.ModuleKey,
+	options ...UpdateBufLockFileOption,
+) error {
+	updateBufLockFileOptions := newUpdateBufLockFileOptions()
+	for _, option := range options {
+		option(updateBufLockFileOptions)
+	}
 	var bufLockFile bufconfig.BufLockFile
 	var err error
 	if w.isV2 {
-		bufLockFile, err = bufconfig.NewBufLockFile(bufconfig.FileVersionV2, depModuleKeys)
+		bufLockFile, err = bufconfig.NewBufLockFile(
+			bufconfig.FileVersionV2,
+			depModuleKeys,
+			bufconfig.BufLockFileWithDepResolutions(updateBufLockFileOptions.moduleFullNameStringToDepResolution),
+		)
 		if err != nil {
 			return err
 		}
@@ -161,4 +239,24 @@ func (w *workspaceDepManager) UpdateBufLockFile(ctx context.Context, depModuleKe
 	return bufconfig.PutBufLockFileForPrefix(ctx, w.bucket, w.targetSubDirPath, bufLockFile)
 }
 
+func (w *workspaceDepManager) UpdateVendorDir(ctx context.Context, depModuleDatas []bufmodule.ModuleData) error {
+	if !w.isV2 {
+		return errors.New("vendoring dependencies is only supported for v2 buf.yaml files, run buf config migrate to migrate to v2")
+	}
+	return putVendorDir(
+		ctx,
+		w.bucket,
+		normalpath.Join(w.targetSubDirPath, VendorDirName),
+		depModuleDatas,
+	)
+}
+
 func (*workspaceDepManager) isWorkspaceDepManager() {}
+
+type updateBufLockFileOptions struct {
+	moduleFullNameStringToDepResolution map[string]bufconfig.BufLockFileDepResolution
+}
+
+func newUpdateBufLockFileOptions() *updateBufLockFileOptions {
+	return &updateBufLockFileOptions{}
+}
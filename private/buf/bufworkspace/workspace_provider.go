@@ -30,7 +30,9 @@ import (
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // WorkspaceProvider provides Workspaces and UpdateableWorkspaces.
@@ -112,8 +114,10 @@ func (w *workspaceProvider) GetWorkspaceForModuleKey(
 	ctx context.Context,
 	moduleKey bufmodule.ModuleKey,
 	options ...WorkspaceModuleKeyOption,
-) (Workspace, error) {
+) (_ Workspace, retErr error) {
 	defer slogext.DebugProfile(w.logger)()
+	ctx, span := tracing.Start(ctx, "bufworkspace.resolve", attribute.String("buf.module", moduleKey.String()))
+	defer func() { tracing.End(span, retErr) }()
 
 	config, err := newWorkspaceModuleKeyConfig(options)
 	if err != nil {
@@ -209,6 +213,7 @@ func (w *workspaceProvider) GetWorkspaceForModuleKey(
 		opaqueIDToBreakingConfig,
 		pluginConfigs,
 		nil,
+		nil,
 		false,
 	), nil
 }
@@ -218,8 +223,10 @@ func (w *workspaceProvider) GetWorkspaceForBucket(
 	bucket storage.ReadBucket,
 	bucketTargeting buftarget.BucketTargeting,
 	options ...WorkspaceBucketOption,
-) (Workspace, error) {
+) (_ Workspace, retErr error) {
 	defer slogext.DebugProfile(w.logger)()
+	ctx, span := tracing.Start(ctx, "bufworkspace.resolve")
+	defer func() { tracing.End(span, retErr) }()
 	config, err := newWorkspaceBucketConfig(options)
 	if err != nil {
 		return nil, err
@@ -359,6 +366,7 @@ func (w *workspaceProvider) getWorkspaceForBucketAndModuleDirPathsV1Beta1OrV1(
 		v1WorkspaceTargeting.bucketIDToModuleConfig,
 		nil,
 		v1WorkspaceTargeting.allConfiguredDepModuleRefs,
+		nil,
 		false,
 	)
 }
@@ -368,7 +376,16 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 	bucket storage.ReadBucket,
 	v2Targeting *v2Targeting,
 ) (*workspace, error) {
-	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, w.logger, w.moduleDataProvider, w.commitProvider)
+	// vendor directories live next to the buf.lock.
+	hasVendorDir, err := isVendorDir(ctx, bucket, VendorDirName)
+	if err != nil {
+		return nil, err
+	}
+	moduleDataProvider := w.moduleDataProvider
+	if hasVendorDir {
+		moduleDataProvider = newVendorModuleDataProvider(w.logger, moduleDataProvider, bucket, VendorDirName)
+	}
+	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, w.logger, moduleDataProvider, w.commitProvider)
 	bufLockFile, err := bufconfig.GetBufLockFileForPrefix(
 		ctx,
 		bucket,
@@ -409,6 +426,40 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 	//       - proot/foo
 	// but duplicate module description in v1 is a system error, which the ModuleSetBuilder catches.
 	seenModuleDescriptions := make(map[string]struct{})
+	// Dependencies replaced with a path are added as non-target local Modules, which take
+	// precedence over any remote Modules with the same ModuleFullName from the buf.lock.
+	var replacePaths []string
+	for _, replaceConfig := range v2Targeting.bufYAMLFile.ReplaceConfigs() {
+		replacePath := replaceConfig.Path()
+		if replacePath == "" {
+			continue
+		}
+		if _, ok := v2Targeting.bucketIDToModuleConfig[replacePath]; ok {
+			return nil, fmt.Errorf("replace path %q for module %s overlaps with a module within the workspace", replacePath, replaceConfig.ModuleFullName().String())
+		}
+		replaceModuleConfig, err := bufconfig.NewModuleConfig(
+			replacePath,
+			replaceConfig.ModuleFullName(),
+			map[string][]string{".": {}},
+			map[string][]string{".": {}},
+			bufconfig.DefaultLintConfigV2,
+			bufconfig.DefaultBreakingConfigV2,
+		)
+		if err != nil {
+			return nil, err
+		}
+		v2Targeting.bucketIDToModuleConfig[replacePath] = replaceModuleConfig
+		replacePaths = append(replacePaths, replacePath)
+		moduleSetBuilder.AddLocalModule(
+			storage.MapReadBucket(bucket, storage.MapOnPrefix(replacePath)),
+			replacePath,
+			false,
+			bufmodule.LocalModuleWithModuleFullName(replaceConfig.ModuleFullName()),
+			bufmodule.LocalModuleWithDescription(
+				fmt.Sprintf("path: %q, replaces: %s", replacePath, replaceConfig.ModuleFullName().String()),
+			),
+		)
+	}
 	for _, moduleBucketAndTargeting := range v2Targeting.moduleBucketsAndTargeting {
 		mappedModuleBucket := moduleBucketAndTargeting.bucket
 		moduleTargeting := moduleBucketAndTargeting.moduleTargeting
@@ -430,6 +481,30 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 			return nil, fmt.Errorf("multiple module configs found with the same description: %s", moduleDescription)
 		}
 		seenModuleDescriptions[moduleDescription] = struct{}{}
+		if hasVendorDir && normalpath.EqualsOrContainsPath(moduleTargeting.moduleDirPath, VendorDirName, normalpath.Relative) {
+			// The vendored modules are not part of the local module.
+			relVendorDirPath, err := normalpath.Rel(moduleTargeting.moduleDirPath, VendorDirName)
+			if err != nil {
+				return nil, err
+			}
+			mappedModuleBucket = storage.FilterReadBucket(
+				mappedModuleBucket,
+				storage.MatchNot(storage.MatchPathEqualOrContained(relVendorDirPath)),
+			)
+		}
+		for _, replacePath := range replacePaths {
+			// Replacement directories are not part of the local module either.
+			if normalpath.EqualsOrContainsPath(moduleTargeting.moduleDirPath, replacePath, normalpath.Relative) {
+				relReplacePath, err := normalpath.Rel(moduleTargeting.moduleDirPath, replacePath)
+				if err != nil {
+					return nil, err
+				}
+				mappedModuleBucket = storage.FilterReadBucket(
+					mappedModuleBucket,
+					storage.MatchNot(storage.MatchPathEqualOrContained(relReplacePath)),
+				)
+			}
+		}
 		moduleSetBuilder.AddLocalModule(
 			mappedModuleBucket,
 			moduleBucketAndTargeting.bucketID,
@@ -455,6 +530,7 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 		v2Targeting.bucketIDToModuleConfig,
 		v2Targeting.bufYAMLFile.PluginConfigs(),
 		v2Targeting.bufYAMLFile.ConfiguredDepModuleRefs(),
+		v2Targeting.bufYAMLFile.ReplaceConfigs(),
 		true,
 	)
 }
@@ -466,6 +542,7 @@ func (w *workspaceProvider) getWorkspaceForBucketModuleSet(
 	pluginConfigs []bufconfig.PluginConfig,
 	// Expected to already be unique by ModuleFullName.
 	configuredDepModuleRefs []bufmodule.ModuleRef,
+	replaceConfigs []bufconfig.ReplaceConfig,
 	isV2 bool,
 ) (*workspace, error) {
 	opaqueIDToLintConfig := make(map[string]bufconfig.LintConfig)
@@ -490,6 +567,7 @@ func (w *workspaceProvider) getWorkspaceForBucketModuleSet(
 		opaqueIDToBreakingConfig,
 		pluginConfigs,
 		configuredDepModuleRefs,
+		replaceConfigs,
 		isV2,
 	), nil
 }
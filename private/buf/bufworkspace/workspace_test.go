@@ -246,6 +246,66 @@ func TestUnusedDep(t *testing.T) {
 	require.Equal(t, MalformedDepTypeUnused, malformedDeps[1].Type())
 }
 
+func TestReplacePath(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// This represents some external dependencies from the BSR. The date module is
+	// replaced with a local directory, so only the extension module is in the buf.lock.
+	workspaceProvider := testNewWorkspaceProvider(
+		t,
+		bufmoduletesting.ModuleData{
+			Name:    "buf.testing/acme/date",
+			DirPath: "testdata/basic/bsr/buf.testing/acme/date",
+		},
+		bufmoduletesting.ModuleData{
+			Name:    "buf.testing/acme/extension",
+			DirPath: "testdata/basic/bsr/buf.testing/acme/extension",
+		},
+	)
+
+	storageosProvider := storageos.NewProvider()
+	bucket, err := storageosProvider.NewReadWriteBucket("testdata/basic/workspace_replace_path")
+	require.NoError(t, err)
+	bucketTargeting, err := buftarget.NewBucketTargeting(
+		ctx,
+		slogtestext.NewLogger(t),
+		bucket,
+		".",
+		nil,
+		nil,
+		buftarget.TerminateAtControllingWorkspace,
+	)
+	require.NoError(t, err)
+
+	workspace, err := workspaceProvider.GetWorkspaceForBucket(
+		ctx,
+		bucket,
+		bucketTargeting,
+	)
+	require.NoError(t, err)
+	require.Len(t, workspace.ReplaceConfigs(), 1)
+	require.Len(t, workspace.Modules(), 3) // 1 local + 1 replacement + 1 remote
+
+	module := workspace.GetModuleForOpaqueID("buf.testing/acme/date")
+	require.NotNil(t, module)
+	require.True(t, module.IsLocal())
+	require.False(t, module.IsTarget())
+	requireModuleContainFileNames(t, module, "acme/date/v1/date.proto")
+	module = workspace.GetModuleForOpaqueID("buf.testing/acme/bond")
+	require.NotNil(t, module)
+	require.True(t, module.IsTarget())
+	requireModuleContainFileNames(t, module, "acme/bond/v1/bond.proto")
+
+	remoteDeps, err := bufmodule.RemoteDepsForModuleSet(workspace)
+	require.NoError(t, err)
+	require.Len(t, remoteDeps, 1)
+	require.Equal(t, "buf.testing/acme/extension", remoteDeps[0].ModuleFullName().String())
+	malformedDeps, err := MalformedDepsForWorkspace(workspace)
+	require.NoError(t, err)
+	require.Empty(t, malformedDeps)
+}
+
 func TestDuplicatePath(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -33,6 +33,8 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv1"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv1beta1"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv2"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/docs"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/image/imageconvert"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/lsp"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/price"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/plugin/plugindelete"
@@ -40,6 +42,8 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookcreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhooklist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/sbom"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/servereflection"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/stats"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/studioagent"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/breaking"
@@ -51,9 +55,12 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/config/configmigrate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/convert"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/curl"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depdownload"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depgraph"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depprune"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depupdate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depvendor"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depwhy"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/export"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/format"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/generate"
@@ -84,8 +91,10 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrycc"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogin"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogout"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrywhoami"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/sdk/version"
 	"github.com/bufbuild/buf/private/bufpkg/bufcobra"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app"
@@ -94,6 +103,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slogapp"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Main is the entrypoint to the buf CLI.
@@ -105,18 +115,48 @@ func Main(name string) {
 //
 // This is public for use in testing.
 func NewRootCommand(name string) *appcmd.Command {
+	var offline bool
+	var noSandbox bool
+	var pluginConcurrency int
+	var fromBundle string
+	var profile string
+	var debugTransport bool
+	var color string
+	var resultFormat string
 	builder := appext.NewBuilder(
 		name,
 		appext.BuilderWithTimeout(120*time.Second),
+		// Must be first, so that the result observes the errors returned to the user.
+		appext.BuilderWithInterceptor(bufcli.NewResultFormatInterceptor(&resultFormat)),
 		appext.BuilderWithInterceptor(newErrorInterceptor()),
+		appext.BuilderWithInterceptor(bufcli.NewProfileInterceptor(&profile)),
+		appext.BuilderWithInterceptor(bufcli.NewTracingInterceptor()),
+		appext.BuilderWithInterceptor(bufcli.NewOfflineInterceptor(&offline)),
+		appext.BuilderWithInterceptor(bufcli.NewNoSandboxInterceptor(&noSandbox)),
+		appext.BuilderWithInterceptor(bufcli.NewPluginConcurrencyInterceptor(&pluginConcurrency)),
+		appext.BuilderWithInterceptor(bufcli.NewDebugTransportInterceptor(&debugTransport)),
+		appext.BuilderWithInterceptor(bufcli.NewColorInterceptor(&color)),
+		appext.BuilderWithInterceptor(bufcli.NewFromBundleInterceptor(&fromBundle)),
 		appext.BuilderWithLoggerProvider(slogapp.LoggerProvider),
 	)
 	return &appcmd.Command{
-		Use:                 name,
-		Short:               "The Buf CLI",
-		Long:                "A tool for working with Protocol Buffers and managing resources on the Buf Schema Registry (BSR)",
-		Version:             bufcli.Version,
-		BindPersistentFlags: builder.BindRoot,
+		Use:         name,
+		Short:       "The Buf CLI",
+		Long:        "A tool for working with Protocol Buffers and managing resources on the Buf Schema Registry (BSR)",
+		Version:     bufcli.Version,
+		ExpandAlias: bufcli.NewExpandAliasFunc(name),
+		External:    bufcli.NewExternalFunc(name),
+		BindPersistentFlags: func(flagSet *pflag.FlagSet) {
+			builder.BindRoot(flagSet)
+			bufcli.BindOffline(flagSet, &offline)
+			bufcli.BindNoSandbox(flagSet, &noSandbox)
+			bufcli.BindPluginConcurrency(flagSet, &pluginConcurrency)
+			bufcli.BindFromBundle(flagSet, &fromBundle)
+			bufcli.BindProfile(flagSet, &profile)
+			bufcli.BindDebugTransport(flagSet, &debugTransport)
+			bufcli.BindColor(flagSet, &color)
+			bufcli.BindResultFormat(flagSet, &resultFormat)
+		},
 		SubCommands: []*appcmd.Command{
 			build.NewCommand("build", builder),
 			export.NewCommand("export", builder),
@@ -132,9 +172,12 @@ func NewRootCommand(name string) *appcmd.Command {
 				Use:   "dep",
 				Short: "Work with dependencies",
 				SubCommands: []*appcmd.Command{
+					depdownload.NewCommand("download", builder),
 					depgraph.NewCommand("graph", builder),
 					depprune.NewCommand("prune", builder, ``, false),
 					depupdate.NewCommand("update", builder, ``, false),
+					depvendor.NewCommand("vendor", builder),
+					depwhy.NewCommand("why", builder),
 				},
 			},
 			{
@@ -176,6 +219,7 @@ func NewRootCommand(name string) *appcmd.Command {
 				SubCommands: []*appcmd.Command{
 					registrylogin.NewCommand("login", builder),
 					registrylogout.NewCommand("logout", builder),
+					registrywhoami.NewCommand("whoami", builder),
 					registrycc.NewCommand("cc", builder, ``, false),
 					{
 						Use:   "commit",
@@ -232,13 +276,23 @@ func NewRootCommand(name string) *appcmd.Command {
 				Use:   "beta",
 				Short: "Beta commands. Unstable and likely to change",
 				SubCommands: []*appcmd.Command{
+					docs.NewCommand("docs", builder),
 					lsp.NewCommand("lsp", builder),
 					price.NewCommand("price", builder),
+					sbom.NewCommand("sbom", builder),
 					stats.NewCommand("stats", builder),
 					bufpluginv1beta1.NewCommand("buf-plugin-v1beta1", builder),
 					bufpluginv1.NewCommand("buf-plugin-v1", builder),
 					bufpluginv2.NewCommand("buf-plugin-v2", builder),
 					studioagent.NewCommand("studio-agent", builder),
+					servereflection.NewCommand("serve-reflection", builder),
+					{
+						Use:   "image",
+						Short: "Work with Buf images and FileDescriptorSets",
+						SubCommands: []*appcmd.Command{
+							imageconvert.NewCommand("convert", builder),
+						},
+					},
 					{
 						Use:   "registry",
 						Short: "Manage assets on the Buf Schema Registry",
@@ -311,6 +365,19 @@ func wrapError(err error) error {
 	if err == nil {
 		return nil
 	}
+	exitCode := getExitCode(err)
+	err = wrapErrorMessage(err)
+	if app.GetExitCode(err) == exitCode {
+		return err
+	}
+	return app.WrapError(exitCode, err)
+}
+
+// wrapErrorMessage returns an error with a user-friendly message for the given error.
+func wrapErrorMessage(err error) error {
+	if err == nil {
+		return nil
+	}
 
 	var connectErr *connect.Error
 	isConnectError := errors.As(err, &connectErr)
@@ -318,6 +385,10 @@ func wrapError(err error) error {
 	if !isConnectError && err.Error() == "" {
 		return err
 	}
+	if retryError := (&bufconnect.RetryError{}); errors.As(err, &retryError) && retryError.RateLimited() {
+		// The message of the RetryError already explains that we were rate limited.
+		return appFailureError(retryError)
+	}
 	if isConnectError {
 		var augmentedConnectError *bufconnect.AugmentedConnectError
 		isAugmentedConnectErr := errors.As(err, &augmentedConnectError)
@@ -404,13 +475,46 @@ func wrapError(err error) error {
 		)
 	}
 
+	return appFailureError(err)
+}
+
+// getExitCode returns the exit code for the error returned by a CLI command.
+//
+// Errors that already have an exit code keep it. Otherwise, the error is classified
+// into one of the exit codes defined in bufctl, or 1 if it cannot be classified.
+func getExitCode(err error) int {
+	if exitCode := app.GetExitCode(err); exitCode != 1 {
+		return exitCode
+	}
 	var importNotExistError *bufmodule.ImportNotExistError
 	if errors.As(err, &importNotExistError) {
 		// There must be a better place to do this, perhaps in the Controller, but this works for now.
-		err = app.WrapError(bufctl.ExitCodeFileAnnotation, importNotExistError)
+		return bufctl.ExitCodeFileAnnotation
 	}
-
-	return appFailureError(err)
+	if _, isSysError := syserror.As(err); isSysError {
+		return bufctl.ExitCodeInternalError
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		switch {
+		case connectErr.Code() == connect.CodeUnauthenticated, isEmptyUnknownError(err):
+			return bufctl.ExitCodeAuthFailure
+		case connectErr.Code() == connect.CodeUnavailable:
+			return bufctl.ExitCodeNetworkFailure
+		}
+	}
+	if retryError := (&bufconnect.RetryError{}); errors.As(err, &retryError) && retryError.RateLimited() {
+		return bufctl.ExitCodeNetworkFailure
+	}
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return bufctl.ExitCodeNetworkFailure
+	}
+	var decodeError *bufconfig.DecodeError
+	if errors.As(err, &decodeError) {
+		return bufctl.ExitCodeConfigError
+	}
+	return 1
 }
 
 // isEmptyUnknownError returns true if the given
@@ -222,6 +222,25 @@ func TestSuccessProfile1(t *testing.T) {
 	testRunStdoutProfile(t, nil, 0, ``, "build", filepath.Join("testdata", "success"))
 }
 
+func TestSuccessProfileDeprecatedFlags(t *testing.T) {
+	t.Parallel()
+	tempDirPath := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"build",
+		filepath.Join("testdata", "success"),
+		"--pprof",
+		fmt.Sprintf("--profile-path=%s", tempDirPath),
+		"--profile-loops=1",
+		"--profile-type=mem",
+	)
+	// The deprecated flags set the same values as the --pprof flags.
+	require.FileExists(t, filepath.Join(tempDirPath, "mem.pprof"))
+}
+
 func TestSuccessDir(t *testing.T) {
 	t.Parallel()
 	testRunStdout(t, nil, 0, ``, "build", filepath.Join("testdata", "successnobufyaml"))
@@ -582,7 +601,7 @@ func TestFailCheckBreaking1(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 		../../../bufpkg/bufcheck/testdata/breaking/current/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
 		../../../bufpkg/bufcheck/testdata/breaking/current/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
@@ -604,7 +623,7 @@ func TestFailCheckBreaking2(t *testing.T) {
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`testdata/protofileref/breaking/a/foo.proto:7:3:Field "2" with name "world" on message "Foo" changed type from "int32" to "string".`),
 		"breaking",
 		filepath.Join("testdata", "protofileref", "breaking", "a", "foo.proto"),
@@ -618,7 +637,7 @@ func TestFailCheckBreaking3(t *testing.T) {
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 		<input>:1:1:Previously present file "bar.proto" was deleted.
 		testdata/protofileref/breaking/a/foo.proto:7:3:Field "2" with name "world" on message "Foo" changed type from "int32" to "string".
@@ -635,7 +654,7 @@ func TestFailCheckBreaking4(t *testing.T) {
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 		testdata/protofileref/breaking/a/bar.proto:5:1:Previously present field "2" with name "value" on message "Bar" was deleted.
 		testdata/protofileref/breaking/a/foo.proto:7:3:Field "2" with name "world" on message "Foo" changed type from "int32" to "string".
@@ -652,7 +671,7 @@ func TestFailCheckBreaking5(t *testing.T) {
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
     <input>:1:1:Previously present file "bar.proto" was deleted.
 		testdata/protofileref/breaking/a/foo.proto:7:3:Field "2" with name "world" on message "Foo" changed type from "int32" to "string".
@@ -2502,6 +2521,271 @@ func TestImageConvertRoundtripJSONBinaryJSON(t *testing.T) {
 	require.Equal(t, json1, stdout.Bytes())
 }
 
+func TestBetaImageConvertRoundtrip(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"build",
+		"-o",
+		"-",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	binary1 := stdout.Bytes()
+	require.NotEmpty(t, binary1)
+
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"beta",
+		"image",
+		"convert",
+		"-",
+		"-o",
+		"-#format=json",
+	)
+
+	stdin = stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"beta",
+		"image",
+		"convert",
+		"-#format=json",
+		"-o",
+		"-",
+	)
+
+	require.Equal(t, binary1, stdout.Bytes())
+}
+
+func TestBetaImageConvertAsFileDescriptorSet(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"build",
+		"-o",
+		"-",
+		"--as-file-descriptor-set",
+		"--exclude-source-info",
+		"--exclude-source-retention-options",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	expected := stdout.Bytes()
+	require.NotEmpty(t, expected)
+
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"build",
+		"-o",
+		"-",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"beta",
+		"image",
+		"convert",
+		"-",
+		"-o",
+		"-",
+		"--as-file-descriptor-set",
+		"--exclude-source-info",
+		"--exclude-source-retention-options",
+	)
+
+	require.Equal(t, expected, stdout.Bytes())
+}
+
+func TestBetaImageConvertNotImage(t *testing.T) {
+	t.Parallel()
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{
+			fmt.Sprintf(`Failure: %q is not an image or FileDescriptorSet`, filepath.Join("testdata", "customoptions1")),
+		},
+		"beta",
+		"image",
+		"convert",
+		filepath.Join("testdata", "customoptions1"),
+		"-o",
+		"-",
+	)
+}
+
+func TestBetaStats(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+		Files  Packages  Messages  Fields  Enums  Enum Values  Extensions  Services  Methods  Deprecated
+		2      2         2         8       1      3            2           1         2        4
+
+		Field Type  Fields
+		double      4
+		enum        1
+		map         1
+		message     1
+		string      1
+
+		Extension                 Uses
+		acme.weather.v1.internal  1
+		acme.weather.v1.unit      3
+		`,
+		"beta",
+		"stats",
+		filepath.Join("testdata", "stats"),
+	)
+}
+
+func TestBetaStatsImage(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"build",
+		"-o",
+		"-",
+		filepath.Join("testdata", "stats"),
+	)
+	testRunStdout(
+		t,
+		stdout,
+		0,
+		`{"num_files":2,"num_packages":2,"num_files_with_syntax_errors":0,"num_messages":2,"num_fields":8,"num_enums":1,"num_enum_values":3,"num_extensions":2,"num_services":1,"num_methods":2,"num_deprecated":4,"num_fields_by_type":{"double":4,"enum":1,"map":1,"message":1,"string":1},"num_extension_uses":{"acme.weather.v1.internal":1,"acme.weather.v1.unit":3}}`,
+		"beta",
+		"stats",
+		"-",
+		"--format",
+		"json",
+	)
+}
+
+func TestBetaDocs(t *testing.T) {
+	t.Parallel()
+	testRunStdoutFile(
+		t,
+		nil,
+		0,
+		filepath.Join("testdata", "docs", "docs.md"),
+		"beta",
+		"docs",
+		filepath.Join("testdata", "docs", "proto"),
+	)
+	testRunStdoutFile(
+		t,
+		nil,
+		0,
+		filepath.Join("testdata", "docs", "docs.html"),
+		"beta",
+		"docs",
+		filepath.Join("testdata", "docs", "proto"),
+		"--format",
+		"html",
+	)
+	testRunStdoutFile(
+		t,
+		nil,
+		0,
+		filepath.Join("testdata", "docs", "docs.json"),
+		"beta",
+		"docs",
+		filepath.Join("testdata", "docs", "proto"),
+		"--format",
+		"json",
+	)
+}
+
+func TestBetaDocsTemplate(t *testing.T) {
+	t.Parallel()
+	templateFilePath := filepath.Join(t.TempDir(), "docs.md.tmpl")
+	require.NoError(
+		t,
+		os.WriteFile(
+			templateFilePath,
+			[]byte(`{{range .Packages}}{{range .Messages}}{{.FullName}}: {{markdownCell .Description}}{{"\n"}}{{end}}{{end}}`),
+			0600,
+		),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+		acme.options.v1.Owner: Owner is the team that owns a message.
+		acme.weather.v1.Location: A location on Earth.
+		acme.weather.v1.Forecast: A forecast for a location.
+		acme.weather.v1.Forecast.Alert: An alert for severe weather.
+		acme.weather.v1.GetForecastRequest:
+		acme.weather.v1.GetForecastResponse:
+		`,
+		"beta",
+		"docs",
+		filepath.Join("testdata", "docs", "proto"),
+		"--template",
+		templateFilePath,
+	)
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"beta",
+		"docs",
+		"--format",
+		"html",
+		"--print-template",
+	)
+	require.True(t, strings.HasPrefix(stdout.String(), `{{- define "packageName"}}`))
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{`Failure: --template cannot be used with --format=json`},
+		"beta",
+		"docs",
+		filepath.Join("testdata", "docs", "proto"),
+		"--format",
+		"json",
+		"--template",
+		templateFilePath,
+	)
+}
+
 func TestModInitBasic(t *testing.T) {
 	t.Parallel()
 	testModInit(
@@ -2661,6 +2945,67 @@ func TestExportExcludeImports(t *testing.T) {
 	)
 }
 
+func TestExportIncludeWKT(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"export",
+		"-o",
+		tempDir,
+		filepath.Join("testdata", "export_wkt"),
+	)
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	storagetesting.AssertPaths(
+		t,
+		readWriteBucket,
+		"",
+		"event.proto",
+	)
+	tempDir = t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"export",
+		"--include-imports",
+		"--include-wkt",
+		"-o",
+		tempDir,
+		filepath.Join("testdata", "export_wkt"),
+	)
+	readWriteBucket, err = storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	storagetesting.AssertPaths(
+		t,
+		readWriteBucket,
+		"",
+		"event.proto",
+		"google/protobuf/timestamp.proto",
+	)
+}
+
+func TestExportIncludeAndExcludeImports(t *testing.T) {
+	t.Parallel()
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{"cannot set both --include-wkt and --exclude-imports"},
+		"export",
+		"--include-wkt",
+		"--exclude-imports",
+		"-o",
+		t.TempDir(),
+		filepath.Join("testdata", "export_wkt"),
+	)
+}
+
 func TestExportPaths(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -3117,7 +3462,7 @@ func TestBreakingWithPaths(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		`a/v3/a.proto:6:3:Field "1" with name "key" on message "Foo" changed type from "string" to "int32".
 a/v3/a.proto:7:3:Field "2" with name "Value" on message "Foo" changed option "json_name" from "value" to "Value".
 a/v3/a.proto:7:10:Field "2" on message "Foo" changed name from "value" to "Value".`,
@@ -3134,7 +3479,7 @@ a/v3/a.proto:7:10:Field "2" on message "Foo" changed name from "value" to "Value
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		`a/v3/a.proto:6:3:Field "1" with name "key" on message "Foo" changed type from "string" to "int32". See https://developers.google.com/protocol-buffers/docs/proto3#updating for wire compatibility rules.`,
 		"",
 		"breaking",
@@ -3186,7 +3531,7 @@ func TestBreakingWithPlugins(t *testing.T) {
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:10:5:max len requirement reduced from 10 to 5 (buf-plugin-protovalidate-ext)
 testdata/check_plugins/current/proto/common/v1alpha1/breaking.proto:10:5:max len requirement reduced from 10 to 5 (buf-plugin-protovalidate-ext)
@@ -3237,7 +3582,7 @@ testdata/check_plugins/current/proto/common/v1alpha1/breaking.proto:10:5:max len
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:10:5:max len requirement reduced from 10 to 5 (buf-plugin-protovalidate-ext)
 	`),
@@ -3279,7 +3624,7 @@ testdata/check_plugins/current/proto/common/v1/breaking.proto:10:5:max len requi
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:14:1:Message "common.v1.MSG_DONT_CHANGE" has a suffix configured for no changes has different fields, previously [], currently [common.v1.MSG_DONT_CHANGE.new_field]. (buf-plugin-suffix)
 testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.v1.E_DO_NOT_CHANGE" has a suffix configured for no changes has different enum values, previously [common.v1.ZERO], currently [common.v1.ONE common.v1.ZERO]. (buf-plugin-suffix)
@@ -3322,7 +3667,7 @@ testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:14:1:Message "common.v1.MSG_DONT_CHANGE" has a suffix configured for no changes has different fields, previously [], currently [common.v1.MSG_DONT_CHANGE.new_field]. (buf-plugin-suffix)
 testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.v1.E_DO_NOT_CHANGE" has a suffix configured for no changes has different enum values, previously [common.v1.ZERO], currently [common.v1.ONE common.v1.ZERO]. (buf-plugin-suffix)
@@ -3366,7 +3711,7 @@ testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:14:1:Message "common.v1.MSG_DONT_CHANGE" has a suffix configured for no changes has different fields, previously [], currently [common.v1.MSG_DONT_CHANGE.new_field]. (buf-plugin-suffix)
 	`),
@@ -3491,7 +3836,7 @@ testdata/check_plugins/current/proto/common/v1/breaking.proto:14:1:Message "comm
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.v1.E_DO_NOT_CHANGE" has a suffix configured for no changes has different enum values, previously [common.v1.ZERO], currently [common.v1.ONE common.v1.ZERO]. (buf-plugin-suffix)
 	`),
@@ -3540,7 +3885,7 @@ testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.
 	testRunStdout(
 		t,
 		nil,
-		bufctl.ExitCodeFileAnnotation,
+		bufctl.ExitCodeBreakingViolation,
 		filepath.FromSlash(`
 testdata/check_plugins/current/proto/common/v1/breaking.proto:14:1:Message "common.v1.MSG_DONT_CHANGE" has a suffix configured for no changes has different fields, previously [], currently [common.v1.MSG_DONT_CHANGE.new_field]. (buf-plugin-suffix)
 testdata/check_plugins/current/proto/common/v1/breaking.proto:18:1:Enum "common.v1.E_DO_NOT_CHANGE" has a suffix configured for no changes has different enum values, previously [common.v1.ZERO], currently [common.v1.ONE common.v1.ZERO]. (buf-plugin-suffix)
@@ -3610,6 +3955,52 @@ func TestConvertWithImage(t *testing.T) {
 	})
 }
 
+func TestConvertValidate(t *testing.T) {
+	t.Parallel()
+	t.Run("single", func(t *testing.T) {
+		t.Parallel()
+		testRunStdoutStderrNoWarn(
+			t,
+			strings.NewReader(`{"items":[{"name":"foo"},{"name":"x"}],"count":0}`),
+			1,
+			"",
+			`Failure: --from: validation error:
+ - items[1].name: value length must be at least 3 characters [string.min_len]
+ - count: value must be greater than 0 [int32.gt]`,
+			"convert",
+			filepath.Join("testdata", "convert_validate"),
+			"--type",
+			"buf.Validated",
+			"--from",
+			"-#format=json",
+			"--validate",
+		)
+	})
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+		testRunStdoutStderrNoWarn(
+			t,
+			nil,
+			1,
+			"",
+			`Failure: record 1: validation error:
+ - items[1].name: value length must be at least 3 characters [string.min_len]
+ - count: value must be greater than 0 [int32.gt]`,
+			"convert",
+			filepath.Join("testdata", "convert_validate"),
+			"--type",
+			"buf.Validated",
+			"--from",
+			filepath.Join("testdata", "convert_validate", "payloads.jsonl")+"#format=json",
+			"--to",
+			"-#format=json",
+			"--framing",
+			"varint",
+			"--validate",
+		)
+	})
+}
+
 func TestConvertOutput(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -4442,10 +4833,10 @@ func testRunStdoutProfile(t *testing.T, stdin io.Reader, expectedExitCode int, e
 		``,
 		append(
 			args,
-			"--profile",
-			fmt.Sprintf("--profile-path=%s", tempDirPath),
-			"--profile-loops=1",
-			"--profile-type=cpu",
+			"--pprof",
+			fmt.Sprintf("--pprof-path=%s", tempDirPath),
+			"--pprof-loops=1",
+			"--pprof-type=cpu",
 		)...,
 	)
 }
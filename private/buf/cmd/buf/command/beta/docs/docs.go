@@ -0,0 +1,192 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufdocs"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatFlagName          = "format"
+	templateFlagName        = "template"
+	printTemplateFlagName   = "print-template"
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Render documentation for the packages, messages, enums, and services of an input",
+		Long: `Render documentation for the packages, messages, enums, and services of an input to stdout.
+
+The documentation is rendered from the comments and options in the input, with links between the
+documented types. Messages and enums defined in imports are not documented, and are not linked to:
+
+    $ buf beta docs > docs.md
+    $ buf beta docs buf.build/acme/weather --format=html > docs.html
+
+The json format prints the structure that the markdown and html formats are rendered from, for use
+with other tools. The markdown and html formats are rendered with Go templates, which can be replaced
+with --template. Markdown templates are text/template templates, and HTML templates are html/template
+templates. Both are executed with the structure printed by the json format, with the fields named as
+in Go, which is with the first letter of the JSON names capitalized. Markdown templates can also call
+markdownCell, which formats text for a cell of a Markdown table. Use --print-template to start from the
+default template of a format:
+
+    $ buf beta docs --format=markdown --print-template > docs.md.tmpl
+    $ buf beta docs --format=markdown --template=docs.md.tmpl > docs.md
+
+` + bufcli.GetInputLong(`the source, module, or image to render documentation for`),
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format          string
+	Template        string
+	PrintTemplate   bool
+	ErrorFormat     string
+	DisableSymlinks bool
+
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		"markdown",
+		fmt.Sprintf(
+			"The documentation format to render. Must be one of %s",
+			stringutil.SliceToString(bufdocs.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Template,
+		templateFlagName,
+		"",
+		"The path to a Go template to render the documentation with instead of the default template of the format. Cannot be used with the json format",
+	)
+	flagSet.BoolVar(
+		&f.PrintTemplate,
+		printTemplateFlagName,
+		false,
+		"Print the default template of the format instead of rendering documentation",
+	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	format, err := bufdocs.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	if format == bufdocs.FormatJSON {
+		if flags.Template != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s=%s", templateFlagName, formatFlagName, format.String())
+		}
+		if flags.PrintTemplate {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s=%s", printTemplateFlagName, formatFlagName, format.String())
+		}
+	}
+	if flags.PrintTemplate {
+		if flags.Template != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s and --%s cannot be used together", templateFlagName, printTemplateFlagName)
+		}
+		template, err := bufdocs.DefaultTemplate(format)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(container.Stdout(), template)
+		return err
+	}
+	var renderOptions []bufdocs.RenderOption
+	if flags.Template != "" {
+		data, err := os.ReadFile(flags.Template)
+		if err != nil {
+			return err
+		}
+		renderOptions = append(renderOptions, bufdocs.RenderWithTemplate(string(data)))
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	// Imports are kept in the image so that the custom options they define can
+	// be resolved.
+	image, err := controller.GetImage(
+		ctx,
+		input,
+	)
+	if err != nil {
+		return err
+	}
+	docs, err := bufdocs.NewDocs(image)
+	if err != nil {
+		return err
+	}
+	return bufdocs.Render(container.Stdout(), format, docs, renderOptions...)
+}
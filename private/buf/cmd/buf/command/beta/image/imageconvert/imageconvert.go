@@ -0,0 +1,158 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imageconvert
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
+)
+
+const (
+	asFileDescriptorSetFlagName           = "as-file-descriptor-set"
+	excludeSourceInfoFlagName             = "exclude-source-info"
+	excludeSourceRetentionOptionsFlagName = "exclude-source-retention-options"
+	outputFlagName                        = "output"
+	outputFlagShortName                   = "o"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <image>",
+		Short: "Convert between Buf images and FileDescriptorSets",
+		Long: `Convert a Buf image or google.protobuf.FileDescriptorSet into another encoding or form.
+
+The first argument is the image or FileDescriptorSet to convert, which must be one of format ` + buffetch.MessageFormatsString + `.
+This defaults to "-", which reads from stdin, if no argument is specified. The format of the input and
+of --output is determined by their extensions, or can be set with #format, as with buf build:
+
+    $ buf beta image convert image.binpb -o image.json
+    $ buf beta image convert image.binpb -o descriptors.binpb --as-file-descriptor-set --exclude-source-info
+
+Images are wire compatible with FileDescriptorSets. Nothing is removed unless requested, so source info,
+source-retention options, and the Buf extensions on each file, which record whether a file is an import,
+its module, and its unused dependencies, are all kept by default. Only --as-file-descriptor-set removes
+the Buf extensions.
+
+A FileDescriptorSet has no record of which files are imports, so every file of a FileDescriptorSet that is
+converted into an image is treated as a target file.`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	AsFileDescriptorSet           bool
+	ExcludeSourceInfo             bool
+	ExcludeSourceRetentionOptions bool
+	Output                        string
+
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindAsFileDescriptorSet(flagSet, &f.AsFileDescriptorSet, asFileDescriptorSetFlagName)
+	bufcli.BindExcludeSourceInfo(flagSet, &f.ExcludeSourceInfo, excludeSourceInfoFlagName)
+	flagSet.BoolVar(
+		&f.ExcludeSourceRetentionOptions,
+		excludeSourceRetentionOptionsFlagName,
+		false,
+		"Exclude options whose retention is source",
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		fmt.Sprintf(
+			`Required. The output location for the converted image. Must be one of format %s`,
+			buffetch.MessageFormatsString,
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateRequiredFlag(outputFlagName, flags.Output); err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, "-")
+	if err != nil {
+		return err
+	}
+	// Only images and FileDescriptorSets can be converted, so refuse to build
+	// sources and modules.
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	if _, ok := ref.(buffetch.MessageRef); !ok {
+		return appcmd.NewInvalidArgumentErrorf(
+			"%q is not an image or FileDescriptorSet, must be one of format %s",
+			input,
+			buffetch.MessageFormatsString,
+		)
+	}
+	controller, err := bufcli.NewController(container)
+	if err != nil {
+		return err
+	}
+	image, err := controller.GetImage(
+		ctx,
+		input,
+		bufctl.WithImageExcludeSourceInfo(flags.ExcludeSourceInfo),
+	)
+	if err != nil {
+		return err
+	}
+	if flags.ExcludeSourceRetentionOptions {
+		image, err = bufimageutil.StripSourceRetentionOptions(image)
+		if err != nil {
+			return err
+		}
+	}
+	return controller.PutImage(
+		ctx,
+		flags.Output,
+		image,
+		bufctl.WithImageAsFileDescriptorSet(flags.AsFileDescriptorSet),
+	)
+}
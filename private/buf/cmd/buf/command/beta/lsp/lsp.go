@@ -26,9 +26,9 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buflsp"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
-	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/ioext"
 	"github.com/bufbuild/buf/private/pkg/wasm"
 	"github.com/spf13/pflag"
@@ -83,6 +83,9 @@ func run(
 	flags *flags,
 ) (retErr error) {
 	bufcli.WarnBetaCommand(ctx, container)
+	if err := bufcli.ValidateResultFormatNotStreaming(ctx); err != nil {
+		return err
+	}
 
 	transport, err := dial(container, flags)
 	if err != nil {
@@ -98,6 +101,11 @@ func run(
 		return err
 	}
 
+	depsDirPath, err := bufcli.CreateLSPDepsCacheDir(container)
+	if err != nil {
+		return err
+	}
+
 	controller, err := bufcli.NewController(container)
 	if err != nil {
 		return err
@@ -114,16 +122,38 @@ func run(
 	defer func() {
 		retErr = multierr.Append(retErr, wasmRuntime.Close(ctx))
 	}()
+	pluginRunner, err := bufcli.NewPluginRunner(container)
+	if err != nil {
+		return err
+	}
+	httpClient, err := bufcli.NewHTTPClient(container)
+	if err != nil {
+		return err
+	}
+	pluginResolver, err := bufpluginmanifest.NewResolverForEnv(container)
+	if err != nil {
+		return err
+	}
+	pluginRunnerInterceptors, err := bufcli.NewPluginRunnerInterceptors(container)
+	if err != nil {
+		return err
+	}
 	checkClient, err := bufcheck.NewClient(
 		container.Logger(),
-		bufcheck.NewRunnerProvider(command.NewRunner(), wasmRuntime),
+		bufcheck.NewRunnerProvider(
+			pluginRunner,
+			wasmRuntime,
+			bufcheck.RunnerProviderWithHTTPClient(httpClient),
+			bufcheck.RunnerProviderWithPluginResolver(pluginResolver),
+			bufcheck.RunnerProviderWithInterceptors(pluginRunnerInterceptors...),
+		),
 		bufcheck.ClientWithStderr(container.Stderr()),
 	)
 	if err != nil {
 		return err
 	}
 
-	conn, err := buflsp.Serve(ctx, wktBucket, container, controller, checkClient, jsonrpc2.NewStream(transport))
+	conn, err := buflsp.Serve(ctx, wktBucket, depsDirPath, container, controller, checkClient, jsonrpc2.NewStream(transport))
 	if err != nil {
 		return err
 	}
@@ -36,7 +36,6 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
 	"github.com/bufbuild/buf/private/pkg/netext"
-	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
@@ -259,7 +258,7 @@ func run(
 		nextRevision = latestPluginResp.Msg.Plugin.Revision + 1
 		currentImageDigest = latestPluginResp.Msg.Plugin.ContainerImageDigest
 	}
-	machine, err := netrc.GetMachineForName(container, pluginConfig.Name.Remote())
+	machine, err := bufcli.GetMachineForName(ctx, container, pluginConfig.Name.Remote())
 	if err != nil {
 		return err
 	}
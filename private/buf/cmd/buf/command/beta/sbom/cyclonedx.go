@@ -0,0 +1,177 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+)
+
+// The CycloneDX 1.5 JSON format is specified at https://cyclonedx.org/docs/1.5/json.
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string              `json:"timestamp"`
+	Tools     cycloneDXTools      `json:"tools"`
+	Component *cycloneDXComponent `json:"component,omitempty"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef             string                       `json:"bom-ref,omitempty"`
+	Type               string                       `json:"type"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	ExternalReferences []cycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type cycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+func newCycloneDXDocument(bom *bom) *cycloneDXDocument {
+	document := &cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + bom.id.String(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: bom.created.Format(time.RFC3339),
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{
+					{
+						Type:    "application",
+						Name:    "buf",
+						Version: bufcli.Version,
+					},
+				},
+			},
+		},
+		Components:   []cycloneDXComponent{},
+		Dependencies: []cycloneDXDependency{},
+	}
+	// The bom-ref of each module is its name, which is unique within the SBOM.
+	var targetRefs []string
+	for _, module := range bom.modules {
+		component := cycloneDXComponent{
+			BOMRef:  module.name,
+			Type:    "library",
+			Name:    module.name,
+			Version: module.commitID,
+			Properties: []cycloneDXProperty{
+				{
+					Name:  "buf:digest",
+					Value: module.digest,
+				},
+			},
+		}
+		if module.url != "" {
+			component.ExternalReferences = []cycloneDXExternalReference{
+				{
+					Type: "distribution",
+					URL:  module.url,
+				},
+			}
+		}
+		if module.isTarget {
+			targetRefs = append(targetRefs, module.name)
+		}
+		document.Components = append(document.Components, component)
+		document.Dependencies = append(
+			document.Dependencies,
+			cycloneDXDependency{
+				Ref:       module.name,
+				DependsOn: append([]string{}, module.directDeps...),
+			},
+		)
+	}
+	// If there is a single target module, it is the subject of the SBOM, which is
+	// recorded in the metadata rather than as a component. Target modules are
+	// always first.
+	if len(targetRefs) == 1 {
+		document.Metadata.Component = &document.Components[0]
+		document.Components = document.Components[1:]
+	}
+	for _, plugin := range bom.plugins {
+		component := cycloneDXComponent{
+			BOMRef:  "plugin:" + plugin.pluginType + ":" + plugin.name,
+			Type:    "application",
+			Name:    plugin.name,
+			Version: plugin.version,
+			Properties: []cycloneDXProperty{
+				{
+					Name:  "buf:plugin_type",
+					Value: plugin.pluginType,
+				},
+			},
+		}
+		if plugin.version != "" {
+			component.BOMRef += ":" + plugin.version
+		}
+		if plugin.revision != 0 {
+			component.BOMRef += ":" + strconv.Itoa(plugin.revision)
+			component.Properties = append(
+				component.Properties,
+				cycloneDXProperty{
+					Name:  "buf:plugin_revision",
+					Value: strconv.Itoa(plugin.revision),
+				},
+			)
+		}
+		if plugin.url != "" {
+			component.ExternalReferences = []cycloneDXExternalReference{
+				{
+					Type: "distribution",
+					URL:  plugin.url,
+				},
+			}
+		}
+		document.Components = append(document.Components, component)
+		document.Dependencies = append(
+			document.Dependencies,
+			cycloneDXDependency{
+				Ref:       component.BOMRef,
+				DependsOn: []string{},
+			},
+		)
+	}
+	return document
+}
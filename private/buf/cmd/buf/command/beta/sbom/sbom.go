@@ -0,0 +1,394 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatFlagName          = "format"
+	templateFlagName        = "template"
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+
+	spdxFormatString      = "spdx"
+	cyclonedxFormatString = "cyclonedx"
+
+	// sourceDateEpochEnvKey is the environment variable defined by
+	// https://reproducible-builds.org/specs/source-date-epoch to set the time
+	// that is recorded in build outputs.
+	sourceDateEpochEnvKey = "SOURCE_DATE_EPOCH"
+)
+
+var allFormatStrings = []string{
+	spdxFormatString,
+	cyclonedxFormatString,
+}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Print a software bill of materials for a module and its dependencies",
+		Long: `Print a software bill of materials (SBOM) in the SPDX 2.3 or CycloneDX 1.5 JSON format.
+
+The SBOM covers the modules of the input, every dependency of those modules with its commit
+and digest, and the plugins used to generate code from them. Plugins are read from the
+generation template given with --template, or from the buf.gen.yaml file in the current
+directory if it exists, as with buf generate:
+
+    $ buf beta sbom --format=cyclonedx > sbom.cdx.json
+    $ buf beta sbom proto --format=spdx --template=buf.gen.yaml > sbom.spdx.json
+
+Module digests are b5 digests, which neither format has a checksum algorithm for, so digests
+are recorded as SPDX external references and CycloneDX properties named "buf:digest".
+
+The SBOM records the current time as its creation time. Set SOURCE_DATE_EPOCH to a Unix time
+to record that time instead, so that the same inputs always result in the same SBOM.
+` + bufcli.GetSourceOrModuleLong(`the source or module to print the SBOM for`),
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format          string
+	Template        string
+	ErrorFormat     string
+	DisableSymlinks bool
+
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		spdxFormatString,
+		fmt.Sprintf(
+			"The SBOM format to print. Must be one of %s",
+			stringutil.SliceToString(allFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Template,
+		templateFlagName,
+		"",
+		`The generation template file or data to read the plugins used for generation from. Must be in either YAML or JSON format. Defaults to the buf.gen.yaml file in the current directory if it exists`,
+	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	if !slices.Contains(allFormatStrings, flags.Format) {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s: unknown format %q, must be one of %s",
+			formatFlagName,
+			flags.Format,
+			stringutil.SliceToString(allFormatStrings),
+		)
+	}
+	created, err := getCreated(container)
+	if err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return err
+	}
+	modules, err := getModules(workspace)
+	if err != nil {
+		return err
+	}
+	bufGenYAMLFile, err := readBufGenYAMLFile(ctx, flags.Template)
+	if err != nil {
+		return err
+	}
+	var plugins []*plugin
+	if bufGenYAMLFile != nil {
+		plugins = getPlugins(bufGenYAMLFile.GenerateConfig().GeneratePluginConfigs())
+	}
+	bom := newBOM(input, created, modules, plugins)
+	var document any
+	switch flags.Format {
+	case spdxFormatString:
+		document = newSPDXDocument(bom)
+	case cyclonedxFormatString:
+		document = newCycloneDXDocument(bom)
+	}
+	encoder := json.NewEncoder(container.Stdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// bom is the contents of an SBOM, independent of its format.
+type bom struct {
+	// name is the name of the SBOM, which is the name of the only target
+	// module if there is one, and otherwise the input.
+	name string
+	// id uniquely identifies the contents of the SBOM.
+	id      uuid.UUID
+	created time.Time
+	// modules are the target modules, followed by their dependencies.
+	modules []*module
+	plugins []*plugin
+}
+
+func newBOM(input string, created time.Time, modules []*module, plugins []*plugin) *bom {
+	name := input
+	if targetModules := getTargetModules(modules); len(targetModules) == 1 {
+		name = targetModules[0].name
+	}
+	// The ID is derived from the contents, rather than being random, so that
+	// the same inputs always result in the same SBOM.
+	var idBuilder strings.Builder
+	_, _ = idBuilder.WriteString(name)
+	for _, module := range modules {
+		_, _ = fmt.Fprintf(&idBuilder, "\nmodule %s %s %s", module.name, module.commitID, module.digest)
+	}
+	for _, plugin := range plugins {
+		_, _ = fmt.Fprintf(&idBuilder, "\nplugin %s %s", plugin.name, plugin.version)
+	}
+	return &bom{
+		name:    name,
+		id:      uuid.NewSHA1(uuid.NameSpaceURL, []byte(idBuilder.String())),
+		created: created,
+		modules: modules,
+		plugins: plugins,
+	}
+}
+
+type module struct {
+	name string
+	// commitID is the dashless commit ID, or empty if the module is not from the BSR.
+	commitID string
+	digest   string
+	// url is the URL of the module on the BSR, or empty if the module has no name.
+	url      string
+	isTarget bool
+	// directDeps are the names of the direct dependencies of the module.
+	directDeps []string
+}
+
+func getModules(moduleSet bufmodule.ModuleSet) ([]*module, error) {
+	var modules []*module
+	for _, bufModule := range moduleSet.Modules() {
+		digest, err := bufModule.Digest(bufmodule.DigestTypeB5)
+		if err != nil {
+			return nil, err
+		}
+		directModuleDeps, err := bufmodule.ModuleDirectModuleDeps(bufModule)
+		if err != nil {
+			return nil, err
+		}
+		directDeps := make([]string, len(directModuleDeps))
+		for i, directModuleDep := range directModuleDeps {
+			directDeps[i] = moduleName(directModuleDep)
+		}
+		slices.Sort(directDeps)
+		module := &module{
+			name:       moduleName(bufModule),
+			digest:     digest.String(),
+			isTarget:   bufModule.IsTarget(),
+			directDeps: directDeps,
+		}
+		if commitID := bufModule.CommitID(); commitID != uuid.Nil {
+			module.commitID = uuidutil.ToDashless(commitID)
+		}
+		if moduleFullName := bufModule.ModuleFullName(); moduleFullName != nil {
+			module.url = "https://" + moduleFullName.String()
+		}
+		modules = append(modules, module)
+	}
+	slices.SortFunc(
+		modules,
+		func(one *module, two *module) int {
+			// Target modules first.
+			if one.isTarget != two.isTarget {
+				if one.isTarget {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(one.name, two.name)
+		},
+	)
+	return modules, nil
+}
+
+// moduleName returns the ModuleFullName of the module if it has one, and
+// otherwise its OpaqueID.
+func moduleName(bufModule bufmodule.Module) string {
+	if moduleFullName := bufModule.ModuleFullName(); moduleFullName != nil {
+		return moduleFullName.String()
+	}
+	return bufModule.OpaqueID()
+}
+
+type plugin struct {
+	name string
+	// version is the version of a remote plugin, or empty if it is not known.
+	version string
+	// pluginType is one of "remote", "local", or "protoc_builtin".
+	pluginType string
+	// revision is the revision of a remote plugin, or zero if it is not known.
+	revision int
+	// url is the URL of a remote plugin on the BSR, or empty for other plugins.
+	url string
+}
+
+func getPlugins(generatePluginConfigs []bufconfig.GeneratePluginConfig) []*plugin {
+	var plugins []*plugin
+	seen := make(map[string]struct{})
+	for _, generatePluginConfig := range generatePluginConfigs {
+		plugin := &plugin{
+			name: generatePluginConfig.Name(),
+		}
+		switch generatePluginConfig.Type() {
+		case bufconfig.GeneratePluginConfigTypeRemote:
+			plugin.pluginType = "remote"
+			plugin.name, plugin.version, _ = strings.Cut(generatePluginConfig.Name(), ":")
+			plugin.revision = generatePluginConfig.Revision()
+			plugin.url = "https://" + plugin.name
+		case bufconfig.GeneratePluginConfigTypeProtocBuiltin:
+			plugin.pluginType = "protoc_builtin"
+		default:
+			plugin.pluginType = "local"
+		}
+		// The same plugin may be used to generate to more than one output.
+		key := plugin.pluginType + " " + plugin.name + " " + plugin.version + " " + strconv.Itoa(plugin.revision)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		plugins = append(plugins, plugin)
+	}
+	return plugins
+}
+
+// readBufGenYAMLFile reads the generation template, as buf generate does.
+//
+// Returns nil if no template was given and there is no buf.gen.yaml file in
+// the current directory.
+func readBufGenYAMLFile(ctx context.Context, template string) (bufconfig.BufGenYAMLFile, error) {
+	switch filepath.Ext(template) {
+	case "":
+		if template != "" {
+			return bufconfig.ReadBufGenYAMLFile(strings.NewReader(template))
+		}
+		bufGenYAMLFile, err := bufcli.GetBufGenYAMLFileForDirPath(ctx, ".")
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return bufGenYAMLFile, nil
+	case ".yaml", ".yml", ".json":
+		file, err := os.Open(template)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return bufconfig.ReadBufGenYAMLFile(file)
+	default:
+		return bufconfig.ReadBufGenYAMLFile(strings.NewReader(template))
+	}
+}
+
+func getCreated(container appext.Container) (time.Time, error) {
+	sourceDateEpoch := container.Env(sourceDateEpochEnvKey)
+	if sourceDateEpoch == "" {
+		return time.Now().UTC().Truncate(time.Second), nil
+	}
+	seconds, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: must be a Unix time in seconds", sourceDateEpochEnvKey, sourceDateEpoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func getTargetModules(modules []*module) []*module {
+	var targetModules []*module
+	for _, module := range modules {
+		if module.isTarget {
+			targetModules = append(targetModules, module)
+		}
+	}
+	return targetModules
+}
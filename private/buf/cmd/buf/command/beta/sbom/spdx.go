@@ -0,0 +1,201 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+)
+
+// The SPDX 2.3 JSON format is specified at https://spdx.github.io/spdx-spec/v2.3.
+
+const (
+	spdxNoAssertion = "NOASSERTION"
+	spdxDocumentID  = "SPDXRef-DOCUMENT"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func newSPDXDocument(bom *bom) *spdxDocument {
+	document := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              bom.name,
+		DocumentNamespace: "https://buf.build/spdx/" + bom.id.String(),
+		CreationInfo: spdxCreationInfo{
+			Created:  bom.created.Format(time.RFC3339),
+			Creators: []string{"Tool: buf-" + bufcli.Version},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	spdxIDs := newSPDXIDs()
+	moduleNameToSPDXID := make(map[string]string, len(bom.modules))
+	for _, module := range bom.modules {
+		moduleNameToSPDXID[module.name] = spdxIDs.newID("Module", module.name)
+	}
+	var targetSPDXIDs []string
+	for _, module := range bom.modules {
+		spdxID := moduleNameToSPDXID[module.name]
+		spdxPackage := spdxPackage{
+			SPDXID:                spdxID,
+			Name:                  module.name,
+			VersionInfo:           module.commitID,
+			DownloadLocation:      spdxNoAssertion,
+			PrimaryPackagePurpose: "SOURCE",
+			ExternalRefs: []spdxExternalRef{
+				{
+					// SPDX has no checksum algorithm for b5 digests, which are SHAKE256
+					// digests of the module files and dependencies.
+					ReferenceCategory: "OTHER",
+					ReferenceType:     "buf-digest",
+					ReferenceLocator:  module.digest,
+				},
+			},
+		}
+		if module.url != "" {
+			spdxPackage.DownloadLocation = module.url
+		}
+		document.Packages = append(document.Packages, spdxPackage)
+		if module.isTarget {
+			targetSPDXIDs = append(targetSPDXIDs, spdxID)
+			document.Relationships = append(
+				document.Relationships,
+				spdxRelationship{
+					SPDXElementID:      spdxDocumentID,
+					RelationshipType:   "DESCRIBES",
+					RelatedSPDXElement: spdxID,
+				},
+			)
+		}
+		for _, directDep := range module.directDeps {
+			document.Relationships = append(
+				document.Relationships,
+				spdxRelationship{
+					SPDXElementID:      spdxID,
+					RelationshipType:   "DEPENDS_ON",
+					RelatedSPDXElement: moduleNameToSPDXID[directDep],
+				},
+			)
+		}
+	}
+	for _, plugin := range bom.plugins {
+		spdxID := spdxIDs.newID("Plugin", plugin.name)
+		spdxPackage := spdxPackage{
+			SPDXID:                spdxID,
+			Name:                  plugin.name,
+			VersionInfo:           plugin.version,
+			DownloadLocation:      spdxNoAssertion,
+			PrimaryPackagePurpose: "APPLICATION",
+			Comment:               "buf " + strings.ReplaceAll(plugin.pluginType, "_", " ") + " plugin",
+		}
+		if plugin.revision != 0 {
+			spdxPackage.Comment += ", revision " + strconv.Itoa(plugin.revision)
+		}
+		if plugin.url != "" {
+			spdxPackage.DownloadLocation = plugin.url
+		}
+		document.Packages = append(document.Packages, spdxPackage)
+		for _, targetSPDXID := range targetSPDXIDs {
+			document.Relationships = append(
+				document.Relationships,
+				spdxRelationship{
+					SPDXElementID:      spdxID,
+					RelationshipType:   "BUILD_TOOL_OF",
+					RelatedSPDXElement: targetSPDXID,
+				},
+			)
+		}
+	}
+	return document
+}
+
+// spdxIDs creates unique SPDX identifiers.
+type spdxIDs struct {
+	seen map[string]struct{}
+}
+
+func newSPDXIDs() *spdxIDs {
+	return &spdxIDs{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// newID returns a new identifier for the element with the given kind and name.
+//
+// SPDX identifiers may only contain letters, numbers, "." and "-", so all other
+// characters of the name are replaced with "-", and a number is appended if
+// this results in an identifier that was already returned.
+func (s *spdxIDs) newID(kind string, name string) string {
+	id := "SPDXRef-" + kind + "-" + strings.Map(
+		func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+				return r
+			}
+			return '-'
+		},
+		name,
+	)
+	uniqueID := id
+	for i := 2; ; i++ {
+		if _, ok := s.seen[uniqueID]; !ok {
+			break
+		}
+		uniqueID = id + "-" + strconv.Itoa(i)
+	}
+	s.seen[uniqueID] = struct{}{}
+	return uniqueID
+}
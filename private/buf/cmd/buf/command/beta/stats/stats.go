@@ -21,16 +21,18 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/buf/bufprint"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/protostat"
-	"github.com/bufbuild/buf/private/pkg/protostat/protostatstorage"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 const (
 	formatFlagName          = "format"
+	errorFormatFlagName     = "error-format"
 	disableSymlinksFlagName = "disable-symlinks"
 )
 
@@ -41,10 +43,18 @@ func NewCommand(
 ) *appcmd.Command {
 	flags := newFlags()
 	return &appcmd.Command{
-		Use:   name + " <source>",
-		Short: "Get statistics for a given source or module",
-		Long:  bufcli.GetSourceOrModuleLong(`the source or module to get statistics for`),
-		Args:  appcmd.MaximumNArgs(1),
+		Use:   name + " <input>",
+		Short: "Get statistics for a given input",
+		Long: bufcli.GetInputLong(`the source, module, or image to get statistics for`) + `
+
+Statistics are reported for the target files of the input, and do not include imports. They
+include the number of files, packages, messages, fields, enums, enum values, extensions,
+services, methods, and deprecated elements, the number of fields of each type, and the number
+of times each extension is set as an option. Track these over time to follow the growth of a
+schema:
+
+    $ buf beta stats buf.build/acme/weather --format=json`,
+		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appext.Container) error {
 				return run(ctx, container, flags)
@@ -56,6 +66,7 @@ func NewCommand(
 
 type flags struct {
 	Format          string
+	ErrorFormat     string
 	DisableSymlinks bool
 
 	// special
@@ -73,6 +84,15 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		bufprint.FormatText.String(),
 		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
 	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 }
@@ -93,27 +113,33 @@ func run(
 	controller, err := bufcli.NewController(
 		container,
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
 	)
 	if err != nil {
 		return err
 	}
-	workspace, err := controller.GetWorkspace(
+	// Imports are kept in the image so that the extensions they define can be
+	// resolved when counting the uses of custom options.
+	image, err := controller.GetImage(
 		ctx,
 		input,
 	)
 	if err != nil {
 		return err
 	}
-	stats, err := protostat.GetStats(
-		ctx,
-		protostatstorage.NewFileWalker(
-			bufmodule.ModuleReadBucketToStorageReadBucket(
-				bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFilesForTargetModules(
-					workspace,
-				),
-			),
-		),
-	)
+	resolver := image.Resolver()
+	var fileDescriptors []protoreflect.FileDescriptor
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := resolver.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return err
+		}
+		fileDescriptors = append(fileDescriptors, fileDescriptor)
+	}
+	stats, err := protostat.GetFileDescriptorStats(resolver, fileDescriptors...)
 	if err != nil {
 		return err
 	}
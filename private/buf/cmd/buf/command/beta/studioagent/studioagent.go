@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufstudioagent"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
@@ -154,6 +155,9 @@ func run(
 	container appext.Container,
 	flags *flags,
 ) error {
+	if err := bufcli.ValidateResultFormatNotStreaming(ctx); err != nil {
+		return err
+	}
 	// CA cert pool is optional. If it is nil, TLS uses the host's root CA set.
 	var rootCAConfig *tls.Config
 	var err error
@@ -62,6 +62,9 @@ func NewCommand(
 			},
 		),
 		BindFlags: flags.Bind,
+		CompleteFlags: map[string]appcmd.CompletionFunc{
+			typeFlagName: bufcli.NewTypeCompletionFunc(builder, false),
+		},
 	}
 }
 
@@ -16,10 +16,10 @@ package configinit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
-	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
@@ -28,6 +28,7 @@ import (
 
 const (
 	documentationCommentsFlagName = "doc"
+	interactiveFlagName           = "interactive"
 	outDirPathFlagName            = "output"
 	outDirPathFlagShortName       = "o"
 	uncommentFlagName             = "uncomment"
@@ -49,7 +50,11 @@ func NewCommand(
 
 If a buf.yaml already exists, this command will not overwrite it, and will produce an error.
 
-The effects of this command may change over time - this command may initialize i.e. buf.gen.yaml files in the future.`,
+If --interactive is set, this command will ask for the module name, the directory that contains
+the .proto files, the lint and breaking change categories, and the languages to generate code for,
+and will also write a buf.gen.yaml file and an example .proto file.
+
+The effects of this command may change over time.`,
 		Args:       appcmd.MaximumNArgs(1),
 		Deprecated: deprecated,
 		Hidden:     hidden,
@@ -63,7 +68,8 @@ The effects of this command may change over time - this command may initialize i
 }
 
 type flags struct {
-	OutDirPath string
+	OutDirPath  string
+	Interactive bool
 
 	// Hidden.
 	DocumentationComments bool
@@ -87,6 +93,12 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		".",
 		`The directory to write the configuration files to`,
 	)
+	flagSet.BoolVar(
+		&f.Interactive,
+		interactiveFlagName,
+		false,
+		`Prompt for the module name, layout, lint and breaking change categories, and languages to generate code for`,
+	)
 	if f.bindOldFlags {
 		// TODO FUTURE: Bring this flag back in future versions if we decide it's important.
 		// We're not breaking anyone by not actually producing comments for now.
@@ -123,63 +135,40 @@ func run(
 		return fmt.Errorf("buf.yaml already exists in directory %s, will not overwrite", flags.OutDirPath)
 	}
 
-	fileVersion := bufconfig.FileVersionV2
-	var moduleFullName bufmodule.ModuleFullName
+	var moduleFullNameString string
 	if container.NumArgs() > 0 {
-		moduleFullName, err = bufmodule.ParseModuleFullName(container.Arg(0))
+		moduleFullNameString = container.Arg(0)
+	}
+	initConfig := newDefaultInitConfig()
+	if flags.Interactive {
+		initConfig, err = promptInitConfig(
+			func(prompt string, defaultValue string) (string, error) {
+				return bufcli.PromptUserWithDefault(container, prompt, defaultValue)
+			},
+			moduleFullNameString,
+		)
+		if err != nil {
+			if errors.Is(err, bufcli.ErrNotATTY) {
+				return errors.New("cannot initialize interactively from a non-TTY device")
+			}
+			return err
+		}
+	} else if moduleFullNameString != "" {
+		initConfig.ModuleFullName, err = bufmodule.ParseModuleFullName(moduleFullNameString)
 		if err != nil {
 			return err
 		}
 	}
-
-	moduleConfig, err := bufconfig.NewModuleConfig(
-		".",
-		moduleFullName,
-		// The default (empty) value for rootToIncludes and rootToExcludes only has key ".".
-		map[string][]string{
-			".": {},
-		},
-		map[string][]string{
-			".": {},
-		},
-		bufconfig.NewLintConfig(
-			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
-				fileVersion,
-				[]string{"STANDARD"},
-				false,
-			),
-			"",
-			false,
-			false,
-			false,
-			"",
-			// We actually want comment ignores enabled by default
-			true,
-		),
-		bufconfig.NewBreakingConfig(
-			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
-				fileVersion,
-				[]string{"FILE"},
-				false,
-			),
-			false,
-		),
-	)
+	filePaths, err := writeInitFiles(ctx, flags.OutDirPath, initConfig)
 	if err != nil {
 		return err
 	}
-	bufYAMLFile, err := bufconfig.NewBufYAMLFile(
-		fileVersion,
-		[]bufconfig.ModuleConfig{
-			moduleConfig,
-		},
-		nil,
-		nil,
-		bufconfig.BufYAMLFileWithIncludeDocsLink(),
-	)
-	if err != nil {
-		return err
+	if flags.Interactive {
+		for _, filePath := range filePaths {
+			if _, err := fmt.Fprintf(container.Stdout(), "Wrote %s\n", filePath); err != nil {
+				return err
+			}
+		}
 	}
-
-	return bufcli.PutBufYAMLFileForDirPath(ctx, flags.OutDirPath, bufYAMLFile)
+	return nil
 }
@@ -0,0 +1,397 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+)
+
+const (
+	defaultInteractiveModuleDirPath = "proto"
+	exampleProtoFileName            = "example.proto"
+)
+
+var (
+	lintCategories     = []string{"MINIMAL", "BASIC", "STANDARD"}
+	breakingCategories = []string{"FILE", "PACKAGE", "WIRE_JSON", "WIRE"}
+
+	// languageToRemotePluginName maps the languages that can be selected to the
+	// remote plugin used to generate code for them.
+	languageToRemotePluginName = map[string]string{
+		"cpp":        "buf.build/protocolbuffers/cpp",
+		"csharp":     "buf.build/protocolbuffers/csharp",
+		"go":         "buf.build/protocolbuffers/go",
+		"java":       "buf.build/protocolbuffers/java",
+		"kotlin":     "buf.build/protocolbuffers/kotlin",
+		"objc":       "buf.build/protocolbuffers/objc",
+		"php":        "buf.build/protocolbuffers/php",
+		"python":     "buf.build/protocolbuffers/python",
+		"ruby":       "buf.build/protocolbuffers/ruby",
+		"swift":      "buf.build/apple/swift",
+		"typescript": "buf.build/bufbuild/es",
+	}
+	// languageToRemotePluginOpt maps languages to the options for their remote plugin.
+	languageToRemotePluginOpt = map[string][]string{
+		"go":         {"paths=source_relative"},
+		"typescript": {"target=ts"},
+	}
+)
+
+// initConfig is the configuration for the files written by init.
+type initConfig struct {
+	// ModuleFullName is the name of the module. Optional.
+	ModuleFullName bufmodule.ModuleFullName
+	// ModuleDirPath is the directory of the module, relative to the output directory.
+	ModuleDirPath string
+	// LintCategory is the lint category to use.
+	LintCategory string
+	// BreakingCategory is the breaking change category to use.
+	BreakingCategory string
+	// Languages are the languages to write a buf.gen.yaml file for.
+	//
+	// If empty, no buf.gen.yaml file is written.
+	Languages []string
+	// GoPackagePrefix is the go_package prefix for managed mode.
+	//
+	// Only set if Languages contains go.
+	GoPackagePrefix string
+	// WriteExample says to write an example .proto file to the module.
+	WriteExample bool
+}
+
+func newDefaultInitConfig() *initConfig {
+	return &initConfig{
+		ModuleDirPath:    ".",
+		LintCategory:     "STANDARD",
+		BreakingCategory: "FILE",
+	}
+}
+
+// promptInitConfig builds an initConfig by prompting with the given function.
+//
+// The prompt function returns the defaultValue if the user provides an empty response.
+func promptInitConfig(
+	prompt func(prompt string, defaultValue string) (string, error),
+	defaultModuleFullNameString string,
+) (*initConfig, error) {
+	initConfig := newDefaultInitConfig()
+	moduleFullNameString, err := prompt(
+		withDefault("Module name, such as buf.build/acme/weather, or empty for none", defaultModuleFullNameString),
+		defaultModuleFullNameString,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if moduleFullNameString != "" {
+		initConfig.ModuleFullName, err = bufmodule.ParseModuleFullName(moduleFullNameString)
+		if err != nil {
+			return nil, err
+		}
+	}
+	moduleDirPath, err := prompt(
+		withDefault("Directory to put your .proto files in, relative to buf.yaml", defaultInteractiveModuleDirPath),
+		defaultInteractiveModuleDirPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	initConfig.ModuleDirPath, err = normalpath.NormalizeAndValidate(filepath.ToSlash(moduleDirPath))
+	if err != nil {
+		return nil, err
+	}
+	initConfig.LintCategory, err = promptChoice(prompt, "Lint category", lintCategories, initConfig.LintCategory)
+	if err != nil {
+		return nil, err
+	}
+	initConfig.BreakingCategory, err = promptChoice(prompt, "Breaking change category", breakingCategories, initConfig.BreakingCategory)
+	if err != nil {
+		return nil, err
+	}
+	languagesString, err := prompt(
+		withDefault(
+			fmt.Sprintf(
+				"Languages to generate code for, comma-separated (%s)",
+				strings.Join(slicesext.MapKeysToSortedSlice(languageToRemotePluginName), ", "),
+			),
+			"none",
+		),
+		"none",
+	)
+	if err != nil {
+		return nil, err
+	}
+	if languagesString != "none" {
+		for _, language := range strings.Split(languagesString, ",") {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if language == "" || slices.Contains(initConfig.Languages, language) {
+				continue
+			}
+			if _, ok := languageToRemotePluginName[language]; !ok {
+				return nil, fmt.Errorf("unknown language %q", language)
+			}
+			initConfig.Languages = append(initConfig.Languages, language)
+		}
+	}
+	if slices.Contains(initConfig.Languages, "go") {
+		defaultGoPackagePrefix := "example.com/gen/go"
+		if initConfig.ModuleFullName != nil {
+			defaultGoPackagePrefix = fmt.Sprintf(
+				"github.com/%s/%s/gen/go",
+				initConfig.ModuleFullName.Owner(),
+				initConfig.ModuleFullName.Name(),
+			)
+		}
+		initConfig.GoPackagePrefix, err = prompt(
+			withDefault("Go import path prefix for the generated code", defaultGoPackagePrefix),
+			defaultGoPackagePrefix,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	writeExample, err := prompt(withDefault("Write an example .proto file (y/n)", "y"), "y")
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(writeExample) {
+	case "y", "yes":
+		initConfig.WriteExample = true
+	case "n", "no":
+	default:
+		return nil, fmt.Errorf("expected y or n, got %q", writeExample)
+	}
+	return initConfig, nil
+}
+
+// writeInitFiles writes the files for the initConfig to the directory.
+//
+// Returns the paths of the files written. Returns an error without writing any files
+// if a buf.yaml or buf.gen.yaml file would be overwritten.
+func writeInitFiles(ctx context.Context, dirPath string, initConfig *initConfig) ([]string, error) {
+	bufYAMLFile, err := newBufYAMLFile(initConfig)
+	if err != nil {
+		return nil, err
+	}
+	var bufGenYAMLFile bufconfig.BufGenYAMLFile
+	if len(initConfig.Languages) > 0 {
+		exists, err := bufcli.BufGenYAMLFileExistsForDirPath(ctx, dirPath)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("buf.gen.yaml already exists in directory %s, will not overwrite", dirPath)
+		}
+		bufGenYAMLFile, err = newBufGenYAMLFile(initConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := bufcli.PutBufYAMLFileForDirPath(ctx, dirPath, bufYAMLFile); err != nil {
+		return nil, err
+	}
+	filePaths := []string{filepath.Join(dirPath, "buf.yaml")}
+	if bufGenYAMLFile != nil {
+		if err := bufcli.PutBufGenYAMLFileForDirPath(ctx, dirPath, bufGenYAMLFile); err != nil {
+			return nil, err
+		}
+		filePaths = append(filePaths, filepath.Join(dirPath, "buf.gen.yaml"))
+	}
+	if initConfig.WriteExample {
+		examplePackage := getExamplePackage(initConfig.ModuleFullName)
+		exampleFilePath := filepath.Join(
+			dirPath,
+			filepath.FromSlash(initConfig.ModuleDirPath),
+			filepath.FromSlash(strings.ReplaceAll(examplePackage, ".", "/")),
+			exampleProtoFileName,
+		)
+		if _, err := os.Stat(exampleFilePath); err == nil || !errors.Is(err, fs.ErrNotExist) {
+			// Never overwrite an existing file.
+			return filePaths, err
+		}
+		if err := os.MkdirAll(filepath.Dir(exampleFilePath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(exampleFilePath, []byte(getExampleProtoFileContent(examplePackage)), 0644); err != nil {
+			return nil, err
+		}
+		filePaths = append(filePaths, exampleFilePath)
+	}
+	return filePaths, nil
+}
+
+func newBufYAMLFile(initConfig *initConfig) (bufconfig.BufYAMLFile, error) {
+	fileVersion := bufconfig.FileVersionV2
+	moduleConfig, err := bufconfig.NewModuleConfig(
+		initConfig.ModuleDirPath,
+		initConfig.ModuleFullName,
+		// The default (empty) value for rootToIncludes and rootToExcludes only has key ".".
+		map[string][]string{
+			".": {},
+		},
+		map[string][]string{
+			".": {},
+		},
+		bufconfig.NewLintConfig(
+			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
+				fileVersion,
+				[]string{initConfig.LintCategory},
+				false,
+			),
+			"",
+			false,
+			false,
+			false,
+			"",
+			// We actually want comment ignores enabled by default
+			true,
+		),
+		bufconfig.NewBreakingConfig(
+			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
+				fileVersion,
+				[]string{initConfig.BreakingCategory},
+				false,
+			),
+			false,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return bufconfig.NewBufYAMLFile(
+		fileVersion,
+		[]bufconfig.ModuleConfig{
+			moduleConfig,
+		},
+		nil,
+		nil,
+		bufconfig.BufYAMLFileWithIncludeDocsLink(),
+	)
+}
+
+func newBufGenYAMLFile(initConfig *initConfig) (bufconfig.BufGenYAMLFile, error) {
+	generatePluginConfigs := make([]bufconfig.GeneratePluginConfig, 0, len(initConfig.Languages))
+	for _, language := range initConfig.Languages {
+		generatePluginConfig, err := bufconfig.NewRemoteGeneratePluginConfig(
+			languageToRemotePluginName[language],
+			normalpath.Join("gen", language),
+			languageToRemotePluginOpt[language],
+			false,
+			false,
+			0,
+		)
+		if err != nil {
+			return nil, err
+		}
+		generatePluginConfigs = append(generatePluginConfigs, generatePluginConfig)
+	}
+	var managedOverrideRules []bufconfig.ManagedOverrideRule
+	if initConfig.GoPackagePrefix != "" {
+		managedOverrideRule, err := bufconfig.NewManagedOverrideRuleForFileOption(
+			"",
+			"",
+			bufconfig.FileOptionGoPackagePrefix,
+			initConfig.GoPackagePrefix,
+		)
+		if err != nil {
+			return nil, err
+		}
+		managedOverrideRules = append(managedOverrideRules, managedOverrideRule)
+	}
+	generateConfig, err := bufconfig.NewGenerateConfig(
+		false,
+		generatePluginConfigs,
+		bufconfig.NewGenerateManagedConfig(true, nil, managedOverrideRules),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return bufconfig.NewBufGenYAMLFile(bufconfig.FileVersionV2, generateConfig, nil), nil
+}
+
+// promptChoice prompts for one of the choices, which must be upper case.
+func promptChoice(
+	prompt func(prompt string, defaultValue string) (string, error),
+	name string,
+	choices []string,
+	defaultChoice string,
+) (string, error) {
+	choice, err := prompt(
+		withDefault(fmt.Sprintf("%s (%s)", name, strings.Join(choices, ", ")), defaultChoice),
+		defaultChoice,
+	)
+	if err != nil {
+		return "", err
+	}
+	choice = strings.ToUpper(choice)
+	if !slices.Contains(choices, choice) {
+		return "", fmt.Errorf("unknown %s %q, expected one of %s", strings.ToLower(name), choice, strings.Join(choices, ", "))
+	}
+	return choice, nil
+}
+
+// getExamplePackage returns the package for the example .proto file.
+//
+// The package is derived from the module name, so that it is unique across modules.
+func getExamplePackage(moduleFullName bufmodule.ModuleFullName) string {
+	if moduleFullName == nil {
+		return "example.v1"
+	}
+	return toPackageComponent(moduleFullName.Owner()) + "." + toPackageComponent(moduleFullName.Name()) + ".v1"
+}
+
+// toPackageComponent converts a module owner or name to a valid package component.
+func toPackageComponent(value string) string {
+	value = strings.ToLower(strings.ReplaceAll(value, "-", "_"))
+	if value != "" && value[0] >= '0' && value[0] <= '9' {
+		value = "_" + value
+	}
+	return value
+}
+
+func getExampleProtoFileContent(examplePackage string) string {
+	return `syntax = "proto3";
+
+package ` + examplePackage + `;
+
+// Example is an example message.
+//
+// Replace this file with your own .proto files.
+message Example {
+  // The name of the example.
+  string name = 1;
+}
+`
+}
+
+func withDefault(prompt string, defaultValue string) string {
+	if defaultValue == "" {
+		return prompt + ": "
+	}
+	return fmt.Sprintf("%s [%s]: ", prompt, defaultValue)
+}
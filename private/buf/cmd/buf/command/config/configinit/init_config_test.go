@@ -0,0 +1,103 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configinit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptInitConfig(t *testing.T) {
+	t.Parallel()
+	initConfig, err := promptInitConfig(newTestPrompt(t, "", "", "basic", "", "go, typescript,go", "", "n"), "buf.build/acme/weather-api")
+	require.NoError(t, err)
+	assert.Equal(t, "buf.build/acme/weather-api", initConfig.ModuleFullName.String())
+	assert.Equal(t, "proto", initConfig.ModuleDirPath)
+	assert.Equal(t, "BASIC", initConfig.LintCategory)
+	assert.Equal(t, "FILE", initConfig.BreakingCategory)
+	assert.Equal(t, []string{"go", "typescript"}, initConfig.Languages)
+	assert.Equal(t, "github.com/acme/weather-api/gen/go", initConfig.GoPackagePrefix)
+	assert.False(t, initConfig.WriteExample)
+
+	initConfig, err = promptInitConfig(newTestPrompt(t, "", ".", "", "wire", "", ""), "")
+	require.NoError(t, err)
+	assert.Nil(t, initConfig.ModuleFullName)
+	assert.Equal(t, ".", initConfig.ModuleDirPath)
+	assert.Equal(t, "STANDARD", initConfig.LintCategory)
+	assert.Equal(t, "WIRE", initConfig.BreakingCategory)
+	assert.Empty(t, initConfig.Languages)
+	assert.True(t, initConfig.WriteExample)
+
+	_, err = promptInitConfig(newTestPrompt(t, "", "", "strict"), "")
+	require.Error(t, err)
+	_, err = promptInitConfig(newTestPrompt(t, "", "", "", "", "cobol"), "")
+	require.Error(t, err)
+	_, err = promptInitConfig(newTestPrompt(t, "", "../proto"), "")
+	require.Error(t, err)
+}
+
+func TestWriteInitFiles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	initConfig, err := promptInitConfig(newTestPrompt(t, "", "", "", "", "go", "", ""), "buf.build/acme/weather")
+	require.NoError(t, err)
+	dirPath := t.TempDir()
+	filePaths, err := writeInitFiles(ctx, dirPath, initConfig)
+	require.NoError(t, err)
+	exampleFilePath := filepath.Join(dirPath, "proto", "acme", "weather", "v1", "example.proto")
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "buf.yaml"),
+			filepath.Join(dirPath, "buf.gen.yaml"),
+			exampleFilePath,
+		},
+		filePaths,
+	)
+	data, err := os.ReadFile(filepath.Join(dirPath, "buf.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "path: proto")
+	assert.Contains(t, string(data), "name: buf.build/acme/weather")
+	data, err = os.ReadFile(filepath.Join(dirPath, "buf.gen.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "remote: buf.build/protocolbuffers/go")
+	assert.Contains(t, string(data), "value: github.com/acme/weather/gen/go")
+	data, err = os.ReadFile(exampleFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "package acme.weather.v1;")
+
+	// Existing files are never overwritten.
+	_, err = writeInitFiles(ctx, dirPath, initConfig)
+	require.Error(t, err)
+}
+
+// newTestPrompt returns a prompt function that returns the given responses in order,
+// or the default value for empty responses.
+func newTestPrompt(t *testing.T, responses ...string) func(string, string) (string, error) {
+	return func(prompt string, defaultValue string) (string, error) {
+		require.NotEmpty(t, responses, "unexpected prompt %q", prompt)
+		response := responses[0]
+		responses = responses[1:]
+		if response == "" {
+			return defaultValue, nil
+		}
+		return response, nil
+	}
+}
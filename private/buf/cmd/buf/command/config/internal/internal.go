@@ -24,9 +24,9 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
-	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
@@ -196,9 +196,31 @@ func lsRun(
 	defer func() {
 		retErr = multierr.Append(retErr, wasmRuntime.Close(ctx))
 	}()
+	pluginRunner, err := bufcli.NewPluginRunner(container)
+	if err != nil {
+		return err
+	}
+	httpClient, err := bufcli.NewHTTPClient(container)
+	if err != nil {
+		return err
+	}
+	pluginResolver, err := bufpluginmanifest.NewResolverForEnv(container)
+	if err != nil {
+		return err
+	}
+	pluginRunnerInterceptors, err := bufcli.NewPluginRunnerInterceptors(container)
+	if err != nil {
+		return err
+	}
 	client, err := bufcheck.NewClient(
 		container.Logger(),
-		bufcheck.NewRunnerProvider(command.NewRunner(), wasmRuntime),
+		bufcheck.NewRunnerProvider(
+			pluginRunner,
+			wasmRuntime,
+			bufcheck.RunnerProviderWithHTTPClient(httpClient),
+			bufcheck.RunnerProviderWithPluginResolver(pluginResolver),
+			bufcheck.RunnerProviderWithInterceptors(pluginRunnerInterceptors...),
+		),
 		bufcheck.ClientWithStderr(container.Stderr()),
 	)
 	if err != nil {
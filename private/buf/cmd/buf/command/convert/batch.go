@@ -0,0 +1,262 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/thread"
+)
+
+var (
+	// batchFileExts are the extensions of the files picked up when --batch is a directory.
+	batchFileExts = []string{
+		".bin",
+		".binpb",
+		".json",
+		".txtpb",
+		".yaml",
+	}
+	messageEncodingToExt = map[buffetch.MessageEncoding]string{
+		buffetch.MessageEncodingBinpb: ".binpb",
+		buffetch.MessageEncodingJSON:  ".json",
+		buffetch.MessageEncodingTxtpb: ".txtpb",
+		buffetch.MessageEncodingYAML:  ".yaml",
+	}
+	formatToMessageEncoding = map[string]buffetch.MessageEncoding{
+		"binpb": buffetch.MessageEncodingBinpb,
+		"json":  buffetch.MessageEncodingJSON,
+		"txtpb": buffetch.MessageEncodingTxtpb,
+		"yaml":  buffetch.MessageEncodingYAML,
+	}
+)
+
+type batchFile struct {
+	// path is the path of the payload file.
+	path string
+	// relPath is the path of the file relative to the batch root, used to lay out
+	// the output directory.
+	relPath    string
+	outputPath string
+	err        error
+}
+
+// runBatch converts every payload file matched by flags.Batch into flags.OutputDir.
+//
+// Each file is converted independently, so that one bad payload does not stop the
+// others, and all failures are reported at the end.
+func runBatch(
+	ctx context.Context,
+	container appext.Container,
+	controller bufctl.Controller,
+	schemaImage bufimage.Image,
+	flags *flags,
+	fromFunctionOptions []bufctl.FunctionOption,
+	toFunctionOptions []bufctl.FunctionOption,
+) error {
+	// Validated in validateFlags, and zero if --to-format is not set.
+	toMessageEncoding := formatToMessageEncoding[flags.ToFormat]
+	batchFiles, err := getBatchFiles(flags.Batch)
+	if err != nil {
+		return fmt.Errorf("--%s: %w", batchFlagName, err)
+	}
+	if len(batchFiles) == 0 {
+		return fmt.Errorf("--%s: no payload files found in %q", batchFlagName, flags.Batch)
+	}
+	// Different payload files can map to the same output file, for example foo.bin
+	// and foo.binpb, so we refuse to convert either rather than have one overwrite
+	// the other.
+	outputPathToBatchFile := make(map[string]*batchFile)
+	for _, batchFile := range batchFiles {
+		batchFileToMessageEncoding := toMessageEncoding
+		if batchFileToMessageEncoding == 0 {
+			batchFileToMessageEncoding, err = inverseEncoding(extToMessageEncoding(filepath.Ext(batchFile.path)))
+			if err != nil {
+				return err
+			}
+		}
+		batchFile.outputPath = filepath.Join(
+			flags.OutputDir,
+			strings.TrimSuffix(batchFile.relPath, filepath.Ext(batchFile.relPath))+messageEncodingToExt[batchFileToMessageEncoding],
+		)
+		if otherBatchFile, ok := outputPathToBatchFile[batchFile.outputPath]; ok {
+			batchFile.err = fmt.Errorf("output %s conflicts with %s", batchFile.outputPath, otherBatchFile.path)
+			if otherBatchFile.err == nil {
+				otherBatchFile.err = fmt.Errorf("output %s conflicts with %s", batchFile.outputPath, batchFile.path)
+			}
+			continue
+		}
+		outputPathToBatchFile[batchFile.outputPath] = batchFile
+	}
+	var jobs []func(context.Context) error
+	for _, batchFile := range batchFiles {
+		if batchFile.err != nil {
+			continue
+		}
+		jobs = append(jobs, func(ctx context.Context) error {
+			// Errors are recorded per file, so that a failure does not cancel the other jobs.
+			batchFile.err = convertBatchFile(
+				ctx,
+				controller,
+				schemaImage,
+				batchFile,
+				flags.Type,
+				fromFunctionOptions,
+				toFunctionOptions,
+			)
+			return nil
+		})
+	}
+	if err := thread.Parallelize(ctx, jobs); err != nil {
+		return err
+	}
+	var numFailed int
+	for _, batchFile := range batchFiles {
+		if batchFile.err != nil {
+			numFailed++
+			if _, err := fmt.Fprintf(container.Stderr(), "%s: %v\n", batchFile.path, batchFile.err); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := fmt.Fprintf(
+		container.Stderr(),
+		"Converted %d of %d files to %s.\n",
+		len(batchFiles)-numFailed,
+		len(batchFiles),
+		flags.OutputDir,
+	); err != nil {
+		return err
+	}
+	if numFailed > 0 {
+		return fmt.Errorf("%d of %d files failed to convert", numFailed, len(batchFiles))
+	}
+	return nil
+}
+
+func convertBatchFile(
+	ctx context.Context,
+	controller bufctl.Controller,
+	schemaImage bufimage.Image,
+	batchFile *batchFile,
+	typeName string,
+	fromFunctionOptions []bufctl.FunctionOption,
+	toFunctionOptions []bufctl.FunctionOption,
+) error {
+	message, _, err := controller.GetMessage(
+		ctx,
+		schemaImage,
+		batchFile.path,
+		typeName,
+		buffetch.MessageEncodingBinpb,
+		fromFunctionOptions...,
+	)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(batchFile.outputPath), 0755); err != nil {
+		return err
+	}
+	// The output encoding is determined by the extension of the output path.
+	return controller.PutMessage(
+		ctx,
+		schemaImage,
+		batchFile.outputPath,
+		message,
+		buffetch.MessageEncodingBinpb,
+		toFunctionOptions...,
+	)
+}
+
+// extToMessageEncoding returns the MessageEncoding that buffetch uses for a
+// payload file with the given extension.
+func extToMessageEncoding(ext string) buffetch.MessageEncoding {
+	switch ext {
+	case ".json":
+		return buffetch.MessageEncodingJSON
+	case ".txtpb":
+		return buffetch.MessageEncodingTxtpb
+	case ".yaml":
+		return buffetch.MessageEncodingYAML
+	default:
+		return buffetch.MessageEncodingBinpb
+	}
+}
+
+// getBatchFiles returns the payload files in the directory or matching the glob,
+// sorted by path.
+func getBatchFiles(dirOrGlob string) ([]*batchFile, error) {
+	fileInfo, err := os.Stat(dirOrGlob)
+	if err == nil && fileInfo.IsDir() {
+		var batchFiles []*batchFile
+		if err := filepath.WalkDir(dirOrGlob, func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if dirEntry.IsDir() || !slices.Contains(batchFileExts, filepath.Ext(path)) {
+				return nil
+			}
+			relPath, err := filepath.Rel(dirOrGlob, path)
+			if err != nil {
+				return err
+			}
+			batchFiles = append(batchFiles, &batchFile{path: path, relPath: relPath})
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return batchFiles, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	paths, err := filepath.Glob(dirOrGlob)
+	if err != nil {
+		return nil, err
+	}
+	// Output paths are relative to the directory before the first glob meta character,
+	// so that matches in different directories do not overwrite each other.
+	root := dirOrGlob
+	if index := strings.IndexAny(root, `*?[\`); index >= 0 {
+		root = filepath.Dir(root[:index+1])
+	}
+	batchFiles := make([]*batchFile, 0, len(paths))
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if fileInfo.IsDir() {
+			continue
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil, err
+		}
+		batchFiles = append(batchFiles, &batchFile{path: path, relPath: relPath})
+	}
+	return batchFiles, nil
+}
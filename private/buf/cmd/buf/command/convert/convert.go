@@ -42,6 +42,14 @@ const (
 	toFlagName              = "to"
 	validateFlagName        = "validate"
 	disableSymlinksFlagName = "disable-symlinks"
+	framingFlagName         = "framing"
+	inputFramingFlagName    = "input-framing"
+	selectFlagName          = "select"
+	batchFlagName           = "batch"
+	outputDirFlagName       = "output-dir"
+	toFormatFlagName        = "to-format"
+	unknownAnyFlagName      = "unknown-any"
+	canonicalFlagName       = "canonical"
 )
 
 // NewCommand returns a new Command.
@@ -52,9 +60,10 @@ func NewCommand(
 	flags := newFlags()
 	return &appcmd.Command{
 		Use:   name + " <input>",
-		Short: "Convert a message between binary, text, or JSON",
+		Short: "Convert a message between binary, JSON, text, or YAML",
 		Long: `
-Use an input proto to interpret a proto/json message and convert it to a different format.
+Use an input proto to interpret a binary, JSON, text format, or YAML message and convert it to a different format.
+Extensions and google.protobuf.Any values are resolved using the types in the input.
 
 Examples:
 
@@ -80,9 +89,55 @@ Redirect from stdin to <input>:
 
     $ buf build -o - | buf convert -#format=binpb --type buf.Foo --from=payload.json
 
+Round-trip a text format message through YAML:
+
+    $ buf convert example.proto --type=buf.Foo --from=config.txtpb --to=config.yaml
+    $ buf convert example.proto --type=buf.Foo --from=config.yaml --to=config.txtpb
+
 Use a module on the bsr:
 
     $ buf convert <buf.build/owner/repository> --type buf.Foo --from=payload.json
+
+Convert a stream of length-prefixed messages one message at a time, without reading the whole stream into memory:
+
+    $ cat messages.binpb | buf convert buf.proto --type buf.Foo --framing=varint > messages.jsonl
+
+With --framing, binary messages are delimited with the given framing and JSON messages are newline-delimited.
+
+Decode a framed log, such as a dump of a Kafka topic, with --input-framing. Only --from uses this framing, and
+binary output uses --framing, which defaults to varint:
+
+    $ buf convert buf.proto --type buf.Foo --from=topic.dump#format=binpb --input-framing=fixed32 > messages.jsonl
+
+Check messages against the protovalidate rules in the schema with --validate. With --framing, the command fails at
+the first invalid message, and reports its violations with the index of the message in the stream:
+
+    $ buf convert example.proto --type=buf.Foo --from=messages.jsonl#format=json --framing=varint --validate --to=/dev/null
+
+Output only part of the message with --select. Repeated fields take an index and map fields take a key:
+
+    $ buf convert example.proto --type=buf.Foo --from=payload.binpb --select='items[2].labels["env"]'
+
+If the selected value is not a message, it can only be written as JSON.
+
+Convert every payload file in a directory, or matching a glob, into an output directory with --batch.
+Each file's format is determined by its extension, and files that fail to convert are reported without
+stopping the others:
+
+    $ buf convert example.proto --type=buf.Foo --batch=payloads --output-dir=out --to-format=json
+    $ buf convert example.proto --type=buf.Foo --batch='payloads/*.binpb' --output-dir=out
+
+When writing JSON, YAML, or text, the contents of google.protobuf.Any fields are expanded using the
+type named by their type URL, which must be in the input. Use --unknown-any to leave out Any fields
+holding types that are not in the input, or to write their contents as bytes:
+
+    $ buf convert example.proto --type=buf.Foo --from=payload.binpb --unknown-any=passthrough
+
+Write JSON in the canonical form of RFC 8785 with --canonical. Object keys are sorted, numbers are written
+in a fixed format, and there is no whitespace, so the same message is always written as the same bytes,
+which makes the output suitable for diffing and hashing:
+
+    $ buf convert example.proto --type=buf.Foo --from=payload.binpb --to=-#format=json --canonical
 `,
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -91,6 +146,9 @@ Use a module on the bsr:
 			},
 		),
 		BindFlags: flags.Bind,
+		CompleteFlags: map[string]appcmd.CompletionFunc{
+			typeFlagName: bufcli.NewTypeCompletionFunc(builder, true),
+		},
 	}
 }
 
@@ -101,6 +159,14 @@ type flags struct {
 	To              string
 	Validate        bool
 	DisableSymlinks bool
+	Framing         string
+	InputFraming    string
+	Select          string
+	Batch           string
+	OutputDir       string
+	ToFormat        string
+	UnknownAny      string
+	Canonical       bool
 
 	// special
 	InputHashtag string
@@ -151,10 +217,82 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		validateFlagName,
 		false,
 		fmt.Sprintf(
-			`Validate the message specified with --%s by applying protovalidate rules to it. See https://github.com/bufbuild/protovalidate for more details.`,
+			`Validate the message specified with --%s by applying protovalidate rules to it, and report violations by field path. See https://github.com/bufbuild/protovalidate for more details.`,
 			fromFlagName,
 		),
 	)
+	flagSet.StringVar(
+		&f.Framing,
+		framingFlagName,
+		"",
+		fmt.Sprintf(
+			`Treat --%s and --%s as streams of messages, converting one message at a time. Binary messages are delimited with this framing, and JSON messages are newline-delimited. Must be one of %s`,
+			fromFlagName,
+			toFlagName,
+			stringutil.SliceToString(bufconvert.AllFramingStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.InputFraming,
+		inputFramingFlagName,
+		"",
+		fmt.Sprintf(
+			`Treat --%s as a stream of binary messages delimited with this framing, instead of --%s. Must be one of %s`,
+			fromFlagName,
+			framingFlagName,
+			stringutil.SliceToString(bufconvert.AllFramingStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Select,
+		selectFlagName,
+		"",
+		`Only output the value at this path within the message, such as foo.bar[2].baz. Repeated fields take an index in brackets, and map fields take a key`,
+	)
+	flagSet.StringVar(
+		&f.Batch,
+		batchFlagName,
+		"",
+		fmt.Sprintf(
+			`A directory or glob of payload files to convert in parallel instead of --%s. Requires --%s`,
+			fromFlagName,
+			outputDirFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.OutputDir,
+		outputDirFlagName,
+		"",
+		fmt.Sprintf(
+			`The directory to write the files converted with --%s to, keeping their relative paths`,
+			batchFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.ToFormat,
+		toFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format of the files converted with --%s. Defaults to json for binary files and binpb otherwise. Must be one of %s`,
+			batchFlagName,
+			buffetch.MessageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.UnknownAny,
+		unknownAnyFlagName,
+		"error",
+		fmt.Sprintf(
+			`How to write google.protobuf.Any fields holding types that are not in the input as JSON, YAML, or text: fail, leave them out, or write their contents as bytes. Must be one of %s`,
+			stringutil.SliceToString(bufconvert.AllUnknownAnyStrings),
+		),
+	)
+	flagSet.BoolVar(
+		&f.Canonical,
+		canonicalFlagName,
+		false,
+		`Write JSON in the canonical form of RFC 8785, with sorted object keys and fixed number formatting, so that the output is byte-stable. Only supported when writing JSON`,
+	)
 }
 
 func run(
@@ -166,6 +304,17 @@ func run(
 	if err != nil {
 		return err
 	}
+	if err := validateFlags(flags); err != nil {
+		return err
+	}
+	inputFraming, outputFraming, err := getFramings(flags)
+	if err != nil {
+		return err
+	}
+	unknownAny, err := bufconvert.ParseUnknownAny(flags.UnknownAny)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
 	controller, err := bufcli.NewController(
 		container,
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
@@ -215,6 +364,41 @@ func run(
 	if flags.Validate {
 		fromFunctionOptions = append(fromFunctionOptions, bufctl.WithMessageValidation())
 	}
+	toFunctionOptions := []bufctl.FunctionOption{
+		bufctl.WithMessageUnknownAny(unknownAny),
+		bufctl.WithMessageJSONCanonical(flags.Canonical),
+	}
+	if flags.Select != "" {
+		toFunctionOptions = append(toFunctionOptions, bufctl.WithMessageSelectPath(flags.Select))
+	}
+	if flags.Batch != "" {
+		return runBatch(
+			ctx,
+			container,
+			controller,
+			schemaImage,
+			flags,
+			fromFunctionOptions,
+			toFunctionOptions,
+		)
+	}
+	if inputFraming != 0 {
+		if err := bufcli.ValidateResultFormatNotStreaming(ctx); err != nil {
+			return err
+		}
+		return controller.StreamMessages(
+			ctx,
+			schemaImage,
+			flags.From,
+			flags.To,
+			flags.Type,
+			inputFraming,
+			outputFraming,
+			buffetch.MessageEncodingBinpb,
+			inverseEncoding,
+			append(fromFunctionOptions, toFunctionOptions...)...,
+		)
+	}
 	fromMessage, fromMessageEncoding, err := controller.GetMessage(
 		ctx,
 		schemaImage,
@@ -236,12 +420,76 @@ func run(
 		flags.To,
 		fromMessage,
 		defaultToMessageEncoding,
+		toFunctionOptions...,
 	); err != nil {
 		return fmt.Errorf("--%s: %w", toFlagName, err)
 	}
 	return nil
 }
 
+// getFramings returns the Framings that delimit the messages read from --from and written
+// to --to. Both are zero if messages are not streamed.
+//
+// --input-framing only applies to --from, in which case binary output defaults to varint.
+func getFramings(flags *flags) (bufconvert.Framing, bufconvert.Framing, error) {
+	var outputFraming bufconvert.Framing
+	if flags.Framing != "" {
+		framing, err := bufconvert.ParseFraming(flags.Framing)
+		if err != nil {
+			return 0, 0, appcmd.WrapInvalidArgumentError(err)
+		}
+		outputFraming = framing
+	}
+	if flags.InputFraming == "" {
+		return outputFraming, outputFraming, nil
+	}
+	inputFraming, err := bufconvert.ParseFraming(flags.InputFraming)
+	if err != nil {
+		return 0, 0, appcmd.WrapInvalidArgumentError(err)
+	}
+	if outputFraming == 0 {
+		outputFraming = bufconvert.FramingVarint
+	}
+	return inputFraming, outputFraming, nil
+}
+
+func validateFlags(flags *flags) error {
+	if flags.Batch == "" {
+		if flags.OutputDir != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", outputDirFlagName, batchFlagName)
+		}
+		if flags.ToFormat != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", toFormatFlagName, batchFlagName)
+		}
+		return nil
+	}
+	if flags.OutputDir == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", batchFlagName, outputDirFlagName)
+	}
+	if _, ok := formatToMessageEncoding[flags.ToFormat]; flags.ToFormat != "" && !ok {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s: unknown format %q, must be one of %s",
+			toFormatFlagName,
+			flags.ToFormat,
+			buffetch.MessageFormatsString,
+		)
+	}
+	if flags.Canonical && flags.ToFormat != "json" {
+		return appcmd.NewInvalidArgumentErrorf("--%s with --%s requires --%s=json", canonicalFlagName, batchFlagName, toFormatFlagName)
+	}
+	if flags.From != "-" || flags.To != "-" || flags.Framing != "" || flags.InputFraming != "" {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s cannot be used with --%s, --%s, --%s, or --%s",
+			batchFlagName,
+			fromFlagName,
+			toFlagName,
+			framingFlagName,
+			inputFramingFlagName,
+		)
+	}
+	return nil
+}
+
 // inverseEncoding returns the opposite encoding of the provided encoding,
 // which will be the default output encoding for a given payload encoding.
 func inverseEncoding(encoding buffetch.MessageEncoding) (buffetch.MessageEncoding, error) {
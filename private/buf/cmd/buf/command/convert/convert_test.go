@@ -15,12 +15,17 @@
 package convert
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConvertDefaultInputBin(t *testing.T) {
@@ -158,6 +163,99 @@ func TestConvertFromStdinTxtpbYAML(t *testing.T) {
 		"-#format=yaml",
 	)
 }
+func TestConvertStreamVarint(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdoutStdinFile(
+		t,
+		testNewCommand,
+		0,
+		`{"one":"55"}
+{}
+{"one":"7"}`,
+		nil,
+		"testdata/convert/bin_json/payloads.varint.binpb",
+
+		"--type",
+		"buf.Foo",
+		"--framing",
+		"varint",
+	)
+}
+func TestConvertStreamConnect(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdoutStdinFile(
+		t,
+		testNewCommand,
+		0,
+		`{"one":"55"}
+{}
+{"one":"7"}`,
+		nil,
+		"testdata/convert/bin_json/payloads.connect.binpb",
+
+		"--type",
+		"buf.Foo",
+		"--framing",
+		"connect",
+	)
+}
+func TestConvertStreamInputFramingFixed32(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdoutStdinFile(
+		t,
+		testNewCommand,
+		0,
+		`{"one":"55"}
+{}
+{"one":"7"}`,
+		nil,
+		"testdata/convert/bin_json/payloads.fixed32.binpb",
+		"--type",
+		"buf.Foo",
+		"--input-framing",
+		"fixed32",
+	)
+}
+
+func TestConvertStreamInputFramingRecordIO(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdoutFile(
+		t,
+		testNewCommand,
+		0,
+		"testdata/convert/bin_json/payloads.varint.binpb",
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--from",
+		"testdata/convert/bin_json/payloads.recordio.binpb",
+		"--to",
+		"-#format=binpb",
+		"--input-framing",
+		"recordio",
+	)
+}
+
+func TestConvertStreamTxtpb(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStderr(
+		t,
+		testNewCommand,
+		1,
+		`"-#format=txtpb": only binpb and json messages can be streamed`,
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--from",
+		"testdata/convert/bin_json/payloads.varint.binpb",
+		"--to",
+		"-#format=txtpb",
+		"--framing",
+		"varint",
+	)
+}
 func TestConvertDiscardedStdin(t *testing.T) {
 	t.Parallel()
 	appcmdtesting.RunCommandExitCodeStdout(
@@ -400,6 +498,160 @@ func TestConvertWKTImport(t *testing.T) {
 	)
 }
 
+func TestConvertExtensionTxtpbYAML(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		testNewCommand,
+		0,
+		`name: foo
+'[buf.priority]': 3`,
+		nil,
+		nil,
+		"testdata/convert/extension",
+		"--type",
+		"buf.Config",
+		"--from",
+		"testdata/convert/extension/config.txtpb",
+		"--to",
+		"-#format=yaml",
+	)
+}
+
+func TestConvertExtensionYAMLTxtpb(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		testNewCommand,
+		0,
+		`name: "foo"
+[buf.priority]: 3`,
+		nil,
+		nil,
+		"testdata/convert/extension",
+		"--type",
+		"buf.Config",
+		"--from",
+		"testdata/convert/extension/config.yaml",
+		"--to",
+		"-#format=txtpb",
+	)
+}
+
+func TestConvertSelect(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		testNewCommand,
+		0,
+		`"55"`,
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--from",
+		"testdata/convert/bin_json/payload.binpb",
+		"--select",
+		"one",
+	)
+}
+
+func TestConvertSelectNotMessageBinpb(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStderr(
+		t,
+		testNewCommand,
+		1,
+		`--to: "one" does not select a message and can only be written as json`,
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--from",
+		"testdata/convert/bin_json/payload.json",
+		"--select",
+		"one",
+	)
+}
+
+func TestConvertCanonical(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		testNewCommand,
+		0,
+		`{"a":[0.5,0,1e-7],"b":1e+21,"c":{"x":null,"y":"café"}}`,
+		nil,
+		strings.NewReader(`{"c": {"y": "caf\u00e9", "x": null}, "b": 1e21, "a": [0.50, -0, 0.0000001]}`),
+		"--type",
+		"google.protobuf.Struct",
+		"--from",
+		"-#format=json",
+		"--to",
+		"-#format=json",
+		"--canonical",
+	)
+}
+
+func TestConvertCanonicalNotJSON(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStderr(
+		t,
+		testNewCommand,
+		1,
+		`--to: canonical output is only supported for json`,
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--from",
+		"testdata/convert/bin_json/payload.json",
+		"--canonical",
+	)
+}
+
+func TestConvertBatch(t *testing.T) {
+	t.Parallel()
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	copyTestFile(t, "testdata/convert/bin_json/payload.binpb", filepath.Join(inputDir, "a.binpb"))
+	copyTestFile(t, "testdata/convert/bin_json/payload.json", filepath.Join(inputDir, "nested", "b.json"))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "c.json"), []byte(`{"one":`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "README.md"), []byte(`not a payload`), 0600))
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		testNewCommand,
+		1,
+		[]string{
+			filepath.Join(inputDir, "c.json") + ": proto:",
+			fmt.Sprintf("Converted 2 of 3 files to %s.", outputDir),
+			"1 of 3 files failed to convert",
+		},
+		nil,
+		nil,
+		"--type",
+		"buf.Foo",
+		"--batch",
+		inputDir,
+		"--output-dir",
+		outputDir,
+		"--to-format",
+		"yaml",
+	)
+	for _, path := range []string{"a.yaml", filepath.Join("nested", "b.yaml")} {
+		data, err := os.ReadFile(filepath.Join(outputDir, path))
+		require.NoError(t, err)
+		assert.Equal(t, "one: \"55\"\n", string(data))
+	}
+}
+
+func copyTestFile(t *testing.T, from string, to string) {
+	data, err := os.ReadFile(from)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(to), 0755))
+	require.NoError(t, os.WriteFile(to, data, 0600))
+}
+
 func testNewCommand(use string) *appcmd.Command {
 	return NewCommand("convert", appext.NewBuilder("convert"))
 }
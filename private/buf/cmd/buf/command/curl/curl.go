@@ -15,6 +15,7 @@
 package curl
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/tls"
@@ -27,6 +28,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -35,11 +37,13 @@ import (
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufcurl"
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/thread"
 	"github.com/bufbuild/buf/private/pkg/verbose"
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
@@ -57,9 +61,11 @@ const (
 	reflectFlagName         = "reflect"
 	reflectHeaderFlagName   = "reflect-header"
 	reflectProtocolFlagName = "reflect-protocol"
+	noReflectCacheFlagName  = "no-reflect-cache"
 
 	// Protocol/transport flags
 	protocolFlagName            = "protocol"
+	grpcWebTextFlagName         = "grpcweb-text"
 	unixSocketFlagName          = "unix-socket"
 	http2PriorKnowledgeFlagName = "http2-prior-knowledge"
 	http3FlagName               = "http3"
@@ -68,6 +74,8 @@ const (
 	keyFlagName           = "key"
 	certFlagName          = "cert"
 	certFlagShortName     = "E"
+	certTypeFlagName      = "cert-type"
+	passFlagName          = "pass"
 	caCertFlagName        = "cacert"
 	serverNameFlagName    = "servername"
 	insecureFlagName      = "insecure"
@@ -82,6 +90,11 @@ const (
 	keepAliveFlagName      = "keepalive-time"
 	connectTimeoutFlagName = "connect-timeout"
 
+	// Retry flags
+	retryFlagName        = "retry"
+	retryBackoffFlagName = "retry-backoff"
+	retryOnFlagName      = "retry-on"
+
 	// Header and request body flags
 	userAgentFlagName      = "user-agent"
 	userAgentFlagShortName = "A"
@@ -90,20 +103,39 @@ const (
 	netrcFlagName          = "netrc"
 	netrcFlagShortName     = "n"
 	netrcFileFlagName      = "netrc-file"
+	useLoginFlagName       = "use-login"
 	headerFlagName         = "header"
 	headerFlagShortName    = "H"
 	dataFlagName           = "data"
 	dataFlagShortName      = "d"
+	noHalfCloseFlagName    = "no-half-close"
+	varFlagName            = "var"
+	dataPerLineFlagName    = "data-per-line"
+	requestsFlagName       = "requests"
+	parallelFlagName       = "parallel"
 
 	// Output flags
-	outputFlagName       = "output"
-	outputFlagShortName  = "o"
-	emitDefaultsFlagName = "emit-defaults"
-
+	outputFlagName           = "output"
+	outputFlagShortName      = "o"
+	emitDefaultsFlagName     = "emit-defaults"
+	enumsAsIntsFlagName      = "enums-as-ints"
+	bytesAsBase64URLFlagName = "bytes-as-base64url"
+	indentFlagName           = "indent"
+	canonicalFlagName        = "canonical"
+	outputFormatFlagName     = "output-format"
+	includeHeadersFlagName   = "include-headers"
+	trailersOnlyFlagName     = "trailers-only"
+
+	timingsFlagName      = "timings"
 	verboseFlagName      = "verbose"
 	verboseFlagShortName = "v"
 )
 
+const (
+	certTypePEM = "PEM"
+	certTypeP12 = "P12"
+)
+
 // NewCommand returns a new Command.
 func NewCommand(
 	name string,
@@ -128,7 +160,9 @@ unless the --http2-prior-knowledge flag is set. If https is used then HTTP/2 wil
 during protocol negotiation and HTTP 1.1 used only if the server does not support HTTP/2.
 
 The default RPC protocol used will be Connect. To use a different protocol (gRPC or gRPC-Web),
-use the --protocol flag. Note that the gRPC protocol cannot be used with HTTP 1.1.
+use the --protocol flag. Note that the gRPC protocol cannot be used with HTTP 1.1. gRPC-Web uses
+binary framing by default; use the --grpcweb-text flag for endpoints that require the base64 text
+framing ("application/grpc-web-text").
 
 The input request is specified via the -d or --data flag. If absent, an empty request is sent. If
 the flag value starts with an at-sign (@), then the rest of the flag value is interpreted as a
@@ -181,6 +215,30 @@ headers and request data are both in a heredoc:
     {"sentence": "If you were a fish, what of fish would you be?."}
     EOM
 
+Issue a bidirectional-streaming RPC interactively, where each line typed on stdin is sent as a
+request message as soon as it is entered, and each response message is printed as it arrives.
+The request stream is kept open after stdin is closed (with Ctrl-D) until the server closes the
+response stream:
+
+    $ buf curl --data @- --no-half-close                                     \
+		 https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Converse
+
+Issue a unary RPC with request data from a file that contains placeholders such as
+{"sentence": "${SENTENCE}"}, where the value of each placeholder is given with --var or read
+from the environment:
+
+    $ buf curl --data @request.json --var SENTENCE=hello                     \
+		 https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say
+
+Issue a unary RPC once for each line of a file, stopping at the first RPC that fails:
+
+    $ buf curl --data-per-line requests.jsonl                                \
+		 https://demo.connectrpc.com/connectrpc.eliza.v1.ElizaService/Say
+
+Invoke each of the RPCs listed in a file, four at a time, printing the result of each RPC:
+
+    $ buf curl --requests requests.yaml --parallel 4
+
 Note that server reflection (i.e. use of the --reflect flag) does not work with HTTP 1.1 since the
 protocol relies on bidirectional streaming. If server reflection is used, the assumed URL for the
 reflection service is the same as the given URL, but with the last two elements removed and
@@ -208,15 +266,18 @@ type flags struct {
 	Reflect         bool
 	ReflectHeaders  []string
 	ReflectProtocol string
+	NoReflectCache  bool
 
 	// Protocol details
 	Protocol            string
+	GRPCWebText         bool
 	UnixSocket          string
 	HTTP2PriorKnowledge bool
 	HTTP3               bool
 
 	// TLS
 	Key, Cert, CACert, ServerName string
+	CertType, Pass                string
 	Insecure                      bool
 	// TODO: CRLFile, CertStatus
 
@@ -228,18 +289,37 @@ type flags struct {
 	KeepAliveTimeSeconds  float64
 	ConnectTimeoutSeconds float64
 
+	// Retries
+	Retry        int
+	RetryBackoff time.Duration
+	RetryOn      []string
+
 	// Handling request and response data and metadata
-	UserAgent string
-	User      string
-	Netrc     bool
-	NetrcFile string
-	Headers   []string
-	Data      string
+	UserAgent   string
+	User        string
+	Netrc       bool
+	NetrcFile   string
+	UseLogin    bool
+	Headers     []string
+	Data        string
+	NoHalfClose bool
+	Vars        []string
+	DataPerLine string
+	Requests    string
+	Parallel    int
 
 	// Output options
-	Output       string
-	EmitDefaults bool
-
+	Output           string
+	EmitDefaults     bool
+	EnumsAsInts      bool
+	BytesAsBase64URL bool
+	Indent           int
+	Canonical        bool
+	OutputFormat     string
+	IncludeHeaders   bool
+	TrailersOnly     bool
+
+	Timings bool
 	Verbose bool
 
 	// so we can inquire about which flags present on command-line
@@ -264,7 +344,9 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 server reflection. The format of this argument is the same as for the <input> arguments to
 other buf sub-commands such as build and generate. It can indicate a directory, a file, a
 remote module in the Buf Schema Registry, or even standard in ("-") for feeding an image or
-file descriptor set to the command in a shell pipeline.
+file descriptor set to the command in a shell pipeline. A file that contains a file descriptor
+set or image, such as the output of "protoc --include_imports --descriptor_set_out", may be given
+regardless of its extension, and its format is detected from its contents.
 If multiple %s flags are present, they will be consulted in order to resolve service and type
 names. Setting this flags implies --%s=false unless a %s flag is explicitly present. If both
 %s and %s flags are in use, reflection will be used first and the schemas will be consulted
@@ -310,12 +392,33 @@ named "grpc.reflection.v1.ServerReflection" and "grpc.reflection.v1alpha.ServerR
 respectively`,
 	)
 
+	flagSet.BoolVar(
+		&f.NoReflectCache,
+		noReflectCacheFlagName,
+		false,
+		fmt.Sprintf(`By default, the descriptors downloaded with server reflection to invoke a method are
+cached on disk for %v, keyed by the server URL and service name, so that repeated
+invocations of the same service do not need to use server reflection. If this flag is set,
+the cache is neither read nor written. Set this flag after changing the schema of a
+server to make sure the latest schema is used`,
+			bufcurl.DefaultReflectionCacheTTL,
+		),
+	)
 	flagSet.StringVar(
 		&f.Protocol,
 		protocolFlagName,
 		connect.ProtocolConnect,
 		`The RPC protocol to use. This can be one of "grpc", "grpcweb", or "connect"`,
 	)
+	flagSet.BoolVar(
+		&f.GRPCWebText,
+		grpcWebTextFlagName,
+		false,
+		`This flag can be used with --protocol grpcweb to indicate that the base64 text framing
+of gRPC-Web should be used, with a content-type of "application/grpc-web-text". Without
+this, the binary framing is used. This is needed for endpoints that only support gRPC-Web
+text, such as some gateways for browser clients.`,
+	)
 	flagSet.StringVar(
 		&f.UnixSocket,
 		unixSocketFlagName,
@@ -364,6 +467,33 @@ will be disabled`,
 no limit if this flag is not present`,
 	)
 
+	flagSet.IntVar(
+		&f.Retry,
+		retryFlagName,
+		0,
+		fmt.Sprintf(`The maximum number of times to retry a failed RPC. Only RPCs that fail with one of the
+codes given with --%s are retried. Client-streaming and bidirectional-streaming RPCs are
+never retried, and server-streaming RPCs are only retried if no response message was received.
+If the server sends "grpc-retry-pushback-ms" metadata, the time to wait before retrying is
+taken from the metadata, and a negative value means that the RPC is not retried`,
+			retryOnFlagName,
+		),
+	)
+	flagSet.DurationVar(
+		&f.RetryBackoff,
+		retryBackoffFlagName,
+		time.Second,
+		`The time to wait before the first retry of a failed RPC. The time to wait doubles for
+each subsequent retry`,
+	)
+	flagSet.StringSliceVar(
+		&f.RetryOn,
+		retryOnFlagName,
+		codesToStrings(bufcurl.DefaultRetryableCodes),
+		`The codes of errors to retry, such as "unavailable" or "resource_exhausted". This flag
+may be specified more than once, or as a comma-separated list`,
+	)
+
 	flagSet.StringVar(
 		&f.Key,
 		keyFlagName,
@@ -386,6 +516,25 @@ present to provide the private key that corresponds to the given certificate`,
 			keyFlagName,
 		),
 	)
+	flagSet.StringVar(
+		&f.CertType,
+		certTypeFlagName,
+		certTypePEM,
+		fmt.Sprintf(`The type of the file given with --%s. Must be one of %q or %q. If %q, the file is a
+PKCS#12 bundle that contains both the certificate and the private key, and a --%s flag
+must not be present. PKCS#12 files must use the legacy encryption algorithms, as created
+with "openssl pkcs12 -export -legacy"`,
+			certFlagName, certTypePEM, certTypeP12, certTypeP12, keyFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.Pass,
+		passFlagName,
+		"",
+		fmt.Sprintf(`The password for a PKCS#12 file given with --%s and --%s %s`,
+			certFlagName, certTypeFlagName, certTypeP12,
+		),
+	)
 	flagSet.StringVar(
 		&f.CACert,
 		caCertFlagName,
@@ -481,6 +630,18 @@ or -%s flag. This is ignored if a --%s or -%s flag is provided that sets a heade
 			netrcFlagName, netrcFlagShortName, netrcFlagName, netrcFlagShortName, headerFlagName, headerFlagShortName,
 		),
 	)
+	flagSet.BoolVar(
+		&f.UseLogin,
+		useLoginFlagName,
+		false,
+		fmt.Sprintf(`If true, the Buf API token for the hostname in the URL is sent via a bearer authorization
+header. The token is the one that "buf registry login" stores for the hostname, or the value
+of the %s environment variable. The command fails if there is no token for the hostname.
+This flag cannot be used with the --%s, --%s, or --%s flags.
+This is ignored if a --%s or -%s flag is provided that sets a header named 'Authorization'.`,
+			bufconnect.TokenEnvKey, userFlagName, netrcFlagName, netrcFileFlagName, headerFlagName, headerFlagShortName,
+		),
+	)
 	flagSet.StringSliceVarP(
 		&f.Headers,
 		headerFlagName,
@@ -511,6 +672,59 @@ provided via stdin as a file descriptor set or image`,
 			headerFlagName, headerFlagShortName,
 		),
 	)
+	flagSet.StringArrayVar(
+		&f.Vars,
+		varFlagName,
+		nil,
+		fmt.Sprintf(`A variable for placeholders in the request data, in the form "name=value". This flag may
+be specified more than once to define multiple variables. Request data given with --%s,
+except when read from stdin, and with --%s may contain placeholders of the form "${name}",
+which are replaced with the value of the variable. Variables that are not defined with this
+flag are read from the environment, and it is an error for a placeholder to refer to a
+variable that is not defined. Values are inserted verbatim, so placeholders for strings
+must be within quotes, such as "${name}". Use "$${name}" for a literal "${name}"`,
+			dataFlagName, dataPerLineFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.DataPerLine,
+		dataPerLineFlagName,
+		"",
+		fmt.Sprintf(`Path to a file that contains one JSON request message per line. The RPC is invoked once
+for each non-empty line, in order, stopping at the first RPC that fails. If the path is "-"
+then the lines are read from stdin. This flag cannot be used with --%s or -%s`,
+			dataFlagName, dataFlagShortName,
+		),
+	)
+	flagSet.StringVar(
+		&f.Requests,
+		requestsFlagName,
+		"",
+		`Path to a YAML or JSON file that lists RPCs to invoke, instead of invoking the RPC for the
+URL argument. Each RPC has a "url", and optionally a "method", "headers", "data", and "name".
+If a "method" is given, the "url" is the base URL of the server. The "headers" are in the same
+form as the values of the --header flag, and are sent in addition to the headers given with
+that flag. The "data" is either a string in the same form as the value of the --data flag, or
+an object that is sent as JSON. The result of each RPC is printed, and the command fails if
+any RPC fails`,
+	)
+	flagSet.IntVar(
+		&f.Parallel,
+		parallelFlagName,
+		1,
+		fmt.Sprintf(`The maximum number of RPCs listed in the --%s file to invoke at the same time. The
+results are always printed in the order the RPCs are listed`,
+			requestsFlagName,
+		),
+	)
+	flagSet.BoolVar(
+		&f.NoHalfClose,
+		noHalfCloseFlagName,
+		false,
+		`By default, the request stream of a bidirectional streaming RPC is closed as soon as all
+request messages have been sent. If this flag is set, the request stream is kept open until
+the server closes the response stream. This flag only affects bidirectional streaming RPCs`,
+	)
 	flagSet.StringVarP(
 		&f.Output,
 		outputFlagName,
@@ -524,7 +738,74 @@ provided via stdin as a file descriptor set or image`,
 		false,
 		`Emit default values for JSON-encoded responses.`,
 	)
+	flagSet.BoolVar(
+		&f.EnumsAsInts,
+		enumsAsIntsFlagName,
+		false,
+		`Print enum values in JSON-encoded responses as numbers instead of names`,
+	)
+	flagSet.BoolVar(
+		&f.BytesAsBase64URL,
+		bytesAsBase64URLFlagName,
+		false,
+		`Print bytes values in JSON-encoded responses with the URL-safe base64 alphabet instead of
+the standard base64 alphabet`,
+	)
+	flagSet.IntVar(
+		&f.Indent,
+		indentFlagName,
+		2,
+		`The number of spaces to indent JSON-encoded responses with. If zero, each response message
+is printed on a single line`,
+	)
+	flagSet.BoolVar(
+		&f.Canonical,
+		canonicalFlagName,
+		false,
+		fmt.Sprintf(
+			`Print JSON-encoded responses in the canonical form of RFC 8785, with sorted object keys and
+fixed number formatting, so that the output is byte-stable. Each response message is printed on a
+single line. This flag cannot be used with --%s`,
+			indentFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.OutputFormat,
+		outputFormatFlagName,
+		bufcurl.OutputFormatJSON.String(),
+		fmt.Sprintf(
+			`The format to print response messages in. Must be one of %s. The binpb format writes
+the raw message bytes received from the server. For server-streaming and bidirectional-streaming
+RPCs, each message in the binpb format is prefixed with its size as a varint`,
+			stringutil.SliceToString(bufcurl.AllOutputFormatStrings),
+		),
+	)
+	flagSet.BoolVar(
+		&f.IncludeHeaders,
+		includeHeadersFlagName,
+		false,
+		fmt.Sprintf(
+			`Print the response headers before the response messages and the response trailers after
+the response messages, as "name: value" lines. This flag cannot be used with --%s binpb`,
+			outputFormatFlagName,
+		),
+	)
+	flagSet.BoolVar(
+		&f.TrailersOnly,
+		trailersOnlyFlagName,
+		false,
+		`Print the response trailers, as "name: value" lines, instead of the response messages`,
+	)
 
+	flagSet.BoolVar(
+		&f.Timings,
+		timingsFlagName,
+		false,
+		`Print a breakdown of the time taken by each HTTP request to stderr: the DNS lookup, TCP
+connect, and TLS handshake times, and the time to first byte and total time since the start
+of the request. For streaming RPCs, the time at which each response message is received is
+also printed`,
+	)
 	flagSet.BoolVarP(
 		&f.Verbose,
 		verboseFlagName,
@@ -560,8 +841,23 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 			"TLS flags (--%s, --%s, --%s, --%s, --%s) should not be used unless URL is secure (https)",
 			keyFlagName, certFlagName, caCertFlagName, insecureFlagName, serverNameFlagName)
 	}
-	if (f.Key != "") != (f.Cert != "") {
-		return fmt.Errorf("if one of --%s or --%s flags is used, both should be used (mutual TLS with a client certificate requires both)", keyFlagName, certFlagName)
+	switch f.CertType {
+	case certTypePEM:
+		if (f.Key != "") != (f.Cert != "") {
+			return fmt.Errorf("if one of --%s or --%s flags is used, both should be used (mutual TLS with a client certificate requires both)", keyFlagName, certFlagName)
+		}
+		if f.Pass != "" {
+			return fmt.Errorf("--%s can only be used with --%s %s", passFlagName, certTypeFlagName, certTypeP12)
+		}
+	case certTypeP12:
+		if f.Cert == "" {
+			return fmt.Errorf("--%s %s requires --%s", certTypeFlagName, certTypeP12, certFlagName)
+		}
+		if f.Key != "" {
+			return fmt.Errorf("--%s should not be used with --%s %s, the private key is read from the PKCS#12 file", keyFlagName, certTypeFlagName, certTypeP12)
+		}
+	default:
+		return fmt.Errorf("--%s value must be one of %q or %q", certTypeFlagName, certTypePEM, certTypeP12)
 	}
 	if f.Insecure && f.CACert != "" {
 		return fmt.Errorf("if --%s is set, --%s should not be set as it is unused", insecureFlagName, caCertFlagName)
@@ -582,6 +878,13 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 	if f.Netrc && f.NetrcFile != "" {
 		return fmt.Errorf("--%s and --%s flags are mutually exclusive; they may not both be specified", netrcFlagName, netrcFileFlagName)
 	}
+	if f.UseLogin {
+		for _, flagName := range []string{userFlagName, netrcFlagName, netrcFileFlagName} {
+			if f.flagSet.Changed(flagName) {
+				return fmt.Errorf("--%s and --%s flags are mutually exclusive; they may not both be specified", useLoginFlagName, flagName)
+			}
+		}
+	}
 
 	var schemaIsStdin bool
 	for _, schema := range f.Schemas {
@@ -619,6 +922,47 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 			"--%s value must be one of %q, %q, or %q",
 			protocolFlagName, connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb)
 	}
+	if f.GRPCWebText && f.Protocol != connect.ProtocolGRPCWeb {
+		return fmt.Errorf("--%s can only be used with --%s %s", grpcWebTextFlagName, protocolFlagName, connect.ProtocolGRPCWeb)
+	}
+
+	outputFormat, err := bufcurl.ParseOutputFormat(f.OutputFormat)
+	if err != nil {
+		return fmt.Errorf(
+			"--%s value must be one of %s",
+			outputFormatFlagName,
+			stringutil.SliceToHumanStringOrQuoted(bufcurl.AllOutputFormatStrings),
+		)
+	}
+	if f.IncludeHeaders && outputFormat == bufcurl.OutputFormatBinpb {
+		return fmt.Errorf("--%s cannot be used with --%s %s", includeHeadersFlagName, outputFormatFlagName, outputFormat)
+	}
+	if outputFormat != bufcurl.OutputFormatJSON {
+		for _, flagName := range []string{enumsAsIntsFlagName, bytesAsBase64URLFlagName, indentFlagName, canonicalFlagName} {
+			if f.flagSet.Changed(flagName) {
+				return fmt.Errorf("--%s can only be used with --%s %s", flagName, outputFormatFlagName, bufcurl.OutputFormatJSON)
+			}
+		}
+	}
+	if f.Indent < 0 {
+		return fmt.Errorf("--%s value must not be negative", indentFlagName)
+	}
+	if f.Canonical && f.flagSet.Changed(indentFlagName) {
+		return fmt.Errorf("--%s cannot be used with --%s", canonicalFlagName, indentFlagName)
+	}
+
+	if f.Retry < 0 {
+		return fmt.Errorf("--%s value must not be negative", retryFlagName)
+	}
+	if f.RetryBackoff < 0 {
+		return fmt.Errorf("--%s value must not be negative", retryBackoffFlagName)
+	}
+	if (f.flagSet.Changed(retryBackoffFlagName) || f.flagSet.Changed(retryOnFlagName)) && f.Retry == 0 {
+		return fmt.Errorf("--%s and --%s should not be used unless --%s is set", retryBackoffFlagName, retryOnFlagName, retryFlagName)
+	}
+	if _, err := stringsToCodes(f.RetryOn); err != nil {
+		return fmt.Errorf("invalid --%s value: %w", retryOnFlagName, err)
+	}
 
 	if f.NoKeepAlive && f.flagSet.Changed(keepAliveFlagName) {
 		return fmt.Errorf("--%s should not be specified if keepalive is disabled", keepAliveFlagName)
@@ -631,6 +975,21 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 		return fmt.Errorf("--%s value must be positive", connectTimeoutFlagName)
 	}
 
+	if f.DataPerLine != "" {
+		if f.Data != "" {
+			return fmt.Errorf("--%s and --%s flags are mutually exclusive; they may not both be specified", dataFlagName, dataPerLineFlagName)
+		}
+		if !hasURL || f.ListServices || f.ListMethods {
+			return fmt.Errorf("--%s can only be used when invoking an RPC", dataPerLineFlagName)
+		}
+		if f.DataPerLine == "-" && schemaIsStdin {
+			return fmt.Errorf("--%s and --%s flags cannot both indicate reading from stdin", schemaFlagName, dataPerLineFlagName)
+		}
+	}
+	if _, err := bufcurl.ParseTemplateVars(f.Vars); err != nil {
+		return fmt.Errorf("invalid --%s value: %w", varFlagName, err)
+	}
+
 	var dataFile string
 	if strings.HasPrefix(f.Data, "@") {
 		dataFile = strings.TrimPrefix(f.Data, "@")
@@ -661,10 +1020,13 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 
 func (f *flags) determineCredentials(
 	ctx context.Context,
-	container app.Container,
+	container appext.Container,
 	verbosePrinter verbose.Printer,
 	host string,
 ) (string, error) {
+	if f.UseLogin {
+		return determineLoginCredentials(ctx, container, verbosePrinter, host)
+	}
 	if f.User != "" {
 		// this flag overrides any netrc-related flags
 		parts := strings.SplitN(f.User, ":", 2)
@@ -728,6 +1090,8 @@ func (f *flags) getTLSConfig(authority string, printer verbose.Printer) (*tls.Co
 	return bufcurl.MakeVerboseTLSConfig(&bufcurl.TLSSettings{
 		KeyFile:             f.Key,
 		CertFile:            f.Cert,
+		CertFileIsPKCS12:    f.CertType == certTypeP12,
+		CertPassword:        f.Pass,
 		CACertFile:          f.CACert,
 		ServerName:          f.ServerName,
 		Insecure:            f.Insecure,
@@ -779,6 +1143,26 @@ func promptForPassword(ctx context.Context, container app.Container, prompt stri
 	}
 }
 
+// determineLoginCredentials returns the authorization header value for the
+// Buf API token of the host, as used by other buf commands for the remote.
+func determineLoginCredentials(
+	ctx context.Context,
+	container appext.Container,
+	verbosePrinter verbose.Printer,
+	host string,
+) (string, error) {
+	remote := host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		remote = hostname
+	}
+	token, err := bufcli.GetTokenForRemote(ctx, container, remote)
+	if err != nil {
+		return "", fmt.Errorf("--%s: %w", useLoginFlagName, err)
+	}
+	verbosePrinter.Printf("* Using the Buf API token for %s", remote)
+	return bufconnect.AuthenticationTokenPrefix + token, nil
+}
+
 func basicAuth(username, password string) string {
 	var buf bytes.Buffer
 	buf.WriteString(username)
@@ -855,6 +1239,9 @@ func parseEndpointURL(urlArg string) (service, method, baseURL string, err error
 }
 
 func run(ctx context.Context, container appext.Container, f *flags) (err error) {
+	if f.Requests != "" {
+		return runBatch(ctx, container, f)
+	}
 	var urlArg, host string
 	var isSecure bool
 	if container.NumArgs() != 0 {
@@ -881,7 +1268,15 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 
 	var verbosePrinter verbose.Printer = verbose.NopPrinter
 	if f.Verbose {
-		verbosePrinter = verbose.NewPrinter(container.Stderr(), container.AppName())
+		styler, err := bufcli.NewStyler(container, container.Stderr())
+		if err != nil {
+			return err
+		}
+		verbosePrinter = verbose.NewPrinter(
+			container.Stderr(),
+			container.AppName(),
+			verbose.PrinterWithStyler(styler),
+		)
 	}
 
 	var clientOptions []connect.ClientOption
@@ -955,6 +1350,28 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 			err = multierr.Append(err, dataReader.Close())
 		}
 	}()
+	templateVars, err := bufcurl.ParseTemplateVars(f.Vars)
+	if err != nil {
+		return err
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value := container.Env(name)
+		return value, value != ""
+	}
+	var requestData io.Reader = dataReader
+	if dataReader != nil && dataFileReference != "-" {
+		// Request data from stdin is not templated, since it may be a stream of
+		// messages that are sent as they are read.
+		data, err := io.ReadAll(dataReader)
+		if err != nil {
+			return bufcurl.ErrorHasFilename(err, dataSource)
+		}
+		data, err = bufcurl.ExpandTemplate(data, templateVars, lookupEnv)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dataSource, err)
+		}
+		requestData = bytes.NewReader(data)
+	}
 
 	makeTransportOnce := sync.OnceValues(func() (connect.HTTPClient, error) {
 		// We do this lazily since some commands don't need a transport, like listing
@@ -970,7 +1387,16 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		if err != nil {
 			return nil, err
 		}
-		return bufcurl.NewVerboseHTTPClient(roundTripper, verbosePrinter), nil
+		httpClient := bufcurl.NewVerboseHTTPClient(roundTripper, verbosePrinter)
+		if f.GRPCWebText {
+			// Wrap the verbose client so that the verbose output shows the
+			// text-encoded request and response.
+			httpClient = bufcurl.NewGRPCWebTextHTTPClient(httpClient)
+		}
+		if f.Timings {
+			httpClient = bufcurl.NewTimingHTTPClient(httpClient, container.Stderr())
+		}
+		return httpClient, nil
 	})
 
 	output := container.Stdout()
@@ -981,7 +1407,9 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		}
 	}
 
-	resolvers := make([]bufcurl.Resolver, 0, len(f.Schemas)+2)
+	resolvers := make([]bufcurl.Resolver, 0, len(f.Schemas)+3)
+	var reflectionCache bufcurl.ReflectionCache
+	var cachedReflectionResolver bufcurl.Resolver
 	if f.Reflect {
 		reflectHeaders, _, err := bufcurl.LoadHeaders(f.ReflectHeaders, "", requestHeaders)
 		if err != nil {
@@ -1011,6 +1439,19 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		if err != nil {
 			return err
 		}
+		// The cache is only used to invoke a method, and only when server reflection
+		// is the only source of descriptors other than the well-known types.
+		if !f.NoReflectCache && service != "" && len(f.Schemas) == 0 {
+			cacheDirPath, err := bufcli.CreateCurlReflectionCacheDir(container)
+			if err != nil {
+				return err
+			}
+			reflectionCache = bufcurl.NewReflectionCache(cacheDirPath, bufcurl.DefaultReflectionCacheTTL, verbosePrinter)
+			cachedReflectionResolver, err = reflectionCache.GetResolver(baseURL, service)
+			if err != nil {
+				return err
+			}
+		}
 		res, closeRes := bufcurl.NewServerReflectionResolver(ctx, transport, clientOptions, baseURL, reflectProtocol, reflectHeaders, verbosePrinter)
 		defer closeRes()
 		resolvers = append(resolvers, res)
@@ -1020,6 +1461,10 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		return err
 	}
 	for _, schema := range f.Schemas {
+		schema, err := bufcurl.SchemaInput(schema)
+		if err != nil {
+			return err
+		}
 		image, err := controller.GetImage(ctx, schema)
 		if err != nil {
 			return err
@@ -1032,7 +1477,11 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 	if err != nil {
 		return err
 	}
-	res := bufcurl.CombineResolvers(append(resolvers, wktResolver)...)
+	resolvers = append(resolvers, wktResolver)
+	res := bufcurl.CombineResolvers(resolvers...)
+	if cachedReflectionResolver != nil {
+		res = bufcurl.CombineResolvers(append([]bufcurl.Resolver{cachedReflectionResolver}, resolvers...)...)
+	}
 
 	switch {
 	case f.ListServices || f.ListMethods:
@@ -1073,15 +1522,86 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 	default:
 		// Invoke RPC
 		methodDescriptor, err := bufcurl.ResolveMethodDescriptor(res, service, method)
+		if err != nil && cachedReflectionResolver != nil {
+			// The schema of the server may have changed since the cache was written.
+			verbosePrinter.Printf("* Method not found in cached server reflection results, using server reflection")
+			cachedReflectionResolver = nil
+			res = bufcurl.CombineResolvers(resolvers...)
+			methodDescriptor, err = bufcurl.ResolveMethodDescriptor(res, service, method)
+		}
 		if err != nil {
 			return err
 		}
+		if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+			if err := bufcli.ValidateResultFormatNotStreaming(ctx); err != nil {
+				return err
+			}
+		}
+		// Only write the cache if it was not used, so that entries expire.
+		if reflectionCache != nil && cachedReflectionResolver == nil {
+			if serviceDescriptor, ok := methodDescriptor.Parent().(protoreflect.ServiceDescriptor); ok {
+				// The cache is an optimization, failing to write it should never fail the RPC.
+				if err := reflectionCache.Put(baseURL, serviceDescriptor); err != nil {
+					verbosePrinter.Printf("* Failed to cache server reflection results: %v", err)
+				}
+			}
+		}
 		transport, err := makeTransportOnce()
 		if err != nil {
 			return err
 		}
-		invoker := bufcurl.NewInvoker(container, verbosePrinter, methodDescriptor, res, f.EmitDefaults, transport, clientOptions, urlArg, output)
-		return invoker.Invoke(ctx, dataSource, dataReader, requestHeaders)
+		var invokerOptions []bufcurl.InvokerOption
+		if f.NoHalfClose {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithNoHalfClose())
+		}
+		outputFormat, err := bufcurl.ParseOutputFormat(f.OutputFormat)
+		if err != nil {
+			return err
+		}
+		invokerOptions = append(
+			invokerOptions,
+			bufcurl.InvokerWithOutputFormat(outputFormat),
+			bufcurl.InvokerWithJSONIndent(f.Indent),
+		)
+		if f.EnumsAsInts {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithEnumsAsInts())
+		}
+		if f.BytesAsBase64URL {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithBytesAsBase64URL())
+		}
+		if f.Canonical {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithJSONCanonical())
+		}
+		if f.Timings {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithTimings())
+		}
+		if f.IncludeHeaders {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithIncludeHeaders())
+		}
+		if f.TrailersOnly {
+			invokerOptions = append(invokerOptions, bufcurl.InvokerWithTrailersOnly())
+		}
+		if f.Retry > 0 {
+			retryableCodes, err := stringsToCodes(f.RetryOn)
+			if err != nil {
+				return err
+			}
+			invokerOptions = append(
+				invokerOptions,
+				bufcurl.InvokerWithRetryPolicy(
+					bufcurl.RetryPolicy{
+						MaxRetries:     f.Retry,
+						InitialBackoff: f.RetryBackoff,
+						RetryableCodes: retryableCodes,
+					},
+				),
+			)
+		}
+		invoker := bufcurl.NewInvoker(container, verbosePrinter, methodDescriptor, res, f.EmitDefaults, transport, clientOptions, urlArg, output, invokerOptions...)
+		if f.DataPerLine != "" {
+			return invokePerLine(ctx, container, invoker, f.DataPerLine, requestHeaders, templateVars, lookupEnv, verbosePrinter)
+		}
+		return invoker.Invoke(ctx, dataSource, requestData, requestHeaders)
 	}
 }
 
@@ -1161,6 +1681,9 @@ func makeHTTPRoundTripper(f *flags, isSecure bool, authority string, printer ver
 			MaxIdleConns:      1,
 		}
 	}
+	if isSecure {
+		transport = bufcurl.NewClientCertificateErrorRoundTripper(transport, f.Cert != "")
+	}
 	return transport, nil
 }
 
@@ -1197,7 +1720,8 @@ func makeHTTP3RoundTripper(f *flags, authority string, printer verbose.Printer)
 			printer.Printf("* ALPN: offering %s", strings.Join(tlsCfg.NextProtos, ","))
 			conn, err := transport.DialEarly(ctx, udpAddr, tlsCfg, cfg)
 			if err != nil {
-				return nil, err
+				printer.Printf("* HTTP/3 connection to %s failed: %v", addr, err)
+				return nil, newHTTP3DialError(addr, err)
 			}
 			printer.Printf("* Connected to %s", conn.RemoteAddr().String())
 			return conn, err
@@ -1210,6 +1734,237 @@ func makeHTTP3RoundTripper(f *flags, authority string, printer verbose.Printer)
 	return roundTripper, nil
 }
 
+// quicNoApplicationProtocolErrorCode is the QUIC error code for the TLS
+// no_application_protocol alert, which is sent when ALPN fails. QUIC
+// represents TLS alerts as 0x100 plus the alert number, which is 120.
+const quicNoApplicationProtocolErrorCode = quic.TransportErrorCode(0x178)
+
+// newHTTP3DialError wraps an error from dialing a QUIC connection with a
+// description of the likely cause, since QUIC errors are not very helpful
+// on their own.
+func newHTTP3DialError(addr string, err error) error {
+	var handshakeTimeoutError *quic.HandshakeTimeoutError
+	var idleTimeoutError *quic.IdleTimeoutError
+	var transportError *quic.TransportError
+	switch {
+	case errors.As(err, &handshakeTimeoutError), errors.As(err, &idleTimeoutError):
+		return fmt.Errorf(
+			"HTTP/3 connection to %s timed out during the QUIC handshake; the server may not support HTTP/3, or UDP traffic may be blocked: %w",
+			addr,
+			err,
+		)
+	case errors.As(err, &transportError) && transportError.ErrorCode == quicNoApplicationProtocolErrorCode:
+		return fmt.Errorf(
+			"HTTP/3 connection to %s failed ALPN negotiation; the server does not support h3: %w",
+			addr,
+			err,
+		)
+	case errors.As(err, &transportError) && transportError.ErrorCode.IsCryptoError():
+		return fmt.Errorf("HTTP/3 connection to %s failed during the TLS handshake: %w", addr, err)
+	default:
+		return fmt.Errorf("HTTP/3 connection to %s failed: %w", addr, err)
+	}
+}
+
+// runBatch invokes each of the RPCs listed in the file given with --requests,
+// printing the result of each RPC followed by a summary.
+func runBatch(ctx context.Context, container appext.Container, f *flags) error {
+	if container.NumArgs() != 0 {
+		return appcmd.NewInvalidArgumentErrorf("URL positional argument cannot be used with --%s", requestsFlagName)
+	}
+	for _, flagName := range []string{
+		dataFlagName,
+		dataPerLineFlagName,
+		outputFlagName,
+		listServicesFlagName,
+		listMethodsFlagName,
+	} {
+		if f.flagSet.Changed(flagName) {
+			return fmt.Errorf("--%s cannot be used with --%s", flagName, requestsFlagName)
+		}
+	}
+	if f.Parallel < 1 {
+		return fmt.Errorf("--%s value must be at least 1", parallelFlagName)
+	}
+	data, err := os.ReadFile(f.Requests)
+	if err != nil {
+		return bufcurl.ErrorHasFilename(err, f.Requests)
+	}
+	batchRequests, err := bufcurl.ParseBatchRequests(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Requests, err)
+	}
+	type batchResult struct {
+		output    bytes.Buffer
+		errOutput bytes.Buffer
+		duration  time.Duration
+		err       error
+	}
+	results := make([]*batchResult, len(batchRequests))
+	jobs := make([]func(context.Context) error, len(batchRequests))
+	for i, batchRequest := range batchRequests {
+		result := &batchResult{}
+		results[i] = result
+		jobs[i] = func(ctx context.Context) error {
+			requestFlags := *f
+			requestFlags.Requests = ""
+			requestFlags.Data = batchRequest.Data
+			requestFlags.Headers = append(slices.Clone(f.Headers), batchRequest.Headers...)
+			requestContainer, err := newBatchRequestContainer(container, &result.output, &result.errOutput, batchRequest.URL)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			result.err = run(ctx, requestContainer, &requestFlags)
+			result.duration = time.Since(start)
+			// The error of the RPC is reported with the result.
+			return nil
+		}
+	}
+	if err := thread.Parallelize(
+		ctx,
+		jobs,
+		thread.ParallelizeWithMaxParallelism(f.Parallel),
+	); err != nil {
+		return err
+	}
+	stdout := container.Stdout()
+	var numFailed int
+	for i, result := range results {
+		// Verbose output and RPC error details are written to stderr, grouped
+		// by RPC so that the output of parallel RPCs is not interleaved.
+		if _, err := container.Stderr().Write(result.errOutput.Bytes()); err != nil {
+			return err
+		}
+		status := "PASS"
+		if result.err != nil {
+			status = "FAIL"
+			numFailed++
+		}
+		if _, err := fmt.Fprintf(stdout, "--- %s: %s (%v)\n", status, batchRequests[i].Name, result.duration.Round(time.Millisecond)); err != nil {
+			return err
+		}
+		if _, err := stdout.Write(result.output.Bytes()); err != nil {
+			return err
+		}
+		if result.err != nil {
+			if _, err := fmt.Fprintf(stdout, "    %s\n", batchErrorMessage(result.err)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := fmt.Fprintf(stdout, "PASS: %d, FAIL: %d\n", len(results)-numFailed, numFailed); err != nil {
+		return err
+	}
+	if numFailed > 0 {
+		return fmt.Errorf("%d of %d requests failed", numFailed, len(results))
+	}
+	return nil
+}
+
+// newBatchRequestContainer returns a copy of the container for invoking a
+// single RPC listed in a --requests file, with the URL as the only argument
+// and stdout and stderr replaced with the writers.
+func newBatchRequestContainer(
+	container appext.Container,
+	stdout io.Writer,
+	stderr io.Writer,
+	url string,
+) (appext.Container, error) {
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			app.EnvironMap(container),
+			container.Stdin(),
+			stdout,
+			stderr,
+			url,
+		),
+		container.AppName(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return appext.NewContainer(nameContainer, container.Logger()), nil
+}
+
+// batchErrorMessage returns the message to print for an RPC listed in a
+// --requests file that failed with the error.
+func batchErrorMessage(err error) string {
+	if message := err.Error(); message != "" {
+		return message
+	}
+	// RPC errors have no message, the details are printed to stderr and
+	// the exit code is the code of the error shifted three bits to the left.
+	return fmt.Sprintf("RPC failed with code %v", connect.Code(app.GetExitCode(err)>>3))
+}
+
+// invokePerLine invokes the RPC once for each non-empty line of the file at
+// the path, or stdin if the path is "-", stopping at the first failure.
+func invokePerLine(
+	ctx context.Context,
+	container appext.Container,
+	invoker bufcurl.Invoker,
+	path string,
+	requestHeaders http.Header,
+	templateVars map[string]string,
+	lookupEnv func(string) (string, bool),
+	verbosePrinter verbose.Printer,
+) (retErr error) {
+	var reader io.Reader = container.Stdin()
+	dataSource := "(stdin)"
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return bufcurl.ErrorHasFilename(err, path)
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, file.Close())
+		}()
+		reader = file
+		dataSource = path
+	}
+	scanner := bufio.NewScanner(reader)
+	// Allow for large request messages.
+	scanner.Buffer(nil, 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lineDataSource := fmt.Sprintf("%s:%d", dataSource, lineNumber)
+		data, err := bufcurl.ExpandTemplate(line, templateVars, lookupEnv)
+		if err != nil {
+			return fmt.Errorf("%s: %w", lineDataSource, err)
+		}
+		verbosePrinter.Printf("* Using request data from %s", lineDataSource)
+		if err := invoker.Invoke(ctx, lineDataSource, bytes.NewReader(data), requestHeaders.Clone()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return bufcurl.ErrorHasFilename(err, dataSource)
+	}
+	return nil
+}
+
+func codesToStrings(codes []connect.Code) []string {
+	codeStrings := make([]string, len(codes))
+	for i, code := range codes {
+		codeStrings[i] = code.String()
+	}
+	return codeStrings
+}
+
+func stringsToCodes(codeStrings []string) ([]connect.Code, error) {
+	codes := make([]connect.Code, len(codeStrings))
+	for i, s := range codeStrings {
+		if err := codes[i].UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
 func secondsToDuration(secs float64) time.Duration {
 	return time.Duration(float64(time.Second) * secs)
 }
@@ -0,0 +1,134 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depdownload
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebundle"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	outputFlagName      = "output"
+	outputFlagShortName = "o"
+)
+
+// NewCommand returns a new download Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: "Download all dependencies from the buf.lock into a bundle",
+		Long: `The first argument is the directory of your buf.yaml configuration file.
+Defaults to "." if no argument is specified.
+
+All dependencies in the buf.lock, including transitive dependencies, are written to the
+bundle file given by --output. The bundle can be copied to an environment without network
+access, and used with the global --from-bundle flag to build without reaching the BSR:
+
+    $ buf dep download --output deps.bundle
+    $ buf build --from-bundle deps.bundle
+
+Dependencies read from a bundle are verified against the digests in the buf.lock, so bundles
+do not need to be trusted.`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Output string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`The file to write the bundle to. Use "-" to write to stdout. Required`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) (retErr error) {
+	if flags.Output == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s is required", outputFlagName)
+	}
+	dirPath := "."
+	if container.NumArgs() > 0 {
+		dirPath = container.Arg(0)
+	}
+	controller, err := bufcli.NewController(container)
+	if err != nil {
+		return err
+	}
+	workspaceDepManager, err := controller.GetWorkspaceDepManager(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	depModuleKeys, err := workspaceDepManager.ExistingBufLockFileDepModuleKeys(ctx)
+	if err != nil {
+		return err
+	}
+	moduleDataProvider, err := bufcli.NewModuleDataProvider(container)
+	if err != nil {
+		return err
+	}
+	depModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	// Verify the digests before writing anything, so that we never bundle tampered content.
+	for _, depModuleData := range depModuleDatas {
+		if _, err := depModuleData.Bucket(); err != nil {
+			return err
+		}
+	}
+	var writer io.Writer = container.Stdout()
+	if flags.Output != "-" {
+		file, err := os.Create(flags.Output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, file.Close())
+		}()
+		writer = file
+	}
+	return bufmodulebundle.WriteBundle(ctx, container.Logger(), writer, depModuleDatas)
+}
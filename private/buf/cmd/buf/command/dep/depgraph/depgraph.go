@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
@@ -41,12 +42,14 @@ const (
 
 	dotFormatString  = "dot"
 	jsonFormatString = "json"
+	textFormatString = "text"
 )
 
 var (
 	allGraphFormatStrings = []string{
 		dotFormatString,
 		jsonFormatString,
+		textFormatString,
 	}
 )
 
@@ -82,6 +85,14 @@ brew install graphviz
 You can easily visualize a dependency graph using the dot tool:
 
 buf dep graph | dot -Tpng >| graph.png && open graph.png
+
+With --format=text, the graph is printed as a tree rooted at each module that no other module
+depends on, with the digest of each module. A dependency that was already printed is marked with
+(*) and its dependencies are not printed again:
+
+src/proto
+  buf.build/foo/bar:12345 b5:...
+    buf.build/foo/baz:67890 b5:...
 ` + bufcli.GetSourceOrModuleLong(`the source or module to print the dependency graph for`),
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -196,6 +207,20 @@ func run(
 			return err
 		}
 		graphString = string(data)
+	case textFormatString:
+		var stringBuilder strings.Builder
+		printed := make(map[string]struct{})
+		if err := graph.WalkNodes(
+			func(module bufmodule.Module, inbound []bufmodule.Module, _ []bufmodule.Module) error {
+				if len(inbound) > 0 {
+					return nil
+				}
+				return writeTextModule(&stringBuilder, graph, module, 0, printed)
+			},
+		); err != nil {
+			return err
+		}
+		graphString = strings.TrimSuffix(stringBuilder.String(), "\n")
 	default:
 		return appcmd.NewInvalidArgumentErrorf("invalid value for --%s: %s", formatFlagName, flags.Format)
 	}
@@ -232,6 +257,43 @@ func dashlessCommitIDStringForModule(module bufmodule.Module) string {
 	return ""
 }
 
+// writeTextModule writes the module and, if the module was not already printed, its
+// dependencies, indented by depth.
+func writeTextModule(
+	stringBuilder *strings.Builder,
+	graph *dag.Graph[string, bufmodule.Module],
+	module bufmodule.Module,
+	depth int,
+	printed map[string]struct{},
+) error {
+	_, _ = stringBuilder.WriteString(strings.Repeat("  ", depth))
+	_, _ = stringBuilder.WriteString(moduleToString(module))
+	if !module.IsLocal() {
+		// We always calculate the b5 digest here, same as for the JSON format.
+		digest, err := module.Digest(bufmodule.DigestTypeB5)
+		if err != nil {
+			return err
+		}
+		_, _ = stringBuilder.WriteString(" " + digest.String())
+	}
+	if _, ok := printed[module.OpaqueID()]; ok {
+		_, _ = stringBuilder.WriteString(" (*)\n")
+		return nil
+	}
+	_, _ = stringBuilder.WriteString("\n")
+	printed[module.OpaqueID()] = struct{}{}
+	deps, err := graph.OutboundNodes(module.OpaqueID())
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		if err := writeTextModule(stringBuilder, graph, dep, depth+1, printed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type externalModule struct {
 	// ModuleFullName if remote, OpaqueID if no ModuleFullName
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
@@ -253,9 +315,9 @@ func (e *externalModule) addDeps(
 		depExternalModule, ok := moduleFullNameOrOpaqueIDToExternalModule[depModuleFullNameOrOpaqueID]
 		if ok {
 			// If this dependency has already been seen, we can simply update our current module
-			// and return early.
+			// and move on to the next dependency.
 			e.Deps = append(e.Deps, depExternalModule)
-			return nil
+			continue
 		}
 		// Otherwise, we create a new external module for our direct dependency. However, we do
 		// not add it to our map yet, we only add it once all transitive dependencies have been
@@ -21,8 +21,11 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/internal"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
 )
 
+const checkFlagName = "check"
+
 // NewCommand returns a new prune Command.
 func NewCommand(
 	name string,
@@ -30,25 +33,52 @@ func NewCommand(
 	deprecated string,
 	hidden bool,
 ) *appcmd.Command {
+	flags := newFlags()
 	return &appcmd.Command{
 		Use:   name + " <directory>",
-		Short: "Prune unused dependencies from a buf.lock",
+		Short: "Prune unused dependencies from a buf.yaml and buf.lock",
 		Long: `The first argument is the directory of your buf.yaml configuration file.
-Defaults to "." if no argument is specified.`,
+Defaults to "." if no argument is specified.
+
+Dependencies that are not imported by any module, directly or transitively, are removed
+from the deps in the buf.yaml and from the buf.lock. Comments and the order of the remaining
+deps in the buf.yaml are preserved.
+
+Use the --check flag in CI to verify that there are no unused dependencies without
+modifying any files.`,
 		Args:       appcmd.MaximumNArgs(1),
 		Deprecated: deprecated,
 		Hidden:     hidden,
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appext.Container) error {
-				return run(ctx, container)
+				return run(ctx, container, flags)
 			},
 		),
+		BindFlags: flags.Bind,
 	}
 }
 
+type flags struct {
+	Check bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.Check,
+		checkFlagName,
+		false,
+		"Do not modify any files, and exit with a non-zero exit code if any dependencies would be pruned",
+	)
+}
+
 func run(
 	ctx context.Context,
 	container appext.Container,
+	flags *flags,
 ) error {
 	dirPath := "."
 	if container.NumArgs() > 0 {
@@ -66,10 +96,15 @@ func run(
 	if err != nil {
 		return err
 	}
+	replaceConfigs, err := workspaceDepManager.ReplaceConfigs(ctx)
+	if err != nil {
+		return err
+	}
 	configuredDepModuleKeys, err := internal.ModuleKeysAndTransitiveDepModuleKeysForModuleRefs(
 		ctx,
 		container,
 		configuredDepModuleRefs,
+		replaceConfigs,
 		workspaceDepManager.BufLockFileDigestType(),
 	)
 	if err != nil {
@@ -82,5 +117,6 @@ func run(
 		configuredDepModuleKeys,
 		workspaceDepManager,
 		dirPath,
+		flags.Check,
 	)
 }
@@ -15,24 +15,33 @@
 package depupdate
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
 	"github.com/spf13/pflag"
 	"go.uber.org/multierr"
 )
 
 const (
-	onlyFlagName = "only"
+	onlyFlagName        = "only"
+	interactiveFlagName = "interactive"
 )
 
 // NewCommand returns a new update Command.
@@ -49,6 +58,14 @@ func NewCommand(
 		Long: `Fetch the latest digests for the specified references in buf.yaml,
 and write them and their transitive dependencies to buf.lock.
 
+References may be semantic version constraints, such as buf.build/acme/weather:^1.4 or
+buf.build/acme/weather:>=1.4,<2. A constraint resolves to the label with the highest
+semantic version that matches the constraint.
+
+If --interactive is set, for every dependency that would change, the current and candidate
+commits and the diff of the files between them are shown, and you are asked whether to update
+the dependency. Dependencies that are not updated stay pinned to their current commit.
+
 The first argument is the directory of the local module to update.
 Defaults to "." if no argument is specified.`,
 		Args:       appcmd.MaximumNArgs(1),
@@ -64,7 +81,8 @@ Defaults to "." if no argument is specified.`,
 }
 
 type flags struct {
-	Only []string
+	Only        []string
+	Interactive bool
 }
 
 func newFlags() *flags {
@@ -80,6 +98,12 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	)
 	// TODO FUTURE: implement
 	_ = flagSet.MarkHidden(onlyFlagName)
+	flagSet.BoolVar(
+		&f.Interactive,
+		interactiveFlagName,
+		false,
+		"Show the changes for each dependency that would be updated, and ask whether to update it. Requires a terminal",
+	)
 }
 
 // run update the buf.lock file for a specific module.
@@ -96,6 +120,10 @@ func run(
 		// TODO FUTURE: implement
 		return syserror.Newf("--%s is not implemented", onlyFlagName)
 	}
+	// Check this before resolving any dependencies, so that we fail fast.
+	if flags.Interactive && !bufcli.IsStdinTerminal(container) {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires a terminal", interactiveFlagName)
+	}
 
 	logger := container.Logger()
 	controller, err := bufcli.NewController(container)
@@ -110,10 +138,15 @@ func run(
 	if err != nil {
 		return err
 	}
+	replaceConfigs, err := workspaceDepManager.ReplaceConfigs(ctx)
+	if err != nil {
+		return err
+	}
 	configuredDepModuleKeys, err := internal.ModuleKeysAndTransitiveDepModuleKeysForModuleRefs(
 		ctx,
 		container,
 		configuredDepModuleRefs,
+		replaceConfigs,
 		workspaceDepManager.BufLockFileDigestType(),
 	)
 	if err != nil {
@@ -139,6 +172,22 @@ func run(
 		logger.Warn(fmt.Sprintf("No configured dependencies were found to update in %q.", dirPath))
 		return nil
 	}
+	if flags.Interactive {
+		moduleDataProvider, err := bufcli.NewModuleDataProvider(container)
+		if err != nil {
+			return err
+		}
+		configuredDepModuleKeys, err = selectDepModuleKeysInteractively(
+			ctx,
+			container,
+			moduleDataProvider,
+			configuredDepModuleKeys,
+			existingDepModuleKeys,
+		)
+		if err != nil {
+			return err
+		}
+	}
 
 	// We're about to edit the buf.lock file on disk. If we have a subsequent error,
 	// attempt to revert the buf.lock file.
@@ -169,6 +218,174 @@ func run(
 	); err != nil {
 		return err
 	}
+	// Now that the workspace builds, record why each dependency was resolved.
+	depResolutions, err := bufworkspace.DepResolutionsForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if err := workspaceDepManager.UpdateBufLockFile(
+		ctx,
+		configuredDepModuleKeys,
+		bufworkspace.UpdateBufLockFileWithDepResolutions(depResolutions),
+	); err != nil {
+		return err
+	}
 	// Log warnings for users on unused configured deps.
 	return internal.LogUnusedConfiguredDepsForWorkspace(workspace, logger)
 }
+
+// selectDepModuleKeysInteractively asks the user whether to update each dependency whose
+// pinned commit would change, and returns the ModuleKeys to write to the buf.lock.
+//
+// Dependencies that were not previously pinned are always added, as the workspace would
+// not build otherwise.
+//
+// Answers are read line by line from the containers Stdin.
+func selectDepModuleKeysInteractively(
+	ctx context.Context,
+	container appext.Container,
+	moduleDataProvider bufmodule.ModuleDataProvider,
+	candidateDepModuleKeys []bufmodule.ModuleKey,
+	existingDepModuleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleKey, error) {
+	moduleFullNameStringToExistingDepModuleKey, err := slicesext.ToUniqueValuesMap(
+		existingDepModuleKeys,
+		func(moduleKey bufmodule.ModuleKey) string {
+			return moduleKey.ModuleFullName().String()
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var changedExistingModuleKeys []bufmodule.ModuleKey
+	var changedCandidateModuleKeys []bufmodule.ModuleKey
+	for _, candidateDepModuleKey := range candidateDepModuleKeys {
+		existingDepModuleKey, ok := moduleFullNameStringToExistingDepModuleKey[candidateDepModuleKey.ModuleFullName().String()]
+		if ok && existingDepModuleKey.CommitID() != candidateDepModuleKey.CommitID() {
+			changedExistingModuleKeys = append(changedExistingModuleKeys, existingDepModuleKey)
+			changedCandidateModuleKeys = append(changedCandidateModuleKeys, candidateDepModuleKey)
+		}
+	}
+	if len(changedCandidateModuleKeys) == 0 {
+		return candidateDepModuleKeys, nil
+	}
+	// ModuleDataProviders expect ModuleKeys that are unique by ModuleFullName, so the
+	// existing and candidate commits are fetched separately.
+	existingModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, changedExistingModuleKeys)
+	if err != nil {
+		return nil, err
+	}
+	candidateModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, changedCandidateModuleKeys)
+	if err != nil {
+		return nil, err
+	}
+	commitIDToModuleData, err := slicesext.ToUniqueValuesMap(
+		append(existingModuleDatas, candidateModuleDatas...),
+		func(moduleData bufmodule.ModuleData) uuid.UUID {
+			return moduleData.ModuleKey().CommitID()
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	runner := command.NewRunner()
+	// A single Scanner is used for all prompts, as a Scanner may read past the end of a line.
+	scanner := bufio.NewScanner(container.Stdin())
+	selectedDepModuleKeys := make([]bufmodule.ModuleKey, 0, len(candidateDepModuleKeys))
+	for _, candidateDepModuleKey := range candidateDepModuleKeys {
+		existingDepModuleKey, ok := moduleFullNameStringToExistingDepModuleKey[candidateDepModuleKey.ModuleFullName().String()]
+		if !ok {
+			if _, err := fmt.Fprintf(container.Stdout(), "Adding new dependency %s.\n", candidateDepModuleKey.String()); err != nil {
+				return nil, err
+			}
+			selectedDepModuleKeys = append(selectedDepModuleKeys, candidateDepModuleKey)
+			continue
+		}
+		if existingDepModuleKey.CommitID() == candidateDepModuleKey.CommitID() {
+			selectedDepModuleKeys = append(selectedDepModuleKeys, candidateDepModuleKey)
+			continue
+		}
+		update, err := promptForDepUpdate(
+			ctx,
+			container,
+			runner,
+			scanner,
+			commitIDToModuleData[existingDepModuleKey.CommitID()],
+			commitIDToModuleData[candidateDepModuleKey.CommitID()],
+		)
+		if err != nil {
+			return nil, err
+		}
+		if update {
+			selectedDepModuleKeys = append(selectedDepModuleKeys, candidateDepModuleKey)
+		} else {
+			selectedDepModuleKeys = append(selectedDepModuleKeys, existingDepModuleKey)
+		}
+	}
+	return selectedDepModuleKeys, nil
+}
+
+// promptForDepUpdate prints the current and candidate commits of a dependency and the diff
+// between them, and asks the user whether to update the dependency.
+func promptForDepUpdate(
+	ctx context.Context,
+	container appext.Container,
+	runner command.Runner,
+	scanner *bufio.Scanner,
+	existingModuleData bufmodule.ModuleData,
+	candidateModuleData bufmodule.ModuleData,
+) (bool, error) {
+	if existingModuleData == nil || candidateModuleData == nil {
+		return false, syserror.New("did not get ModuleData for dependency")
+	}
+	existingBucket, err := existingModuleData.Bucket()
+	if err != nil {
+		return false, err
+	}
+	candidateBucket, err := candidateModuleData.Bucket()
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintf(
+		container.Stdout(),
+		"\n%s\n  current:   %s\n  candidate: %s\n\n",
+		candidateModuleData.ModuleKey().ModuleFullName().String(),
+		uuidutil.ToDashless(existingModuleData.ModuleKey().CommitID()),
+		uuidutil.ToDashless(candidateModuleData.ModuleKey().CommitID()),
+	); err != nil {
+		return false, err
+	}
+	changedPaths, err := storage.DiffWithFilenames(
+		ctx,
+		runner,
+		container.Stdout(),
+		existingBucket,
+		candidateBucket,
+		storage.DiffWithSuppressTimestamps(),
+	)
+	if err != nil {
+		return false, err
+	}
+	if len(changedPaths) == 0 {
+		if _, err := fmt.Fprintln(container.Stdout(), "No files changed."); err != nil {
+			return false, err
+		}
+	}
+	for {
+		if _, err := fmt.Fprint(container.Stdout(), "Update? [y/n]: "); err != nil {
+			return false, err
+		}
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return false, err
+			}
+			return false, errors.New("did not receive an answer")
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
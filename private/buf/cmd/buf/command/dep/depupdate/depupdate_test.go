@@ -0,0 +1,246 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depupdate
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectDepModuleKeysInteractively(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	existingProvider := testNewOmniProvider(
+		t,
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/a",
+			PathToData: map[string][]byte{"a.proto": []byte("syntax = \"proto3\";\npackage a;\n")},
+		},
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/b",
+			PathToData: map[string][]byte{"b.proto": []byte("syntax = \"proto3\";\npackage b;\n")},
+		},
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/d",
+			PathToData: map[string][]byte{"d.proto": []byte("syntax = \"proto3\";\npackage d;\n")},
+		},
+	)
+	candidateProvider := testNewOmniProvider(
+		t,
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/a",
+			CommitID:   uuid.New(),
+			PathToData: map[string][]byte{"a.proto": []byte("syntax = \"proto3\";\npackage a;\nmessage A {}\n")},
+		},
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/c",
+			CommitID:   uuid.New(),
+			PathToData: map[string][]byte{"c.proto": []byte("syntax = \"proto3\";\npackage c;\n")},
+		},
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/d",
+			CommitID:   uuid.New(),
+			PathToData: map[string][]byte{"d.proto": []byte("syntax = \"proto3\";\npackage d;\nmessage D {}\n")},
+		},
+	)
+	existingModuleKeys := testGetModuleKeys(t, ctx, existingProvider, "a", "b", "d")
+	candidateModuleKeys := testGetModuleKeys(t, ctx, candidateProvider, "a", "c", "d")
+	moduleDataProvider := testNewModuleDataProvider(t, ctx, existingProvider, candidateProvider, existingModuleKeys, candidateModuleKeys)
+
+	stdout := bytes.NewBuffer(nil)
+	selectedModuleKeys, err := selectDepModuleKeysInteractively(
+		ctx,
+		testNewContainer(t, "maybe\ny\nn\n", stdout),
+		moduleDataProvider,
+		[]bufmodule.ModuleKey{
+			candidateModuleKeys[0],
+			existingModuleKeys[1],
+			candidateModuleKeys[1],
+			candidateModuleKeys[2],
+		},
+		existingModuleKeys,
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			candidateModuleKeys[0].String(),
+			existingModuleKeys[1].String(),
+			candidateModuleKeys[1].String(),
+			existingModuleKeys[2].String(),
+		},
+		testModuleKeyStrings(selectedModuleKeys),
+	)
+	output := stdout.String()
+	// An unrecognized answer asks again.
+	assert.Equal(t, 3, strings.Count(output, "Update? [y/n]: "))
+	assert.Contains(t, output, "+message A {}")
+	assert.Contains(t, output, "+message D {}")
+	assert.Contains(t, output, "Adding new dependency "+candidateModuleKeys[1].String()+".")
+	assert.NotContains(t, output, "buf.build/foo/b")
+
+	// Running out of input is an error rather than a silent answer.
+	_, err = selectDepModuleKeysInteractively(
+		ctx,
+		testNewContainer(t, "y\n", bytes.NewBuffer(nil)),
+		moduleDataProvider,
+		[]bufmodule.ModuleKey{
+			candidateModuleKeys[0],
+			existingModuleKeys[1],
+			candidateModuleKeys[2],
+		},
+		existingModuleKeys,
+	)
+	require.EqualError(t, err, "did not receive an answer")
+}
+
+func TestSelectDepModuleKeysInteractivelyUnchanged(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	provider := testNewOmniProvider(
+		t,
+		bufmoduletesting.ModuleData{
+			Name:       "buf.build/foo/a",
+			PathToData: map[string][]byte{"a.proto": []byte(`syntax = "proto3";`)},
+		},
+	)
+	moduleKeys := testGetModuleKeys(t, ctx, provider, "a")
+	stdout := bytes.NewBuffer(nil)
+	// No prompts are shown, so nothing is read from stdin.
+	selectedModuleKeys, err := selectDepModuleKeysInteractively(
+		ctx,
+		testNewContainer(t, "", stdout),
+		provider,
+		moduleKeys,
+		moduleKeys,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, testModuleKeyStrings(moduleKeys), testModuleKeyStrings(selectedModuleKeys))
+	assert.Empty(t, stdout.String())
+}
+
+// testModuleDataProvider is a ModuleDataProvider that serves ModuleDatas by commit ID, so
+// that it can provide multiple commits of the same module.
+type testModuleDataProvider struct {
+	commitIDToModuleData map[uuid.UUID]bufmodule.ModuleData
+}
+
+func testNewModuleDataProvider(
+	t *testing.T,
+	ctx context.Context,
+	existingProvider bufmoduletesting.OmniProvider,
+	candidateProvider bufmoduletesting.OmniProvider,
+	existingModuleKeys []bufmodule.ModuleKey,
+	candidateModuleKeys []bufmodule.ModuleKey,
+) *testModuleDataProvider {
+	commitIDToModuleData := make(map[uuid.UUID]bufmodule.ModuleData)
+	for _, moduleDatas := range [][]bufmodule.ModuleData{
+		testGetModuleDatas(t, ctx, existingProvider, existingModuleKeys),
+		testGetModuleDatas(t, ctx, candidateProvider, candidateModuleKeys),
+	} {
+		for _, moduleData := range moduleDatas {
+			commitIDToModuleData[moduleData.ModuleKey().CommitID()] = moduleData
+		}
+	}
+	return &testModuleDataProvider{
+		commitIDToModuleData: commitIDToModuleData,
+	}
+}
+
+func (p *testModuleDataProvider) GetModuleDatasForModuleKeys(
+	_ context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	if _, err := bufmodule.ModuleFullNameStringToUniqueValue(moduleKeys); err != nil {
+		return nil, err
+	}
+	moduleDatas := make([]bufmodule.ModuleData, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		moduleData, ok := p.commitIDToModuleData[moduleKey.CommitID()]
+		if !ok {
+			return nil, &fs.PathError{Op: "read", Path: moduleKey.String(), Err: fs.ErrNotExist}
+		}
+		moduleDatas[i] = moduleData
+	}
+	return moduleDatas, nil
+}
+
+func testNewOmniProvider(t *testing.T, moduleDatas ...bufmoduletesting.ModuleData) bufmoduletesting.OmniProvider {
+	omniProvider, err := bufmoduletesting.NewOmniProvider(moduleDatas...)
+	require.NoError(t, err)
+	return omniProvider
+}
+
+func testGetModuleKeys(
+	t *testing.T,
+	ctx context.Context,
+	omniProvider bufmoduletesting.OmniProvider,
+	names ...string,
+) []bufmodule.ModuleKey {
+	var moduleRefs []bufmodule.ModuleRef
+	for _, name := range names {
+		moduleRef, err := bufmodule.NewModuleRef("buf.build", "foo", name, "")
+		require.NoError(t, err)
+		moduleRefs = append(moduleRefs, moduleRef)
+	}
+	moduleKeys, err := omniProvider.GetModuleKeysForModuleRefs(ctx, moduleRefs, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	return moduleKeys
+}
+
+func testGetModuleDatas(
+	t *testing.T,
+	ctx context.Context,
+	omniProvider bufmoduletesting.OmniProvider,
+	moduleKeys []bufmodule.ModuleKey,
+) []bufmodule.ModuleData {
+	moduleDatas, err := omniProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	return moduleDatas
+}
+
+func testNewContainer(t *testing.T, stdin string, stdout *bytes.Buffer) appext.Container {
+	nameContainer, err := appext.NewNameContainer(
+		app.NewContainer(
+			nil,
+			strings.NewReader(stdin),
+			stdout,
+			bytes.NewBuffer(nil),
+		),
+		"buf",
+	)
+	require.NoError(t, err)
+	return appext.NewContainer(nameContainer, slogtestext.NewLogger(t))
+}
+
+func testModuleKeyStrings(moduleKeys []bufmodule.ModuleKey) []string {
+	moduleKeyStrings := make([]string, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		moduleKeyStrings[i] = moduleKey.String()
+	}
+	return moduleKeyStrings
+}
@@ -0,0 +1,105 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depvendor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+)
+
+// NewCommand returns a new vendor Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	return newCommand(name, builder, bufcli.NewModuleDataProvider)
+}
+
+// newCommand returns a new vendor Command that reads dependencies from the
+// ModuleDataProvider returned by newModuleDataProvider.
+func newCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+	newModuleDataProvider func(appext.Container) (bufmodule.ModuleDataProvider, error),
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: "Copy all dependencies from the buf.lock into a vendor directory",
+		Long: fmt.Sprintf(`The first argument is the directory of your buf.yaml configuration file.
+Defaults to "." if no argument is specified.
+
+All dependencies in the buf.lock, including transitive dependencies, are copied into a %q
+directory next to the buf.yaml. Any existing vendor directory is replaced.
+
+When a vendor directory is present, dependencies are read from the vendor directory instead
+of the cache or the BSR. Dependencies in the buf.lock that are not in the vendor directory are
+still read from the cache or the BSR, so run this command again after running buf dep update.
+The vendored files are verified against the digests in the buf.lock when read.
+
+Only v2 buf.yaml files are supported.`,
+			bufworkspace.VendorDirName,
+		),
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, newModuleDataProvider)
+			},
+		),
+	}
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	newModuleDataProvider func(appext.Container) (bufmodule.ModuleDataProvider, error),
+) error {
+	dirPath := "."
+	if container.NumArgs() > 0 {
+		dirPath = container.Arg(0)
+	}
+	controller, err := bufcli.NewController(container)
+	if err != nil {
+		return err
+	}
+	workspaceDepManager, err := controller.GetWorkspaceDepManager(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	depModuleKeys, err := workspaceDepManager.ExistingBufLockFileDepModuleKeys(ctx)
+	if err != nil {
+		return err
+	}
+	moduleDataProvider, err := newModuleDataProvider(container)
+	if err != nil {
+		return err
+	}
+	depModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	// Verify the digests before writing anything, so that we never vendor tampered content.
+	for _, depModuleData := range depModuleDatas {
+		if _, err := depModuleData.Bucket(); err != nil {
+			return err
+		}
+	}
+	return workspaceDepManager.UpdateVendorDir(ctx, depModuleDatas)
+}
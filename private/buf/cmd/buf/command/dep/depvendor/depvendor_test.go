@@ -0,0 +1,192 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depvendor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	omniProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a/a.proto": []byte(`syntax = "proto3"; package a;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/b",
+			PathToData: map[string][]byte{
+				"b/b.proto": []byte(`syntax = "proto3"; package b; import "a/a.proto";`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleKeys := testGetModuleKeys(t, ctx, omniProvider, "a", "b")
+	dirPath := t.TempDir()
+	testWriteFile(
+		t,
+		filepath.Join(dirPath, "buf.yaml"),
+		`version: v2
+deps:
+  - buf.build/foo/b
+`,
+	)
+	testWriteBufLockFile(t, dirPath, moduleKeys)
+
+	testRunVendor(t, omniProvider, dirPath)
+	vendorDirPath := filepath.Join(dirPath, "vendor")
+	for _, moduleKey := range moduleKeys {
+		moduleDirPath := filepath.Join(
+			vendorDirPath,
+			"b5",
+			"buf.build",
+			"foo",
+			moduleKey.ModuleFullName().Name(),
+			uuidutil.ToDashless(moduleKey.CommitID()),
+		)
+		assert.FileExists(t, filepath.Join(moduleDirPath, "module.yaml"))
+		assert.FileExists(
+			t,
+			filepath.Join(
+				moduleDirPath,
+				"files",
+				moduleKey.ModuleFullName().Name(),
+				moduleKey.ModuleFullName().Name()+".proto",
+			),
+		)
+	}
+	data, err := os.ReadFile(filepath.Join(vendorDirPath, "modules.yaml"))
+	require.NoError(t, err)
+	expectedModules := "# Generated by buf. DO NOT EDIT.\nversion: v1\nmodules:\n"
+	for _, moduleKey := range moduleKeys {
+		digest, err := moduleKey.Digest()
+		require.NoError(t, err)
+		expectedModules += fmt.Sprintf(
+			"  - name: %s\n    commit: %s\n    digest: %s\n",
+			moduleKey.ModuleFullName().String(),
+			uuidutil.ToDashless(moduleKey.CommitID()),
+			digest.String(),
+		)
+	}
+	assert.Equal(t, expectedModules, string(data))
+
+	// Running again replaces the contents of the existing vendor directory.
+	staleFilePath := filepath.Join(vendorDirPath, "stale.txt")
+	testWriteFile(t, staleFilePath, "stale")
+	testWriteBufLockFile(t, dirPath, moduleKeys[:1])
+	testRunVendor(t, omniProvider, dirPath)
+	assert.NoFileExists(t, staleFilePath)
+	assert.NoDirExists(t, filepath.Join(vendorDirPath, "b5", "buf.build", "foo", "b"))
+	assert.DirExists(t, filepath.Join(vendorDirPath, "b5", "buf.build", "foo", "a"))
+	data, err = os.ReadFile(filepath.Join(vendorDirPath, "modules.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "buf.build/foo/a")
+	assert.NotContains(t, string(data), "buf.build/foo/b")
+}
+
+func TestVendorV1(t *testing.T) {
+	t.Parallel()
+	omniProvider, err := bufmoduletesting.NewOmniProvider()
+	require.NoError(t, err)
+	dirPath := t.TempDir()
+	testWriteFile(t, filepath.Join(dirPath, "buf.yaml"), "version: v1\n")
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		func(use string) *appcmd.Command { return testNewCommand(omniProvider) },
+		1,
+		[]string{"vendoring dependencies is only supported for v2 buf.yaml files"},
+		testNewEnv(t),
+		nil,
+		dirPath,
+	)
+	assert.NoDirExists(t, filepath.Join(dirPath, "vendor"))
+}
+
+func testRunVendor(t *testing.T, moduleDataProvider bufmodule.ModuleDataProvider, dirPath string) {
+	appcmdtesting.RunCommandSuccess(
+		t,
+		func(use string) *appcmd.Command { return testNewCommand(moduleDataProvider) },
+		testNewEnv(t),
+		nil,
+		bytes.NewBuffer(nil),
+		dirPath,
+	)
+}
+
+func testNewCommand(moduleDataProvider bufmodule.ModuleDataProvider) *appcmd.Command {
+	return newCommand(
+		"vendor",
+		appext.NewBuilder("vendor"),
+		func(appext.Container) (bufmodule.ModuleDataProvider, error) {
+			return moduleDataProvider, nil
+		},
+	)
+}
+
+func testNewEnv(t *testing.T) func(string) map[string]string {
+	cacheDirPath := t.TempDir()
+	return func(use string) map[string]string {
+		return map[string]string{
+			"BUF_CACHE_DIR": cacheDirPath,
+		}
+	}
+}
+
+func testGetModuleKeys(
+	t *testing.T,
+	ctx context.Context,
+	omniProvider bufmoduletesting.OmniProvider,
+	names ...string,
+) []bufmodule.ModuleKey {
+	var moduleRefs []bufmodule.ModuleRef
+	for _, name := range names {
+		moduleRef, err := bufmodule.NewModuleRef("buf.build", "foo", name, "")
+		require.NoError(t, err)
+		moduleRefs = append(moduleRefs, moduleRef)
+	}
+	moduleKeys, err := omniProvider.GetModuleKeysForModuleRefs(ctx, moduleRefs, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	return moduleKeys
+}
+
+func testWriteBufLockFile(t *testing.T, dirPath string, depModuleKeys []bufmodule.ModuleKey) {
+	bufLockFile, err := bufconfig.NewBufLockFile(bufconfig.FileVersionV2, depModuleKeys)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, bufconfig.WriteBufLockFile(buffer, bufLockFile))
+	testWriteFile(t, filepath.Join(dirPath, "buf.lock"), buffer.String())
+}
+
+func testWriteFile(t *testing.T, path string, data string) {
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
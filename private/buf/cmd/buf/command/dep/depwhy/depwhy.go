@@ -0,0 +1,254 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depwhy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <module> [input]",
+		Short: "Explain why a module is in the dependency graph",
+		Long: `Prints the shortest chain of imports from a file in the workspace to a file in the given module.
+
+The module is either the name of a module, such as "buf.build/acme/date", or the path of a local module
+within the workspace. As an example, if "src/proto/acme/bond/v1/bond.proto" imports
+"acme/extension/v1/extension.proto" from module "buf.build/acme/extension", which in turn imports
+"acme/date/v1/date.proto" from module "buf.build/acme/date", "buf dep why buf.build/acme/date" prints:
+
+# buf.build/acme/date
+acme/bond/v1/bond.proto (src/proto)
+acme/extension/v1/extension.proto (buf.build/acme/extension)
+acme/date/v1/date.proto (buf.build/acme/date)
+
+If the module is in the dependency graph but no file in the workspace imports a file from the
+module, this is printed instead of the chain of imports. The module can then be removed with
+"buf dep prune".
+` + bufcli.GetSourceOrModuleLong(`the source or module to explain the dependency for`),
+		Args: appcmd.RangeArgs(1, 2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	DisableSymlinks bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	moduleString := container.Arg(0)
+	input := "."
+	if container.NumArgs() > 1 {
+		input = container.Arg(1)
+	}
+	if flags.InputHashtag != "" {
+		if container.NumArgs() > 1 {
+			return appcmd.NewInvalidArgumentErrorf("only 1 input can be specified, got %q and %q", input, flags.InputHashtag)
+		}
+		input = flags.InputHashtag
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return err
+	}
+	whyModule := getModuleForModuleString(workspace, moduleString)
+	if whyModule == nil {
+		return appcmd.NewInvalidArgumentErrorf("%s is not in the dependency graph of %s", moduleString, input)
+	}
+	chain, err := getShortestImportChain(ctx, workspace, whyModule)
+	if err != nil {
+		return err
+	}
+	var stringBuilder strings.Builder
+	_, _ = stringBuilder.WriteString("# " + moduleString + "\n")
+	if len(chain) == 0 {
+		_, _ = stringBuilder.WriteString(fmt.Sprintf("(no file in the workspace imports a file from %s)\n", moduleString))
+	}
+	for _, fileInfo := range chain {
+		_, _ = stringBuilder.WriteString(fmt.Sprintf("%s (%s)\n", fileInfo.Path(), moduleFullNameOrOpaqueID(fileInfo.Module())))
+	}
+	_, err = container.Stdout().Write([]byte(stringBuilder.String()))
+	return err
+}
+
+// getModuleForModuleString gets the Module for the ModuleFullName or OpaqueID.
+//
+// Returns nil if there is no such Module.
+func getModuleForModuleString(moduleSet bufmodule.ModuleSet, moduleString string) bufmodule.Module {
+	if moduleFullName, err := bufmodule.ParseModuleFullName(moduleString); err == nil {
+		if module := moduleSet.GetModuleForModuleFullName(moduleFullName); module != nil {
+			return module
+		}
+	}
+	return moduleSet.GetModuleForOpaqueID(moduleString)
+}
+
+// getShortestImportChain returns the shortest chain of imports from a target file in the
+// ModuleSet to a file in whyModule, starting with the target file and ending with the file
+// in whyModule.
+//
+// Files in whyModule are not used as the start of a chain. Returns nil if no chain exists.
+func getShortestImportChain(
+	ctx context.Context,
+	moduleSet bufmodule.ModuleSet,
+	whyModule bufmodule.Module,
+) ([]bufmodule.FileInfo, error) {
+	moduleReadBucket := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet)
+	var startFileInfos []bufmodule.FileInfo
+	for _, module := range bufmodule.ModuleSetTargetModules(moduleSet) {
+		if module.OpaqueID() == whyModule.OpaqueID() {
+			continue
+		}
+		if err := bufmodule.ModuleReadBucketWithOnlyProtoFiles(module).WalkFileInfos(
+			ctx,
+			func(fileInfo bufmodule.FileInfo) error {
+				startFileInfos = append(startFileInfos, fileInfo)
+				return nil
+			},
+			bufmodule.WalkFileInfosWithOnlyTargetFiles(),
+		); err != nil {
+			return nil, err
+		}
+	}
+	// Sort for deterministic output when there are multiple shortest chains.
+	sort.Slice(
+		startFileInfos,
+		func(i int, j int) bool {
+			return startFileInfos[i].Path() < startFileInfos[j].Path()
+		},
+	)
+	// Breadth-first search over the imports, so that the first chain found is the shortest.
+	pathToParentFileInfo := make(map[string]bufmodule.FileInfo)
+	visitedPaths := make(map[string]struct{})
+	for _, startFileInfo := range startFileInfos {
+		visitedPaths[startFileInfo.Path()] = struct{}{}
+	}
+	remainingFileInfos := startFileInfos
+	for len(remainingFileInfos) > 0 {
+		fileInfo := remainingFileInfos[0]
+		remainingFileInfos = remainingFileInfos[1:]
+		if fileInfo.Module().OpaqueID() == whyModule.OpaqueID() {
+			return getChainForFileInfo(fileInfo, pathToParentFileInfo), nil
+		}
+		imports, err := fileInfo.ProtoFileImports()
+		if err != nil {
+			return nil, err
+		}
+		for _, importPath := range imports {
+			if _, ok := visitedPaths[importPath]; ok {
+				continue
+			}
+			visitedPaths[importPath] = struct{}{}
+			importFileInfo, err := moduleReadBucket.StatFileInfo(ctx, importPath)
+			if err != nil {
+				// Well-known types are not within any Module.
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			pathToParentFileInfo[importPath] = fileInfo
+			remainingFileInfos = append(remainingFileInfos, importFileInfo)
+		}
+	}
+	return nil, nil
+}
+
+func getChainForFileInfo(
+	fileInfo bufmodule.FileInfo,
+	pathToParentFileInfo map[string]bufmodule.FileInfo,
+) []bufmodule.FileInfo {
+	chain := []bufmodule.FileInfo{fileInfo}
+	for {
+		parentFileInfo, ok := pathToParentFileInfo[fileInfo.Path()]
+		if !ok {
+			break
+		}
+		chain = append([]bufmodule.FileInfo{parentFileInfo}, chain...)
+		fileInfo = parentFileInfo
+	}
+	return chain
+}
+
+// moduleFullNameOrOpaqueID returns the ModuleFullName for a module if available, otherwise
+// it returns the OpaqueID.
+func moduleFullNameOrOpaqueID(module bufmodule.Module) string {
+	if moduleFullName := module.ModuleFullName(); moduleFullName != nil {
+		return moduleFullName.String()
+	}
+	return module.OpaqueID()
+}
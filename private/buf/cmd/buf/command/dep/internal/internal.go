@@ -18,40 +18,102 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/google/uuid"
 )
 
-// ModuleKeysAndTransitiveDepModuleKeysForModuleKeys gets the ModuleKeys for the
+// ModuleKeysAndTransitiveDepModuleKeysForModuleRefs gets the ModuleKeys for the
 // ModuleRefs, and all the transitive dependencies.
+//
+// The ReplaceConfigs are applied to both the ModuleRefs and the transitive dependencies.
+// Dependencies replaced with a path are local to the workspace, and are not returned.
+// Dependencies replaced with a ModuleRef are substituted with the ModuleKey for the replacement
+// and its transitive dependencies.
 func ModuleKeysAndTransitiveDepModuleKeysForModuleRefs(
 	ctx context.Context,
 	container appext.Container,
 	moduleRefs []bufmodule.ModuleRef,
+	replaceConfigs []bufconfig.ReplaceConfig,
 	digestType bufmodule.DigestType,
 ) ([]bufmodule.ModuleKey, error) {
 	moduleKeyProvider, err := bufcli.NewModuleKeyProvider(container)
 	if err != nil {
 		return nil, err
 	}
+	pathReplacedModuleFullNameStrings := make(map[string]struct{})
+	moduleFullNameStringToReplacementModuleRef := make(map[string]bufmodule.ModuleRef)
+	for _, replaceConfig := range replaceConfigs {
+		if moduleRef := replaceConfig.ModuleRef(); moduleRef != nil {
+			moduleFullNameStringToReplacementModuleRef[replaceConfig.ModuleFullName().String()] = moduleRef
+		} else {
+			pathReplacedModuleFullNameStrings[replaceConfig.ModuleFullName().String()] = struct{}{}
+		}
+	}
+	var replacedModuleRefs []bufmodule.ModuleRef
+	for _, moduleRef := range moduleRefs {
+		moduleFullNameString := moduleRef.ModuleFullName().String()
+		if _, ok := pathReplacedModuleFullNameStrings[moduleFullNameString]; ok {
+			continue
+		}
+		if replacementModuleRef, ok := moduleFullNameStringToReplacementModuleRef[moduleFullNameString]; ok {
+			moduleRef = replacementModuleRef
+		}
+		replacedModuleRefs = append(replacedModuleRefs, moduleRef)
+	}
 	moduleKeys, err := moduleKeyProvider.GetModuleKeysForModuleRefs(
 		ctx,
-		moduleRefs,
+		replacedModuleRefs,
 		digestType,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return moduleKeysAndTransitiveDepModuleKeysForModuleKeys(ctx, container, moduleKeys)
+	if len(replaceConfigs) == 0 {
+		return moduleKeysAndTransitiveDepModuleKeysForModuleKeys(ctx, container, moduleKeys)
+	}
+	// Replacements also apply to transitive dependencies, so we need the ModuleKeys for all
+	// replacements, regardless of whether they are a configured dependency.
+	replacedModuleFullNameStrings := slicesext.MapKeysToSortedSlice(moduleFullNameStringToReplacementModuleRef)
+	replacementModuleKeys, err := moduleKeyProvider.GetModuleKeysForModuleRefs(
+		ctx,
+		slicesext.Map(
+			replacedModuleFullNameStrings,
+			func(moduleFullNameString string) bufmodule.ModuleRef {
+				return moduleFullNameStringToReplacementModuleRef[moduleFullNameString]
+			},
+		),
+		digestType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	moduleFullNameStringToReplacementModuleKey := make(map[string]bufmodule.ModuleKey, len(replacementModuleKeys))
+	for i, replacementModuleKey := range replacementModuleKeys {
+		moduleFullNameStringToReplacementModuleKey[replacedModuleFullNameStrings[i]] = replacementModuleKey
+	}
+	return moduleKeysAndTransitiveDepModuleKeysForModuleKeysWithReplacements(
+		ctx,
+		container,
+		moduleKeys,
+		pathReplacedModuleFullNameStrings,
+		moduleFullNameStringToReplacementModuleKey,
+	)
 }
 
-// Prune prunes the buf.lock.
+// Prune prunes the buf.yaml and buf.lock.
+//
+// Dependencies in the buf.yaml that are not used are removed from the buf.yaml, and dependencies
+// in the buf.lock that are not used are removed from the buf.lock. If check is true, nothing is
+// written, and an error is returned if anything would be pruned.
 //
 // Used by dep/mod prune.
 func Prune(
@@ -69,6 +131,7 @@ func Prune(
 	bufYAMLBasedDepModuleKeys []bufmodule.ModuleKey,
 	workspaceDepManager bufworkspace.WorkspaceDepManager,
 	dirPath string,
+	check bool,
 ) error {
 	workspace, err := controller.GetWorkspace(ctx, dirPath, bufctl.WithIgnoreAndDisallowV1BufWorkYAMLs())
 	if err != nil {
@@ -82,9 +145,9 @@ func Prune(
 	); err != nil {
 		return err
 	}
-	// Compute those dependencies that are in buf.yaml that are not used at all, and warn
-	// about them.
-	if err := LogUnusedConfiguredDepsForWorkspace(workspace, logger); err != nil {
+	// Compute those dependencies that are in buf.yaml that are not used at all.
+	unusedConfiguredDepModuleFullNames, err := unusedConfiguredDepModuleFullNamesForWorkspace(workspace)
+	if err != nil {
 		return err
 	}
 	// Step that actually computes remote dependencies based on imports. These are all
@@ -105,7 +168,28 @@ func Prune(
 	if err := validateModuleKeysContains(bufYAMLBasedDepModuleKeys, depModuleKeys); err != nil {
 		return err
 	}
-	return workspaceDepManager.UpdateBufLockFile(ctx, depModuleKeys)
+	if check {
+		existingDepModuleKeys, err := workspaceDepManager.ExistingBufLockFileDepModuleKeys(ctx)
+		if err != nil {
+			return err
+		}
+		return checkPrune(unusedConfiguredDepModuleFullNames, existingDepModuleKeys, depModuleKeys)
+	}
+	for _, unusedConfiguredDepModuleFullName := range unusedConfiguredDepModuleFullNames {
+		logger.Info(fmt.Sprintf("Removing unused dependency %s from buf.yaml.", unusedConfiguredDepModuleFullName.String()))
+	}
+	if err := workspaceDepManager.RemoveConfiguredDeps(ctx, unusedConfiguredDepModuleFullNames); err != nil {
+		return err
+	}
+	depResolutions, err := bufworkspace.DepResolutionsForWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	return workspaceDepManager.UpdateBufLockFile(
+		ctx,
+		depModuleKeys,
+		bufworkspace.UpdateBufLockFileWithDepResolutions(depResolutions),
+	)
 }
 
 // LogUnusedConfiugredDepsForWorkspace takes a workspace and logs the unused configured
@@ -132,6 +216,59 @@ func LogUnusedConfiguredDepsForWorkspace(
 	return nil
 }
 
+// unusedConfiguredDepModuleFullNamesForWorkspace returns the ModuleFullNames of the configured
+// dependencies that are unused.
+func unusedConfiguredDepModuleFullNamesForWorkspace(workspace bufworkspace.Workspace) ([]bufmodule.ModuleFullName, error) {
+	malformedDeps, err := bufworkspace.MalformedDepsForWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	var unusedConfiguredDepModuleFullNames []bufmodule.ModuleFullName
+	for _, malformedDep := range malformedDeps {
+		switch t := malformedDep.Type(); t {
+		case bufworkspace.MalformedDepTypeUnused:
+			unusedConfiguredDepModuleFullNames = append(unusedConfiguredDepModuleFullNames, malformedDep.ModuleRef().ModuleFullName())
+		default:
+			return nil, fmt.Errorf("unknown MalformedDepType: %v", t)
+		}
+	}
+	return unusedConfiguredDepModuleFullNames, nil
+}
+
+// checkPrune returns an error if prune would change the buf.yaml or buf.lock.
+func checkPrune(
+	unusedConfiguredDepModuleFullNames []bufmodule.ModuleFullName,
+	existingDepModuleKeys []bufmodule.ModuleKey,
+	depModuleKeys []bufmodule.ModuleKey,
+) error {
+	existingModuleFullNameStringToModuleKey, err := getModuleFullNameStringToModuleKey(existingDepModuleKeys)
+	if err != nil {
+		return err
+	}
+	moduleFullNameStringToModuleKey, err := getModuleFullNameStringToModuleKey(depModuleKeys)
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for _, unusedConfiguredDepModuleFullName := range unusedConfiguredDepModuleFullNames {
+		messages = append(messages, fmt.Sprintf("buf.yaml: unused dependency %s", unusedConfiguredDepModuleFullName.String()))
+	}
+	for _, moduleFullNameString := range slicesext.MapKeysToSortedSlice(existingModuleFullNameStringToModuleKey) {
+		if _, ok := moduleFullNameStringToModuleKey[moduleFullNameString]; !ok {
+			messages = append(messages, fmt.Sprintf("buf.lock: unused dependency %s", moduleFullNameString))
+		}
+	}
+	for _, moduleFullNameString := range slicesext.MapKeysToSortedSlice(moduleFullNameStringToModuleKey) {
+		if _, ok := existingModuleFullNameStringToModuleKey[moduleFullNameString]; !ok {
+			messages = append(messages, fmt.Sprintf("buf.lock: missing dependency %s", moduleFullNameString))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("dependencies need to be pruned, run buf dep prune to fix:\n  %s", strings.Join(messages, "\n  "))
+}
+
 // moduleKeysAndTransitiveDepModuleKeysForModuleKeys returns the ModuleKeys
 // and all the transitive dependencies.
 func moduleKeysAndTransitiveDepModuleKeysForModuleKeys(
@@ -160,6 +297,65 @@ func moduleKeysAndTransitiveDepModuleKeysForModuleKeys(
 	return newModuleKeys, nil
 }
 
+// moduleKeysAndTransitiveDepModuleKeysForModuleKeysWithReplacements returns the ModuleKeys
+// and all the transitive dependencies, applying replacements.
+//
+// The graph is walked from the given ModuleKeys. Any dependency that is replaced with a path
+// is skipped along with the dependencies only reachable through it, and any dependency that is
+// replaced with a ModuleKey is substituted with that ModuleKey and its dependencies.
+func moduleKeysAndTransitiveDepModuleKeysForModuleKeysWithReplacements(
+	ctx context.Context,
+	container appext.Container,
+	moduleKeys []bufmodule.ModuleKey,
+	pathReplacedModuleFullNameStrings map[string]struct{},
+	moduleFullNameStringToReplacementModuleKey map[string]bufmodule.ModuleKey,
+) ([]bufmodule.ModuleKey, error) {
+	graphProvider, err := bufcli.NewGraphProvider(container)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := graphProvider.GetGraphForModuleKeys(
+		ctx,
+		append(
+			slicesext.Copy(moduleKeys),
+			slicesext.MapValuesToSlice(moduleFullNameStringToReplacementModuleKey)...,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	commitIDToOutboundModuleKeys := make(map[uuid.UUID][]bufmodule.ModuleKey)
+	if err := graph.WalkNodes(
+		func(moduleKey bufmodule.ModuleKey, _ []bufmodule.ModuleKey, outboundModuleKeys []bufmodule.ModuleKey) error {
+			commitIDToOutboundModuleKeys[moduleKey.CommitID()] = outboundModuleKeys
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	var newModuleKeys []bufmodule.ModuleKey
+	visitedCommitIDs := make(map[uuid.UUID]struct{})
+	remainingModuleKeys := slicesext.Copy(moduleKeys)
+	for len(remainingModuleKeys) > 0 {
+		moduleKey := remainingModuleKeys[0]
+		remainingModuleKeys = remainingModuleKeys[1:]
+		moduleFullNameString := moduleKey.ModuleFullName().String()
+		if _, ok := pathReplacedModuleFullNameStrings[moduleFullNameString]; ok {
+			continue
+		}
+		if replacementModuleKey, ok := moduleFullNameStringToReplacementModuleKey[moduleFullNameString]; ok {
+			moduleKey = replacementModuleKey
+		}
+		if _, ok := visitedCommitIDs[moduleKey.CommitID()]; ok {
+			continue
+		}
+		visitedCommitIDs[moduleKey.CommitID()] = struct{}{}
+		newModuleKeys = append(newModuleKeys, moduleKey)
+		remainingModuleKeys = append(remainingModuleKeys, commitIDToOutboundModuleKeys[moduleKey.CommitID()]...)
+	}
+	return newModuleKeys, nil
+}
+
 // validateModuleKeysContains validates that containingModuleKeys is a superset of moduleKeys.
 //
 // This is used by Prune to validate that bufYAMLBasedDepModuleKeys are a superset of RemoteDepsForModuleSet.
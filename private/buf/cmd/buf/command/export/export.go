@@ -17,8 +17,12 @@ package export
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
@@ -27,6 +31,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/spf13/pflag"
@@ -35,12 +40,17 @@ import (
 
 const (
 	excludeImportsFlagName  = "exclude-imports"
+	includeImportsFlagName  = "include-imports"
+	includeWKTFlagName      = "include-wkt"
 	pathsFlagName           = "path"
 	outputFlagName          = "output"
 	outputFlagShortName     = "o"
 	configFlagName          = "config"
 	excludePathsFlagName    = "exclude-path"
 	disableSymlinksFlagName = "disable-symlinks"
+	tarVolumeSizeFlagName   = "tar-volume-size"
+
+	tarExt = ".tar"
 )
 
 // NewCommand returns a new Command.
@@ -75,6 +85,19 @@ Export a specific version of a remote module to a local directory.
 Export a git repo to a local directory.
 
     $ buf export https://github.com/owner/repository.git --output=<output-dir>
+
+Export a self-contained tree that compiles standalone with protoc, including the
+Well-Known Types.
+
+    $ buf export <source> --include-imports --include-wkt --output=<output-dir>
+
+Export only the first-party files of <source>, without any imports.
+
+    $ buf export <source> --exclude-imports --output=<output-dir>
+
+Export to tar archives of at most 1024 megabytes each.
+
+    $ buf export <source> --output=<output>.tar --tar-volume-size=1024
 `,
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -88,11 +111,14 @@ Export a git repo to a local directory.
 
 type flags struct {
 	ExcludeImports  bool
+	IncludeImports  bool
+	IncludeWKT      bool
 	Paths           []string
 	Output          string
 	Config          string
 	ExcludePaths    []string
 	DisableSymlinks bool
+	TarVolumeSize   int64
 
 	// special
 	InputHashtag string
@@ -106,6 +132,26 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 	bufcli.BindExcludeImports(flagSet, &f.ExcludeImports, excludeImportsFlagName)
+	flagSet.BoolVar(
+		&f.IncludeImports,
+		includeImportsFlagName,
+		false,
+		fmt.Sprintf(
+			`Include imports. This is the default, and cannot be used with --%s`,
+			excludeImportsFlagName,
+		),
+	)
+	flagSet.BoolVar(
+		&f.IncludeWKT,
+		includeWKTFlagName,
+		false,
+		fmt.Sprintf(
+			`Include the Well-Known Types that are imported, even if they are not part of a module or dependency.
+By default, Well-Known Types are only exported if they are part of a module or dependency, as protoc
+provides them. Cannot be used with --%s`,
+			excludeImportsFlagName,
+		),
+	)
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	flagSet.StringVarP(
@@ -113,7 +159,18 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		outputFlagName,
 		outputFlagShortName,
 		"",
-		`The output directory for exported files`,
+		`The output directory for exported files, a .tar file to export to an archive, or an s3:// or gs:// URL to export to object storage.
+Object storage credentials are only read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or GOOGLE_OAUTH_ACCESS_TOKEN, and each file is limited to 5 GiB`,
+	)
+	flagSet.Int64Var(
+		&f.TarVolumeSize,
+		tarVolumeSizeFlagName,
+		0,
+		fmt.Sprintf(
+			`Split a .tar --%s into volumes of at most this many megabytes, named <name>.000.tar, <name>.001.tar, and so on.
+Files are never split across volumes`,
+			outputFlagName,
+		),
 	)
 	_ = appcmd.MarkFlagRequired(flagSet, outputFlagName)
 	flagSet.StringVar(
@@ -128,7 +185,21 @@ func run(
 	ctx context.Context,
 	container appext.Container,
 	flags *flags,
-) error {
+) (retErr error) {
+	if flags.TarVolumeSize < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be positive", tarVolumeSizeFlagName)
+	}
+	if flags.TarVolumeSize > 0 && filepath.Ext(flags.Output) != tarExt {
+		return appcmd.NewInvalidArgumentErrorf("--%s can only be set if --%s is a %s file", tarVolumeSizeFlagName, outputFlagName, tarExt)
+	}
+	if flags.ExcludeImports {
+		if flags.IncludeImports {
+			return appcmd.NewInvalidArgumentErrorf("cannot set both --%s and --%s", includeImportsFlagName, excludeImportsFlagName)
+		}
+		if flags.IncludeWKT {
+			return appcmd.NewInvalidArgumentErrorf("cannot set both --%s and --%s", includeWKTFlagName, excludeImportsFlagName)
+		}
+	}
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
@@ -151,20 +222,13 @@ func run(
 	}
 	moduleReadBucket := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(workspace)
 
-	if err := os.MkdirAll(flags.Output, 0755); err != nil {
-		return err
-	}
-	var options []storageos.ProviderOption
-	if !flags.DisableSymlinks {
-		options = append(options, storageos.ProviderWithSymlinks())
-	}
-	readWriteBucket, err := storageos.NewProvider(options...).NewReadWriteBucket(
-		flags.Output,
-		storageos.ReadWriteBucketWithSymlinksIfSupported(),
-	)
+	readWriteBucket, closeOutputBucket, err := newOutputBucket(container, flags)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, closeOutputBucket())
+	}()
 
 	// In the case where we are excluding imports, we are allowing users to specify an input
 	// that may not have resolved imports (https://github.com/bufbuild/buf/issues/3002).
@@ -214,6 +278,13 @@ func run(
 				//
 				// This is the only case where a file may exist in the Image but not in the Workspace. Any other case where a file
 				// does not exist is a system error.
+				//
+				// If the user asked for the WKTs, we export them from our embedded copy.
+				if flags.IncludeWKT {
+					if err := storage.CopyPath(ctx, datawkt.ReadBucket, imageFile.Path(), readWriteBucket, imageFile.Path()); err != nil {
+						return err
+					}
+				}
 				continue
 			}
 			return syserror.Wrap(err)
@@ -227,3 +298,60 @@ func run(
 	}
 	return nil
 }
+
+// newOutputBucket returns the bucket for the output, which is either an object
+// storage URL, a tar archive, or a directory that is created if it does not exist.
+//
+// The returned function must be called once all files are written.
+func newOutputBucket(container appext.Container, flags *flags) (storage.WriteBucket, func() error, error) {
+	nopClose := func() error { return nil }
+	readWriteBucket, ok, err := bufcli.NewObjectStorageBucket(container, flags.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		// Objects are only created once fully written, so writes are already atomic.
+		return readWriteBucket, nopClose, nil
+	}
+	if filepath.Ext(flags.Output) == tarExt {
+		tarWriteBucket := newTarWriteBucket(flags.Output, flags.TarVolumeSize)
+		return tarWriteBucket, tarWriteBucket.Close, nil
+	}
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return nil, nil, err
+	}
+	var options []storageos.ProviderOption
+	if !flags.DisableSymlinks {
+		options = append(options, storageos.ProviderWithSymlinks())
+	}
+	readWriteBucket, err = storageos.NewProvider(options...).NewReadWriteBucket(
+		flags.Output,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+		// An interrupted export should not leave partially-written files behind.
+		storageos.ReadWriteBucketWithAtomicWrites(),
+		storageos.ReadWriteBucketWithSyncWrites(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return readWriteBucket, nopClose, nil
+}
+
+// newTarWriteBucket returns a new TarWriteBucket that streams files to the tar file at
+// outputPath, or to volumes next to it if tarVolumeSize is set.
+func newTarWriteBucket(outputPath string, tarVolumeSize int64) storagearchive.TarWriteBucket {
+	if tarVolumeSize == 0 {
+		return storagearchive.NewTarWriteBucket(
+			func(int) (io.WriteCloser, error) {
+				return os.Create(outputPath)
+			},
+		)
+	}
+	outputPathWithoutExt := strings.TrimSuffix(outputPath, tarExt)
+	return storagearchive.NewTarWriteBucket(
+		func(volumeIndex int) (io.WriteCloser, error) {
+			return os.Create(fmt.Sprintf("%s.%03d%s", outputPathWithoutExt, volumeIndex, tarExt))
+		},
+		storagearchive.TarWriteBucketWithMaxVolumeSize(tarVolumeSize*1024*1024),
+	)
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 	"github.com/spf13/pflag"
 	"go.uber.org/multierr"
 )
@@ -344,7 +345,11 @@ func run(
 
 	if flags.Diff {
 		if diffExists {
-			if _, err := io.Copy(container.Stdout(), diffBuffer); err != nil {
+			styler, err := bufcli.NewStyler(container, container.Stdout())
+			if err != nil {
+				return err
+			}
+			if _, err := container.Stdout().Write(termstyle.StyleDiff(styler, diffBuffer.Bytes())); err != nil {
 				return err
 			}
 		}
@@ -31,7 +31,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
-	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/pflag"
@@ -51,6 +51,7 @@ const (
 	disableSymlinksFlagName     = "disable-symlinks"
 	typeFlagName                = "type"
 	typeDeprecatedFlagName      = "include-types"
+	pluginLogDirPathFlagName    = "plugin-log-dir"
 )
 
 // NewCommand returns a new Command.
@@ -366,6 +367,9 @@ Insertion points are processed in the order the plugins are specified in the tem
 			},
 		),
 		BindFlags: flags.Bind,
+		CompleteFlags: map[string]appcmd.CompletionFunc{
+			typeFlagName: bufcli.NewTypeCompletionFunc(builder, false),
+		},
 	}
 }
 
@@ -381,6 +385,7 @@ type flags struct {
 	IncludeWKTOverride     *bool
 	ExcludePaths           []string
 	DisableSymlinks        bool
+	PluginLogDirPath       string
 	// We may be able to bind two flags to one string slice but I don't
 	// want to find out what will break if we do.
 	Types           []string
@@ -424,7 +429,9 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		baseOutDirPathFlagName,
 		baseOutDirPathFlagShortName,
 		".",
-		`The base directory to generate to. This is prepended to the out directories in the generation template`,
+		`The base directory to generate to. This is prepended to the out directories in the generation template.
+May be an s3:// or gs:// URL to generate to object storage.
+Object storage credentials are only read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or GOOGLE_OAUTH_ACCESS_TOKEN, and each file is limited to 5 GiB`,
 	)
 	bindBoolPointer(
 		flagSet,
@@ -447,6 +454,12 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		"",
 		`The buf.yaml file or data to use for configuration`,
 	)
+	flagSet.StringVar(
+		&f.PluginLogDirPath,
+		pluginLogDirPathFlagName,
+		"",
+		`The directory to write the full stderr of each local plugin to. Each plugin is written to a file named after the plugin, and the directory is created if it does not exist`,
+	)
 	flagSet.StringSliceVar(
 		&f.Types,
 		typeFlagName,
@@ -518,8 +531,19 @@ func run(
 	if err != nil {
 		return err
 	}
+	pluginOutputLimit, err := bufcli.GetPluginOutputLimit(container)
+	if err != nil {
+		return err
+	}
 	generateOptions := []bufgen.GenerateOption{
 		bufgen.GenerateWithBaseOutDirPath(flags.BaseOutDirPath),
+		bufgen.GenerateWithPluginOutputLimit(pluginOutputLimit),
+		// Outputs may be s3:// or gs:// URLs, either through --output or the out of a plugin.
+		bufgen.GenerateWithOutBucketFunc(
+			func(_ context.Context, pluginOut string) (storage.ReadWriteBucket, bool, error) {
+				return bufcli.NewObjectStorageBucket(container, pluginOut)
+			},
+		),
 	}
 	if flags.DeleteOuts != nil {
 		generateOptions = append(
@@ -539,10 +563,20 @@ func run(
 			bufgen.GenerateWithIncludeWellKnownTypesOverride(*flags.IncludeWKTOverride),
 		)
 	}
+	if flags.PluginLogDirPath != "" {
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithPluginLogDirPath(flags.PluginLogDirPath),
+		)
+	}
+	pluginRunner, err := bufcli.NewPluginRunner(container)
+	if err != nil {
+		return err
+	}
 	return bufgen.NewGenerator(
 		logger,
 		storageosProvider,
-		command.NewRunner(),
+		pluginRunner,
 		clientConfig,
 	).Generate(
 		ctx,
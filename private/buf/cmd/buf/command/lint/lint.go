@@ -23,9 +23,9 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
-	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/wasm"
 	"github.com/spf13/pflag"
@@ -143,11 +143,33 @@ func run(
 	defer func() {
 		retErr = multierr.Append(retErr, wasmRuntime.Close(ctx))
 	}()
+	pluginRunner, err := bufcli.NewPluginRunner(container)
+	if err != nil {
+		return err
+	}
+	httpClient, err := bufcli.NewHTTPClient(container)
+	if err != nil {
+		return err
+	}
+	pluginResolver, err := bufpluginmanifest.NewResolverForEnv(container)
+	if err != nil {
+		return err
+	}
+	pluginRunnerInterceptors, err := bufcli.NewPluginRunnerInterceptors(container)
+	if err != nil {
+		return err
+	}
 	var allFileAnnotations []bufanalysis.FileAnnotation
 	for _, imageWithConfig := range imageWithConfigs {
 		client, err := bufcheck.NewClient(
 			container.Logger(),
-			bufcheck.NewRunnerProvider(command.NewRunner(), wasmRuntime),
+			bufcheck.NewRunnerProvider(
+				pluginRunner,
+				wasmRuntime,
+				bufcheck.RunnerProviderWithHTTPClient(httpClient),
+				bufcheck.RunnerProviderWithPluginResolver(pluginResolver),
+				bufcheck.RunnerProviderWithInterceptors(pluginRunnerInterceptors...),
+			),
 			bufcheck.ClientWithStderr(container.Stderr()),
 		)
 		if err != nil {
@@ -180,15 +202,23 @@ func run(
 				return err
 			}
 		} else {
+			styler, err := bufcli.NewStyler(container, container.Stdout())
+			if err != nil {
+				return err
+			}
 			if err := bufanalysis.PrintFileAnnotationSet(
 				container.Stdout(),
 				allFileAnnotationSet,
 				flags.ErrorFormat,
+				bufanalysis.PrintFileAnnotationSetWithStyler(styler),
 			); err != nil {
 				return err
 			}
 		}
-		return bufctl.ErrFileAnnotation
+		if err := bufcli.SetFileAnnotationSetResult(ctx, allFileAnnotationSet); err != nil {
+			return err
+		}
+		return bufctl.ErrLintViolation
 	}
 	return nil
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/buf/bufworkspace"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -320,6 +321,22 @@ func getBuildableWorkspace(
 	if err != nil {
 		return nil, err
 	}
+	// Replacements only exist for local development, the pushed Modules would depend on
+	// content that is not what the BSR has for the replaced dependencies.
+	if replaceConfigs := workspace.ReplaceConfigs(); len(replaceConfigs) > 0 {
+		return nil, appcmd.NewInvalidArgumentErrorf(
+			"cannot push a workspace with replace directives, remove the replace directives for %s from your buf.yaml and run buf dep update",
+			strings.Join(
+				slicesext.Map(
+					replaceConfigs,
+					func(replaceConfig bufconfig.ReplaceConfig) string {
+						return replaceConfig.ModuleFullName().String()
+					},
+				),
+				", ",
+			),
+		)
+	}
 	// Make sure the workspace builds.
 	if _, err := controller.GetImageForWorkspace(
 		ctx,
@@ -22,6 +22,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/commit/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -40,9 +41,12 @@ func NewCommand(
 ) *appcmd.Command {
 	flags := newFlags()
 	return &appcmd.Command{
-		Use:   name + " <remote/owner/repository:commit>",
+		Use:   name + " <remote/owner/repository:ref>",
 		Short: "Get commit information",
-		Args:  appcmd.ExactArgs(1),
+		Long: `Get information about a commit, including its digest, author, and the labels that point to it.
+
+The ref may be a commit ID or a label. If the ref is a label, the commit the label currently points to is used.`,
+		Args: appcmd.ExactArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appext.Container) error {
 				return run(ctx, container, flags)
@@ -79,11 +83,7 @@ func run(
 		return appcmd.WrapInvalidArgumentError(err)
 	}
 	if moduleRef.Ref() == "" {
-		return appcmd.NewInvalidArgumentErrorf("%q does not have a commit specified", moduleRef.String())
-	}
-	commitID := moduleRef.Ref()
-	if _, err := uuidutil.FromDashless(commitID); err != nil {
-		return appcmd.NewInvalidArgumentErrorf("invalid commit: %w", err)
+		return appcmd.NewInvalidArgumentErrorf("%q does not have a commit or label specified", moduleRef.String())
 	}
 	format, err := bufprint.ParseFormat(flags.Format)
 	if err != nil {
@@ -94,17 +94,14 @@ func run(
 	if err != nil {
 		return err
 	}
-	commitServiceClient := bufapi.NewClientProvider(clientConfig).V1CommitServiceClient(moduleRef.ModuleFullName().Registry())
+	clientProvider := bufapi.NewClientProvider(clientConfig)
+	commitServiceClient := clientProvider.V1CommitServiceClient(moduleRef.ModuleFullName().Registry())
 	resp, err := commitServiceClient.GetCommits(
 		ctx,
 		connect.NewRequest(
 			&modulev1.GetCommitsRequest{
 				ResourceRefs: []*modulev1.ResourceRef{
-					{
-						Value: &modulev1.ResourceRef_Id{
-							Id: moduleRef.Ref(),
-						},
-					},
+					getResourceRef(moduleRef),
 				},
 			},
 		),
@@ -119,9 +116,42 @@ func run(
 	if len(commits) != 1 {
 		return syserror.Newf("expect 1 commit from response, got %d", len(commits))
 	}
+	entities, err := internal.NewCommitEntities(
+		ctx,
+		container,
+		clientProvider,
+		moduleRef.ModuleFullName(),
+		commits,
+		true,
+	)
+	if err != nil {
+		return err
+	}
 	return bufprint.PrintEntity(
 		container.Stdout(),
 		format,
-		bufprint.NewCommitEntity(commits[0], moduleRef.ModuleFullName()),
+		entities[0],
 	)
 }
+
+// getResourceRef returns the ResourceRef for the commit or label referenced by the ModuleRef.
+func getResourceRef(moduleRef bufmodule.ModuleRef) *modulev1.ResourceRef {
+	if _, err := uuidutil.FromDashless(moduleRef.Ref()); err == nil {
+		return &modulev1.ResourceRef{
+			Value: &modulev1.ResourceRef_Id{
+				Id: moduleRef.Ref(),
+			},
+		}
+	}
+	return &modulev1.ResourceRef{
+		Value: &modulev1.ResourceRef_Name_{
+			Name: &modulev1.ResourceRef_Name{
+				Owner:  moduleRef.ModuleFullName().Owner(),
+				Module: moduleRef.ModuleFullName().Name(),
+				Child: &modulev1.ResourceRef_Name_Ref{
+					Ref: moduleRef.Ref(),
+				},
+			},
+		},
+	}
+}
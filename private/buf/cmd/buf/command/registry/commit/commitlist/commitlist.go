@@ -22,6 +22,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/commit/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -153,13 +154,24 @@ func run(
 	}
 	resource := resources[0]
 	if commit := resource.GetCommit(); commit != nil {
+		entities, err := internal.NewCommitEntities(
+			ctx,
+			container,
+			clientProvider,
+			moduleRef.ModuleFullName(),
+			[]*modulev1.Commit{commit},
+			false,
+		)
+		if err != nil {
+			return err
+		}
 		// If the ref is a commit, the commit is the only result and there is no next page.
 		return bufprint.PrintPage(
 			container.Stdout(),
 			format,
 			"",
 			"",
-			[]bufprint.Entity{bufprint.NewCommitEntity(commit, moduleRef.ModuleFullName())},
+			entities,
 		)
 	}
 	if resource.GetModule() != nil {
@@ -192,14 +204,23 @@ func run(
 			}
 			return err
 		}
+		entities, err := internal.NewCommitEntities(
+			ctx,
+			container,
+			clientProvider,
+			moduleRef.ModuleFullName(),
+			resp.Msg.Commits,
+			false,
+		)
+		if err != nil {
+			return err
+		}
 		return bufprint.PrintPage(
 			container.Stdout(),
 			format,
 			resp.Msg.NextPageToken,
 			nextPageCommand(container, flags, resp.Msg.NextPageToken),
-			slicesext.Map(resp.Msg.Commits, func(commit *modulev1.Commit) bufprint.Entity {
-				return bufprint.NewCommitEntity(commit, moduleRef.ModuleFullName())
-			}),
+			entities,
 		)
 	}
 	label := resource.GetLabel()
@@ -244,14 +265,23 @@ func run(
 			return value.Commit
 		},
 	)
+	entities, err := internal.NewCommitEntities(
+		ctx,
+		container,
+		clientProvider,
+		moduleRef.ModuleFullName(),
+		commits,
+		false,
+	)
+	if err != nil {
+		return err
+	}
 	return bufprint.PrintPage(
 		container.Stdout(),
 		format,
 		resp.Msg.NextPageToken,
 		nextPageCommand(container, flags, resp.Msg.NextPageToken),
-		slicesext.Map(commits, func(commit *modulev1.Commit) bufprint.Entity {
-			return bufprint.NewCommitEntity(commit, moduleRef.ModuleFullName())
-		}),
+		entities,
 	)
 }
 
@@ -0,0 +1,163 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+
+	"buf.build/gen/go/bufbuild/registry/connectrpc/go/buf/registry/module/v1/modulev1connect"
+	"buf.build/gen/go/bufbuild/registry/connectrpc/go/buf/registry/owner/v1/ownerv1connect"
+	modulev1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/module/v1"
+	ownerv1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/owner/v1"
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+)
+
+// NewCommitEntities returns the entities to print for the commits.
+//
+// The author of each commit is resolved to the name of the user that created the commit.
+// If the user cannot be resolved, for example because the user was deleted, the user ID is used.
+//
+// If withLabels is true, the labels that currently point to each commit are also listed,
+// which requires one request per commit.
+func NewCommitEntities(
+	ctx context.Context,
+	container appext.Container,
+	clientProvider bufapi.ClientProvider,
+	moduleFullName bufmodule.ModuleFullName,
+	commits []*modulev1.Commit,
+	withLabels bool,
+) ([]bufprint.Entity, error) {
+	registry := moduleFullName.Registry()
+	userIDToName := getUserIDToName(
+		ctx,
+		container,
+		clientProvider.V1UserServiceClient(registry),
+		slicesext.ToUniqueSorted(
+			slicesext.Map(
+				commits,
+				func(commit *modulev1.Commit) string {
+					return commit.CreatedByUserId
+				},
+			),
+		),
+	)
+	entities := make([]bufprint.Entity, len(commits))
+	for i, commit := range commits {
+		var options []bufprint.CommitEntityOption
+		if commit.CreatedByUserId != "" {
+			author, ok := userIDToName[commit.CreatedByUserId]
+			if !ok {
+				author = commit.CreatedByUserId
+			}
+			options = append(options, bufprint.CommitEntityWithAuthor(author))
+		}
+		if withLabels {
+			labelNames, err := getLabelNamesForCommitID(ctx, clientProvider.V1LabelServiceClient(registry), commit.Id)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, bufprint.CommitEntityWithLabels(labelNames...))
+		}
+		entities[i] = bufprint.NewCommitEntity(commit, moduleFullName, options...)
+	}
+	return entities, nil
+}
+
+// *** PRIVATE ***
+
+const listLabelsPageSize = 250
+
+// getUserIDToName resolves the user IDs to user names.
+//
+// Resolving the author is best-effort, any user that cannot be resolved is not present
+// in the returned map.
+func getUserIDToName(
+	ctx context.Context,
+	container appext.Container,
+	userServiceClient ownerv1connect.UserServiceClient,
+	userIDs []string,
+) map[string]string {
+	userIDs = slicesext.Filter(userIDs, func(userID string) bool { return userID != "" })
+	if len(userIDs) == 0 {
+		return nil
+	}
+	response, err := userServiceClient.GetUsers(
+		ctx,
+		connect.NewRequest(
+			&ownerv1.GetUsersRequest{
+				UserRefs: slicesext.Map(
+					userIDs,
+					func(userID string) *ownerv1.UserRef {
+						return &ownerv1.UserRef{
+							Value: &ownerv1.UserRef_Id{
+								Id: userID,
+							},
+						}
+					},
+				),
+			},
+		),
+	)
+	if err != nil {
+		container.Logger().DebugContext(ctx, "failed to resolve commit authors", slogext.ErrorAttr(err))
+		return nil
+	}
+	userIDToName := make(map[string]string, len(response.Msg.Users))
+	for _, user := range response.Msg.Users {
+		userIDToName[user.Id] = user.Name
+	}
+	return userIDToName
+}
+
+func getLabelNamesForCommitID(
+	ctx context.Context,
+	labelServiceClient modulev1connect.LabelServiceClient,
+	commitID string,
+) ([]string, error) {
+	var labelNames []string
+	var pageToken string
+	for {
+		response, err := labelServiceClient.ListLabels(
+			ctx,
+			connect.NewRequest(
+				&modulev1.ListLabelsRequest{
+					PageSize:  listLabelsPageSize,
+					PageToken: pageToken,
+					ResourceRef: &modulev1.ResourceRef{
+						Value: &modulev1.ResourceRef_Id{
+							Id: commitID,
+						},
+					},
+				},
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range response.Msg.Labels {
+			labelNames = append(labelNames, label.Name)
+		}
+		pageToken = response.Msg.NextPageToken
+		if pageToken == "" {
+			return slicesext.ToUniqueSorted(labelNames), nil
+		}
+	}
+}
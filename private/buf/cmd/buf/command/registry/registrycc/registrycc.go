@@ -16,17 +16,29 @@ package registrycc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/spf13/pflag"
 )
 
+const (
+	statsFlagName    = "stats"
+	statsTopFlagName = "stats-top"
+	formatFlagName   = "format"
+
+	defaultStatsTop = 10
+)
+
 // NewCommand returns a new Command.
 func NewCommand(
 	name string,
@@ -37,9 +49,13 @@ func NewCommand(
 ) *appcmd.Command {
 	flags := newFlags()
 	return &appcmd.Command{
-		Use:        name,
-		Aliases:    aliases,
-		Short:      "Clear the registry cache",
+		Use:     name,
+		Aliases: aliases,
+		Short:   "Clear the registry cache",
+		Long: `Clear the registry cache.
+
+If --stats is set, statistics about the registry cache are printed instead, and the cache
+is not cleared. Cache hit and miss counts are reset whenever the cache is cleared.`,
 		Args:       appcmd.NoArgs,
 		Deprecated: deprecated,
 		Hidden:     hidden,
@@ -52,19 +68,52 @@ func NewCommand(
 	}
 }
 
-type flags struct{}
+type flags struct {
+	Stats    bool
+	StatsTop int
+	Format   string
+
+	// so we can inquire about which flags present on command-line
+	flagSet *pflag.FlagSet
+}
 
 func newFlags() *flags {
 	return &flags{}
 }
 
-func (f *flags) Bind(flagSet *pflag.FlagSet) {}
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	f.flagSet = flagSet
+	flagSet.BoolVar(
+		&f.Stats,
+		statsFlagName,
+		false,
+		"Print statistics about the registry cache instead of clearing it",
+	)
+	flagSet.IntVar(
+		&f.StatsTop,
+		statsTopFlagName,
+		defaultStatsTop,
+		fmt.Sprintf("The number of largest modules to print with --%s", statsFlagName),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf("The output format to use with --%s. Must be one of %s", statsFlagName, bufprint.AllFormatsString),
+	)
+}
 
 func run(
 	ctx context.Context,
 	container appext.Container,
 	flags *flags,
 ) error {
+	if flags.Stats {
+		return runStats(ctx, container, flags)
+	}
+	if flags.flagSet.Changed(statsTopFlagName) {
+		return appcmd.NewInvalidArgumentErrorf("--%s can only be set with --%s", statsTopFlagName, statsFlagName)
+	}
 	for _, cacheModuleRelDirPath := range bufcli.AllCacheModuleRelDirPaths {
 		dirPath := filepath.Join(container.CacheDirPath(), normalpath.Unnormalize(cacheModuleRelDirPath))
 		fileInfo, err := os.Stat(dirPath)
@@ -86,3 +135,94 @@ func run(
 	}
 	return nil
 }
+
+func runStats(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	if flags.StatsTop < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be non-negative", statsTopFlagName)
+	}
+	cacheStats, err := bufcli.GetCacheStats(ctx, container, flags.StatsTop)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case bufprint.FormatText:
+		return printCacheStatsText(container, cacheStats)
+	case bufprint.FormatJSON:
+		return json.NewEncoder(container.Stdout()).Encode(cacheStats)
+	default:
+		return syserror.Newf("unknown format: %s", format)
+	}
+}
+
+func printCacheStatsText(container appext.Container, cacheStats *bufcli.CacheStats) error {
+	if err := bufprint.WithTabWriter(
+		container.Stdout(),
+		[]string{"Modules", "Blobs", "Bytes", "Hits", "Misses"},
+		func(tabWriter bufprint.TabWriter) error {
+			return tabWriter.Write(
+				strconv.Itoa(cacheStats.NumModules),
+				strconv.Itoa(cacheStats.NumBlobs),
+				strconv.FormatInt(cacheStats.TotalBytes, 10),
+				strconv.FormatInt(cacheStats.Hits, 10),
+				strconv.FormatInt(cacheStats.Misses, 10),
+			)
+		},
+	); err != nil {
+		return err
+	}
+	if len(cacheStats.Remotes) > 0 {
+		if _, err := fmt.Fprintln(container.Stdout()); err != nil {
+			return err
+		}
+		if err := bufprint.WithTabWriter(
+			container.Stdout(),
+			[]string{"Remote", "Modules", "Blobs", "Bytes"},
+			func(tabWriter bufprint.TabWriter) error {
+				for _, cacheRemoteStats := range cacheStats.Remotes {
+					if err := tabWriter.Write(
+						cacheRemoteStats.Remote,
+						strconv.Itoa(cacheRemoteStats.NumModules),
+						strconv.Itoa(cacheRemoteStats.NumBlobs),
+						strconv.FormatInt(cacheRemoteStats.TotalBytes, 10),
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+	}
+	if len(cacheStats.LargestModules) > 0 {
+		if _, err := fmt.Fprintln(container.Stdout()); err != nil {
+			return err
+		}
+		return bufprint.WithTabWriter(
+			container.Stdout(),
+			[]string{"Module", "Commit", "Blobs", "Bytes"},
+			func(tabWriter bufprint.TabWriter) error {
+				for _, cacheModuleStats := range cacheStats.LargestModules {
+					if err := tabWriter.Write(
+						cacheModuleStats.Name,
+						cacheModuleStats.Commit,
+						strconv.Itoa(cacheModuleStats.NumBlobs),
+						strconv.FormatInt(cacheModuleStats.TotalBytes, 10),
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		)
+	}
+	return nil
+}
@@ -19,13 +19,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 
 	"connectrpc.com/connect"
-	"github.com/bufbuild/buf/private/buf/bufapp"
 	"github.com/bufbuild/buf/private/buf/bufcli"
-	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrywhoami"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -34,15 +35,17 @@ import (
 	"github.com/bufbuild/buf/private/pkg/netext"
 	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/bufbuild/buf/private/pkg/oauth2"
-	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
 	"github.com/pkg/browser"
 	"github.com/spf13/pflag"
 )
 
 const (
-	usernameFlagName   = "username"
-	tokenStdinFlagName = "token-stdin"
-	promptFlagName     = "prompt"
+	usernameFlagName    = "username"
+	tokenStdinFlagName  = "token-stdin"
+	promptFlagName      = "prompt"
+	ssoFlagName         = "sso"
+	ssoClientIDFlagName = "sso-client-id"
+	listFlagName        = "list"
 )
 
 // NewCommand returns a new Command.
@@ -54,8 +57,10 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:   name + " <domain>",
 		Short: `Log in to the Buf Schema Registry`,
-		Long:  fmt.Sprintf(`This command will open a browser to complete the login process. Use the flags --%s or --%s to complete an alternative login flow. The token is saved to your %s file. The <domain> argument will default to buf.build if not specified.`, promptFlagName, tokenStdinFlagName, netrc.Filename),
-		Args:  appcmd.MaximumNArgs(1),
+		Long: fmt.Sprintf(`This command will open a browser to complete the login process. Use the flags --%s or --%s to complete an alternative login flow. The token is saved to the OS keychain (the macOS Keychain, the Windows Credential Manager, or the Secret Service on other platforms) if it is available, and to your %s file otherwise. Set BUF_CREDENTIAL_STORE to "keychain" or "netrc" to require a specific store. The <domain> argument will default to the remote of the selected profile, or buf.build, if not specified.
+
+Use the flag --%s to log in with the single sign-on provider of the remote, using the OAuth 2.0 device authorization flow. This requires the remote to serve OpenID Connect discovery metadata. Instead of a long-lived token, a short-lived access token and a refresh token are saved to %s within the configuration directory, and the access token is refreshed automatically as it expires.`, promptFlagName, tokenStdinFlagName, netrc.Filename, ssoFlagName, bufcli.SSOCredentialsFileName),
+		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appext.Container) error {
 				return run(ctx, container, flags)
@@ -66,9 +71,12 @@ func NewCommand(
 }
 
 type flags struct {
-	Username   string
-	TokenStdin bool
-	Prompt     bool
+	Username    string
+	TokenStdin  bool
+	Prompt      bool
+	SSO         bool
+	SSOClientID string
+	List        bool
 }
 
 func newFlags() *flags {
@@ -102,6 +110,31 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 			tokenStdinFlagName,
 		),
 	)
+	flagSet.BoolVar(
+		&f.SSO,
+		ssoFlagName,
+		false,
+		fmt.Sprintf(
+			"Log in with the single sign-on provider of the remote using the device authorization flow. Exclusive with the flags --%s and --%s.",
+			tokenStdinFlagName,
+			promptFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.SSOClientID,
+		ssoClientIDFlagName,
+		"",
+		fmt.Sprintf(
+			"The OAuth 2.0 client ID to use with --%s. Required if the single sign-on provider does not support dynamic client registration.",
+			ssoFlagName,
+		),
+	)
+	flagSet.BoolVar(
+		&f.List,
+		listFlagName,
+		false,
+		"List every remote that has credentials configured, with the user, source, and expiry of the credentials, instead of logging in. The same as buf registry whoami --all.",
+	)
 }
 
 func run(
@@ -123,6 +156,12 @@ func run(
 	// Note that this does not gracefully handle the case where the terminal is
 	// in no-echo mode, as is the case when prompting for a password
 	// interactively.
+	if flags.List {
+		if container.NumArgs() > 0 || flags.TokenStdin || flags.Prompt || flags.SSO {
+			return appcmd.NewInvalidArgumentErrorf("cannot use --%s with a <domain> argument or other flags", listFlagName)
+		}
+		return registrywhoami.PrintAllLoginStatuses(ctx, container, bufprint.FormatText)
+	}
 	errC := make(chan error, 1)
 	go func() {
 		errC <- inner(ctx, container, flags)
@@ -151,7 +190,7 @@ func inner(
 	container appext.Container,
 	flags *flags,
 ) error {
-	remote := bufconnect.DefaultRemote
+	remote := bufcli.GetDefaultRemote(container)
 	if container.NumArgs() == 1 {
 		remote = container.Arg(0)
 		if _, err := netext.ValidateHostname(remote); err != nil {
@@ -161,8 +200,22 @@ func inner(
 	if flags.TokenStdin && flags.Prompt {
 		return appcmd.NewInvalidArgumentErrorf("cannot use both --%s and --%s flags", tokenStdinFlagName, promptFlagName)
 	}
+	if flags.SSO && (flags.TokenStdin || flags.Prompt) {
+		return appcmd.NewInvalidArgumentErrorf("cannot use --%s with the --%s or --%s flags", ssoFlagName, tokenStdinFlagName, promptFlagName)
+	}
+	if flags.SSOClientID != "" && !flags.SSO {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", ssoClientIDFlagName, ssoFlagName)
+	}
 	var token string
-	if flags.TokenStdin {
+	var ssoCredentials *bufcli.SSOCredentials
+	if flags.SSO {
+		var err error
+		ssoCredentials, err = doSSOLogin(ctx, container, remote, flags.SSOClientID)
+		if err != nil {
+			return err
+		}
+		token = ssoCredentials.AccessToken
+	} else if flags.TokenStdin {
 		data, err := io.ReadAll(container.Stdin())
 		if err != nil {
 			return fmt.Errorf("unable to read token from stdin: %w", err)
@@ -212,24 +265,30 @@ func inner(
 	if user == nil {
 		return errors.New("no user found for provided token")
 	}
-	if err := netrc.PutMachines(
-		container,
-		netrc.NewMachine(
-			remote,
-			user.Username,
-			token,
-		),
-	); err != nil {
-		return err
-	}
-	if _, err := netrc.DeleteMachineForName(container, "go."+remote); err != nil {
-		return err
-	}
-	netrcFilePath, err := netrc.GetFilePath(container)
-	if err != nil {
-		return err
+	var credentialsFilePath string
+	if ssoCredentials != nil {
+		if err := bufcli.PutSSOCredentials(ctx, container, remote, ssoCredentials); err != nil {
+			return err
+		}
+		credentialsFilePath = bufcli.GetSSOCredentialsFilePath(container)
+	} else {
+		credentialsFilePath, err = bufcli.PutMachine(
+			ctx,
+			container,
+			netrc.NewMachine(
+				remote,
+				user.Username,
+				token,
+			),
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := bufcli.DeleteMachineForName(ctx, container, "go."+remote); err != nil {
+			return err
+		}
 	}
-	loggedInMessage := fmt.Sprintf("Logged in as %s. Credentials saved to %s.\n", user.Username, netrcFilePath)
+	loggedInMessage := fmt.Sprintf("Logged in as %s. Credentials saved to %s.\n", user.Username, credentialsFilePath)
 	// Unless we did not prompt at all, print a newline first
 	if !flags.TokenStdin {
 		loggedInMessage = "\n" + loggedInMessage
@@ -275,15 +334,10 @@ func doBrowserLogin(
 	if err != nil {
 		return "", err
 	}
-	externalConfig := bufapp.ExternalConfig{}
-	if err := appext.ReadConfig(container, &externalConfig); err != nil {
-		return "", err
-	}
-	appConfig, err := bufapp.NewConfig(container, externalConfig)
+	client, err := bufcli.NewHTTPClient(container)
 	if err != nil {
 		return "", err
 	}
-	client := httpclient.NewClient(appConfig.TLS)
 	oauth2Client := oauth2.NewClient(baseURL, client)
 	// Register the device.
 	deviceRegistration, err := oauth2Client.RegisterDevice(ctx, &oauth2.DeviceRegistrationRequest{
@@ -340,3 +394,103 @@ If your browser doesn't open automatically, please open this URL in a browser to
 	}
 	return deviceToken.AccessToken, nil
 }
+
+// doSSOLogin performs the device authorization grant flow with the OpenID Connect
+// provider of the remote.
+func doSSOLogin(
+	ctx context.Context,
+	container appext.Container,
+	remote string,
+	clientID string,
+) (*bufcli.SSOCredentials, error) {
+	baseURL := "https://" + remote
+	client, err := bufcli.NewHTTPClient(container)
+	if err != nil {
+		return nil, err
+	}
+	serverMetadata, err := oauth2.NewClient(baseURL, client).GetServerMetadata(ctx)
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, fmt.Errorf("%s does not support single sign-on login", remote)
+		}
+		return nil, err
+	}
+	if serverMetadata.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("the single sign-on provider of %s does not support the device authorization flow", remote)
+	}
+	tokenEndpoint := serverMetadata.TokenEndpoint
+	if tokenEndpoint == "" {
+		tokenEndpoint = baseURL + oauth2.DeviceTokenPath
+	}
+	oauth2Client := oauth2.NewClient(baseURL, client, oauth2.ClientWithServerMetadata(serverMetadata))
+	var clientSecret string
+	if clientID == "" {
+		if serverMetadata.RegistrationEndpoint == "" {
+			return nil, fmt.Errorf(
+				"the single sign-on provider of %s does not support dynamic client registration, use --%s to set the client ID",
+				remote,
+				ssoClientIDFlagName,
+			)
+		}
+		clientName, err := getClientName()
+		if err != nil {
+			return nil, err
+		}
+		deviceRegistration, err := oauth2Client.RegisterDevice(ctx, &oauth2.DeviceRegistrationRequest{
+			ClientName: clientName,
+		})
+		if err != nil {
+			return nil, newAuthorizationError(err)
+		}
+		clientID = deviceRegistration.ClientID
+		clientSecret = deviceRegistration.ClientSecret
+	}
+	var scope string
+	if slices.Contains(serverMetadata.ScopesSupported, oauth2.OfflineAccessScope) {
+		// Request a refresh token, so that the short-lived access token can be refreshed.
+		scope = "openid " + oauth2.OfflineAccessScope
+	}
+	deviceAuthorization, err := oauth2Client.AuthorizeDevice(ctx, &oauth2.DeviceAuthorizationRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        scope,
+	})
+	if err != nil {
+		return nil, newAuthorizationError(err)
+	}
+	verificationURI := deviceAuthorization.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = deviceAuthorization.VerificationURI
+	}
+	if _, err := fmt.Fprintf(
+		container.Stdout(),
+		`To log in, open this URL in a browser and confirm the code %s:
+
+%s
+`,
+		deviceAuthorization.UserCode,
+		verificationURI,
+	); err != nil {
+		return nil, err
+	}
+	// The device may not have a browser, in which case the user opens the URL elsewhere.
+	_ = browser.OpenURL(verificationURI)
+	deviceToken, err := oauth2Client.AccessDeviceToken(ctx, &oauth2.DeviceAccessTokenRequest{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		DeviceCode:   deviceAuthorization.DeviceCode,
+		GrantType:    oauth2.DeviceAuthorizationGrantType,
+	}, oauth2.AccessDeviceTokenWithPollingInterval(time.Duration(deviceAuthorization.Interval)*time.Second))
+	if err != nil {
+		return nil, newAuthorizationError(err)
+	}
+	return bufcli.NewSSOCredentials(clientID, clientSecret, tokenEndpoint, deviceToken), nil
+}
+
+func newAuthorizationError(err error) error {
+	var oauth2Err *oauth2.Error
+	if errors.As(err, &oauth2Err) {
+		return fmt.Errorf("authorization failed: %s", oauth2Err.ErrorDescription)
+	}
+	return err
+}
@@ -18,7 +18,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/netext"
@@ -33,11 +33,9 @@ func NewCommand(
 ) *appcmd.Command {
 	flags := newFlags()
 	return &appcmd.Command{
-		// Not documenting the first arg (remote) as this is just for testing for now.
-		// TODO: Update when we have self-hosted.
-		Use:   name,
+		Use:   name + " <domain>",
 		Short: `Log out of the Buf Schema Registry`,
-		Long:  fmt.Sprintf(`This command removes any BSR credentials from your %s file`, netrc.Filename),
+		Long:  fmt.Sprintf(`This command removes any BSR credentials from the OS keychain and your %s file, and any single sign-on credentials from %s within the configuration directory. The <domain> argument will default to the remote of the selected profile, or buf.build, if not specified. Run buf registry whoami --all to list the remotes you are logged in to.`, netrc.Filename, bufcli.SSOCredentialsFileName),
 		Args:  appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appext.Container) error {
@@ -61,18 +59,22 @@ func run(
 	container appext.Container,
 	flags *flags,
 ) error {
-	remote := bufconnect.DefaultRemote
+	remote := bufcli.GetDefaultRemote(container)
 	if container.NumArgs() == 1 {
 		remote = container.Arg(0)
 		if _, err := netext.ValidateHostname(remote); err != nil {
 			return err
 		}
 	}
-	modified1, err := netrc.DeleteMachineForName(container, remote)
+	modified1, err := bufcli.DeleteMachineForName(ctx, container, remote)
 	if err != nil {
 		return err
 	}
-	modified2, err := netrc.DeleteMachineForName(container, "go."+remote)
+	modified2, err := bufcli.DeleteMachineForName(ctx, container, "go."+remote)
+	if err != nil {
+		return err
+	}
+	modified3, err := bufcli.DeleteSSOCredentials(ctx, container, remote)
 	if err != nil {
 		return err
 	}
@@ -80,9 +82,16 @@ func run(
 	if err != nil {
 		return err
 	}
-	loggedOutMessage := fmt.Sprintf("All existing BSR credentials removed from %s\n", netrcFilePath)
-	if !modified1 && !modified2 {
-		loggedOutMessage = fmt.Sprintf("No BSR credentials found in %s; you are already logged out\n", netrcFilePath)
+	credentialsLocation := "the OS keychain and " + netrcFilePath
+	loggedOutMessage := fmt.Sprintf("All existing BSR credentials removed from %s\n", credentialsLocation)
+	if modified3 {
+		loggedOutMessage = fmt.Sprintf(
+			"All existing BSR credentials removed from the OS keychain, %s, and %s\n",
+			netrcFilePath,
+			bufcli.GetSSOCredentialsFilePath(container),
+		)
+	} else if !modified1 && !modified2 {
+		loggedOutMessage = fmt.Sprintf("No BSR credentials found in %s; you are already logged out\n", credentialsLocation)
 	}
 	if _, err := container.Stdout().Write([]byte(loggedOutMessage)); err != nil {
 		return err
@@ -0,0 +1,142 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrywhoami
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/netext"
+	"github.com/spf13/pflag"
+)
+
+const (
+	allFlagName    = "all"
+	formatFlagName = "format"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <domain>",
+		Short: "Show the user that you are logged in to the Buf Schema Registry as",
+		Long: fmt.Sprintf(`This command shows the user that the credentials for a remote authenticate as, where the credentials are configured, and when they expire. The <domain> argument will default to the remote of the selected profile, or buf.build, if not specified.
+
+Use the flag --%s to show this for every remote that has credentials configured. Credentials are read from the BUF_TOKEN environment variable, single sign-on credentials from buf registry login --sso, the OS keychain, and your .netrc file, in that order of precedence.`, allFlagName),
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	All    bool
+	Format string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.All,
+		allFlagName,
+		false,
+		"Show every remote that has credentials configured.",
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	if flags.All {
+		if container.NumArgs() > 0 {
+			return appcmd.NewInvalidArgumentErrorf("cannot use --%s with a <domain> argument", allFlagName)
+		}
+		return PrintAllLoginStatuses(ctx, container, format)
+	}
+	remote := bufcli.GetDefaultRemote(container)
+	if container.NumArgs() == 1 {
+		remote = container.Arg(0)
+		if _, err := netext.ValidateHostname(remote); err != nil {
+			return err
+		}
+	}
+	remoteCredentials, err := bufcli.GetRemoteCredentialsForRemote(ctx, container, remote)
+	if err != nil {
+		return err
+	}
+	if remoteCredentials == nil {
+		return fmt.Errorf("not logged in to %s, run \"buf registry login %s\" to log in", remote, remote)
+	}
+	loginStatuses, err := bufcli.GetLoginStatuses(ctx, container, []*bufcli.RemoteCredentials{remoteCredentials})
+	if err != nil {
+		return err
+	}
+	if err := bufprint.PrintLoginStatuses(container.Stdout(), format, loginStatuses...); err != nil {
+		return err
+	}
+	if loginStatuses[0].Error != "" {
+		return fmt.Errorf("credentials for %s are not valid: %s", remote, loginStatuses[0].Error)
+	}
+	return nil
+}
+
+// PrintAllLoginStatuses prints the login status of every remote that has credentials configured.
+//
+// This is shared with buf registry login --list.
+func PrintAllLoginStatuses(ctx context.Context, container appext.Container, format bufprint.Format) error {
+	remoteCredentialsList, err := bufcli.GetRemoteCredentials(ctx, container)
+	if err != nil {
+		return err
+	}
+	if len(remoteCredentialsList) == 0 {
+		if format == bufprint.FormatText {
+			_, err := fmt.Fprintln(container.Stdout(), "Not logged in to any remotes.")
+			return err
+		}
+		return nil
+	}
+	loginStatuses, err := bufcli.GetLoginStatuses(ctx, container, remoteCredentialsList)
+	if err != nil {
+		return err
+	}
+	return bufprint.PrintLoginStatuses(container.Stdout(), format, loginStatuses...)
+}
@@ -16,10 +16,12 @@ package buf
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -28,6 +30,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+const testSBOMTemplate = `version: v2
+plugins:
+  - remote: buf.build/protocolbuffers/go:v1.34.0
+    revision: 1
+    out: gen/go
+  - remote: buf.build/protocolbuffers/go:v1.34.0
+    revision: 1
+    out: gen/other
+  - local: protoc-gen-validate
+    out: gen/validate
+  - protoc_builtin: java
+    out: gen/java
+`
+
 func TestValidNoImports(t *testing.T) {
 	t.Parallel()
 	testRunStderrWithCache(
@@ -48,6 +64,38 @@ func TestValidImportFromCache(t *testing.T) {
 	)
 }
 
+func TestValidImportFromCacheOffline(t *testing.T) {
+	t.Parallel()
+	testRunStderrWithCache(
+		t, nil, 0,
+		"",
+		"build",
+		filepath.Join("testdata", "imports", "success", "students"),
+		"--offline",
+	)
+}
+
+func TestInvalidImportNotInCacheOffline(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		1,
+		[]string{
+			"not in cache: network access is disabled by --offline or BUF_OFFLINE",
+		},
+		func(use string) map[string]string {
+			return map[string]string{
+				useEnvVar(use, "CACHE_DIR"): t.TempDir(),
+			}
+		},
+		nil,
+		"build",
+		filepath.Join("testdata", "imports", "success", "students"),
+		"--offline",
+	)
+}
+
 func TestValidImportFromCorruptedCacheFile(t *testing.T) {
 	t.Parallel()
 
@@ -206,6 +254,100 @@ func TestGraphNoWarningsValidImportFromWorkspaceNamedModules(t *testing.T) {
 	)
 }
 
+func TestGraphTextFormat(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t, nil, 0,
+		`buf.build/foo/mod-a
+  buf.build/foo/mod-b`,
+		"dep",
+		"graph",
+		"--format",
+		"text",
+		filepath.Join("testdata", "imports", "success", "workspace", "valid_explicit_deps"),
+	)
+}
+
+func TestWhy(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t, nil, 0,
+		`# buf.build/foo/mod-b
+a/v1/a.proto (buf.build/foo/mod-a)
+b/v1/b.proto (buf.build/foo/mod-b)`,
+		"dep",
+		"why",
+		"buf.build/foo/mod-b",
+		filepath.Join("testdata", "imports", "success", "workspace", "valid_explicit_deps"),
+	)
+}
+
+func TestWhyNotImported(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t, nil, 0,
+		`# buf.build/foo/mod-a
+(no file in the workspace imports a file from buf.build/foo/mod-a)`,
+		"dep",
+		"why",
+		"buf.build/foo/mod-a",
+		filepath.Join("testdata", "imports", "success", "workspace", "valid_explicit_deps"),
+	)
+}
+
+func TestWhyUnknownModule(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t, nil, 1,
+		"",
+		"dep",
+		"why",
+		"buf.build/foo/mod-c",
+		filepath.Join("testdata", "imports", "success", "workspace", "valid_explicit_deps"),
+	)
+}
+
+func TestBetaSBOMSPDX(t *testing.T) {
+	t.Parallel()
+	testRunStdoutFileWithSourceDateEpochAndCache(
+		t, nil, 0,
+		filepath.Join("testdata", "sbom", "school.spdx.json"),
+		"beta",
+		"sbom",
+		"--template",
+		testSBOMTemplate,
+		filepath.Join("testdata", "imports", "success", "school"),
+	)
+}
+
+func TestBetaSBOMCycloneDX(t *testing.T) {
+	t.Parallel()
+	testRunStdoutFileWithSourceDateEpochAndCache(
+		t, nil, 0,
+		filepath.Join("testdata", "sbom", "school.cdx.json"),
+		"beta",
+		"sbom",
+		"--format",
+		"cyclonedx",
+		"--template",
+		testSBOMTemplate,
+		filepath.Join("testdata", "imports", "success", "school"),
+	)
+}
+
+func TestBetaSBOMInvalidFormat(t *testing.T) {
+	t.Parallel()
+	testRunStderrContainsWithCache(
+		t, nil, 1,
+		[]string{`Failure: --format: unknown format "swid", must be one of [spdx,cyclonedx]`},
+		"beta",
+		"sbom",
+		"--format",
+		"swid",
+		filepath.Join("testdata", "imports", "success", "school"),
+	)
+}
+
 func testRunStderrWithCache(t *testing.T, stdin io.Reader, expectedExitCode int, expectedStderr string, args ...string) {
 	appcmdtesting.RunCommandExitCodeStderr(
 		t,
@@ -238,6 +380,27 @@ func testRunStderrContainsWithCache(t *testing.T, stdin io.Reader, expectedExitC
 	)
 }
 
+// testRunStdoutFileWithSourceDateEpochAndCache compares stdout to the contents of the
+// file, in which ${BUF_VERSION} is replaced with the current version of buf.
+func testRunStdoutFileWithSourceDateEpochAndCache(t *testing.T, stdin io.Reader, expectedExitCode int, expectedStdoutFilePath string, args ...string) {
+	data, err := os.ReadFile(expectedStdoutFilePath)
+	require.NoError(t, err)
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		expectedExitCode,
+		strings.ReplaceAll(string(data), "${BUF_VERSION}", bufcli.Version),
+		func(use string) map[string]string {
+			return map[string]string{
+				useEnvVar(use, "CACHE_DIR"): filepath.Join("testdata", "imports", "cache"),
+				"SOURCE_DATE_EPOCH":         "1700000000",
+			}
+		},
+		stdin,
+		args...,
+	)
+}
+
 func useEnvVar(use string, suffix string) string {
 	return strings.ToUpper(use) + "_" + suffix
 }
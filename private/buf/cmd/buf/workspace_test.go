@@ -167,7 +167,7 @@ func TestWorkspaceDir(t *testing.T) {
 		testRunStdout(
 			t,
 			nil,
-			1,
+			bufctl.ExitCodeConfigError,
 			"",
 			"lint",
 			filepath.Join("testdata", "workspace", "success", baseDirPath),
@@ -240,7 +240,7 @@ func TestWorkspaceBreaking(t *testing.T) {
 		testRunStdout(
 			t,
 			nil,
-			bufctl.ExitCodeFileAnnotation,
+			bufctl.ExitCodeBreakingViolation,
 			filepath.FromSlash(`testdata/workspace/success/`+dirPaths.against+`/other/proto/request.proto:5:1:Previously present field "1" with name "name" on message "Request" was deleted.
 		    testdata/workspace/success/`+dirPaths.against+`/proto/rpc.proto:8:5:Field "1" with name "request" on message "RPC" changed option "json_name" from "req" to "request".
 		    testdata/workspace/success/`+dirPaths.against+`/proto/rpc.proto:8:21:Field "1" on message "RPC" changed name from "req" to "request".`),
@@ -1267,7 +1267,7 @@ func TestWorkspaceJumpContextFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/jumpcontext/buf.work.yaml: directory "../breaking/other/proto" is invalid: ../breaking/other/proto: is outside the context directory`,
@@ -1277,7 +1277,7 @@ func TestWorkspaceJumpContextFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/v2/jumpcontext/buf.yaml: invalid module path: ../breaking/other/proto: is outside the context directory`,
@@ -1292,7 +1292,7 @@ func TestWorkspaceDirOverlapFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/diroverlap/buf.work.yaml: directory "foo" contains directory "foo/bar"`,
@@ -1348,7 +1348,7 @@ func TestWorkspaceNoVersionFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/noversion/buf.work.yaml: "version" is not set. Please add "version: v1"`,
@@ -1363,7 +1363,7 @@ func TestWorkspaceInvalidVersionFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/invalidversion/buf.work.yaml: unknown file version: "v9"`,
@@ -1378,7 +1378,7 @@ func TestWorkspaceNoDirectoriesFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		// TODO FUTURE: figure out why even on windows, the cleaned, unnormalised path is "/"-separated from decode error
 		`Failure: decode testdata/workspace/fail/nodirectories/buf.work.yaml: directories is empty`,
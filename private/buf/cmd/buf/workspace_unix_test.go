@@ -120,7 +120,7 @@ func TestWorkspaceAbsoluteFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		`Failure: decode testdata/workspace/fail/absolute/buf.work.yaml: directory "/home/buf" is invalid: /home/buf: expected to be relative`,
 		"build",
@@ -129,7 +129,7 @@ func TestWorkspaceAbsoluteFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		`Failure: decode testdata/workspace/fail/v2/absolute/buf.yaml: invalid module path: /home/buf: expected to be relative`,
 		"build",
@@ -20,6 +20,8 @@ package buf
 import (
 	"path/filepath"
 	"testing"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
 )
 
 func TestWorkspaceAbsoluteFail(t *testing.T) {
@@ -27,7 +29,7 @@ func TestWorkspaceAbsoluteFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		`Failure: decode testdata/workspace/fail/absolute/windows/buf.work.yaml: directory "C:\\buf" is invalid: C:\buf: expected to be relative`,
 		"build",
@@ -36,7 +38,7 @@ func TestWorkspaceAbsoluteFail(t *testing.T) {
 	testRunStdoutStderrNoWarn(
 		t,
 		nil,
-		1,
+		bufctl.ExitCodeConfigError,
 		``,
 		`Failure: decode testdata/workspace/fail/v2/absolute/windows/buf.yaml: invalid module path: C:\buf: expected to be relative`,
 		"build",
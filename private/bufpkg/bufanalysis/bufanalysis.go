@@ -19,6 +19,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 )
 
 const (
@@ -201,14 +203,26 @@ func NewFileAnnotationSet(fileAnnotations ...FileAnnotation) FileAnnotationSet {
 }
 
 // PrintFileAnnotations prints the file annotations separated by newlines.
-func PrintFileAnnotationSet(writer io.Writer, fileAnnotationSet FileAnnotationSet, formatString string) error {
+func PrintFileAnnotationSet(
+	writer io.Writer,
+	fileAnnotationSet FileAnnotationSet,
+	formatString string,
+	options ...PrintFileAnnotationSetOption,
+) error {
 	format, err := ParseFormat(formatString)
 	if err != nil {
 		return err
 	}
+	printFileAnnotationSetOptions := newPrintFileAnnotationSetOptions()
+	for _, option := range options {
+		option(printFileAnnotationSetOptions)
+	}
 
 	switch format {
 	case FormatText:
+		if printFileAnnotationSetOptions.styler.Enabled() {
+			return printAsStyledText(writer, fileAnnotationSet.FileAnnotations(), printFileAnnotationSetOptions.styler)
+		}
 		return printAsText(writer, fileAnnotationSet.FileAnnotations())
 	case FormatJSON:
 		return printAsJSON(writer, fileAnnotationSet.FileAnnotations())
@@ -222,3 +236,28 @@ func PrintFileAnnotationSet(writer io.Writer, fileAnnotationSet FileAnnotationSe
 		return fmt.Errorf("unknown FileAnnotation Format: %v", format)
 	}
 }
+
+// PrintFileAnnotationSetOption is an option for PrintFileAnnotationSet.
+type PrintFileAnnotationSetOption func(*printFileAnnotationSetOptions)
+
+// PrintFileAnnotationSetWithStyler returns a new PrintFileAnnotationSetOption that styles
+// the text format with the given Styler.
+//
+// Other formats are never styled, as they are meant to be parsed.
+func PrintFileAnnotationSetWithStyler(styler termstyle.Styler) PrintFileAnnotationSetOption {
+	return func(printFileAnnotationSetOptions *printFileAnnotationSetOptions) {
+		printFileAnnotationSetOptions.styler = styler
+	}
+}
+
+// *** PRIVATE ***
+
+type printFileAnnotationSetOptions struct {
+	styler termstyle.Styler
+}
+
+func newPrintFileAnnotationSetOptions() *printFileAnnotationSetOptions {
+	return &printFileAnnotationSetOptions{
+		styler: termstyle.NewStyler(false),
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 )
 
 func printAsText(writer io.Writer, fileAnnotations []FileAnnotation) error {
@@ -32,6 +34,16 @@ func printAsText(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	)
 }
 
+func printAsStyledText(writer io.Writer, fileAnnotations []FileAnnotation, styler termstyle.Styler) error {
+	return printEachAnnotationOnNewLine(
+		writer,
+		fileAnnotations,
+		func(buffer *bytes.Buffer, f FileAnnotation) error {
+			return printFileAnnotationAsStyledText(buffer, f, styler)
+		},
+	)
+}
+
 func printAsMSVS(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	return printEachAnnotationOnNewLine(
 		writer,
@@ -159,6 +171,34 @@ func printFileAnnotationAsText(buffer *bytes.Buffer, f FileAnnotation) error {
 	return nil
 }
 
+// printFileAnnotationAsStyledText prints the same text as printFileAnnotationAsText,
+// with the path in bold, the message in red, and the plugin name dimmed.
+func printFileAnnotationAsStyledText(buffer *bytes.Buffer, f FileAnnotation, styler termstyle.Styler) error {
+	// This will work as long as f != (*fileAnnotation)(nil)
+	if f == nil {
+		return nil
+	}
+	path := "<input>"
+	if fileInfo := f.FileInfo(); fileInfo != nil {
+		path = fileInfo.ExternalPath()
+	}
+	message := f.Message()
+	if message == "" {
+		message = f.Type()
+		// should never happen but just in case
+		if message == "" {
+			message = "FAILURE"
+		}
+	}
+	_, _ = buffer.WriteString(styler.Bold(path))
+	_, _ = buffer.WriteString(styler.Faint(":" + strconv.Itoa(atLeast1(f.StartLine())) + ":" + strconv.Itoa(atLeast1(f.StartColumn())) + ":"))
+	_, _ = buffer.WriteString(styler.Red(message))
+	if pluginName := f.PluginName(); pluginName != "" {
+		_, _ = buffer.WriteString(styler.Faint(" (" + pluginName + ")"))
+	}
+	return nil
+}
+
 func printFileAnnotationAsMSVS(buffer *bytes.Buffer, f FileAnnotation) error {
 	// This will work as long as f != (*fileAnnotation)(nil)
 	if f == nil {
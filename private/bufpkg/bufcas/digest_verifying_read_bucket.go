@@ -0,0 +1,218 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/private/pkg/shake256"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+	"go.uber.org/multierr"
+)
+
+// FileDigestMismatchError is the error returned by a ReadBucket created with
+// NewDigestVerifyingReadBucket if the content of a file does not match its expected Digest.
+type FileDigestMismatchError struct {
+	// Path is the path of the file.
+	Path string
+	// ExpectedDigest is the Digest of the file in the Manifest.
+	//
+	// Nil if the file is not in the Manifest.
+	ExpectedDigest Digest
+	// ActualDigest is the Digest of the content of the file.
+	//
+	// Nil if the file is not in the Manifest.
+	ActualDigest Digest
+}
+
+// Error implements the error interface.
+func (f *FileDigestMismatchError) Error() string {
+	if f.ExpectedDigest == nil {
+		return fmt.Sprintf("file %q is not in the expected manifest", f.Path)
+	}
+	return fmt.Sprintf("file %q has digest %q, expected %q", f.Path, f.ActualDigest.String(), f.ExpectedDigest.String())
+}
+
+// NewDigestVerifyingReadBucket returns a new ReadBucket that verifies the content of each file
+// read from the delegate against the Digest of its FileNode in the Manifest.
+//
+// The Digest of a file is computed as the file is read. Once the content is fully read, if it
+// does not match the expected Digest, Read returns a *FileDigestMismatchError instead of io.EOF.
+// Files that are closed before being fully read are not verified.
+//
+// Files that are not in the Manifest cannot be read, and Get, Stat, and Walk return a
+// *FileDigestMismatchError for them. Files in the Manifest that do not exist in the delegate
+// are not detected by Walk.
+func NewDigestVerifyingReadBucket(delegate storage.ReadBucket, manifest Manifest) storage.ReadBucket {
+	return newDigestVerifyingReadBucket(delegate, manifest)
+}
+
+// *** PRIVATE ***
+
+type digestVerifyingReadBucket struct {
+	delegate storage.ReadBucket
+	manifest Manifest
+}
+
+func newDigestVerifyingReadBucket(delegate storage.ReadBucket, manifest Manifest) *digestVerifyingReadBucket {
+	return &digestVerifyingReadBucket{
+		delegate: delegate,
+		manifest: manifest,
+	}
+}
+
+func (d *digestVerifyingReadBucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
+	readObjectCloser, err := d.delegate.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	// Use the path of the ReadObjectCloser, as the delegate normalizes the path.
+	fileNode, err := d.getFileNode(readObjectCloser.Path())
+	if err != nil {
+		return nil, multierr.Append(err, readObjectCloser.Close())
+	}
+	hasher, err := newDigestHasher(fileNode.Digest().Type())
+	if err != nil {
+		return nil, multierr.Append(err, readObjectCloser.Close())
+	}
+	return newDigestVerifyingReadObjectCloser(readObjectCloser, fileNode, hasher), nil
+}
+
+func (d *digestVerifyingReadBucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	objectInfo, err := d.delegate.Stat(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.getFileNode(objectInfo.Path()); err != nil {
+		return nil, err
+	}
+	return objectInfo, nil
+}
+
+func (d *digestVerifyingReadBucket) Walk(ctx context.Context, prefix string, f func(storage.ObjectInfo) error) error {
+	return d.delegate.Walk(
+		ctx,
+		prefix,
+		func(objectInfo storage.ObjectInfo) error {
+			if _, err := d.getFileNode(objectInfo.Path()); err != nil {
+				return err
+			}
+			return f(objectInfo)
+		},
+	)
+}
+
+func (d *digestVerifyingReadBucket) getFileNode(path string) (FileNode, error) {
+	fileNode := d.manifest.GetFileNode(path)
+	if fileNode == nil {
+		return nil, &FileDigestMismatchError{
+			Path: path,
+		}
+	}
+	return fileNode, nil
+}
+
+type digestVerifyingReadObjectCloser struct {
+	storage.ReadObjectCloser
+
+	fileNode FileNode
+	hasher   *digestHasher
+	// verified is set once the content is fully read and verified, and
+	// verifyErr is the result of the verification.
+	verified  bool
+	verifyErr error
+}
+
+func newDigestVerifyingReadObjectCloser(
+	readObjectCloser storage.ReadObjectCloser,
+	fileNode FileNode,
+	hasher *digestHasher,
+) *digestVerifyingReadObjectCloser {
+	return &digestVerifyingReadObjectCloser{
+		ReadObjectCloser: readObjectCloser,
+		fileNode:         fileNode,
+		hasher:           hasher,
+	}
+}
+
+func (d *digestVerifyingReadObjectCloser) Read(p []byte) (int, error) {
+	if d.verified {
+		if d.verifyErr != nil {
+			return 0, d.verifyErr
+		}
+		return 0, io.EOF
+	}
+	n, err := d.ReadObjectCloser.Read(p)
+	// Writes to a hasher never error.
+	_, _ = d.hasher.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		d.verified = true
+		d.verifyErr = d.verify()
+		if d.verifyErr != nil {
+			return n, d.verifyErr
+		}
+	}
+	return n, err
+}
+
+func (d *digestVerifyingReadObjectCloser) verify() error {
+	actualDigest, err := d.hasher.Digest()
+	if err != nil {
+		return err
+	}
+	if !DigestEqual(d.fileNode.Digest(), actualDigest) {
+		return &FileDigestMismatchError{
+			Path:           d.fileNode.Path(),
+			ExpectedDigest: d.fileNode.Digest(),
+			ActualDigest:   actualDigest,
+		}
+	}
+	return nil
+}
+
+// digestHasher computes a Digest of a given DigestType for the content written to it.
+type digestHasher struct {
+	digestType     DigestType
+	shake256Hasher shake256.Hasher
+}
+
+func newDigestHasher(digestType DigestType) (*digestHasher, error) {
+	switch digestType {
+	case DigestTypeShake256:
+		return &digestHasher{
+			digestType:     digestType,
+			shake256Hasher: shake256.NewHasher(),
+		}, nil
+	default:
+		// This is a system error.
+		return nil, syserror.Newf("unknown DigestType: %v", digestType)
+	}
+}
+
+func (d *digestHasher) Write(p []byte) (int, error) {
+	return d.shake256Hasher.Write(p)
+}
+
+func (d *digestHasher) Digest() (Digest, error) {
+	shake256Digest, err := d.shake256Hasher.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return newDigest(d.digestType, shake256Digest.Value()), nil
+}
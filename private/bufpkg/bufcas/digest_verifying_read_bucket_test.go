@@ -0,0 +1,64 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcas
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestVerifyingReadBucket(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a.proto", []byte("a")))
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.proto", []byte("b")))
+	manifest, err := NewManifestForBucket(ctx, readWriteBucket)
+	require.NoError(t, err)
+	readBucket := NewDigestVerifyingReadBucket(readWriteBucket, manifest)
+
+	data, err := storage.ReadPath(ctx, readBucket, "a.proto")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(data))
+	paths, err := storage.AllPaths(ctx, readBucket, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto", "b.proto"}, paths)
+
+	// Modified content fails the read.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.proto", []byte("bb")))
+	_, err = storage.ReadPath(ctx, readBucket, "b.proto")
+	fileDigestMismatchError := &FileDigestMismatchError{}
+	require.ErrorAs(t, err, &fileDigestMismatchError)
+	require.Equal(t, "b.proto", fileDigestMismatchError.Path)
+	require.Equal(t, manifest.GetDigest("b.proto"), fileDigestMismatchError.ExpectedDigest)
+	expectedActualDigest, err := NewDigestForContent(strings.NewReader("bb"))
+	require.NoError(t, err)
+	require.True(t, DigestEqual(expectedActualDigest, fileDigestMismatchError.ActualDigest))
+
+	// Files not in the Manifest cannot be read.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "c.proto", []byte("c")))
+	_, err = storage.ReadPath(ctx, readBucket, "c.proto")
+	require.ErrorAs(t, err, &fileDigestMismatchError)
+	require.Nil(t, fileDigestMismatchError.ExpectedDigest)
+	_, err = readBucket.Stat(ctx, "c.proto")
+	require.ErrorAs(t, err, &fileDigestMismatchError)
+	_, err = storage.AllPaths(ctx, readBucket, "")
+	require.ErrorAs(t, err, &fileDigestMismatchError)
+}
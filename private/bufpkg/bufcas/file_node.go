@@ -17,15 +17,66 @@ package bufcas
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 )
 
+const (
+	// FileNodeTypeRegular represents a regular file.
+	//
+	// This is the default FileNodeType.
+	FileNodeTypeRegular FileNodeType = iota + 1
+	// FileNodeTypeExecutable represents a regular file with the executable bit set.
+	FileNodeTypeExecutable
+	// FileNodeTypeSymlink represents a symlink.
+	//
+	// The Digest of a symlink FileNode is the Digest of the target path of the symlink.
+	FileNodeTypeSymlink
+)
+
+var (
+	fileNodeTypeToString = map[FileNodeType]string{
+		FileNodeTypeRegular:    "",
+		FileNodeTypeExecutable: "x",
+		FileNodeTypeSymlink:    "l",
+	}
+	stringToFileNodeType = map[string]FileNodeType{
+		"":  FileNodeTypeRegular,
+		"x": FileNodeTypeExecutable,
+		"l": FileNodeTypeSymlink,
+	}
+)
+
+// FileNodeType is the type of a FileNode.
+//
+// FileNodeTypes are only recorded within a ManifestVersionV2 Manifest. Module files
+// are always of type FileNodeTypeRegular, as file modes and symlinks are not yet
+// read from or written to module files.
+type FileNodeType int
+
+// String prints the string representation of the FileNodeType.
+//
+// This is the representation used within a FileNode's string representation.
+// FileNodeTypeRegular is represented by the empty string.
+func (f FileNodeType) String() string {
+	s, ok := fileNodeTypeToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
 // FileNode is a path and associated digest.
 type FileNode interface {
 	// String encodes the FileNode into its canonical form:
 	//
+	//   digestString[SP]typeString[SP]path
+	//
+	// For FileNodeTypeRegular, the typeString is empty, so this is equivalent to the
+	// original encoding:
+	//
 	//   digestString[SP][SP]path
 	fmt.Stringer
 
@@ -37,6 +88,10 @@ type FileNode interface {
 	//
 	// The Digest is always non-nil.
 	Digest() Digest
+	// Type returns the FileNodeType of the file.
+	//
+	// Always a valid value.
+	Type() FileNodeType
 
 	// Protect against creation of a FileNode outside of this package, as we
 	// do very careful validation.
@@ -47,28 +102,49 @@ type FileNode interface {
 //
 // The path is validated to be normalized and non-empty.
 // The digest is validated to be non-nil.
-func NewFileNode(path string, digest Digest) (FileNode, error) {
-	if err := validateFileNodeParameters(path, digest); err != nil {
+func NewFileNode(path string, digest Digest, options ...FileNodeOption) (FileNode, error) {
+	fileNodeOptions := newFileNodeOptions()
+	for _, option := range options {
+		option(fileNodeOptions)
+	}
+	if err := validateFileNodeParameters(path, digest, fileNodeOptions.fileNodeType); err != nil {
 		return nil, err
 	}
-	return newFileNode(path, digest), nil
+	return newFileNode(path, digest, fileNodeOptions.fileNodeType), nil
+}
+
+// FileNodeOption is an option for a new FileNode.
+type FileNodeOption func(*fileNodeOptions)
+
+// FileNodeWithType returns a new FileNodeOption that sets the FileNodeType.
+//
+// The default is FileNodeTypeRegular.
+func FileNodeWithType(fileNodeType FileNodeType) FileNodeOption {
+	return func(fileNodeOptions *fileNodeOptions) {
+		fileNodeOptions.fileNodeType = fileNodeType
+	}
 }
 
 // ParseFileNode parses the FileNode from its string representation.
 //
-// The string representation is "digestString[SP][SP]path".
+// The string representation is "digestString[SP]typeString[SP]path", where
+// typeString is empty for FileNodeTypeRegular.
 //
 // This reverses FileNode.String().
 func ParseFileNode(s string) (FileNode, error) {
-	split := strings.Split(s, "  ")
-	if len(split) != 2 {
+	digestString, rest, ok := strings.Cut(s, " ")
+	var typeString, path string
+	if ok {
+		typeString, path, ok = strings.Cut(rest, " ")
+	}
+	if !ok {
 		return nil, &ParseError{
 			typeString: "file node",
 			input:      s,
-			err:        errors.New(`must in the form "digest[SP][SP]path"`),
+			err:        errors.New(`must in the form "digest[SP]type[SP]path"`),
 		}
 	}
-	digest, err := ParseDigest(split[0])
+	digest, err := ParseDigest(digestString)
 	if err != nil {
 		return nil, &ParseError{
 			typeString: "file node",
@@ -76,29 +152,38 @@ func ParseFileNode(s string) (FileNode, error) {
 			err:        err,
 		}
 	}
-	path := split[1]
-	if err := validateFileNodeParameters(path, digest); err != nil {
+	fileNodeType, ok := stringToFileNodeType[typeString]
+	if !ok {
+		return nil, &ParseError{
+			typeString: "file node",
+			input:      s,
+			err:        fmt.Errorf("unknown file node type: %q", typeString),
+		}
+	}
+	if err := validateFileNodeParameters(path, digest, fileNodeType); err != nil {
 		return nil, &ParseError{
 			typeString: "file node",
 			input:      s,
 			err:        err,
 		}
 	}
-	return newFileNode(path, digest), nil
+	return newFileNode(path, digest, fileNodeType), nil
 }
 
 // *** PRIVATE ***
 
 type fileNode struct {
-	path   string
-	digest Digest
+	path         string
+	digest       Digest
+	fileNodeType FileNodeType
 }
 
 // validation should occur outside of this function.
-func newFileNode(path string, digest Digest) *fileNode {
+func newFileNode(path string, digest Digest, fileNodeType FileNodeType) *fileNode {
 	return &fileNode{
-		path:   path,
-		digest: digest,
+		path:         path,
+		digest:       digest,
+		fileNodeType: fileNodeType,
 	}
 }
 
@@ -110,13 +195,17 @@ func (f *fileNode) Digest() Digest {
 	return f.digest
 }
 
+func (f *fileNode) Type() FileNodeType {
+	return f.fileNodeType
+}
+
 func (f *fileNode) String() string {
-	return f.digest.String() + "  " + f.path
+	return f.digest.String() + " " + f.fileNodeType.String() + " " + f.path
 }
 
 func (*fileNode) isFileNode() {}
 
-func validateFileNodeParameters(path string, digest Digest) error {
+func validateFileNodeParameters(path string, digest Digest, fileNodeType FileNodeType) error {
 	if path == "" {
 		return errors.New("path was empty")
 	}
@@ -130,5 +219,18 @@ func validateFileNodeParameters(path string, digest Digest) error {
 	if digest == nil {
 		return errors.New("no digest specified")
 	}
+	if _, ok := fileNodeTypeToString[fileNodeType]; !ok {
+		return fmt.Errorf("unknown file node type: %v", fileNodeType)
+	}
 	return nil
 }
+
+type fileNodeOptions struct {
+	fileNodeType FileNodeType
+}
+
+func newFileNodeOptions() *fileNodeOptions {
+	return &fileNodeOptions{
+		fileNodeType: FileNodeTypeRegular,
+	}
+}
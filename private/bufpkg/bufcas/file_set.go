@@ -111,6 +111,34 @@ func NewFileSetForBucket(ctx context.Context, bucket storage.ReadBucket) (FileSe
 	), nil
 }
 
+// NewManifestForBucket returns a new Manifest for the given ReadBucket.
+//
+// This is the Manifest of the FileSet returned by NewFileSetForBucket, without keeping
+// the content of the files in memory.
+func NewManifestForBucket(ctx context.Context, bucket storage.ReadBucket) (Manifest, error) {
+	var fileNodes []FileNode
+	if err := storage.WalkReadObjects(
+		ctx,
+		bucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			digest, err := NewDigestForContent(readObject)
+			if err != nil {
+				return fmt.Errorf("error creating Digest for file %q: %w", readObject.Path(), err)
+			}
+			fileNode, err := NewFileNode(readObject.Path(), digest)
+			if err != nil {
+				return fmt.Errorf("error creating FileNode for file %q: %w", readObject.Path(), err)
+			}
+			fileNodes = append(fileNodes, fileNode)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return NewManifest(fileNodes)
+}
+
 // PutFileSetToBucket writes the FileSet to the given WriteBucket.
 func PutFileSetToBucket(
 	ctx context.Context,
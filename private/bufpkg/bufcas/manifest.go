@@ -19,9 +19,49 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+const (
+	// ManifestVersionV1 is the original manifest format.
+	//
+	// All FileNodes within a v1 Manifest are of type FileNodeTypeRegular, and the
+	// Manifest has no header line.
+	ManifestVersionV1 ManifestVersion = iota + 1
+	// ManifestVersionV2 is the manifest format that records FileNodeTypes.
+	//
+	// A v2 Manifest starts with the header line "manifest v2".
+	//
+	// Only the format is supported. Modules are not yet read, pushed, or exported
+	// with FileNodeTypes, so the Manifests of modules are always v1.
+	ManifestVersionV2
+)
+
+var (
+	manifestVersionToHeader = map[ManifestVersion]string{
+		ManifestVersionV2: "manifest v2",
+	}
+	headerToManifestVersion = map[string]ManifestVersion{
+		"manifest v2": ManifestVersionV2,
+	}
+)
+
+// ManifestVersion is the version of the Manifest format.
+type ManifestVersion int
+
+// String prints the string representation of the ManifestVersion.
+func (m ManifestVersion) String() string {
+	switch m {
+	case ManifestVersionV1:
+		return "v1"
+	case ManifestVersionV2:
+		return "v2"
+	default:
+		return strconv.Itoa(int(m))
+	}
+}
+
 // Manifest is a set of FileNodes.
 type Manifest interface {
 	// fmt.Stringer encodes the Manifest into its canonical form, consisting of
@@ -35,8 +75,25 @@ type Manifest interface {
 	//	shake256:3b353aa5aacd11015e8577f16e2c4e7a242ce773d8e3a16806795bb94f76e601b0db9bf42d5e1907fda63303e1fa1c65f1c175ecc025a3ef29c3456ad237ad84  buf.md
 	//	shake256:7c88a20cf931702d042a4ddee3fde5de84814544411f1c62dbf435b1b81a12a8866a070baabcf8b5a0d31675af361ccb2d93ddada4cdcc11bab7ea3d8d7c4667  buf.yaml
 	//	shake256:9db25155eafd19b36882cff129daac575baa67ee44d1cb1fd3894342b28c72b83eb21aa595b806e9cb5344759bc8308200c5af98e4329aa83014dde99afa903a  pet/v1/pet.proto
+	//
+	// If any FileNode is not of type FileNodeTypeRegular, the Manifest is encoded as
+	// ManifestVersionV2, which adds a header line:
+	//
+	//	manifest v2
+	//	shake256:cd22db48cf7c274bbffcb5494a854000cd21b074df7c6edabbd0102c4be8d7623e3931560fcda7acfab286ae1d4f506911daa31f223ee159f59ffce0c7acbbaa  buf.yaml
+	//	shake256:3b353aa5aacd11015e8577f16e2c4e7a242ce773d8e3a16806795bb94f76e601b0db9bf42d5e1907fda63303e1fa1c65f1c175ecc025a3ef29c3456ad237ad84 x scripts/gen.sh
+	//	shake256:7c88a20cf931702d042a4ddee3fde5de84814544411f1c62dbf435b1b81a12a8866a070baabcf8b5a0d31675af361ccb2d93ddada4cdcc11bab7ea3d8d7c4667 l scripts/latest.sh
+	//
+	// Manifests that only contain regular files are always encoded as ManifestVersionV1,
+	// so that their Digests do not change.
 	fmt.Stringer
 
+	// Version returns the ManifestVersion of the Manifest.
+	//
+	// This is ManifestVersionV2 if any FileNode is not of type FileNodeTypeRegular,
+	// and ManifestVersionV1 otherwise.
+	Version() ManifestVersion
+
 	// FileNodes returns the set of FileNodes that make up the Manifest.
 	//
 	// The paths of the given FileNodes are guaranteed to be unique.
@@ -70,10 +127,14 @@ func NewManifest(fileNodes []FileNode) (Manifest, error) {
 
 // ParseManifest parses a Manifest from its string representation.
 //
+// Both ManifestVersionV1 and ManifestVersionV2 are accepted. A v1 Manifest
+// may only contain FileNodes of type FileNodeTypeRegular.
+//
 // This reverses Manifest.String().
 func ParseManifest(s string) (Manifest, error) {
 	var fileNodes []FileNode
 	original := s
+	version := ManifestVersionV1
 	if len(s) > 0 {
 		if s[len(s)-1] != '\n' {
 			return nil, &ParseError{
@@ -83,7 +144,12 @@ func ParseManifest(s string) (Manifest, error) {
 			}
 		}
 		s = s[:len(s)-1]
-		for i, line := range strings.Split(s, "\n") {
+		lines := strings.Split(s, "\n")
+		if headerVersion, ok := headerToManifestVersion[lines[0]]; ok {
+			version = headerVersion
+			lines = lines[1:]
+		}
+		for i, line := range lines {
 			fileNode, err := ParseFileNode(line)
 			if err != nil {
 				return nil, &ParseError{
@@ -92,6 +158,13 @@ func ParseManifest(s string) (Manifest, error) {
 					err:        fmt.Errorf("line %d: %w", i, err),
 				}
 			}
+			if version == ManifestVersionV1 && fileNode.Type() != FileNodeTypeRegular {
+				return nil, &ParseError{
+					typeString: "manifest",
+					input:      original,
+					err:        fmt.Errorf("line %d: file node type %q is not allowed in a %v manifest", i, fileNode.Type().String(), version),
+				}
+			}
 			fileNodes = append(fileNodes, fileNode)
 		}
 	}
@@ -106,7 +179,17 @@ func ParseManifest(s string) (Manifest, error) {
 			err:        err,
 		}
 	}
-	return newManifest(pathToFileNode), nil
+	manifest := newManifest(pathToFileNode)
+	// A v2 header on a Manifest that only contains regular files would not round-trip,
+	// as such Manifests are always encoded as v1.
+	if version != manifest.Version() {
+		return nil, &ParseError{
+			typeString: "manifest",
+			input:      original,
+			err:        fmt.Errorf("manifest with header %q only contains regular files", manifestVersionToHeader[version]),
+		}
+	}
+	return manifest, nil
 }
 
 // ManifestToBlob converts the string representation of the given Manifest into a Blob.
@@ -137,6 +220,7 @@ func BlobToManifest(blob Blob) (Manifest, error) {
 type manifest struct {
 	pathToFileNode        map[string]FileNode
 	sortedUniqueFileNodes []FileNode
+	version               ManifestVersion
 }
 
 // use getAndValidateManifestPathToFileNode to create pathToFileNode.
@@ -152,12 +236,24 @@ func newManifest(pathToFileNode map[string]FileNode) *manifest {
 			return sortedUniqueFileNodes[i].Path() < sortedUniqueFileNodes[j].Path()
 		},
 	)
+	version := ManifestVersionV1
+	for _, fileNode := range sortedUniqueFileNodes {
+		if fileNode.Type() != FileNodeTypeRegular {
+			version = ManifestVersionV2
+			break
+		}
+	}
 	return &manifest{
 		pathToFileNode:        pathToFileNode,
 		sortedUniqueFileNodes: sortedUniqueFileNodes,
+		version:               version,
 	}
 }
 
+func (m *manifest) Version() ManifestVersion {
+	return m.version
+}
+
 func (m *manifest) FileNodes() []FileNode {
 	return m.sortedUniqueFileNodes
 }
@@ -176,6 +272,10 @@ func (m *manifest) GetDigest(path string) Digest {
 
 func (m *manifest) String() string {
 	buffer := bytes.NewBuffer(nil)
+	if header, ok := manifestVersionToHeader[m.version]; ok {
+		_, _ = buffer.WriteString(header)
+		_, _ = buffer.WriteRune('\n')
+	}
 	for _, fileNode := range m.sortedUniqueFileNodes {
 		_, _ = buffer.WriteString(fileNode.String())
 		_, _ = buffer.WriteRune('\n')
@@ -197,6 +297,8 @@ func getAndValidateManifestPathToFileNode(fileNodes []FileNode) (map[string]File
 					existingFileNode.Digest().String(),
 					fileNode.Digest().String(),
 				)
+			} else if existingFileNode.Type() != fileNode.Type() {
+				errorMessage += " and the two path entries had different types"
 			}
 			return nil, errors.New(errorMessage)
 		} else {
@@ -15,12 +15,14 @@
 package bufcas
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
 	"strings"
 	"testing"
 
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +64,49 @@ func TestManifest(t *testing.T) {
 	assert.Equal(t, manifestFileNodes, parsedManifest.FileNodes())
 }
 
+func TestManifestV2(t *testing.T) {
+	t.Parallel()
+	regularDigest, err := NewDigestForContent(strings.NewReader("regular"))
+	require.NoError(t, err)
+	executableDigest, err := NewDigestForContent(strings.NewReader("#!/bin/sh"))
+	require.NoError(t, err)
+	symlinkDigest, err := NewDigestForContent(strings.NewReader("gen.sh"))
+	require.NoError(t, err)
+	regularFileNode, err := NewFileNode("buf.yaml", regularDigest)
+	require.NoError(t, err)
+	executableFileNode, err := NewFileNode("scripts/gen.sh", executableDigest, FileNodeWithType(FileNodeTypeExecutable))
+	require.NoError(t, err)
+	symlinkFileNode, err := NewFileNode("scripts/latest.sh", symlinkDigest, FileNodeWithType(FileNodeTypeSymlink))
+	require.NoError(t, err)
+
+	v1Manifest, err := NewManifest([]FileNode{regularFileNode})
+	require.NoError(t, err)
+	assert.Equal(t, ManifestVersionV1, v1Manifest.Version())
+	assert.Equal(t, regularDigest.String()+"  buf.yaml\n", v1Manifest.String())
+
+	v2Manifest, err := NewManifest([]FileNode{symlinkFileNode, regularFileNode, executableFileNode})
+	require.NoError(t, err)
+	assert.Equal(t, ManifestVersionV2, v2Manifest.Version())
+	assert.Equal(
+		t,
+		"manifest v2\n"+
+			regularDigest.String()+"  buf.yaml\n"+
+			executableDigest.String()+" x scripts/gen.sh\n"+
+			symlinkDigest.String()+" l scripts/latest.sh\n",
+		v2Manifest.String(),
+	)
+	parsedManifest, err := ParseManifest(v2Manifest.String())
+	require.NoError(t, err)
+	assert.Equal(t, ManifestVersionV2, parsedManifest.Version())
+	assert.Equal(t, v2Manifest.FileNodes(), parsedManifest.FileNodes())
+	assert.Equal(t, FileNodeTypeSymlink, parsedManifest.GetFileNode("scripts/latest.sh").Type())
+
+	_, err = NewFileNode("foo", regularDigest, FileNodeWithType(FileNodeType(0)))
+	assert.Error(t, err)
+	_, err = NewManifest([]FileNode{regularFileNode, newFileNode("buf.yaml", regularDigest, FileNodeTypeExecutable)})
+	assert.Error(t, err)
+}
+
 func TestEmptyManifest(t *testing.T) {
 	t.Parallel()
 	manifest, err := NewManifest(nil)
@@ -84,6 +129,33 @@ func TestParseManifestError(t *testing.T) {
 	testParseManifestError(t, "md5:d41d8cd98f00b204e9800998ecf8427e  foo\n")
 	testParseManifestError(t, "bar  foo\n")
 	testParseManifestError(t, "shake256:_  foo\n")
+	validDigest, err := NewDigestForContent(strings.NewReader("foo"))
+	require.NoError(t, err)
+	// Non-regular files require a v2 header.
+	testParseManifestError(t, validDigest.String()+" x foo\n")
+	// A v2 header requires a non-regular file.
+	testParseManifestError(t, "manifest v2\n"+validDigest.String()+"  foo\n")
+	testParseManifestError(t, "manifest v2\n"+validDigest.String()+" z foo\n")
+	testParseManifestError(t, "manifest v3\n"+validDigest.String()+" x foo\n")
+}
+
+func TestNewManifestForBucket(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto":     []byte("foo"),
+			"b/b.proto":   []byte("bar"),
+			"c/d/e.proto": []byte(""),
+		},
+	)
+	require.NoError(t, err)
+	manifest, err := NewManifestForBucket(ctx, bucket)
+	require.NoError(t, err)
+	fileSet, err := NewFileSetForBucket(ctx, bucket)
+	require.NoError(t, err)
+	assert.Equal(t, fileSet.Manifest().String(), manifest.String())
+	assert.Len(t, manifest.FileNodes(), 3)
 }
 
 func testParseManifestError(t *testing.T, manifestString string) {
@@ -20,9 +20,13 @@ import (
 	"log/slog"
 
 	"buf.build/go/bufplugin/check"
+	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/pluginrpcutil"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/bufbuild/buf/private/pkg/wasm"
@@ -179,10 +183,88 @@ func (r RunnerProviderFunc) NewRunner(pluginConfig bufconfig.PluginConfig) (plug
 // The supported types are:
 //   - bufconfig.PluginConfigTypeLocal
 //   - bufconfig.PluginConfigTypeLocalWasm
+//   - bufconfig.PluginConfigTypeRemote
 //
 // If the PluginConfigType is not supported, an error is returned.
-func NewRunnerProvider(commandRunner command.Runner, wasmRuntime wasm.Runtime) RunnerProvider {
-	return newRunnerProvider(commandRunner, wasmRuntime)
+func NewRunnerProvider(
+	commandRunner command.Runner,
+	wasmRuntime wasm.Runtime,
+	options ...RunnerProviderOption,
+) RunnerProvider {
+	return newRunnerProvider(commandRunner, wasmRuntime, options...)
+}
+
+// RunnerProviderOption is an option for NewRunnerProvider.
+type RunnerProviderOption func(*runnerProvider)
+
+// RunnerProviderWithHTTPClient returns a new RunnerProviderOption that sets the
+// HTTP client used to call remote execution services for remote plugins.
+//
+// The default is http.DefaultClient.
+func RunnerProviderWithHTTPClient(httpClient connect.HTTPClient) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		runnerProvider.httpClient = httpClient
+	}
+}
+
+// RunnerProviderWithInterceptors returns a new RunnerProviderOption that applies the
+// interceptors to every pluginrpc.Runner returned by the RunnerProvider.
+//
+// The interceptors are applied in the order they are declared, that is the first
+// interceptor is the outermost. The name of the plugin is passed to each interceptor.
+func RunnerProviderWithInterceptors(interceptors ...pluginrpcutil.RunnerInterceptor) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		runnerProvider.interceptors = append(runnerProvider.interceptors, interceptors...)
+	}
+}
+
+// RunnerProviderWithEnv returns a new RunnerProviderOption that injects the environment
+// variables into every local plugin.
+//
+// Local plugins never inherit the environment of the caller. By default, local plugins
+// are run with an empty environment, and only the variables set with RunnerProviderWithEnv
+// and RunnerProviderWithEnvAllowlist are visible to them. If a variable is set by both, the
+// last option specified wins.
+func RunnerProviderWithEnv(env map[string]string) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		runnerProvider.addEnv(env)
+	}
+}
+
+// RunnerProviderWithEnvAllowlist returns a new RunnerProviderOption that passes the
+// environment variables with the given keys from the app.EnvContainer to every local plugin.
+//
+// Variables that are not in the allowlist are stripped. Keys that are not set in the
+// app.EnvContainer are ignored.
+func RunnerProviderWithEnvAllowlist(envContainer app.EnvContainer, keys ...string) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		env := make(map[string]string, len(keys))
+		for _, key := range keys {
+			if value := envContainer.Env(key); value != "" {
+				env[key] = value
+			}
+		}
+		runnerProvider.addEnv(env)
+	}
+}
+
+// RunnerProviderWithDir returns a new RunnerProviderOption that sets the working
+// directory of every local plugin.
+//
+// The default is the current working directory.
+func RunnerProviderWithDir(dir string) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		runnerProvider.dir = dir
+	}
+}
+
+// RunnerProviderWithPluginResolver returns a new RunnerProviderOption that resolves
+// local plugins against the plugin manifests of the bufpluginmanifest.Resolver before
+// falling back to a lookup on $PATH.
+func RunnerProviderWithPluginResolver(resolver bufpluginmanifest.Resolver) RunnerProviderOption {
+	return func(runnerProvider *runnerProvider) {
+		runnerProvider.resolver = resolver
+	}
 }
 
 // NewClient returns a new Client.
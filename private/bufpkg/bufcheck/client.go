@@ -33,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/protoversion"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"pluginrpc.com/pluginrpc"
@@ -410,11 +411,11 @@ func ignoreFileLocation(
 
 	protoreflectFileDescriptor := fileDescriptor.ProtoreflectFileDescriptor()
 	path := protoreflectFileDescriptor.Path()
-	if normalpath.MapHasEqualOrContainingPath(config.IgnoreRootPaths, path, normalpath.Relative) {
+	if mapHasEqualOrContainingPathOrGlob(config.IgnoreRootPaths, path) {
 		return true, nil
 	}
 	// If the config says to ignore this specific rule for this path, ignore this location, otherwise we look for other forms of ignores.
-	if ignoreRootPaths, ok := config.IgnoreRuleIDToRootPaths[ruleID]; ok && normalpath.MapHasEqualOrContainingPath(ignoreRootPaths, path, normalpath.Relative) {
+	if ignoreRootPaths, ok := config.IgnoreRuleIDToRootPaths[ruleID]; ok && mapHasEqualOrContainingPathOrGlob(ignoreRootPaths, path) {
 		return true, nil
 	}
 
@@ -553,3 +554,17 @@ func (p *pluginConfigsOption) applyToAllRules(allRulesOptions *allRulesOptions)
 func (p *pluginConfigsOption) applyToAllCategories(allCategoriesOptions *allCategoriesOptions) {
 	allCategoriesOptions.pluginConfigs = append(allCategoriesOptions.pluginConfigs, p.pluginConfigs...)
 }
+
+// mapHasEqualOrContainingPathOrGlob returns true if the map has a path equal to or containing
+// the path, or a glob pattern that matches the path or one of its parent directories.
+func mapHasEqualOrContainingPathOrGlob(m map[string]struct{}, path string) bool {
+	if normalpath.MapHasEqualOrContainingPath(m, path, normalpath.Relative) {
+		return true
+	}
+	for pattern := range m {
+		if storage.IsGlob(pattern) && storage.MatchGlobEqualOrContained(pattern).MatchPath(path) {
+			return true
+		}
+	}
+	return false
+}
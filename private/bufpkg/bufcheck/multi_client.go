@@ -26,6 +26,8 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/buf/private/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type multiClient struct {
@@ -94,8 +96,19 @@ func (c *multiClient) Check(ctx context.Context, request check.Request) ([]*anno
 		}
 		jobs = append(
 			jobs,
-			func(ctx context.Context) error {
+			func(ctx context.Context) (retErr error) {
 				defer slogext.DebugProfile(c.logger, slog.String("plugin", delegate.PluginName))()
+				pluginName := delegate.PluginName
+				if pluginName == "" {
+					pluginName = "builtin"
+				}
+				ctx, span := tracing.Start(
+					ctx,
+					"bufcheck.check",
+					attribute.String("buf.check.plugin", pluginName),
+					attribute.StringSlice("buf.check.rule_ids", requestDelegateRuleIDs),
+				)
+				defer func() { tracing.End(span, retErr) }()
 				delegateResponse, err := delegate.Client.Check(ctx, delegateRequest)
 				if err != nil {
 					if delegate.PluginName == "" {
@@ -24,6 +24,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 )
@@ -603,6 +604,11 @@ func normalizeIgnoreRootPaths(rootPaths []string) ([]string, error) {
 		if rootPath == "." {
 			return nil, fmt.Errorf("cannot specify %q as an ignore path", rootPath)
 		}
+		if storage.IsGlob(rootPath) {
+			if err := storage.ValidateGlob(rootPath); err != nil {
+				return nil, err
+			}
+		}
 		rootPathMap[rootPath] = struct{}{}
 	}
 	return slicesext.MapKeysToSortedSlice(rootPathMap), nil
@@ -15,7 +15,12 @@
 package bufcheck
 
 import (
+	"maps"
+	"net/http"
+
+	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginmanifest"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/pluginrpcutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
@@ -26,24 +31,58 @@ import (
 type runnerProvider struct {
 	commandRunner command.Runner
 	wasmRuntime   wasm.Runtime
+	httpClient    connect.HTTPClient
+	interceptors  []pluginrpcutil.RunnerInterceptor
+	env           map[string]string
+	dir           string
+	resolver      bufpluginmanifest.Resolver
 }
 
-func newRunnerProvider(commandRunner command.Runner, wasmRuntime wasm.Runtime) *runnerProvider {
-	return &runnerProvider{
+func newRunnerProvider(
+	commandRunner command.Runner,
+	wasmRuntime wasm.Runtime,
+	options ...RunnerProviderOption,
+) *runnerProvider {
+	runnerProvider := &runnerProvider{
 		commandRunner: commandRunner,
 		wasmRuntime:   wasmRuntime,
+		httpClient:    http.DefaultClient,
+	}
+	for _, option := range options {
+		option(runnerProvider)
 	}
+	return runnerProvider
 }
 
 func (r *runnerProvider) NewRunner(pluginConfig bufconfig.PluginConfig) (pluginrpc.Runner, error) {
+	runner, err := r.newRunner(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	return pluginrpcutil.NewInterceptedRunner(runner, pluginConfig.Name(), r.interceptors...), nil
+}
+
+func (r *runnerProvider) newRunner(pluginConfig bufconfig.PluginConfig) (pluginrpc.Runner, error) {
 	switch pluginConfig.Type() {
 	case bufconfig.PluginConfigTypeLocal:
 		path := pluginConfig.Path()
-		return pluginrpcutil.NewRunner(
+		// We know that Path is of at least length 1.
+		programName := path[0]
+		if r.resolver != nil {
+			binaryPath, ok, err := r.resolver.Resolve(programName, bufpluginmanifest.CapabilityCheck)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				programName = binaryPath
+			}
+		}
+		return pluginrpcutil.NewRunnerWithOptions(
 			r.commandRunner,
-			// We know that Path is of at least length 1.
-			path[0],
-			path[1:]...,
+			programName,
+			path[1:],
+			pluginrpcutil.RunnerWithEnv(r.env),
+			pluginrpcutil.RunnerWithDir(r.dir),
 		), nil
 	case bufconfig.PluginConfigTypeLocalWasm:
 		path := pluginConfig.Path()
@@ -53,7 +92,26 @@ func (r *runnerProvider) NewRunner(pluginConfig bufconfig.PluginConfig) (pluginr
 			path[0],
 			path[1:]...,
 		), nil
+	case bufconfig.PluginConfigTypeRemote:
+		path := pluginConfig.Path()
+		return pluginrpcutil.NewRemoteRunner(
+			r.httpClient,
+			pluginConfig.RemoteURL(),
+			// We know that Path is of at least length 1.
+			path[0],
+			path[1:]...,
+		), nil
 	default:
 		return nil, syserror.Newf("unknown PluginConfigType: %v", pluginConfig.Type())
 	}
 }
+
+func (r *runnerProvider) addEnv(env map[string]string) {
+	if len(env) == 0 {
+		return
+	}
+	if r.env == nil {
+		r.env = make(map[string]string, len(env))
+	}
+	maps.Copy(r.env, env)
+}
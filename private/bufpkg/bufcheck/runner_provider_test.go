@@ -0,0 +1,63 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcheck
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/wasm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerProviderEnv(t *testing.T) {
+	t.Parallel()
+	runnerProvider := newRunnerProvider(
+		command.NewRunner(),
+		wasm.UnimplementedRuntime,
+		RunnerProviderWithEnvAllowlist(
+			app.NewEnvContainer(
+				map[string]string{
+					"HOME":             "/home/user",
+					"PATH":             "/usr/bin",
+					"BUF_TOKEN":        "secret",
+					"AWS_ACCESS_KEY":   "secret",
+					"GOOGLE_CLOUD_KEY": "secret",
+				},
+			),
+			"HOME",
+			"PATH",
+			"UNSET",
+		),
+		RunnerProviderWithEnv(
+			map[string]string{
+				"PATH": "/opt/bin",
+				"FOO":  "bar",
+			},
+		),
+		RunnerProviderWithDir("/tmp"),
+	)
+	assert.Equal(
+		t,
+		map[string]string{
+			"HOME": "/home/user",
+			"PATH": "/opt/bin",
+			"FOO":  "bar",
+		},
+		runnerProvider.env,
+	)
+	assert.Equal(t, "/tmp", runnerProvider.dir)
+}
@@ -127,6 +127,23 @@ plugins:
 		t,
 		// input
 		`version: v2
+plugins:
+  - local: protoc-gen-java
+    out: gen/java
+    persistent: true
+`,
+		// expected output
+		`version: v2
+plugins:
+  - local: protoc-gen-java
+    out: gen/java
+    persistent: true
+`,
+	)
+	testReadWriteBufGenYAMLFileRoundTrip(
+		t,
+		// input
+		`version: v2
 managed:
   disable:
     - module: buf.build/googleapis/googleapis
@@ -410,6 +427,24 @@ plugins:
 	require.ErrorContains(t, err, "cannot specify protoc_path for local plugin")
 	_, err = ReadBufGenYAMLFile(
 		strings.NewReader(`version: v2
+plugins:
+  - remote: buf.build/protocolbuffers/go
+    persistent: true
+    out: .
+`),
+	)
+	require.ErrorContains(t, err, "cannot specify persistent for remote plugin")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - protoc_builtin: cpp
+    persistent: true
+    out: .
+`),
+	)
+	require.ErrorContains(t, err, "cannot specify persistent for protoc built-in plugin")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
 plugins:
   - revision: 1
     out: .
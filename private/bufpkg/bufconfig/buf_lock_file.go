@@ -37,6 +37,9 @@ const (
 	DefaultBufLockFileName = "buf.lock"
 
 	defaultBufLockFileVersion = FileVersionV1Beta1
+
+	externalBufLockFileDepReasonDirect     = "direct"
+	externalBufLockFileDepReasonTransitive = "transitive"
 )
 
 var (
@@ -71,6 +74,12 @@ type BufLockFile interface {
 	// Files with FileVersionV1Beta1 or FileVersionV1 will only have ModuleKeys with Digests of DigestTypeB4,
 	// while Files with FileVersionV2 will only have ModuleKeys with Digests of DigestTypeB5.
 	DepModuleKeys() []bufmodule.ModuleKey
+	// DepResolution returns the reason the dependency with the given ModuleFullName was resolved
+	// into the buf.lock file.
+	//
+	// Returns nil if no reason was recorded. Reasons are only recorded in files with FileVersionV2,
+	// and buf.lock files written by older versions of the buf CLI do not have them.
+	DepResolution(moduleFullName bufmodule.ModuleFullName) BufLockFileDepResolution
 
 	isBufLockFile()
 }
@@ -79,8 +88,39 @@ type BufLockFile interface {
 //
 // Note that digests are lazily-loaded; if you need to ensure that all digests are valid, run
 // ValidateBufLockFileDigests().
-func NewBufLockFile(fileVersion FileVersion, depModuleKeys []bufmodule.ModuleKey) (BufLockFile, error) {
-	return newBufLockFile(fileVersion, nil, depModuleKeys)
+func NewBufLockFile(
+	fileVersion FileVersion,
+	depModuleKeys []bufmodule.ModuleKey,
+	options ...BufLockFileOption,
+) (BufLockFile, error) {
+	bufLockFileOptions := newBufLockFileOptions()
+	for _, option := range options {
+		option(bufLockFileOptions)
+	}
+	return newBufLockFile(fileVersion, nil, depModuleKeys, bufLockFileOptions.moduleFullNameStringToDepResolution)
+}
+
+// BufLockFileDepResolution describes why a dependency was resolved into a buf.lock file.
+//
+// This allows the dependency graph to be understood, and resolution to be reproduced,
+// without querying the registry.
+type BufLockFileDepResolution interface {
+	// IsDirect returns true if the dependency is directly depended on by a Module in the workspace.
+	//
+	// If false, the dependency is only a transitive dependency.
+	IsDirect() bool
+	// RequiredBy returns the names of the other dependencies in the buf.lock file that directly
+	// depend on this dependency.
+	//
+	// Sorted.
+	RequiredBy() []bufmodule.ModuleFullName
+
+	isBufLockFileDepResolution()
+}
+
+// NewBufLockFileDepResolution returns a new BufLockFileDepResolution.
+func NewBufLockFileDepResolution(isDirect bool, requiredBy []bufmodule.ModuleFullName) BufLockFileDepResolution {
+	return newBufLockFileDepResolution(isDirect, requiredBy)
 }
 
 // GetBufLockFileForPrefix gets the buf.lock file at the given bucket prefix.
@@ -157,9 +197,23 @@ func WriteBufLockFile(writer io.Writer, bufLockFile BufLockFile) error {
 	return writeFile(writer, bufLockFile, writeBufLockFile)
 }
 
-// BufLockFileOption is an option for getting a new BufLockFile via Get or Read.
+// BufLockFileOption is an option for getting a new BufLockFile via New, Get, or Read.
 type BufLockFileOption func(*bufLockFileOptions)
 
+// BufLockFileWithDepResolutions returns a new BufLockFileOption that records why each
+// dependency was resolved into the buf.lock file.
+//
+// The keys are the ModuleFullName strings of the dependencies. Every key must be the name of
+// a dependency in the buf.lock file, but not every dependency needs to have a resolution.
+// This is only valid for buf.lock files with FileVersionV2, and only has an effect on New.
+func BufLockFileWithDepResolutions(
+	moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution,
+) BufLockFileOption {
+	return func(bufLockFileOptions *bufLockFileOptions) {
+		bufLockFileOptions.moduleFullNameStringToDepResolution = moduleFullNameStringToDepResolution
+	}
+}
+
 // BufLockFileWithDigestResolver returns a new BufLockFileOption that will resolve digests from commits.
 //
 // Pre-approximately-v1.10 of the buf CLI, we did not store digests in buf.lock files, we only stored commits.
@@ -180,19 +234,24 @@ func BufLockFileWithDigestResolver(
 // *** PRIVATE ***
 
 type bufLockFile struct {
-	fileVersion   FileVersion
-	objectData    ObjectData
-	depModuleKeys []bufmodule.ModuleKey
+	fileVersion                         FileVersion
+	objectData                          ObjectData
+	depModuleKeys                       []bufmodule.ModuleKey
+	moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution
 }
 
 func newBufLockFile(
 	fileVersion FileVersion,
 	objectData ObjectData,
 	depModuleKeys []bufmodule.ModuleKey,
+	moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution,
 ) (*bufLockFile, error) {
 	if err := validateNoDuplicateModuleKeysByModuleFullName(depModuleKeys); err != nil {
 		return nil, err
 	}
+	if err := validateDepResolutions(fileVersion, depModuleKeys, moduleFullNameStringToDepResolution); err != nil {
+		return nil, err
+	}
 	switch fileVersion {
 	case FileVersionV1Beta1, FileVersionV1:
 		if err := validateExpectedDigestType(depModuleKeys, fileVersion, bufmodule.DigestTypeB4); err != nil {
@@ -214,9 +273,10 @@ func newBufLockFile(
 		},
 	)
 	bufLockFile := &bufLockFile{
-		fileVersion:   fileVersion,
-		objectData:    objectData,
-		depModuleKeys: depModuleKeys,
+		fileVersion:                         fileVersion,
+		objectData:                          objectData,
+		depModuleKeys:                       depModuleKeys,
+		moduleFullNameStringToDepResolution: moduleFullNameStringToDepResolution,
 	}
 	if err := validateV1AndV1Beta1DepsHaveCommits(bufLockFile); err != nil {
 		return nil, err
@@ -240,6 +300,10 @@ func (l *bufLockFile) DepModuleKeys() []bufmodule.ModuleKey {
 	return l.depModuleKeys
 }
 
+func (l *bufLockFile) DepResolution(moduleFullName bufmodule.ModuleFullName) BufLockFileDepResolution {
+	return l.moduleFullNameStringToDepResolution[moduleFullName.String()]
+}
+
 func (*bufLockFile) isBufLockFile() {}
 func (*bufLockFile) isFile()        {}
 func (*bufLockFile) isFileInfo()    {}
@@ -314,13 +378,14 @@ func readBufLockFile(
 			}
 			depModuleKeys[i] = depModuleKey
 		}
-		return newBufLockFile(fileVersion, objectData, depModuleKeys)
+		return newBufLockFile(fileVersion, objectData, depModuleKeys, nil)
 	case FileVersionV2:
 		var externalBufLockFile externalBufLockFileV2
 		if err := getUnmarshalStrict(allowJSON)(data, &externalBufLockFile); err != nil {
 			return nil, fmt.Errorf("invalid as version %v: %w", fileVersion, err)
 		}
 		depModuleKeys := make([]bufmodule.ModuleKey, len(externalBufLockFile.Deps))
+		var moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution
 		for i, dep := range externalBufLockFile.Deps {
 			dep := dep
 			if dep.Name == "" {
@@ -355,8 +420,18 @@ func readBufLockFile(
 				return nil, err
 			}
 			depModuleKeys[i] = depModuleKey
+			depResolution, err := getDepResolutionForExternalBufLockFileDepV2(dep)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resolution for module %s: %w", moduleFullName.String(), err)
+			}
+			if depResolution != nil {
+				if moduleFullNameStringToDepResolution == nil {
+					moduleFullNameStringToDepResolution = make(map[string]BufLockFileDepResolution)
+				}
+				moduleFullNameStringToDepResolution[moduleFullName.String()] = depResolution
+			}
 		}
-		return newBufLockFile(fileVersion, objectData, depModuleKeys)
+		return newBufLockFile(fileVersion, objectData, depModuleKeys, moduleFullNameStringToDepResolution)
 	default:
 		// This is a system error since we've already parsed.
 		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
@@ -413,6 +488,16 @@ func writeBufLockFile(
 				Commit: uuidutil.ToDashless(depModuleKey.CommitID()),
 				Digest: digest.String(),
 			}
+			if depResolution := bufLockFile.DepResolution(depModuleKey.ModuleFullName()); depResolution != nil {
+				externalBufLockFile.Deps[i].Reason = externalBufLockFileDepReasonTransitive
+				if depResolution.IsDirect() {
+					externalBufLockFile.Deps[i].Reason = externalBufLockFileDepReasonDirect
+				}
+				externalBufLockFile.Deps[i].RequiredBy = slicesext.Map(
+					depResolution.RequiredBy(),
+					bufmodule.ModuleFullName.String,
+				)
+			}
 		}
 		// No need to sort - depModuleKeys is already sorted by ModuleFullName
 		data, err := encoding.MarshalYAML(&externalBufLockFile)
@@ -499,6 +584,84 @@ func validateExpectedDigestType(
 	return nil
 }
 
+func validateDepResolutions(
+	fileVersion FileVersion,
+	depModuleKeys []bufmodule.ModuleKey,
+	moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution,
+) error {
+	if len(moduleFullNameStringToDepResolution) == 0 {
+		return nil
+	}
+	if fileVersion != FileVersionV2 {
+		return syserror.Newf("dependency resolutions are only supported for %v buf.lock files", FileVersionV2)
+	}
+	moduleFullNameStrings := make(map[string]struct{}, len(depModuleKeys))
+	for _, depModuleKey := range depModuleKeys {
+		moduleFullNameStrings[depModuleKey.ModuleFullName().String()] = struct{}{}
+	}
+	for moduleFullNameString, depResolution := range moduleFullNameStringToDepResolution {
+		if _, ok := moduleFullNameStrings[moduleFullNameString]; !ok {
+			return fmt.Errorf("resolution specified for module %s which is not a dependency", moduleFullNameString)
+		}
+		for _, requiredBy := range depResolution.RequiredBy() {
+			if _, ok := moduleFullNameStrings[requiredBy.String()]; !ok {
+				return fmt.Errorf("module %s is required by module %s which is not a dependency", moduleFullNameString, requiredBy.String())
+			}
+		}
+	}
+	return nil
+}
+
+func getDepResolutionForExternalBufLockFileDepV2(dep externalBufLockFileDepV2) (BufLockFileDepResolution, error) {
+	var isDirect bool
+	switch dep.Reason {
+	case "":
+		if len(dep.RequiredBy) > 0 {
+			return nil, errors.New("required_by set without reason")
+		}
+		return nil, nil
+	case externalBufLockFileDepReasonDirect:
+		isDirect = true
+	case externalBufLockFileDepReasonTransitive:
+	default:
+		return nil, fmt.Errorf("unknown reason %q, must be one of %q or %q", dep.Reason, externalBufLockFileDepReasonDirect, externalBufLockFileDepReasonTransitive)
+	}
+	requiredBy, err := slicesext.MapError(dep.RequiredBy, bufmodule.ParseModuleFullName)
+	if err != nil {
+		return nil, err
+	}
+	return newBufLockFileDepResolution(isDirect, requiredBy), nil
+}
+
+type bufLockFileDepResolution struct {
+	isDirect   bool
+	requiredBy []bufmodule.ModuleFullName
+}
+
+func newBufLockFileDepResolution(isDirect bool, requiredBy []bufmodule.ModuleFullName) *bufLockFileDepResolution {
+	requiredBy = slicesext.Copy(requiredBy)
+	sort.Slice(
+		requiredBy,
+		func(i int, j int) bool {
+			return requiredBy[i].String() < requiredBy[j].String()
+		},
+	)
+	return &bufLockFileDepResolution{
+		isDirect:   isDirect,
+		requiredBy: requiredBy,
+	}
+}
+
+func (r *bufLockFileDepResolution) IsDirect() bool {
+	return r.isDirect
+}
+
+func (r *bufLockFileDepResolution) RequiredBy() []bufmodule.ModuleFullName {
+	return slicesext.Copy(r.requiredBy)
+}
+
+func (*bufLockFileDepResolution) isBufLockFileDepResolution() {}
+
 // externalBufLockFileV1Beta1V1 represents the v1 or v1beta1 buf.lock file,
 // which have the same shape.
 type externalBufLockFileV1Beta1V1 struct {
@@ -531,6 +694,11 @@ type externalBufLockFileDepV2 struct {
 	// Dashless
 	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
 	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// One of externalBufLockFileDepReasonDirect or externalBufLockFileDepReasonTransitive.
+	//
+	// Optional, buf.lock files written by older versions of the buf CLI do not have reasons.
+	Reason     string   `json:"reason,omitempty" yaml:"reason,omitempty"`
+	RequiredBy []string `json:"required_by,omitempty" yaml:"required_by,omitempty"`
 }
 
 type bufLockFileOptions struct {
@@ -539,6 +707,7 @@ type bufLockFileOptions struct {
 		remote string,
 		commitID uuid.UUID,
 	) (bufmodule.Digest, error)
+	moduleFullNameStringToDepResolution map[string]BufLockFileDepResolution
 }
 
 func newBufLockFileOptions() *bufLockFileOptions {
@@ -0,0 +1,329 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBufLockFileB4Digest = "shake256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	testBufLockFileB5Digest = "b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+)
+
+func TestReadWriteBufLockFileRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testReadWriteBufLockFileRoundTrip(
+		t,
+		// input
+		`version: v1
+deps:
+  - remote: buf.build
+    owner: acme
+    repository: date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: shake256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb
+`,
+		// expected output
+		`# Generated by buf. DO NOT EDIT.
+version: v1
+deps:
+  - remote: buf.build
+    owner: acme
+    repository: date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: shake256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb
+`,
+	)
+
+	// v2 buf.lock files written by older versions of the buf CLI do not have reasons.
+	testReadWriteBufLockFileRoundTrip(
+		t,
+		// input
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+`,
+		// expected output
+		`# Generated by buf. DO NOT EDIT.
+version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+`,
+	)
+
+	testReadWriteBufLockFileRoundTrip(
+		t,
+		// input
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: transitive
+    required_by:
+      - buf.build/acme/extension
+      - buf.build/acme/api
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+  - name: buf.build/acme/api
+    commit: 0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+`,
+		// expected output
+		`# Generated by buf. DO NOT EDIT.
+version: v2
+deps:
+  - name: buf.build/acme/api
+    commit: 0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: transitive
+    required_by:
+      - buf.build/acme/api
+      - buf.build/acme/extension
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+`,
+	)
+}
+
+func TestBufLockFileDepResolution(t *testing.T) {
+	t.Parallel()
+	bufLockFile := testReadBufLockFile(
+		t,
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: transitive
+    required_by:
+      - buf.build/acme/extension
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+  - name: buf.build/acme/other
+    commit: 0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+`,
+	)
+	depResolution := bufLockFile.DepResolution(testParseModuleFullName(t, "buf.build/acme/date"))
+	require.NotNil(t, depResolution)
+	assert.False(t, depResolution.IsDirect())
+	require.Len(t, depResolution.RequiredBy(), 1)
+	assert.Equal(t, "buf.build/acme/extension", depResolution.RequiredBy()[0].String())
+	depResolution = bufLockFile.DepResolution(testParseModuleFullName(t, "buf.build/acme/extension"))
+	require.NotNil(t, depResolution)
+	assert.True(t, depResolution.IsDirect())
+	assert.Empty(t, depResolution.RequiredBy())
+	assert.Nil(t, bufLockFile.DepResolution(testParseModuleFullName(t, "buf.build/acme/other")))
+}
+
+func TestNewBufLockFileDepResolutions(t *testing.T) {
+	t.Parallel()
+	dateModuleKey := testNewModuleKey(t, "buf.build/acme/date", "8be2ed6c2d5a4b0e9bbc8c25ad26c3ce", testBufLockFileB5Digest)
+	extensionModuleKey := testNewModuleKey(t, "buf.build/acme/extension", "3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12", testBufLockFileB5Digest)
+	depResolutions := map[string]BufLockFileDepResolution{
+		"buf.build/acme/date": NewBufLockFileDepResolution(
+			false,
+			[]bufmodule.ModuleFullName{
+				extensionModuleKey.ModuleFullName(),
+			},
+		),
+		"buf.build/acme/extension": NewBufLockFileDepResolution(true, nil),
+	}
+	bufLockFile, err := NewBufLockFile(
+		FileVersionV2,
+		[]bufmodule.ModuleKey{dateModuleKey, extensionModuleKey},
+		BufLockFileWithDepResolutions(depResolutions),
+	)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBufLockFile(buffer, bufLockFile))
+	assert.Equal(
+		t,
+		testCleanYAMLData(`# Generated by buf. DO NOT EDIT.
+version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: transitive
+    required_by:
+      - buf.build/acme/extension
+  - name: buf.build/acme/extension
+    commit: 3f5a8f2e4a2f4d1e8d3b5d7a6c9e0f12
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: direct
+`),
+		testCleanYAMLData(buffer.String()),
+	)
+
+	// Resolutions are only supported for v2.
+	_, err = NewBufLockFile(
+		FileVersionV1,
+		[]bufmodule.ModuleKey{
+			testNewModuleKey(t, "buf.build/acme/date", "8be2ed6c2d5a4b0e9bbc8c25ad26c3ce", testBufLockFileB4Digest),
+		},
+		BufLockFileWithDepResolutions(
+			map[string]BufLockFileDepResolution{
+				"buf.build/acme/date": NewBufLockFileDepResolution(true, nil),
+			},
+		),
+	)
+	require.Error(t, err)
+	// Resolutions must be for dependencies in the buf.lock file.
+	_, err = NewBufLockFile(
+		FileVersionV2,
+		[]bufmodule.ModuleKey{dateModuleKey},
+		BufLockFileWithDepResolutions(depResolutions),
+	)
+	require.Error(t, err)
+}
+
+func TestBufLockFileInvalidDepResolution(t *testing.T) {
+	t.Parallel()
+	testReadBufLockFileFail(
+		t,
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: indirect
+`,
+		`unknown reason "indirect"`,
+	)
+	testReadBufLockFileFail(
+		t,
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    required_by:
+      - buf.build/acme/extension
+`,
+		"required_by set without reason",
+	)
+	testReadBufLockFileFail(
+		t,
+		`version: v2
+deps:
+  - name: buf.build/acme/date
+    commit: 8be2ed6c2d5a4b0e9bbc8c25ad26c3ce
+    digest: b5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    reason: transitive
+    required_by:
+      - buf.build/acme/extension
+`,
+		"which is not a dependency",
+	)
+}
+
+func testReadWriteBufLockFileRoundTrip(
+	t *testing.T,
+	inputBufLockFileData string,
+	expectedOutputBufLockFileData string,
+) {
+	bufLockFile := testReadBufLockFile(t, inputBufLockFileData)
+	buffer := bytes.NewBuffer(nil)
+	err := WriteBufLockFile(buffer, bufLockFile)
+	require.NoError(t, err)
+	outputBufLockFileData := testCleanYAMLData(buffer.String())
+	assert.Equal(t, testCleanYAMLData(expectedOutputBufLockFileData), outputBufLockFileData, "output:\n%s", outputBufLockFileData)
+}
+
+func testReadBufLockFile(
+	t *testing.T,
+	inputBufLockFileData string,
+) BufLockFile {
+	bufLockFile, err := ReadBufLockFile(
+		context.Background(),
+		strings.NewReader(testCleanYAMLData(inputBufLockFileData)),
+		DefaultBufLockFileName,
+	)
+	require.NoError(t, err)
+	return bufLockFile
+}
+
+func testReadBufLockFileFail(
+	t *testing.T,
+	inputBufLockFileData string,
+	errorContains string,
+) {
+	_, err := ReadBufLockFile(
+		context.Background(),
+		strings.NewReader(testCleanYAMLData(inputBufLockFileData)),
+		DefaultBufLockFileName,
+	)
+	require.ErrorContains(t, err, errorContains)
+}
+
+func testParseModuleFullName(t *testing.T, moduleFullNameString string) bufmodule.ModuleFullName {
+	moduleFullName, err := bufmodule.ParseModuleFullName(moduleFullNameString)
+	require.NoError(t, err)
+	return moduleFullName
+}
+
+func testNewModuleKey(
+	t *testing.T,
+	moduleFullNameString string,
+	dashlessCommitID string,
+	digestString string,
+) bufmodule.ModuleKey {
+	commitID, err := uuidutil.FromDashless(dashlessCommitID)
+	require.NoError(t, err)
+	digest, err := bufmodule.ParseDigest(digestString)
+	require.NoError(t, err)
+	moduleKey, err := bufmodule.NewModuleKey(
+		testParseModuleFullName(t, moduleFullNameString),
+		commitID,
+		func() (bufmodule.Digest, error) {
+			return digest, nil
+		},
+	)
+	require.NoError(t, err)
+	return moduleKey
+}
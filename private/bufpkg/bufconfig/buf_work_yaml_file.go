@@ -16,8 +16,13 @@ package bufconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
@@ -70,8 +75,15 @@ type BufWorkYAMLFile interface {
 	//   construction time of a BufWorkYAMLFile.
 	//
 	// Returned paths are sorted.
+	//
+	// If the BufWorkYAMLFile was read with GetBufWorkYAMLFileForPrefix, nested workspaces
+	// and glob patterns in the directories list have been resolved, and DirPaths contains
+	// the resulting module directories. See GetBufWorkYAMLFileForPrefix for more details.
 	DirPaths() []string
 
+	// declaredDirPaths returns the directory paths as declared in the file, before
+	// nested workspaces and glob patterns were resolved.
+	declaredDirPaths() []string
 	isBufWorkYAMLFile()
 }
 
@@ -83,12 +95,22 @@ func NewBufWorkYAMLFile(fileVersion FileVersion, dirPaths []string) (BufWorkYAML
 // GetBufWorkYAMLFileForPrefix gets the buf.work.yaml file at the given bucket prefix.
 //
 // The buf.work.yaml file will be attempted to be read at prefix/buf.work.yaml.
+//
+// Entries in the directories list are resolved against the bucket:
+//
+//   - An entry that is a directory containing its own buf.work.yaml is replaced by the
+//     directories of that workspace, recursively. This allows a workspace to be composed
+//     of other workspaces, for example sub-repositories checked out into a platform repository.
+//   - An entry that is a glob pattern, such as "repos/*", is replaced by all matching
+//     directories that contain a buf.yaml or buf.work.yaml. Patterns use the syntax of path.Match.
+//
+// An error is returned if the nested workspaces form a cycle, or if a glob pattern matches nothing.
 func GetBufWorkYAMLFileForPrefix(
 	ctx context.Context,
 	bucket storage.ReadBucket,
 	prefix string,
 ) (BufWorkYAMLFile, error) {
-	return getFileForPrefix(ctx, bucket, prefix, bufWorkYAMLFileNames, bufWorkYAMLFileNameToSupportedFileVersions, readBufWorkYAMLFile)
+	return getResolvedBufWorkYAMLFileForPrefix(ctx, bucket, prefix, nil)
 }
 
 // GetBufWorkYAMLFileForPrefix gets the buf.work.yaml file version at the given bucket prefix.
@@ -130,9 +152,10 @@ func WriteBufWorkYAMLFile(writer io.Writer, bufWorkYAMLFile BufWorkYAMLFile) err
 // *** PRIVATE ***
 
 type bufWorkYAMLFile struct {
-	fileVersion FileVersion
-	objectData  ObjectData
-	dirPaths    []string
+	fileVersion          FileVersion
+	objectData           ObjectData
+	dirPaths             []string
+	declaredDirPathsList []string
 }
 
 func newBufWorkYAMLFile(fileVersion FileVersion, objectData ObjectData, dirPaths []string) (*bufWorkYAMLFile, error) {
@@ -144,9 +167,10 @@ func newBufWorkYAMLFile(fileVersion FileVersion, objectData ObjectData, dirPaths
 		return nil, err
 	}
 	return &bufWorkYAMLFile{
-		fileVersion: fileVersion,
-		objectData:  objectData,
-		dirPaths:    sortedNormalizedDirPaths,
+		fileVersion:          fileVersion,
+		objectData:           objectData,
+		dirPaths:             sortedNormalizedDirPaths,
+		declaredDirPathsList: sortedNormalizedDirPaths,
 	}, nil
 }
 
@@ -166,6 +190,10 @@ func (w *bufWorkYAMLFile) DirPaths() []string {
 	return slicesext.Copy(w.dirPaths)
 }
 
+func (w *bufWorkYAMLFile) declaredDirPaths() []string {
+	return slicesext.Copy(w.declaredDirPathsList)
+}
+
 func (*bufWorkYAMLFile) isBufWorkYAMLFile() {}
 func (*bufWorkYAMLFile) isFile()            {}
 func (*bufWorkYAMLFile) isFileInfo()        {}
@@ -198,8 +226,9 @@ func writeBufWorkYAMLFile(writer io.Writer, bufWorkYAMLFile BufWorkYAMLFile) err
 	}
 	externalBufWorkYAMLFile := externalBufWorkYAMLFileV1{
 		Version: fileVersion.String(),
-		// No need to sort - DirPaths() is already sorted per the documentation on BufWorkYAMLFile
-		Directories: bufWorkYAMLFile.DirPaths(),
+		// No need to sort - the declared paths are sorted at construction time. We write the
+		// declared paths so that nested workspaces and glob patterns are preserved.
+		Directories: bufWorkYAMLFile.declaredDirPaths(),
 	}
 	data, err := encoding.MarshalYAML(&externalBufWorkYAMLFile)
 	if err != nil {
@@ -224,6 +253,9 @@ func validateBufWorkYAMLDirPaths(dirPaths []string) ([]string, error) {
 		if _, ok := normalizedDirPathToDirPath[normalizedDirPath]; ok {
 			return nil, fmt.Errorf(`directory %q is listed more than once`, dirPath)
 		}
+		if _, err := path.Match(normalizedDirPath, ""); err != nil {
+			return nil, fmt.Errorf(`directory %q is an invalid glob pattern: %w`, dirPath, err)
+		}
 		if normalizedDirPath == "." {
 			return nil, fmt.Errorf(`directory "." is listed, it is not valid to have "." as a workspace directory, as this is no different than not having a workspace at all, see https://buf.build/docs/reference/workspaces/#directories for more details`)
 		}
@@ -255,6 +287,152 @@ func validateBufWorkYAMLDirPaths(dirPaths []string) ([]string, error) {
 	return sortedNormalizedDirPaths, nil
 }
 
+// getResolvedBufWorkYAMLFileForPrefix reads the buf.work.yaml at the prefix and resolves
+// nested workspaces and glob patterns in its directories.
+//
+// parentWorkspaceIDs are the IDs of the workspaces that are currently being resolved, from
+// outermost to innermost, and are used for cycle detection.
+func getResolvedBufWorkYAMLFileForPrefix(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	prefix string,
+	parentWorkspaceIDs []string,
+) (BufWorkYAMLFile, error) {
+	declaredBufWorkYAMLFile, err := getFileForPrefix(ctx, bucket, prefix, bufWorkYAMLFileNames, bufWorkYAMLFileNameToSupportedFileVersions, readBufWorkYAMLFile)
+	if err != nil {
+		return nil, err
+	}
+	workspaceID, err := getBufWorkYAMLWorkspaceID(ctx, bucket, prefix, declaredBufWorkYAMLFile)
+	if err != nil {
+		return nil, err
+	}
+	for i, parentWorkspaceID := range parentWorkspaceIDs {
+		if parentWorkspaceID == workspaceID {
+			return nil, fmt.Errorf(
+				"cycle detected in nested workspaces: %s",
+				strings.Join(append(parentWorkspaceIDs[i:], workspaceID), " -> "),
+			)
+		}
+	}
+	workspaceIDs := append(slicesext.Copy(parentWorkspaceIDs), workspaceID)
+	var resolvedDirPaths []string
+	for _, declaredDirPath := range declaredBufWorkYAMLFile.declaredDirPaths() {
+		dirPaths := []string{declaredDirPath}
+		if isBufWorkYAMLGlobPattern(declaredDirPath) {
+			dirPaths, err = getBufWorkYAMLGlobMatches(ctx, bucket, prefix, declaredDirPath)
+			if err != nil {
+				return nil, err
+			}
+			if len(dirPaths) == 0 {
+				return nil, fmt.Errorf("directory pattern %q in %q did not match any directories containing a %s or %s", declaredDirPath, workspaceID, DefaultBufYAMLFileName, DefaultBufWorkYAMLFileName)
+			}
+		}
+		for _, dirPath := range dirPaths {
+			nestedBufWorkYAMLFile, err := getResolvedBufWorkYAMLFileForPrefix(ctx, bucket, normalpath.Join(prefix, dirPath), workspaceIDs)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// Not a nested workspace, this is a module directory.
+					resolvedDirPaths = append(resolvedDirPaths, dirPath)
+					continue
+				}
+				return nil, err
+			}
+			for _, nestedDirPath := range nestedBufWorkYAMLFile.DirPaths() {
+				resolvedDirPaths = append(resolvedDirPaths, normalpath.Join(dirPath, nestedDirPath))
+			}
+		}
+	}
+	sortedResolvedDirPaths, err := validateBufWorkYAMLDirPaths(resolvedDirPaths)
+	if err != nil {
+		return nil, newDecodeError(normalpath.Join(prefix, declaredBufWorkYAMLFile.ObjectData().Name()), err)
+	}
+	return &bufWorkYAMLFile{
+		fileVersion:          declaredBufWorkYAMLFile.FileVersion(),
+		objectData:           declaredBufWorkYAMLFile.ObjectData(),
+		dirPaths:             sortedResolvedDirPaths,
+		declaredDirPathsList: declaredBufWorkYAMLFile.declaredDirPaths(),
+	}, nil
+}
+
+// getBufWorkYAMLWorkspaceID returns an identifier for the workspace at the prefix, used
+// for cycle detection.
+//
+// If the bucket is backed by the local filesystem, this is the directory with symlinks
+// evaluated, as symlinks are the only way that nested workspaces can form a cycle.
+// Otherwise, this is the prefix.
+func getBufWorkYAMLWorkspaceID(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	prefix string,
+	bufWorkYAMLFile BufWorkYAMLFile,
+) (string, error) {
+	objectInfo, err := bucket.Stat(ctx, normalpath.Join(prefix, bufWorkYAMLFile.ObjectData().Name()))
+	if err != nil {
+		return "", err
+	}
+	if localPath := objectInfo.LocalPath(); localPath != "" {
+		dirPath, err := filepath.EvalSymlinks(filepath.Dir(localPath))
+		if err != nil {
+			return "", err
+		}
+		return dirPath, nil
+	}
+	return prefix, nil
+}
+
+// getBufWorkYAMLGlobMatches returns the directories relative to the prefix that match
+// the pattern and contain a buf.yaml or buf.work.yaml.
+//
+// Returned paths are sorted.
+func getBufWorkYAMLGlobMatches(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	prefix string,
+	pattern string,
+) ([]string, error) {
+	// Only walk the part of the bucket that can possibly match.
+	var staticComponents []string
+	for _, component := range normalpath.Components(pattern) {
+		if isBufWorkYAMLGlobPattern(component) {
+			break
+		}
+		staticComponents = append(staticComponents, component)
+	}
+	configFileNames := make(map[string]struct{})
+	for _, fileName := range append(slicesext.Copy(bufYAMLFileNames), bufWorkYAMLFileNames...) {
+		configFileNames[fileName] = struct{}{}
+	}
+	matches := make(map[string]struct{})
+	if err := bucket.Walk(
+		ctx,
+		normalpath.Join(prefix, normalpath.Join(staticComponents...)),
+		func(objectInfo storage.ObjectInfo) error {
+			if _, ok := configFileNames[normalpath.Base(objectInfo.Path())]; !ok {
+				return nil
+			}
+			dirPath, err := normalpath.Rel(prefix, normalpath.Dir(objectInfo.Path()))
+			if err != nil {
+				return err
+			}
+			matched, err := path.Match(pattern, dirPath)
+			if err != nil {
+				return err
+			}
+			if matched {
+				matches[dirPath] = struct{}{}
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return slicesext.MapKeysToSortedSlice(matches), nil
+}
+
+func isBufWorkYAMLGlobPattern(dirPath string) bool {
+	return strings.ContainsAny(dirPath, "*?[")
+}
+
 // externalBufWorkYAMLFileV1 represents the v1 buf.work.yaml file.
 type externalBufWorkYAMLFileV1 struct {
 	Version     string   `json:"version,omitempty" yaml:"version,omitempty"`
@@ -15,11 +15,14 @@
 package bufconfig
 
 import (
+	"bytes"
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/stretchr/testify/require"
 )
 
@@ -102,6 +105,136 @@ directories:
 	}
 }
 
+func TestGetBufWorkYAMLFileForPrefixNested(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"buf.work.yaml": []byte(`version: v1
+directories:
+  - proto
+  - platform
+  - repos/*
+`),
+			"proto/buf.yaml": []byte("version: v1\n"),
+			"platform/buf.work.yaml": []byte(`version: v1
+directories:
+  - a
+  - vendor/b
+`),
+			"platform/a/buf.yaml":        []byte("version: v1\n"),
+			"platform/vendor/b/buf.yaml": []byte("version: v1\n"),
+			"repos/one/buf.yaml":         []byte("version: v1\n"),
+			"repos/two/buf.work.yaml": []byte(`version: v1
+directories:
+  - proto
+`),
+			"repos/two/proto/buf.yaml": []byte("version: v1\n"),
+			// Does not contain a buf.yaml, so does not match repos/*.
+			"repos/three/README.md": []byte("readme\n"),
+		},
+	)
+	require.NoError(t, err)
+	bufWorkYAMLFile, err := GetBufWorkYAMLFileForPrefix(ctx, readBucket, ".")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"platform/a",
+			"platform/vendor/b",
+			"proto",
+			"repos/one",
+			"repos/two/proto",
+		},
+		bufWorkYAMLFile.DirPaths(),
+	)
+	// The declared directories are preserved when writing.
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBufWorkYAMLFile(buffer, bufWorkYAMLFile))
+	require.Equal(
+		t,
+		`version: v1
+directories:
+  - platform
+  - proto
+  - repos/*
+`,
+		buffer.String(),
+	)
+
+	_, err = GetBufWorkYAMLFileForPrefix(ctx, readBucket, "platform")
+	require.NoError(t, err)
+}
+
+func TestGetBufWorkYAMLFileForPrefixNestedFail(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	testcases := []struct {
+		description string
+		files       map[string][]byte
+	}{
+		{
+			description: "glob_no_match",
+			files: map[string][]byte{
+				"buf.work.yaml": []byte(`version: v1
+directories:
+  - repos/*
+`),
+				"repos/one/README.md": []byte("readme\n"),
+			},
+		},
+		{
+			description: "nested_duplicate",
+			files: map[string][]byte{
+				"buf.work.yaml": []byte(`version: v1
+directories:
+  - repos/*
+  - repos/one
+`),
+				"repos/one/buf.yaml": []byte("version: v1\n"),
+			},
+		},
+		{
+			description: "invalid_glob",
+			files: map[string][]byte{
+				"buf.work.yaml": []byte(`version: v1
+directories:
+  - repos/[
+`),
+			},
+		},
+	}
+	for _, testcase := range testcases {
+		testcase := testcase
+		t.Run(testcase.description, func(t *testing.T) {
+			t.Parallel()
+			readBucket, err := storagemem.NewReadBucket(testcase.files)
+			require.NoError(t, err)
+			_, err = GetBufWorkYAMLFileForPrefix(ctx, readBucket, ".")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGetBufWorkYAMLFileForPrefixNestedCycle(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDirPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDirPath, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDirPath, "buf.work.yaml"), []byte("version: v1\ndirectories:\n  - sub\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDirPath, "sub", "buf.work.yaml"), []byte("version: v1\ndirectories:\n  - parent\n"), 0600))
+	if err := os.Symlink(tempDirPath, filepath.Join(tempDirPath, "sub", "parent")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	readBucket, err := storageos.NewProvider(storageos.ProviderWithSymlinks()).NewReadWriteBucket(
+		tempDirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	require.NoError(t, err)
+	_, err = GetBufWorkYAMLFileForPrefix(ctx, readBucket, ".")
+	require.ErrorContains(t, err, "cycle detected")
+}
+
 func TestNewBufWorkYAMLFile(t *testing.T) {
 	t.Parallel()
 	testcases := []struct {
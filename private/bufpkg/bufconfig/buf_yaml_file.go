@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/encoding"
@@ -32,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -111,6 +113,13 @@ type BufYAMLFile interface {
 	// The ModuleRefs in this list will be unique by ModuleFullName.
 	// Sorted by ModuleFullName.
 	ConfiguredDepModuleRefs() []bufmodule.ModuleRef
+	// ReplaceConfigs returns the replace directives for the dependencies of the Workspace.
+	//
+	// The ReplaceConfigs in this list will be unique by ModuleFullName.
+	// Sorted by ModuleFullName.
+	//
+	// For v1 buf.yaml files, this will always return nil.
+	ReplaceConfigs() []ReplaceConfig
 	//IncludeDocsLink specifies whether a top-level comment with a link to our public docs
 	// should be included at the top of the buf.yaml file.
 	IncludeDocsLink() bool
@@ -140,6 +149,7 @@ func NewBufYAMLFile(
 		nil, // Do not set top-level breaking config, use only module configs
 		pluginConfigs,
 		configuredDepModuleRefs,
+		bufYAMLFileOptions.replaceConfigs,
 		bufYAMLFileOptions.includeDocsLink,
 	)
 }
@@ -156,6 +166,16 @@ func BufYAMLFileWithIncludeDocsLink() BufYAMLFileOption {
 	}
 }
 
+// BufYAMLFileWithReplaceConfigs returns a new BufYAMLFileOption that sets the replace
+// directives for the dependencies of the Workspace.
+//
+// This is only valid for v2 buf.yaml files.
+func BufYAMLFileWithReplaceConfigs(replaceConfigs []ReplaceConfig) BufYAMLFileOption {
+	return func(bufYAMLFileOptions *bufYAMLFileOptions) {
+		bufYAMLFileOptions.replaceConfigs = replaceConfigs
+	}
+}
+
 // GetBufYAMLFileForPrefix gets the buf.yaml file at the given bucket prefix.
 //
 // The buf.yaml file will be attempted to be read at prefix/buf.yaml.
@@ -232,6 +252,43 @@ func PutBufYAMLFileForPrefix(
 	return putFileForPrefix(ctx, bucket, prefix, bufYAMLFile, DefaultBufYAMLFileName, bufYAMLFileNameToSupportedFileVersions, writeBufYAMLFile)
 }
 
+// RemoveBufYAMLFileDepsForPrefix removes the deps with the given ModuleFullNames from the
+// buf.yaml file at the given bucket prefix.
+//
+// Unlike PutBufYAMLFileForPrefix, the existing buf.yaml file is edited rather than rewritten, so
+// that comments and the order of the remaining deps are preserved. If the buf.yaml file has no
+// deps with the given ModuleFullNames, the buf.yaml file is not written.
+//
+// Returns an error that fulfills fs.ErrNotExist if the buf.yaml file does not exist.
+func RemoveBufYAMLFileDepsForPrefix(
+	ctx context.Context,
+	bucket storage.ReadWriteBucket,
+	prefix string,
+	moduleFullNames []bufmodule.ModuleFullName,
+) (retErr error) {
+	bufYAMLFile, err := GetBufYAMLFileForPrefix(ctx, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	objectData := bufYAMLFile.ObjectData()
+	if objectData == nil {
+		return syserror.Newf("no ObjectData for buf.yaml file at prefix %q", prefix)
+	}
+	path := normalpath.Join(prefix, objectData.Name())
+	data, changed, err := removeBufYAMLFileDeps(objectData.Data(), moduleFullNames)
+	if err != nil {
+		return newEncodeError(path, err)
+	}
+	if !changed {
+		return nil
+	}
+	// Make sure we never write a buf.yaml file that we cannot read.
+	if _, err := readBufYAMLFile(data, newObjectData(objectData.Name(), data), false); err != nil {
+		return syserror.Wrap(newEncodeError(path, err))
+	}
+	return storage.PutPath(ctx, bucket, path, data, storage.PutWithAtomic())
+}
+
 // ReadBufYAMLFile reads the BufYAMLFile from the io.Reader.
 //
 // fileName may be empty.
@@ -254,6 +311,7 @@ type bufYAMLFile struct {
 	topLevelBreakingConfig  BreakingConfig
 	pluginConfigs           []PluginConfig
 	configuredDepModuleRefs []bufmodule.ModuleRef
+	replaceConfigs          []ReplaceConfig
 	includeDocsLink         bool
 }
 
@@ -265,6 +323,7 @@ func newBufYAMLFile(
 	topLevelBreakingConfig BreakingConfig,
 	pluginConfigs []PluginConfig,
 	configuredDepModuleRefs []bufmodule.ModuleRef,
+	replaceConfigs []ReplaceConfig,
 	includeDocsLink bool,
 ) (*bufYAMLFile, error) {
 	if (fileVersion == FileVersionV1Beta1 || fileVersion == FileVersionV1) && len(moduleConfigs) > 1 {
@@ -296,6 +355,9 @@ func newBufYAMLFile(
 	if _, err := bufmodule.ModuleFullNameStringToUniqueValue(configuredDepModuleRefs); err != nil {
 		return nil, err
 	}
+	if err := validateReplaceConfigs(fileVersion, moduleConfigs, replaceConfigs); err != nil {
+		return nil, err
+	}
 	// Since multiple module configs with the same DirPath are allowed in v2, we need a stable sort
 	// so that the relative order among module configs with the same DirPath is preserved from the
 	// external buf.yaml, as specified in BufYAMLFile.ModuleConfigs' doc.
@@ -312,6 +374,13 @@ func newBufYAMLFile(
 				configuredDepModuleRefs[j].ModuleFullName().String()
 		},
 	)
+	sort.Slice(
+		replaceConfigs,
+		func(i int, j int) bool {
+			return replaceConfigs[i].ModuleFullName().String() <
+				replaceConfigs[j].ModuleFullName().String()
+		},
+	)
 	return &bufYAMLFile{
 		fileVersion:             fileVersion,
 		objectData:              objectData,
@@ -320,6 +389,7 @@ func newBufYAMLFile(
 		topLevelBreakingConfig:  topLevelBreakingConfig,
 		pluginConfigs:           pluginConfigs,
 		configuredDepModuleRefs: configuredDepModuleRefs,
+		replaceConfigs:          replaceConfigs,
 		includeDocsLink:         includeDocsLink,
 	}, nil
 }
@@ -356,6 +426,10 @@ func (c *bufYAMLFile) ConfiguredDepModuleRefs() []bufmodule.ModuleRef {
 	return slicesext.Copy(c.configuredDepModuleRefs)
 }
 
+func (c *bufYAMLFile) ReplaceConfigs() []ReplaceConfig {
+	return slicesext.Copy(c.replaceConfigs)
+}
+
 func (c *bufYAMLFile) IncludeDocsLink() bool {
 	return c.includeDocsLink
 }
@@ -365,9 +439,64 @@ func (*bufYAMLFile) isFile()        {}
 func (*bufYAMLFile) isFileInfo()    {}
 
 type bufYAMLFileOptions struct {
+	replaceConfigs  []ReplaceConfig
 	includeDocsLink bool
 }
 
+// removeBufYAMLFileDeps removes the deps with the given ModuleFullNames from the buf.yaml data.
+//
+// If all deps are removed, the deps key is removed. Returns false if nothing was removed.
+func removeBufYAMLFileDeps(data []byte, moduleFullNames []bufmodule.ModuleFullName) ([]byte, bool, error) {
+	moduleFullNameStrings := slicesext.ToStructMap(
+		slicesext.Map(moduleFullNames, bufmodule.ModuleFullName.String),
+	)
+	var documentNode yaml.Node
+	if err := yaml.Unmarshal(data, &documentNode); err != nil {
+		return nil, false, err
+	}
+	if documentNode.Kind != yaml.DocumentNode || len(documentNode.Content) != 1 || documentNode.Content[0].Kind != yaml.MappingNode {
+		return nil, false, errors.New("expected a YAML mapping")
+	}
+	mappingNode := documentNode.Content[0]
+	changed := false
+	// Mapping nodes alternate between keys and values.
+	for i := 0; i+1 < len(mappingNode.Content); i += 2 {
+		if mappingNode.Content[i].Value != "deps" {
+			continue
+		}
+		depsNode := mappingNode.Content[i+1]
+		if depsNode.Kind != yaml.SequenceNode {
+			return nil, false, errors.New("expected deps to be a sequence")
+		}
+		var remainingDepNodes []*yaml.Node
+		for _, depNode := range depsNode.Content {
+			moduleRef, err := bufmodule.ParseModuleRef(depNode.Value)
+			if err != nil {
+				return nil, false, err
+			}
+			if _, ok := moduleFullNameStrings[moduleRef.ModuleFullName().String()]; ok {
+				changed = true
+				continue
+			}
+			remainingDepNodes = append(remainingDepNodes, depNode)
+		}
+		if len(remainingDepNodes) == 0 {
+			mappingNode.Content = append(mappingNode.Content[:i], mappingNode.Content[i+2:]...)
+		} else {
+			depsNode.Content = remainingDepNodes
+		}
+		break
+	}
+	if !changed {
+		return data, false, nil
+	}
+	newData, err := encoding.MarshalYAML(&documentNode)
+	if err != nil {
+		return nil, false, err
+	}
+	return newData, true, nil
+}
+
 func newBufYAMLFileOptions() *bufYAMLFileOptions {
 	return &bufYAMLFileOptions{}
 }
@@ -455,6 +584,7 @@ func readBufYAMLFile(
 			breakingConfig,
 			nil,
 			configuredDepModuleRefs,
+			nil,
 			includeDocsLink,
 		)
 	case FileVersionV2:
@@ -654,6 +784,14 @@ func readBufYAMLFile(
 		if err != nil {
 			return nil, err
 		}
+		var replaceConfigs []ReplaceConfig
+		for _, externalReplace := range externalBufYAMLFile.Replace {
+			replaceConfig, err := newReplaceConfigForExternalV2(externalReplace)
+			if err != nil {
+				return nil, err
+			}
+			replaceConfigs = append(replaceConfigs, replaceConfig)
+		}
 		return newBufYAMLFile(
 			fileVersion,
 			objectData,
@@ -662,6 +800,7 @@ func readBufYAMLFile(
 			topLevelBreakingConfig,
 			pluginConfigs,
 			configuredDepModuleRefs,
+			replaceConfigs,
 			includeDocsLink,
 		)
 	default:
@@ -868,6 +1007,11 @@ func writeBufYAMLFile(writer io.Writer, bufYAMLFile BufYAMLFile) error {
 			externalPlugins = append(externalPlugins, externalPlugin)
 		}
 		externalBufYAMLFile.Plugins = externalPlugins
+		// Already sorted.
+		externalBufYAMLFile.Replace = slicesext.Map(
+			bufYAMLFile.ReplaceConfigs(),
+			newExternalV2ForReplaceConfig,
+		)
 
 		data, err := encoding.MarshalYAML(&externalBufYAMLFile)
 		if err != nil {
@@ -975,6 +1119,44 @@ func getConfiguredDepModuleRefsForExternalDeps(
 	return configuredDepModuleRefs, nil
 }
 
+func validateReplaceConfigs(
+	fileVersion FileVersion,
+	moduleConfigs []ModuleConfig,
+	replaceConfigs []ReplaceConfig,
+) error {
+	if len(replaceConfigs) == 0 {
+		return nil
+	}
+	if fileVersion != FileVersionV2 {
+		return fmt.Errorf("replace is only supported for %v buf.yaml files", FileVersionV2)
+	}
+	moduleFullNameStrings := make(map[string]struct{}, len(moduleConfigs))
+	dirPaths := make(map[string]struct{}, len(moduleConfigs))
+	for _, moduleConfig := range moduleConfigs {
+		if moduleFullName := moduleConfig.ModuleFullName(); moduleFullName != nil {
+			moduleFullNameStrings[moduleFullName.String()] = struct{}{}
+		}
+		dirPaths[moduleConfig.DirPath()] = struct{}{}
+	}
+	replacedModuleFullNameStrings := make(map[string]struct{}, len(replaceConfigs))
+	for _, replaceConfig := range replaceConfigs {
+		moduleFullNameString := replaceConfig.ModuleFullName().String()
+		if _, ok := replacedModuleFullNameStrings[moduleFullNameString]; ok {
+			return fmt.Errorf("module %s is replaced more than once", moduleFullNameString)
+		}
+		replacedModuleFullNameStrings[moduleFullNameString] = struct{}{}
+		if _, ok := moduleFullNameStrings[moduleFullNameString]; ok {
+			return fmt.Errorf("module %s cannot be replaced as it is a module within the workspace", moduleFullNameString)
+		}
+		if path := replaceConfig.Path(); path != "" {
+			if _, ok := dirPaths[path]; ok {
+				return fmt.Errorf("module %s cannot be replaced with path %q as it is the path of a module within the workspace", moduleFullNameString, path)
+			}
+		}
+	}
+	return nil
+}
+
 func getLintConfigForExternalLintV1Beta1V1(
 	fileVersion FileVersion,
 	externalLint externalBufYAMLFileLintV1Beta1V1,
@@ -1154,6 +1336,7 @@ func isLintOrBreakingDisabledBasedOnIgnores(
 //     returned slice if requirePathsToBeContainedWithinModuleDirPath is false. This can happen when we
 //     are transforming a path from the default workspace-wide lint or breaking config. We want to skip these paths.
 //     If requirePathsToBeContainedWithinModuleDirPath is true, return error.
+//   - If the path is a glob pattern that starts with "**", adds the path as-is to the returned slice.
 //   - Otherwise, adds the path relative to the given module directory path to the returned slice.
 //
 // It is important to note that because we are only taking paths that are contained in the module
@@ -1175,6 +1358,12 @@ func getRelPathsForLintOrBreakingExternalPaths(
 			// user error
 			return nil, fmt.Errorf("%s: invalid path: %w", fieldName, err)
 		}
+		if path == "**" || strings.HasPrefix(path, "**/") {
+			// Globs such as "**/*_test.proto" are not anchored to a directory, and apply
+			// within every module as-is.
+			relPaths = append(relPaths, path)
+			continue
+		}
 		if !normalpath.EqualsOrContainsPath(moduleDirPath, path, normalpath.Relative) {
 			if !requirePathsToBeContainedWithinModuleDirPath {
 				continue
@@ -1277,6 +1466,7 @@ type externalBufYAMLFileV2 struct {
 	Name     string                                 `json:"name,omitempty" yaml:"name,omitempty"`
 	Modules  []externalBufYAMLFileModuleV2          `json:"modules,omitempty" yaml:"modules,omitempty"`
 	Deps     []string                               `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Replace  []externalBufYAMLFileReplaceV2         `json:"replace,omitempty" yaml:"replace,omitempty"`
 	Lint     externalBufYAMLFileLintV2              `json:"lint,omitempty" yaml:"lint,omitempty"`
 	Breaking externalBufYAMLFileBreakingV1Beta1V1V2 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
 	Plugins  []externalBufYAMLFilePluginV2          `json:"plugins,omitempty" yaml:"plugins,omitempty"`
@@ -1401,6 +1591,18 @@ func (eb externalBufYAMLFileBreakingV1Beta1V1V2) isEmpty() bool {
 type externalBufYAMLFilePluginV2 struct {
 	Plugin  any            `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+	// RemoteURL is the base URL of a remote execution service to run the plugin on,
+	// instead of running the plugin locally.
+	RemoteURL string `json:"remote_url,omitempty" yaml:"remote_url,omitempty"`
+}
+
+// externalBufYAMLFileReplaceV2 represents a single replace directive in a v2 buf.yaml file.
+//
+// Exactly one of Path and With is set.
+type externalBufYAMLFileReplaceV2 struct {
+	Module string `json:"module,omitempty" yaml:"module,omitempty"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	With   string `json:"with,omitempty" yaml:"with,omitempty"`
 }
 
 func getZeroOrSingleValueForMap[K comparable, V any](m map[K]V) (V, error) {
@@ -20,6 +20,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -233,6 +234,24 @@ plugins:
 `,
 	)
 
+	testReadWriteBufYAMLFileRoundTrip(
+		t,
+		// input
+		`version: v2
+plugins:
+  - plugin: [buf-plugin-foo, --bar]
+    remote_url: https://plugins.example.com
+`,
+		// expected output
+		`version: v2
+plugins:
+  - plugin:
+      - buf-plugin-foo
+      - --bar
+    remote_url: https://plugins.example.com
+`,
+	)
+
 	testReadWriteBufYAMLFileRoundTrip(
 		t,
 		// input
@@ -688,6 +707,179 @@ modules:
 	)
 }
 
+func TestBufYAMLFileReplace(t *testing.T) {
+	t.Parallel()
+	testReadWriteBufYAMLFileRoundTrip(
+		t,
+		// input
+		`version: v2
+deps:
+  - buf.build/acme/date
+  - buf.build/acme/extension
+replace:
+  - module: buf.build/acme/extension
+    with: buf.build/acme/extension:unpublished
+  - module: buf.build/acme/date
+    path: ./third_party/date/
+`,
+		// expected output
+		`version: v2
+deps:
+  - buf.build/acme/date
+  - buf.build/acme/extension
+replace:
+  - module: buf.build/acme/date
+    path: third_party/date
+  - module: buf.build/acme/extension
+    with: buf.build/acme/extension:unpublished
+`,
+	)
+	bufYAMLFile := testReadBufYAMLFile(
+		t,
+		`version: v2
+replace:
+  - module: buf.build/acme/date
+    with: buf.build/someone/date
+`,
+	)
+	replaceConfigs := bufYAMLFile.ReplaceConfigs()
+	require.Len(t, replaceConfigs, 1)
+	assert.Equal(t, "buf.build/acme/date", replaceConfigs[0].ModuleFullName().String())
+	assert.Empty(t, replaceConfigs[0].Path())
+	require.NotNil(t, replaceConfigs[0].ModuleRef())
+	assert.Equal(t, "buf.build/someone/date", replaceConfigs[0].ModuleRef().ModuleFullName().String())
+
+	testReadBufYAMLFileFail(
+		t,
+		`version: v1
+replace:
+  - module: buf.build/acme/date
+    path: third_party/date
+`,
+		`field replace not found`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+replace:
+  - module: buf.build/acme/date
+`,
+		`must set one of path or with`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+replace:
+  - module: buf.build/acme/date
+    path: third_party/date
+    with: buf.build/someone/date
+`,
+		`cannot set both path and with`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+replace:
+  - module: buf.build/acme/date
+    path: ../date
+`,
+		`invalid replace path`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+replace:
+  - module: buf.build/acme/date
+    path: third_party/date
+  - module: buf.build/acme/date
+    with: buf.build/someone/date
+`,
+		`replaced more than once`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+modules:
+  - path: proto
+    name: buf.build/acme/date
+replace:
+  - module: buf.build/acme/date
+    with: buf.build/someone/date
+`,
+		`is a module within the workspace`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+modules:
+  - path: proto
+replace:
+  - module: buf.build/acme/date
+    path: proto
+`,
+		`is the path of a module within the workspace`,
+	)
+}
+
+func TestRemoveBufYAMLFileDeps(t *testing.T) {
+	t.Parallel()
+	data := `# The weather module.
+version: v2
+name: buf.build/acme/weather
+deps:
+  # Units are used.
+  - buf.build/acme/units
+  - buf.build/acme/unused:v1.0.0
+lint:
+  use:
+    - STANDARD
+`
+	unusedModuleFullName, err := bufmodule.ParseModuleFullName("buf.build/acme/unused")
+	require.NoError(t, err)
+	unitsModuleFullName, err := bufmodule.ParseModuleFullName("buf.build/acme/units")
+	require.NoError(t, err)
+	otherModuleFullName, err := bufmodule.ParseModuleFullName("buf.build/acme/other")
+	require.NoError(t, err)
+
+	newData, changed, err := removeBufYAMLFileDeps([]byte(data), []bufmodule.ModuleFullName{otherModuleFullName})
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, data, string(newData))
+
+	newData, changed, err = removeBufYAMLFileDeps([]byte(data), []bufmodule.ModuleFullName{unusedModuleFullName})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(
+		t,
+		`# The weather module.
+version: v2
+name: buf.build/acme/weather
+deps:
+  # Units are used.
+  - buf.build/acme/units
+lint:
+  use:
+    - STANDARD
+`,
+		string(newData),
+	)
+
+	newData, changed, err = removeBufYAMLFileDeps([]byte(data), []bufmodule.ModuleFullName{unusedModuleFullName, unitsModuleFullName})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(
+		t,
+		`# The weather module.
+version: v2
+name: buf.build/acme/weather
+lint:
+  use:
+    - STANDARD
+`,
+		string(newData),
+	)
+}
+
 func testReadWriteBufYAMLFileRoundTrip(
 	t *testing.T,
 	inputBufYAMLFileData string,
@@ -0,0 +1,39 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+// DecodeError is the error returned if a configuration file cannot be decoded.
+type DecodeError struct {
+	// Path is the path of the file.
+	Path string
+	// Err is the error that occurred while decoding the file.
+	Err error
+}
+
+// Error implements the error interface.
+func (d *DecodeError) Error() string {
+	if d == nil {
+		return ""
+	}
+	return "decode " + d.Path + ": " + d.Err.Error()
+}
+
+// Unwrap returns the error that occurred while decoding the file.
+func (d *DecodeError) Unwrap() error {
+	if d == nil {
+		return nil
+	}
+	return d.Err
+}
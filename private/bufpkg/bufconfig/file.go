@@ -195,9 +195,9 @@ func newDecodeError(fileName string, err error) error {
 	if fileName == "" {
 		fileName = "config file"
 	}
-	// We intercept PathErrors in buffetch to deal with fixing of paths.
+	// We intercept DecodeErrors in buffetch to deal with fixing of paths.
 	// We return a cleaned, unnormalized path in the error for clarity with user's filesystem.
-	return &fs.PathError{Op: "decode", Path: filepath.Clean(normalpath.Unnormalize(fileName)), Err: err}
+	return &DecodeError{Path: filepath.Clean(normalpath.Unnormalize(fileName)), Err: err}
 }
 
 func newEncodeError(fileName string, err error) error {
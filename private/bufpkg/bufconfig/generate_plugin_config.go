@@ -100,6 +100,11 @@ type GeneratePluginConfig interface {
 	//
 	// This is not empty only when the plugin is local.
 	Path() []string
+	// Persistent returns whether to keep the plugin process alive across multiple
+	// CodeGeneratorRequests, feeding the requests to the plugin over a single pipe.
+	//
+	// This is true only when the plugin is local. This is always false in v1.
+	Persistent() bool
 	// ProtocPath returns a path to protoc, including any extra arguments.
 	//
 	// This is not empty only when the plugin is protoc-builtin.
@@ -229,6 +234,7 @@ type generatePluginConfig struct {
 	includeWKT               bool
 	strategy                 *GenerateStrategy
 	path                     []string
+	persistent               bool
 	protocPath               []string
 	remoteHost               string
 	revision                 int
@@ -412,6 +418,9 @@ func newGeneratePluginConfigFromExternalV2(
 		if externalConfig.ProtocPath != nil {
 			return nil, fmt.Errorf("cannot specify protoc_path for remote plugin %s", *externalConfig.Remote)
 		}
+		if externalConfig.Persistent {
+			return nil, fmt.Errorf("cannot specify persistent for remote plugin %s", *externalConfig.Remote)
+		}
 		return newRemoteGeneratePluginConfig(
 			*externalConfig.Remote,
 			externalConfig.Out,
@@ -432,7 +441,7 @@ func newGeneratePluginConfigFromExternalV2(
 		if externalConfig.ProtocPath != nil {
 			return nil, fmt.Errorf("cannot specify protoc_path for local plugin %s", localPluginName)
 		}
-		return newLocalGeneratePluginConfig(
+		generatePluginConfig, err := newLocalGeneratePluginConfig(
 			strings.Join(path, " "),
 			externalConfig.Out,
 			opt,
@@ -441,6 +450,11 @@ func newGeneratePluginConfigFromExternalV2(
 			parsedStrategy,
 			path,
 		)
+		if err != nil {
+			return nil, err
+		}
+		generatePluginConfig.persistent = externalConfig.Persistent
+		return generatePluginConfig, nil
 	case externalConfig.ProtocBuiltin != nil:
 		protocPath, err := encoding.InterfaceSliceOrStringToStringSlice(externalConfig.ProtocPath)
 		if err != nil {
@@ -449,6 +463,9 @@ func newGeneratePluginConfigFromExternalV2(
 		if externalConfig.Revision != nil {
 			return nil, fmt.Errorf("cannot specify revision for protoc built-in plugin %s", *externalConfig.ProtocBuiltin)
 		}
+		if externalConfig.Persistent {
+			return nil, fmt.Errorf("cannot specify persistent for protoc built-in plugin %s", *externalConfig.ProtocBuiltin)
+		}
 		return newProtocBuiltinGeneratePluginConfig(
 			*externalConfig.ProtocBuiltin,
 			externalConfig.Out,
@@ -601,6 +618,10 @@ func (p *generatePluginConfig) Path() []string {
 	return p.path
 }
 
+func (p *generatePluginConfig) Persistent() bool {
+	return p.persistent
+}
+
 func (p *generatePluginConfig) ProtocPath() []string {
 	return p.protocPath
 }
@@ -655,6 +676,7 @@ func newExternalGeneratePluginConfigV2FromPluginConfig(
 		case len(path) > 1:
 			externalPluginConfigV2.Local = path
 		}
+		externalPluginConfigV2.Persistent = generatePluginConfig.Persistent()
 	case GeneratePluginConfigTypeProtocBuiltin:
 		externalPluginConfigV2.ProtocBuiltin = toPointer(generatePluginConfig.Name())
 		if protocPath := generatePluginConfig.ProtocPath(); len(protocPath) > 0 {
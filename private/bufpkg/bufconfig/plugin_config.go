@@ -17,6 +17,7 @@ package bufconfig
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -29,6 +30,9 @@ const (
 	PluginConfigTypeLocal PluginConfigType = iota + 1
 	// PluginConfigTypeLocalWasm is the local Wasm plugin config type.
 	PluginConfigTypeLocalWasm
+	// PluginConfigTypeRemote is the plugin config type for plugins run on a remote
+	// execution service.
+	PluginConfigTypeRemote
 )
 
 // PluginConfigType is a generate plugin configuration type.
@@ -47,8 +51,13 @@ type PluginConfig interface {
 	Options() map[string]any
 	// Path returns the path, including arguments, to invoke the binary plugin.
 	//
-	// This is not empty only when the plugin is local.
+	// This is not empty only when the plugin is local or remote. For remote plugins,
+	// this is the program and arguments to invoke on the remote execution service.
 	Path() []string
+	// RemoteURL returns the base URL of the remote execution service to run the plugin on.
+	//
+	// This is not empty only when the plugin is remote.
+	RemoteURL() string
 
 	isPluginConfig()
 }
@@ -83,6 +92,25 @@ func NewLocalWasmPluginConfig(
 	)
 }
 
+// NewRemotePluginConfig returns a new PluginConfig for a plugin run on the remote
+// execution service at the given base URL.
+//
+// The first path argument is the program to run on the remote execution service. The
+// remaining path arguments are the arguments to the program.
+func NewRemotePluginConfig(
+	name string,
+	options map[string]any,
+	path []string,
+	remoteURL string,
+) (PluginConfig, error) {
+	return newRemotePluginConfig(
+		name,
+		options,
+		path,
+		remoteURL,
+	)
+}
+
 // *** PRIVATE ***
 
 type pluginConfig struct {
@@ -90,6 +118,7 @@ type pluginConfig struct {
 	name             string
 	options          map[string]any
 	path             []string
+	remoteURL        string
 }
 
 func newPluginConfigForExternalV2(
@@ -115,6 +144,14 @@ func newPluginConfigForExternalV2(
 	if len(path) == 0 {
 		return nil, errors.New("must specify a path to the plugin")
 	}
+	if externalConfig.RemoteURL != "" {
+		return newRemotePluginConfig(
+			strings.Join(path, " "),
+			options,
+			path,
+			externalConfig.RemoteURL,
+		)
+	}
 	// Wasm plugins are suffixed with .wasm. Otherwise, it's a binary.
 	if filepath.Ext(path[0]) == ".wasm" {
 		return newLocalWasmPluginConfig(
@@ -165,6 +202,31 @@ func newLocalWasmPluginConfig(
 	}, nil
 }
 
+func newRemotePluginConfig(
+	name string,
+	options map[string]any,
+	path []string,
+	remoteURL string,
+) (*pluginConfig, error) {
+	if len(path) == 0 {
+		return nil, errors.New("must specify a path to the plugin")
+	}
+	parsedURL, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote_url %q: %w", remoteURL, err)
+	}
+	if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+		return nil, fmt.Errorf("invalid remote_url %q: must be an http or https URL", remoteURL)
+	}
+	return &pluginConfig{
+		pluginConfigType: PluginConfigTypeRemote,
+		name:             name,
+		options:          options,
+		path:             path,
+		remoteURL:        remoteURL,
+	}, nil
+}
+
 func (p *pluginConfig) Type() PluginConfigType {
 	return p.pluginConfigType
 }
@@ -181,6 +243,10 @@ func (p *pluginConfig) Path() []string {
 	return p.path
 }
 
+func (p *pluginConfig) RemoteURL() string {
+	return p.remoteURL
+}
+
 func (p *pluginConfig) isPluginConfig() {}
 
 func newExternalV2ForPluginConfig(
@@ -194,7 +260,7 @@ func newExternalV2ForPluginConfig(
 		Options: pluginConfig.Options(),
 	}
 	switch pluginConfig.Type() {
-	case PluginConfigTypeLocal:
+	case PluginConfigTypeLocal, PluginConfigTypeRemote:
 		path := pluginConfig.Path()
 		switch {
 		case len(path) == 1:
@@ -202,6 +268,7 @@ func newExternalV2ForPluginConfig(
 		case len(path) > 1:
 			externalBufYAMLFilePluginV2.Plugin = path
 		}
+		externalBufYAMLFilePluginV2.RemoteURL = pluginConfig.RemoteURL()
 	}
 	return externalBufYAMLFilePluginV2, nil
 }
@@ -0,0 +1,148 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+)
+
+// ReplaceConfig is a replace directive within a v2 buf.yaml.
+//
+// A ReplaceConfig replaces a dependency with either a local directory or another
+// module reference, typically to develop against unpublished changes to a dependency.
+// This mirrors replace directives in go.mod files.
+//
+// Replacements apply to both direct and transitive dependencies. Replacements only apply
+// to the Workspace that declares them - replacements declared by dependencies are ignored.
+type ReplaceConfig interface {
+	// ModuleFullName returns the name of the dependency being replaced.
+	//
+	// Always present.
+	ModuleFullName() bufmodule.ModuleFullName
+	// Path returns the path of the directory to use in place of the dependency.
+	//
+	// The path is normalized and relative to the directory of the buf.yaml, and must be
+	// contained within that directory. The directory is added to the Workspace as a
+	// non-target local Module with ModuleFullName.
+	//
+	// Empty if ModuleRef is set.
+	Path() string
+	// ModuleRef returns the reference of the module to use in place of the dependency.
+	//
+	// This may be a different module, such as a fork, or a different reference of the same
+	// module, such as a label with unpublished changes.
+	//
+	// Nil if Path is set.
+	ModuleRef() bufmodule.ModuleRef
+
+	isReplaceConfig()
+}
+
+// NewReplaceConfigForPath returns a new ReplaceConfig that replaces the dependency with
+// the local directory at the path.
+func NewReplaceConfigForPath(moduleFullName bufmodule.ModuleFullName, path string) (ReplaceConfig, error) {
+	return newReplaceConfig(moduleFullName, path, nil)
+}
+
+// NewReplaceConfigForModuleRef returns a new ReplaceConfig that replaces the dependency with
+// the given ModuleRef.
+func NewReplaceConfigForModuleRef(moduleFullName bufmodule.ModuleFullName, moduleRef bufmodule.ModuleRef) (ReplaceConfig, error) {
+	return newReplaceConfig(moduleFullName, "", moduleRef)
+}
+
+// *** PRIVATE ***
+
+type replaceConfig struct {
+	moduleFullName bufmodule.ModuleFullName
+	path           string
+	moduleRef      bufmodule.ModuleRef
+}
+
+func newReplaceConfig(
+	moduleFullName bufmodule.ModuleFullName,
+	path string,
+	moduleRef bufmodule.ModuleRef,
+) (*replaceConfig, error) {
+	if moduleFullName == nil {
+		return nil, errors.New("replace module must be set")
+	}
+	switch {
+	case path == "" && moduleRef == nil:
+		return nil, fmt.Errorf("replace for module %s must set one of path or with", moduleFullName.String())
+	case path != "" && moduleRef != nil:
+		return nil, fmt.Errorf("replace for module %s cannot set both path and with", moduleFullName.String())
+	case path != "":
+		normalizedPath, err := normalpath.NormalizeAndValidate(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replace path for module %s: %w", moduleFullName.String(), err)
+		}
+		if normalizedPath == "." {
+			return nil, fmt.Errorf("invalid replace path for module %s: cannot replace a dependency with the directory of the buf.yaml", moduleFullName.String())
+		}
+		path = normalizedPath
+	}
+	return &replaceConfig{
+		moduleFullName: moduleFullName,
+		path:           path,
+		moduleRef:      moduleRef,
+	}, nil
+}
+
+func newReplaceConfigForExternalV2(externalReplace externalBufYAMLFileReplaceV2) (*replaceConfig, error) {
+	if externalReplace.Module == "" {
+		return nil, errors.New("replace module must be set")
+	}
+	moduleFullName, err := bufmodule.ParseModuleFullName(externalReplace.Module)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replace module: %w", err)
+	}
+	var moduleRef bufmodule.ModuleRef
+	if externalReplace.With != "" {
+		moduleRef, err = bufmodule.ParseModuleRef(externalReplace.With)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replace with for module %s: %w", moduleFullName.String(), err)
+		}
+	}
+	return newReplaceConfig(moduleFullName, externalReplace.Path, moduleRef)
+}
+
+func newExternalV2ForReplaceConfig(replaceConfig ReplaceConfig) externalBufYAMLFileReplaceV2 {
+	externalReplace := externalBufYAMLFileReplaceV2{
+		Module: replaceConfig.ModuleFullName().String(),
+		Path:   replaceConfig.Path(),
+	}
+	if moduleRef := replaceConfig.ModuleRef(); moduleRef != nil {
+		externalReplace.With = moduleRef.String()
+	}
+	return externalReplace
+}
+
+func (r *replaceConfig) ModuleFullName() bufmodule.ModuleFullName {
+	return r.moduleFullName
+}
+
+func (r *replaceConfig) Path() string {
+	return r.path
+}
+
+func (r *replaceConfig) ModuleRef() bufmodule.ModuleRef {
+	return r.moduleRef
+}
+
+func (*replaceConfig) isReplaceConfig() {}
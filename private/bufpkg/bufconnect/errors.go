@@ -14,7 +14,11 @@
 
 package bufconnect
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // AuthError wraps the error returned in the auth provider to add additional context.
 type AuthError struct {
@@ -89,3 +93,57 @@ func (e *AugmentedConnectError) Procedure() string {
 func (e *AugmentedConnectError) Addr() string {
 	return e.addr
 }
+
+// RetryError is returned when all attempts of a retried RPC fail.
+//
+// It wraps the errors of all attempts, and unwraps to the error of the last attempt.
+type RetryError struct {
+	procedure   string
+	addr        string
+	causes      []error
+	rateLimited bool
+}
+
+// Error implements the error interface and returns the error message.
+func (e *RetryError) Error() string {
+	if len(e.causes) == 0 {
+		return "unknown error"
+	}
+	if e.rateLimited {
+		return fmt.Sprintf(
+			"rate limited by remote %s, retried %d times: %v",
+			e.addr,
+			len(e.causes)-1,
+			e.causes[len(e.causes)-1],
+		)
+	}
+	attemptMessages := make([]string, len(e.causes))
+	for i, cause := range e.causes {
+		attemptMessages[i] = fmt.Sprintf("attempt %d: %v", i+1, cause)
+	}
+	return fmt.Sprintf(
+		"%v (%s failed after %d attempts: %s)",
+		e.causes[len(e.causes)-1],
+		e.procedure,
+		len(e.causes),
+		strings.Join(attemptMessages, "; "),
+	)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	if len(e.causes) == 0 {
+		return nil
+	}
+	return e.causes[len(e.causes)-1]
+}
+
+// Attempts returns the number of attempts made.
+func (e *RetryError) Attempts() int {
+	return len(e.causes)
+}
+
+// RateLimited returns true if the last attempt was rate limited by the remote.
+func (e *RetryError) RateLimited() bool {
+	return e.rateLimited
+}
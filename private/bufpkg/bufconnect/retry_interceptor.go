@@ -0,0 +1,369 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+const (
+	defaultRetryMaxAttempts           = 3
+	defaultRetryInitialBackoff        = 200 * time.Millisecond
+	defaultRetryMaxBackoff            = 5 * time.Second
+	defaultCircuitBreakerThreshold    = 5
+	defaultCircuitBreakerOpenDuration = 30 * time.Second
+	// The maximum Retry-After we honor. If the remote asks us to wait longer, we give up.
+	maxRetryAfter = time.Minute
+)
+
+// NewRetryInterceptor returns a new Connect Interceptor that retries unary RPCs that fail
+// with a transient error.
+//
+// Only RPCs that are marked as idempotent or as having no side effects are retried, and only
+// if they fail with CodeUnavailable, CodeAborted, or CodeResourceExhausted. Retries are done with
+// exponential backoff and full jitter. If all attempts fail, a *RetryError is returned that wraps
+// the error of every attempt.
+//
+// If the remote rate limits an RPC, that is the RPC fails with CodeResourceExhausted or the
+// error has a Retry-After header, the retry waits for the duration given by the Retry-After header
+// plus jitter. RPCs with a Retry-After header are retried even if they are not idempotent, as
+// the remote did not process them. All other RPCs to the same address are queued until the
+// Retry-After duration has passed, so that concurrent RPCs do not all hit the rate limit again.
+//
+// The interceptor also acts as a circuit breaker per remote address. After a number of
+// consecutive transient failures, all RPCs to the address fail fast with CodeUnavailable
+// until the circuit breaker closes again.
+func NewRetryInterceptor(logger *slog.Logger, options ...RetryInterceptorOption) connect.UnaryInterceptorFunc {
+	return newRetryInterceptor(logger, options...).wrapUnary
+}
+
+// RetryInterceptorOption is an option for a new retry Interceptor.
+type RetryInterceptorOption func(*retryInterceptor)
+
+// RetryInterceptorWithMaxAttempts returns a new RetryInterceptorOption that sets the maximum
+// number of attempts for an RPC, including the first attempt.
+//
+// The default is 3. A value of 1 disables retries. A value of <1 has no meaning.
+func RetryInterceptorWithMaxAttempts(maxAttempts int) RetryInterceptorOption {
+	return func(retryInterceptor *retryInterceptor) {
+		if maxAttempts > 0 {
+			retryInterceptor.maxAttempts = maxAttempts
+		}
+	}
+}
+
+// RetryInterceptorWithBackoff returns a new RetryInterceptorOption that sets the initial and
+// maximum backoff between attempts.
+//
+// The backoff doubles after every attempt, up to the maximum. The actual delay is chosen
+// uniformly at random between zero and the backoff.
+//
+// The default is an initial backoff of 200ms and a maximum backoff of 5s.
+func RetryInterceptorWithBackoff(initialBackoff time.Duration, maxBackoff time.Duration) RetryInterceptorOption {
+	return func(retryInterceptor *retryInterceptor) {
+		retryInterceptor.initialBackoff = initialBackoff
+		retryInterceptor.maxBackoff = maxBackoff
+	}
+}
+
+// RetryInterceptorWithCircuitBreaker returns a new RetryInterceptorOption that opens the
+// circuit breaker for a remote address for openDuration after threshold consecutive transient
+// failures.
+//
+// The default is to open the circuit breaker for 30s after 5 consecutive transient failures.
+// A threshold of <1 disables the circuit breaker.
+func RetryInterceptorWithCircuitBreaker(threshold int, openDuration time.Duration) RetryInterceptorOption {
+	return func(retryInterceptor *retryInterceptor) {
+		retryInterceptor.circuitBreakerThreshold = threshold
+		retryInterceptor.circuitBreakerOpenDuration = openDuration
+	}
+}
+
+// *** PRIVATE ***
+
+type retryInterceptor struct {
+	logger                     *slog.Logger
+	maxAttempts                int
+	initialBackoff             time.Duration
+	maxBackoff                 time.Duration
+	circuitBreakerThreshold    int
+	circuitBreakerOpenDuration time.Duration
+	// Replaced in tests.
+	sleep func(context.Context, time.Duration) error
+
+	addrToCircuitBreaker map[string]*circuitBreaker
+	lock                 sync.Mutex
+}
+
+func newRetryInterceptor(logger *slog.Logger, options ...RetryInterceptorOption) *retryInterceptor {
+	retryInterceptor := &retryInterceptor{
+		logger:                     logger,
+		maxAttempts:                defaultRetryMaxAttempts,
+		initialBackoff:             defaultRetryInitialBackoff,
+		maxBackoff:                 defaultRetryMaxBackoff,
+		circuitBreakerThreshold:    defaultCircuitBreakerThreshold,
+		circuitBreakerOpenDuration: defaultCircuitBreakerOpenDuration,
+		sleep:                      sleepContext,
+		addrToCircuitBreaker:       make(map[string]*circuitBreaker),
+	}
+	for _, option := range options {
+		option(retryInterceptor)
+	}
+	return retryInterceptor
+}
+
+func (r *retryInterceptor) wrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		circuitBreaker := r.getCircuitBreaker(request.Peer().Addr)
+		idempotent := isRetryableIdempotencyLevel(request.Spec().IdempotencyLevel)
+		var causes []error
+		for attempt := 0; attempt < r.maxAttempts; attempt++ {
+			if attempt > 0 {
+				backoff := r.getBackoff(attempt)
+				if retryAfter, ok := getRetryAfter(causes[len(causes)-1], time.Now()); ok {
+					if retryAfter > maxRetryAfter {
+						break
+					}
+					// The backoff is the jitter, so that queued RPCs do not all retry at once.
+					backoff += retryAfter
+				}
+				r.logger.DebugContext(
+					ctx,
+					"retrying request",
+					slog.String("procedure", request.Spec().Procedure),
+					slog.Int("attempt", attempt+1),
+					slog.Duration("backoff", backoff),
+				)
+				if err := r.sleep(ctx, backoff); err != nil {
+					causes = append(causes, err)
+					break
+				}
+			}
+			if rateLimitDelay := circuitBreaker.getRateLimitDelay(time.Now()); rateLimitDelay > 0 {
+				// Another RPC to the same address was rate limited, wait until the remote is ready.
+				if err := r.sleep(ctx, rateLimitDelay+r.getBackoff(1)); err != nil {
+					causes = append(causes, err)
+					break
+				}
+			}
+			if err := circuitBreaker.allow(time.Now()); err != nil {
+				causes = append(causes, err)
+				break
+			}
+			response, err := next(ctx, request)
+			if err == nil {
+				circuitBreaker.recordSuccess()
+				return response, nil
+			}
+			causes = append(causes, err)
+			if retryAfter, ok := getRetryAfter(err, time.Now()); ok {
+				circuitBreaker.recordRateLimited(time.Now().Add(retryAfter))
+			}
+			switch {
+			case isRateLimitedError(err):
+				// The remote is reachable, it is just busy.
+				circuitBreaker.recordSuccess()
+			case isRetryableCode(connect.CodeOf(err)):
+				circuitBreaker.recordFailure(time.Now())
+			default:
+				// Not a transient failure, the remote is reachable.
+				circuitBreaker.recordSuccess()
+			}
+			if !isRetryableError(err, idempotent) {
+				break
+			}
+		}
+		if len(causes) == 1 {
+			return nil, causes[0]
+		}
+		return nil, &RetryError{
+			procedure:   request.Spec().Procedure,
+			addr:        request.Peer().Addr,
+			causes:      causes,
+			rateLimited: isRateLimitedError(causes[len(causes)-1]),
+		}
+	}
+}
+
+func (r *retryInterceptor) getCircuitBreaker(addr string) *circuitBreaker {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	circuitBreaker, ok := r.addrToCircuitBreaker[addr]
+	if !ok {
+		circuitBreaker = newCircuitBreaker(addr, r.circuitBreakerThreshold, r.circuitBreakerOpenDuration)
+		r.addrToCircuitBreaker[addr] = circuitBreaker
+	}
+	return circuitBreaker
+}
+
+// getBackoff returns the delay before the given attempt, where attempt 0 is the first attempt.
+func (r *retryInterceptor) getBackoff(attempt int) time.Duration {
+	backoff := r.initialBackoff
+	for i := 1; i < attempt && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// Full jitter.
+	return rand.N(backoff + 1)
+}
+
+type circuitBreaker struct {
+	addr         string
+	threshold    int
+	openDuration time.Duration
+
+	consecutiveFailures int
+	openUntil           time.Time
+	rateLimitedUntil    time.Time
+	lock                sync.Mutex
+}
+
+func newCircuitBreaker(addr string, threshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		addr:         addr,
+		threshold:    threshold,
+		openDuration: openDuration,
+	}
+}
+
+// allow returns an error if the circuit breaker is open.
+func (c *circuitBreaker) allow(now time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.threshold < 1 || !now.Before(c.openUntil) {
+		return nil
+	}
+	return connect.NewError(
+		connect.CodeUnavailable,
+		fmt.Errorf("%s failed %d consecutive requests, not sending requests for %v", c.addr, c.consecutiveFailures, c.openUntil.Sub(now).Round(time.Second)),
+	)
+}
+
+func (c *circuitBreaker) recordSuccess() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.consecutiveFailures = 0
+	c.openUntil = time.Time{}
+}
+
+func (c *circuitBreaker) recordFailure(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.consecutiveFailures++
+	if c.threshold > 0 && c.consecutiveFailures >= c.threshold {
+		// If the circuit breaker was half-open, that is the open duration expired and a
+		// request was allowed through, this re-opens it.
+		c.openUntil = now.Add(c.openDuration)
+	}
+}
+
+// recordRateLimited records that the remote asked us to not send requests until the given time.
+func (c *circuitBreaker) recordRateLimited(until time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if until.After(c.rateLimitedUntil) {
+		c.rateLimitedUntil = until
+	}
+}
+
+// getRateLimitDelay returns how long to wait before sending a request, if the remote
+// rate limited a previous request.
+func (c *circuitBreaker) getRateLimitDelay(now time.Time) time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if delay := c.rateLimitedUntil.Sub(now); delay > 0 && delay <= maxRetryAfter {
+		return delay
+	}
+	return 0
+}
+
+func isRetryableIdempotencyLevel(idempotencyLevel connect.IdempotencyLevel) bool {
+	return idempotencyLevel == connect.IdempotencyNoSideEffects || idempotencyLevel == connect.IdempotencyIdempotent
+}
+
+func isRetryableCode(code connect.Code) bool {
+	return code == connect.CodeUnavailable || code == connect.CodeAborted || code == connect.CodeResourceExhausted
+}
+
+// isRetryableError returns true if the RPC that failed with the error can be retried.
+func isRetryableError(err error, idempotent bool) bool {
+	if idempotent {
+		return isRetryableCode(connect.CodeOf(err))
+	}
+	// The remote asked us to retry, so it did not process the RPC.
+	_, ok := getRetryAfter(err, time.Now())
+	return ok
+}
+
+// isRateLimitedError returns true if the remote rate limited the RPC.
+func isRateLimitedError(err error) bool {
+	if connect.CodeOf(err) == connect.CodeResourceExhausted {
+		return true
+	}
+	_, ok := getRetryAfter(err, time.Now())
+	return ok
+}
+
+// getRetryAfter returns the delay requested by the Retry-After header of the error, if any.
+//
+// The header is either a number of seconds or an HTTP date.
+func getRetryAfter(err error, now time.Time) (time.Duration, bool) {
+	connectErr := &connect.Error{}
+	if !errors.As(err, &connectErr) {
+		return 0, false
+	}
+	value := strings.TrimSpace(connectErr.Meta().Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	retryTime, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(retryTime.Sub(now), 0), true
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
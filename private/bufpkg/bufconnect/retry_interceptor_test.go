@@ -0,0 +1,272 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const testRetryProcedure = "/test.v1.TestService/Test"
+
+func TestRetryInterceptorRetriesTransientErrors(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			if calls.Add(1) < 3 {
+				return connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+			}
+			return nil
+		},
+		connect.IdempotencyNoSideEffects,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), calls.Load())
+}
+
+func TestRetryInterceptorReturnsRetryError(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+		},
+		connect.IdempotencyNoSideEffects,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Error(t, err)
+	require.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	retryError := &RetryError{}
+	require.True(t, errors.As(err, &retryError))
+	require.Equal(t, 3, retryError.Attempts())
+	require.Equal(t, int64(3), calls.Load())
+}
+
+func TestRetryInterceptorDoesNotRetryNonIdempotent(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+		},
+		connect.IdempotencyUnknown,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	require.Equal(t, int64(1), calls.Load())
+}
+
+func TestRetryInterceptorDoesNotRetryPermanentErrors(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return connect.NewError(connect.CodeNotFound, errors.New("not found"))
+		},
+		connect.IdempotencyNoSideEffects,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+	require.Equal(t, int64(1), calls.Load())
+}
+
+func TestRetryInterceptorCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+		},
+		connect.IdempotencyNoSideEffects,
+		RetryInterceptorWithMaxAttempts(1),
+		RetryInterceptorWithCircuitBreaker(2, time.Hour),
+	)
+	for i := 0; i < 2; i++ {
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	}
+	require.Equal(t, int64(2), calls.Load())
+	// The circuit breaker is now open, the request is never sent.
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	require.ErrorContains(t, err, "consecutive requests")
+	require.Equal(t, int64(2), calls.Load())
+}
+
+func TestRetryInterceptorRetriesRateLimitedWithRetryAfter(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			if calls.Add(1) < 2 {
+				return newTestRateLimitedError("1")
+			}
+			return nil
+		},
+		// Retried even though it is not idempotent, as the remote asked us to retry.
+		connect.IdempotencyUnknown,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), calls.Load())
+}
+
+func TestRetryInterceptorReturnsRateLimitedRetryError(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return newTestRateLimitedError("")
+		},
+		connect.IdempotencyNoSideEffects,
+		// Rate limiting does not open the circuit breaker.
+		RetryInterceptorWithCircuitBreaker(1, time.Hour),
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+	retryError := &RetryError{}
+	require.True(t, errors.As(err, &retryError))
+	require.True(t, retryError.RateLimited())
+	require.ErrorContains(t, err, "rate limited by remote")
+	require.ErrorContains(t, err, "retried 2 times")
+	require.Equal(t, int64(3), calls.Load())
+}
+
+func TestRetryInterceptorDoesNotRetryLongRetryAfter(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int64
+	client := newTestRetryClient(
+		t,
+		func() error {
+			calls.Add(1)
+			return newTestRateLimitedError("3600")
+		},
+		connect.IdempotencyNoSideEffects,
+	)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+	require.Equal(t, int64(1), calls.Load())
+}
+
+func TestGetRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testGetRetryAfter(t, now, "", 0, false)
+	testGetRetryAfter(t, now, "foo", 0, false)
+	testGetRetryAfter(t, now, "-1", 0, false)
+	testGetRetryAfter(t, now, "0", 0, true)
+	testGetRetryAfter(t, now, "30", 30*time.Second, true)
+	testGetRetryAfter(t, now, now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true)
+	testGetRetryAfter(t, now, now.Add(-time.Minute).Format(http.TimeFormat), 0, true)
+	_, ok := getRetryAfter(errors.New("foo"), now)
+	require.False(t, ok)
+}
+
+func TestRetryInterceptorBackoff(t *testing.T) {
+	t.Parallel()
+	retryInterceptor := newRetryInterceptor(
+		slogext.NopLogger,
+		RetryInterceptorWithBackoff(100*time.Millisecond, 300*time.Millisecond),
+	)
+	for attempt, maxBackoff := range []time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond,
+		4: 300 * time.Millisecond,
+	} {
+		if attempt == 0 {
+			continue
+		}
+		for i := 0; i < 10; i++ {
+			backoff := retryInterceptor.getBackoff(attempt)
+			require.GreaterOrEqual(t, backoff, time.Duration(0))
+			require.LessOrEqual(t, backoff, maxBackoff)
+		}
+	}
+}
+
+func testGetRetryAfter(t *testing.T, now time.Time, value string, expected time.Duration, expectedOK bool) {
+	err := connect.NewError(connect.CodeResourceExhausted, errors.New("rate limited"))
+	if value != "" {
+		err.Meta().Set("Retry-After", value)
+	}
+	retryAfter, ok := getRetryAfter(err, now)
+	require.Equal(t, expectedOK, ok, value)
+	require.Equal(t, expected, retryAfter, value)
+}
+
+func newTestRateLimitedError(retryAfter string) error {
+	err := connect.NewError(connect.CodeResourceExhausted, errors.New("rate limited"))
+	if retryAfter != "" {
+		err.Meta().Set("Retry-After", retryAfter)
+	}
+	return err
+}
+
+func newTestRetryClient(
+	t *testing.T,
+	handle func() error,
+	idempotencyLevel connect.IdempotencyLevel,
+	options ...RetryInterceptorOption,
+) *connect.Client[emptypb.Empty, emptypb.Empty] {
+	mux := http.NewServeMux()
+	mux.Handle(
+		testRetryProcedure,
+		connect.NewUnaryHandler(
+			testRetryProcedure,
+			func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				if err := handle(); err != nil {
+					return nil, err
+				}
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			},
+		),
+	)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	retryInterceptor := newRetryInterceptor(slogext.NopLogger, options...)
+	// Do not actually sleep in tests.
+	retryInterceptor.sleep = func(context.Context, time.Duration) error { return nil }
+	return connect.NewClient[emptypb.Empty, emptypb.Empty](
+		server.Client(),
+		server.URL+testRetryProcedure,
+		connect.WithIdempotency(idempotencyLevel),
+		connect.WithInterceptors(connect.UnaryInterceptorFunc(retryInterceptor.wrapUnary)),
+	)
+}
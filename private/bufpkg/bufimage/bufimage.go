@@ -363,12 +363,16 @@ func NewImageForProto(protoImage *imagev1.Image, options ...NewImageForProtoOpti
 	}
 	// TODO FUTURE: right now, NewResolver sets AllowUnresolvable to true all the time
 	// we want to make this into a check, and we verify if we need this for the individual command
-	resolver := protoencoding.NewLazyResolver(protoImage.File...)
 	if !newImageOptions.noReparse {
-		if err := reparseImageProto(protoImage, resolver, newImageOptions.computeUnusedImports); err != nil {
+		// The resolver of the image must not be used to reparse, as it would be constructed
+		// while the options of the files are partway through being reparsed, and so would
+		// not have all of their options.
+		reparseResolver := protoencoding.NewLazyResolver(protoImage.File...)
+		if err := reparseImageProto(protoImage, reparseResolver, newImageOptions.computeUnusedImports); err != nil {
 			return nil, err
 		}
 	}
+	resolver := protoencoding.NewLazyResolver(protoImage.File...)
 	if err := validateProtoImage(protoImage); err != nil {
 		return nil, err
 	}
@@ -29,6 +29,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/buf/private/pkg/tracing"
 	"github.com/bufbuild/protocompile"
 	"github.com/bufbuild/protocompile/linker"
 	"github.com/bufbuild/protocompile/parser"
@@ -45,8 +46,10 @@ func buildImage(
 	moduleReadBucket bufmodule.ModuleReadBucket,
 	excludeSourceCodeInfo bool,
 	noParallelism bool,
-) (Image, error) {
+) (_ Image, retErr error) {
 	defer slogext.DebugProfile(logger)()
+	ctx, span := tracing.Start(ctx, "bufimage.build")
+	defer func() { tracing.End(span, retErr) }()
 
 	if !moduleReadBucket.ShouldBeSelfContained() {
 		return nil, syserror.New("passed a ModuleReadBucket to BuildImage that was not expected to be self-contained")
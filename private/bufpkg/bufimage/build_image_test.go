@@ -33,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/prototesting"
 	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/testingext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -211,6 +212,15 @@ func TestGoogleapis(t *testing.T) {
 	assert.NoError(t, err)
 	testImageWithExcludedFilePaths(t, imageWithExcludes, excludePaths)
 
+	imageWithGlobExcludes, err := bufimage.ImageWithOnlyPaths(image, []string{"google/type"}, []string{"**/date*.proto"})
+	assert.NoError(t, err)
+	for _, imageFile := range imageWithGlobExcludes.Files() {
+		if !imageFile.IsImport() {
+			assert.True(t, normalpath.EqualsOrContainsPath("google/type", imageFile.Path(), normalpath.Relative), imageFile.Path())
+			assert.False(t, storage.MatchGlob("**/date*.proto").MatchPath(imageFile.Path()), imageFile.Path())
+		}
+	}
+
 	assert.Equal(t, buftesting.NumGoogleapisFilesWithImports, len(image.Files()))
 	// basic check to make sure there is no error at this scale
 	_, err = bufprotosource.NewFiles(context.Background(), image.Files(), image.Resolver())
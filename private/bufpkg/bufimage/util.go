@@ -23,6 +23,7 @@ import (
 	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 	"github.com/bufbuild/protoplugin/protopluginutil"
 	"github.com/google/uuid"
@@ -150,6 +151,11 @@ func imageWithOnlyPaths(image Image, fileOrDirPaths []string, excludeFileOrDirPa
 			imageFilePath,
 			normalpath.Relative,
 		)
+		for excludeFileOrDirPath := range excludeFileOrDirPathMap {
+			if storage.IsGlob(excludeFileOrDirPath) && storage.MatchGlobEqualOrContained(excludeFileOrDirPath).MatchPath(imageFilePath) {
+				fileMatchingExcludePathMap[excludeFileOrDirPath] = struct{}{}
+			}
+		}
 		if len(fileMatchingExcludePathMap) > 0 {
 			for key := range fileMatchingExcludePathMap {
 				matchingPotentialExcludePathMap[key] = struct{}{}
@@ -206,6 +212,13 @@ func shouldExcludeFile(
 	fileMatchingPathMap map[string]struct{},
 	fileMatchingExcludePathMap map[string]struct{},
 ) bool {
+	for fileMatchingExcludePath := range fileMatchingExcludePathMap {
+		// A glob that matches the file always excludes it, as it cannot be compared
+		// to the target paths.
+		if storage.IsGlob(fileMatchingExcludePath) {
+			return true
+		}
+	}
 	for fileMatchingPath := range fileMatchingPathMap {
 		for fileMatchingExcludePath := range fileMatchingExcludePathMap {
 			if normalpath.EqualsOrContainsPath(fileMatchingPath, fileMatchingExcludePath, normalpath.Relative) {
@@ -280,7 +293,8 @@ func checkExcludePathsExistInImage(image Image, excludeFileOrDirPaths []string)
 	for _, excludeFileOrDirPath := range excludeFileOrDirPaths {
 		var foundPath bool
 		for _, imageFile := range image.Files() {
-			if normalpath.EqualsOrContainsPath(excludeFileOrDirPath, imageFile.Path(), normalpath.Relative) {
+			if normalpath.EqualsOrContainsPath(excludeFileOrDirPath, imageFile.Path(), normalpath.Relative) ||
+				(storage.IsGlob(excludeFileOrDirPath) && storage.MatchGlobEqualOrContained(excludeFileOrDirPath).MatchPath(imageFile.Path())) {
 				foundPath = true
 				break
 			}
@@ -658,6 +658,46 @@ func TestProtoFileTargetPath(t *testing.T) {
 	)
 }
 
+func TestTargetExcludeGlob(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bucket := testNewBucketForPathToData(
+		t,
+		map[string][]byte{
+			"a/1.proto": []byte(
+				`syntax = proto3; package a;`,
+			),
+			"a/internal/1.proto": []byte(
+				`syntax = proto3; package a.internal;`,
+			),
+			"b/internal/1.proto": []byte(
+				`syntax = proto3; package b.internal;`,
+			),
+			"b/1_test.proto": []byte(
+				`syntax = proto3; package b;`,
+			),
+		},
+	)
+	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, slogtestext.NewLogger(t), bufmodule.NopModuleDataProvider, bufmodule.NopCommitProvider)
+	moduleSetBuilder.AddLocalModule(
+		bucket,
+		"module1",
+		true,
+		// "**/internal" excludes the contents of directories that match, like a plain path.
+		bufmodule.LocalModuleWithTargetPaths(nil, []string{"**/internal", "**/*_test.proto"}),
+	)
+	moduleSet, err := moduleSetBuilder.Build()
+	require.NoError(t, err)
+	module1 := moduleSet.GetModuleForOpaqueID("module1")
+	require.NotNil(t, module1)
+	testTargetFilePaths(
+		t,
+		module1,
+		"a/1.proto",
+	)
+}
+
 func testNewBucketForPathToData(t *testing.T, pathToData map[string][]byte) storage.ReadBucket {
 	bucket, err := storagemem.NewReadBucket(pathToData)
 	require.NoError(t, err)
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"time"
 
 	modulev1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/module/v1"
 	"github.com/bufbuild/buf/private/bufpkg/bufapi"
@@ -26,13 +27,19 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/buf/private/pkg/tracing"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // NewModuleDataProvider returns a new ModuleDataProvider for the given API client.
 //
 // A warning is printed to the logger if a given Module is deprecated.
+//
+// The content for multiple Modules is downloaded in parallel requests, see
+// ModuleDataProviderWithDownloadConcurrency.
 func NewModuleDataProvider(
 	logger *slog.Logger,
 	clientProvider interface {
@@ -41,12 +48,39 @@ func NewModuleDataProvider(
 		bufapi.V1Beta1DownloadServiceClientProvider
 	},
 	graphProvider bufmodule.GraphProvider,
+	options ...ModuleDataProviderOption,
 ) bufmodule.ModuleDataProvider {
-	return newModuleDataProvider(logger, clientProvider, graphProvider)
+	return newModuleDataProvider(logger, clientProvider, graphProvider, options...)
+}
+
+// ModuleDataProviderOption is an option for a new ModuleDataProvider.
+type ModuleDataProviderOption func(*moduleDataProvider)
+
+// ModuleDataProviderWithDownloadConcurrency returns a new ModuleDataProviderOption that sets
+// the maximum number of concurrent download requests per registry.
+//
+// The Modules to download are split evenly across at most this many requests.
+// The default is 8. A downloadConcurrency of <1 has no meaning.
+func ModuleDataProviderWithDownloadConcurrency(downloadConcurrency int) ModuleDataProviderOption {
+	return func(moduleDataProvider *moduleDataProvider) {
+		if downloadConcurrency > 0 {
+			moduleDataProvider.downloadConcurrency = downloadConcurrency
+		}
+	}
+}
+
+// ModuleDataProviderWithObserver returns a new ModuleDataProviderOption that reports
+// the start and finish of every download request to the ModuleDataProviderObserver.
+func ModuleDataProviderWithObserver(observer bufmodule.ModuleDataProviderObserver) ModuleDataProviderOption {
+	return func(moduleDataProvider *moduleDataProvider) {
+		moduleDataProvider.observer = observer
+	}
 }
 
 // *** PRIVATE ***
 
+const defaultDownloadConcurrency = 8
+
 type moduleDataProvider struct {
 	logger         *slog.Logger
 	clientProvider interface {
@@ -54,7 +88,9 @@ type moduleDataProvider struct {
 		bufapi.V1ModuleServiceClientProvider
 		bufapi.V1Beta1DownloadServiceClientProvider
 	}
-	graphProvider bufmodule.GraphProvider
+	graphProvider       bufmodule.GraphProvider
+	downloadConcurrency int
+	observer            bufmodule.ModuleDataProviderObserver
 }
 
 func newModuleDataProvider(
@@ -65,21 +101,34 @@ func newModuleDataProvider(
 		bufapi.V1Beta1DownloadServiceClientProvider
 	},
 	graphProvider bufmodule.GraphProvider,
+	options ...ModuleDataProviderOption,
 ) *moduleDataProvider {
-	return &moduleDataProvider{
-		logger:         logger,
-		clientProvider: clientProvider,
-		graphProvider:  graphProvider,
+	moduleDataProvider := &moduleDataProvider{
+		logger:              logger,
+		clientProvider:      clientProvider,
+		graphProvider:       graphProvider,
+		downloadConcurrency: defaultDownloadConcurrency,
+		observer:            bufmodule.NopModuleDataProviderObserver(),
 	}
+	for _, option := range options {
+		option(moduleDataProvider)
+	}
+	return moduleDataProvider
 }
 
 func (a *moduleDataProvider) GetModuleDatasForModuleKeys(
 	ctx context.Context,
 	moduleKeys []bufmodule.ModuleKey,
-) ([]bufmodule.ModuleData, error) {
+) (_ []bufmodule.ModuleData, retErr error) {
 	if len(moduleKeys) == 0 {
 		return nil, nil
 	}
+	ctx, span := tracing.Start(
+		ctx,
+		"bufmoduleapi.download",
+		attribute.StringSlice("buf.modules", slicesext.Map(moduleKeys, bufmodule.ModuleKey.String)),
+	)
+	defer func() { tracing.End(span, retErr) }()
 	digestType, err := bufmodule.UniqueDigestTypeForModuleKeys(moduleKeys)
 	if err != nil {
 		return nil, err
@@ -216,9 +265,8 @@ func (a *moduleDataProvider) getCommitIDToUniversalProtoContentForRegistryAndInd
 	digestType bufmodule.DigestType,
 ) (map[uuid.UUID]*universalProtoContent, error) {
 	commitIDs := slicesext.MapKeysToSlice(commitIDToIndexedModuleKey)
-	universalProtoContents, err := getUniversalProtoContentsForRegistryAndCommitIDs(
+	universalProtoContents, err := a.getUniversalProtoContentsForRegistryAndCommitIDs(
 		ctx,
-		a.clientProvider,
 		registry,
 		commitIDs,
 		digestType,
@@ -253,6 +301,89 @@ func (a *moduleDataProvider) getCommitIDToUniversalProtoContentForRegistryAndInd
 	return commitIDToUniversalProtoContent, nil
 }
 
+// getUniversalProtoContentsForRegistryAndCommitIDs downloads the content for the commits in
+// up to downloadConcurrency parallel requests.
+//
+// Returns the contents in the same order as the commitIDs.
+func (a *moduleDataProvider) getUniversalProtoContentsForRegistryAndCommitIDs(
+	ctx context.Context,
+	registry string,
+	commitIDs []uuid.UUID,
+	digestType bufmodule.DigestType,
+) ([]*universalProtoContent, error) {
+	commitIDChunks := splitCommitIDs(commitIDs, a.downloadConcurrency)
+	chunkUniversalProtoContents := make([][]*universalProtoContent, len(commitIDChunks))
+	jobs := make([]func(context.Context) error, len(commitIDChunks))
+	for i, commitIDChunk := range commitIDChunks {
+		jobs[i] = func(ctx context.Context) error {
+			a.observer.DownloadStart(ctx, registry, commitIDChunk)
+			start := time.Now()
+			universalProtoContents, err := getUniversalProtoContentsForRegistryAndCommitIDs(
+				ctx,
+				a.clientProvider,
+				registry,
+				commitIDChunk,
+				digestType,
+			)
+			a.observer.DownloadFinish(
+				ctx,
+				registry,
+				commitIDChunk,
+				getUniversalProtoContentsNumBytes(universalProtoContents),
+				time.Since(start),
+				err,
+			)
+			if err != nil {
+				return err
+			}
+			// Each job writes to its own index, no locking is needed.
+			chunkUniversalProtoContents[i] = universalProtoContents
+			return nil
+		}
+	}
+	if err := thread.Parallelize(
+		ctx,
+		jobs,
+		thread.ParallelizeWithMaxParallelism(a.downloadConcurrency),
+	); err != nil {
+		return nil, err
+	}
+	universalProtoContents := make([]*universalProtoContent, 0, len(commitIDs))
+	for _, chunk := range chunkUniversalProtoContents {
+		universalProtoContents = append(universalProtoContents, chunk...)
+	}
+	return universalProtoContents, nil
+}
+
+// getUniversalProtoContentsNumBytes returns the total size of the file content.
+func getUniversalProtoContentsNumBytes(universalProtoContents []*universalProtoContent) int64 {
+	var numBytes int64
+	for _, universalProtoContent := range universalProtoContents {
+		for _, universalProtoFile := range universalProtoContent.Files {
+			numBytes += int64(len(universalProtoFile.Content))
+		}
+	}
+	return numBytes
+}
+
+// splitCommitIDs splits the commitIDs into at most numChunks chunks of roughly equal size,
+// preserving order.
+func splitCommitIDs(commitIDs []uuid.UUID, numChunks int) [][]uuid.UUID {
+	if numChunks > len(commitIDs) {
+		numChunks = len(commitIDs)
+	}
+	if numChunks < 1 {
+		return nil
+	}
+	chunks := make([][]uuid.UUID, 0, numChunks)
+	for i := 0; i < numChunks; i++ {
+		start := i * len(commitIDs) / numChunks
+		end := (i + 1) * len(commitIDs) / numChunks
+		chunks = append(chunks, commitIDs[start:end])
+	}
+	return chunks
+}
+
 // In the future, we might want to add State, Visibility, etc as parameters to bufmodule.Module, to
 // match what we are doing with Commit and Graph to some degree, and then bring this warning
 // out of the ModuleDataProvider. However, if we did this, this has unintended consequences - right now,
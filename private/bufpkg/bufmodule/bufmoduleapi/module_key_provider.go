@@ -25,11 +25,15 @@ import (
 )
 
 // NewModuleKeyProvider returns a new ModuleKeyProvider for the given API clients.
+//
+// ModuleRefs with refs that are semantic version constraints, such as "^1.4", are resolved
+// to the label with the highest semantic version that matches the constraint.
 func NewModuleKeyProvider(
 	logger *slog.Logger,
 	clientProvider interface {
 		bufapi.V1CommitServiceClientProvider
 		bufapi.V1Beta1CommitServiceClientProvider
+		bufapi.V1LabelServiceClientProvider
 	},
 ) bufmodule.ModuleKeyProvider {
 	return newModuleKeyProvider(logger, clientProvider)
@@ -42,6 +46,7 @@ type moduleKeyProvider struct {
 	clientProvider interface {
 		bufapi.V1CommitServiceClientProvider
 		bufapi.V1Beta1CommitServiceClientProvider
+		bufapi.V1LabelServiceClientProvider
 	}
 }
 
@@ -50,6 +55,7 @@ func newModuleKeyProvider(
 	clientProvider interface {
 		bufapi.V1CommitServiceClientProvider
 		bufapi.V1Beta1CommitServiceClientProvider
+		bufapi.V1LabelServiceClientProvider
 	},
 ) *moduleKeyProvider {
 	return &moduleKeyProvider{
@@ -72,6 +78,10 @@ func (a *moduleKeyProvider) GetModuleKeysForModuleRefs(
 	); err != nil {
 		return nil, err
 	}
+	moduleRefs, err := resolveModuleRefConstraints(ctx, a.clientProvider, moduleRefs)
+	if err != nil {
+		return nil, err
+	}
 
 	registryToIndexedModuleRefs := slicesext.ToIndexedValuesMap(
 		moduleRefs,
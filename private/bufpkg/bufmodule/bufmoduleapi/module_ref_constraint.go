@@ -0,0 +1,108 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleapi
+
+import (
+	"context"
+	"fmt"
+
+	modulev1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/module/v1"
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/semverconstraint"
+)
+
+// The page size to use when listing labels to resolve a version constraint.
+const listLabelsPageSize = 250
+
+// resolveModuleRefConstraints replaces every ModuleRef whose ref is a version constraint with a
+// ModuleRef that references the label with the highest semantic version matching the constraint.
+//
+// ModuleRefs that do not have version constraints are returned as-is.
+func resolveModuleRefConstraints(
+	ctx context.Context,
+	clientProvider bufapi.V1LabelServiceClientProvider,
+	moduleRefs []bufmodule.ModuleRef,
+) ([]bufmodule.ModuleRef, error) {
+	resolvedModuleRefs := make([]bufmodule.ModuleRef, len(moduleRefs))
+	for i, moduleRef := range moduleRefs {
+		if !semverconstraint.IsConstraint(moduleRef.Ref()) {
+			resolvedModuleRefs[i] = moduleRef
+			continue
+		}
+		resolvedModuleRef, err := resolveModuleRefConstraint(ctx, clientProvider, moduleRef)
+		if err != nil {
+			return nil, err
+		}
+		resolvedModuleRefs[i] = resolvedModuleRef
+	}
+	return resolvedModuleRefs, nil
+}
+
+func resolveModuleRefConstraint(
+	ctx context.Context,
+	clientProvider bufapi.V1LabelServiceClientProvider,
+	moduleRef bufmodule.ModuleRef,
+) (bufmodule.ModuleRef, error) {
+	constraint, err := semverconstraint.Parse(moduleRef.Ref())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", moduleRef.String(), err)
+	}
+	moduleFullName := moduleRef.ModuleFullName()
+	labelServiceClient := clientProvider.V1LabelServiceClient(moduleFullName.Registry())
+	var labelNames []string
+	var pageToken string
+	for {
+		response, err := labelServiceClient.ListLabels(
+			ctx,
+			connect.NewRequest(
+				&modulev1.ListLabelsRequest{
+					PageSize:  listLabelsPageSize,
+					PageToken: pageToken,
+					ResourceRef: &modulev1.ResourceRef{
+						Value: &modulev1.ResourceRef_Name_{
+							Name: &modulev1.ResourceRef_Name{
+								Owner:  moduleFullName.Owner(),
+								Module: moduleFullName.Name(),
+							},
+						},
+					},
+					ArchiveFilter: modulev1.ListLabelsRequest_ARCHIVE_FILTER_UNARCHIVED_ONLY,
+				},
+			),
+		)
+		if err != nil {
+			return nil, maybeNewNotFoundError(err)
+		}
+		for _, label := range response.Msg.Labels {
+			labelNames = append(labelNames, label.Name)
+		}
+		pageToken = response.Msg.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	labelName, ok := semverconstraint.Max(constraint, labelNames)
+	if !ok {
+		return nil, fmt.Errorf("%s: no label matches version constraint %q", moduleFullName.String(), constraint.String())
+	}
+	return bufmodule.NewModuleRef(
+		moduleFullName.Registry(),
+		moduleFullName.Owner(),
+		moduleFullName.Name(),
+		labelName,
+	)
+}
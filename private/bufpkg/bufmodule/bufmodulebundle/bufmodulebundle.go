@@ -0,0 +1,210 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmodulebundle reads and writes dependency bundles.
+//
+// A bundle is a gzipped tarball that contains the files, buf.yaml, buf.lock, and declared
+// dependencies of a set of Modules, so that Modules can be built in environments without
+// network access. The ModuleDatas in a bundle are verified against the digests of the
+// requested ModuleKeys when read, so bundles do not need to be trusted.
+package bufmodulebundle
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"go.uber.org/multierr"
+)
+
+const (
+	// bundleFileName is the name of the file at the root of the bundle that describes
+	// the bundle.
+	bundleFileName = "bundle.yaml"
+	// bundleModulesDirName is the directory within the bundle that contains the
+	// ModuleDatas, in the layout of a bufmodulestore.ModuleDataStore.
+	bundleModulesDirName = "modules"
+	bundleVersion        = "v1"
+)
+
+// WriteBundle writes a bundle containing the ModuleDatas to the writer.
+func WriteBundle(
+	ctx context.Context,
+	logger *slog.Logger,
+	writer io.Writer,
+	moduleDatas []bufmodule.ModuleData,
+) (retErr error) {
+	bucket := storagemem.NewReadWriteBucket()
+	if err := newModuleDataStore(
+		logger,
+		storage.MapReadWriteBucket(bucket, storage.MapOnPrefix(bundleModulesDirName)),
+	).PutModuleDatas(ctx, moduleDatas); err != nil {
+		return err
+	}
+	externalBundle := externalBundle{
+		Version: bundleVersion,
+	}
+	for _, moduleData := range moduleDatas {
+		moduleKey := moduleData.ModuleKey()
+		digest, err := moduleKey.Digest()
+		if err != nil {
+			return err
+		}
+		externalBundle.Modules = append(
+			externalBundle.Modules,
+			externalBundleModule{
+				Name:   moduleKey.ModuleFullName().String(),
+				Commit: uuidutil.ToDashless(moduleKey.CommitID()),
+				Digest: digest.String(),
+			},
+		)
+	}
+	data, err := encoding.MarshalYAML(&externalBundle)
+	if err != nil {
+		return err
+	}
+	if err := storage.PutPath(ctx, bucket, bundleFileName, data); err != nil {
+		return err
+	}
+	gzipWriter := gzip.NewWriter(writer)
+	defer func() {
+		retErr = multierr.Append(retErr, gzipWriter.Close())
+	}()
+	return storagearchive.Tar(ctx, bucket, gzipWriter)
+}
+
+// NewModuleDataProvider returns a new ModuleDataProvider that reads ModuleDatas from the
+// bundle read from the reader, and uses the delegate for any ModuleKeys that are not in the bundle.
+//
+// The bundle is read into memory on the first call to GetModuleDatasForModuleKeys.
+func NewModuleDataProvider(
+	logger *slog.Logger,
+	reader io.Reader,
+	delegate bufmodule.ModuleDataProvider,
+) bufmodule.ModuleDataProvider {
+	return newModuleDataProvider(logger, reader, delegate)
+}
+
+// *** PRIVATE ***
+
+type externalBundle struct {
+	Version string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	Modules []externalBundleModule `json:"modules,omitempty" yaml:"modules,omitempty"`
+}
+
+type externalBundleModule struct {
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+type moduleDataProvider struct {
+	logger   *slog.Logger
+	reader   io.Reader
+	delegate bufmodule.ModuleDataProvider
+
+	once                    sync.Once
+	cacheModuleDataProvider bufmodule.ModuleDataProvider
+	err                     error
+}
+
+func newModuleDataProvider(
+	logger *slog.Logger,
+	reader io.Reader,
+	delegate bufmodule.ModuleDataProvider,
+) *moduleDataProvider {
+	return &moduleDataProvider{
+		logger:   logger,
+		reader:   reader,
+		delegate: delegate,
+	}
+}
+
+func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	p.once.Do(
+		func() {
+			var bucket storage.ReadWriteBucket
+			bucket, p.err = readBundle(ctx, p.reader)
+			if p.err != nil {
+				return
+			}
+			// The bundle is in memory, so ModuleDatas from the delegate being put to the
+			// store only affect this process.
+			p.cacheModuleDataProvider = bufmodulecache.NewModuleDataProvider(
+				p.logger,
+				p.delegate,
+				newModuleDataStore(p.logger, storage.MapReadWriteBucket(bucket, storage.MapOnPrefix(bundleModulesDirName))),
+			)
+		},
+	)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.cacheModuleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+}
+
+func readBundle(ctx context.Context, reader io.Reader) (_ storage.ReadWriteBucket, retErr error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, gzipReader.Close())
+	}()
+	bucket := storagemem.NewReadWriteBucket()
+	if err := storagearchive.Untar(ctx, gzipReader, bucket); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	data, err := storage.ReadPath(ctx, bucket, bundleFileName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	var externalBundle externalBundle
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalBundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %s: %w", bundleFileName, err)
+	}
+	switch externalBundle.Version {
+	case bundleVersion:
+	case "":
+		return nil, errors.New("invalid bundle: no version")
+	default:
+		return nil, fmt.Errorf("unsupported bundle version %q, upgrade buf to read this bundle", externalBundle.Version)
+	}
+	return bucket, nil
+}
+
+func newModuleDataStore(logger *slog.Logger, bucket storage.ReadWriteBucket) bufmodulestore.ModuleDataStore {
+	return bufmodulestore.NewModuleDataStore(
+		logger,
+		bucket,
+		// Bundles are only ever in memory within a single process.
+		filelock.NewNopLocker(),
+	)
+}
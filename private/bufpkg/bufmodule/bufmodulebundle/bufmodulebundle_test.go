@@ -0,0 +1,91 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulebundle
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	bsrProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod1",
+			PathToData: map[string][]byte{
+				"mod1.proto": []byte(`syntax = "proto3"; package mod1;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod2",
+			PathToData: map[string][]byte{
+				"mod2.proto": []byte(`syntax = "proto3"; package mod2; import "mod1.proto";`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleRefMod1, err := bufmodule.NewModuleRef("buf.build", "foo", "mod1", "")
+	require.NoError(t, err)
+	moduleRefMod2, err := bufmodule.NewModuleRef("buf.build", "foo", "mod2", "")
+	require.NoError(t, err)
+	moduleKeys, err := bsrProvider.GetModuleKeysForModuleRefs(
+		ctx,
+		[]bufmodule.ModuleRef{moduleRefMod1, moduleRefMod2},
+		bufmodule.DigestTypeB5,
+	)
+	require.NoError(t, err)
+	moduleDatas, err := bsrProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBundle(ctx, logger, buffer, moduleDatas[:1]))
+
+	// The delegate has nothing, so only ModuleDatas in the bundle can be found.
+	moduleDataProvider := NewModuleDataProvider(logger, buffer, bufmodule.NopModuleDataProvider)
+	bundleModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	require.Len(t, bundleModuleDatas, 1)
+	require.Equal(t, "buf.build/foo/mod1", bundleModuleDatas[0].ModuleKey().ModuleFullName().String())
+	bucket, err := bundleModuleDatas[0].Bucket()
+	require.NoError(t, err)
+	data, err := storage.ReadPath(ctx, bucket, "mod1.proto")
+	require.NoError(t, err)
+	require.Equal(t, `syntax = "proto3"; package mod1;`, string(data))
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys[1:])
+	require.Error(t, err)
+}
+
+func TestBundleInvalid(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	moduleDataProvider := NewModuleDataProvider(logger, bytes.NewReader([]byte("foo")), bufmodule.NopModuleDataProvider)
+	_, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, nil)
+	require.ErrorContains(t, err, "invalid bundle")
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBundle(ctx, logger, buffer, nil))
+	moduleDataProvider = NewModuleDataProvider(logger, buffer, bufmodule.NopModuleDataProvider)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, nil)
+	require.NoError(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"log/slog"
 	"sync/atomic"
+	"time"
 
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/syserror"
@@ -32,6 +33,8 @@ type baseProvider[K any, V any] struct {
 	storePutValues           func(context.Context, []V) error
 	keyToCommitID            func(K) uuid.UUID
 	valueToCommitID          func(V) uuid.UUID
+	// observeStoreLookup may be nil.
+	observeStoreLookup func(ctx context.Context, foundValues []V, notFoundKeys []K, duration time.Duration)
 
 	keysRetrieved atomic.Int64
 	keysHit       atomic.Int64
@@ -44,6 +47,7 @@ func newBaseProvider[K any, V any](
 	storePutValues func(context.Context, []V) error,
 	keyToCommitID func(K) uuid.UUID,
 	valueToCommitID func(V) uuid.UUID,
+	observeStoreLookup func(ctx context.Context, foundValues []V, notFoundKeys []K, duration time.Duration),
 ) *baseProvider[K, V] {
 	return &baseProvider[K, V]{
 		logger:                   logger,
@@ -52,6 +56,7 @@ func newBaseProvider[K any, V any](
 		storePutValues:           storePutValues,
 		keyToCommitID:            keyToCommitID,
 		valueToCommitID:          valueToCommitID,
+		observeStoreLookup:       observeStoreLookup,
 	}
 }
 
@@ -63,22 +68,31 @@ func (p *baseProvider[K, V]) getValuesForKeys(ctx context.Context, keys []K) ([]
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 	foundValues, notFoundKeys, err := p.storeGetValuesForKeys(ctx, keys)
 	if err != nil {
 		return nil, err
 	}
-	delegateValues, err := p.delegateGetValuesForKeys(
-		ctx,
-		notFoundKeys,
-	)
-	if err != nil {
-		return nil, err
+	if p.observeStoreLookup != nil {
+		p.observeStoreLookup(ctx, foundValues, notFoundKeys, time.Since(start))
 	}
-	if err := p.storePutValues(
-		ctx,
-		delegateValues,
-	); err != nil {
-		return nil, err
+	var delegateValues []V
+	// Do not call the delegate if every key was found, the delegate may not be able
+	// to serve any requests at all, for example when reading from a bundle offline.
+	if len(notFoundKeys) > 0 {
+		delegateValues, err = p.delegateGetValuesForKeys(
+			ctx,
+			notFoundKeys,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.storePutValues(
+			ctx,
+			delegateValues,
+		); err != nil {
+			return nil, err
+		}
 	}
 
 	p.keysRetrieved.Add(int64(len(keys)))
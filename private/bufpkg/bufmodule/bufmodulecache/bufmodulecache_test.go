@@ -224,6 +224,34 @@ func TestModuleDataProviderBasic(t *testing.T) {
 	)
 }
 
+func TestModuleDataProviderObserver(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	bsrProvider, moduleKeys := testGetBSRProviderAndModuleKeys(t, ctx)
+	logger := slogtestext.NewLogger(t)
+	observer := &testModuleDataProviderObserver{}
+
+	cacheProvider := NewModuleDataProvider(
+		logger,
+		bsrProvider,
+		bufmodulestore.NewModuleDataStore(
+			logger,
+			storagemem.NewReadWriteBucket(),
+			filelock.NewNopLocker(),
+		),
+		ModuleDataProviderWithObserver(observer),
+	)
+	_, err := cacheProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	require.Equal(t, 0, observer.hits)
+	require.Equal(t, 1, observer.misses)
+	_, err = cacheProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Equal(t, 1, observer.hits)
+	require.Equal(t, 3, observer.misses)
+}
+
 func TestConcurrentCacheReadWrite(t *testing.T) {
 	t.Parallel()
 
@@ -332,3 +360,20 @@ func testGetBSRProviderAndModuleKeys(t *testing.T, ctx context.Context) (bufmodu
 	require.Equal(t, 3, len(moduleKeys))
 	return bsrProvider, moduleKeys
 }
+
+type testModuleDataProviderObserver struct {
+	bufmodule.ModuleDataProviderObserver
+
+	hits   int
+	misses int
+}
+
+func (o *testModuleDataProviderObserver) CacheLookup(
+	_ context.Context,
+	hitModuleKeys []bufmodule.ModuleKey,
+	missedModuleKeys []bufmodule.ModuleKey,
+	_ time.Duration,
+) {
+	o.hits += len(hitModuleKeys)
+	o.misses += len(missedModuleKeys)
+}
@@ -56,6 +56,7 @@ func newCommitProvider(
 			func(commit bufmodule.Commit) uuid.UUID {
 				return commit.ModuleKey().CommitID()
 			},
+			nil,
 		),
 		byCommitKey: newBaseProvider(
 			logger,
@@ -66,6 +67,7 @@ func newCommitProvider(
 			func(commit bufmodule.Commit) uuid.UUID {
 				return commit.ModuleKey().CommitID()
 			},
+			nil,
 		),
 	}
 }
@@ -17,9 +17,11 @@ package bufmodulecache
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/google/uuid"
 )
 
@@ -30,8 +32,20 @@ func NewModuleDataProvider(
 	logger *slog.Logger,
 	delegate bufmodule.ModuleDataProvider,
 	store bufmodulestore.ModuleDataStore,
+	options ...ModuleDataProviderOption,
 ) bufmodule.ModuleDataProvider {
-	return newModuleDataProvider(logger, delegate, store)
+	return newModuleDataProvider(logger, delegate, store, options...)
+}
+
+// ModuleDataProviderOption is an option for a new ModuleDataProvider.
+type ModuleDataProviderOption func(*moduleDataProviderOptions)
+
+// ModuleDataProviderWithObserver returns a new ModuleDataProviderOption that reports
+// cache hits and misses to the ModuleDataProviderObserver.
+func ModuleDataProviderWithObserver(observer bufmodule.ModuleDataProviderObserver) ModuleDataProviderOption {
+	return func(moduleDataProviderOptions *moduleDataProviderOptions) {
+		moduleDataProviderOptions.observer = observer
+	}
 }
 
 /// *** PRIVATE ***
@@ -44,7 +58,13 @@ func newModuleDataProvider(
 	logger *slog.Logger,
 	delegate bufmodule.ModuleDataProvider,
 	store bufmodulestore.ModuleDataStore,
+	options ...ModuleDataProviderOption,
 ) *moduleDataProvider {
+	moduleDataProviderOptions := newModuleDataProviderOptions()
+	for _, option := range options {
+		option(moduleDataProviderOptions)
+	}
+	observer := moduleDataProviderOptions.observer
 	return &moduleDataProvider{
 		baseProvider: newBaseProvider(
 			logger,
@@ -55,6 +75,19 @@ func newModuleDataProvider(
 			func(moduleData bufmodule.ModuleData) uuid.UUID {
 				return moduleData.ModuleKey().CommitID()
 			},
+			func(
+				ctx context.Context,
+				foundModuleDatas []bufmodule.ModuleData,
+				notFoundModuleKeys []bufmodule.ModuleKey,
+				duration time.Duration,
+			) {
+				observer.CacheLookup(
+					ctx,
+					slicesext.Map(foundModuleDatas, bufmodule.ModuleData.ModuleKey),
+					notFoundModuleKeys,
+					duration,
+				)
+			},
 		),
 	}
 }
@@ -65,3 +98,13 @@ func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
 ) ([]bufmodule.ModuleData, error) {
 	return p.baseProvider.getValuesForKeys(ctx, moduleKeys)
 }
+
+type moduleDataProviderOptions struct {
+	observer bufmodule.ModuleDataProviderObserver
+}
+
+func newModuleDataProviderOptions() *moduleDataProviderOptions {
+	return &moduleDataProviderOptions{
+		observer: bufmodule.NopModuleDataProviderObserver(),
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmodulefs provides Modules from a registry stored on the filesystem.
+//
+// This is used for remotes of the form file:///path/to/registry, for tests, local
+// development, and simple self-hosting. The registry directory has the layout:
+//
+//	modules/{owner}/{name}/labels/{label}
+//	commits/{commitID}/commit.yaml
+//	commits/{commitID}/files/...
+//
+// Commit IDs are dashless UUIDs. Each label file contains the commit ID that the label
+// points to. The "main" label is used when a reference does not specify a label or commit.
+// The files directory of a commit contains the files of the Module, and commit.yaml
+// describes the commit:
+//
+//	name: buf.local/acme/weather
+//	digest: b5:...
+//	create_time: 2024-01-01T00:00:00Z
+//	deps:
+//	  - name: buf.local/acme/units
+//	    commit: 0123456789abcdef0123456789abcdef
+//
+// The deps are all dependencies of the commit, including transitive dependencies, as in
+// a buf.lock, and must be commits within the same registry. Only b5 digests are supported.
+//
+// The content of each commit is verified against its digest when read.
+package bufmodulefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/dag"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLabelName = "main"
+
+	modulesDirName     = "modules"
+	labelsDirName      = "labels"
+	commitsDirName     = "commits"
+	commitFileName     = "commit.yaml"
+	commitFilesDirName = "files"
+)
+
+// Provider provides Modules for a single registry stored on the filesystem.
+type Provider interface {
+	bufmodule.ModuleKeyProvider
+	bufmodule.ModuleDataProvider
+	bufmodule.CommitProvider
+	bufmodule.GraphProvider
+
+	// Registry is the registry hostname that this Provider provides Modules for.
+	Registry() string
+
+	isProvider()
+}
+
+// NewProvider returns a new Provider for the registry stored in the bucket.
+func NewProvider(
+	logger *slog.Logger,
+	registry string,
+	bucket storage.ReadBucket,
+) Provider {
+	return newProvider(logger, registry, bucket)
+}
+
+/// *** PRIVATE ***
+
+type provider struct {
+	logger   *slog.Logger
+	registry string
+	bucket   storage.ReadBucket
+}
+
+func newProvider(
+	logger *slog.Logger,
+	registry string,
+	bucket storage.ReadBucket,
+) *provider {
+	return &provider{
+		logger:   logger,
+		registry: registry,
+		bucket:   bucket,
+	}
+}
+
+func (p *provider) Registry() string {
+	return p.registry
+}
+
+func (p *provider) GetModuleKeysForModuleRefs(
+	ctx context.Context,
+	moduleRefs []bufmodule.ModuleRef,
+	digestType bufmodule.DigestType,
+) ([]bufmodule.ModuleKey, error) {
+	if err := validateDigestType(digestType); err != nil {
+		return nil, err
+	}
+	moduleKeys := make([]bufmodule.ModuleKey, len(moduleRefs))
+	for i, moduleRef := range moduleRefs {
+		commitID, err := p.getCommitIDForModuleRef(ctx, moduleRef)
+		if err != nil {
+			return nil, err
+		}
+		moduleKey, _, err := p.getModuleKeyForCommitID(ctx, commitID)
+		if err != nil {
+			return nil, err
+		}
+		if !bufmodule.ModuleFullNameEqual(moduleKey.ModuleFullName(), moduleRef.ModuleFullName()) {
+			return nil, &fs.PathError{Op: "read", Path: moduleRef.String(), Err: fs.ErrNotExist}
+		}
+		moduleKeys[i] = moduleKey
+	}
+	return moduleKeys, nil
+}
+
+func (p *provider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	moduleDatas := make([]bufmodule.ModuleData, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		if err := validateModuleKey(moduleKey); err != nil {
+			return nil, err
+		}
+		commitModuleKey, externalCommit, err := p.getModuleKeyForCommitID(ctx, moduleKey.CommitID())
+		if err != nil {
+			return nil, err
+		}
+		if !bufmodule.ModuleFullNameEqual(commitModuleKey.ModuleFullName(), moduleKey.ModuleFullName()) {
+			return nil, fmt.Errorf(
+				"commit %s is a commit of %q, not %q",
+				uuidutil.ToDashless(moduleKey.CommitID()),
+				commitModuleKey.ModuleFullName().String(),
+				moduleKey.ModuleFullName().String(),
+			)
+		}
+		declaredDepModuleKeys, err := p.getDepModuleKeysForExternalCommit(ctx, externalCommit)
+		if err != nil {
+			return nil, err
+		}
+		commitFilesDirPath := normalpath.Join(
+			commitsDirName,
+			uuidutil.ToDashless(moduleKey.CommitID()),
+			commitFilesDirName,
+		)
+		moduleDatas[i] = bufmodule.NewModuleData(
+			ctx,
+			// The ModuleKey that was requested is used, so that its Digest is the one verified.
+			moduleKey,
+			func() (storage.ReadBucket, error) {
+				return storage.MapReadBucket(p.bucket, storage.MapOnPrefix(commitFilesDirPath)), nil
+			},
+			func() ([]bufmodule.ModuleKey, error) {
+				return declaredDepModuleKeys, nil
+			},
+			// b5 digests do not use v1 buf.yaml or buf.lock files.
+			func() (bufmodule.ObjectData, error) {
+				return nil, nil
+			},
+			func() (bufmodule.ObjectData, error) {
+				return nil, nil
+			},
+		)
+	}
+	return moduleDatas, nil
+}
+
+func (p *provider) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	commits := make([]bufmodule.Commit, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		if err := validateModuleKey(moduleKey); err != nil {
+			return nil, err
+		}
+		commit, err := p.getCommitForCommitID(ctx, moduleKey.CommitID())
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = commit
+	}
+	return commits, nil
+}
+
+func (p *provider) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	commits := make([]bufmodule.Commit, len(commitKeys))
+	for i, commitKey := range commitKeys {
+		if err := validateDigestType(commitKey.DigestType()); err != nil {
+			return nil, err
+		}
+		commit, err := p.getCommitForCommitID(ctx, commitKey.CommitID())
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = commit
+	}
+	return commits, nil
+}
+
+func (p *provider) GetGraphForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) (*dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey], error) {
+	graph := dag.NewGraph[bufmodule.RegistryCommitID, bufmodule.ModuleKey](bufmodule.ModuleKeyToRegistryCommitID)
+	for _, moduleKey := range moduleKeys {
+		if err := validateModuleKey(moduleKey); err != nil {
+			return nil, err
+		}
+		if err := p.addModuleKeyToGraphRec(ctx, moduleKey, graph); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+func (p *provider) addModuleKeyToGraphRec(
+	ctx context.Context,
+	moduleKey bufmodule.ModuleKey,
+	graph *dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey],
+) error {
+	if graph.ContainsNode(bufmodule.ModuleKeyToRegistryCommitID(moduleKey)) {
+		return nil
+	}
+	graph.AddNode(moduleKey)
+	_, externalCommit, err := p.getModuleKeyForCommitID(ctx, moduleKey.CommitID())
+	if err != nil {
+		return err
+	}
+	depModuleKeys, err := p.getDepModuleKeysForExternalCommit(ctx, externalCommit)
+	if err != nil {
+		return err
+	}
+	for _, depModuleKey := range depModuleKeys {
+		graph.AddEdge(moduleKey, depModuleKey)
+		if err := p.addModuleKeyToGraphRec(ctx, depModuleKey, graph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *provider) getCommitIDForModuleRef(ctx context.Context, moduleRef bufmodule.ModuleRef) (uuid.UUID, error) {
+	ref := moduleRef.Ref()
+	if ref == "" {
+		ref = defaultLabelName
+	}
+	moduleFullName := moduleRef.ModuleFullName()
+	labelFilePath, err := normalpath.NormalizeAndValidate(
+		normalpath.Join(
+			modulesDirName,
+			moduleFullName.Owner(),
+			moduleFullName.Name(),
+			labelsDirName,
+			ref,
+		),
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid reference %q: %w", moduleRef.String(), err)
+	}
+	data, err := storage.ReadPath(ctx, p.bucket, labelFilePath)
+	if err == nil {
+		commitID, err := uuidutil.FromDashless(strings.TrimSpace(string(data)))
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid commit ID in label file %q: %w", labelFilePath, err)
+		}
+		return commitID, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return uuid.Nil, err
+	}
+	// The ref may be a commit ID.
+	commitID, err := uuidutil.FromDashless(ref)
+	if err != nil {
+		return uuid.Nil, &fs.PathError{Op: "read", Path: moduleRef.String(), Err: fs.ErrNotExist}
+	}
+	return commitID, nil
+}
+
+func (p *provider) getCommitForCommitID(ctx context.Context, commitID uuid.UUID) (bufmodule.Commit, error) {
+	moduleKey, externalCommit, err := p.getModuleKeyForCommitID(ctx, commitID)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewCommit(
+		moduleKey,
+		func() (time.Time, error) {
+			return externalCommit.CreateTime, nil
+		},
+	), nil
+}
+
+func (p *provider) getModuleKeyForCommitID(
+	ctx context.Context,
+	commitID uuid.UUID,
+) (bufmodule.ModuleKey, *externalCommit, error) {
+	commitFilePath := normalpath.Join(commitsDirName, uuidutil.ToDashless(commitID), commitFileName)
+	data, err := storage.ReadPath(ctx, p.bucket, commitFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var externalCommit externalCommit
+	if err := encoding.UnmarshalYAMLStrict(data, &externalCommit); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", commitFilePath, err)
+	}
+	moduleFullName, err := bufmodule.ParseModuleFullName(externalCommit.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", commitFilePath, err)
+	}
+	if moduleFullName.Registry() != p.registry {
+		return nil, nil, fmt.Errorf("invalid %s: module %q is not in registry %q", commitFilePath, moduleFullName.String(), p.registry)
+	}
+	digest, err := bufmodule.ParseDigest(externalCommit.Digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", commitFilePath, err)
+	}
+	if err := validateDigestType(digest.Type()); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", commitFilePath, err)
+	}
+	moduleKey, err := bufmodule.NewModuleKey(
+		moduleFullName,
+		commitID,
+		func() (bufmodule.Digest, error) {
+			return digest, nil
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return moduleKey, &externalCommit, nil
+}
+
+func (p *provider) getDepModuleKeysForExternalCommit(
+	ctx context.Context,
+	externalCommit *externalCommit,
+) ([]bufmodule.ModuleKey, error) {
+	depModuleKeys := make([]bufmodule.ModuleKey, len(externalCommit.Deps))
+	for i, externalCommitDep := range externalCommit.Deps {
+		depCommitID, err := uuidutil.FromDashless(externalCommitDep.Commit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit for dependency %q of %q: %w", externalCommitDep.Name, externalCommit.Name, err)
+		}
+		depModuleKey, _, err := p.getModuleKeyForCommitID(ctx, depCommitID)
+		if err != nil {
+			return nil, err
+		}
+		if depModuleKey.ModuleFullName().String() != externalCommitDep.Name {
+			return nil, fmt.Errorf(
+				"dependency %q of %q has commit %s, which is a commit of %q",
+				externalCommitDep.Name,
+				externalCommit.Name,
+				externalCommitDep.Commit,
+				depModuleKey.ModuleFullName().String(),
+			)
+		}
+		depModuleKeys[i] = depModuleKey
+	}
+	return depModuleKeys, nil
+}
+
+func (*provider) isProvider() {}
+
+type externalCommit struct {
+	Name       string              `json:"name,omitempty" yaml:"name,omitempty"`
+	Digest     string              `json:"digest,omitempty" yaml:"digest,omitempty"`
+	CreateTime time.Time           `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	Deps       []externalCommitDep `json:"deps,omitempty" yaml:"deps,omitempty"`
+}
+
+type externalCommitDep struct {
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+func validateModuleKey(moduleKey bufmodule.ModuleKey) error {
+	digest, err := moduleKey.Digest()
+	if err != nil {
+		return err
+	}
+	return validateDigestType(digest.Type())
+}
+
+func validateDigestType(digestType bufmodule.DigestType) error {
+	if digestType != bufmodule.DigestTypeB5 {
+		return fmt.Errorf("file remotes only support %s digests, got %s", bufmodule.DigestTypeB5, digestType)
+	}
+	return nil
+}
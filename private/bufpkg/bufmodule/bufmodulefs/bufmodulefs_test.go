@@ -0,0 +1,207 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulefs
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, moduleKeys := testNewRegistryBucket(t, ctx)
+	provider := NewProvider(slogtestext.NewLogger(t), "buf.local", bucket)
+
+	moduleRefMod2, err := bufmodule.NewModuleRef("buf.local", "foo", "mod2", "")
+	require.NoError(t, err)
+	moduleRefMod1, err := bufmodule.NewModuleRef("buf.local", "foo", "mod1", uuidutil.ToDashless(moduleKeys[0].CommitID()))
+	require.NoError(t, err)
+	resolvedModuleKeys, err := provider.GetModuleKeysForModuleRefs(
+		ctx,
+		[]bufmodule.ModuleRef{moduleRefMod2, moduleRefMod1},
+		bufmodule.DigestTypeB5,
+	)
+	require.NoError(t, err)
+	require.Len(t, resolvedModuleKeys, 2)
+	require.Equal(t, moduleKeys[1].CommitID(), resolvedModuleKeys[0].CommitID())
+	require.Equal(t, moduleKeys[0].CommitID(), resolvedModuleKeys[1].CommitID())
+	_, err = provider.GetModuleKeysForModuleRefs(ctx, []bufmodule.ModuleRef{moduleRefMod2}, bufmodule.DigestTypeB4)
+	require.Error(t, err)
+	moduleRefMissing, err := bufmodule.NewModuleRef("buf.local", "foo", "mod2", "missing")
+	require.NoError(t, err)
+	_, err = provider.GetModuleKeysForModuleRefs(ctx, []bufmodule.ModuleRef{moduleRefMissing}, bufmodule.DigestTypeB5)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	moduleDatas, err := provider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, moduleDatas, 2)
+	moduleDataBucket, err := moduleDatas[1].Bucket()
+	require.NoError(t, err)
+	paths, err := storage.AllPaths(ctx, moduleDataBucket, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"mod2.proto"}, paths)
+	declaredDepModuleKeys, err := moduleDatas[1].DeclaredDepModuleKeys()
+	require.NoError(t, err)
+	require.Len(t, declaredDepModuleKeys, 1)
+	require.Equal(t, "buf.local/foo/mod1", declaredDepModuleKeys[0].ModuleFullName().String())
+
+	commits, err := provider.GetCommitsForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	createTime, err := commits[0].CreateTime()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), createTime.UTC())
+
+	graph, err := provider.GetGraphForModuleKeys(ctx, moduleKeys[1:])
+	require.NoError(t, err)
+	require.Equal(t, 2, graph.NumNodes())
+	require.Equal(t, 1, graph.NumEdges())
+}
+
+func TestProviderTampered(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, moduleKeys := testNewRegistryBucket(t, ctx)
+	require.NoError(
+		t,
+		storage.PutPath(
+			ctx,
+			bucket,
+			normalpath.Join(commitsDirName, uuidutil.ToDashless(moduleKeys[0].CommitID()), commitFilesDirName, "mod1.proto"),
+			[]byte(`syntax = "proto3"; package tampered;`),
+		),
+	)
+	provider := NewProvider(slogtestext.NewLogger(t), "buf.local", bucket)
+	moduleDatas, err := provider.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	_, err = moduleDatas[0].Bucket()
+	require.Error(t, err)
+}
+
+func TestModuleDataProviderRouting(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, moduleKeys := testNewRegistryBucket(t, ctx)
+	moduleDataProvider := NewModuleDataProvider(
+		bufmodule.NopModuleDataProvider,
+		[]Provider{
+			NewProvider(slogtestext.NewLogger(t), "buf.local", bucket),
+		},
+	)
+	moduleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, moduleDatas, 2)
+	otherModuleFullName, err := bufmodule.NewModuleFullName("buf.build", "foo", "mod1")
+	require.NoError(t, err)
+	otherModuleKey, err := bufmodule.NewModuleKey(otherModuleFullName, moduleKeys[0].CommitID(), moduleKeys[0].Digest)
+	require.NoError(t, err)
+	// Other registries go to the delegate.
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, []bufmodule.ModuleKey{moduleKeys[0], otherModuleKey})
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// testNewRegistryBucket returns a registry bucket with buf.local/foo/mod1 and buf.local/foo/mod2,
+// where mod2 depends on mod1, and the ModuleKeys for mod1 and mod2.
+func testNewRegistryBucket(t *testing.T, ctx context.Context) (storage.ReadWriteBucket, []bufmodule.ModuleKey) {
+	omniProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.local/foo/mod1",
+			PathToData: map[string][]byte{
+				"mod1.proto": []byte(`syntax = "proto3"; package mod1;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.local/foo/mod2",
+			PathToData: map[string][]byte{
+				"mod2.proto": []byte(`syntax = "proto3"; package mod2; import "mod1.proto";`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleRefMod1, err := bufmodule.NewModuleRef("buf.local", "foo", "mod1", "")
+	require.NoError(t, err)
+	moduleRefMod2, err := bufmodule.NewModuleRef("buf.local", "foo", "mod2", "")
+	require.NoError(t, err)
+	moduleKeys, err := omniProvider.GetModuleKeysForModuleRefs(
+		ctx,
+		[]bufmodule.ModuleRef{moduleRefMod1, moduleRefMod2},
+		bufmodule.DigestTypeB5,
+	)
+	require.NoError(t, err)
+	moduleDatas, err := omniProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	bucket := storagemem.NewReadWriteBucket()
+	for _, moduleData := range moduleDatas {
+		moduleKey := moduleData.ModuleKey()
+		digest, err := moduleKey.Digest()
+		require.NoError(t, err)
+		declaredDepModuleKeys, err := moduleData.DeclaredDepModuleKeys()
+		require.NoError(t, err)
+		externalCommit := externalCommit{
+			Name:       moduleKey.ModuleFullName().String(),
+			Digest:     digest.String(),
+			CreateTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		for _, declaredDepModuleKey := range declaredDepModuleKeys {
+			externalCommit.Deps = append(
+				externalCommit.Deps,
+				externalCommitDep{
+					Name:   declaredDepModuleKey.ModuleFullName().String(),
+					Commit: uuidutil.ToDashless(declaredDepModuleKey.CommitID()),
+				},
+			)
+		}
+		data, err := encoding.MarshalYAML(&externalCommit)
+		require.NoError(t, err)
+		commitDirPath := normalpath.Join(commitsDirName, uuidutil.ToDashless(moduleKey.CommitID()))
+		require.NoError(t, storage.PutPath(ctx, bucket, normalpath.Join(commitDirPath, commitFileName), data))
+		moduleDataBucket, err := moduleData.Bucket()
+		require.NoError(t, err)
+		_, err = storage.Copy(
+			ctx,
+			moduleDataBucket,
+			storage.MapWriteBucket(bucket, storage.MapOnPrefix(normalpath.Join(commitDirPath, commitFilesDirName))),
+		)
+		require.NoError(t, err)
+		require.NoError(
+			t,
+			storage.PutPath(
+				ctx,
+				bucket,
+				normalpath.Join(
+					modulesDirName,
+					moduleKey.ModuleFullName().Owner(),
+					moduleKey.ModuleFullName().Name(),
+					labelsDirName,
+					defaultLabelName,
+				),
+				[]byte(uuidutil.ToDashless(moduleKey.CommitID())+"\n"),
+			),
+		)
+	}
+	return bucket, moduleKeys
+}
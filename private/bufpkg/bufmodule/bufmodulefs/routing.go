@@ -0,0 +1,278 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulefs
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/dag"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+)
+
+// NewModuleKeyProvider returns a new ModuleKeyProvider that uses the Provider for the
+// registry of each ModuleRef, and the delegate for all other registries.
+//
+// If there are no Providers, the delegate is returned.
+func NewModuleKeyProvider(
+	delegate bufmodule.ModuleKeyProvider,
+	providers []Provider,
+) bufmodule.ModuleKeyProvider {
+	if len(providers) == 0 {
+		return delegate
+	}
+	return &moduleKeyProvider{
+		delegate:           delegate,
+		registryToProvider: getRegistryToProvider(providers),
+	}
+}
+
+// NewModuleDataProvider returns a new ModuleDataProvider that uses the Provider for the
+// registry of each ModuleKey, and the delegate for all other registries.
+//
+// If there are no Providers, the delegate is returned.
+func NewModuleDataProvider(
+	delegate bufmodule.ModuleDataProvider,
+	providers []Provider,
+) bufmodule.ModuleDataProvider {
+	if len(providers) == 0 {
+		return delegate
+	}
+	return &moduleDataProvider{
+		delegate:           delegate,
+		registryToProvider: getRegistryToProvider(providers),
+	}
+}
+
+// NewCommitProvider returns a new CommitProvider that uses the Provider for the
+// registry of each key, and the delegate for all other registries.
+//
+// If there are no Providers, the delegate is returned.
+func NewCommitProvider(
+	delegate bufmodule.CommitProvider,
+	providers []Provider,
+) bufmodule.CommitProvider {
+	if len(providers) == 0 {
+		return delegate
+	}
+	return &commitProvider{
+		delegate:           delegate,
+		registryToProvider: getRegistryToProvider(providers),
+	}
+}
+
+// NewGraphProvider returns a new GraphProvider that uses the Provider for the
+// registry of each ModuleKey, and the delegate for all other registries.
+//
+// If there are no Providers, the delegate is returned.
+func NewGraphProvider(
+	delegate bufmodule.GraphProvider,
+	providers []Provider,
+) bufmodule.GraphProvider {
+	if len(providers) == 0 {
+		return delegate
+	}
+	return &graphProvider{
+		delegate:           delegate,
+		registryToProvider: getRegistryToProvider(providers),
+	}
+}
+
+/// *** PRIVATE ***
+
+type moduleKeyProvider struct {
+	delegate           bufmodule.ModuleKeyProvider
+	registryToProvider map[string]Provider
+}
+
+func (p *moduleKeyProvider) GetModuleKeysForModuleRefs(
+	ctx context.Context,
+	moduleRefs []bufmodule.ModuleRef,
+	digestType bufmodule.DigestType,
+) ([]bufmodule.ModuleKey, error) {
+	return getForRegistries(
+		moduleRefs,
+		func(moduleRef bufmodule.ModuleRef) string {
+			return moduleRef.ModuleFullName().Registry()
+		},
+		p.registryToProvider,
+		func(moduleKeyProvider bufmodule.ModuleKeyProvider, moduleRefs []bufmodule.ModuleRef) ([]bufmodule.ModuleKey, error) {
+			return moduleKeyProvider.GetModuleKeysForModuleRefs(ctx, moduleRefs, digestType)
+		},
+		p.delegate,
+	)
+}
+
+type moduleDataProvider struct {
+	delegate           bufmodule.ModuleDataProvider
+	registryToProvider map[string]Provider
+}
+
+func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	return getForRegistries(
+		moduleKeys,
+		moduleKeyToRegistry,
+		p.registryToProvider,
+		func(moduleDataProvider bufmodule.ModuleDataProvider, moduleKeys []bufmodule.ModuleKey) ([]bufmodule.ModuleData, error) {
+			return moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+		},
+		p.delegate,
+	)
+}
+
+type commitProvider struct {
+	delegate           bufmodule.CommitProvider
+	registryToProvider map[string]Provider
+}
+
+func (p *commitProvider) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	return getForRegistries(
+		moduleKeys,
+		moduleKeyToRegistry,
+		p.registryToProvider,
+		func(commitProvider bufmodule.CommitProvider, moduleKeys []bufmodule.ModuleKey) ([]bufmodule.Commit, error) {
+			return commitProvider.GetCommitsForModuleKeys(ctx, moduleKeys)
+		},
+		p.delegate,
+	)
+}
+
+func (p *commitProvider) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	return getForRegistries(
+		commitKeys,
+		bufmodule.CommitKey.Registry,
+		p.registryToProvider,
+		func(commitProvider bufmodule.CommitProvider, commitKeys []bufmodule.CommitKey) ([]bufmodule.Commit, error) {
+			return commitProvider.GetCommitsForCommitKeys(ctx, commitKeys)
+		},
+		p.delegate,
+	)
+}
+
+type graphProvider struct {
+	delegate           bufmodule.GraphProvider
+	registryToProvider map[string]Provider
+}
+
+func (p *graphProvider) GetGraphForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) (*dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey], error) {
+	graph := dag.NewGraph[bufmodule.RegistryCommitID, bufmodule.ModuleKey](bufmodule.ModuleKeyToRegistryCommitID)
+	// The graphs of each registry are merged. Results are ignored, we only need the graphs.
+	if _, err := getForRegistries(
+		moduleKeys,
+		moduleKeyToRegistry,
+		p.registryToProvider,
+		func(graphProvider bufmodule.GraphProvider, moduleKeys []bufmodule.ModuleKey) ([]struct{}, error) {
+			registryGraph, err := graphProvider.GetGraphForModuleKeys(ctx, moduleKeys)
+			if err != nil {
+				return nil, err
+			}
+			if err := registryGraph.WalkNodes(
+				func(moduleKey bufmodule.ModuleKey, _ []bufmodule.ModuleKey, outboundModuleKeys []bufmodule.ModuleKey) error {
+					graph.AddNode(moduleKey)
+					for _, outboundModuleKey := range outboundModuleKeys {
+						graph.AddEdge(moduleKey, outboundModuleKey)
+					}
+					return nil
+				},
+			); err != nil {
+				return nil, err
+			}
+			return make([]struct{}, len(moduleKeys)), nil
+		},
+		p.delegate,
+	); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// getForRegistries splits the values by registry, calls get with the Provider for each
+// registry or the delegate for registries without a Provider, and returns the results
+// in the order of the values.
+func getForRegistries[T any, R any, P any](
+	values []T,
+	toRegistry func(T) string,
+	registryToProvider map[string]Provider,
+	get func(P, []T) ([]R, error),
+	delegate P,
+) ([]R, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	// The empty registry is used for the delegate, registries are never empty.
+	var registries []string
+	registryToIndexes := make(map[string][]int)
+	for i, value := range values {
+		registry := toRegistry(value)
+		if _, ok := registryToProvider[registry]; !ok {
+			registry = ""
+		}
+		if _, ok := registryToIndexes[registry]; !ok {
+			registries = append(registries, registry)
+		}
+		registryToIndexes[registry] = append(registryToIndexes[registry], i)
+	}
+	results := make([]R, len(values))
+	for _, registry := range registries {
+		indexes := registryToIndexes[registry]
+		registryValues := make([]T, len(indexes))
+		for i, index := range indexes {
+			registryValues[i] = values[index]
+		}
+		getter := delegate
+		if registry != "" {
+			provider, ok := registryToProvider[registry].(P)
+			if !ok {
+				return nil, syserror.Newf("Provider does not implement %T", getter)
+			}
+			getter = provider
+		}
+		registryResults, err := get(getter, registryValues)
+		if err != nil {
+			return nil, err
+		}
+		if len(registryResults) != len(registryValues) {
+			return nil, syserror.Newf("expected %d results, got %d", len(registryValues), len(registryResults))
+		}
+		for i, index := range indexes {
+			results[index] = registryResults[i]
+		}
+	}
+	return results, nil
+}
+
+func getRegistryToProvider(providers []Provider) map[string]Provider {
+	registryToProvider := make(map[string]Provider, len(providers))
+	for _, provider := range providers {
+		registryToProvider[provider.Registry()] = provider
+	}
+	return registryToProvider
+}
+
+func moduleKeyToRegistry(moduleKey bufmodule.ModuleKey) string {
+	return moduleKey.ModuleFullName().Registry()
+}
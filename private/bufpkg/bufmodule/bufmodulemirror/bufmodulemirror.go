@@ -0,0 +1,313 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulemirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/google/uuid"
+)
+
+// Mirror is an ordered list of remotes to download modules with a given prefix from.
+type Mirror interface {
+	// Prefix is the prefix of the ModuleFullNames this Mirror applies to.
+	//
+	// This is one of "registry", "registry/owner", or "registry/owner/name".
+	Prefix() string
+	// Remotes are the registries to try, in order.
+	//
+	// To fall back to the original registry, it must be included.
+	Remotes() []string
+
+	isMirror()
+}
+
+// NewMirror returns a new Mirror.
+func NewMirror(prefix string, remotes []string) (Mirror, error) {
+	return newMirror(prefix, remotes)
+}
+
+// NewModuleDataProvider returns a new ModuleDataProvider that downloads ModuleDatas
+// from mirrors.
+//
+// For every ModuleKey, the Mirror with the longest matching Prefix is used. The delegate
+// is called for each of the Mirror's Remotes in order, with the registry of the ModuleKey
+// replaced by the remote, until a remote returns ModuleData whose Digest matches the
+// Digest of the original ModuleKey. Mirrors therefore never change the content of a module,
+// a mirror that is unavailable or that serves different content is skipped.
+//
+// ModuleKeys that do not match any Mirror are passed to the delegate as-is.
+//
+// The returned ModuleDatas have the original ModuleKeys. Declared dependencies on a
+// remote are mapped back to the original registry.
+func NewModuleDataProvider(
+	logger *slog.Logger,
+	delegate bufmodule.ModuleDataProvider,
+	mirrors []Mirror,
+) (bufmodule.ModuleDataProvider, error) {
+	return newModuleDataProvider(logger, delegate, mirrors)
+}
+
+/// *** PRIVATE ***
+
+type mirror struct {
+	prefix  string
+	remotes []string
+}
+
+func newMirror(prefix string, remotes []string) (*mirror, error) {
+	components := strings.Split(prefix, "/")
+	if len(components) > 3 || slices.Contains(components, "") {
+		return nil, fmt.Errorf(`invalid mirror prefix %q: must be one of "registry", "registry/owner", or "registry/owner/name"`, prefix)
+	}
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("mirror for %q has no remotes", prefix)
+	}
+	for _, remote := range remotes {
+		if remote == "" || strings.Contains(remote, "/") {
+			return nil, fmt.Errorf("invalid remote %q for mirror %q: must be a registry hostname", remote, prefix)
+		}
+	}
+	return &mirror{
+		prefix:  prefix,
+		remotes: remotes,
+	}, nil
+}
+
+func (m *mirror) Prefix() string {
+	return m.prefix
+}
+
+func (m *mirror) Remotes() []string {
+	return m.remotes
+}
+
+func (*mirror) isMirror() {}
+
+type moduleDataProvider struct {
+	logger   *slog.Logger
+	delegate bufmodule.ModuleDataProvider
+	// Sorted by prefix length descending, so that the first match is the longest.
+	mirrors []Mirror
+}
+
+func newModuleDataProvider(
+	logger *slog.Logger,
+	delegate bufmodule.ModuleDataProvider,
+	mirrors []Mirror,
+) (*moduleDataProvider, error) {
+	prefixToMirror := make(map[string]Mirror, len(mirrors))
+	for _, mirror := range mirrors {
+		if _, ok := prefixToMirror[mirror.Prefix()]; ok {
+			return nil, fmt.Errorf("duplicate mirror prefix %q", mirror.Prefix())
+		}
+		prefixToMirror[mirror.Prefix()] = mirror
+	}
+	sortedMirrors := slicesext.Copy(mirrors)
+	sort.SliceStable(
+		sortedMirrors,
+		func(i int, j int) bool {
+			return len(sortedMirrors[i].Prefix()) > len(sortedMirrors[j].Prefix())
+		},
+	)
+	return &moduleDataProvider{
+		logger:   logger,
+		delegate: delegate,
+		mirrors:  sortedMirrors,
+	}, nil
+}
+
+func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	// The index of the Mirror for each ModuleKey, or -1 for no Mirror.
+	mirrorIndexToModuleKeys := make(map[int][]bufmodule.ModuleKey)
+	for _, moduleKey := range moduleKeys {
+		mirrorIndex := p.getMirrorIndex(moduleKey)
+		mirrorIndexToModuleKeys[mirrorIndex] = append(mirrorIndexToModuleKeys[mirrorIndex], moduleKey)
+	}
+	commitIDToModuleData := make(map[uuid.UUID]bufmodule.ModuleData, len(moduleKeys))
+	for mirrorIndex, mirrorModuleKeys := range mirrorIndexToModuleKeys {
+		var moduleDatas []bufmodule.ModuleData
+		var err error
+		if mirrorIndex < 0 {
+			moduleDatas, err = p.delegate.GetModuleDatasForModuleKeys(ctx, mirrorModuleKeys)
+		} else {
+			moduleDatas, err = p.getModuleDatasForMirror(ctx, p.mirrors[mirrorIndex], mirrorModuleKeys)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, moduleData := range moduleDatas {
+			commitIDToModuleData[moduleData.ModuleKey().CommitID()] = moduleData
+		}
+	}
+	// Restore the order of the input ModuleKeys.
+	return slicesext.MapError(
+		moduleKeys,
+		func(moduleKey bufmodule.ModuleKey) (bufmodule.ModuleData, error) {
+			moduleData, ok := commitIDToModuleData[moduleKey.CommitID()]
+			if !ok {
+				// This should never happen.
+				return nil, fmt.Errorf("no ModuleData returned for %s", moduleKey.String())
+			}
+			return moduleData, nil
+		},
+	)
+}
+
+func (p *moduleDataProvider) getMirrorIndex(moduleKey bufmodule.ModuleKey) int {
+	moduleFullNameString := moduleKey.ModuleFullName().String()
+	for i, mirror := range p.mirrors {
+		if moduleFullNameString == mirror.Prefix() || strings.HasPrefix(moduleFullNameString, mirror.Prefix()+"/") {
+			return i
+		}
+	}
+	return -1
+}
+
+// getModuleDatasForMirror tries each remote of the Mirror in order, until ModuleDatas
+// have been found and verified for all ModuleKeys.
+//
+// If no remote succeeds, the error from the last remote is returned.
+func (p *moduleDataProvider) getModuleDatasForMirror(
+	ctx context.Context,
+	mirror Mirror,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	var foundModuleDatas []bufmodule.ModuleData
+	remainingModuleKeys := moduleKeys
+	var lastErr error
+	for _, remote := range mirror.Remotes() {
+		remoteModuleKeys, err := slicesext.MapError(
+			remainingModuleKeys,
+			func(moduleKey bufmodule.ModuleKey) (bufmodule.ModuleKey, error) {
+				return withRegistry(moduleKey, remote)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		remoteModuleDatas, err := p.delegate.GetModuleDatasForModuleKeys(ctx, remoteModuleKeys)
+		if err != nil {
+			p.logger.WarnContext(
+				ctx,
+				"mirror remote failed",
+				slog.String("prefix", mirror.Prefix()),
+				slog.String("remote", remote),
+				slogext.ErrorAttr(err),
+			)
+			lastErr = err
+			continue
+		}
+		var notVerifiedModuleKeys []bufmodule.ModuleKey
+		for i, remoteModuleData := range remoteModuleDatas {
+			moduleData, err := verifyAndRestoreModuleData(ctx, remainingModuleKeys[i], remoteModuleData, remote)
+			if err != nil {
+				p.logger.WarnContext(
+					ctx,
+					"mirror remote served invalid module",
+					slog.String("prefix", mirror.Prefix()),
+					slog.String("remote", remote),
+					slog.String("moduleKey", remainingModuleKeys[i].String()),
+					slogext.ErrorAttr(err),
+				)
+				lastErr = err
+				notVerifiedModuleKeys = append(notVerifiedModuleKeys, remainingModuleKeys[i])
+				continue
+			}
+			foundModuleDatas = append(foundModuleDatas, moduleData)
+		}
+		remainingModuleKeys = notVerifiedModuleKeys
+		if len(remainingModuleKeys) == 0 {
+			return foundModuleDatas, nil
+		}
+	}
+	if lastErr == nil {
+		// This should never happen, as a Mirror always has remotes.
+		lastErr = errors.New("no remotes")
+	}
+	return nil, fmt.Errorf("all remotes for mirror %q failed: %w", mirror.Prefix(), lastErr)
+}
+
+// verifyAndRestoreModuleData verifies the ModuleData downloaded from the remote against
+// the Digest of the ModuleKey, and returns a ModuleData for the original ModuleKey.
+func verifyAndRestoreModuleData(
+	ctx context.Context,
+	moduleKey bufmodule.ModuleKey,
+	remoteModuleData bufmodule.ModuleData,
+	remote string,
+) (bufmodule.ModuleData, error) {
+	// Bucket checks the Digest of the remote ModuleKey, which is the Digest of the
+	// original ModuleKey. We do this eagerly so that we can fall back to the next remote.
+	if _, err := remoteModuleData.Bucket(); err != nil {
+		return nil, err
+	}
+	remoteDeclaredDepModuleKeys, err := remoteModuleData.DeclaredDepModuleKeys()
+	if err != nil {
+		return nil, err
+	}
+	declaredDepModuleKeys, err := slicesext.MapError(
+		remoteDeclaredDepModuleKeys,
+		func(depModuleKey bufmodule.ModuleKey) (bufmodule.ModuleKey, error) {
+			if depModuleKey.ModuleFullName().Registry() != remote {
+				return depModuleKey, nil
+			}
+			return withRegistry(depModuleKey, moduleKey.ModuleFullName().Registry())
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleData(
+		ctx,
+		moduleKey,
+		remoteModuleData.Bucket,
+		func() ([]bufmodule.ModuleKey, error) {
+			return declaredDepModuleKeys, nil
+		},
+		remoteModuleData.V1Beta1OrV1BufYAMLObjectData,
+		remoteModuleData.V1Beta1OrV1BufLockObjectData,
+	), nil
+}
+
+func withRegistry(moduleKey bufmodule.ModuleKey, registry string) (bufmodule.ModuleKey, error) {
+	if moduleKey.ModuleFullName().Registry() == registry {
+		return moduleKey, nil
+	}
+	moduleFullName, err := bufmodule.NewModuleFullName(
+		registry,
+		moduleKey.ModuleFullName().Owner(),
+		moduleKey.ModuleFullName().Name(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleKey(
+		moduleFullName,
+		moduleKey.CommitID(),
+		moduleKey.Digest,
+	)
+}
@@ -0,0 +1,169 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulemirror
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleDataProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	delegate := newTestRegistryModuleDataProvider(
+		t,
+		map[string]string{
+			"buf.build":          `syntax = "proto3"; package foo;`,
+			"mirror.example.com": `syntax = "proto3"; package foo;`,
+			"bad.example.com":    `syntax = "proto3"; package bar;`,
+		},
+	)
+	moduleKeys := delegate.getModuleKeys(t, ctx, "buf.build")
+
+	testModuleDataProvider(t, ctx, delegate, moduleKeys, []string{"mirror.example.com", "buf.build"}, "mirror.example.com")
+	// Unavailable remotes are skipped.
+	testModuleDataProvider(t, ctx, delegate, moduleKeys, []string{"down.example.com", "mirror.example.com"}, "mirror.example.com")
+	// Remotes that serve different content are skipped.
+	testModuleDataProvider(t, ctx, delegate, moduleKeys, []string{"bad.example.com", "buf.build"}, "buf.build")
+
+	mirror, err := NewMirror("buf.build/foo", []string{"bad.example.com"})
+	require.NoError(t, err)
+	moduleDataProvider, err := NewModuleDataProvider(slogtestext.NewLogger(t), delegate, []Mirror{mirror})
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	digestMismatchError := &bufmodule.DigestMismatchError{}
+	require.ErrorAs(t, err, &digestMismatchError)
+
+	// Mirrors that do not match are not used.
+	mirror, err = NewMirror("buf.build/bar", []string{"bad.example.com"})
+	require.NoError(t, err)
+	moduleDataProvider, err = NewModuleDataProvider(slogtestext.NewLogger(t), delegate, []Mirror{mirror})
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+}
+
+func TestNewMirrorError(t *testing.T) {
+	t.Parallel()
+	_, err := NewMirror("", []string{"buf.build"})
+	require.Error(t, err)
+	_, err = NewMirror("buf.build/foo/bar/baz", []string{"buf.build"})
+	require.Error(t, err)
+	_, err = NewMirror("buf.build//bar", []string{"buf.build"})
+	require.Error(t, err)
+	_, err = NewMirror("buf.build", nil)
+	require.Error(t, err)
+	_, err = NewMirror("buf.build", []string{"mirror.example.com/foo"})
+	require.Error(t, err)
+}
+
+func testModuleDataProvider(
+	t *testing.T,
+	ctx context.Context,
+	delegate *testRegistryModuleDataProvider,
+	moduleKeys []bufmodule.ModuleKey,
+	remotes []string,
+	expectedRemote string,
+) {
+	mirror, err := NewMirror("buf.build", remotes)
+	require.NoError(t, err)
+	moduleDataProvider, err := NewModuleDataProvider(slogtestext.NewLogger(t), delegate, []Mirror{mirror})
+	require.NoError(t, err)
+	delegate.registryToCalls = make(map[string]int)
+	moduleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		slicesext.Map(moduleKeys, bufmodule.ModuleKey.String),
+		slicesext.Map(
+			moduleDatas,
+			func(moduleData bufmodule.ModuleData) string {
+				return moduleData.ModuleKey().String()
+			},
+		),
+	)
+	for _, moduleData := range moduleDatas {
+		_, err := moduleData.Bucket()
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, delegate.registryToCalls[expectedRemote])
+}
+
+// testRegistryModuleDataProvider serves a module "foo" with the given content
+// for each registry.
+type testRegistryModuleDataProvider struct {
+	registryToOmniProvider map[string]bufmoduletesting.OmniProvider
+	registryToCalls        map[string]int
+}
+
+func newTestRegistryModuleDataProvider(
+	t *testing.T,
+	registryToContent map[string]string,
+) *testRegistryModuleDataProvider {
+	registryToOmniProvider := make(map[string]bufmoduletesting.OmniProvider)
+	for registry, content := range registryToContent {
+		omniProvider, err := bufmoduletesting.NewOmniProvider(
+			bufmoduletesting.ModuleData{
+				Name: registry + "/foo/foo",
+				PathToData: map[string][]byte{
+					"foo.proto": []byte(content),
+				},
+			},
+		)
+		require.NoError(t, err)
+		registryToOmniProvider[registry] = omniProvider
+	}
+	return &testRegistryModuleDataProvider{
+		registryToOmniProvider: registryToOmniProvider,
+		registryToCalls:        make(map[string]int),
+	}
+}
+
+func (p *testRegistryModuleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	registry := moduleKeys[0].ModuleFullName().Registry()
+	p.registryToCalls[registry]++
+	omniProvider, ok := p.registryToOmniProvider[registry]
+	if !ok {
+		return nil, fmt.Errorf("registry %s unavailable: %w", registry, fs.ErrNotExist)
+	}
+	return omniProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+}
+
+func (p *testRegistryModuleDataProvider) getModuleKeys(
+	t *testing.T,
+	ctx context.Context,
+	registry string,
+) []bufmodule.ModuleKey {
+	moduleRef, err := bufmodule.NewModuleRef(registry, "foo", "foo", "")
+	require.NoError(t, err)
+	moduleKeys, err := p.registryToOmniProvider[registry].GetModuleKeysForModuleRefs(
+		ctx,
+		[]bufmodule.ModuleRef{moduleRef},
+		bufmodule.DigestTypeB5,
+	)
+	require.NoError(t, err)
+	return moduleKeys
+}
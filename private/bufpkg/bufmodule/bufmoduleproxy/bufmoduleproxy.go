@@ -0,0 +1,93 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleproxy
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+)
+
+const (
+	// DirectProxyListEntry is the proxy list entry that gets ModuleDatas directly from the
+	// registry, using the direct ModuleDataProvider.
+	DirectProxyListEntry = "direct"
+	// OffProxyListEntry is the proxy list entry that disallows getting ModuleDatas.
+	OffProxyListEntry = "off"
+)
+
+// NewModuleDataProvider returns a new ModuleDataProvider that gets ModuleDatas through
+// the module proxies in the proxy list.
+//
+// The proxy list is analogous to GOPROXY. It is a list of module proxy URLs, or the
+// keywords "direct" and "off", separated by commas or pipes. Each entry is tried in
+// order:
+//
+//   - A module proxy URL gets ModuleDatas from the module proxy. If a module is not found,
+//     the next entry is tried. If the module proxy fails for any other reason, the next
+//     entry is only tried if the entry is followed by a pipe, otherwise the error is returned.
+//   - "direct" gets ModuleDatas from the direct ModuleDataProvider. Later entries are ignored.
+//   - "off" fails for any ModuleDatas not yet found. Later entries are ignored.
+//
+// For example, "https://proxy.example.com,direct" gets ModuleDatas from the module
+// proxy, and then falls back to the registry for modules the module proxy does not have.
+//
+// A module proxy serves the following HTTP API:
+//
+//   - GET {proxyURL}/{digestType}/{registry}/{owner}/{name}/{dashlessCommitID}.tar
+//     returns 200 with a tarball of the ModuleData, or 404 or 410 if the module proxy does
+//     not have the module.
+//
+// The tarball is in the format written by a ModuleDataStore created with
+// bufmodulestore.ModuleDataStoreWithTar, so a remote module cache populated by buf can be
+// served as a module proxy. The ModuleDatas returned are verified against the digests of
+// the ModuleKeys as with any other ModuleDataProvider.
+func NewModuleDataProvider(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	proxyList string,
+	direct bufmodule.ModuleDataProvider,
+	options ...ModuleDataProviderOption,
+) (bufmodule.ModuleDataProvider, error) {
+	return newModuleDataProvider(logger, httpClient, proxyList, direct, options...)
+}
+
+// ModuleDataProviderOption is an option for a new ModuleDataProvider.
+type ModuleDataProviderOption func(*moduleDataProviderOptions)
+
+// ModuleDataProviderWithHeader returns a new ModuleDataProviderOption that sets the given
+// header on every request to a module proxy.
+//
+// This is typically used to pass an authorization header to the module proxies.
+func ModuleDataProviderWithHeader(key string, value string) ModuleDataProviderOption {
+	return func(moduleDataProviderOptions *moduleDataProviderOptions) {
+		moduleDataProviderOptions.httpCacheBackendOptions = append(
+			moduleDataProviderOptions.httpCacheBackendOptions,
+			bufmodulestore.HTTPCacheBackendWithHeader(key, value),
+		)
+	}
+}
+
+/// *** PRIVATE ***
+
+type moduleDataProviderOptions struct {
+	httpCacheBackendOptions []bufmodulestore.HTTPCacheBackendOption
+}
+
+func newModuleDataProviderOptions() *moduleDataProviderOptions {
+	return &moduleDataProviderOptions{}
+}
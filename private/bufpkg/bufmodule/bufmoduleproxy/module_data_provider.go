@@ -0,0 +1,207 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/google/uuid"
+)
+
+type moduleDataProvider struct {
+	logger    *slog.Logger
+	proxyList string
+	entries   []*proxyEntry
+}
+
+func newModuleDataProvider(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	proxyList string,
+	direct bufmodule.ModuleDataProvider,
+	options ...ModuleDataProviderOption,
+) (*moduleDataProvider, error) {
+	moduleDataProviderOptions := newModuleDataProviderOptions()
+	for _, option := range options {
+		option(moduleDataProviderOptions)
+	}
+	entries, err := parseProxyList(
+		logger,
+		httpClient,
+		proxyList,
+		direct,
+		moduleDataProviderOptions.httpCacheBackendOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &moduleDataProvider{
+		logger:    logger,
+		proxyList: proxyList,
+		entries:   entries,
+	}, nil
+}
+
+func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	var foundModuleDatas []bufmodule.ModuleData
+	remainingModuleKeys := moduleKeys
+	for _, entry := range p.entries {
+		if len(remainingModuleKeys) == 0 {
+			break
+		}
+		switch {
+		case entry.direct != nil:
+			moduleDatas, err := entry.direct.GetModuleDatasForModuleKeys(ctx, remainingModuleKeys)
+			if err != nil {
+				return nil, err
+			}
+			foundModuleDatas = append(foundModuleDatas, moduleDatas...)
+			remainingModuleKeys = nil
+		case entry.store != nil:
+			moduleDatas, notFoundModuleKeys, err := entry.store.GetModuleDatasForModuleKeys(ctx, remainingModuleKeys)
+			if err != nil {
+				if !entry.fallbackOnError {
+					return nil, fmt.Errorf("module proxy %s: %w", entry.name, err)
+				}
+				p.logger.DebugContext(
+					ctx,
+					"module proxy failed, falling back",
+					slog.String("proxy", entry.name),
+					slogext.ErrorAttr(err),
+				)
+				continue
+			}
+			foundModuleDatas = append(foundModuleDatas, moduleDatas...)
+			remainingModuleKeys = notFoundModuleKeys
+		default:
+			return nil, fmt.Errorf(
+				"module downloads are disabled by module proxy list %q: %s",
+				p.proxyList,
+				moduleKeysString(remainingModuleKeys),
+			)
+		}
+	}
+	if len(remainingModuleKeys) > 0 {
+		return nil, fmt.Errorf(
+			"not found in any module proxy in %q: %s: %w",
+			p.proxyList,
+			moduleKeysString(remainingModuleKeys),
+			fs.ErrNotExist,
+		)
+	}
+	// Restore the order of the input ModuleKeys.
+	commitIDToModuleData, err := slicesext.ToUniqueValuesMapError(
+		foundModuleDatas,
+		func(moduleData bufmodule.ModuleData) (uuid.UUID, error) {
+			return moduleData.ModuleKey().CommitID(), nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return slicesext.MapError(
+		moduleKeys,
+		func(moduleKey bufmodule.ModuleKey) (bufmodule.ModuleData, error) {
+			moduleData, ok := commitIDToModuleData[moduleKey.CommitID()]
+			if !ok {
+				// This should never happen.
+				return nil, fmt.Errorf("no ModuleData returned for %s", moduleKey.String())
+			}
+			return moduleData, nil
+		},
+	)
+}
+
+type proxyEntry struct {
+	// name is the proxy list entry.
+	name string
+	// Exactly one of direct and store is set, unless this is the "off" entry.
+	direct bufmodule.ModuleDataProvider
+	store  bufmodulestore.ModuleDataStore
+	// fallbackOnError is true if the entry was followed by a pipe.
+	fallbackOnError bool
+}
+
+func parseProxyList(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	proxyList string,
+	direct bufmodule.ModuleDataProvider,
+	httpCacheBackendOptions []bufmodulestore.HTTPCacheBackendOption,
+) ([]*proxyEntry, error) {
+	var entries []*proxyEntry
+	remaining := proxyList
+	for remaining != "" {
+		name := remaining
+		var fallbackOnError bool
+		if i := strings.IndexAny(remaining, ",|"); i >= 0 {
+			name = remaining[:i]
+			fallbackOnError = remaining[i] == '|'
+			remaining = remaining[i+1:]
+			if remaining == "" {
+				return nil, fmt.Errorf("invalid module proxy list %q: trailing separator", proxyList)
+			}
+		} else {
+			remaining = ""
+		}
+		name = strings.TrimSpace(name)
+		entry := &proxyEntry{
+			name:            name,
+			fallbackOnError: fallbackOnError,
+		}
+		switch name {
+		case "":
+			return nil, fmt.Errorf("invalid module proxy list %q: empty entry", proxyList)
+		case DirectProxyListEntry:
+			entry.direct = direct
+		case OffProxyListEntry:
+		default:
+			cacheBackend, err := bufmodulestore.NewHTTPCacheBackend(
+				httpClient,
+				name,
+				httpCacheBackendOptions...,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("invalid module proxy list %q: %w", proxyList, err)
+			}
+			entry.store = bufmodulestore.NewCacheBackendModuleDataStore(
+				logger,
+				cacheBackend,
+			)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("module proxy list is empty")
+	}
+	return entries, nil
+}
+
+func moduleKeysString(moduleKeys []bufmodule.ModuleKey) string {
+	return strings.Join(slicesext.Map(moduleKeys, bufmodule.ModuleKey.String), ", ")
+}
@@ -0,0 +1,265 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleDataProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	bsrProvider, moduleKeys := testGetBSRProviderAndModuleKeys(t, ctx)
+	server := newTestProxyServer(t)
+	// The proxy only has mod1.
+	testPutModuleDatas(t, ctx, server, bsrProvider, moduleKeys[:1])
+
+	moduleDataProvider, err := NewModuleDataProvider(
+		logger,
+		server.Client(),
+		server.URL+",direct",
+		bsrProvider,
+	)
+	require.NoError(t, err)
+	moduleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"buf.build/foo/mod1",
+			"buf.build/foo/mod3",
+			"buf.build/foo/mod2",
+		},
+		slicesext.Map(
+			moduleDatas,
+			func(moduleData bufmodule.ModuleData) string {
+				return moduleData.ModuleKey().ModuleFullName().String()
+			},
+		),
+	)
+	require.Equal(t, 3, server.numGets())
+
+	moduleDataProvider, err = NewModuleDataProvider(
+		logger,
+		server.Client(),
+		server.URL,
+		bsrProvider,
+	)
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	moduleDataProvider, err = NewModuleDataProvider(
+		logger,
+		server.Client(),
+		server.URL+",off,direct",
+		bsrProvider,
+	)
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestModuleDataProviderFallbackOnError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	bsrProvider, moduleKeys := testGetBSRProviderAndModuleKeys(t, ctx)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.WriteHeader(http.StatusBadGateway)
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+
+	moduleDataProvider, err := NewModuleDataProvider(
+		logger,
+		server.Client(),
+		server.URL+",direct",
+		bsrProvider,
+	)
+	require.NoError(t, err)
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.Error(t, err)
+
+	moduleDataProvider, err = NewModuleDataProvider(
+		logger,
+		server.Client(),
+		server.URL+"|direct",
+		bsrProvider,
+	)
+	require.NoError(t, err)
+	moduleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, moduleDatas, 3)
+}
+
+func TestNewModuleDataProviderInvalidProxyList(t *testing.T) {
+	t.Parallel()
+	logger := slogtestext.NewLogger(t)
+	for _, proxyList := range []string{
+		"",
+		"direct,",
+		"https://proxy.example.com,,direct",
+		"ftp://proxy.example.com",
+		"proxy.example.com",
+	} {
+		_, err := NewModuleDataProvider(
+			logger,
+			http.DefaultClient,
+			proxyList,
+			bufmodule.NopModuleDataProvider,
+		)
+		require.Error(t, err, proxyList)
+	}
+}
+
+func testGetBSRProviderAndModuleKeys(t *testing.T, ctx context.Context) (bufmoduletesting.OmniProvider, []bufmodule.ModuleKey) {
+	bsrProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod1",
+			PathToData: map[string][]byte{
+				"mod1.proto": []byte(
+					`syntax = proto3; package mod1;`,
+				),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod2",
+			PathToData: map[string][]byte{
+				"mod2.proto": []byte(
+					`syntax = proto3; package mod2; import "mod1.proto";`,
+				),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod3",
+			PathToData: map[string][]byte{
+				"mod3.proto": []byte(
+					`syntax = proto3; package mod3;`,
+				),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleRefMod1, err := bufmodule.NewModuleRef("buf.build", "foo", "mod1", "")
+	require.NoError(t, err)
+	moduleRefMod2, err := bufmodule.NewModuleRef("buf.build", "foo", "mod2", "")
+	require.NoError(t, err)
+	moduleRefMod3, err := bufmodule.NewModuleRef("buf.build", "foo", "mod3", "")
+	require.NoError(t, err)
+	moduleKeys, err := bsrProvider.GetModuleKeysForModuleRefs(
+		ctx,
+		[]bufmodule.ModuleRef{
+			moduleRefMod1,
+			// Switching order on purpose.
+			moduleRefMod3,
+			moduleRefMod2,
+		},
+		bufmodule.DigestTypeB5,
+	)
+	require.NoError(t, err)
+	return bsrProvider, moduleKeys
+}
+
+func testPutModuleDatas(
+	t *testing.T,
+	ctx context.Context,
+	server *testProxyServer,
+	bsrProvider bufmodule.ModuleDataProvider,
+	moduleKeys []bufmodule.ModuleKey,
+) {
+	moduleDatas, err := bsrProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	cacheBackend, err := bufmodulestore.NewHTTPCacheBackend(server.Client(), server.URL)
+	require.NoError(t, err)
+	require.NoError(
+		t,
+		bufmodulestore.NewCacheBackendModuleDataStore(
+			slogtestext.NewLogger(t),
+			cacheBackend,
+		).PutModuleDatas(ctx, moduleDatas),
+	)
+}
+
+type testProxyServer struct {
+	*httptest.Server
+
+	keyToValue map[string][]byte
+	gets       int
+	lock       sync.Mutex
+}
+
+func newTestProxyServer(t *testing.T) *testProxyServer {
+	testProxyServer := &testProxyServer{
+		keyToValue: make(map[string][]byte),
+	}
+	testProxyServer.Server = httptest.NewServer(http.HandlerFunc(testProxyServer.serveHTTP))
+	t.Cleanup(testProxyServer.Close)
+	return testProxyServer
+}
+
+func (s *testProxyServer) serveHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := strings.TrimPrefix(request.URL.Path, "/")
+	switch request.Method {
+	case http.MethodGet:
+		s.gets++
+		value, ok := s.keyToValue[key]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusGone)
+			return
+		}
+		_, _ = responseWriter.Write(value)
+	case http.MethodPut:
+		value, err := io.ReadAll(request.Body)
+		if err != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.keyToValue[key] = value
+		responseWriter.WriteHeader(http.StatusCreated)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *testProxyServer) numGets() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.gets
+}
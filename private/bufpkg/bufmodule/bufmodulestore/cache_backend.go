@@ -0,0 +1,208 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+)
+
+// CacheBackend is a key/value backend for cached module data.
+//
+// Keys are normalized relative paths, such as "b5/buf.build/acme/weather/12345abcde.tar".
+// Values are opaque blobs. A CacheBackend does not need to validate the values it
+// stores, all values are verified against their expected digests when read.
+type CacheBackend interface {
+	// Get gets the value for the key.
+	//
+	// Returns an error that fulfills fs.ErrNotExist if the key is not present.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put puts the value for the key.
+	//
+	// Existing values may be overwritten.
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// NewHTTPCacheBackend returns a new CacheBackend that reads from and writes to a
+// remote HTTP cache.
+//
+// The HTTP API is intentionally minimal so that it can be served by any static file
+// server or generic HTTP cache:
+//
+//   - GET {baseURL}/{key} returns 200 with the value, or 404 or 410 if the key is not present.
+//   - PUT {baseURL}/{key} stores the request body as the value, and returns any 2xx status.
+func NewHTTPCacheBackend(
+	httpClient *http.Client,
+	baseURL string,
+	options ...HTTPCacheBackendOption,
+) (CacheBackend, error) {
+	return newHTTPCacheBackend(httpClient, baseURL, options...)
+}
+
+// NewBucketCacheBackend returns a new CacheBackend that reads from and writes to
+// the given bucket, such as an object storage bucket.
+//
+// Keys are used as paths within the bucket.
+func NewBucketCacheBackend(bucket storage.ReadWriteBucket) CacheBackend {
+	return newBucketCacheBackend(bucket)
+}
+
+// HTTPCacheBackendOption is an option for a new HTTP CacheBackend.
+type HTTPCacheBackendOption func(*httpCacheBackend)
+
+// HTTPCacheBackendWithHeader returns a new HTTPCacheBackendOption that sets the given
+// header on every request to the remote cache.
+//
+// This is typically used to pass an authorization header to the remote cache.
+func HTTPCacheBackendWithHeader(key string, value string) HTTPCacheBackendOption {
+	return func(httpCacheBackend *httpCacheBackend) {
+		httpCacheBackend.header.Add(key, value)
+	}
+}
+
+/// *** PRIVATE ***
+
+type httpCacheBackend struct {
+	httpClient *http.Client
+	baseURL    string
+	header     http.Header
+}
+
+func newHTTPCacheBackend(
+	httpClient *http.Client,
+	baseURL string,
+	options ...HTTPCacheBackendOption,
+) (*httpCacheBackend, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote cache URL %q: %w", baseURL, err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid remote cache URL %q: scheme must be http or https", baseURL)
+	}
+	httpCacheBackend := &httpCacheBackend{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		header:     make(http.Header),
+	}
+	for _, option := range options {
+		option(httpCacheBackend)
+	}
+	return httpCacheBackend, nil
+}
+
+func (h *httpCacheBackend) Get(ctx context.Context, key string) (_ []byte, retErr error) {
+	keyURL, err := h.getURL(key)
+	if err != nil {
+		return nil, err
+	}
+	request, err := h.newRequest(ctx, http.MethodGet, keyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	switch response.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(response.Body)
+	case http.StatusNotFound, http.StatusGone:
+		return nil, &fs.PathError{Op: "read", Path: key, Err: fs.ErrNotExist}
+	default:
+		return nil, fmt.Errorf("remote cache returned unexpected status %q for GET %s", response.Status, keyURL)
+	}
+}
+
+func (h *httpCacheBackend) Put(ctx context.Context, key string, value []byte) (retErr error) {
+	keyURL, err := h.getURL(key)
+	if err != nil {
+		return err
+	}
+	request, err := h.newRequest(ctx, http.MethodPut, keyURL, value)
+	if err != nil {
+		return err
+	}
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("remote cache returned unexpected status %q for PUT %s", response.Status, keyURL)
+	}
+	return nil
+}
+
+func (h *httpCacheBackend) getURL(key string) (string, error) {
+	key, err := normalpath.NormalizeAndValidate(key)
+	if err != nil {
+		return "", err
+	}
+	return h.baseURL + "/" + key, nil
+}
+
+func (h *httpCacheBackend) newRequest(ctx context.Context, method string, url string, body []byte) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range h.header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return request, nil
+}
+
+type bucketCacheBackend struct {
+	bucket storage.ReadWriteBucket
+}
+
+func newBucketCacheBackend(bucket storage.ReadWriteBucket) *bucketCacheBackend {
+	return &bucketCacheBackend{
+		bucket: bucket,
+	}
+}
+
+func (b *bucketCacheBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	return storage.ReadPath(ctx, b.bucket, key)
+}
+
+func (b *bucketCacheBackend) Put(ctx context.Context, key string, value []byte) error {
+	return storage.PutPath(ctx, b.bucket, key, value)
+}
@@ -0,0 +1,179 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+)
+
+// NewCacheBackendModuleDataStore returns a new ModuleDataStore that stores ModuleDatas
+// directly in the CacheBackend.
+//
+// ModuleDatas are stored in the CacheBackend as tarballs, keyed by the same paths that
+// a ModuleDataStore created with ModuleDataStoreWithTar uses, that is:
+//
+//	digestType/registry/owner/name/dashlessCommitID.tar
+//
+// Unlike a remote ModuleDataStore, errors from the CacheBackend other than a missing key
+// are returned.
+func NewCacheBackendModuleDataStore(
+	logger *slog.Logger,
+	backend CacheBackend,
+) ModuleDataStore {
+	return newCacheBackendModuleDataStore(logger, backend)
+}
+
+/// *** PRIVATE ***
+
+type cacheBackendModuleDataStore struct {
+	logger  *slog.Logger
+	backend CacheBackend
+}
+
+func newCacheBackendModuleDataStore(
+	logger *slog.Logger,
+	backend CacheBackend,
+) *cacheBackendModuleDataStore {
+	return &cacheBackendModuleDataStore{
+		logger:  logger,
+		backend: backend,
+	}
+}
+
+func (p *cacheBackendModuleDataStore) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, []bufmodule.ModuleKey, error) {
+	var foundModuleDatas []bufmodule.ModuleData
+	var notFoundModuleKeys []bufmodule.ModuleKey
+	for _, moduleKey := range moduleKeys {
+		moduleData, err := p.getModuleDataForModuleKey(ctx, moduleKey)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, nil, err
+			}
+			notFoundModuleKeys = append(notFoundModuleKeys, moduleKey)
+		} else {
+			foundModuleDatas = append(foundModuleDatas, moduleData)
+		}
+	}
+	return foundModuleDatas, notFoundModuleKeys, nil
+}
+
+func (p *cacheBackendModuleDataStore) PutModuleDatas(
+	ctx context.Context,
+	moduleDatas []bufmodule.ModuleData,
+) error {
+	for _, moduleData := range moduleDatas {
+		if err := p.putModuleData(ctx, moduleData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getModuleDataForModuleKey gets the tarball for the ModuleKey from the CacheBackend,
+// and then reads it using an in-memory tar ModuleDataStore, so that the CacheBackend and
+// local representations can never drift.
+//
+// Returns an error that fulfills fs.ErrNotExist if the ModuleKey was not found.
+func (p *cacheBackendModuleDataStore) getModuleDataForModuleKey(
+	ctx context.Context,
+	moduleKey bufmodule.ModuleKey,
+) (bufmodule.ModuleData, error) {
+	tarPath, err := getModuleDataStoreTarPath(moduleKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.backend.Get(ctx, tarPath)
+	p.logDebugModuleKey(
+		ctx,
+		moduleKey,
+		"cache backend module data store get",
+		slog.String("tarPath", tarPath),
+		slog.Bool("found", err == nil),
+		slogext.ErrorAttr(err),
+	)
+	if err != nil {
+		return nil, err
+	}
+	bucket := storagemem.NewReadWriteBucket()
+	if err := storage.PutPath(ctx, bucket, tarPath, data); err != nil {
+		return nil, err
+	}
+	foundModuleDatas, _, err := p.newTarModuleDataStore(bucket).GetModuleDatasForModuleKeys(
+		ctx,
+		[]bufmodule.ModuleKey{moduleKey},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(foundModuleDatas) != 1 {
+		// The tarball was present but invalid.
+		return nil, &fs.PathError{Op: "read", Path: tarPath, Err: fs.ErrNotExist}
+	}
+	return foundModuleDatas[0], nil
+}
+
+func (p *cacheBackendModuleDataStore) putModuleData(
+	ctx context.Context,
+	moduleData bufmodule.ModuleData,
+) error {
+	tarPath, err := getModuleDataStoreTarPath(moduleData.ModuleKey())
+	if err != nil {
+		return err
+	}
+	bucket := storagemem.NewReadWriteBucket()
+	if err := p.newTarModuleDataStore(bucket).PutModuleDatas(
+		ctx,
+		[]bufmodule.ModuleData{moduleData},
+	); err != nil {
+		return err
+	}
+	data, err := storage.ReadPath(ctx, bucket, tarPath)
+	if err != nil {
+		return err
+	}
+	p.logDebugModuleKey(
+		ctx,
+		moduleData.ModuleKey(),
+		"cache backend module data store put",
+		slog.String("tarPath", tarPath),
+	)
+	return p.backend.Put(ctx, tarPath, data)
+}
+
+func (p *cacheBackendModuleDataStore) newTarModuleDataStore(bucket storage.ReadWriteBucket) ModuleDataStore {
+	return newModuleDataStore(
+		p.logger,
+		bucket,
+		// The bucket is private to a single call, no locking needed.
+		filelock.NewNopLocker(),
+		ModuleDataStoreWithTar(),
+	)
+}
+
+func (p *cacheBackendModuleDataStore) logDebugModuleKey(ctx context.Context, moduleKey bufmodule.ModuleKey, message string, fields ...any) {
+	logDebugModuleKey(ctx, p.logger, moduleKey, message, fields...)
+}
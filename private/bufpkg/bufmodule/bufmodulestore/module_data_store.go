@@ -21,6 +21,7 @@ import (
 	"io/fs"
 	"log/slog"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/bufbuild/buf/private/pkg/filelock"
@@ -40,7 +41,10 @@ var (
 	externalModuleDataFilesDir     = "files"
 	externalModuleDataV1BufYAMLDir = "v1_buf_yaml"
 	externalModuleDataV1BufLockDir = "v1_buf_lock"
-	externalModuleDataLockFileExt  = ".lock"
+	// externalModuleDataFilesManifestFile is the Manifest of the files, used to report
+	// which files changed if a module in the store fails digest verification.
+	externalModuleDataFilesManifestFile = "files_manifest"
+	externalModuleDataLockFileExt       = ".lock"
 )
 
 // ModuleDatasResult is a result for a get of ModuleDatas.
@@ -100,6 +104,22 @@ func ModuleDataStoreWithTar() ModuleDataStoreOption {
 	}
 }
 
+// ModuleDataStoreWithFileDigestVerification returns a new ModuleDataStoreOption that
+// verifies the content of each file of a ModuleData read from the store against the
+// Manifest of the files written with the ModuleData, failing the read on mismatch.
+//
+// The Digest of a ModuleData is verified when its content is first accessed, but the files
+// are read from the store again afterwards, so without this option, content that changes
+// on disk after the ModuleData is verified is trusted. ModuleDatas put before the Manifest
+// was written to the store are not verified.
+//
+// The default is to not verify the content of each file.
+func ModuleDataStoreWithFileDigestVerification() ModuleDataStoreOption {
+	return func(moduleDataStore *moduleDataStore) {
+		moduleDataStore.fileDigestVerification = true
+	}
+}
+
 /// *** PRIVATE ***
 
 type moduleDataStore struct {
@@ -107,7 +127,8 @@ type moduleDataStore struct {
 	bucket storage.ReadWriteBucket
 	locker filelock.Locker
 
-	tar bool
+	tar                    bool
+	fileDigestVerification bool
 }
 
 func newModuleDataStore(
@@ -301,6 +322,23 @@ func (p *moduleDataStore) getModuleDataForModuleKey(
 			return nil, err
 		}
 	}
+	var moduleDataOptions []bufmodule.ModuleDataOption
+	var filesManifest bufcas.Manifest
+	if externalModuleData.FilesManifestFile != "" {
+		// The Manifest is only used for reporting and file digest verification, so we do not
+		// fail if it cannot be read.
+		filesManifest, err = readFilesManifest(ctx, moduleCacheBucket, externalModuleData.FilesManifestFile)
+		if err != nil {
+			p.logDebugModuleKey(
+				ctx,
+				moduleKey,
+				"module data store could not read files manifest",
+				slogext.ErrorAttr(err),
+			)
+		} else {
+			moduleDataOptions = append(moduleDataOptions, bufmodule.ModuleDataWithExpectedFilesManifest(filesManifest))
+		}
+	}
 	// We rely on the module.yaml file being the last file to be written in the store.
 	// If module.yaml does not exist, we act as if there is no value in the store, which will
 	// result in bad data being overwritten.
@@ -308,12 +346,14 @@ func (p *moduleDataStore) getModuleDataForModuleKey(
 		ctx,
 		moduleKey,
 		func() (storage.ReadBucket, error) {
-			return storage.StripReadBucketExternalPaths(
-				storage.MapReadBucket(
-					moduleCacheBucket,
-					storage.MapOnPrefix(externalModuleData.FilesDir),
-				),
-			), nil
+			filesBucket := storage.MapReadBucket(
+				moduleCacheBucket,
+				storage.MapOnPrefix(externalModuleData.FilesDir),
+			)
+			if p.fileDigestVerification && filesManifest != nil {
+				filesBucket = bufcas.NewDigestVerifyingReadBucket(filesBucket, filesManifest)
+			}
+			return storage.StripReadBucketExternalPaths(filesBucket), nil
 		},
 		func() ([]bufmodule.ModuleKey, error) {
 			return declaredDepModuleKeys, nil
@@ -324,6 +364,7 @@ func (p *moduleDataStore) getModuleDataForModuleKey(
 		func() (bufmodule.ObjectData, error) {
 			return v1BufLockObjectData, nil
 		},
+		moduleDataOptions...,
 	), nil
 }
 
@@ -494,6 +535,16 @@ func (p *moduleDataStore) putModuleData(
 		return err
 	}
 	externalModuleData.FilesDir = externalModuleDataFilesDir
+	// The files have been verified by moduleData.Bucket(), unless digest verification was
+	// disabled, so this is the Manifest of the last verified files.
+	filesManifest, err := bufcas.NewManifestForBucket(ctx, filesBucket)
+	if err != nil {
+		return err
+	}
+	if err := storage.PutPath(ctx, moduleCacheBucket, externalModuleDataFilesManifestFile, []byte(filesManifest.String())); err != nil {
+		return err
+	}
+	externalModuleData.FilesManifestFile = externalModuleDataFilesManifestFile
 
 	v1BufYAMLObjectData, err := moduleData.V1Beta1OrV1BufYAMLObjectData()
 	if err != nil {
@@ -705,6 +756,8 @@ type externalModuleData struct {
 	Deps          []externalModuleDataDep `json:"deps,omitempty" yaml:"deps,omitempty"`
 	V1BufYAMLFile string                  `json:"v1_buf_yaml_file,omitempty" yaml:"v1_buf_yaml_file,omitempty"`
 	V1BufLockFile string                  `json:"v1_buf_lock_file,omitempty" yaml:"v1_buf_lock_file,omitempty"`
+	// Optional, not present for modules put before this was added.
+	FilesManifestFile string `json:"files_manifest_file,omitempty" yaml:"files_manifest_file,omitempty"`
 }
 
 // isValid returns true if all the information we currently expect to be on
@@ -722,6 +775,14 @@ func (e externalModuleData) isValid() bool {
 		len(e.FilesDir) > 0
 }
 
+func readFilesManifest(ctx context.Context, bucket storage.ReadBucket, path string) (bufcas.Manifest, error) {
+	data, err := storage.ReadPath(ctx, bucket, path)
+	if err != nil {
+		return nil, err
+	}
+	return bufcas.ParseManifest(string(data))
+}
+
 // externalModuleDataDep represents a dependency.
 type externalModuleDataDep struct {
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
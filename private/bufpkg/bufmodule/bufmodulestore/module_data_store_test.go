@@ -18,6 +18,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
 	"github.com/bufbuild/buf/private/pkg/filelock"
@@ -45,6 +46,45 @@ func TestModuleDataStoreOS(t *testing.T) {
 	testModuleDataStoreOS(t)
 }
 
+func TestModuleDataStoreFileDigestVerification(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := storagemem.NewReadWriteBucket()
+	moduleDataStore := NewModuleDataStore(
+		slogtestext.NewLogger(t),
+		bucket,
+		filelock.NewNopLocker(),
+		ModuleDataStoreWithFileDigestVerification(),
+	)
+	moduleKeys, moduleDatas := testGetModuleKeysAndModuleDatas(t, ctx)
+	require.NoError(t, moduleDataStore.PutModuleDatas(ctx, moduleDatas))
+	foundModuleDatas, _, err := moduleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 1)
+	// This verifies the Digest of the ModuleData.
+	moduleDataBucket, err := foundModuleDatas[0].Bucket()
+	require.NoError(t, err)
+	data, err := storage.ReadPath(ctx, moduleDataBucket, "mod1.proto")
+	require.NoError(t, err)
+
+	// Modify the file in the store after the Digest of the ModuleData was verified.
+	dirPath, err := getModuleDataStoreDirPath(moduleKeys[0])
+	require.NoError(t, err)
+	require.NoError(
+		t,
+		storage.PutPath(
+			ctx,
+			bucket,
+			normalpath.Join(dirPath, externalModuleDataFilesDir, "mod1.proto"),
+			append(data, []byte(" message Foo {}")...),
+		),
+	)
+	_, err = storage.ReadPath(ctx, moduleDataBucket, "mod1.proto")
+	fileDigestMismatchError := &bufcas.FileDigestMismatchError{}
+	require.ErrorAs(t, err, &fileDigestMismatchError)
+	require.Equal(t, "mod1.proto", fileDigestMismatchError.Path)
+}
+
 func testModuleDataStoreBasic(t *testing.T, tar bool) {
 	bucket := storagemem.NewReadWriteBucket()
 	filelocker := filelock.NewNopLocker()
@@ -0,0 +1,154 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/google/uuid"
+)
+
+// NewRemoteModuleDataStore returns a new ModuleDataStore that reads through to the
+// CacheBackend for any ModuleKeys not found in the local ModuleDataStore.
+//
+// ModuleDatas found in the CacheBackend are put to the local ModuleDataStore. ModuleDatas
+// are only put to the CacheBackend if RemoteModuleDataStoreWithWrite is set.
+//
+// ModuleDatas are stored in the CacheBackend as tarballs, keyed by the same paths that
+// a ModuleDataStore created with ModuleDataStoreWithTar uses.
+//
+// Errors from the CacheBackend are logged and otherwise ignored. A remote cache is an
+// optimization, and an unavailable remote cache should never fail a build.
+func NewRemoteModuleDataStore(
+	logger *slog.Logger,
+	local ModuleDataStore,
+	backend CacheBackend,
+	options ...RemoteModuleDataStoreOption,
+) ModuleDataStore {
+	return newRemoteModuleDataStore(logger, local, backend, options...)
+}
+
+// RemoteModuleDataStoreOption is an option for a new remote ModuleDataStore.
+type RemoteModuleDataStoreOption func(*remoteModuleDataStore)
+
+// RemoteModuleDataStoreWithWrite returns a new RemoteModuleDataStoreOption that puts
+// ModuleDatas to the CacheBackend in addition to the local ModuleDataStore.
+//
+// The default is to only read from the CacheBackend.
+func RemoteModuleDataStoreWithWrite() RemoteModuleDataStoreOption {
+	return func(remoteModuleDataStore *remoteModuleDataStore) {
+		remoteModuleDataStore.write = true
+	}
+}
+
+/// *** PRIVATE ***
+
+type remoteModuleDataStore struct {
+	logger  *slog.Logger
+	local   ModuleDataStore
+	backend *cacheBackendModuleDataStore
+
+	write bool
+}
+
+func newRemoteModuleDataStore(
+	logger *slog.Logger,
+	local ModuleDataStore,
+	backend CacheBackend,
+	options ...RemoteModuleDataStoreOption,
+) *remoteModuleDataStore {
+	remoteModuleDataStore := &remoteModuleDataStore{
+		logger:  logger,
+		local:   local,
+		backend: newCacheBackendModuleDataStore(logger, backend),
+	}
+	for _, option := range options {
+		option(remoteModuleDataStore)
+	}
+	return remoteModuleDataStore
+}
+
+func (p *remoteModuleDataStore) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, []bufmodule.ModuleKey, error) {
+	foundModuleDatas, localNotFoundModuleKeys, err := p.local.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	var remoteFoundModuleDatas []bufmodule.ModuleData
+	var notFoundModuleKeys []bufmodule.ModuleKey
+	for _, moduleKey := range localNotFoundModuleKeys {
+		moduleData, err := p.backend.getModuleDataForModuleKey(ctx, moduleKey)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				p.logger.WarnContext(
+					ctx,
+					"remote cache read failed",
+					slog.String("moduleKey", moduleKey.String()),
+					slogext.ErrorAttr(err),
+				)
+			}
+			notFoundModuleKeys = append(notFoundModuleKeys, moduleKey)
+			continue
+		}
+		remoteFoundModuleDatas = append(remoteFoundModuleDatas, moduleData)
+	}
+	if len(remoteFoundModuleDatas) > 0 {
+		if err := p.local.PutModuleDatas(ctx, remoteFoundModuleDatas); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Restore the order of the input ModuleKeys.
+	commitIDToModuleData := make(map[uuid.UUID]bufmodule.ModuleData, len(foundModuleDatas)+len(remoteFoundModuleDatas))
+	for _, moduleData := range append(foundModuleDatas, remoteFoundModuleDatas...) {
+		commitIDToModuleData[moduleData.ModuleKey().CommitID()] = moduleData
+	}
+	orderedFoundModuleDatas := make([]bufmodule.ModuleData, 0, len(commitIDToModuleData))
+	for _, moduleKey := range moduleKeys {
+		if moduleData, ok := commitIDToModuleData[moduleKey.CommitID()]; ok {
+			orderedFoundModuleDatas = append(orderedFoundModuleDatas, moduleData)
+		}
+	}
+	return orderedFoundModuleDatas, notFoundModuleKeys, nil
+}
+
+func (p *remoteModuleDataStore) PutModuleDatas(
+	ctx context.Context,
+	moduleDatas []bufmodule.ModuleData,
+) error {
+	if err := p.local.PutModuleDatas(ctx, moduleDatas); err != nil {
+		return err
+	}
+	if !p.write {
+		return nil
+	}
+	for _, moduleData := range moduleDatas {
+		if err := p.backend.putModuleData(ctx, moduleData); err != nil {
+			p.logger.WarnContext(
+				ctx,
+				"remote cache write failed",
+				slog.String("moduleKey", moduleData.ModuleKey().String()),
+				slogext.ErrorAttr(err),
+			)
+		}
+	}
+	return nil
+}
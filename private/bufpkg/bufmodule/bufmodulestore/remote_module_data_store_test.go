@@ -0,0 +1,219 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteModuleDataStoreHTTP(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	server := newTestHTTPCacheServer(t)
+	moduleKeys, moduleDatas := testGetModuleKeysAndModuleDatas(t, ctx)
+
+	// The writer has write access to the remote cache, the reader does not.
+	writerBackend, err := NewHTTPCacheBackend(server.Client(), server.URL)
+	require.NoError(t, err)
+	writerModuleDataStore := NewRemoteModuleDataStore(
+		logger,
+		NewModuleDataStore(logger, storagemem.NewReadWriteBucket(), filelock.NewNopLocker()),
+		writerBackend,
+		RemoteModuleDataStoreWithWrite(),
+	)
+	readerBackend, err := NewHTTPCacheBackend(server.Client(), server.URL+"/")
+	require.NoError(t, err)
+	readerLocalModuleDataStore := NewModuleDataStore(logger, storagemem.NewReadWriteBucket(), filelock.NewNopLocker())
+	readerModuleDataStore := NewRemoteModuleDataStore(
+		logger,
+		readerLocalModuleDataStore,
+		readerBackend,
+	)
+
+	foundModuleDatas, notFoundModuleKeys, err := readerModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	testRequireModuleDataNamesEqual(t, nil, foundModuleDatas)
+	testRequireModuleKeyNamesEqual(
+		t,
+		[]string{
+			"buf.build/foo/mod1",
+			"buf.build/foo/mod3",
+			"buf.build/foo/mod2",
+		},
+		notFoundModuleKeys,
+	)
+
+	// Only put mod1 and mod2 remotely.
+	require.NoError(t, writerModuleDataStore.PutModuleDatas(ctx, []bufmodule.ModuleData{moduleDatas[0], moduleDatas[2]}))
+	require.Equal(t, 2, server.numObjects())
+	// The reader cannot write, so putting mod3 does not change the remote cache.
+	require.NoError(t, readerModuleDataStore.PutModuleDatas(ctx, moduleDatas[1:2]))
+	require.Equal(t, 2, server.numObjects())
+
+	foundModuleDatas, notFoundModuleKeys, err = readerModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	testRequireModuleDataNamesEqual(
+		t,
+		[]string{
+			"buf.build/foo/mod1",
+			"buf.build/foo/mod3",
+			"buf.build/foo/mod2",
+		},
+		foundModuleDatas,
+	)
+	testRequireModuleKeyNamesEqual(t, nil, notFoundModuleKeys)
+	for _, moduleData := range foundModuleDatas {
+		bucket, err := moduleData.Bucket()
+		require.NoError(t, err)
+		paths, err := storage.AllPaths(ctx, bucket, "")
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+	}
+
+	// Remote hits are read through to the local store.
+	foundModuleDatas, notFoundModuleKeys, err = readerLocalModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 3)
+	testRequireModuleKeyNamesEqual(t, nil, notFoundModuleKeys)
+}
+
+func TestRemoteModuleDataStoreBucket(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	cacheBucket := storagemem.NewReadWriteBucket()
+	moduleKeys, moduleDatas := testGetModuleKeysAndModuleDatas(t, ctx)
+	writerModuleDataStore := NewRemoteModuleDataStore(
+		logger,
+		NewModuleDataStore(logger, storagemem.NewReadWriteBucket(), filelock.NewNopLocker()),
+		NewBucketCacheBackend(cacheBucket),
+		RemoteModuleDataStoreWithWrite(),
+	)
+	require.NoError(t, writerModuleDataStore.PutModuleDatas(ctx, moduleDatas))
+	paths, err := storage.AllPaths(ctx, cacheBucket, "")
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+
+	readerModuleDataStore := NewRemoteModuleDataStore(
+		logger,
+		NewModuleDataStore(logger, storagemem.NewReadWriteBucket(), filelock.NewNopLocker()),
+		NewBucketCacheBackend(cacheBucket),
+	)
+	foundModuleDatas, notFoundModuleKeys, err := readerModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 3)
+	testRequireModuleKeyNamesEqual(t, nil, notFoundModuleKeys)
+}
+
+func TestRemoteModuleDataStoreUnavailable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.WriteHeader(http.StatusInternalServerError)
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	moduleKeys, moduleDatas := testGetModuleKeysAndModuleDatas(t, ctx)
+	backend, err := NewHTTPCacheBackend(server.Client(), server.URL)
+	require.NoError(t, err)
+	moduleDataStore := NewRemoteModuleDataStore(
+		logger,
+		NewModuleDataStore(logger, storagemem.NewReadWriteBucket(), filelock.NewNopLocker()),
+		backend,
+		RemoteModuleDataStoreWithWrite(),
+	)
+	// Errors from the remote cache are never surfaced.
+	_, notFoundModuleKeys, err := moduleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, notFoundModuleKeys, 3)
+	require.NoError(t, moduleDataStore.PutModuleDatas(ctx, moduleDatas))
+	foundModuleDatas, notFoundModuleKeys, err := moduleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 3)
+	require.Len(t, notFoundModuleKeys, 0)
+}
+
+func TestNewHTTPCacheBackendInvalidURL(t *testing.T) {
+	t.Parallel()
+	_, err := NewHTTPCacheBackend(http.DefaultClient, "ftp://example.com/cache")
+	require.Error(t, err)
+	_, err = NewHTTPCacheBackend(http.DefaultClient, "example.com/cache")
+	require.Error(t, err)
+}
+
+type testHTTPCacheServer struct {
+	*httptest.Server
+
+	keyToValue map[string][]byte
+	lock       sync.Mutex
+}
+
+func newTestHTTPCacheServer(t *testing.T) *testHTTPCacheServer {
+	testHTTPCacheServer := &testHTTPCacheServer{
+		keyToValue: make(map[string][]byte),
+	}
+	testHTTPCacheServer.Server = httptest.NewServer(http.HandlerFunc(testHTTPCacheServer.serveHTTP))
+	t.Cleanup(testHTTPCacheServer.Close)
+	return testHTTPCacheServer
+}
+
+func (s *testHTTPCacheServer) serveHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := strings.TrimPrefix(request.URL.Path, "/")
+	switch request.Method {
+	case http.MethodGet:
+		value, ok := s.keyToValue[key]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = responseWriter.Write(value)
+	case http.MethodPut:
+		value, err := io.ReadAll(request.Body)
+		if err != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.keyToValue[key] = value
+		responseWriter.WriteHeader(http.StatusCreated)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *testHTTPCacheServer) numObjects() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.keyToValue)
+}
@@ -0,0 +1,160 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DigestVerificationStrict fails with a *DigestMismatchError if the content of a
+	// ModuleData does not match the Digest of its ModuleKey.
+	//
+	// This is the default.
+	DigestVerificationStrict DigestVerification = iota + 1
+	// DigestVerificationWarn logs the *DigestMismatchError as a warning and uses the
+	// content of the ModuleData anyways.
+	DigestVerificationWarn
+	// DigestVerificationOff does not verify the content of a ModuleData.
+	DigestVerificationOff
+)
+
+var (
+	// AllDigestVerifications are all known DigestVerifications.
+	AllDigestVerifications = []DigestVerification{
+		DigestVerificationStrict,
+		DigestVerificationWarn,
+		DigestVerificationOff,
+	}
+	digestVerificationToString = map[DigestVerification]string{
+		DigestVerificationStrict: "strict",
+		DigestVerificationWarn:   "warn",
+		DigestVerificationOff:    "off",
+	}
+	stringToDigestVerification = map[string]DigestVerification{
+		"strict": DigestVerificationStrict,
+		"warn":   DigestVerificationWarn,
+		"off":    DigestVerificationOff,
+	}
+)
+
+// DigestVerification is a policy for what happens when the content of a ModuleData does
+// not match the Digest of its ModuleKey.
+type DigestVerification int
+
+// String prints the string representation of the DigestVerification.
+func (d DigestVerification) String() string {
+	s, ok := digestVerificationToString[d]
+	if !ok {
+		return strconv.Itoa(int(d))
+	}
+	return s
+}
+
+// ParseDigestVerification parses a DigestVerification from its string representation.
+//
+// This reverses DigestVerification.String().
+//
+// Returns an error of type *ParseError if the string could not be parsed.
+func ParseDigestVerification(s string) (DigestVerification, error) {
+	d, ok := stringToDigestVerification[s]
+	if !ok {
+		return 0, &ParseError{
+			typeString: "digest verification",
+			input:      s,
+			err:        fmt.Errorf("must be one of strict, warn, or off, got %q", s),
+		}
+	}
+	return d, nil
+}
+
+// NewDigestVerificationModuleDataProvider returns a new ModuleDataProvider that applies
+// the DigestVerification to all ModuleDatas returned by the delegate.
+//
+// If the DigestVerification is DigestVerificationStrict, the delegate is returned, as
+// all ModuleDatas are strictly verified by default.
+func NewDigestVerificationModuleDataProvider(
+	logger *slog.Logger,
+	delegate ModuleDataProvider,
+	digestVerification DigestVerification,
+) ModuleDataProvider {
+	if digestVerification == DigestVerificationStrict {
+		return delegate
+	}
+	return &digestVerificationModuleDataProvider{
+		logger:             logger,
+		delegate:           delegate,
+		digestVerification: digestVerification,
+	}
+}
+
+// *** PRIVATE ***
+
+type digestVerificationModuleDataProvider struct {
+	logger             *slog.Logger
+	delegate           ModuleDataProvider
+	digestVerification DigestVerification
+}
+
+func (p *digestVerificationModuleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []ModuleKey,
+) ([]ModuleData, error) {
+	moduleDatas, err := p.delegate.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i, moduleData := range moduleDatas {
+		moduleDatas[i] = p.applyDigestVerification(ctx, moduleData)
+	}
+	return moduleDatas, nil
+}
+
+func (p *digestVerificationModuleDataProvider) applyDigestVerification(
+	ctx context.Context,
+	in ModuleData,
+) ModuleData {
+	// ModuleData can only be implemented within this package.
+	original, ok := in.(*moduleData)
+	if !ok {
+		return in
+	}
+	// Copy so that the ModuleData returned by the delegate, which may be shared, is unaffected.
+	modified := *original
+	switch p.digestVerification {
+	case DigestVerificationWarn:
+		modified.checkDigest = sync.OnceValue(
+			func() error {
+				err := original.verifyDigest()
+				digestMismatchError := &DigestMismatchError{}
+				if errors.As(err, &digestMismatchError) {
+					p.logger.WarnContext(ctx, digestMismatchError.Error())
+					return nil
+				}
+				return err
+			},
+		)
+	case DigestVerificationOff:
+		modified.checkDigest = func() error {
+			return nil
+		}
+	}
+	return &modified
+}
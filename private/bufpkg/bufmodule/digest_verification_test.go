@@ -0,0 +1,148 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestVerification(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := slogtestext.NewLogger(t)
+	omniProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/mod1",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a;`),
+				"b.proto": []byte(`syntax = "proto3"; package b;`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleRef, err := bufmodule.NewModuleRef("buf.build", "foo", "mod1", "")
+	require.NoError(t, err)
+	moduleKeys, err := omniProvider.GetModuleKeysForModuleRefs(ctx, []bufmodule.ModuleRef{moduleRef}, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	expectedBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package a;`),
+			"b.proto": []byte(`syntax = "proto3"; package b;`),
+		},
+	)
+	require.NoError(t, err)
+	expectedFilesManifest, err := bufcas.NewManifestForBucket(ctx, expectedBucket)
+	require.NoError(t, err)
+	tamperedBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package tampered;`),
+			"c.proto": []byte(`syntax = "proto3"; package c;`),
+		},
+	)
+	require.NoError(t, err)
+	tamperedModuleDataProvider := &testModuleDataProvider{
+		getModuleData: func(moduleKey bufmodule.ModuleKey) bufmodule.ModuleData {
+			return bufmodule.NewModuleData(
+				ctx,
+				moduleKey,
+				func() (storage.ReadBucket, error) {
+					return tamperedBucket, nil
+				},
+				func() ([]bufmodule.ModuleKey, error) {
+					return nil, nil
+				},
+				func() (bufmodule.ObjectData, error) {
+					return nil, nil
+				},
+				func() (bufmodule.ObjectData, error) {
+					return nil, nil
+				},
+				bufmodule.ModuleDataWithExpectedFilesManifest(expectedFilesManifest),
+			)
+		},
+	}
+
+	moduleDatas, err := bufmodule.NewDigestVerificationModuleDataProvider(
+		logger,
+		tamperedModuleDataProvider,
+		bufmodule.DigestVerificationStrict,
+	).GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	_, err = moduleDatas[0].Bucket()
+	digestMismatchError := &bufmodule.DigestMismatchError{}
+	require.True(t, errors.As(err, &digestMismatchError))
+	require.Len(t, digestMismatchError.Files, 3)
+	require.Equal(t, "a.proto", digestMismatchError.Files[0].Path)
+	require.NotNil(t, digestMismatchError.Files[0].ExpectedDigest)
+	require.NotNil(t, digestMismatchError.Files[0].ActualDigest)
+	require.Equal(t, "b.proto", digestMismatchError.Files[1].Path)
+	require.Nil(t, digestMismatchError.Files[1].ActualDigest)
+	require.Equal(t, "c.proto", digestMismatchError.Files[2].Path)
+	require.Nil(t, digestMismatchError.Files[2].ExpectedDigest)
+	require.Contains(t, err.Error(), "b.proto: missing file")
+
+	for _, digestVerification := range []bufmodule.DigestVerification{
+		bufmodule.DigestVerificationWarn,
+		bufmodule.DigestVerificationOff,
+	} {
+		moduleDatas, err := bufmodule.NewDigestVerificationModuleDataProvider(
+			logger,
+			tamperedModuleDataProvider,
+			digestVerification,
+		).GetModuleDatasForModuleKeys(ctx, moduleKeys)
+		require.NoError(t, err)
+		bucket, err := moduleDatas[0].Bucket()
+		require.NoError(t, err, digestVerification.String())
+		data, err := storage.ReadPath(ctx, bucket, "a.proto")
+		require.NoError(t, err)
+		require.Equal(t, `syntax = "proto3"; package tampered;`, string(data))
+	}
+}
+
+func TestParseDigestVerification(t *testing.T) {
+	t.Parallel()
+	for _, digestVerification := range bufmodule.AllDigestVerifications {
+		parsedDigestVerification, err := bufmodule.ParseDigestVerification(digestVerification.String())
+		require.NoError(t, err)
+		require.Equal(t, digestVerification, parsedDigestVerification)
+	}
+	_, err := bufmodule.ParseDigestVerification("lenient")
+	require.Error(t, err)
+}
+
+type testModuleDataProvider struct {
+	getModuleData func(bufmodule.ModuleKey) bufmodule.ModuleData
+}
+
+func (p *testModuleDataProvider) GetModuleDatasForModuleKeys(
+	_ context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	moduleDatas := make([]bufmodule.ModuleData, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		moduleDatas[i] = p.getModuleData(moduleKey)
+	}
+	return moduleDatas, nil
+}
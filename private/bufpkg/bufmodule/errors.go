@@ -16,9 +16,11 @@ package bufmodule
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 	"github.com/google/uuid"
 )
@@ -226,6 +228,11 @@ type DigestMismatchError struct {
 	CommitID       uuid.UUID
 	ExpectedDigest Digest
 	ActualDigest   Digest
+	// Files are the files that differ from the files of the Module when it was last verified.
+	//
+	// This is only set if the files of the last verified Module are known, for example if
+	// the Module was read from the cache.
+	Files []*DigestMismatchFile
 }
 
 // Error implements the error interface.
@@ -258,6 +265,16 @@ func (m *DigestMismatchError) Error() string {
 		_, _ = builder.WriteString(`"`)
 		_, _ = builder.WriteString("\n")
 	}
+	if len(m.Files) > 0 {
+		_, _ = builder.WriteString("\t")
+		_, _ = builder.WriteString(`Files that differ from the last verified copy:`)
+		_, _ = builder.WriteString("\n")
+		for _, file := range m.Files {
+			_, _ = builder.WriteString("\t\t")
+			_, _ = builder.WriteString(file.String())
+			_, _ = builder.WriteString("\n")
+		}
+	}
 	_, _ = builder.WriteString("\t")
 	_, _ = builder.WriteString(`This may be the result of a hand-edited or corrupted buf.lock file, a corrupted local cache, and/or an attack.`)
 	_, _ = builder.WriteString("\n")
@@ -265,3 +282,24 @@ func (m *DigestMismatchError) Error() string {
 	_, _ = builder.WriteString(`To clear your local cache, run "buf registry cc".`)
 	return builder.String()
 }
+
+// DigestMismatchFile is a file that differs between the expected and actual files of a Module.
+type DigestMismatchFile struct {
+	Path string
+	// ExpectedDigest is nil if the file was not expected.
+	ExpectedDigest bufcas.Digest
+	// ActualDigest is nil if the file is missing.
+	ActualDigest bufcas.Digest
+}
+
+// String prints the path and the expected and actual digests of the file.
+func (d *DigestMismatchFile) String() string {
+	switch {
+	case d.ExpectedDigest == nil:
+		return fmt.Sprintf(`%s: unexpected file with digest "%s"`, d.Path, d.ActualDigest.String())
+	case d.ActualDigest == nil:
+		return fmt.Sprintf(`%s: missing file with expected digest "%s"`, d.Path, d.ExpectedDigest.String())
+	default:
+		return fmt.Sprintf(`%s: expected digest "%s", actual digest "%s"`, d.Path, d.ExpectedDigest.String(), d.ActualDigest.String())
+	}
+}
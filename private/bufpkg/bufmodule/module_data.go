@@ -16,8 +16,10 @@ package bufmodule
 
 import (
 	"context"
+	"sort"
 	"sync"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
 
@@ -72,6 +74,7 @@ func NewModuleData(
 	getDeclaredDepModuleKeys func() ([]ModuleKey, error),
 	getV1BufYAMLObjectData func() (ObjectData, error),
 	getV1BufLockObjectData func() (ObjectData, error),
+	options ...ModuleDataOption,
 ) ModuleData {
 	return newModuleData(
 		ctx,
@@ -80,9 +83,25 @@ func NewModuleData(
 		getDeclaredDepModuleKeys,
 		getV1BufYAMLObjectData,
 		getV1BufLockObjectData,
+		options...,
 	)
 }
 
+// ModuleDataOption is an option for a new ModuleData.
+type ModuleDataOption func(*moduleDataOptions)
+
+// ModuleDataWithExpectedFilesManifest returns a new ModuleDataOption that sets the Manifest
+// of the files of the ModuleData when it was last verified, for example when it was put
+// to a cache.
+//
+// This is only used to report the files that changed if the ModuleData fails digest
+// verification. The Manifest is not trusted for verification.
+func ModuleDataWithExpectedFilesManifest(expectedFilesManifest bufcas.Manifest) ModuleDataOption {
+	return func(moduleDataOptions *moduleDataOptions) {
+		moduleDataOptions.expectedFilesManifest = expectedFilesManifest
+	}
+}
+
 // *** PRIVATE ***
 
 // moduleData
@@ -94,6 +113,10 @@ type moduleData struct {
 	getV1BufYAMLObjectData   func() (ObjectData, error)
 	getV1BufLockObjectData   func() (ObjectData, error)
 
+	// verifyDigest verifies the content against the Digest of the ModuleKey.
+	verifyDigest func() error
+	// checkDigest is called before returning any content, and applies the DigestVerification
+	// to verifyDigest. By default, this is verifyDigest.
 	checkDigest func() error
 }
 
@@ -104,7 +127,12 @@ func newModuleData(
 	getDeclaredDepModuleKeys func() ([]ModuleKey, error),
 	getV1BufYAMLObjectData func() (ObjectData, error),
 	getV1BufLockObjectData func() (ObjectData, error),
+	options ...ModuleDataOption,
 ) *moduleData {
+	moduleDataOptions := newModuleDataOptions()
+	for _, option := range options {
+		option(moduleDataOptions)
+	}
 	moduleData := &moduleData{
 		moduleKey:                moduleKey,
 		getBucket:                getSyncOnceValuesGetBucketWithStorageMatcherApplied(ctx, getBucket),
@@ -112,7 +140,7 @@ func newModuleData(
 		getV1BufYAMLObjectData:   sync.OnceValues(getV1BufYAMLObjectData),
 		getV1BufLockObjectData:   sync.OnceValues(getV1BufLockObjectData),
 	}
-	moduleData.checkDigest = sync.OnceValue(
+	moduleData.verifyDigest = sync.OnceValue(
 		func() error {
 			// We have to use the get.* functions so that we don't invoke checkDigest.
 			bucket, err := moduleData.getBucket()
@@ -165,16 +193,24 @@ func newModuleData(
 				}
 			}
 			if !DigestEqual(expectedDigest, actualDigest) {
-				return &DigestMismatchError{
+				digestMismatchError := &DigestMismatchError{
 					ModuleFullName: moduleKey.ModuleFullName(),
 					CommitID:       moduleKey.CommitID(),
 					ExpectedDigest: expectedDigest,
 					ActualDigest:   actualDigest,
 				}
+				if moduleDataOptions.expectedFilesManifest != nil {
+					digestMismatchError.Files, err = getDigestMismatchFiles(ctx, bucket, moduleDataOptions.expectedFilesManifest)
+					if err != nil {
+						return err
+					}
+				}
+				return digestMismatchError
 			}
 			return nil
 		},
 	)
+	moduleData.checkDigest = moduleData.verifyDigest
 	return moduleData
 }
 
@@ -218,3 +254,56 @@ func (m *moduleData) V1Beta1OrV1BufLockObjectData() (ObjectData, error) {
 }
 
 func (*moduleData) isModuleData() {}
+
+type moduleDataOptions struct {
+	expectedFilesManifest bufcas.Manifest
+}
+
+func newModuleDataOptions() *moduleDataOptions {
+	return &moduleDataOptions{}
+}
+
+// getDigestMismatchFiles returns the files that differ between the bucket and the expected Manifest.
+func getDigestMismatchFiles(
+	ctx context.Context,
+	bucketWithStorageMatcherApplied storage.ReadBucket,
+	expectedFilesManifest bufcas.Manifest,
+) ([]*DigestMismatchFile, error) {
+	actualFilesManifest, err := bufcas.NewManifestForBucket(ctx, bucketWithStorageMatcherApplied)
+	if err != nil {
+		return nil, err
+	}
+	var digestMismatchFiles []*DigestMismatchFile
+	for _, expectedFileNode := range expectedFilesManifest.FileNodes() {
+		actualDigest := actualFilesManifest.GetDigest(expectedFileNode.Path())
+		if actualDigest != nil && bufcas.DigestEqual(expectedFileNode.Digest(), actualDigest) {
+			continue
+		}
+		digestMismatchFiles = append(
+			digestMismatchFiles,
+			&DigestMismatchFile{
+				Path:           expectedFileNode.Path(),
+				ExpectedDigest: expectedFileNode.Digest(),
+				ActualDigest:   actualDigest,
+			},
+		)
+	}
+	for _, actualFileNode := range actualFilesManifest.FileNodes() {
+		if expectedFilesManifest.GetFileNode(actualFileNode.Path()) == nil {
+			digestMismatchFiles = append(
+				digestMismatchFiles,
+				&DigestMismatchFile{
+					Path:         actualFileNode.Path(),
+					ActualDigest: actualFileNode.Digest(),
+				},
+			)
+		}
+	}
+	sort.Slice(
+		digestMismatchFiles,
+		func(i int, j int) bool {
+			return digestMismatchFiles[i].Path < digestMismatchFiles[j].Path
+		},
+	)
+	return digestMismatchFiles, nil
+}
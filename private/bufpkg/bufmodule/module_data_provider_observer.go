@@ -0,0 +1,80 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodule
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModuleDataProviderObserver observes the operations of ModuleDataProviders.
+//
+// This is used to diagnose slow dependency resolution. ModuleDataProviders that support
+// observation accept a ModuleDataProviderObserver as an option, and call the methods
+// that apply to them. For example, caching ModuleDataProviders call CacheLookup, and
+// ModuleDataProviders backed by a registry call DownloadStart and DownloadFinish.
+//
+// Implementations must be safe for concurrent use, as downloads may happen in parallel.
+// Implementations should return quickly, as they are called inline.
+type ModuleDataProviderObserver interface {
+	// CacheLookup is called after the cache was checked for the ModuleKeys.
+	//
+	// hitModuleKeys were found in the cache, missedModuleKeys were not and will
+	// be retrieved from the delegate.
+	CacheLookup(
+		ctx context.Context,
+		hitModuleKeys []ModuleKey,
+		missedModuleKeys []ModuleKey,
+		duration time.Duration,
+	)
+	// DownloadStart is called before the content for the commits is downloaded from the registry
+	// in a single request.
+	DownloadStart(
+		ctx context.Context,
+		registry string,
+		commitIDs []uuid.UUID,
+	)
+	// DownloadFinish is called after the download started with DownloadStart completes.
+	//
+	// numBytes is the total size of the file content downloaded. err is the error
+	// the download failed with, if any.
+	DownloadFinish(
+		ctx context.Context,
+		registry string,
+		commitIDs []uuid.UUID,
+		numBytes int64,
+		duration time.Duration,
+		err error,
+	)
+}
+
+// NopModuleDataProviderObserver returns a new ModuleDataProviderObserver that does nothing.
+func NopModuleDataProviderObserver() ModuleDataProviderObserver {
+	return nopModuleDataProviderObserver{}
+}
+
+// *** PRIVATE ***
+
+type nopModuleDataProviderObserver struct{}
+
+func (nopModuleDataProviderObserver) CacheLookup(context.Context, []ModuleKey, []ModuleKey, time.Duration) {
+}
+
+func (nopModuleDataProviderObserver) DownloadStart(context.Context, string, []uuid.UUID) {}
+
+func (nopModuleDataProviderObserver) DownloadFinish(context.Context, string, []uuid.UUID, int64, time.Duration, error) {
+}
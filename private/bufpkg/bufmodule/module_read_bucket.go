@@ -563,17 +563,33 @@ func (b *moduleReadBucket) getIsTargetFileForPathUncached(ctx context.Context, p
 		return true, nil
 	case len(b.targetPathMap) == 0 && len(b.targetExcludePathMap) != 0:
 		// We only have exclude paths, no paths.
-		return !normalpath.MapHasEqualOrContainingPath(b.targetExcludePathMap, path, normalpath.Relative), nil
+		return !mapHasEqualOrContainingPathOrGlob(b.targetExcludePathMap, path), nil
 	case len(b.targetPathMap) != 0 && len(b.targetExcludePathMap) == 0:
 		// We only have paths, no exclude paths.
 		return normalpath.MapHasEqualOrContainingPath(b.targetPathMap, path, normalpath.Relative), nil
 	default:
 		// We have both paths and exclude paths.
 		return normalpath.MapHasEqualOrContainingPath(b.targetPathMap, path, normalpath.Relative) &&
-			!normalpath.MapHasEqualOrContainingPath(b.targetExcludePathMap, path, normalpath.Relative), nil
+			!mapHasEqualOrContainingPathOrGlob(b.targetExcludePathMap, path), nil
 	}
 }
 
+// mapHasEqualOrContainingPathOrGlob returns true if the map has a path equal to or containing
+// the path, or a glob pattern that matches the path or one of its parent directories.
+//
+// Exclude paths may be glob patterns such as "**/internal".
+func mapHasEqualOrContainingPathOrGlob(m map[string]struct{}, path string) bool {
+	if normalpath.MapHasEqualOrContainingPath(m, path, normalpath.Relative) {
+		return true
+	}
+	for pattern := range m {
+		if storage.IsGlob(pattern) && storage.MatchGlobEqualOrContained(pattern).MatchPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
 // Only will work for .proto files.
 func (b *moduleReadBucket) getFastscanResultForPath(ctx context.Context, path string) (fastscan.Result, error) {
 	return b.pathToFastscanResultCache.GetOrAdd(
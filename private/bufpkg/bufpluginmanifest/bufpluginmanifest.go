@@ -0,0 +1,110 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufpluginmanifest discovers local plugins from plugin directories.
+//
+// A plugin directory contains buf.plugin.yaml manifests, either directly or in
+// immediate subdirectories. Plugin references in buf.yaml and buf.gen.yaml are
+// resolved against these manifests before falling back to a lookup on $PATH,
+// so that teams can vendor plugins per-repository deterministically.
+package bufpluginmanifest
+
+import (
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+)
+
+const (
+	// FileName is the name of a plugin manifest file.
+	FileName = "buf.plugin.yaml"
+	// DirsEnvKey is the environment variable that specifies the plugin directories.
+	//
+	// This is a list of directories separated by os.PathListSeparator, like $PATH.
+	// Directories earlier in the list take precedence.
+	DirsEnvKey = "BUF_PLUGIN_DIRS"
+
+	// CapabilityCheck is the capability of a plugin that can be used for lint and breaking
+	// change detection.
+	CapabilityCheck = "check"
+	// CapabilityGenerate is the capability of a plugin that can be used for code generation.
+	CapabilityGenerate = "generate"
+)
+
+// Manifest is a plugin manifest.
+//
+// This is read from a buf.plugin.yaml file.
+type Manifest interface {
+	// Name is the name of the plugin, such as buf-plugin-timestamp-suffix or protoc-gen-go.
+	//
+	// Always present.
+	Name() string
+	// Version is the version of the plugin.
+	//
+	// Optional.
+	Version() string
+	// BinaryPath is the path to the plugin binary.
+	//
+	// A relative binary path in the manifest is relative to the directory of the manifest.
+	//
+	// Always present.
+	BinaryPath() string
+	// Capabilities are the capabilities of the plugin, such as CapabilityCheck.
+	//
+	// If empty, the plugin can be used for any purpose.
+	Capabilities() []string
+
+	isManifest()
+}
+
+// ReadManifest reads the Manifest at the file path.
+func ReadManifest(filePath string) (Manifest, error) {
+	manifest, err := readManifest(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Resolver resolves plugin references against plugin manifests.
+type Resolver interface {
+	// Resolve resolves the plugin reference to the path of the plugin binary.
+	//
+	// The reference is a plugin name, optionally followed by @version, such as
+	// protoc-gen-go@v1.34.0. If a version is specified, the manifest must have the
+	// same version. If no version is specified and there are multiple manifests
+	// with the name, the manifest in the earliest plugin directory is used.
+	//
+	// Returns false if there is no manifest for the plugin name, in which case the
+	// caller should fall back to a lookup on $PATH. Returns error if there is a
+	// manifest for the plugin name, but no manifest has the requested version, or
+	// the manifest does not have the required capability.
+	Resolve(reference string, capability string) (string, bool, error)
+}
+
+// NewResolver returns a new Resolver for the plugin directories.
+//
+// Directories earlier in the list take precedence. Directories that do not exist
+// are ignored.
+func NewResolver(dirPaths ...string) (Resolver, error) {
+	return newResolver(dirPaths)
+}
+
+// NewResolverForEnv returns a new Resolver for the plugin directories specified
+// by DirsEnvKey.
+//
+// If DirsEnvKey is not set, the Resolver never resolves any reference.
+func NewResolverForEnv(envContainer app.EnvContainer) (Resolver, error) {
+	return newResolver(filepath.SplitList(envContainer.Env(DirsEnvKey)))
+}
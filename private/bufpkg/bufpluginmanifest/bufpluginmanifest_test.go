@@ -0,0 +1,108 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+	vendorDirPath := t.TempDir()
+	otherDirPath := t.TempDir()
+	writeManifest(t, filepath.Join(vendorDirPath, "go"), `name: protoc-gen-go
+version: v1.34.0
+binary: bin/protoc-gen-go
+capabilities:
+  - generate
+`)
+	writeManifest(t, filepath.Join(vendorDirPath, "suffix"), `name: buf-plugin-suffix
+version: v0.1.0
+binary: buf-plugin-suffix
+`)
+	writeManifest(t, otherDirPath, `name: protoc-gen-go
+version: v1.35.0
+binary: /opt/bin/protoc-gen-go
+`)
+	resolver, err := NewResolverForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				DirsEnvKey: vendorDirPath + string(os.PathListSeparator) + otherDirPath,
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	binaryPath, ok, err := resolver.Resolve("protoc-gen-go", CapabilityGenerate)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(vendorDirPath, "go", "bin", "protoc-gen-go"), binaryPath)
+
+	binaryPath, ok, err = resolver.Resolve("protoc-gen-go@v1.35.0", CapabilityGenerate)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.FromSlash("/opt/bin/protoc-gen-go"), binaryPath)
+
+	binaryPath, ok, err = resolver.Resolve("buf-plugin-suffix@v0.1.0", CapabilityCheck)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(vendorDirPath, "suffix", "buf-plugin-suffix"), binaryPath)
+
+	_, ok, err = resolver.Resolve("protoc-gen-go@v1.36.0", CapabilityGenerate)
+	require.Error(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, err.Error(), "version v1.36.0 not found in plugin directories, found versions v1.34.0, v1.35.0")
+
+	_, ok, err = resolver.Resolve("protoc-gen-go@v1.34.0", CapabilityCheck)
+	require.Error(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = resolver.Resolve("protoc-gen-es", CapabilityGenerate)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestReadManifestInvalid(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	writeManifest(t, dirPath, `name: protoc-gen-go
+capabilities:
+  - lint
+`)
+	_, err := ReadManifest(filepath.Join(dirPath, FileName))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "binary is required")
+	writeManifest(t, dirPath, `name: protoc-gen-go
+binary: protoc-gen-go
+capabilities:
+  - lint
+`)
+	_, err = ReadManifest(filepath.Join(dirPath, FileName))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown capability "lint"`)
+	_, err = NewResolver(dirPath)
+	require.Error(t, err)
+}
+
+func writeManifest(t *testing.T, dirPath string, content string) {
+	require.NoError(t, os.MkdirAll(dirPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, FileName), []byte(content), 0600))
+}
@@ -0,0 +1,104 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginmanifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+)
+
+type manifest struct {
+	name         string
+	version      string
+	binaryPath   string
+	capabilities []string
+}
+
+func readManifest(filePath string) (*manifest, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var externalManifest externalManifest
+	if err := encoding.UnmarshalYAMLStrict(data, &externalManifest); err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest %s: %w", filePath, err)
+	}
+	manifest, err := newManifest(filepath.Dir(filePath), externalManifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest %s: %w", filePath, err)
+	}
+	return manifest, nil
+}
+
+func newManifest(dirPath string, externalManifest externalManifest) (*manifest, error) {
+	if externalManifest.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if externalManifest.Binary == "" {
+		return nil, errors.New("binary is required")
+	}
+	for _, capability := range externalManifest.Capabilities {
+		switch capability {
+		case CapabilityCheck, CapabilityGenerate:
+		default:
+			return nil, fmt.Errorf("unknown capability %q, must be one of %q, %q", capability, CapabilityCheck, CapabilityGenerate)
+		}
+	}
+	binaryPath := externalManifest.Binary
+	if !filepath.IsAbs(binaryPath) {
+		binaryPath = filepath.Join(dirPath, filepath.FromSlash(binaryPath))
+	}
+	return &manifest{
+		name:         externalManifest.Name,
+		version:      externalManifest.Version,
+		binaryPath:   binaryPath,
+		capabilities: externalManifest.Capabilities,
+	}, nil
+}
+
+func (m *manifest) Name() string {
+	return m.name
+}
+
+func (m *manifest) Version() string {
+	return m.version
+}
+
+func (m *manifest) BinaryPath() string {
+	return m.binaryPath
+}
+
+func (m *manifest) Capabilities() []string {
+	return slices.Clone(m.capabilities)
+}
+
+func (m *manifest) hasCapability(capability string) bool {
+	return len(m.capabilities) == 0 || slices.Contains(m.capabilities, capability)
+}
+
+func (*manifest) isManifest() {}
+
+// externalManifest represents the buf.plugin.yaml file.
+type externalManifest struct {
+	Name         string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Version      string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Binary       string   `json:"binary,omitempty" yaml:"binary,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+}
@@ -0,0 +1,115 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginmanifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type resolver struct {
+	// manifests are in the order of the plugin directories.
+	manifests []*manifest
+}
+
+func newResolver(dirPaths []string) (*resolver, error) {
+	resolver := &resolver{}
+	for _, dirPath := range dirPaths {
+		if dirPath == "" {
+			continue
+		}
+		manifests, err := readManifestsInDir(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		resolver.manifests = append(resolver.manifests, manifests...)
+	}
+	return resolver, nil
+}
+
+func (r *resolver) Resolve(reference string, capability string) (string, bool, error) {
+	name, version, hasVersion := strings.Cut(reference, "@")
+	var foundVersions []string
+	for _, manifest := range r.manifests {
+		if manifest.name != name {
+			continue
+		}
+		if hasVersion && manifest.version != version {
+			foundVersions = append(foundVersions, manifest.version)
+			continue
+		}
+		if !manifest.hasCapability(capability) {
+			return "", false, fmt.Errorf("plugin %s does not have capability %q, has %q", reference, capability, manifest.capabilities)
+		}
+		return manifest.binaryPath, true, nil
+	}
+	if len(foundVersions) > 0 {
+		return "", false, fmt.Errorf(
+			"plugin %s: version %s not found in plugin directories, found versions %s",
+			name,
+			version,
+			strings.Join(foundVersions, ", "),
+		)
+	}
+	return "", false, nil
+}
+
+// readManifestsInDir reads the manifest in the directory, and the manifests in its
+// immediate subdirectories, sorted by subdirectory name.
+func readManifestsInDir(dirPath string) ([]*manifest, error) {
+	dirEntries, err := os.ReadDir(dirPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var manifests []*manifest
+	manifest, err := readManifestIfExists(filepath.Join(dirPath, FileName))
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		manifests = append(manifests, manifest)
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		manifest, err := readManifestIfExists(filepath.Join(dirPath, dirEntry.Name(), FileName))
+		if err != nil {
+			return nil, err
+		}
+		if manifest != nil {
+			manifests = append(manifests, manifest)
+		}
+	}
+	return manifests, nil
+}
+
+func readManifestIfExists(filePath string) (*manifest, error) {
+	manifest, err := readManifest(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return manifest, nil
+}
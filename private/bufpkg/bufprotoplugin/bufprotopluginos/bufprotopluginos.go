@@ -20,6 +20,7 @@ import (
 	"io"
 	"log/slog"
 
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"google.golang.org/protobuf/types/pluginpb"
 )
@@ -33,7 +34,8 @@ type ResponseWriter interface {
 	// AddResponse adds the response to the writer, switching on the file extension.
 	// If there is a .jar extension, this generates a jar. If there is a .zip
 	// extension, this generates a zip. If there is no extension, this outputs
-	// to the directory.
+	// to the directory. If the OutBucketFunc returns a bucket for pluginOut, this
+	// outputs to the bucket regardless of any extension.
 	//
 	// pluginOut will be unnormalized within this function.
 	AddResponse(
@@ -67,6 +69,21 @@ func ResponseWriterWithCreateOutDirIfNotExists() ResponseWriterOption {
 	}
 }
 
+// ResponseWriterWithOutBucketFunc returns a new ResponseWriterOption that writes to the
+// buckets returned by the OutBucketFunc, such as object storage buckets, instead of
+// the OS filesystem.
+func ResponseWriterWithOutBucketFunc(outBucketFunc OutBucketFunc) ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.outBucketFunc = outBucketFunc
+	}
+}
+
+// OutBucketFunc returns the bucket to write the output for pluginOut to, or false if
+// pluginOut is a path on the OS filesystem.
+//
+// This is used to output to locations other than the OS filesystem, such as object storage.
+type OutBucketFunc func(ctx context.Context, pluginOut string) (storage.ReadWriteBucket, bool, error)
+
 // Cleaner deletes output locations prior to generation.
 //
 // This must be done before any interaction with  ResponseWriters, as multiple plugins may output to a single
@@ -76,6 +93,17 @@ type Cleaner interface {
 }
 
 // NewCleaner returns a new Cleaner.
-func NewCleaner(storageosProvider storageos.Provider) Cleaner {
-	return newCleaner(storageosProvider)
+func NewCleaner(storageosProvider storageos.Provider, options ...CleanerOption) Cleaner {
+	return newCleaner(storageosProvider, options...)
+}
+
+// CleanerOption is an option for the Cleaner.
+type CleanerOption func(*cleanerOptions)
+
+// CleanerWithOutBucketFunc returns a new CleanerOption that deletes everything in the
+// buckets returned by the OutBucketFunc instead of on the OS filesystem.
+func CleanerWithOutBucketFunc(outBucketFunc OutBucketFunc) CleanerOption {
+	return func(cleanerOptions *cleanerOptions) {
+		cleanerOptions.outBucketFunc = outBucketFunc
+	}
 }
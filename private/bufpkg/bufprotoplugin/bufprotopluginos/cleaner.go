@@ -23,19 +23,27 @@ import (
 	"github.com/bufbuild/buf/private/pkg/filepathext"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/osext"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 )
 
 type cleaner struct {
 	storageosProvider storageos.Provider
+	outBucketFunc     OutBucketFunc
 }
 
 func newCleaner(
 	storageosProvider storageos.Provider,
+	options ...CleanerOption,
 ) *cleaner {
+	cleanerOptions := newCleanerOptions()
+	for _, option := range options {
+		option(cleanerOptions)
+	}
 	return &cleaner{
 		storageosProvider: storageosProvider,
+		outBucketFunc:     cleanerOptions.outBucketFunc,
 	}
 }
 
@@ -51,12 +59,29 @@ func (c *cleaner) DeleteOuts(
 	if err != nil {
 		return err
 	}
-	for _, pluginOut := range pluginOuts {
+	outBuckets := make([]storage.ReadWriteBucket, len(pluginOuts))
+	for i, pluginOut := range pluginOuts {
+		if c.outBucketFunc != nil {
+			outBucket, ok, err := c.outBucketFunc(ctx, pluginOut)
+			if err != nil {
+				return err
+			}
+			if ok {
+				outBuckets[i] = outBucket
+				continue
+			}
+		}
 		if err := validatePluginOut(pwd, pluginOut); err != nil {
 			return err
 		}
 	}
-	for _, pluginOut := range pluginOuts {
+	for i, pluginOut := range pluginOuts {
+		if outBucket := outBuckets[i]; outBucket != nil {
+			if err := outBucket.DeleteAll(ctx, ""); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := c.deleteOut(ctx, pluginOut); err != nil {
 			return err
 		}
@@ -122,3 +147,11 @@ func reallyCleanPath(path string) (string, error) {
 	}
 	return filepath.EvalSymlinks(path)
 }
+
+type cleanerOptions struct {
+	outBucketFunc OutBucketFunc
+}
+
+func newCleanerOptions() *cleanerOptions {
+	return &cleanerOptions{}
+}
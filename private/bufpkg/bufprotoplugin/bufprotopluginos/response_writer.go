@@ -47,6 +47,7 @@ type responseWriter struct {
 	responseWriter    bufprotoplugin.ResponseWriter
 	// If set, create directories if they don't already exist.
 	createOutDirIfNotExists bool
+	outBucketFunc           OutBucketFunc
 	// Cache the readWriteBuckets by their respective output paths.
 	// These builders are transformed to storage.ReadBuckets and written
 	// to disk once the responseWriter is flushed.
@@ -84,6 +85,7 @@ func newResponseWriter(
 		storageosProvider:       storageosProvider,
 		responseWriter:          bufprotoplugin.NewResponseWriter(logger),
 		createOutDirIfNotExists: responseWriterOptions.createOutDirIfNotExists,
+		outBucketFunc:           responseWriterOptions.outBucketFunc,
 		readWriteBuckets:        make(map[string]storage.ReadWriteBucket),
 	}
 }
@@ -93,6 +95,17 @@ func (w *responseWriter) AddResponse(
 	response *pluginpb.CodeGeneratorResponse,
 	pluginOut string,
 ) error {
+	if w.outBucketFunc != nil {
+		outBucket, ok, err := w.outBucketFunc(ctx, pluginOut)
+		if err != nil {
+			return err
+		}
+		if ok {
+			w.lock.Lock()
+			defer w.lock.Unlock()
+			return w.writeOutBucket(ctx, response, pluginOut, outBucket)
+		}
+	}
 	// It's important that we get a consistent output path
 	// so that we use the same in-memory bucket for paths
 	// set to the same directory.
@@ -280,6 +293,9 @@ func (w *responseWriter) writeDirectory(
 		osReadWriteBucket, err := w.storageosProvider.NewReadWriteBucket(
 			outDirPath,
 			storageos.ReadWriteBucketWithSymlinksIfSupported(),
+			// An interrupted generation should not leave partially-written files behind.
+			storageos.ReadWriteBucketWithAtomicWrites(),
+			storageos.ReadWriteBucketWithSyncWrites(),
 		)
 		if err != nil {
 			return err
@@ -292,8 +308,44 @@ func (w *responseWriter) writeDirectory(
 	return nil
 }
 
+// writeOutBucket writes the response to the bucket returned by the OutBucketFunc.
+//
+// Like directories, the response is only written to the bucket when the
+// responseWriter is closed.
+func (w *responseWriter) writeOutBucket(
+	ctx context.Context,
+	response *pluginpb.CodeGeneratorResponse,
+	pluginOut string,
+	outBucket storage.ReadWriteBucket,
+) error {
+	if readWriteBucket, ok := w.readWriteBuckets[pluginOut]; ok {
+		return w.responseWriter.WriteResponse(
+			ctx,
+			readWriteBucket,
+			response,
+			bufprotoplugin.WriteResponseWithInsertionPointReadBucket(readWriteBucket),
+		)
+	}
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	if err := w.responseWriter.WriteResponse(
+		ctx,
+		readWriteBucket,
+		response,
+		bufprotoplugin.WriteResponseWithInsertionPointReadBucket(readWriteBucket),
+	); err != nil {
+		return err
+	}
+	w.readWriteBuckets[pluginOut] = readWriteBucket
+	w.closers = append(w.closers, func() error {
+		_, err := storage.Copy(ctx, readWriteBucket, outBucket)
+		return err
+	})
+	return nil
+}
+
 type responseWriterOptions struct {
 	createOutDirIfNotExists bool
+	outBucketFunc           OutBucketFunc
 }
 
 func newResponseWriterOptions() *responseWriterOptions {
@@ -285,6 +285,17 @@ func EnvBool(container EnvContainer, key string, defaultValue bool) (bool, error
 	return strconv.ParseBool(value)
 }
 
+// EnvInt gets and parses the environment variable int value for the key.
+//
+// Returns error on parsing error.
+func EnvInt(container EnvContainer, key string, defaultValue int) (int, error) {
+	value := container.Env(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
 // IsDevStdin returns true if the path is the equivalent of /dev/stdin.
 func IsDevStdin(path string) bool {
 	return path != "" && path == DevStdinFilePath
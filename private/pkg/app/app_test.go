@@ -160,3 +160,22 @@ func TestEnvBool(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, true, val)
 }
+
+func TestEnvInt(t *testing.T) {
+	t.Parallel()
+	envContainer := NewEnvContainer(
+		map[string]string{
+			"foo1": "bar1",
+			"foo2": "4",
+		},
+	)
+	val, err := EnvInt(envContainer, "foo1", 1)
+	assert.Error(t, err)
+	assert.Equal(t, 0, val)
+	val, err = EnvInt(envContainer, "foo2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, val)
+	val, err = EnvInt(envContainer, "notset", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+}
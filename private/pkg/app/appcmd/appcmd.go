@@ -66,11 +66,27 @@ type Command struct {
 	// SubCommands are the sub-commands. Optional.
 	// Must be unset if there is a run function.
 	SubCommands []*Command
+	// CompleteArgs returns the shell completions for positional arguments. Optional.
+	CompleteArgs CompletionFunc
+	// CompleteFlags returns the shell completions for the values of the flags
+	// with the given names. Optional.
+	//
+	// All flags must be bound by BindFlags or BindPersistentFlags.
+	CompleteFlags map[string]CompletionFunc
 	// ModifyCobra will modify the underlying [cobra.Command] that is created from this [Command].
 	//
 	// This should be used sparingly. Almost all operations should be able to be performed
 	// by the fields of Command. However, ModifyCommand exists as a break-class feature.
 	ModifyCobra func(*cobra.Command) error
+	// ExpandAlias expands a user-defined alias for a sub-command that does not exist. Optional.
+	//
+	// This is only used on the root command, and only if it has sub-commands. Aliases
+	// take precedence over External.
+	ExpandAlias ExpandAliasFunc
+	// External runs an external command for a sub-command that does not exist. Optional.
+	//
+	// This is only used on the root command, and only if it has sub-commands.
+	External ExternalFunc
 	// Version the version of the command.
 	//
 	// If this is specified, a flag --version will be added to the command
@@ -79,6 +95,31 @@ type Command struct {
 	Version string
 }
 
+// CompletionFunc returns the shell completions for a positional argument or flag value.
+//
+// The container has the positional arguments that precede the value being completed,
+// and toComplete is the partial value typed so far. Completions should start with
+// toComplete. If no completions are returned, the shell falls back to completing
+// file names.
+type CompletionFunc func(ctx context.Context, container app.Container, toComplete string) ([]string, error)
+
+// ExternalFunc runs the external command with the given name, with the arguments that
+// followed the name on the command line.
+//
+// This allows commands to be extended by separate executables, similar to git and kubectl
+// plugins. Returns false if there is no external command with the name, in which case
+// the usual unknown command error is returned.
+type ExternalFunc func(ctx context.Context, container app.Container, name string, args []string) (bool, error)
+
+// ExpandAliasFunc returns the argument lists that the alias with the given name expands to.
+// Returns nil if there is no alias with the name.
+//
+// Each argument list is run as a separate invocation of the root command, in order, with
+// the arguments that followed the alias on the command line appended. The first invocation
+// that fails stops the expansion. Aliases are not expanded recursively, and cannot shadow
+// existing sub-commands.
+type ExpandAliasFunc func(container app.Container, name string) ([][]string, error)
+
 // NewInvalidArgumentError creates a new InvalidArgumentError, indicating that
 // the error was caused by argument validation. This causes us to print the usage
 // help text for the command that it is returned from.
@@ -236,6 +277,27 @@ func run(
 
 	cobraCommand.SetOut(container.Stderr())
 	args := app.Args(container)[1:]
+	if (command.ExpandAlias != nil || command.External != nil) && len(command.SubCommands) > 0 && isExternalName(args) {
+		// The help command is otherwise only added on Execute.
+		cobraCommand.InitDefaultHelpCmd()
+		if _, _, err := cobraCommand.Find(args); err != nil {
+			if command.ExpandAlias != nil {
+				argLists, err := command.ExpandAlias(container, args[0])
+				if err != nil {
+					return err
+				}
+				if len(argLists) > 0 {
+					return runAlias(ctx, container, command, argLists, args[1:])
+				}
+			}
+			if command.External != nil {
+				ok, err := command.External(ctx, container, args[0], args[1:])
+				if ok {
+					return err
+				}
+			}
+		}
+	}
 	// cobra will implicitly create __complete and __completeNoDesc subcommands
 	// https://github.com/spf13/cobra/blob/4590150168e93f4b017c6e33469e26590ba839df/completions.go#L14-L17
 	// at the very last possible point, to enable them to be overridden. Unfortunately
@@ -273,6 +335,39 @@ func run(
 	return runErr
 }
 
+// runAlias runs the root command once for each of the argument lists of an alias,
+// with the extra arguments appended.
+func runAlias(
+	ctx context.Context,
+	container app.Container,
+	command *Command,
+	argLists [][]string,
+	extraArgs []string,
+) error {
+	// Aliases are not expanded recursively.
+	commandWithoutAliases := *command
+	commandWithoutAliases.ExpandAlias = nil
+	for _, argList := range argLists {
+		args := append([]string{container.Arg(0)}, argList...)
+		args = append(args, extraArgs...)
+		if err := run(ctx, app.NewContainerForArgs(container, args...), &commandWithoutAliases); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExternalName returns true if the first argument could be the name of an external command.
+//
+// The name must come before any flags.
+func isExternalName(args []string) bool {
+	return len(args) > 0 &&
+		args[0] != "" &&
+		!strings.HasPrefix(args[0], "-") &&
+		!strings.HasPrefix(args[0], "__") &&
+		!strings.ContainsAny(args[0], `/\`)
+}
+
 func commandToCobra(
 	ctx context.Context,
 	container app.Container,
@@ -321,6 +416,17 @@ func commandToCobra(
 	if command.NormalizePersistentFlag != nil {
 		cobraCommand.PersistentFlags().SetNormalizeFunc(normalizeFunc(command.NormalizePersistentFlag))
 	}
+	if command.CompleteArgs != nil {
+		cobraCommand.ValidArgsFunction = newCobraCompletionFunc(ctx, container, command.CompleteArgs)
+	}
+	for flagName, completionFunc := range command.CompleteFlags {
+		if err := cobraCommand.RegisterFlagCompletionFunc(
+			flagName,
+			newCobraCompletionFunc(ctx, container, completionFunc),
+		); err != nil {
+			return nil, err
+		}
+	}
 	if command.Run != nil {
 		cobraCommand.Run = func(_ *cobra.Command, args []string) {
 			runErr := command.Run(ctx, app.NewContainerForArgs(container, args...))
@@ -375,6 +481,24 @@ func commandToCobra(
 	return cobraCommand, nil
 }
 
+func newCobraCompletionFunc(
+	ctx context.Context,
+	container app.Container,
+	completionFunc CompletionFunc,
+) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		completions, err := completionFunc(ctx, app.NewContainerForArgs(container, args...), toComplete)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+		if len(completions) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
 func commandValidate(command *Command) error {
 	if command.Use == "" {
 		return errors.New("must set Command.Use")
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"slices"
 	"strings"
 	"testing"
 
@@ -226,3 +227,145 @@ func TestIncorrectFlagEmptyStdout(t *testing.T) {
 	require.Empty(t, stdout.String())
 	require.NotEmpty(t, stderr.String())
 }
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+	var bar string
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				BindFlags: func(flagSet *pflag.FlagSet) {
+					flagSet.StringVar(&bar, "bar", "", "Bar.")
+				},
+				CompleteArgs: func(ctx context.Context, container app.Container, toComplete string) ([]string, error) {
+					return []string{toComplete + strings.Join(app.Args(container), "")}, nil
+				},
+				CompleteFlags: map[string]CompletionFunc{
+					"bar": func(ctx context.Context, container app.Container, toComplete string) ([]string, error) {
+						if toComplete == "none" {
+							return nil, nil
+						}
+						return []string{toComplete + "1", toComplete + "2"}, nil
+					},
+				},
+				Run: func(ctx context.Context, container app.Container) error {
+					return nil
+				},
+			},
+		},
+	}
+	testCompletion := func(t *testing.T, expected string, args ...string) {
+		stdout := bytes.NewBuffer(nil)
+		container := app.NewContainer(
+			nil,
+			nil,
+			stdout,
+			io.Discard,
+			append([]string{"test", "__complete", "sub"}, args...)...,
+		)
+		require.NoError(t, Run(context.Background(), container, rootCommand))
+		assert.Equal(t, expected, stdout.String())
+	}
+	testCompletion(t, "foo1\nfoo2\n:4\n", "--bar", "foo")
+	testCompletion(t, ":0\n", "--bar", "none")
+	testCompletion(t, "fooab\n:4\n", "a", "b", "foo")
+}
+
+func TestExternal(t *testing.T) {
+	t.Parallel()
+	var actualName string
+	var actualArgs []string
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				Run: func(ctx context.Context, container app.Container) error {
+					return app.NewError(5, "sub")
+				},
+			},
+		},
+		External: func(ctx context.Context, container app.Container, name string, args []string) (bool, error) {
+			if name != "foo" {
+				return false, nil
+			}
+			actualName = name
+			actualArgs = args
+			return true, app.NewError(3, "foo")
+		},
+	}
+	run := func(args ...string) error {
+		return Run(
+			context.Background(),
+			app.NewContainer(nil, nil, nil, nil, append([]string{"test"}, args...)...),
+			rootCommand,
+		)
+	}
+	require.Equal(t, app.NewError(3, "foo"), run("foo", "one", "--two"))
+	assert.Equal(t, "foo", actualName)
+	assert.Equal(t, []string{"one", "--two"}, actualArgs)
+	// Existing commands take precedence.
+	require.Equal(t, app.NewError(5, "sub"), run("sub"))
+	require.NoError(t, run("help"))
+	// Unknown commands without an external command are still an error.
+	err := run("bar")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown command "bar"`)
+}
+
+func TestExpandAlias(t *testing.T) {
+	t.Parallel()
+	var actualArgLists [][]string
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				Run: func(ctx context.Context, container app.Container) error {
+					args := app.Args(container)
+					actualArgLists = append(actualArgLists, args)
+					if slices.Contains(args, "fail") {
+						return app.NewError(5, "sub")
+					}
+					return nil
+				},
+			},
+		},
+		ExpandAlias: func(container app.Container, name string) ([][]string, error) {
+			switch name {
+			case "both":
+				return [][]string{{"sub", "one"}, {"sub", "two"}}, nil
+			case "fail":
+				return [][]string{{"sub", "fail"}, {"sub", "two"}}, nil
+			case "sub", "loop":
+				// Aliases do not shadow existing commands, and are not expanded recursively.
+				return [][]string{{"loop"}}, nil
+			default:
+				return nil, nil
+			}
+		},
+		External: func(ctx context.Context, container app.Container, name string, args []string) (bool, error) {
+			return name == "external", nil
+		},
+	}
+	run := func(args ...string) error {
+		actualArgLists = nil
+		return Run(
+			context.Background(),
+			app.NewContainer(nil, nil, nil, nil, append([]string{"test"}, args...)...),
+			rootCommand,
+		)
+	}
+	require.NoError(t, run("both", "extra"))
+	assert.Equal(t, [][]string{{"one", "extra"}, {"two", "extra"}}, actualArgLists)
+	require.Equal(t, app.NewError(5, "sub"), run("fail"))
+	assert.Equal(t, [][]string{{"fail"}}, actualArgLists)
+	require.NoError(t, run("sub", "three"))
+	assert.Equal(t, [][]string{{"three"}}, actualArgLists)
+	require.NoError(t, run("external"))
+	err := run("loop")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown command "loop"`)
+}
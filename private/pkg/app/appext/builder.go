@@ -68,15 +68,30 @@ func (b *builder) BindRoot(flagSet *pflag.FlagSet) {
 		flagSet.DurationVar(&b.timeout, "timeout", b.defaultTimeout, `The duration until timing out, setting it to zero means no timeout`)
 	}
 
-	flagSet.BoolVar(&b.profile, "profile", false, "Run profiling")
-	_ = flagSet.MarkHidden("profile")
+	// These are named pprof so that --profile is available to applications for configuration profiles.
+	flagSet.BoolVar(&b.profile, "pprof", false, "Run profiling")
+	_ = flagSet.MarkHidden("pprof")
+	flagSet.StringVar(&b.profilePath, "pprof-path", "", "The profile base directory path")
+	_ = flagSet.MarkHidden("pprof-path")
+	flagSet.IntVar(&b.profileLoops, "pprof-loops", 1, "The number of loops to run")
+	_ = flagSet.MarkHidden("pprof-loops")
+	flagSet.StringVar(&b.profileType, "pprof-type", "cpu", "The profile type [cpu,mem,block,mutex]")
+	_ = flagSet.MarkHidden("pprof-type")
+	flagSet.BoolVar(&b.profileAllowError, "pprof-allow-error", false, "Allow errors for profiled commands")
+	_ = flagSet.MarkHidden("pprof-allow-error")
+	// The profiling flags used to be named profile. --profile is now taken, but the
+	// other flags are kept as deprecated aliases.
 	flagSet.StringVar(&b.profilePath, "profile-path", "", "The profile base directory path")
+	_ = flagSet.MarkDeprecated("profile-path", "use --pprof-path instead")
 	_ = flagSet.MarkHidden("profile-path")
 	flagSet.IntVar(&b.profileLoops, "profile-loops", 1, "The number of loops to run")
+	_ = flagSet.MarkDeprecated("profile-loops", "use --pprof-loops instead")
 	_ = flagSet.MarkHidden("profile-loops")
 	flagSet.StringVar(&b.profileType, "profile-type", "cpu", "The profile type [cpu,mem,block,mutex]")
+	_ = flagSet.MarkDeprecated("profile-type", "use --pprof-type instead")
 	_ = flagSet.MarkHidden("profile-type")
 	flagSet.BoolVar(&b.profileAllowError, "profile-allow-error", false, "Allow errors for profiled commands")
+	_ = flagSet.MarkDeprecated("profile-allow-error", "use --pprof-allow-error instead")
 	_ = flagSet.MarkHidden("profile-allow-error")
 
 	// We do not officially support this flag, this is for testing, where we need warnings turned off.
@@ -218,6 +218,21 @@ func RunnerWithParallelism(parallelism int) RunnerOption {
 	}
 }
 
+// RunnerWithSandbox returns a new RunnerOption that runs all external commands in a sandbox.
+//
+// Sandboxed commands have no network access, cannot write to the file system except for
+// the temporary directory and the directories given with SandboxWithWritableDirPaths, and
+// have resource limits applied. Core dumps are disabled.
+//
+// On Linux, this requires bubblewrap (bwrap) to be installed. On macOS, sandbox-exec is
+// used. Sandboxing is not supported on other platforms. Use CheckSandboxSupported to check
+// if sandboxing is supported. If sandboxing is not supported, running a command errors.
+func RunnerWithSandbox(options ...SandboxOption) RunnerOption {
+	return func(runner *runner) {
+		runner.sandbox = newSandbox(options...)
+	}
+}
+
 // RunStdout is a convenience function that attaches the container environment,
 // stdin, and stderr, and returns the stdout as a byte slice.
 func RunStdout(
@@ -32,6 +32,8 @@ var emptyEnv = envSlice(
 
 type runner struct {
 	parallelism int
+	// sandbox is set if commands are run in a sandbox.
+	sandbox *sandbox
 
 	semaphoreC chan struct{}
 }
@@ -52,10 +54,14 @@ func (r *runner) Run(ctx context.Context, name string, options ...RunOption) err
 	for _, option := range options {
 		option(execOptions)
 	}
-	cmd := exec.CommandContext(ctx, name, execOptions.args...)
+	name, args, err := r.getNameAndArgs(name, execOptions)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
 	execOptions.ApplyToCmd(cmd)
 	r.increment()
-	err := cmd.Run()
+	err = cmd.Run()
 	r.decrement()
 	return err
 }
@@ -65,7 +71,11 @@ func (r *runner) Start(name string, options ...StartOption) (Process, error) {
 	for _, option := range options {
 		option(execOptions)
 	}
-	cmd := exec.Command(name, execOptions.args...)
+	name, args, err := r.getNameAndArgs(name, execOptions)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, args...)
 	execOptions.ApplyToCmd(cmd)
 	r.increment()
 	if err := cmd.Start(); err != nil {
@@ -76,6 +86,15 @@ func (r *runner) Start(name string, options ...StartOption) (Process, error) {
 	return process, nil
 }
 
+// getNameAndArgs returns the name and args to execute, wrapping the command in the
+// sandbox if configured.
+func (r *runner) getNameAndArgs(name string, execOptions *execOptions) (string, []string, error) {
+	if r.sandbox == nil {
+		return name, execOptions.args, nil
+	}
+	return sandboxCommand(r.sandbox, name, execOptions.args, execOptions.dir)
+}
+
 func (r *runner) increment() {
 	r.semaphoreC <- struct{}{}
 }
@@ -18,8 +18,12 @@
 package command
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -50,3 +54,34 @@ func TestNoDeadlock(t *testing.T) {
 		require.NoError(t, process.Wait(ctx))
 	}
 }
+
+func TestSandboxLimits(t *testing.T) {
+	t.Parallel()
+
+	sandbox := newSandbox(SandboxWithMaxCPUTime(1500*time.Millisecond), SandboxWithMaxOpenFiles(64))
+	name, args := sandbox.wrapWithLimits("sh", []string{"-c", "ulimit -c && ulimit -t && ulimit -n"})
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, NewRunner().Run(context.Background(), name, RunWithArgs(args...), RunWithStdout(stdout)))
+	require.Equal(t, "0\n2\n64\n", stdout.String())
+}
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+	if err := CheckSandboxSupported(); err != nil {
+		t.Skip(err)
+	}
+
+	ctx := context.Background()
+	writableDirPath := t.TempDir()
+	readOnlyDirPath, err := os.MkdirTemp(".", "sandbox")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(readOnlyDirPath) })
+	runner := NewRunner(RunnerWithSandbox(SandboxWithWritableDirPaths(writableDirPath)))
+
+	writableFilePath := filepath.Join(writableDirPath, "foo")
+	require.NoError(t, runner.Run(ctx, "touch", RunWithArgs(writableFilePath)))
+	require.FileExists(t, writableFilePath)
+	readOnlyFilePath := filepath.Join(readOnlyDirPath, "foo")
+	require.Error(t, runner.Run(ctx, "touch", RunWithArgs(readOnlyFilePath)))
+	require.NoFileExists(t, readOnlyFilePath)
+}
@@ -0,0 +1,128 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ErrSandboxNotSupported is wrapped by the error returned by CheckSandboxSupported
+// if sandboxing is not supported on the current platform, or the program used for
+// sandboxing is not installed.
+//
+// Other errors returned by CheckSandboxSupported mean that the program is installed,
+// but cannot create the sandbox.
+var ErrSandboxNotSupported = errors.New("sandboxing is not supported")
+
+// SandboxOption is an option for RunnerWithSandbox.
+type SandboxOption func(*sandbox)
+
+// SandboxWithWritableDirPaths returns a new SandboxOption that allows commands to write
+// to the given directories in addition to the temporary directory.
+func SandboxWithWritableDirPaths(writableDirPaths ...string) SandboxOption {
+	return func(sandbox *sandbox) {
+		sandbox.writableDirPaths = append(sandbox.writableDirPaths, writableDirPaths...)
+	}
+}
+
+// SandboxWithMaxCPUTime returns a new SandboxOption that limits the CPU time of commands.
+//
+// The limit is rounded up to the second. The default is no limit.
+func SandboxWithMaxCPUTime(maxCPUTime time.Duration) SandboxOption {
+	return func(sandbox *sandbox) {
+		sandbox.maxCPUTime = maxCPUTime
+	}
+}
+
+// SandboxWithMaxOpenFiles returns a new SandboxOption that limits the number of files
+// commands can have open.
+//
+// The default is no limit.
+func SandboxWithMaxOpenFiles(maxOpenFiles int) SandboxOption {
+	return func(sandbox *sandbox) {
+		sandbox.maxOpenFiles = maxOpenFiles
+	}
+}
+
+// CheckSandboxSupported returns an error if RunnerWithSandbox is not supported on the
+// current platform, describing why.
+//
+// On Linux, this runs a command in the sandbox once per process, as bwrap may be installed
+// but not permitted to create namespaces, such as within containers.
+func CheckSandboxSupported() error {
+	return checkSandboxSupported()
+}
+
+// *** PRIVATE ***
+
+type sandbox struct {
+	writableDirPaths []string
+	maxCPUTime       time.Duration
+	maxOpenFiles     int
+}
+
+func newSandbox(options ...SandboxOption) *sandbox {
+	sandbox := &sandbox{}
+	for _, option := range options {
+		option(sandbox)
+	}
+	return sandbox
+}
+
+// getWritableDirPaths returns the absolute paths with symlinks evaluated of the
+// directories commands can write to, including the temporary directory.
+//
+// Directories that do not exist are skipped.
+func (s *sandbox) getWritableDirPaths() ([]string, error) {
+	var writableDirPaths []string
+	for _, dirPath := range append([]string{os.TempDir()}, s.writableDirPaths...) {
+		dirPath, err := filepath.Abs(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		dirPath, err = filepath.EvalSymlinks(dirPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		writableDirPaths = append(writableDirPaths, dirPath)
+	}
+	return writableDirPaths, nil
+}
+
+// wrapWithLimits returns the name and args that run the command with name and args
+// with the resource limits of the sandbox applied.
+//
+// The limits are applied with the ulimit builtin of /bin/sh within the sandbox, so
+// that they are applied before the command is started. Core dumps are always disabled.
+func (s *sandbox) wrapWithLimits(name string, args []string) (string, []string) {
+	script := "ulimit -c 0"
+	if s.maxCPUTime > 0 {
+		maxCPUSeconds := int64((s.maxCPUTime + time.Second - 1) / time.Second)
+		script += " && ulimit -t " + strconv.FormatInt(maxCPUSeconds, 10)
+	}
+	if s.maxOpenFiles > 0 {
+		script += " && ulimit -n " + strconv.Itoa(s.maxOpenFiles)
+	}
+	script += ` && exec "$@"`
+	// The first argument after the script is $0.
+	return "/bin/sh", append([]string{"-c", script, "buf-sandbox", name}, args...)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// sandboxExecPath is the path to the sandbox-exec binary used to sandbox commands on macOS.
+const sandboxExecPath = "/usr/bin/sandbox-exec"
+
+func checkSandboxSupported() error {
+	if _, err := exec.LookPath(sandboxExecPath); err != nil {
+		return fmt.Errorf("%w: %w", ErrSandboxNotSupported, err)
+	}
+	return nil
+}
+
+// sandboxCommand returns the name and args that run the command with name and args in the sandbox.
+//
+// On macOS, commands are run with sandbox-exec with a profile that denies network access
+// and denies writes to the file system except for the writable directories.
+func sandboxCommand(sandbox *sandbox, name string, args []string, _ string) (string, []string, error) {
+	if err := checkSandboxSupported(); err != nil {
+		return "", nil, err
+	}
+	namePath, err := exec.LookPath(name)
+	if err != nil {
+		return "", nil, err
+	}
+	writableDirPaths, err := sandbox.getWritableDirPaths()
+	if err != nil {
+		return "", nil, err
+	}
+	var profile strings.Builder
+	_, _ = profile.WriteString("(version 1)\n(allow default)\n(deny network*)\n(deny file-write*)\n")
+	_, _ = profile.WriteString("(allow file-write* (subpath \"/dev\")")
+	for _, writableDirPath := range writableDirPaths {
+		_, _ = profile.WriteString(" (subpath " + strconv.Quote(writableDirPath) + ")")
+	}
+	_, _ = profile.WriteString(")\n")
+	limitsName, limitsArgs := sandbox.wrapWithLimits(namePath, args)
+	return sandboxExecPath, append([]string{"-p", profile.String(), limitsName}, limitsArgs...), nil
+}
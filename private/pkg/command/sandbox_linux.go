@@ -0,0 +1,98 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// bwrapName is the name of the bubblewrap binary used to sandbox commands on Linux.
+	bwrapName = "bwrap"
+	// bwrapCheckTimeout is the maximum time to check that bwrap can create the sandbox.
+	bwrapCheckTimeout = 5 * time.Second
+)
+
+// checkSandboxSupported runs a no-op command in the sandbox, and is only run once
+// per process.
+var checkSandboxSupported = sync.OnceValue(func() error {
+	bwrapPath, err := exec.LookPath(bwrapName)
+	if err != nil {
+		return fmt.Errorf("%w: %s must be installed: %w", ErrSandboxNotSupported, bwrapName, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bwrapCheckTimeout)
+	defer cancel()
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, bwrapPath, append(getBwrapArgs(nil, ""), "--", "true")...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s cannot create the sandbox: %s", bwrapName, message)
+		}
+		return fmt.Errorf("%s cannot create the sandbox: %w", bwrapName, err)
+	}
+	return nil
+})
+
+// sandboxCommand returns the name and args that run the command with name and args in the sandbox.
+//
+// On Linux, commands are run with bubblewrap in new namespaces without network access,
+// with the file system mounted read-only except for the writable directories.
+func sandboxCommand(sandbox *sandbox, name string, args []string, dir string) (string, []string, error) {
+	bwrapPath, err := exec.LookPath(bwrapName)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s must be installed: %w", ErrSandboxNotSupported, bwrapName, err)
+	}
+	// Resolve the command outside of the sandbox, as PATH is the same within the sandbox.
+	namePath, err := exec.LookPath(name)
+	if err != nil {
+		return "", nil, err
+	}
+	writableDirPaths, err := sandbox.getWritableDirPaths()
+	if err != nil {
+		return "", nil, err
+	}
+	limitsName, limitsArgs := sandbox.wrapWithLimits(namePath, args)
+	bwrapArgs := append(getBwrapArgs(writableDirPaths, dir), "--", limitsName)
+	return bwrapPath, append(bwrapArgs, limitsArgs...), nil
+}
+
+// getBwrapArgs returns the arguments to bwrap that create the sandbox, before the command.
+func getBwrapArgs(writableDirPaths []string, dir string) []string {
+	bwrapArgs := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+	}
+	for _, writableDirPath := range writableDirPaths {
+		bwrapArgs = append(bwrapArgs, "--bind", writableDirPath, writableDirPath)
+	}
+	bwrapArgs = append(
+		bwrapArgs,
+		"--unshare-net",
+		"--unshare-ipc",
+		"--die-with-parent",
+	)
+	if dir != "" {
+		bwrapArgs = append(bwrapArgs, "--chdir", dir)
+	}
+	return bwrapArgs
+}
@@ -0,0 +1,158 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// CanonicalizeJSON rewrites the JSON data in the canonical form defined by the
+// JSON Canonicalization Scheme, RFC 8785.
+//
+// The result has no insignificant whitespace, object keys sorted by their UTF-16
+// code units, numbers formatted as ECMAScript formats them, and strings escaped
+// only where required. The same value always results in the same bytes, so the
+// result is suitable for comparing and hashing.
+func CanonicalizeJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Numbers are parsed and formatted by writeCanonicalJSONNumber.
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after top-level JSON value")
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := writeCanonicalJSON(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCanonicalJSON(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		buffer.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		return writeCanonicalJSONNumber(buffer, value)
+	case string:
+		writeCanonicalJSONString(buffer, value)
+	case []interface{}:
+		buffer.WriteByte('[')
+		for i, element := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a string, b string) int {
+			return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+		})
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeCanonicalJSONString(buffer, key)
+			buffer.WriteByte(':')
+			if err := writeCanonicalJSON(buffer, value[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", value)
+	}
+	return nil
+}
+
+// writeCanonicalJSONNumber writes the number as the ECMAScript Number.prototype.toString
+// method formats it, which uses the shortest decimal that round-trips and switches to
+// exponential notation outside of [1e-6, 1e21).
+func writeCanonicalJSONNumber(buffer *bytes.Buffer, number json.Number) error {
+	f, err := strconv.ParseFloat(number.String(), 64)
+	if err != nil {
+		return fmt.Errorf("invalid JSON number %q: %w", number.String(), err)
+	}
+	if math.IsInf(f, 0) {
+		return fmt.Errorf("JSON number %q is out of range", number.String())
+	}
+	if f == 0 {
+		// This includes negative zero.
+		buffer.WriteByte('0')
+		return nil
+	}
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+		buffer.WriteString(mantissa)
+		buffer.WriteByte('e')
+		// Go pads the exponent to two digits, ECMAScript does not.
+		buffer.WriteByte(exponent[0])
+		buffer.WriteString(strings.TrimLeft(exponent[1:], "0"))
+		return nil
+	}
+	buffer.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+	return nil
+}
+
+func writeCanonicalJSONString(buffer *bytes.Buffer, s string) {
+	buffer.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\b':
+			buffer.WriteString(`\b`)
+		case '\f':
+			buffer.WriteString(`\f`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buffer, `\u%04x`, r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+}
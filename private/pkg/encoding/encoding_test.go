@@ -37,6 +37,26 @@ func TestInterfaceSliceOrStringToCommaSepString(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestCanonicalizeJSON(t *testing.T) {
+	t.Parallel()
+	testCanonicalizeJSON(t, `{"b": [1, 2.50, {"z": null, "a": true}], "a": "x"}`, `{"a":"x","b":[1,2.5,{"a":true,"z":null}]}`)
+	// Keys are sorted by UTF-16 code units, so U+1F600 sorts before U+FB33.
+	testCanonicalizeJSON(t, `{"\ufb33": 1, "\ud83d\ude00": 2, "\u00e9": 3}`, "{\"\u00e9\":3,\"\U0001f600\":2,\"\ufb33\":1}")
+	testCanonicalizeJSON(t, `[1e21, 1e-7, 123456789012, -0, 0.000001, 1E+2, 4.50e-10]`, `[1e+21,1e-7,123456789012,0,0.000001,100,4.5e-10]`)
+	testCanonicalizeJSON(t, `"\u0001\u000a\u2028</>\"\\"`, `"\u0001\n`+"\u2028"+`</>\"\\"`)
+
+	_, err := CanonicalizeJSON([]byte(`1e400`))
+	require.Error(t, err)
+	_, err = CanonicalizeJSON([]byte(`{} {}`))
+	require.Error(t, err)
+}
+
+func testCanonicalizeJSON(t *testing.T, in string, expected string) {
+	data, err := CanonicalizeJSON([]byte(in))
+	require.NoError(t, err)
+	require.Equal(t, expected, string(data))
+}
+
 func testInterfaceSliceOrStringToCommaSepString(t *testing.T, in interface{}, expected string) {
 	v, err := InterfaceSliceOrStringToCommaSepString(in)
 	require.NoError(t, err)
@@ -16,6 +16,10 @@ package filelock
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -77,3 +81,43 @@ func TestLockerBasic(t *testing.T) {
 	_, err = locker.Lock(ctx, absolutePath)
 	require.Error(t, err)
 }
+
+func TestLockOwnerErrorMessage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDirPath := t.TempDir()
+	filePath := filepath.Join(tempDirPath, "path/to/lock")
+	unlocker, err := Lock(ctx, filePath)
+	require.NoError(t, err)
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	owner, err := readLockOwner(filePath)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), owner.PID)
+	require.Equal(t, hostname, owner.Hostname)
+	_, err = Lock(ctx, filePath, LockWithTimeout(100*time.Millisecond), LockWithRetryDelay(10*time.Millisecond))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("held by process %d on %q", os.Getpid(), hostname))
+	// The lock is never broken, even if it was acquired a long time ago.
+	data, err := json.Marshal(
+		&lockOwner{
+			PID:         os.Getpid(),
+			Hostname:    hostname,
+			AcquireTime: time.Now().Add(-24 * time.Hour),
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(getLockOwnerFilePath(filePath), data, 0644))
+	_, err = Lock(ctx, filePath, LockWithTimeout(100*time.Millisecond), LockWithRetryDelay(10*time.Millisecond))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "held by process")
+	require.NoError(t, unlocker.Unlock())
+	_, err = readLockOwner(filePath)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	// Shared locks do not record an owner.
+	unlocker, err = RLock(ctx, filePath)
+	require.NoError(t, err)
+	_, err = readLockOwner(filePath)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.NoError(t, unlocker.Unlock())
+}
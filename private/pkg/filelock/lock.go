@@ -33,6 +33,7 @@ func lock(
 		ctx,
 		filePath,
 		(*flock.Flock).TryLockContext,
+		true,
 		options...,
 	)
 }
@@ -46,6 +47,7 @@ func rlock(
 		ctx,
 		filePath,
 		(*flock.Flock).TryRLockContext,
+		false,
 		options...,
 	)
 }
@@ -54,6 +56,7 @@ func lockForFunc(
 	ctx context.Context,
 	filePath string,
 	tryLockContextFunc func(*flock.Flock, context.Context, time.Duration) (bool, error),
+	exclusive bool,
 	options ...LockOption,
 ) (Unlocker, error) {
 	lockOptions := newLockOptions()
@@ -72,12 +75,20 @@ func lockForFunc(
 	flock := flock.New(filePath)
 	locked, err := tryLockContextFunc(flock, ctx, lockOptions.retryDelay)
 	if err != nil {
+		if owner, ownerErr := readLockOwner(filePath); ownerErr == nil {
+			return nil, fmt.Errorf("could not get file lock %q held by %s: %w", filePath, owner.String(), err)
+		}
 		return nil, fmt.Errorf("could not get file lock %q: %w", filePath, err)
 	}
 	if !locked {
 		return nil, fmt.Errorf("could not lock %q", filePath)
 	}
-	return flock, nil
+	if !exclusive {
+		return flock, nil
+	}
+	// Recording the owner is best-effort, it is only used for error messages.
+	_ = writeLockOwner(filePath)
+	return newOwnerUnlocker(flock, filePath), nil
 }
 
 type lockOptions struct {
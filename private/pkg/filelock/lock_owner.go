@@ -0,0 +1,103 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filelock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const lockOwnerFileExt = ".owner"
+
+// lockOwner is the recorded holder of an exclusive lock.
+type lockOwner struct {
+	PID         int       `json:"pid,omitempty"`
+	Hostname    string    `json:"hostname,omitempty"`
+	AcquireTime time.Time `json:"acquire_time,omitempty"`
+}
+
+func (l *lockOwner) String() string {
+	return fmt.Sprintf("process %d on %q since %s", l.PID, l.Hostname, l.AcquireTime.Format(time.RFC3339))
+}
+
+type ownerUnlocker struct {
+	delegate Unlocker
+	filePath string
+}
+
+func newOwnerUnlocker(delegate Unlocker, filePath string) *ownerUnlocker {
+	return &ownerUnlocker{
+		delegate: delegate,
+		filePath: filePath,
+	}
+}
+
+func (o *ownerUnlocker) Unlock() error {
+	// Remove the owner file before unlocking, so that the next owner never has its
+	// owner file removed.
+	var err error
+	if removeErr := os.Remove(getLockOwnerFilePath(o.filePath)); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+		err = removeErr
+	}
+	return multierr.Append(err, o.delegate.Unlock())
+}
+
+func getLockOwnerFilePath(filePath string) string {
+	return filePath + lockOwnerFileExt
+}
+
+func writeLockOwner(filePath string) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(
+		&lockOwner{
+			PID:         os.Getpid(),
+			Hostname:    hostname,
+			AcquireTime: time.Now().UTC(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	ownerFilePath := getLockOwnerFilePath(filePath)
+	tempOwnerFilePath := fmt.Sprintf("%s.%d.tmp", ownerFilePath, os.Getpid())
+	if err := os.WriteFile(tempOwnerFilePath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempOwnerFilePath, ownerFilePath)
+}
+
+// readLockOwner reads the lock owner for the lock file.
+//
+// Returns error if there is no valid owner recorded.
+func readLockOwner(filePath string) (*lockOwner, error) {
+	data, err := os.ReadFile(getLockOwnerFilePath(filePath))
+	if err != nil {
+		return nil, err
+	}
+	owner := &lockOwner{}
+	if err := json.Unmarshal(data, owner); err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
@@ -308,6 +308,36 @@ func GetCurrentHEADGitCommit(
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// ListBranchesAndTags returns the names of the local branches and tags of the
+// repository with the given git directory, such as ".git".
+func ListBranchesAndTags(
+	ctx context.Context,
+	runner command.Runner,
+	envContainer app.EnvContainer,
+	gitDirPath string,
+) (branches []string, tags []string, _ error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	if err := runner.Run(
+		ctx,
+		gitCommand,
+		command.RunWithArgs("--git-dir", gitDirPath, "for-each-ref", "--format=%(refname)", headsPrefix, tagsPrefix),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(stderr),
+		command.RunWithEnv(app.EnvironMap(envContainer)),
+	); err != nil {
+		return nil, nil, fmt.Errorf("failed to list branches and tags: %w: %s", err, stderr.String())
+	}
+	for _, ref := range getAllTrimmedLinesFromBuffer(stdout) {
+		if branch, ok := strings.CutPrefix(ref, headsPrefix); ok {
+			branches = append(branches, branch)
+		} else if tag, ok := strings.CutPrefix(ref, tagsPrefix); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return branches, tags, nil
+}
+
 // GetRefsForGitCommitAndRemote returns all refs pointing to a given commit based on the
 // given remote for the given directory. Querying the remote for refs information requires
 // passing the environment for permissions.
@@ -326,6 +326,20 @@ func readBucketForName(ctx context.Context, t *testing.T, runner command.Runner,
 	return readWriteBucket
 }
 
+func TestListBranchesAndTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container, err := app.NewContainerForOS()
+	require.NoError(t, err)
+	runner := command.NewRunner()
+	originDir, _ := createGitDirs(ctx, t, container, runner)
+
+	branches, tags, err := ListBranchesAndTags(ctx, runner, container, filepath.Join(originDir, ".git"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main", "remote-branch"}, branches)
+	assert.Equal(t, []string{"remote-annotated-tag", "remote-tag"}, tags)
+}
+
 func createGitDirs(
 	ctx context.Context,
 	t *testing.T,
@@ -50,6 +50,27 @@ func LockedWriter(writer io.Writer) io.Writer {
 	return &lockedWriter{writer: writer}
 }
 
+// LimitedWriter is an io.Writer that writes at most a limit of bytes to a delegate io.Writer.
+//
+// Bytes written past the limit are discarded, but still counted, so that callers can
+// report the full size of the data after the fact.
+type LimitedWriter interface {
+	io.Writer
+	// Size returns the number of bytes written to the LimitedWriter, including those
+	// that were discarded.
+	Size() int64
+	// Exceeded returns true if more than the limit of bytes were written to the LimitedWriter.
+	Exceeded() bool
+}
+
+// NewLimitedWriter returns a new LimitedWriter that writes at most limit bytes to the writer.
+//
+// Writes never return an error due to the limit, so that the writer of the data is not
+// interrupted. Callers check Exceeded once all data has been written.
+func NewLimitedWriter(writer io.Writer, limit int64) LimitedWriter {
+	return &limitedWriter{writer: writer, limit: limit}
+}
+
 // CompositeReadCloser returns a io.ReadCloser that is a composite of the Reader and Closer.
 func CompositeReadCloser(reader io.Reader, closer io.Closer) io.ReadCloser {
 	return compositeReadCloser{Reader: reader, Closer: closer}
@@ -114,6 +135,34 @@ func (l *lockedWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+type limitedWriter struct {
+	writer io.Writer
+	limit  int64
+	size   int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if remaining := l.limit - l.size; remaining > 0 {
+		toWrite := p
+		if int64(len(toWrite)) > remaining {
+			toWrite = toWrite[:remaining]
+		}
+		if _, err := l.writer.Write(toWrite); err != nil {
+			return 0, err
+		}
+	}
+	l.size += int64(len(p))
+	return len(p), nil
+}
+
+func (l *limitedWriter) Size() int64 {
+	return l.size
+}
+
+func (l *limitedWriter) Exceeded() bool {
+	return l.size > l.limit
+}
+
 type compositeReadCloser struct {
 	io.Reader
 	io.Closer
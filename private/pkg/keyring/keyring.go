@@ -0,0 +1,49 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keyring provides access to the credential store of the operating system.
+package keyring
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+// Keyring stores secrets by service and account.
+type Keyring interface {
+	// Get gets the secret for the service and account.
+	//
+	// Returns an error that fulfills fs.ErrNotExist if there is no such secret.
+	Get(ctx context.Context, service string, account string) (string, error)
+	// Set sets the secret for the service and account, replacing any existing secret.
+	Set(ctx context.Context, service string, account string, secret string) error
+	// Delete deletes the secret for the service and account.
+	//
+	// Returns an error that fulfills fs.ErrNotExist if there is no such secret.
+	Delete(ctx context.Context, service string, account string) error
+}
+
+// NewOSKeyring returns a new Keyring for the credential store of the operating system.
+//
+// This is the Keychain on macOS, the Credential Manager on Windows, and the Secret Service
+// on other platforms. The Secret Service is accessed with the secret-tool command from
+// libsecret, which must be on the PATH of the EnvContainer.
+//
+// Returns an error that fulfills errors.ErrUnsupported if the operating system does not
+// have a supported credential store.
+func NewOSKeyring(envContainer app.EnvContainer, runner command.Runner) (Keyring, error) {
+	return newOSKeyring(envContainer, runner)
+}
@@ -0,0 +1,123 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+const (
+	securityFilePath = "/usr/bin/security"
+	// securityItemNotFoundExitCode is the exit code of security if the item
+	// could not be found.
+	securityItemNotFoundExitCode = 44
+)
+
+// securityKeyring is a Keyring for the macOS Keychain that uses the security command.
+type securityKeyring struct {
+	environ []string
+	runner  command.Runner
+}
+
+func newOSKeyring(envContainer app.EnvContainer, runner command.Runner) (*securityKeyring, error) {
+	return &securityKeyring{
+		environ: app.Environ(envContainer),
+		runner:  runner,
+	}, nil
+}
+
+func (k *securityKeyring) Get(ctx context.Context, service string, account string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	if err := k.run(
+		ctx,
+		[]string{"find-generic-password", "-s", service, "-a", account, "-w"},
+		nil,
+		stdout,
+	); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+func (k *securityKeyring) Set(ctx context.Context, service string, account string, secret string) error {
+	// Run security interactively and pass the command on stdin, so that the secret
+	// is not visible in the process list.
+	return k.run(
+		ctx,
+		[]string{"-i"},
+		strings.NewReader(
+			fmt.Sprintf(
+				"add-generic-password -U -s %s -a %s -w %s\n",
+				quote(service),
+				quote(account),
+				quote(secret),
+			),
+		),
+		nil,
+	)
+}
+
+func (k *securityKeyring) Delete(ctx context.Context, service string, account string) error {
+	return k.run(
+		ctx,
+		[]string{"delete-generic-password", "-s", service, "-a", account},
+		nil,
+		nil,
+	)
+}
+
+func (k *securityKeyring) run(
+	ctx context.Context,
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+) error {
+	stderr := bytes.NewBuffer(nil)
+	options := []command.RunOption{
+		command.RunWithArgs(args...),
+		command.RunWithEnviron(k.environ),
+		command.RunWithStderr(stderr),
+	}
+	if stdin != nil {
+		options = append(options, command.RunWithStdin(stdin))
+	}
+	if stdout != nil {
+		options = append(options, command.RunWithStdout(stdout))
+	}
+	if err := k.runner.Run(ctx, securityFilePath, options...); err != nil {
+		exitError := &exec.ExitError{}
+		if errors.As(err, &exitError) && exitError.ExitCode() == securityItemNotFoundExitCode {
+			return fs.ErrNotExist
+		}
+		return fmt.Errorf("security %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// quote quotes the value for the command parser of security -i, which
+// follows the quoting rules of the shell.
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
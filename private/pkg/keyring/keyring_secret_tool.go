@@ -0,0 +1,120 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !darwin && !windows
+
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+const secretToolName = "secret-tool"
+
+// secretToolKeyring is a Keyring for the Secret Service that uses the secret-tool
+// command from libsecret.
+type secretToolKeyring struct {
+	environ            []string
+	runner             command.Runner
+	secretToolFilePath string
+}
+
+func newOSKeyring(envContainer app.EnvContainer, runner command.Runner) (*secretToolKeyring, error) {
+	for _, dirPath := range filepath.SplitList(envContainer.Env("PATH")) {
+		if dirPath == "" {
+			continue
+		}
+		if filePath, err := exec.LookPath(filepath.Join(dirPath, secretToolName)); err == nil {
+			return newSecretToolKeyring(app.Environ(envContainer), runner, filePath), nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found on PATH: %w", secretToolName, errors.ErrUnsupported)
+}
+
+func newSecretToolKeyring(environ []string, runner command.Runner, secretToolFilePath string) *secretToolKeyring {
+	return &secretToolKeyring{
+		environ:            environ,
+		runner:             runner,
+		secretToolFilePath: secretToolFilePath,
+	}
+}
+
+func (k *secretToolKeyring) Get(ctx context.Context, service string, account string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	if err := k.run(ctx, []string{"lookup", "service", service, "account", account}, nil, stdout); err != nil {
+		// secret-tool lookup exits with 1 and no output if there is no such secret.
+		exitError := &exec.ExitError{}
+		if errors.As(err, &exitError) && exitError.ExitCode() == 1 && stdout.Len() == 0 {
+			return "", fs.ErrNotExist
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (k *secretToolKeyring) Set(ctx context.Context, service string, account string, secret string) error {
+	// The secret is read from stdin, so that it is not visible in the process list.
+	return k.run(
+		ctx,
+		[]string{"store", "--label", service + " (" + account + ")", "service", service, "account", account},
+		strings.NewReader(secret),
+		nil,
+	)
+}
+
+func (k *secretToolKeyring) Delete(ctx context.Context, service string, account string) error {
+	// secret-tool clear does not report whether a secret was deleted.
+	if _, err := k.Get(ctx, service, account); err != nil {
+		return err
+	}
+	return k.run(ctx, []string{"clear", "service", service, "account", account}, nil, nil)
+}
+
+func (k *secretToolKeyring) run(
+	ctx context.Context,
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+) error {
+	stderr := bytes.NewBuffer(nil)
+	options := []command.RunOption{
+		command.RunWithArgs(args...),
+		command.RunWithEnviron(k.environ),
+		command.RunWithStderr(stderr),
+	}
+	if stdin != nil {
+		options = append(options, command.RunWithStdin(stdin))
+	}
+	if stdout != nil {
+		options = append(options, command.RunWithStdout(stdout))
+	}
+	if err := k.runner.Run(ctx, k.secretToolFilePath, options...); err != nil {
+		if stderr.Len() == 0 {
+			return err
+		}
+		return fmt.Errorf("%s %s: %w: %s", secretToolName, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !darwin && !windows
+
+package keyring
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSecretTool emulates secret-tool with a file per service and account.
+const fakeSecretTool = `#!/bin/sh
+set -e
+command="$1"
+if [ "$command" = "store" ]; then
+  shift 2
+fi
+file="$SECRETS_DIR/$3.$5"
+case "$command" in
+  store) cat > "$file" ;;
+  lookup) [ -f "$file" ] || exit 1; cat "$file" ;;
+  clear) rm -f "$file" ;;
+esac
+`
+
+func TestSecretToolKeyring(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dirPath := t.TempDir()
+	secretToolFilePath := filepath.Join(dirPath, secretToolName)
+	require.NoError(t, os.WriteFile(secretToolFilePath, []byte(fakeSecretTool), 0755))
+	secretsDirPath := t.TempDir()
+
+	keyring, err := newOSKeyring(
+		app.NewEnvContainer(map[string]string{"PATH": dirPath + string(os.PathListSeparator) + os.Getenv("PATH"), "SECRETS_DIR": secretsDirPath}),
+		command.NewRunner(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, secretToolFilePath, keyring.secretToolFilePath)
+
+	_, err = keyring.Get(ctx, "buf", "buf.build")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	require.NoError(t, keyring.Set(ctx, "buf", "buf.build", "secret\nwith newline"))
+	secret, err := keyring.Get(ctx, "buf", "buf.build")
+	require.NoError(t, err)
+	assert.Equal(t, "secret\nwith newline", secret)
+	require.NoError(t, keyring.Set(ctx, "buf", "buf.build", "other"))
+	secret, err = keyring.Get(ctx, "buf", "buf.build")
+	require.NoError(t, err)
+	assert.Equal(t, "other", secret)
+	require.NoError(t, keyring.Delete(ctx, "buf", "buf.build"))
+	assert.ErrorIs(t, keyring.Delete(ctx, "buf", "buf.build"), fs.ErrNotExist)
+
+	_, err = newOSKeyring(app.NewEnvContainer(map[string]string{"PATH": t.TempDir()}), command.NewRunner())
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+}
@@ -0,0 +1,136 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"unsafe"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure.
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/wincred/ns-wincred-credentialw
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialManagerKeyring is a Keyring for the Windows Credential Manager.
+type credentialManagerKeyring struct{}
+
+func newOSKeyring(app.EnvContainer, command.Runner) (*credentialManagerKeyring, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errors.ErrUnsupported, err)
+	}
+	return &credentialManagerKeyring{}, nil
+}
+
+func (*credentialManagerKeyring) Get(_ context.Context, service string, account string) (string, error) {
+	targetName, err := windows.UTF16PtrFromString(getTargetName(service, account))
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	if r, _, err := procCredReadW.Call(
+		uintptr(unsafe.Pointer(targetName)),
+		credTypeGeneric,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	); r == 0 {
+		return "", convertError(err)
+	}
+	defer func() {
+		_, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	}()
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (*credentialManagerKeyring) Set(_ context.Context, service string, account string, secret string) error {
+	targetName, err := windows.UTF16PtrFromString(getTargetName(service, account))
+	if err != nil {
+		return err
+	}
+	userName, err := windows.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	cred := &credential{
+		Type:               credTypeGeneric,
+		TargetName:         targetName,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(secret) > 0 {
+		blob := []byte(secret)
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(cred)), 0); r == 0 {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (*credentialManagerKeyring) Delete(_ context.Context, service string, account string) error {
+	targetName, err := windows.UTF16PtrFromString(getTargetName(service, account))
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(targetName)), credTypeGeneric, 0); r == 0 {
+		return convertError(err)
+	}
+	return nil
+}
+
+func getTargetName(service string, account string) string {
+	return service + ":" + account
+}
+
+func convertError(err error) error {
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return fs.ErrNotExist
+	}
+	return err
+}
@@ -55,6 +55,45 @@ func GetMachineForName(envContainer app.EnvContainer, name string) (_ Machine, r
 	return GetMachineForNameAndFilePath(name, filePath)
 }
 
+// GetMachines returns all Machines in the configured netrc file, in the order they
+// appear in the file.
+//
+// The default machine, if any, has an empty name. Returns an empty slice if there
+// is no netrc file.
+func GetMachines(envContainer app.EnvContainer) ([]Machine, error) {
+	filePath, err := GetFilePath(envContainer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	netrcStruct, err := netrc.Parse(filePath)
+	if err != nil {
+		return nil, err
+	}
+	netrcMachines := netrcStruct.Machines()
+	machines := make([]Machine, 0, len(netrcMachines))
+	for _, netrcMachine := range netrcMachines {
+		machineName := netrcMachine.Name
+		if netrcMachine.IsDefault {
+			machineName = ""
+		}
+		machines = append(
+			machines,
+			newMachine(
+				machineName,
+				netrcMachine.Get("login"),
+				netrcMachine.Get("password"),
+			),
+		)
+	}
+	return machines, nil
+}
+
 // PutMachines adds the given Machines to the configured netrc file.
 func PutMachines(envContainer app.EnvContainer, machines ...Machine) error {
 	filePath, err := GetFilePath(envContainer)
@@ -93,6 +93,19 @@ func TestGetMachineForName(t *testing.T) {
 	)
 }
 
+func TestGetMachines(t *testing.T) {
+	t.Parallel()
+	machines, err := GetMachines(app.NewEnvContainer(map[string]string{"HOME": "testdata/unix/home1"}))
+	require.NoError(t, err)
+	assert.Equal(t, []Machine{NewMachine("foo.com", "bar", "baz")}, machines)
+	machines, err = GetMachines(app.NewEnvContainer(map[string]string{"HOME": "testdata/unix/home2"}))
+	require.NoError(t, err)
+	assert.Equal(t, []Machine{NewMachine("", "bar", "baz")}, machines)
+	machines, err = GetMachines(app.NewEnvContainer(map[string]string{"HOME": t.TempDir()}))
+	require.NoError(t, err)
+	assert.Empty(t, machines)
+}
+
 func TestPutMachines(t *testing.T) {
 	t.Parallel()
 	testPutMachinesSuccess(
@@ -37,18 +37,81 @@ const (
 )
 
 // Client is an OAuth 2.0 client that can register a device, authorize a device,
-// and poll for the device access token.
+// poll for the device access token, and refresh an access token.
 type Client struct {
 	baseURL string
 	client  *http.Client
+
+	registrationURL        string
+	deviceAuthorizationURL string
+	tokenURL               string
 }
 
 // NewClient returns a new Client with the given base URL and HTTP client.
-func NewClient(baseURL string, client *http.Client) *Client {
-	return &Client{
-		baseURL: strings.TrimSuffix(baseURL, "/"),
-		client:  client,
+//
+// By default, the endpoints are the Device*Path constants relative to the base URL.
+func NewClient(baseURL string, client *http.Client, options ...ClientOption) *Client {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	c := &Client{
+		baseURL:                baseURL,
+		client:                 client,
+		registrationURL:        baseURL + DeviceRegistrationPath,
+		deviceAuthorizationURL: baseURL + DeviceAuthorizationPath,
+		tokenURL:               baseURL + DeviceTokenPath,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*Client)
+
+// ClientWithServerMetadata returns a new ClientOption that uses the endpoints of the
+// authorization server metadata, as returned by GetServerMetadata.
+//
+// Endpoints that are not set in the metadata keep their defaults.
+func ClientWithServerMetadata(serverMetadata *ServerMetadata) ClientOption {
+	return func(c *Client) {
+		if serverMetadata.RegistrationEndpoint != "" {
+			c.registrationURL = serverMetadata.RegistrationEndpoint
+		}
+		if serverMetadata.DeviceAuthorizationEndpoint != "" {
+			c.deviceAuthorizationURL = serverMetadata.DeviceAuthorizationEndpoint
+		}
+		if serverMetadata.TokenEndpoint != "" {
+			c.tokenURL = serverMetadata.TokenEndpoint
+		}
+	}
+}
+
+// GetServerMetadata gets the OpenID Connect Discovery 1.0 metadata of the authorization server.
+//
+// Returns an error that wraps errors.ErrUnsupported if the server does not serve metadata.
+func (c *Client) GetServerMetadata(ctx context.Context) (_ *ServerMetadata, retErr error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+ServerMetadataPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+
+	payload := &ServerMetadata{}
+	if err := parseJSONResponse(response, payload); err != nil {
+		return nil, err
+	}
+	if code := response.StatusCode; code != http.StatusOK {
+		return nil, fmt.Errorf("oauth2: invalid status: %v", code)
 	}
+	return payload, nil
 }
 
 // RegisterDevice registers a new device with the authorization server.
@@ -61,7 +124,7 @@ func (c *Client) RegisterDevice(
 		return nil, err
 	}
 	body := bytes.NewReader(input)
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+DeviceRegistrationPath, body)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.registrationURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +162,7 @@ func (c *Client) AuthorizeDevice(
 	deviceAuthorizationRequest *DeviceAuthorizationRequest,
 ) (_ *DeviceAuthorizationResponse, retErr error) {
 	body := strings.NewReader(deviceAuthorizationRequest.ToValues().Encode())
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+DeviceAuthorizationPath, body)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.deviceAuthorizationURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +221,7 @@ func (c *Client) AccessDeviceToken(
 			return nil, ctx.Err()
 		case <-timer.C:
 			body := strings.NewReader(encodedValues)
-			request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+DeviceTokenPath, body)
+			request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, body)
 			if err != nil {
 				return nil, err
 			}
@@ -202,6 +265,46 @@ func (c *Client) AccessDeviceToken(
 	}
 }
 
+// RefreshAccessToken exchanges a refresh token for a new access token.
+//
+// The response may contain a new refresh token, which replaces the refresh token of
+// the request.
+func (c *Client) RefreshAccessToken(
+	ctx context.Context,
+	refreshTokenRequest *RefreshTokenRequest,
+) (_ *DeviceAccessTokenResponse, retErr error) {
+	body := strings.NewReader(refreshTokenRequest.ToValues().Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+
+	payload := &struct {
+		Error
+		DeviceAccessTokenResponse
+	}{}
+	if err := parseJSONResponse(response, payload); err != nil {
+		return nil, err
+	}
+	if payload.ErrorCode != "" {
+		return nil, &payload.Error
+	}
+	if code := response.StatusCode; code != http.StatusOK {
+		return nil, fmt.Errorf("oauth2: invalid status: %v", code)
+	}
+	return &payload.DeviceAccessTokenResponse, nil
+}
+
 // AccessDeviceTokenOption is an option for AccessDeviceToken.
 type AccessDeviceTokenOption func(*accessDeviceTokenOptions)
 
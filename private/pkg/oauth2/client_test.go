@@ -279,6 +279,104 @@ func TestAccessDeviceToken(t *testing.T) {
 	}
 }
 
+func TestGetServerMetadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := NewClient("https://buf.build", &http.Client{
+		Transport: testRoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, r.Method, http.MethodGet)
+			assert.Equal(t, r.URL.Path, ServerMetadataPath)
+			return testNewJSONResponse(t, http.StatusOK, `{"issuer":"https://buf.build","token_endpoint":"https://idp.example.com/token","device_authorization_endpoint":"https://idp.example.com/device","scopes_supported":["openid","offline_access"]}`), nil
+		}),
+	})
+	serverMetadata, err := c.GetServerMetadata(ctx)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		&ServerMetadata{
+			Issuer:                      "https://buf.build",
+			TokenEndpoint:               "https://idp.example.com/token",
+			DeviceAuthorizationEndpoint: "https://idp.example.com/device",
+			ScopesSupported:             []string{"openid", "offline_access"},
+		},
+		serverMetadata,
+	)
+	// The endpoints of the metadata are used, and the registration endpoint keeps its default.
+	var requestURLs []string
+	c = NewClient("https://buf.build", &http.Client{
+		Transport: testRoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requestURLs = append(requestURLs, r.URL.String())
+			return testNewJSONResponse(t, http.StatusBadRequest, `{"error":"invalid_request"}`), nil
+		}),
+	}, ClientWithServerMetadata(serverMetadata))
+	_, _ = c.RegisterDevice(ctx, &DeviceRegistrationRequest{})
+	_, _ = c.AuthorizeDevice(ctx, &DeviceAuthorizationRequest{})
+	_, _ = c.RefreshAccessToken(ctx, &RefreshTokenRequest{})
+	assert.Equal(
+		t,
+		[]string{
+			"https://buf.build" + DeviceRegistrationPath,
+			"https://idp.example.com/device",
+			"https://idp.example.com/token",
+		},
+		requestURLs,
+	)
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		transport func(t *testing.T, r *http.Request) (*http.Response, error)
+		output    *DeviceAccessTokenResponse
+		err       error
+	}{{
+		name: "success",
+		transport: func(t *testing.T, r *http.Request) (*http.Response, error) {
+			testAssertFormRequest(t, r, url.Values{"client_id": {"clientID"}, "refresh_token": {"refreshToken"}, "grant_type": {"refresh_token"}})
+			return testNewJSONResponse(t, http.StatusOK, `{"access_token":"accessToken","token_type":"bearer","expires_in":3600,"refresh_token":"newRefreshToken"}`), nil
+		},
+		output: &DeviceAccessTokenResponse{
+			AccessToken:  "accessToken",
+			TokenType:    "bearer",
+			ExpiresIn:    3600,
+			RefreshToken: "newRefreshToken",
+		},
+	}, {
+		name: "error",
+		transport: func(t *testing.T, r *http.Request) (*http.Response, error) {
+			return testNewJSONResponse(t, http.StatusBadRequest, `{"error":"invalid_grant","error_description":"refresh token revoked"}`), nil
+		},
+		err: &Error{
+			ErrorCode:        ErrorCodeInvalidGrant,
+			ErrorDescription: "refresh token revoked",
+		},
+	}}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			c := NewClient("https://buf.build", &http.Client{
+				Transport: testRoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					assert.Equal(t, r.Method, http.MethodPost)
+					assert.Equal(t, r.URL.Path, DeviceTokenPath)
+					assert.Equal(t, r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+					return test.transport(t, r)
+				}),
+			})
+			output, err := c.RefreshAccessToken(ctx, &RefreshTokenRequest{
+				ClientID:     "clientID",
+				RefreshToken: "refreshToken",
+				GrantType:    RefreshTokenGrantType,
+			})
+			assert.Equal(t, test.output, output)
+			assert.Equal(t, err, test.err)
+		})
+	}
+}
+
 type testRoundTripFunc func(r *http.Request) (*http.Response, error)
 
 func (s testRoundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
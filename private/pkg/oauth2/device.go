@@ -78,15 +78,20 @@ type DeviceAuthorizationRequest struct {
 	ClientID string `json:"client_id"`
 	// ClientSecret is the client secret. May be empty.
 	ClientSecret string `json:"client_secret,omitempty"`
+	// Scope is the space-separated scope of the access request. May be empty.
+	Scope string `json:"scope,omitempty"`
 }
 
 // ToValues converts the DeviceAuthorizationRequest to url.Values.
 func (d *DeviceAuthorizationRequest) ToValues() url.Values {
-	values := make(url.Values, 2)
+	values := make(url.Values, 3)
 	values.Set("client_id", d.ClientID)
 	if d.ClientSecret != "" {
 		values.Set("client_secret", d.ClientSecret)
 	}
+	if d.Scope != "" {
+		values.Set("scope", d.Scope)
+	}
 	return values
 }
 
@@ -94,6 +99,7 @@ func (d *DeviceAuthorizationRequest) ToValues() url.Values {
 func (d *DeviceAuthorizationRequest) FromValues(values url.Values) error {
 	d.ClientID = values.Get("client_id")
 	d.ClientSecret = values.Get("client_secret")
+	d.Scope = values.Get("scope")
 	return nil
 }
 
@@ -0,0 +1,82 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth2
+
+import (
+	"net/url"
+)
+
+const (
+	// ServerMetadataPath is the path for the OpenID Connect Discovery 1.0 metadata endpoint.
+	ServerMetadataPath = "/.well-known/openid-configuration"
+	// RefreshTokenGrantType is the grant type for refreshing an access token.
+	RefreshTokenGrantType = "refresh_token"
+	// OfflineAccessScope is the scope that requests a refresh token from an
+	// OpenID Connect provider.
+	OfflineAccessScope = "offline_access"
+)
+
+// ServerMetadata describes the OpenID Connect Discovery 1.0 metadata of an authorization
+// server. It is a subset of the full specification.
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type ServerMetadata struct {
+	// Issuer is the issuer identifier of the authorization server.
+	Issuer string `json:"issuer"`
+	// TokenEndpoint is the URL of the token endpoint.
+	TokenEndpoint string `json:"token_endpoint"`
+	// DeviceAuthorizationEndpoint is the URL of the RFC 8628 device authorization endpoint.
+	// May be empty if the server does not support the device authorization flow.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+	// RegistrationEndpoint is the URL of the dynamic client registration endpoint.
+	// May be empty if the server does not support dynamic client registration.
+	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
+	// ScopesSupported are the scopes that the server supports. May be empty.
+	ScopesSupported []string `json:"scopes_supported,omitempty"`
+}
+
+// RefreshTokenRequest describes an RFC 6749 Refresh Token Request.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-6
+type RefreshTokenRequest struct {
+	// ClientID is the client identifier issued to the client during the registration process.
+	ClientID string `json:"client_id"`
+	// ClientSecret is the client secret. May be empty.
+	ClientSecret string `json:"client_secret,omitempty"`
+	// RefreshToken is the refresh token issued to the client.
+	RefreshToken string `json:"refresh_token"`
+	// GrantType is the grant type for refreshing an access token. Must be
+	// set to "refresh_token".
+	GrantType string `json:"grant_type"`
+}
+
+// ToValues converts the RefreshTokenRequest to url.Values.
+func (r *RefreshTokenRequest) ToValues() url.Values {
+	values := make(url.Values, 4)
+	values.Set("client_id", r.ClientID)
+	if r.ClientSecret != "" {
+		values.Set("client_secret", r.ClientSecret)
+	}
+	values.Set("refresh_token", r.RefreshToken)
+	values.Set("grant_type", r.GrantType)
+	return values
+}
+
+// FromValues converts the url.Values to a RefreshTokenRequest.
+func (r *RefreshTokenRequest) FromValues(values url.Values) error {
+	r.ClientID = values.Get("client_id")
+	r.ClientSecret = values.Get("client_secret")
+	r.RefreshToken = values.Get("refresh_token")
+	r.GrantType = values.Get("grant_type")
+	return nil
+}
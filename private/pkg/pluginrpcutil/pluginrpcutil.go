@@ -15,19 +15,169 @@
 package pluginrpcutil
 
 import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/wasm"
 	"pluginrpc.com/pluginrpc"
 )
 
+// RemoteRunProcedure is the Connect procedure of a remote execution service that runs plugins.
+const RemoteRunProcedure = "/buf.plugin.exec.v1.ExecutionService/Run"
+
+// RemoteRunRequest is a request to run a plugin on a remote execution service.
+//
+// This is encoded as JSON, as it is not a Protobuf message.
+type RemoteRunRequest struct {
+	// ProgramName is the name of the plugin program to run.
+	ProgramName string `json:"program_name,omitempty"`
+	// Args are the arguments to the plugin program.
+	Args []string `json:"args,omitempty"`
+	// Stdin is the stdin of the plugin program.
+	Stdin []byte `json:"stdin,omitempty"`
+}
+
+// RemoteRunResponse is the response of a plugin run on a remote execution service.
+//
+// This is encoded as JSON, as it is not a Protobuf message.
+type RemoteRunResponse struct {
+	// Stdout is the stdout of the plugin program.
+	Stdout []byte `json:"stdout,omitempty"`
+	// Stderr is the stderr of the plugin program.
+	Stderr []byte `json:"stderr,omitempty"`
+	// ExitCode is the exit code of the plugin program.
+	ExitCode int `json:"exit_code,omitempty"`
+}
+
 // NewRunner returns a new pluginrpc.Runner for the command.Runner and program name.
 func NewRunner(delegate command.Runner, programName string, programArgs ...string) pluginrpc.Runner {
-	return newRunner(delegate, programName, programArgs...)
+	return newRunner(delegate, programName, programArgs)
+}
+
+// NewRunnerWithOptions returns a new pluginrpc.Runner for the command.Runner, program name,
+// program arguments, and options.
+func NewRunnerWithOptions(
+	delegate command.Runner,
+	programName string,
+	programArgs []string,
+	options ...RunnerOption,
+) pluginrpc.Runner {
+	return newRunner(delegate, programName, programArgs, options...)
+}
+
+// RunnerOption is an option for NewRunnerWithOptions.
+type RunnerOption func(*runner)
+
+// RunnerWithEnv returns a new RunnerOption that sets the environment variables of the program.
+//
+// The default is to run the program with an empty environment, that is the environment of
+// the caller is never inherited.
+func RunnerWithEnv(env map[string]string) RunnerOption {
+	return func(runner *runner) {
+		runner.env = env
+	}
+}
+
+// RunnerWithDir returns a new RunnerOption that sets the working directory of the program.
+//
+// The default is the current working directory.
+func RunnerWithDir(dir string) RunnerOption {
+	return func(runner *runner) {
+		runner.dir = dir
+	}
 }
 
 // NewWasmRunner returns a new pluginrpc.Runner for the wasm.Runtime and program name.
 //
 // This runner is used for local Wasm plugins. The program name is the path to the Wasm file.
 func NewWasmRunner(delegate wasm.Runtime, programName string, programArgs ...string) pluginrpc.Runner {
-	return newWasmRunner(delegate, programName, programArgs...)
+	return newWasmRunner(delegate, programName, nil, programArgs...)
+}
+
+// NewWasmRunnerForModule returns a new pluginrpc.Runner for the wasm.Runtime and Wasm module.
+//
+// This runner is used for Wasm plugins that are not on the local filesystem, such as
+// plugins downloaded from a registry. The program name is only used to identify the
+// module. The module is compiled on the first run, and runs with the same
+// request/response framing as NewRunner, without spawning a process.
+func NewWasmRunnerForModule(delegate wasm.Runtime, programName string, moduleWasm []byte, programArgs ...string) pluginrpc.Runner {
+	return newWasmRunner(delegate, programName, moduleWasm, programArgs...)
+}
+
+// NewRemoteRunner returns a new pluginrpc.Runner that runs the program on the remote
+// execution service at the base URL.
+//
+// Each invocation is forwarded over Connect to RemoteRunProcedure. The stdout and stderr
+// of the remote program are written to the stdout and stderr of the invocation, and a
+// non-zero exit code is returned as a *pluginrpc.ExitError.
+func NewRemoteRunner(httpClient connect.HTTPClient, baseURL string, programName string, programArgs ...string) pluginrpc.Runner {
+	return newRemoteRunner(httpClient, baseURL, programName, programArgs...)
+}
+
+// NewRemoteRunHandler returns a new path and http.Handler for a remote execution
+// service that runs plugins with the pluginrpc.Runners returned by getRunner.
+//
+// This can be mounted on an http.ServeMux, and is the server for NewRemoteRunner.
+func NewRemoteRunHandler(getRunner func(programName string) (pluginrpc.Runner, error)) (string, http.Handler) {
+	return RemoteRunProcedure, connect.NewUnaryHandler(
+		RemoteRunProcedure,
+		newRemoteRunHandler(getRunner).Run,
+		connect.WithCodec(remoteRunCodec{}),
+	)
+}
+
+// RunnerInterceptor intercepts and adapts invocations of a pluginrpc.Runner.
+//
+// The programName is the name of the program run by the pluginrpc.Runner, for use in
+// logging and errors. Interceptors are used to uniformly attach timeouts, retries, logging,
+// and metrics to every plugin invocation.
+type RunnerInterceptor func(programName string, next pluginrpc.Runner) pluginrpc.Runner
+
+// NewInterceptedRunner returns a new pluginrpc.Runner that applies the interceptors to
+// every invocation of the delegate.
+//
+// The interceptors are applied in the order they are declared, that is the first
+// interceptor is the outermost.
+func NewInterceptedRunner(delegate pluginrpc.Runner, programName string, interceptors ...RunnerInterceptor) pluginrpc.Runner {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		if interceptor := interceptors[i]; interceptor != nil {
+			delegate = interceptor(programName, delegate)
+		}
+	}
+	return delegate
+}
+
+// NewTimeoutRunnerInterceptor returns a new RunnerInterceptor that cancels each
+// invocation after the timeout.
+func NewTimeoutRunnerInterceptor(timeout time.Duration) RunnerInterceptor {
+	return newTimeoutRunnerInterceptor(timeout)
+}
+
+// NewRetryRunnerInterceptor returns a new RunnerInterceptor that retries each invocation
+// up to maxAttempts times in total, with exponential backoff.
+//
+// Only errors that are not a *pluginrpc.ExitError are retried, that is failures to run
+// the plugin, as opposed to the plugin itself failing. The stdin of the invocation is
+// buffered so it can be replayed, and only the stdout and stderr of the last attempt
+// are written.
+func NewRetryRunnerInterceptor(maxAttempts int) RunnerInterceptor {
+	return newRetryRunnerInterceptor(maxAttempts)
+}
+
+// NewLoggingRunnerInterceptor returns a new RunnerInterceptor that logs the duration
+// and any error of each invocation at debug level.
+func NewLoggingRunnerInterceptor(logger *slog.Logger) RunnerInterceptor {
+	return newLoggingRunnerInterceptor(logger)
+}
+
+// NewOutputLimitRunnerInterceptor returns a new RunnerInterceptor that fails each
+// invocation in which the program writes more than outputLimit bytes to stdout.
+//
+// Output past the limit is discarded instead of being buffered in memory. An outputLimit
+// of 0 or less means no limit.
+func NewOutputLimitRunnerInterceptor(outputLimit int64) RunnerInterceptor {
+	return newOutputLimitRunnerInterceptor(outputLimit)
 }
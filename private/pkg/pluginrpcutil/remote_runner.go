@@ -0,0 +1,161 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"pluginrpc.com/pluginrpc"
+)
+
+type remoteRunner struct {
+	client      *connect.Client[RemoteRunRequest, RemoteRunResponse]
+	programName string
+	programArgs []string
+}
+
+func newRemoteRunner(
+	httpClient connect.HTTPClient,
+	baseURL string,
+	programName string,
+	programArgs ...string,
+) *remoteRunner {
+	return &remoteRunner{
+		client: connect.NewClient[RemoteRunRequest, RemoteRunResponse](
+			httpClient,
+			strings.TrimSuffix(baseURL, "/")+RemoteRunProcedure,
+			connect.WithCodec(remoteRunCodec{}),
+		),
+		programName: programName,
+		programArgs: programArgs,
+	}
+}
+
+func (r *remoteRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	args := env.Args
+	if len(r.programArgs) > 0 {
+		args = append(slices.Clone(r.programArgs), env.Args...)
+	}
+	var stdin []byte
+	if env.Stdin != nil {
+		var err error
+		stdin, err = io.ReadAll(env.Stdin)
+		if err != nil {
+			return err
+		}
+	}
+	response, err := r.client.CallUnary(
+		ctx,
+		connect.NewRequest(
+			&RemoteRunRequest{
+				ProgramName: r.programName,
+				Args:        args,
+				Stdin:       stdin,
+			},
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("could not run plugin %q remotely: %w", r.programName, err)
+	}
+	if err := writeAll(env.Stdout, response.Msg.Stdout); err != nil {
+		return err
+	}
+	if err := writeAll(env.Stderr, response.Msg.Stderr); err != nil {
+		return err
+	}
+	if exitCode := response.Msg.ExitCode; exitCode != 0 {
+		return pluginrpc.NewExitError(exitCode, fmt.Errorf("remote plugin %q exited with code %d", r.programName, exitCode))
+	}
+	return nil
+}
+
+type remoteRunHandler struct {
+	getRunner func(programName string) (pluginrpc.Runner, error)
+}
+
+func newRemoteRunHandler(
+	getRunner func(programName string) (pluginrpc.Runner, error),
+) *remoteRunHandler {
+	return &remoteRunHandler{
+		getRunner: getRunner,
+	}
+}
+
+func (h *remoteRunHandler) Run(
+	ctx context.Context,
+	request *connect.Request[RemoteRunRequest],
+) (*connect.Response[RemoteRunResponse], error) {
+	if request.Msg.ProgramName == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("program name is required"))
+	}
+	runner, err := h.getRunner(request.Msg.ProgramName)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	response := &RemoteRunResponse{}
+	if err := runner.Run(
+		ctx,
+		pluginrpc.Env{
+			Args:   request.Msg.Args,
+			Stdin:  bytes.NewReader(request.Msg.Stdin),
+			Stdout: stdout,
+			Stderr: stderr,
+		},
+	); err != nil {
+		exitError := &pluginrpc.ExitError{}
+		if !errors.As(err, &exitError) {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		response.ExitCode = exitError.ExitCode()
+	}
+	response.Stdout = stdout.Bytes()
+	response.Stderr = stderr.Bytes()
+	return connect.NewResponse(response), nil
+}
+
+// remoteRunCodec is a JSON codec for RemoteRunRequests and RemoteRunResponses.
+//
+// These are not Protobuf messages, so we cannot use the default codecs.
+type remoteRunCodec struct{}
+
+func (remoteRunCodec) Name() string {
+	return "json"
+}
+
+func (remoteRunCodec) Marshal(src any) ([]byte, error) {
+	return json.Marshal(src)
+}
+
+func (remoteRunCodec) Unmarshal(src []byte, dst any) error {
+	return json.Unmarshal(src, dst)
+}
+
+func writeAll(writer io.Writer, data []byte) error {
+	if writer == nil || len(data) == 0 {
+		return nil
+	}
+	_, err := writer.Write(data)
+	return err
+}
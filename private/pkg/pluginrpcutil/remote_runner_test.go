@@ -0,0 +1,102 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestRemoteRunner(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.Handle(
+		NewRemoteRunHandler(
+			func(programName string) (pluginrpc.Runner, error) {
+				if programName != "buf-plugin-echo" {
+					return nil, fmt.Errorf("unknown program: %s", programName)
+				}
+				return runnerFunc(
+					func(ctx context.Context, env pluginrpc.Env) error {
+						data, err := io.ReadAll(env.Stdin)
+						if err != nil {
+							return err
+						}
+						if _, err := fmt.Fprintf(env.Stdout, "%s %s", strings.Join(env.Args, " "), data); err != nil {
+							return err
+						}
+						if _, err := env.Stderr.Write([]byte("warning")); err != nil {
+							return err
+						}
+						if len(data) == 0 {
+							return pluginrpc.NewExitError(3, errors.New("no stdin"))
+						}
+						return nil
+					},
+				), nil
+			},
+		),
+	)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	runner := NewRemoteRunner(server.Client(), server.URL, "buf-plugin-echo", "--foo")
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Args:   []string{"--bar"},
+			Stdin:  strings.NewReader("hello"),
+			Stdout: stdout,
+			Stderr: stderr,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "--foo --bar hello", stdout.String())
+	assert.Equal(t, "warning", stderr.String())
+
+	stdout.Reset()
+	err = runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Stdin:  strings.NewReader(""),
+			Stdout: stdout,
+			Stderr: io.Discard,
+		},
+	)
+	exitError := &pluginrpc.ExitError{}
+	require.ErrorAs(t, err, &exitError)
+	assert.Equal(t, 3, exitError.ExitCode())
+	assert.Equal(t, "--foo ", stdout.String())
+
+	err = NewRemoteRunner(server.Client(), server.URL, "buf-plugin-unknown").Run(
+		context.Background(),
+		pluginrpc.Env{},
+	)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &exitError))
+}
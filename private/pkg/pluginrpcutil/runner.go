@@ -28,18 +28,25 @@ type runner struct {
 	delegate    command.Runner
 	programName string
 	programArgs []string
+	env         map[string]string
+	dir         string
 }
 
 func newRunner(
 	delegate command.Runner,
 	programName string,
-	programArgs ...string,
+	programArgs []string,
+	options ...RunnerOption,
 ) *runner {
-	return &runner{
+	runner := &runner{
 		delegate:    delegate,
 		programName: programName,
 		programArgs: programArgs,
 	}
+	for _, option := range options {
+		option(runner)
+	}
+	return runner
 }
 
 func (r *runner) Run(ctx context.Context, env pluginrpc.Env) error {
@@ -47,14 +54,19 @@ func (r *runner) Run(ctx context.Context, env pluginrpc.Env) error {
 	if len(r.programArgs) > 0 {
 		args = append(slices.Clone(r.programArgs), env.Args...)
 	}
-	if err := r.delegate.Run(
-		ctx,
-		r.programName,
+	runOptions := []command.RunOption{
 		command.RunWithArgs(args...),
 		command.RunWithStdin(env.Stdin),
 		command.RunWithStdout(env.Stdout),
 		command.RunWithStderr(env.Stderr),
-	); err != nil {
+	}
+	if len(r.env) > 0 {
+		runOptions = append(runOptions, command.RunWithEnv(r.env))
+	}
+	if r.dir != "" {
+		runOptions = append(runOptions, command.RunWithDir(r.dir))
+	}
+	if err := r.delegate.Run(ctx, r.programName, runOptions...); err != nil {
 		execExitError := &exec.ExitError{}
 		if errors.As(err, &execExitError) {
 			return pluginrpc.NewExitError(execExitError.ExitCode(), execExitError)
@@ -0,0 +1,167 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/ioext"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"pluginrpc.com/pluginrpc"
+)
+
+const (
+	retryInitialBackoff = 100 * time.Millisecond
+	retryMaxBackoff     = 2 * time.Second
+)
+
+type runnerFunc func(context.Context, pluginrpc.Env) error
+
+func (f runnerFunc) Run(ctx context.Context, env pluginrpc.Env) error {
+	return f(ctx, env)
+}
+
+func newTimeoutRunnerInterceptor(timeout time.Duration) RunnerInterceptor {
+	return func(programName string, next pluginrpc.Runner) pluginrpc.Runner {
+		return runnerFunc(
+			func(ctx context.Context, env pluginrpc.Env) error {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				err := next.Run(ctx, env)
+				if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return &timeoutError{programName: programName, timeout: timeout, err: err}
+				}
+				return err
+			},
+		)
+	}
+}
+
+func newRetryRunnerInterceptor(maxAttempts int) RunnerInterceptor {
+	return func(programName string, next pluginrpc.Runner) pluginrpc.Runner {
+		return runnerFunc(
+			func(ctx context.Context, env pluginrpc.Env) error {
+				if maxAttempts <= 1 {
+					return next.Run(ctx, env)
+				}
+				// Each attempt needs the full stdin, and only the output of the
+				// last attempt should be written.
+				var stdin []byte
+				if env.Stdin != nil {
+					var err error
+					stdin, err = io.ReadAll(env.Stdin)
+					if err != nil {
+						return err
+					}
+				}
+				backoff := retryInitialBackoff
+				for attempt := 1; ; attempt++ {
+					stdout := bytes.NewBuffer(nil)
+					stderr := bytes.NewBuffer(nil)
+					err := next.Run(
+						ctx,
+						pluginrpc.Env{
+							Args:   env.Args,
+							Stdin:  bytes.NewReader(stdin),
+							Stdout: stdout,
+							Stderr: stderr,
+						},
+					)
+					if err == nil || attempt == maxAttempts || !isRetryableRunError(ctx, err) {
+						if writeErr := writeAll(env.Stdout, stdout.Bytes()); writeErr != nil {
+							return writeErr
+						}
+						if writeErr := writeAll(env.Stderr, stderr.Bytes()); writeErr != nil {
+							return writeErr
+						}
+						return err
+					}
+					select {
+					case <-ctx.Done():
+						return err
+					case <-time.After(backoff):
+					}
+					backoff = min(2*backoff, retryMaxBackoff)
+				}
+			},
+		)
+	}
+}
+
+func newLoggingRunnerInterceptor(logger *slog.Logger) RunnerInterceptor {
+	return func(programName string, next pluginrpc.Runner) pluginrpc.Runner {
+		return runnerFunc(
+			func(ctx context.Context, env pluginrpc.Env) error {
+				defer slogext.DebugProfile(logger, slog.String("plugin", programName), slog.Any("args", env.Args))()
+				err := next.Run(ctx, env)
+				if err != nil {
+					logger.DebugContext(ctx, "plugin_run_failed", slog.String("plugin", programName), slogext.ErrorAttr(err))
+				}
+				return err
+			},
+		)
+	}
+}
+
+func newOutputLimitRunnerInterceptor(outputLimit int64) RunnerInterceptor {
+	return func(programName string, next pluginrpc.Runner) pluginrpc.Runner {
+		return runnerFunc(
+			func(ctx context.Context, env pluginrpc.Env) error {
+				if outputLimit <= 0 || env.Stdout == nil {
+					return next.Run(ctx, env)
+				}
+				limitedStdout := ioext.NewLimitedWriter(env.Stdout, outputLimit)
+				env.Stdout = limitedStdout
+				err := next.Run(ctx, env)
+				if limitedStdout.Exceeded() {
+					// This takes precedence, as any other error is likely a result of the
+					// output being truncated.
+					return fmt.Errorf("plugin %s produced %d bytes, limit %d", programName, limitedStdout.Size(), outputLimit)
+				}
+				return err
+			},
+		)
+	}
+}
+
+// isRetryableRunError returns true if the error is not a result of the plugin itself
+// exiting with an error, and the context is not done.
+func isRetryableRunError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	exitError := &pluginrpc.ExitError{}
+	return !errors.As(err, &exitError)
+}
+
+type timeoutError struct {
+	programName string
+	timeout     time.Duration
+	err         error
+}
+
+func (e *timeoutError) Error() string {
+	return "plugin " + e.programName + " timed out after " + e.timeout.String() + ": " + e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,174 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestNewInterceptedRunnerOrder(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	newInterceptor := func(name string) RunnerInterceptor {
+		return func(programName string, next pluginrpc.Runner) pluginrpc.Runner {
+			return runnerFunc(
+				func(ctx context.Context, env pluginrpc.Env) error {
+					calls = append(calls, name+":"+programName)
+					return next.Run(ctx, env)
+				},
+			)
+		}
+	}
+	runner := NewInterceptedRunner(
+		runnerFunc(
+			func(context.Context, pluginrpc.Env) error {
+				calls = append(calls, "delegate")
+				return nil
+			},
+		),
+		"buf-plugin-test",
+		newInterceptor("first"),
+		nil,
+		newInterceptor("second"),
+	)
+	require.NoError(t, runner.Run(context.Background(), pluginrpc.Env{}))
+	assert.Equal(t, []string{"first:buf-plugin-test", "second:buf-plugin-test", "delegate"}, calls)
+}
+
+func TestTimeoutRunnerInterceptor(t *testing.T) {
+	t.Parallel()
+	runner := NewInterceptedRunner(
+		runnerFunc(
+			func(ctx context.Context, _ pluginrpc.Env) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		),
+		"buf-plugin-test",
+		NewTimeoutRunnerInterceptor(10*time.Millisecond),
+	)
+	err := runner.Run(context.Background(), pluginrpc.Env{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "plugin buf-plugin-test timed out after 10ms")
+}
+
+func TestRetryRunnerInterceptor(t *testing.T) {
+	t.Parallel()
+	var attempts int
+	runner := NewInterceptedRunner(
+		runnerFunc(
+			func(_ context.Context, env pluginrpc.Env) error {
+				attempts++
+				data, err := io.ReadAll(env.Stdin)
+				if err != nil {
+					return err
+				}
+				if _, err := env.Stdout.Write(data); err != nil {
+					return err
+				}
+				if attempts < 3 {
+					return errors.New("transient")
+				}
+				return nil
+			},
+		),
+		"buf-plugin-test",
+		NewRetryRunnerInterceptor(3),
+	)
+	stdout := bytes.NewBuffer(nil)
+	err := runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Stdin:  strings.NewReader("hello"),
+			Stdout: stdout,
+			Stderr: io.Discard,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	// Only the output of the last attempt is written.
+	assert.Equal(t, "hello", stdout.String())
+}
+
+func TestRetryRunnerInterceptorExitError(t *testing.T) {
+	t.Parallel()
+	var attempts int
+	runner := NewInterceptedRunner(
+		runnerFunc(
+			func(context.Context, pluginrpc.Env) error {
+				attempts++
+				return pluginrpc.NewExitError(1, errors.New("failed"))
+			},
+		),
+		"buf-plugin-test",
+		NewRetryRunnerInterceptor(3),
+	)
+	err := runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+	)
+	exitError := &pluginrpc.ExitError{}
+	require.ErrorAs(t, err, &exitError)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestOutputLimitRunnerInterceptor(t *testing.T) {
+	t.Parallel()
+	runner := NewInterceptedRunner(
+		runnerFunc(
+			func(_ context.Context, env pluginrpc.Env) error {
+				_, err := env.Stdout.Write([]byte(env.Args[0]))
+				return err
+			},
+		),
+		"buf-plugin-test",
+		NewOutputLimitRunnerInterceptor(5),
+	)
+	stdout := bytes.NewBuffer(nil)
+	err := runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Args:   []string{"hello"},
+			Stdout: stdout,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", stdout.String())
+	stdout.Reset()
+	err = runner.Run(
+		context.Background(),
+		pluginrpc.Env{
+			Args:   []string{"hello world"},
+			Stdout: stdout,
+		},
+	)
+	require.EqualError(t, err, "plugin buf-plugin-test produced 11 bytes, limit 5")
+	// Output past the limit is discarded.
+	assert.Equal(t, "hello", stdout.String())
+}
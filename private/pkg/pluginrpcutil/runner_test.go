@@ -0,0 +1,59 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestRunnerWithEnvAndDir(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	dir := t.TempDir()
+	runner := NewRunnerWithOptions(
+		command.NewRunner(),
+		"sh",
+		[]string{"-c", `echo "$FOO|$HOME|$(pwd -P)"`},
+		RunnerWithEnv(map[string]string{"FOO": "bar"}),
+		RunnerWithDir(dir),
+	)
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		runner.Run(
+			context.Background(),
+			pluginrpc.Env{
+				Stdout: stdout,
+			},
+		),
+	)
+	fields := strings.Split(strings.TrimSpace(stdout.String()), "|")
+	require.Len(t, fields, 3)
+	assert.Equal(t, "bar", fields[0])
+	// The environment of the caller is not inherited.
+	assert.Empty(t, fields[1])
+	assert.NotEmpty(t, fields[2])
+}
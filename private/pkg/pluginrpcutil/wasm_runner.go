@@ -31,6 +31,9 @@ type wasmRunner struct {
 	delegate    wasm.Runtime
 	programName string
 	programArgs []string
+	// moduleWasm is the Wasm module. If nil, the module is read from
+	// the file found for programName.
+	moduleWasm []byte
 	// lock protects compiledModule and compiledModuleErr. Store called as
 	// a boolean to avoid nil comparison.
 	lock              sync.RWMutex
@@ -42,12 +45,14 @@ type wasmRunner struct {
 func newWasmRunner(
 	delegate wasm.Runtime,
 	programName string,
+	moduleWasm []byte,
 	programArgs ...string,
 ) *wasmRunner {
 	return &wasmRunner{
 		delegate:    delegate,
 		programName: programName,
 		programArgs: programArgs,
+		moduleWasm:  moduleWasm,
 	}
 }
 
@@ -79,6 +84,25 @@ func (r *wasmRunner) loadCompiledModuleOnce(ctx context.Context) (wasm.CompiledM
 }
 
 func (r *wasmRunner) loadCompiledModule(ctx context.Context) (wasm.CompiledModule, error) {
+	moduleWasm := r.moduleWasm
+	if moduleWasm == nil {
+		var err error
+		moduleWasm, err = r.readModuleWasm()
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Compile the module. This CompiledModule is never released, so
+	// subsequent calls to this function will benefit from the cached
+	// module. This is only safe as the runner is limited to the CLI.
+	compiledModule, err := r.delegate.Compile(ctx, r.programName, moduleWasm)
+	if err != nil {
+		return nil, err
+	}
+	return compiledModule, nil
+}
+
+func (r *wasmRunner) readModuleWasm() ([]byte, error) {
 	// Find the plugin path. We use the same logic as exec.LookPath, but we do
 	// not require the file to be executable. So check the local directory
 	// first before checking the PATH.
@@ -96,14 +120,7 @@ func (r *wasmRunner) loadCompiledModule(ctx context.Context) (wasm.CompiledModul
 	if err != nil {
 		return nil, fmt.Errorf("could not read plugin %q: %v", r.programName, err)
 	}
-	// Compile the module. This CompiledModule is never released, so
-	// subsequent calls to this function will benefit from the cached
-	// module. This is only safe as the runner is limited to the CLI.
-	compiledModule, err := r.delegate.Compile(ctx, r.programName, moduleWasm)
-	if err != nil {
-		return nil, err
-	}
-	return compiledModule, nil
+	return moduleWasm, nil
 }
 
 // unsafeLookPath is a wrapper around exec.LookPath that restores the original
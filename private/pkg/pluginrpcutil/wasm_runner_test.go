@@ -0,0 +1,99 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpcutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/wasm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pluginrpc.com/pluginrpc"
+)
+
+func TestWasmRunnerForModule(t *testing.T) {
+	t.Parallel()
+	runtime := &testWasmRuntime{}
+	// The program name does not exist on disk, the module is never read from the file system.
+	runner := NewWasmRunnerForModule(runtime, "buf-plugin-missing.wasm", []byte("module"), "--foo")
+	stdout := bytes.NewBuffer(nil)
+	for range 2 {
+		stdout.Reset()
+		require.NoError(
+			t,
+			runner.Run(
+				context.Background(),
+				pluginrpc.Env{
+					Args:   []string{"--bar"},
+					Stdout: stdout,
+				},
+			),
+		)
+		assert.Equal(t, "--foo --bar", stdout.String())
+	}
+	// The module is only compiled once.
+	assert.Equal(t, []string{"buf-plugin-missing.wasm:module"}, runtime.compiled)
+
+	runtime = &testWasmRuntime{compileErr: errors.New("invalid module")}
+	runner = NewWasmRunnerForModule(runtime, "buf-plugin-invalid.wasm", []byte("invalid"))
+	for range 2 {
+		require.EqualError(t, runner.Run(context.Background(), pluginrpc.Env{}), "invalid module")
+	}
+	// Compile errors are cached.
+	assert.Equal(t, []string{"buf-plugin-invalid.wasm:invalid"}, runtime.compiled)
+}
+
+func TestWasmRunnerMissingFile(t *testing.T) {
+	t.Parallel()
+	runtime := &testWasmRuntime{}
+	err := NewWasmRunner(runtime, "buf-plugin-missing.wasm").Run(context.Background(), pluginrpc.Env{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `could not find plugin "buf-plugin-missing.wasm" in PATH`)
+	assert.Empty(t, runtime.compiled)
+}
+
+// testWasmRuntime is a wasm.Runtime that records the modules it compiles, and returns
+// CompiledModules that write their args to stdout.
+type testWasmRuntime struct {
+	compileErr error
+	compiled   []string
+}
+
+func (r *testWasmRuntime) Compile(_ context.Context, moduleName string, moduleWasm []byte) (wasm.CompiledModule, error) {
+	r.compiled = append(r.compiled, moduleName+":"+string(moduleWasm))
+	if r.compileErr != nil {
+		return nil, r.compileErr
+	}
+	return testCompiledModule{}, nil
+}
+
+func (*testWasmRuntime) Close(context.Context) error {
+	return nil
+}
+
+type testCompiledModule struct{}
+
+func (testCompiledModule) Run(_ context.Context, env pluginrpc.Env) error {
+	_, err := env.Stdout.Write([]byte(strings.Join(env.Args, " ")))
+	return err
+}
+
+func (testCompiledModule) Close(context.Context) error {
+	return nil
+}
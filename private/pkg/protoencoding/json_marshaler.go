@@ -15,6 +15,7 @@
 package protoencoding
 
 import (
+	"github.com/bufbuild/buf/private/pkg/encoding"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
@@ -25,6 +26,7 @@ type jsonMarshaler struct {
 	useProtoNames   bool
 	useEnumNumbers  bool
 	emitUnpopulated bool
+	canonical       bool
 }
 
 func newJSONMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshaler {
@@ -51,5 +53,14 @@ func (m *jsonMarshaler) Marshal(message proto.Message) ([]byte, error) {
 		EmitUnpopulated: m.emitUnpopulated,
 		Indent:          m.indent,
 	}
+	if m.canonical {
+		// The canonical form has no whitespace, so the indent is not used.
+		options.Indent = ""
+		data, err := options.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.CanonicalizeJSON(data)
+	}
 	return options.Marshal(message)
 }
@@ -113,6 +113,17 @@ func JSONMarshalerWithEmitUnpopulated() JSONMarshalerOption {
 	}
 }
 
+// JSONMarshalerWithCanonical says to write the JSON in the canonical form of
+// RFC 8785, with object keys sorted and numbers in a fixed format, so that the
+// same message is always written as the same bytes.
+//
+// The indent is ignored, as the canonical form has no whitespace.
+func JSONMarshalerWithCanonical() JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.canonical = true
+	}
+}
+
 // NewTxtpbMarshaler returns a new Marshaler for txtpb.
 //
 // If the resolver is nil, EmptyResolver will be used.
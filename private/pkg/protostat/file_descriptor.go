@@ -0,0 +1,194 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protostat
+
+import (
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const mapFieldType = "map"
+
+// GetFileDescriptorStats gathers statistics about a set of compiled Protobuf files.
+//
+// As opposed to GetStats, this includes the number of fields of each type, the
+// number of deprecated elements, and the number of times each extension is set
+// as an option, as these can only be determined once the files are compiled.
+//
+// The resolver is used to parse the custom options of each element, and should
+// be able to resolve all extensions used as options by the files, including
+// those defined in imports.
+func GetFileDescriptorStats(
+	resolver protoencoding.Resolver,
+	fileDescriptors ...protoreflect.FileDescriptor,
+) (*Stats, error) {
+	statsBuilder := newStatsBuilder()
+	statsBuilder.NumFieldsByType = make(map[string]int)
+	statsBuilder.NumExtensionUses = make(map[string]int)
+	fileDescriptorExaminer := &fileDescriptorExaminer{
+		statsBuilder: statsBuilder,
+		resolver:     resolver,
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		if err := fileDescriptorExaminer.examineFile(fileDescriptor); err != nil {
+			return nil, err
+		}
+	}
+	statsBuilder.NumPackages = len(statsBuilder.packages)
+	return statsBuilder.Stats, nil
+}
+
+// *** PRIVATE ***
+
+type fileDescriptorExaminer struct {
+	statsBuilder *statsBuilder
+	resolver     protoencoding.Resolver
+}
+
+func (e *fileDescriptorExaminer) examineFile(fileDescriptor protoreflect.FileDescriptor) error {
+	e.statsBuilder.NumFiles++
+	if packageName := fileDescriptor.Package(); packageName != "" {
+		e.statsBuilder.packages[string(packageName)] = struct{}{}
+	}
+	if err := e.examineOptions(fileDescriptor); err != nil {
+		return err
+	}
+	if err := e.examineMessages(fileDescriptor.Messages()); err != nil {
+		return err
+	}
+	if err := e.examineEnums(fileDescriptor.Enums()); err != nil {
+		return err
+	}
+	if err := e.examineExtensions(fileDescriptor.Extensions()); err != nil {
+		return err
+	}
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		e.statsBuilder.NumServices++
+		if err := e.examineOptions(service); err != nil {
+			return err
+		}
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			e.statsBuilder.NumMethods++
+			if err := e.examineOptions(methods.Get(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *fileDescriptorExaminer) examineMessages(messages protoreflect.MessageDescriptors) error {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		// Map entries are synthesized for map fields, which are counted as fields.
+		if message.IsMapEntry() {
+			continue
+		}
+		e.statsBuilder.NumMessages++
+		if err := e.examineOptions(message); err != nil {
+			return err
+		}
+		fields := message.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			field := fields.Get(j)
+			e.statsBuilder.NumFields++
+			fieldType := field.Kind().String()
+			if field.IsMap() {
+				fieldType = mapFieldType
+			}
+			e.statsBuilder.NumFieldsByType[fieldType]++
+			if err := e.examineOptions(field); err != nil {
+				return err
+			}
+		}
+		oneofs := message.Oneofs()
+		for j := 0; j < oneofs.Len(); j++ {
+			if err := e.examineOptions(oneofs.Get(j)); err != nil {
+				return err
+			}
+		}
+		if err := e.examineMessages(message.Messages()); err != nil {
+			return err
+		}
+		if err := e.examineEnums(message.Enums()); err != nil {
+			return err
+		}
+		if err := e.examineExtensions(message.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *fileDescriptorExaminer) examineEnums(enums protoreflect.EnumDescriptors) error {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		e.statsBuilder.NumEnums++
+		if err := e.examineOptions(enum); err != nil {
+			return err
+		}
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			e.statsBuilder.NumEnumValues++
+			if err := e.examineOptions(values.Get(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *fileDescriptorExaminer) examineExtensions(extensions protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < extensions.Len(); i++ {
+		e.statsBuilder.NumExtensions++
+		if err := e.examineOptions(extensions.Get(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// examineOptions counts the descriptor if it is deprecated, and each extension
+// set in its options.
+func (e *fileDescriptorExaminer) examineOptions(descriptor protoreflect.Descriptor) error {
+	options := descriptor.Options()
+	// Descriptors without options return a typed nil message.
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return nil
+	}
+	if deprecatedOptions, ok := options.(interface{ GetDeprecated() bool }); ok && deprecatedOptions.GetDeprecated() {
+		e.statsBuilder.NumDeprecated++
+	}
+	// Custom options are unrecognized fields until they are parsed with a resolver
+	// that knows their extensions. The options are shared with the descriptor, so
+	// they are cloned before being parsed.
+	options = proto.Clone(options)
+	if err := protoencoding.ReparseExtensions(e.resolver, options.ProtoReflect()); err != nil {
+		return err
+	}
+	options.ProtoReflect().Range(
+		func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if field.IsExtension() {
+				e.statsBuilder.NumExtensionUses[string(field.FullName())]++
+			}
+			return true
+		},
+	)
+	return nil
+}
@@ -38,6 +38,16 @@ type Stats struct {
 	NumExtensions            int `json:"num_extensions" yaml:"num_extensions"`
 	NumServices              int `json:"num_services" yaml:"num_services"`
 	NumMethods               int `json:"num_methods" yaml:"num_methods"`
+	// NumDeprecated is the number of files, messages, fields, enums, enum values, extensions,
+	// services, and methods that are deprecated.
+	NumDeprecated int `json:"num_deprecated" yaml:"num_deprecated"`
+	// NumFieldsByType is the number of fields of each type, keyed by the name of the
+	// type as written in a .proto file, such as "int32", or "message", "enum", "group",
+	// or "map" for fields that are not scalars.
+	NumFieldsByType map[string]int `json:"num_fields_by_type" yaml:"num_fields_by_type"`
+	// NumExtensionUses is the number of times each extension is set as an option,
+	// keyed by the fully-qualified name of the extension.
+	NumExtensionUses map[string]int `json:"num_extension_uses" yaml:"num_extension_uses"`
 }
 
 // FileWalker goes through all .proto files for GetStats.
@@ -50,6 +60,9 @@ type FileWalker interface {
 //
 // See the packages protostatos and protostatstorage for helpers for the
 // os and storage packages.
+//
+// The files are only parsed, so NumDeprecated, NumFieldsByType, and NumExtensionUses
+// are not set. Use GetFileDescriptorStats to get these for compiled files.
 func GetStats(ctx context.Context, fileWalker FileWalker) (*Stats, error) {
 	handler := reporter.NewHandler(
 		reporter.NewReporter(
@@ -103,20 +116,36 @@ func MergeStats(statsSlice ...*Stats) *Stats {
 		resultStats.NumExtensions += stats.NumExtensions
 		resultStats.NumServices += stats.NumServices
 		resultStats.NumMethods += stats.NumMethods
+		resultStats.NumDeprecated += stats.NumDeprecated
+		resultStats.NumFieldsByType = mergeCounts(resultStats.NumFieldsByType, stats.NumFieldsByType)
+		resultStats.NumExtensionUses = mergeCounts(resultStats.NumExtensionUses, stats.NumExtensionUses)
 	}
 	return resultStats
 }
 
+func mergeCounts(result map[string]int, counts map[string]int) map[string]int {
+	if counts == nil {
+		return result
+	}
+	if result == nil {
+		result = make(map[string]int, len(counts))
+	}
+	for key, count := range counts {
+		result[key] += count
+	}
+	return result
+}
+
 type statsBuilder struct {
 	*Stats
 
-	packages map[ast.Identifier]struct{}
+	packages map[string]struct{}
 }
 
 func newStatsBuilder() *statsBuilder {
 	return &statsBuilder{
 		Stats:    &Stats{},
-		packages: make(map[ast.Identifier]struct{}),
+		packages: make(map[string]struct{}),
 	}
 }
 
@@ -125,7 +154,7 @@ func examineFile(statsBuilder *statsBuilder, fileNode *ast.FileNode) {
 	for _, decl := range fileNode.Decls {
 		switch decl := decl.(type) {
 		case *ast.PackageNode:
-			statsBuilder.packages[decl.Name.AsIdentifier()] = struct{}{}
+			statsBuilder.packages[string(decl.Name.AsIdentifier())] = struct{}{}
 		case *ast.MessageNode:
 			examineMessage(statsBuilder, &decl.MessageBody)
 		case *ast.EnumNode:
@@ -83,6 +83,21 @@ func (m *Map[K, V]) Get(key K) *V {
 	return &value.value
 }
 
+// Range calls f for each key in the map and the value it maps to, in no particular
+// order. If f returns false, iteration stops.
+//
+// The map is read-locked for the duration of the call, so f must not insert into or
+// delete from the map.
+func (m *Map[K, V]) Range(f func(key K, value *V) bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for key, value := range m.table {
+		if !f(key, &value.value) {
+			return
+		}
+	}
+}
+
 // Delete deletes a key from the map.
 //
 // The key will only be evicted once [Map.Delete] has been called an equal number of times
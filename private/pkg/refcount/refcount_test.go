@@ -40,3 +40,32 @@ func TestMap(t *testing.T) {
 	assert.Nil(t, table.Delete("foo"))
 	assert.Equal(t, *table.Delete("foo"), 42)
 }
+
+func TestMapRange(t *testing.T) {
+	t.Parallel()
+
+	table := &Map[string, int]{}
+	table.Range(func(string, *int) bool {
+		t.Fatal("unexpected call on empty map")
+		return true
+	})
+
+	for i, key := range []string{"foo", "bar", "baz"} {
+		value, _ := table.Insert(key)
+		*value = i
+	}
+
+	seen := make(map[string]int)
+	table.Range(func(key string, value *int) bool {
+		seen[key] = *value
+		return true
+	})
+	assert.Equal(t, map[string]int{"foo": 0, "bar": 1, "baz": 2}, seen)
+
+	var calls int
+	table.Range(func(string, *int) bool {
+		calls++
+		return false
+	})
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,331 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package semverconstraint implements semantic version constraints.
+//
+// The syntax follows the conventions of npm and Cargo:
+//
+//   - "^1.4" matches ">=1.4.0, <2.0.0". "^0.4" matches ">=0.4.0, <0.5.0".
+//   - "~1.4.2" matches ">=1.4.2, <1.5.0". "~1" matches ">=1.0.0, <2.0.0".
+//   - ">=1.4", ">1.4.2", "<2", "<=1.4", and "=1.4.2" compare as expected. Partial versions
+//     are treated as ranges, so "<=1.4" matches "1.4.9" and "=1.4" matches ">=1.4.0, <1.5.0".
+//   - Comparators separated by "," or whitespace must all match.
+//   - Sets of comparators separated by "||" match if any set matches.
+//
+// Versions may optionally be prefixed with "v". Pre-release versions only match if a
+// comparator in the same set names a pre-release of the same major, minor, and patch version.
+package semverconstraint
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Constraint is a semantic version constraint.
+type Constraint interface {
+	// String returns the constraint as given to Parse.
+	fmt.Stringer
+
+	// Matches returns true if the version matches the Constraint.
+	//
+	// Returns false if the version is not a complete semantic version.
+	Matches(version string) bool
+
+	isConstraint()
+}
+
+// IsConstraint returns true if the string looks like a version constraint, as opposed
+// to a plain version or label.
+//
+// This does not validate the constraint, use Parse to validate.
+func IsConstraint(s string) bool {
+	return strings.ContainsAny(s, "^~<>=|")
+}
+
+// Parse parses a Constraint.
+func Parse(s string) (Constraint, error) {
+	return parseConstraint(s)
+}
+
+// Max returns the highest version of the given versions that matches the Constraint.
+//
+// Versions are returned exactly as given. If two versions are equal, the first is returned.
+// Returns false if no version matches.
+func Max(constraint Constraint, versions []string) (string, bool) {
+	var maxVersion string
+	var maxCanonicalVersion string
+	for _, version := range versions {
+		if !constraint.Matches(version) {
+			continue
+		}
+		canonicalVersion, _ := toCanonicalVersion(version)
+		if maxVersion == "" || semver.Compare(canonicalVersion, maxCanonicalVersion) > 0 {
+			maxVersion = version
+			maxCanonicalVersion = canonicalVersion
+		}
+	}
+	return maxVersion, maxVersion != ""
+}
+
+// *** PRIVATE ***
+
+type constraint struct {
+	value string
+	// Any of the comparatorSets must match.
+	comparatorSets [][]*comparator
+}
+
+func parseConstraint(value string) (*constraint, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, errors.New("version constraint is empty")
+	}
+	constraint := &constraint{
+		value: value,
+	}
+	for _, comparatorSetString := range strings.Split(value, "||") {
+		var comparatorSet []*comparator
+		for _, comparatorString := range strings.FieldsFunc(
+			comparatorSetString,
+			func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			},
+		) {
+			comparators, err := parseComparators(comparatorString)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", value, err)
+			}
+			comparatorSet = append(comparatorSet, comparators...)
+		}
+		if len(comparatorSet) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty comparator set", value)
+		}
+		constraint.comparatorSets = append(constraint.comparatorSets, comparatorSet)
+	}
+	return constraint, nil
+}
+
+func (c *constraint) String() string {
+	return c.value
+}
+
+func (c *constraint) Matches(version string) bool {
+	canonicalVersion, ok := toCanonicalVersion(version)
+	if !ok {
+		return false
+	}
+	for _, comparatorSet := range c.comparatorSets {
+		if comparatorSetMatches(comparatorSet, canonicalVersion) {
+			return true
+		}
+	}
+	return false
+}
+
+func (*constraint) isConstraint() {}
+
+type operator int
+
+const (
+	operatorEqual operator = iota + 1
+	operatorGreater
+	operatorGreaterOrEqual
+	operatorLess
+	operatorLessOrEqual
+)
+
+// comparator is a primitive comparison against a canonical version.
+type comparator struct {
+	operator operator
+	// Canonical, with a "v" prefix.
+	version string
+}
+
+func (c *comparator) matches(canonicalVersion string) bool {
+	compare := semver.Compare(canonicalVersion, c.version)
+	switch c.operator {
+	case operatorEqual:
+		return compare == 0
+	case operatorGreater:
+		return compare > 0
+	case operatorGreaterOrEqual:
+		return compare >= 0
+	case operatorLess:
+		return compare < 0
+	case operatorLessOrEqual:
+		return compare <= 0
+	default:
+		return false
+	}
+}
+
+func comparatorSetMatches(comparatorSet []*comparator, canonicalVersion string) bool {
+	for _, comparator := range comparatorSet {
+		if !comparator.matches(canonicalVersion) {
+			return false
+		}
+	}
+	if semver.Prerelease(canonicalVersion) == "" {
+		return true
+	}
+	// Pre-releases only match if explicitly opted into for the same major, minor, and patch.
+	versionCore := strings.TrimSuffix(canonicalVersion, semver.Prerelease(canonicalVersion))
+	for _, comparator := range comparatorSet {
+		if prerelease := semver.Prerelease(comparator.version); prerelease != "" &&
+			strings.TrimSuffix(comparator.version, prerelease) == versionCore {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparators parses a single comparator string such as "^1.4" into primitive comparators.
+func parseComparators(s string) ([]*comparator, error) {
+	var prefix string
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, candidate) {
+			prefix = candidate
+			break
+		}
+	}
+	partial, err := parsePartialVersion(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return nil, err
+	}
+	lower := partial.canonical()
+	switch prefix {
+	case "", "=":
+		if partial.isComplete() {
+			return []*comparator{{operatorEqual, lower}}, nil
+		}
+		return []*comparator{{operatorGreaterOrEqual, lower}, {operatorLess, partial.next()}}, nil
+	case ">=":
+		return []*comparator{{operatorGreaterOrEqual, lower}}, nil
+	case ">":
+		if partial.isComplete() {
+			return []*comparator{{operatorGreater, lower}}, nil
+		}
+		return []*comparator{{operatorGreaterOrEqual, partial.next()}}, nil
+	case "<":
+		return []*comparator{{operatorLess, lower}}, nil
+	case "<=":
+		if partial.isComplete() {
+			return []*comparator{{operatorLessOrEqual, lower}}, nil
+		}
+		return []*comparator{{operatorLess, partial.next()}}, nil
+	case "~":
+		if len(partial.numbers) == 1 {
+			return []*comparator{{operatorGreaterOrEqual, lower}, {operatorLess, partial.bump(0)}}, nil
+		}
+		return []*comparator{{operatorGreaterOrEqual, lower}, {operatorLess, partial.bump(1)}}, nil
+	case "^":
+		// Bump the leftmost non-zero component, or the last given component if all are zero.
+		index := len(partial.numbers) - 1
+		for i, number := range partial.numbers {
+			if number != 0 {
+				index = i
+				break
+			}
+		}
+		return []*comparator{{operatorGreaterOrEqual, lower}, {operatorLess, partial.bump(index)}}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", prefix)
+	}
+}
+
+// partialVersion is a version with one to three numeric components.
+type partialVersion struct {
+	numbers []int
+	// Only set if all three components are present. Includes the leading "-".
+	prerelease string
+}
+
+func parsePartialVersion(s string) (*partialVersion, error) {
+	if s == "" {
+		return nil, errors.New("missing version")
+	}
+	original := s
+	s = strings.TrimPrefix(s, "v")
+	// Build metadata is ignored for comparisons.
+	s, _, _ = strings.Cut(s, "+")
+	s, prerelease, hasPrerelease := strings.Cut(s, "-")
+	components := strings.Split(s, ".")
+	if len(components) > 3 {
+		return nil, fmt.Errorf("invalid version %q", original)
+	}
+	partial := &partialVersion{}
+	for _, component := range components {
+		number, err := strconv.Atoi(component)
+		if err != nil || number < 0 || (len(component) > 1 && component[0] == '0') {
+			return nil, fmt.Errorf("invalid version %q", original)
+		}
+		partial.numbers = append(partial.numbers, number)
+	}
+	if hasPrerelease {
+		if !partial.isComplete() {
+			return nil, fmt.Errorf("invalid version %q: pre-releases require a major, minor, and patch version", original)
+		}
+		partial.prerelease = "-" + prerelease
+	}
+	if !semver.IsValid(partial.canonical()) {
+		return nil, fmt.Errorf("invalid version %q", original)
+	}
+	return partial, nil
+}
+
+func (p *partialVersion) isComplete() bool {
+	return len(p.numbers) == 3
+}
+
+// canonical returns the canonical version with missing components set to zero.
+func (p *partialVersion) canonical() string {
+	numbers := make([]int, 3)
+	copy(numbers, p.numbers)
+	return fmt.Sprintf("v%d.%d.%d%s", numbers[0], numbers[1], numbers[2], p.prerelease)
+}
+
+// next returns the smallest version greater than every version within the partial version.
+func (p *partialVersion) next() string {
+	return p.bump(len(p.numbers) - 1)
+}
+
+// bump returns the canonical version with the component at index incremented and all
+// following components set to zero.
+func (p *partialVersion) bump(index int) string {
+	numbers := make([]int, 3)
+	copy(numbers, p.numbers[:index+1])
+	numbers[index]++
+	return fmt.Sprintf("v%d.%d.%d", numbers[0], numbers[1], numbers[2])
+}
+
+// toCanonicalVersion returns the version with a "v" prefix and no build metadata.
+//
+// Returns false if the version is not a complete semantic version.
+func toCanonicalVersion(version string) (string, bool) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return "", false
+	}
+	// Reject shorthands such as "v1.4" that semver.IsValid accepts.
+	core := strings.TrimSuffix(strings.TrimSuffix(version, semver.Build(version)), semver.Prerelease(version))
+	if strings.Count(core, ".") != 2 {
+		return "", false
+	}
+	return semver.Canonical(version), true
+}
@@ -0,0 +1,89 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semverconstraint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+	testMatches(t, "^1.4", []string{"1.4.0", "v1.4.2", "1.9.9"}, []string{"1.3.9", "2.0.0", "2.0.0-rc1", "1.5.0-rc1"})
+	testMatches(t, "^0.4", []string{"0.4.0", "0.4.9"}, []string{"0.5.0", "0.3.0"})
+	testMatches(t, "^0.0.3", []string{"0.0.3"}, []string{"0.0.4", "0.0.2"})
+	testMatches(t, "^0", []string{"0.0.0", "0.9.0"}, []string{"1.0.0"})
+	testMatches(t, "~1.4.2", []string{"1.4.2", "1.4.9"}, []string{"1.4.1", "1.5.0"})
+	testMatches(t, "~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"})
+	testMatches(t, ">=1.4, <2", []string{"1.4.0", "1.99.0"}, []string{"1.3.0", "2.0.0"})
+	testMatches(t, ">=1.4 <2", []string{"1.4.0"}, []string{"2.0.0"})
+	testMatches(t, ">1.4", []string{"1.5.0"}, []string{"1.4.9"})
+	testMatches(t, ">1.4.2", []string{"1.4.3"}, []string{"1.4.2"})
+	testMatches(t, "<=1.4", []string{"1.4.9"}, []string{"1.5.0"})
+	testMatches(t, "=1.4", []string{"1.4.0", "1.4.9"}, []string{"1.5.0"})
+	testMatches(t, "=1.4.2", []string{"1.4.2", "1.4.2+build"}, []string{"1.4.3"})
+	testMatches(t, "^1 || ^3", []string{"1.0.0", "3.1.0"}, []string{"2.0.0"})
+	testMatches(t, ">=2.0.0-rc1", []string{"2.0.0-rc2", "2.0.0", "3.0.0"}, []string{"2.0.0-alpha", "3.0.0-rc1"})
+	testMatches(t, "^1.4", nil, []string{"main", "v1.4", "1"})
+}
+
+func TestParseError(t *testing.T) {
+	t.Parallel()
+	for _, value := range []string{
+		"",
+		"^",
+		">=a",
+		"^1.2.3.4",
+		"^1.2-rc1",
+		"^01.2",
+		"^1 ||",
+		"=>1",
+	} {
+		_, err := Parse(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	t.Parallel()
+	require.True(t, IsConstraint("^1.4"))
+	require.True(t, IsConstraint(">=1, <2"))
+	require.False(t, IsConstraint("v1.4.2"))
+	require.False(t, IsConstraint("main"))
+}
+
+func TestMax(t *testing.T) {
+	t.Parallel()
+	constraint, err := Parse("^1.4")
+	require.NoError(t, err)
+	version, ok := Max(constraint, []string{"v1.4.0", "main", "v1.10.0", "v2.0.0", "v1.9.0"})
+	require.True(t, ok)
+	require.Equal(t, "v1.10.0", version)
+	_, ok = Max(constraint, []string{"v2.0.0", "main"})
+	require.False(t, ok)
+}
+
+func testMatches(t *testing.T, value string, matching []string, notMatching []string) {
+	constraint, err := Parse(value)
+	require.NoError(t, err)
+	require.Equal(t, value, constraint.String())
+	for _, version := range matching {
+		require.True(t, constraint.Matches(version), "%s should match %s", version, value)
+	}
+	for _, version := range notMatching {
+		require.False(t, constraint.Matches(version), "%s should not match %s", version, value)
+	}
+}
@@ -39,19 +39,28 @@ func NewDigest(value []byte) (Digest, error) {
 
 // NewDigest returns a new Digest for the content read from the Reader.
 func NewDigestForContent(reader io.Reader) (Digest, error) {
-	shakeHash := sha3.NewShake256()
-	// TODO FUTURE: remove in the future, this should have no effect
-	shakeHash.Reset()
-	if _, err := io.Copy(shakeHash, reader); err != nil {
+	hasher := newHasher()
+	if _, err := io.Copy(hasher, reader); err != nil {
 		return nil, err
 	}
-	value := make([]byte, shake256Length)
-	if _, err := shakeHash.Read(value); err != nil {
-		// sha3.ShakeHash never errors or short reads. Something horribly wrong
-		// happened if your computer ended up here.
-		return nil, err
-	}
-	return newDigest(value)
+	return hasher.Digest()
+}
+
+// Hasher computes a Digest for the content written to it.
+//
+// This is used to compute a Digest for content as it is read elsewhere.
+type Hasher interface {
+	io.Writer
+
+	// Digest returns the Digest of the content written.
+	//
+	// Content should not be written after Digest is called.
+	Digest() (Digest, error)
+}
+
+// NewHasher returns a new Hasher.
+func NewHasher() Hasher {
+	return newHasher()
 }
 
 // *** PRIVATE ***
@@ -74,3 +83,30 @@ func (d *digest) Value() []byte {
 }
 
 func (*digest) isDigest() {}
+
+type hasher struct {
+	shakeHash sha3.ShakeHash
+}
+
+func newHasher() *hasher {
+	shakeHash := sha3.NewShake256()
+	// TODO FUTURE: remove in the future, this should have no effect
+	shakeHash.Reset()
+	return &hasher{
+		shakeHash: shakeHash,
+	}
+}
+
+func (h *hasher) Write(p []byte) (int, error) {
+	return h.shakeHash.Write(p)
+}
+
+func (h *hasher) Digest() (Digest, error) {
+	value := make([]byte, shake256Length)
+	if _, err := h.shakeHash.Read(value); err != nil {
+		// sha3.ShakeHash never errors or short reads. Something horribly wrong
+		// happened if your computer ended up here.
+		return nil, err
+	}
+	return newDigest(value)
+}
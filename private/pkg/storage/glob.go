@@ -0,0 +1,100 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MatchGlob returns a Matcher for the glob pattern.
+//
+// Patterns use the syntax of path.Match within each path component, and additionally
+// support "**" as an entire component, which matches zero or more components. For example,
+// "**/*.proto" matches all .proto files, and "foo/**" matches all paths contained in foo.
+//
+// Invalid patterns match nothing, use ValidateGlob to check a pattern before use.
+func MatchGlob(pattern string) Matcher {
+	patternComponents := strings.Split(pattern, "/")
+	return pathMatcherFunc(func(path string) bool {
+		return matchGlobComponents(patternComponents, strings.Split(path, "/"))
+	})
+}
+
+// MatchGlobEqualOrContained returns a Matcher for the glob pattern that also matches
+// paths contained in a directory that matches the pattern.
+//
+// This gives globs the same semantics as MatchPathEqualOrContained gives plain paths,
+// so that a value that may be either can be matched the same way.
+func MatchGlobEqualOrContained(pattern string) Matcher {
+	return MatchOr(
+		MatchGlob(pattern),
+		MatchGlob(pattern+"/**"),
+	)
+}
+
+// IsGlob returns true if the value contains any glob metacharacters.
+//
+// Values that are not globs can be treated as plain paths.
+func IsGlob(value string) bool {
+	return strings.ContainsAny(value, "*?[")
+}
+
+// ValidateGlob returns an error if the glob pattern is malformed.
+func ValidateGlob(pattern string) error {
+	for _, patternComponent := range strings.Split(pattern, "/") {
+		if patternComponent == "**" {
+			continue
+		}
+		if _, err := path.Match(patternComponent, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+func matchGlobComponents(patternComponents []string, pathComponents []string) bool {
+	for len(patternComponents) > 0 {
+		patternComponent := patternComponents[0]
+		if patternComponent == "**" {
+			// Collapse consecutive "**" components, they are equivalent to one.
+			for len(patternComponents) > 0 && patternComponents[0] == "**" {
+				patternComponents = patternComponents[1:]
+			}
+			if len(patternComponents) == 0 {
+				return true
+			}
+			for i := 0; i <= len(pathComponents); i++ {
+				if matchGlobComponents(patternComponents, pathComponents[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(pathComponents) == 0 {
+			return false
+		}
+		matched, err := path.Match(patternComponent, pathComponents[0])
+		if err != nil || !matched {
+			return false
+		}
+		patternComponents = patternComponents[1:]
+		pathComponents = pathComponents[1:]
+	}
+	return len(pathComponents) == 0
+}
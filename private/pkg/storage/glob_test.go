@@ -0,0 +1,81 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+	testMatchGlob(t, "**/*.proto", "a.proto", true)
+	testMatchGlob(t, "**/*.proto", "a/b/c.proto", true)
+	testMatchGlob(t, "**/*.proto", "a/b/c.txt", false)
+	testMatchGlob(t, "a/**", "a/b/c.proto", true)
+	testMatchGlob(t, "a/**", "a", true)
+	testMatchGlob(t, "a/**", "ab/c.proto", false)
+	testMatchGlob(t, "a/**/c.proto", "a/c.proto", true)
+	testMatchGlob(t, "a/**/c.proto", "a/b/b/c.proto", true)
+	testMatchGlob(t, "a/**/**/c.proto", "a/b/c.proto", true)
+	testMatchGlob(t, "a/**/c.proto", "b/a/c.proto", false)
+	testMatchGlob(t, "a/*.proto", "a/b.proto", true)
+	testMatchGlob(t, "a/*.proto", "a/b/c.proto", false)
+	testMatchGlob(t, "a/?.proto", "a/b.proto", true)
+	testMatchGlob(t, "a/[bc].proto", "a/c.proto", true)
+	testMatchGlob(t, "a/[bc].proto", "a/d.proto", false)
+	testMatchGlob(t, "a/b.proto", "a/b.proto", true)
+	testMatchGlob(t, "a/[", "a/[", false)
+}
+
+func TestMatchGlobComposes(t *testing.T) {
+	t.Parallel()
+	matcher := storage.MatchAnd(
+		storage.MatchGlob("**/*.proto"),
+		storage.MatchNot(storage.MatchGlob("**/internal/**")),
+	)
+	assert.True(t, matcher.MatchPath("a/b.proto"))
+	assert.False(t, matcher.MatchPath("a/internal/b.proto"))
+	assert.False(t, matcher.MatchPath("a/b.txt"))
+}
+
+func TestMatchGlobEqualOrContained(t *testing.T) {
+	t.Parallel()
+	matcher := storage.MatchGlobEqualOrContained("**/internal")
+	assert.True(t, matcher.MatchPath("internal"))
+	assert.True(t, matcher.MatchPath("a/internal/b.proto"))
+	assert.False(t, matcher.MatchPath("a/internals/b.proto"))
+}
+
+func TestIsGlob(t *testing.T) {
+	t.Parallel()
+	assert.True(t, storage.IsGlob("**/*.proto"))
+	assert.True(t, storage.IsGlob("a/?.proto"))
+	assert.True(t, storage.IsGlob("a/[bc].proto"))
+	assert.False(t, storage.IsGlob("a/b.proto"))
+}
+
+func TestValidateGlob(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, storage.ValidateGlob("a/**/[bc]*.proto"))
+	assert.Error(t, storage.ValidateGlob("a/[.proto"))
+	assert.Error(t, storage.ValidateGlob("a/b\\"))
+}
+
+func testMatchGlob(t *testing.T, pattern string, path string, expected bool) {
+	assert.Equal(t, expected, storage.MatchGlob(pattern).MatchPath(path), "pattern %q, path %q", pattern, path)
+}
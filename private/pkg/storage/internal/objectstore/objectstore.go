@@ -0,0 +1,377 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objectstore implements a storage Bucket on top of an object store client.
+//
+// This is used by the object store implementations of storage, such as storages3 and storagegcs.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
+	"github.com/bufbuild/buf/private/pkg/thread"
+)
+
+// DefaultMaxConcurrency is the default maximum number of concurrent requests to the object store.
+const DefaultMaxConcurrency = 16
+
+// Client is a client for an object store.
+//
+// Keys are the full keys of objects in the object store, including any prefix.
+type Client interface {
+	// GetObject returns the data of the object.
+	//
+	// Returns an error that fulfills fs.ErrNotExist if the object does not exist.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	// StatObject returns nil if the object exists.
+	//
+	// Returns an error that fulfills fs.ErrNotExist if the object does not exist.
+	StatObject(ctx context.Context, key string) error
+	// ListObjects calls f with the key of every object whose key starts with the prefix.
+	ListObjects(ctx context.Context, prefix string, f func(key string) error) error
+	// PutObject creates or overwrites the object.
+	PutObject(ctx context.Context, key string, data []byte) error
+	// DeleteObject deletes the object.
+	//
+	// Deleting an object that does not exist is not an error.
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// NewReadWriteBucket returns a new ReadWriteBucket for the Client.
+//
+// All paths are relative to the prefix, which may be empty. The external path of an
+// object is the externalPathBase joined with its key, for example "s3://bucket/key".
+// At most maxConcurrency requests are made to the object store at the same time. If
+// maxConcurrency is <1, DefaultMaxConcurrency is used.
+func NewReadWriteBucket(
+	client Client,
+	externalPathBase string,
+	prefix string,
+	maxConcurrency int,
+) (storage.ReadWriteBucket, error) {
+	return newBucket(client, externalPathBase, prefix, maxConcurrency)
+}
+
+// *** PRIVATE ***
+
+type bucket struct {
+	client           Client
+	externalPathBase string
+	prefix           string
+	semaphore        chan struct{}
+}
+
+func newBucket(
+	client Client,
+	externalPathBase string,
+	prefix string,
+	maxConcurrency int,
+) (*bucket, error) {
+	if prefix != "" {
+		var err error
+		prefix, err = storageutil.ValidatePrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if maxConcurrency < 1 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+	return &bucket{
+		client:           client,
+		externalPathBase: externalPathBase,
+		prefix:           prefix,
+		semaphore:        make(chan struct{}, maxConcurrency),
+	}, nil
+}
+
+func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	readCloser, err := b.client.GetObject(ctx, b.getKey(path))
+	if err != nil {
+		b.release()
+		return nil, toStorageError(path, err)
+	}
+	return &readObjectCloser{
+		ObjectInfo: b.newObjectInfo(path),
+		readCloser: readCloser,
+		release:    b.release,
+	}, nil
+}
+
+func (b *bucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.stat(ctx, path); err != nil {
+		return nil, err
+	}
+	return b.newObjectInfo(path), nil
+}
+
+func (b *bucket) Walk(ctx context.Context, prefix string, f func(storage.ObjectInfo) error) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	paths, err := b.listPaths(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	walkChecker := storageutil.NewWalkChecker()
+	for _, path := range paths {
+		if err := walkChecker.Check(ctx); err != nil {
+			return err
+		}
+		if err := f(b.newObjectInfo(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bucket) Put(ctx context.Context, path string, _ ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// Objects are only created when the WriteObjectCloser is closed, so writes are always atomic.
+	return newWriteObjectCloser(
+		func(data []byte) error {
+			if err := b.acquire(ctx); err != nil {
+				return err
+			}
+			defer b.release()
+			return b.client.PutObject(ctx, b.getKey(path), data)
+		},
+	), nil
+}
+
+func (b *bucket) Delete(ctx context.Context, path string) error {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return err
+	}
+	// Deletes of objects that do not exist succeed in object stores.
+	if err := b.stat(ctx, path); err != nil {
+		return err
+	}
+	return b.delete(ctx, path)
+}
+
+func (b *bucket) DeleteAll(ctx context.Context, prefix string) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	paths, err := b.listPaths(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	jobs := make([]func(context.Context) error, len(paths))
+	for i, path := range paths {
+		jobs[i] = func(ctx context.Context) error {
+			return b.delete(ctx, path)
+		}
+	}
+	return thread.Parallelize(
+		ctx,
+		jobs,
+		thread.ParallelizeWithMaxParallelism(cap(b.semaphore)),
+		thread.ParallelizeWithCancelOnFailure(),
+	)
+}
+
+func (*bucket) SetExternalAndLocalPathsSupported() bool {
+	return false
+}
+
+func (b *bucket) stat(ctx context.Context, path string) error {
+	if err := b.acquire(ctx); err != nil {
+		return err
+	}
+	defer b.release()
+	return toStorageError(path, b.client.StatObject(ctx, b.getKey(path)))
+}
+
+func (b *bucket) delete(ctx context.Context, path string) error {
+	if err := b.acquire(ctx); err != nil {
+		return err
+	}
+	defer b.release()
+	return b.client.DeleteObject(ctx, b.getKey(path))
+}
+
+// listPaths returns the sorted paths of all objects in the prefix.
+func (b *bucket) listPaths(ctx context.Context, prefix string) ([]string, error) {
+	// The prefix may be the path of an object, so the key prefix does not end in "/"
+	// unless listing the whole bucket. Keys that merely share the prefix, such as
+	// "a/bc" for the prefix "a/b", are filtered out below.
+	keyPrefix := b.getKey(prefix)
+	if prefix == "." && keyPrefix != "" {
+		keyPrefix += "/"
+	}
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.release()
+	var paths []string
+	if err := b.client.ListObjects(
+		ctx,
+		keyPrefix,
+		func(key string) error {
+			path, ok := b.getPath(key)
+			if !ok {
+				return nil
+			}
+			// Object stores allow keys that are not valid paths, such as "a//b" or "a/../b".
+			if _, err := storageutil.ValidatePath(path); err != nil {
+				return nil
+			}
+			if normalpath.EqualsOrContainsPath(prefix, path, normalpath.Relative) {
+				paths = append(paths, path)
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// getKey returns the key for the path, which may be "." for the root.
+func (b *bucket) getKey(path string) string {
+	if path == "." {
+		path = ""
+	}
+	switch {
+	case b.prefix == "" || b.prefix == ".":
+		return path
+	case path == "":
+		return b.prefix
+	default:
+		return b.prefix + "/" + path
+	}
+}
+
+// getPath returns the path for the key, or false if the key is not within the prefix.
+func (b *bucket) getPath(key string) (string, bool) {
+	if b.prefix == "" || b.prefix == "." {
+		return key, key != ""
+	}
+	path, ok := strings.CutPrefix(key, b.prefix+"/")
+	return path, ok && path != ""
+}
+
+func (b *bucket) newObjectInfo(path string) storageutil.ObjectInfo {
+	return storageutil.NewObjectInfo(path, b.externalPathBase+"/"+b.getKey(path), "")
+}
+
+func (b *bucket) acquire(ctx context.Context) error {
+	select {
+	case b.semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bucket) release() {
+	<-b.semaphore
+}
+
+type readObjectCloser struct {
+	storageutil.ObjectInfo
+
+	readCloser io.ReadCloser
+	release    func()
+	closed     bool
+}
+
+func (r *readObjectCloser) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, storage.ErrClosed
+	}
+	return r.readCloser.Read(p)
+}
+
+func (r *readObjectCloser) Close() error {
+	if r.closed {
+		return storage.ErrClosed
+	}
+	r.closed = true
+	defer r.release()
+	return r.readCloser.Close()
+}
+
+type writeObjectCloser struct {
+	put    func([]byte) error
+	data   []byte
+	closed bool
+}
+
+func newWriteObjectCloser(put func([]byte) error) *writeObjectCloser {
+	return &writeObjectCloser{
+		put: put,
+	}
+}
+
+func (w *writeObjectCloser) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, storage.ErrClosed
+	}
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+func (*writeObjectCloser) SetExternalPath(string) error {
+	return storage.ErrSetExternalPathUnsupported
+}
+
+func (*writeObjectCloser) SetLocalPath(string) error {
+	return storage.ErrSetLocalPathUnsupported
+}
+
+func (w *writeObjectCloser) Close() error {
+	if w.closed {
+		return storage.ErrClosed
+	}
+	w.closed = true
+	return w.put(w.data)
+}
+
+// toStorageError returns an error with the path, rather than the key, for errors
+// that fulfill fs.ErrNotExist.
+func toStorageError(path string, err error) error {
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"io/fs"
+	"sort"
 
 	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
 )
@@ -61,6 +62,57 @@ func OverlayReadBucket(readBuckets ...ReadBucket) ReadBucket {
 	}
 }
 
+// OverlayConflict is a path that exists in more than one of the ReadBuckets of an overlay.
+type OverlayConflict struct {
+	// Path is the path that exists in more than one ReadBucket.
+	Path string
+	// ExternalPaths are the external paths of the path in each ReadBucket it exists in,
+	// in order of precedence. The first is the one that OverlayReadBucket uses.
+	ExternalPaths []string
+}
+
+// GetOverlayConflicts returns the paths with the prefix that exist in more than one of
+// the ReadBuckets, sorted by path.
+//
+// This is used to report the paths that OverlayReadBucket would shadow.
+func GetOverlayConflicts(ctx context.Context, prefix string, readBuckets ...ReadBucket) ([]OverlayConflict, error) {
+	pathToExternalPaths := make(map[string][]string)
+	for _, readBucket := range readBuckets {
+		if err := readBucket.Walk(
+			ctx,
+			prefix,
+			func(objectInfo ObjectInfo) error {
+				pathToExternalPaths[objectInfo.Path()] = append(
+					pathToExternalPaths[objectInfo.Path()],
+					objectInfo.ExternalPath(),
+				)
+				return nil
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	var overlayConflicts []OverlayConflict
+	for path, externalPaths := range pathToExternalPaths {
+		if len(externalPaths) > 1 {
+			overlayConflicts = append(
+				overlayConflicts,
+				OverlayConflict{
+					Path:          path,
+					ExternalPaths: externalPaths,
+				},
+			)
+		}
+	}
+	sort.Slice(
+		overlayConflicts,
+		func(i int, j int) bool {
+			return overlayConflicts[i].Path < overlayConflicts[j].Path
+		},
+	)
+	return overlayConflicts, nil
+}
+
 type multiReadBucket struct {
 	delegates []ReadBucket
 	overlay   bool
@@ -0,0 +1,115 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlayReadBucketConflicts(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	aReadBucket := testNewReadBucket(
+		t,
+		map[string]string{
+			"a/1.proto": "a1",
+			"a/2.proto": "a2",
+			"b/1.proto": "a4",
+		},
+	)
+	bReadBucket := testNewReadBucket(
+		t,
+		map[string]string{
+			"a/2.proto": "b2",
+			"a/3.proto": "b3",
+			"b/1.proto": "b4",
+		},
+	)
+	cReadBucket := testNewReadBucket(
+		t,
+		map[string]string{
+			"a/2.proto": "c2",
+			"a/3.proto": "c3",
+		},
+	)
+
+	// The first ReadBucket that has a conflicting path wins.
+	overlayReadBucket := storage.OverlayReadBucket(aReadBucket, bReadBucket, cReadBucket)
+	for path, expectedData := range map[string]string{
+		"a/1.proto": "a1",
+		"a/2.proto": "a2",
+		"a/3.proto": "b3",
+		"b/1.proto": "a4",
+	} {
+		data, err := storage.ReadPath(ctx, overlayReadBucket, path)
+		require.NoError(t, err)
+		assert.Equal(t, expectedData, string(data))
+	}
+	paths, err := storage.AllPaths(ctx, overlayReadBucket, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1.proto", "a/2.proto", "a/3.proto", "b/1.proto"}, paths)
+
+	overlayConflicts, err := storage.GetOverlayConflicts(ctx, "", aReadBucket, bReadBucket, cReadBucket)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]storage.OverlayConflict{
+			{
+				Path:          "a/2.proto",
+				ExternalPaths: []string{"a/2.proto", "a/2.proto", "a/2.proto"},
+			},
+			{
+				Path:          "a/3.proto",
+				ExternalPaths: []string{"a/3.proto", "a/3.proto"},
+			},
+			{
+				Path:          "b/1.proto",
+				ExternalPaths: []string{"b/1.proto", "b/1.proto"},
+			},
+		},
+		overlayConflicts,
+	)
+	overlayConflicts, err = storage.GetOverlayConflicts(ctx, "b", aReadBucket, bReadBucket, cReadBucket)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]storage.OverlayConflict{
+			{
+				Path:          "b/1.proto",
+				ExternalPaths: []string{"b/1.proto", "b/1.proto"},
+			},
+		},
+		overlayConflicts,
+	)
+	overlayConflicts, err = storage.GetOverlayConflicts(ctx, "", aReadBucket, testNewReadBucket(t, map[string]string{"c/1.proto": ""}))
+	require.NoError(t, err)
+	assert.Empty(t, overlayConflicts)
+}
+
+func testNewReadBucket(t *testing.T, pathToData map[string]string) storage.ReadBucket {
+	pathToBytes := make(map[string][]byte, len(pathToData))
+	for path, data := range pathToData {
+		pathToBytes[path] = []byte(data)
+	}
+	readBucket, err := storagemem.NewReadBucket(pathToBytes)
+	require.NoError(t, err)
+	return readBucket
+}
@@ -0,0 +1,229 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagearchive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
+	"go.uber.org/multierr"
+)
+
+const (
+	tarBlockSize = 512
+	// The end of a tar archive is marked by two zero blocks.
+	tarTrailerSize = 2 * tarBlockSize
+)
+
+// errTarWriteBucketDelete is returned when deleting from a TarWriteBucket.
+var errTarWriteBucketDelete = errors.New("cannot delete from a tar archive")
+
+// TarWriteBucket is a WriteBucket that writes every object as an entry of a tar archive
+// as soon as the object is closed.
+//
+// Only the data of the object currently being written is held in memory, so arbitrarily
+// large trees can be written. Objects cannot be deleted.
+type TarWriteBucket interface {
+	storage.WriteBucket
+	// Close finishes and closes the current volume.
+	//
+	// If no objects were written, a single empty volume is written.
+	// No further calls can be made to the TarWriteBucket after Close.
+	io.Closer
+}
+
+// NewTarWriteBucket returns a new TarWriteBucket.
+//
+// newVolume is called to create the writer for each tar volume, starting at index 0.
+// Each volume is a complete tar archive on its own. Only one volume is created unless
+// TarWriteBucketWithMaxVolumeSize is set.
+func NewTarWriteBucket(
+	newVolume func(volumeIndex int) (io.WriteCloser, error),
+	options ...TarWriteBucketOption,
+) TarWriteBucket {
+	return newTarWriteBucket(newVolume, options...)
+}
+
+// TarWriteBucketOption is an option for a new TarWriteBucket.
+type TarWriteBucketOption func(*tarWriteBucket)
+
+// TarWriteBucketWithMaxVolumeSize returns a new TarWriteBucketOption that starts a new
+// volume before an entry would make the current volume larger than the given size in bytes.
+//
+// Entries are never split across volumes, so a volume with a single entry may still be
+// larger than the given size. The default is to write a single volume.
+func TarWriteBucketWithMaxVolumeSize(maxVolumeSize int64) TarWriteBucketOption {
+	return func(tarWriteBucket *tarWriteBucket) {
+		tarWriteBucket.maxVolumeSize = maxVolumeSize
+	}
+}
+
+// *** PRIVATE ***
+
+type tarWriteBucket struct {
+	newVolume     func(int) (io.WriteCloser, error)
+	maxVolumeSize int64
+
+	// All nil until the first entry is written.
+	volumeWriteCloser io.WriteCloser
+	tarWriter         *tar.Writer
+	volumeIndex       int
+	volumeSize        int64
+	closed            bool
+	lock              sync.Mutex
+}
+
+func newTarWriteBucket(
+	newVolume func(int) (io.WriteCloser, error),
+	options ...TarWriteBucketOption,
+) *tarWriteBucket {
+	tarWriteBucket := &tarWriteBucket{
+		newVolume: newVolume,
+	}
+	for _, option := range options {
+		option(tarWriteBucket)
+	}
+	return tarWriteBucket
+}
+
+func (t *tarWriteBucket) Put(ctx context.Context, path string, _ ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tarWriteObjectCloser{
+		tarWriteBucket: t,
+		path:           path,
+	}, nil
+}
+
+func (*tarWriteBucket) Delete(context.Context, string) error {
+	return errTarWriteBucketDelete
+}
+
+func (*tarWriteBucket) DeleteAll(context.Context, string) error {
+	return errTarWriteBucketDelete
+}
+
+func (*tarWriteBucket) SetExternalAndLocalPathsSupported() bool {
+	return false
+}
+
+func (t *tarWriteBucket) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return storage.ErrClosed
+	}
+	t.closed = true
+	if t.tarWriter == nil {
+		if err := t.startVolume(); err != nil {
+			return err
+		}
+	}
+	return t.closeVolume()
+}
+
+func (t *tarWriteBucket) writeEntry(path string, data []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return storage.ErrClosed
+	}
+	entrySize := tarBlockSize + (int64(len(data))+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	if t.tarWriter != nil &&
+		t.maxVolumeSize > 0 &&
+		t.volumeSize > 0 &&
+		t.volumeSize+entrySize+tarTrailerSize > t.maxVolumeSize {
+		if err := t.closeVolume(); err != nil {
+			return err
+		}
+		t.volumeIndex++
+	}
+	if t.tarWriter == nil {
+		if err := t.startVolume(); err != nil {
+			return err
+		}
+	}
+	if err := t.tarWriter.WriteHeader(
+		&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path,
+			Size:     int64(len(data)),
+			Mode:     0644,
+		},
+	); err != nil {
+		return err
+	}
+	if _, err := t.tarWriter.Write(data); err != nil {
+		return err
+	}
+	t.volumeSize += entrySize
+	return nil
+}
+
+func (t *tarWriteBucket) startVolume() error {
+	volumeWriteCloser, err := t.newVolume(t.volumeIndex)
+	if err != nil {
+		return err
+	}
+	t.volumeWriteCloser = volumeWriteCloser
+	t.tarWriter = tar.NewWriter(volumeWriteCloser)
+	t.volumeSize = 0
+	return nil
+}
+
+func (t *tarWriteBucket) closeVolume() error {
+	err := multierr.Append(t.tarWriter.Close(), t.volumeWriteCloser.Close())
+	t.tarWriter = nil
+	t.volumeWriteCloser = nil
+	return err
+}
+
+type tarWriteObjectCloser struct {
+	tarWriteBucket *tarWriteBucket
+	path           string
+	buffer         bytes.Buffer
+	closed         bool
+}
+
+func (w *tarWriteObjectCloser) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, storage.ErrClosed
+	}
+	return w.buffer.Write(p)
+}
+
+func (*tarWriteObjectCloser) SetExternalPath(string) error {
+	return storage.ErrSetExternalPathUnsupported
+}
+
+func (*tarWriteObjectCloser) SetLocalPath(string) error {
+	return storage.ErrSetLocalPathUnsupported
+}
+
+func (w *tarWriteObjectCloser) Close() error {
+	if w.closed {
+		return storage.ErrClosed
+	}
+	w.closed = true
+	return w.tarWriteBucket.writeEntry(w.path, w.buffer.Bytes())
+}
@@ -0,0 +1,107 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagearchive_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarWriteBucketVolumes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var volumes []*testVolume
+	tarWriteBucket := storagearchive.NewTarWriteBucket(
+		func(volumeIndex int) (io.WriteCloser, error) {
+			require.Equal(t, len(volumes), volumeIndex)
+			volume := &testVolume{}
+			volumes = append(volumes, volume)
+			return volume, nil
+		},
+		// Each 1000-byte entry takes 1536 bytes, and each volume has a 1024-byte trailer,
+		// so two entries fit in each volume.
+		storagearchive.TarWriteBucketWithMaxVolumeSize(4096),
+	)
+	pathToData := make(map[string][]byte)
+	for i := 0; i < 5; i++ {
+		path := fmt.Sprintf("a/%d.proto", i)
+		data := []byte(strings.Repeat(fmt.Sprint(i), 1000))
+		pathToData[path] = data
+		require.NoError(t, storage.PutPath(ctx, tarWriteBucket, path, data))
+	}
+	require.NoError(t, tarWriteBucket.Close())
+	require.ErrorIs(t, tarWriteBucket.Close(), storage.ErrClosed)
+	require.Error(t, tarWriteBucket.Delete(ctx, "a/0.proto"))
+
+	require.Len(t, volumes, 3)
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	var numPaths []int
+	for _, volume := range volumes {
+		require.True(t, volume.closed)
+		require.LessOrEqual(t, volume.Len(), 4096)
+		volumeBucket := storagemem.NewReadWriteBucket()
+		require.NoError(t, storagearchive.Untar(ctx, bytes.NewReader(volume.Bytes()), volumeBucket))
+		paths, err := storage.AllPaths(ctx, volumeBucket, "")
+		require.NoError(t, err)
+		numPaths = append(numPaths, len(paths))
+		_, err = storage.Copy(ctx, volumeBucket, readWriteBucket)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int{2, 2, 1}, numPaths)
+	for path, data := range pathToData {
+		actualData, err := storage.ReadPath(ctx, readWriteBucket, path)
+		require.NoError(t, err)
+		require.Equal(t, data, actualData)
+	}
+}
+
+func TestTarWriteBucketEmpty(t *testing.T) {
+	t.Parallel()
+	var volumes []*testVolume
+	tarWriteBucket := storagearchive.NewTarWriteBucket(
+		func(int) (io.WriteCloser, error) {
+			volume := &testVolume{}
+			volumes = append(volumes, volume)
+			return volume, nil
+		},
+	)
+	require.NoError(t, tarWriteBucket.Close())
+	require.Len(t, volumes, 1)
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, storagearchive.Untar(context.Background(), bytes.NewReader(volumes[0].Bytes()), readWriteBucket))
+	paths, err := storage.AllPaths(context.Background(), readWriteBucket, "")
+	require.NoError(t, err)
+	require.Empty(t, paths)
+}
+
+type testVolume struct {
+	bytes.Buffer
+
+	closed bool
+}
+
+func (v *testVolume) Close() error {
+	v.closed = true
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagegcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// Only used for the body of error responses, the data of objects is never limited.
+const maxErrorResponseSize = 64 * 1024
+
+type client struct {
+	httpClient *http.Client
+	bucketName string
+	endpoint   string
+	token      string
+}
+
+func newClient(
+	httpClient *http.Client,
+	bucketName string,
+	endpoint string,
+	token string,
+) (*client, error) {
+	if bucketName == "" {
+		return nil, errors.New("Cloud Storage bucket name is required")
+	}
+	parsedEndpoint, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Cloud Storage endpoint %q: %w", endpoint, err)
+	}
+	if parsedEndpoint.Scheme != "http" && parsedEndpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Cloud Storage endpoint %q: scheme must be http or https", endpoint)
+	}
+	return &client{
+		httpClient: httpClient,
+		bucketName: bucketName,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		token:      token,
+	}, nil
+}
+
+func (c *client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	response, err := c.do(ctx, http.MethodGet, c.objectURL(key, url.Values{"alt": []string{"media"}}), nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, c.newResponseError(http.MethodGet, key, response)
+	}
+	return response.Body, nil
+}
+
+func (c *client) StatObject(ctx context.Context, key string) (retErr error) {
+	response, err := c.do(ctx, http.MethodGet, c.objectURL(key, url.Values{"fields": []string{"name"}}), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return c.newResponseError(http.MethodGet, key, response)
+	}
+	return nil
+}
+
+func (c *client) ListObjects(ctx context.Context, prefix string, f func(key string) error) error {
+	var pageToken string
+	for {
+		query := url.Values{
+			"prefix": []string{prefix},
+			"fields": []string{"items(name),nextPageToken"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		listResponse, err := c.listObjects(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, item := range listResponse.Items {
+			if err := f(item.Name); err != nil {
+				return err
+			}
+		}
+		if listResponse.NextPageToken == "" {
+			return nil
+		}
+		pageToken = listResponse.NextPageToken
+	}
+}
+
+func (c *client) PutObject(ctx context.Context, key string, data []byte) (retErr error) {
+	query := url.Values{
+		"uploadType": []string{"media"},
+		"name":       []string{key},
+	}
+	requestURL := c.endpoint + "/upload/storage/v1/b/" + url.PathEscape(c.bucketName) + "/o?" + query.Encode()
+	response, err := c.do(ctx, http.MethodPost, requestURL, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return c.newResponseError(http.MethodPost, key, response)
+	}
+	return nil
+}
+
+func (c *client) DeleteObject(ctx context.Context, key string) (retErr error) {
+	response, err := c.do(ctx, http.MethodDelete, c.objectURL(key, nil), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return c.newResponseError(http.MethodDelete, key, response)
+	}
+}
+
+func (c *client) listObjects(ctx context.Context, query url.Values) (_ *listResponse, retErr error) {
+	requestURL := c.endpoint + "/storage/v1/b/" + url.PathEscape(c.bucketName) + "/o?" + query.Encode()
+	response, err := c.do(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, c.newResponseError(http.MethodGet, "", response)
+	}
+	listResponse := &listResponse{}
+	if err := json.NewDecoder(response.Body).Decode(listResponse); err != nil {
+		return nil, fmt.Errorf("could not parse Cloud Storage list response: %w", err)
+	}
+	return listResponse, nil
+}
+
+// objectURL returns the JSON API URL of the object.
+//
+// Object names are escaped as a single path segment, including any slashes.
+func (c *client) objectURL(key string, query url.Values) string {
+	objectURL := c.endpoint + "/storage/v1/b/" + url.PathEscape(c.bucketName) + "/o/" + url.PathEscape(key)
+	if len(query) > 0 {
+		objectURL += "?" + query.Encode()
+	}
+	return objectURL
+}
+
+func (c *client) do(ctx context.Context, method string, requestURL string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.httpClient.Do(request)
+}
+
+// newResponseError returns an error for an unexpected response, and closes the body of the response.
+//
+// The error fulfills fs.ErrNotExist if the response is a 404.
+func (c *client) newResponseError(method string, key string, response *http.Response) error {
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxErrorResponseSize))
+	err = multierr.Append(err, response.Body.Close())
+	if response.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: "read", Path: key, Err: fs.ErrNotExist}
+	}
+	message := fmt.Sprintf("Cloud Storage returned unexpected status %q for %s gs://%s/%s", response.Status, method, c.bucketName, key)
+	errorResponse := &errorResponse{}
+	if json.Unmarshal(data, errorResponse) == nil && errorResponse.Error.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, errorResponse.Error.Message)
+	}
+	return multierr.Append(errors.New(message), err)
+}
+
+type listResponse struct {
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
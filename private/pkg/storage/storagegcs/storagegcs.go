@@ -0,0 +1,116 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagegcs implements a storage Bucket backed by Google Cloud Storage.
+package storagegcs
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/internal/objectstore"
+)
+
+// DefaultEndpoint is the default endpoint of the Cloud Storage JSON API.
+const DefaultEndpoint = "https://storage.googleapis.com"
+
+// NewReadWriteBucket returns a new ReadWriteBucket for the Cloud Storage bucket with the given name.
+//
+// Requests are made to the JSON API with the given http.Client, which is responsible for
+// authentication unless a token is given. Objects are only created when their
+// WriteObjectCloser is closed, so all writes are atomic. External paths are of the form
+// gs://bucket/key.
+func NewReadWriteBucket(
+	httpClient *http.Client,
+	bucketName string,
+	options ...ReadWriteBucketOption,
+) (storage.ReadWriteBucket, error) {
+	readWriteBucketOptions := newReadWriteBucketOptions()
+	for _, option := range options {
+		option(readWriteBucketOptions)
+	}
+	client, err := newClient(
+		httpClient,
+		bucketName,
+		readWriteBucketOptions.endpoint,
+		readWriteBucketOptions.token,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return objectstore.NewReadWriteBucket(
+		client,
+		"gs://"+bucketName,
+		readWriteBucketOptions.prefix,
+		readWriteBucketOptions.maxConcurrency,
+	)
+}
+
+// ReadWriteBucketOption is an option for a new ReadWriteBucket.
+type ReadWriteBucketOption func(*readWriteBucketOptions)
+
+// ReadWriteBucketWithPrefix returns a new ReadWriteBucketOption that scopes the bucket
+// to the given object name prefix, as if it were a directory.
+func ReadWriteBucketWithPrefix(prefix string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.prefix = prefix
+	}
+}
+
+// ReadWriteBucketWithEndpoint returns a new ReadWriteBucketOption that sets the endpoint.
+//
+// The default is DefaultEndpoint.
+func ReadWriteBucketWithEndpoint(endpoint string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		if endpoint != "" {
+			readWriteBucketOptions.endpoint = endpoint
+		}
+	}
+}
+
+// ReadWriteBucketWithToken returns a new ReadWriteBucketOption that authenticates
+// requests with the given OAuth 2.0 access token.
+//
+// The default is to not set an Authorization header.
+func ReadWriteBucketWithToken(token string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.token = token
+	}
+}
+
+// ReadWriteBucketWithMaxConcurrency returns a new ReadWriteBucketOption that limits the
+// number of concurrent requests to Cloud Storage.
+//
+// The default is 16.
+func ReadWriteBucketWithMaxConcurrency(maxConcurrency int) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.maxConcurrency = maxConcurrency
+	}
+}
+
+// *** PRIVATE ***
+
+type readWriteBucketOptions struct {
+	prefix         string
+	endpoint       string
+	token          string
+	maxConcurrency int
+}
+
+func newReadWriteBucketOptions() *readWriteBucketOptions {
+	return &readWriteBucketOptions{
+		endpoint:       DefaultEndpoint,
+		maxConcurrency: objectstore.DefaultMaxConcurrency,
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagegcs_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagegcs"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storagetesting"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBucketName = "test-bucket"
+	testToken      = "test-token"
+)
+
+var storagetestingDirPath = filepath.Join("..", "storagetesting")
+
+func TestGCS(t *testing.T) {
+	t.Parallel()
+	storagetesting.RunTestSuite(
+		t,
+		storagetestingDirPath,
+		testNewReadBucket,
+		testNewWriteBucket,
+		testWriteBucketToReadBucket,
+		false,
+	)
+}
+
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	server := newTestServer(t)
+	readWriteBucket := testNewReadWriteBucket(t, server, "prefix")
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a/b.proto", []byte("b")))
+	require.Equal(t, []string{"prefix/a/b.proto"}, server.names())
+	objectInfo, err := readWriteBucket.Stat(ctx, "a/b.proto")
+	require.NoError(t, err)
+	require.Equal(t, "gs://test-bucket/prefix/a/b.proto", objectInfo.ExternalPath())
+	require.NoError(t, readWriteBucket.Delete(ctx, "a/b.proto"))
+	require.Empty(t, server.names())
+	require.ErrorIs(t, readWriteBucket.Delete(ctx, "a/b.proto"), fs.ErrNotExist)
+}
+
+func testNewReadBucket(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+	osBucket, err := storageosProvider.NewReadWriteBucket(
+		dirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	require.NoError(t, err)
+	// The prefix is unique per directory so that external paths differ between buckets.
+	prefix := filepath.Base(dirPath)
+	readWriteBucket := testNewReadWriteBucket(t, newTestServer(t), prefix)
+	_, err = storage.Copy(context.Background(), osBucket, readWriteBucket)
+	require.NoError(t, err)
+	return readWriteBucket, func(t *testing.T, rootPath string, path string) string {
+		return "gs://" + testBucketName + "/" + prefix + "/" + normalpath.Normalize(path)
+	}
+}
+
+func testNewWriteBucket(t *testing.T, _ storageos.Provider) storage.WriteBucket {
+	return testNewReadWriteBucket(t, newTestServer(t), "prefix")
+}
+
+func testWriteBucketToReadBucket(t *testing.T, writeBucket storage.WriteBucket) storage.ReadBucket {
+	// hacky
+	readWriteBucket, ok := writeBucket.(storage.ReadWriteBucket)
+	require.True(t, ok)
+	return readWriteBucket
+}
+
+func testNewReadWriteBucket(t *testing.T, server *testServer, prefix string) storage.ReadWriteBucket {
+	readWriteBucket, err := storagegcs.NewReadWriteBucket(
+		server.httpServer.Client(),
+		testBucketName,
+		storagegcs.ReadWriteBucketWithEndpoint(server.httpServer.URL),
+		storagegcs.ReadWriteBucketWithPrefix(prefix),
+		storagegcs.ReadWriteBucketWithToken(testToken),
+		storagegcs.ReadWriteBucketWithMaxConcurrency(4),
+	)
+	require.NoError(t, err)
+	return readWriteBucket
+}
+
+// testServer is a minimal in-memory Cloud Storage JSON API server that supports a single bucket.
+type testServer struct {
+	httpServer *httptest.Server
+
+	nameToData map[string][]byte
+	lock       sync.RWMutex
+}
+
+func newTestServer(t *testing.T) *testServer {
+	server := &testServer{
+		nameToData: make(map[string][]byte),
+	}
+	server.httpServer = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	t.Cleanup(server.httpServer.Close)
+	return server
+}
+
+func (s *testServer) names() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.nameToData))
+	for name := range s.nameToData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *testServer) serveHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Header.Get("Authorization") != "Bearer "+testToken {
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	bucketPath := "/storage/v1/b/" + testBucketName + "/o"
+	switch {
+	case request.Method == http.MethodPost && request.URL.Path == "/upload"+bucketPath:
+		data, err := io.ReadAll(request.Body)
+		if err != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		s.nameToData[request.URL.Query().Get("name")] = data
+		s.lock.Unlock()
+		s.writeJSON(responseWriter, map[string]string{})
+	case request.Method == http.MethodGet && request.URL.Path == bucketPath:
+		s.list(responseWriter, request.URL.Query().Get("prefix"))
+	case strings.HasPrefix(request.URL.Path, bucketPath+"/"):
+		name := strings.TrimPrefix(request.URL.Path, bucketPath+"/")
+		s.lock.Lock()
+		data, ok := s.nameToData[name]
+		if request.Method == http.MethodDelete {
+			delete(s.nameToData, name)
+		}
+		s.lock.Unlock()
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case request.Method == http.MethodGet && request.URL.Query().Get("alt") == "media":
+			_, _ = responseWriter.Write(data)
+		case request.Method == http.MethodGet:
+			s.writeJSON(responseWriter, map[string]string{"name": name})
+		case request.Method == http.MethodDelete:
+			responseWriter.WriteHeader(http.StatusNoContent)
+		default:
+			responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testServer) list(responseWriter http.ResponseWriter, prefix string) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	var items []item
+	for _, name := range s.names() {
+		if strings.HasPrefix(name, prefix) {
+			items = append(items, item{Name: name})
+		}
+	}
+	s.writeJSON(responseWriter, map[string]any{"items": items})
+}
+
+func (*testServer) writeJSON(responseWriter http.ResponseWriter, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, _ = responseWriter.Write(data)
+}
@@ -17,6 +17,7 @@ package storagemem
 import (
 	"context"
 	"io/fs"
+	"maps"
 	"sort"
 	"sync"
 
@@ -29,7 +30,15 @@ import (
 
 type bucket struct {
 	pathToImmutableObject map[string]*internal.ImmutableObject
-	lock                  sync.RWMutex
+	// copyOnWrite is set if pathToImmutableObject is shared with another bucket
+	// created by branch, and must be copied before it is modified.
+	copyOnWrite bool
+	// spillConfig is set if data is spilled to disk once the memory limit is exceeded.
+	spillConfig *spillConfig
+	// memoryBytes is the number of bytes of data held in memory by the objects
+	// in pathToImmutableObject.
+	memoryBytes int64
+	lock        sync.RWMutex
 }
 
 func newBucket(pathToImmutableObject map[string]*internal.ImmutableObject) *bucket {
@@ -46,7 +55,7 @@ func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser
 	if err != nil {
 		return nil, err
 	}
-	return newReadObjectCloser(immutableObject), nil
+	return newReadObjectCloser(immutableObject)
 }
 
 func (b *bucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
@@ -108,10 +117,11 @@ func (b *bucket) Delete(ctx context.Context, path string) error {
 	if _, ok := b.pathToImmutableObject[path]; !ok {
 		return &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
 	}
+	b.copyIfSharedLocked()
 	// Note that if there is an existing reader for an object of the same path,
 	// that reader will continue to read the original file, but we accept this
 	// as no less consistent than os mechanics.
-	delete(b.pathToImmutableObject, path)
+	b.deleteLocked(path)
 	return nil
 }
 
@@ -122,12 +132,13 @@ func (b *bucket) DeleteAll(ctx context.Context, prefix string) error {
 	}
 	b.lock.Lock()
 	defer b.lock.Unlock()
+	b.copyIfSharedLocked()
 	for path := range b.pathToImmutableObject {
 		if normalpath.EqualsOrContainsPath(prefix, path, normalpath.Relative) {
 			// Note that if there is an existing reader for an object of the same path,
 			// that reader will continue to read the original file, but we accept this
 			// as no less consistent than os mechanics.
-			delete(b.pathToImmutableObject, path)
+			b.deleteLocked(path)
 		}
 	}
 	return nil
@@ -141,6 +152,63 @@ func (b *bucket) ToReadBucket() (storage.ReadBucket, error) {
 	return b, nil
 }
 
+// branch returns a new bucket with the same objects as this bucket.
+//
+// The objects are immutable, so they are shared between the buckets. The map of paths
+// to objects is shared as well, and is only copied by the first bucket to modify it.
+func (b *bucket) branch() *bucket {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.copyOnWrite = true
+	return &bucket{
+		pathToImmutableObject: b.pathToImmutableObject,
+		copyOnWrite:           true,
+		spillConfig:           b.spillConfig,
+		memoryBytes:           b.memoryBytes,
+	}
+}
+
+// hasMemoryBytes returns true if size more bytes of data can be held in memory.
+//
+// This is always true if data is not spilled to disk.
+func (b *bucket) hasMemoryBytes(size int64) bool {
+	if b.spillConfig == nil {
+		return true
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.memoryBytes+size <= b.spillConfig.maxMemoryBytes
+}
+
+// putLocked sets the object for the path, keeping track of the memory held.
+//
+// This must be called with the write lock held, after copyIfSharedLocked.
+func (b *bucket) putLocked(path string, immutableObject *internal.ImmutableObject) {
+	b.deleteLocked(path)
+	b.pathToImmutableObject[path] = immutableObject
+	b.memoryBytes += immutableObject.MemorySize()
+}
+
+// deleteLocked deletes the object for the path if it exists, keeping track of the memory held.
+//
+// This must be called with the write lock held, after copyIfSharedLocked.
+func (b *bucket) deleteLocked(path string) {
+	if immutableObject, ok := b.pathToImmutableObject[path]; ok {
+		b.memoryBytes -= immutableObject.MemorySize()
+		delete(b.pathToImmutableObject, path)
+	}
+}
+
+// copyIfSharedLocked copies pathToImmutableObject if it is shared with another bucket.
+//
+// This must be called with the write lock held, before pathToImmutableObject is modified.
+func (b *bucket) copyIfSharedLocked() {
+	if b.copyOnWrite {
+		b.pathToImmutableObject = maps.Clone(b.pathToImmutableObject)
+		b.copyOnWrite = false
+	}
+}
+
 func (b *bucket) readLockAndGetImmutableObject(ctx context.Context, path string) (*internal.ImmutableObject, error) {
 	path, err := storageutil.ValidatePath(path)
 	if err != nil {
@@ -158,3 +226,11 @@ func (b *bucket) readLockAndGetImmutableObject(ctx context.Context, path string)
 	}
 	return immutableObject, nil
 }
+
+type spillConfig struct {
+	maxMemoryBytes int64
+	// dirPath is the directory to spill to.
+	//
+	// If empty, os.TempDir() is used.
+	dirPath string
+}
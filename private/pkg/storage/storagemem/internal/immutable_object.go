@@ -17,6 +17,11 @@
 package internal
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
 )
@@ -24,11 +29,15 @@ import (
 // ImmutableObject is an object that contains a path, external path,
 // and data that is never modified.
 //
+// The data is either held in memory, or spilled to a file on disk.
+//
 // We make this a struct so there is no weirdness with returning a nil interface.
 type ImmutableObject struct {
 	storageutil.ObjectInfo
 
 	data []byte
+	// spillFilePath is set if the data was spilled to disk.
+	spillFilePath string
 }
 
 // NewImmutableObject returns a new ImmutableObject.
@@ -50,9 +59,90 @@ func NewImmutableObject(
 	}
 }
 
+// NewSpilledImmutableObject returns a new ImmutableObject with data that was
+// spilled to the file at spillFilePath.
+//
+// The ImmutableObject takes ownership of the file, and removes it once the
+// ImmutableObject is garbage collected. The file must not be modified.
+//
+// path is expected to always be non-empty.
+// If externalPath is empty, normalpath.Unnormalize(path) is used.
+func NewSpilledImmutableObject(
+	path string,
+	externalPath string,
+	localPath string,
+	spillFilePath string,
+) *ImmutableObject {
+	if externalPath == "" {
+		externalPath = normalpath.Unnormalize(path)
+	}
+	immutableObject := &ImmutableObject{
+		ObjectInfo:    storageutil.NewObjectInfo(path, externalPath, localPath),
+		spillFilePath: spillFilePath,
+	}
+	runtime.SetFinalizer(
+		immutableObject,
+		func(immutableObject *ImmutableObject) {
+			_ = os.Remove(immutableObject.spillFilePath)
+		},
+	)
+	return immutableObject
+}
+
+// Spilled returns true if the data was spilled to disk.
+func (i *ImmutableObject) Spilled() bool {
+	return i.spillFilePath != ""
+}
+
+// MemorySize returns the number of bytes of data held in memory.
+//
+// This is 0 if the data was spilled to disk.
+func (i *ImmutableObject) MemorySize() int64 {
+	return int64(len(i.data))
+}
+
 // Data returns the data.
 //
+// If the data was spilled to disk, this reads the data from disk.
+//
 // DO NOT MODIFY.
-func (i *ImmutableObject) Data() []byte {
-	return i.data
+func (i *ImmutableObject) Data() ([]byte, error) {
+	if i.spillFilePath == "" {
+		return i.data, nil
+	}
+	// The finalizer must not run while we are reading the file.
+	defer runtime.KeepAlive(i)
+	return os.ReadFile(i.spillFilePath)
+}
+
+// Open returns a new reader for the data.
+//
+// If the data was spilled to disk, the data is read from disk as it is read
+// from the reader, and the reader must be closed.
+func (i *ImmutableObject) Open() (io.ReadCloser, error) {
+	if i.spillFilePath == "" {
+		return io.NopCloser(bytes.NewReader(i.data)), nil
+	}
+	// The file is removed by the finalizer once the ImmutableObject is garbage
+	// collected, so we keep a reference to the ImmutableObject in the reader.
+	file, err := os.Open(i.spillFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &spilledReadCloser{
+		File:            file,
+		immutableObject: i,
+	}, nil
+}
+
+type spilledReadCloser struct {
+	*os.File
+
+	immutableObject *ImmutableObject
+}
+
+func (s *spilledReadCloser) Close() error {
+	err := s.File.Close()
+	runtime.KeepAlive(s.immutableObject)
+	return err
 }
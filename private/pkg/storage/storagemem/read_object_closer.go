@@ -15,7 +15,7 @@
 package storagemem
 
 import (
-	"bytes"
+	"io"
 
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem/internal"
@@ -25,22 +25,26 @@ import (
 type readObjectCloser struct {
 	storageutil.ObjectInfo
 
-	reader *bytes.Reader
-	closed bool
+	readCloser io.ReadCloser
+	closed     bool
 }
 
-func newReadObjectCloser(immutableObject *internal.ImmutableObject) *readObjectCloser {
+func newReadObjectCloser(immutableObject *internal.ImmutableObject) (*readObjectCloser, error) {
+	readCloser, err := immutableObject.Open()
+	if err != nil {
+		return nil, err
+	}
 	return &readObjectCloser{
 		ObjectInfo: immutableObject.ObjectInfo,
-		reader:     bytes.NewReader(immutableObject.Data()),
-	}
+		readCloser: readCloser,
+	}, nil
 }
 
 func (r *readObjectCloser) Read(p []byte) (int, error) {
 	if r.closed {
 		return 0, storage.ErrClosed
 	}
-	return r.reader.Read(p)
+	return r.readCloser.Read(p)
 }
 
 func (r *readObjectCloser) Close() error {
@@ -48,5 +52,5 @@ func (r *readObjectCloser) Close() error {
 		return storage.ErrClosed
 	}
 	r.closed = true
-	return nil
+	return r.readCloser.Close()
 }
@@ -16,8 +16,10 @@
 package storagemem
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"sort"
 
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem/internal"
@@ -27,8 +29,52 @@ import (
 var errDuplicatePath = errors.New("duplicate path")
 
 // NewReadWriteBucket returns a new in-memory ReadWriteBucket.
-func NewReadWriteBucket() storage.ReadWriteBucket {
-	return newBucket(nil)
+func NewReadWriteBucket(options ...ReadWriteBucketOption) storage.ReadWriteBucket {
+	readWriteBucketOptions := newReadWriteBucketOptions()
+	for _, option := range options {
+		option(readWriteBucketOptions)
+	}
+	bucket := newBucket(nil)
+	if readWriteBucketOptions.maxMemoryBytes > 0 {
+		bucket.spillConfig = &spillConfig{
+			maxMemoryBytes: readWriteBucketOptions.maxMemoryBytes,
+			dirPath:        readWriteBucketOptions.spillDirPath,
+		}
+	}
+	return bucket
+}
+
+// ReadWriteBucketOption is an option for a new ReadWriteBucket.
+type ReadWriteBucketOption func(*readWriteBucketOptions)
+
+// ReadWriteBucketWithMaxMemoryBytes returns a ReadWriteBucketOption that limits the number
+// of bytes of data the ReadWriteBucket holds in memory.
+//
+// Once the limit would be exceeded, the data of new objects is transparently spilled to
+// temporary files on disk instead, and read back from disk as needed. Spilled files are
+// removed once the objects are no longer referenced by any bucket or reader. Objects
+// that are deleted or overwritten free up their memory for new objects.
+//
+// Buckets created from the ReadWriteBucket with Branch or Snapshot have the same limit.
+//
+// The default is to hold all data in memory. A non-positive limit is ignored.
+func ReadWriteBucketWithMaxMemoryBytes(maxMemoryBytes int64) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		if maxMemoryBytes > 0 {
+			readWriteBucketOptions.maxMemoryBytes = maxMemoryBytes
+		}
+	}
+}
+
+// ReadWriteBucketWithSpillDirPath returns a ReadWriteBucketOption that sets the directory
+// that data is spilled to when the limit set with ReadWriteBucketWithMaxMemoryBytes is
+// exceeded.
+//
+// The directory must exist. The default is os.TempDir().
+func ReadWriteBucketWithSpillDirPath(spillDirPath string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.spillDirPath = spillDirPath
+	}
 }
 
 // NewReadBucket returns a new ReadBucket.
@@ -60,3 +106,99 @@ func CopyReadBucket(ctx context.Context, inputBucket storage.ReadBucket) (storag
 	}
 	return inMemoryBucket, nil
 }
+
+// Branch returns a new in-memory ReadWriteBucket that starts with the contents of the given
+// ReadBucket.
+//
+// Writes to the returned ReadWriteBucket are not visible in the given ReadBucket, and vice
+// versa. If the given ReadBucket is an in-memory bucket, no data is copied: the objects are
+// shared between the buckets, and only the set of paths is copied on the first write to
+// either bucket. Otherwise, the given ReadBucket is copied into memory.
+func Branch(ctx context.Context, readBucket storage.ReadBucket) (storage.ReadWriteBucket, error) {
+	return branch(ctx, readBucket)
+}
+
+// Snapshot returns an in-memory ReadBucket with the current contents of the given ReadBucket.
+//
+// Later writes to the given ReadBucket are not visible in the snapshot. See Branch for
+// when data is copied.
+func Snapshot(ctx context.Context, readBucket storage.ReadBucket) (storage.ReadBucket, error) {
+	return branch(ctx, readBucket)
+}
+
+// ChangedPaths returns the sorted paths that differ between the two ReadBuckets.
+//
+// A path differs if it is only in one of the ReadBuckets, or if its data differs. External
+// and local paths are not compared. Objects shared by buckets created with Branch or Snapshot
+// are known to be equal without comparing their data, so comparing a branch to the bucket it
+// was created from only compares the data of paths that were written to.
+func ChangedPaths(ctx context.Context, from storage.ReadBucket, to storage.ReadBucket) ([]string, error) {
+	fromBucket, err := branch(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toBucket, err := branch(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	// The branches are not modified, so we can read them without locks.
+	var changedPaths []string
+	for path, fromImmutableObject := range fromBucket.pathToImmutableObject {
+		toImmutableObject, ok := toBucket.pathToImmutableObject[path]
+		if !ok {
+			changedPaths = append(changedPaths, path)
+			continue
+		}
+		if fromImmutableObject == toImmutableObject {
+			continue
+		}
+		equal, err := immutableObjectDataEqual(fromImmutableObject, toImmutableObject)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	for path := range toBucket.pathToImmutableObject {
+		if _, ok := fromBucket.pathToImmutableObject[path]; !ok {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	sort.Strings(changedPaths)
+	return changedPaths, nil
+}
+
+// *** PRIVATE ***
+
+type readWriteBucketOptions struct {
+	maxMemoryBytes int64
+	spillDirPath   string
+}
+
+func newReadWriteBucketOptions() *readWriteBucketOptions {
+	return &readWriteBucketOptions{}
+}
+
+func immutableObjectDataEqual(one *internal.ImmutableObject, two *internal.ImmutableObject) (bool, error) {
+	oneData, err := one.Data()
+	if err != nil {
+		return false, err
+	}
+	twoData, err := two.Data()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(oneData, twoData), nil
+}
+
+func branch(ctx context.Context, readBucket storage.ReadBucket) (*bucket, error) {
+	if inMemoryBucket, ok := readBucket.(*bucket); ok {
+		return inMemoryBucket.branch(), nil
+	}
+	inMemoryBucket := newBucket(nil)
+	if _, err := storage.Copy(ctx, readBucket, inMemoryBucket, storage.CopyWithExternalAndLocalPaths()); err != nil {
+		return nil, err
+	}
+	return inMemoryBucket, nil
+}
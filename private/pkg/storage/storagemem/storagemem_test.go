@@ -16,6 +16,7 @@ package storagemem_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -41,13 +42,127 @@ func TestMem(t *testing.T) {
 	)
 }
 
+func TestMemWithMaxMemoryBytes(t *testing.T) {
+	t.Parallel()
+	// Every object with data is spilled to disk.
+	storagetesting.RunTestSuite(
+		t,
+		storagetestingDirPath,
+		func(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+			return testNewReadBucketWithOptions(t, dirPath, storageosProvider, storagemem.ReadWriteBucketWithMaxMemoryBytes(1))
+		},
+		func(t *testing.T, _ storageos.Provider) storage.WriteBucket {
+			return storagemem.NewReadWriteBucket(
+				storagemem.ReadWriteBucketWithMaxMemoryBytes(1),
+				storagemem.ReadWriteBucketWithSpillDirPath(t.TempDir()),
+			)
+		},
+		testWriteBucketToReadBucket,
+		false,
+	)
+}
+
+func TestSpill(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	spillDirPath := t.TempDir()
+	readWriteBucket := storagemem.NewReadWriteBucket(
+		storagemem.ReadWriteBucketWithMaxMemoryBytes(4),
+		storagemem.ReadWriteBucketWithSpillDirPath(spillDirPath),
+	)
+	requireSpillFileCount := func(expected int) {
+		dirEntries, err := os.ReadDir(spillDirPath)
+		require.NoError(t, err)
+		require.Len(t, dirEntries, expected)
+	}
+
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a.txt", []byte("abc")))
+	requireSpillFileCount(0)
+	// Exceeds the remaining budget of 1 byte.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.txt", []byte("defgh")))
+	requireSpillFileCount(1)
+	testRequirePathData(t, readWriteBucket, "a.txt", "abc")
+	testRequirePathData(t, readWriteBucket, "b.txt", "defgh")
+
+	// Deleting a.txt frees up its memory.
+	require.NoError(t, readWriteBucket.Delete(ctx, "a.txt"))
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "c.txt", []byte("ijkl")))
+	requireSpillFileCount(1)
+	testRequirePathData(t, readWriteBucket, "c.txt", "ijkl")
+
+	// Branches share spilled objects, and compare spilled data.
+	branch, err := storagemem.Branch(ctx, readWriteBucket)
+	require.NoError(t, err)
+	require.NoError(t, storage.PutPath(ctx, branch, "b.txt", []byte("defgh")))
+	requireSpillFileCount(2)
+	changedPaths, err := storagemem.ChangedPaths(ctx, readWriteBucket, branch)
+	require.NoError(t, err)
+	require.Empty(t, changedPaths)
+	require.NoError(t, storage.PutPath(ctx, branch, "b.txt", []byte("mnopq")))
+	changedPaths, err = storagemem.ChangedPaths(ctx, readWriteBucket, branch)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b.txt"}, changedPaths)
+}
+
+func TestBranch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a.txt", []byte("a")))
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.txt", []byte("b")))
+	snapshot, err := storagemem.Snapshot(ctx, readWriteBucket)
+	require.NoError(t, err)
+	branch, err := storagemem.Branch(ctx, readWriteBucket)
+	require.NoError(t, err)
+
+	// Writes to the branch are not visible in the original bucket.
+	require.NoError(t, storage.PutPath(ctx, branch, "a.txt", []byte("aa")))
+	require.NoError(t, storage.PutPath(ctx, branch, "c.txt", []byte("c")))
+	require.NoError(t, branch.Delete(ctx, "b.txt"))
+	testRequirePathData(t, readWriteBucket, "a.txt", "a")
+	testRequirePathData(t, readWriteBucket, "b.txt", "b")
+	changedPaths, err := storagemem.ChangedPaths(ctx, readWriteBucket, branch)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, changedPaths)
+
+	// Writes to the original bucket are not visible in the branch or the snapshot.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.txt", []byte("bb")))
+	testRequirePathData(t, snapshot, "b.txt", "b")
+	_, err = branch.Stat(ctx, "b.txt")
+	require.Error(t, err)
+	changedPaths, err = storagemem.ChangedPaths(ctx, snapshot, readWriteBucket)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b.txt"}, changedPaths)
+
+	// Writing the same data is not a change.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b.txt", []byte("b")))
+	changedPaths, err = storagemem.ChangedPaths(ctx, snapshot, readWriteBucket)
+	require.NoError(t, err)
+	require.Empty(t, changedPaths)
+}
+
+func testRequirePathData(t *testing.T, readBucket storage.ReadBucket, path string, expected string) {
+	data, err := storage.ReadPath(context.Background(), readBucket, path)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(data))
+}
+
 func testNewReadBucket(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+	return testNewReadBucketWithOptions(t, dirPath, storageosProvider)
+}
+
+func testNewReadBucketWithOptions(
+	t *testing.T,
+	dirPath string,
+	storageosProvider storageos.Provider,
+	options ...storagemem.ReadWriteBucketOption,
+) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
 	osBucket, err := storageosProvider.NewReadWriteBucket(
 		dirPath,
 		storageos.ReadWriteBucketWithSymlinksIfSupported(),
 	)
 	require.NoError(t, err)
-	readWriteBucket := storagemem.NewReadWriteBucket()
+	readWriteBucket := storagemem.NewReadWriteBucket(options...)
 	_, err = storage.Copy(
 		context.Background(),
 		osBucket,
@@ -17,9 +17,11 @@ package storagemem
 import (
 	"bytes"
 	"fmt"
+	"os"
 
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem/internal"
+	"go.uber.org/multierr"
 )
 
 type writeObjectCloser struct {
@@ -28,7 +30,10 @@ type writeObjectCloser struct {
 	externalPath string
 	localPath    string
 	buffer       *bytes.Buffer
-	closed       bool
+	// spillFile is set once the data is spilled to disk, after which
+	// buffer is nil.
+	spillFile *os.File
+	closed    bool
 }
 
 func newWriteObjectCloser(
@@ -46,6 +51,14 @@ func (w *writeObjectCloser) Write(p []byte) (int, error) {
 	if w.closed {
 		return 0, storage.ErrClosed
 	}
+	if w.spillFile == nil && !w.bucket.hasMemoryBytes(int64(w.buffer.Len()+len(p))) {
+		if err := w.spill(); err != nil {
+			return 0, err
+		}
+	}
+	if w.spillFile != nil {
+		return w.spillFile.Write(p)
+	}
 	return w.buffer.Write(p)
 }
 
@@ -70,18 +83,60 @@ func (w *writeObjectCloser) Close() error {
 		return storage.ErrClosed
 	}
 	w.closed = true
+	immutableObject, err := w.newImmutableObject()
+	if err != nil {
+		return err
+	}
 	// overwrites anything existing
 	// this is the same behavior as storageos
 	w.bucket.lock.Lock()
 	defer w.bucket.lock.Unlock()
+	w.bucket.copyIfSharedLocked()
 	// Note that if there is an existing reader for an object of the same path,
 	// that reader will continue to read the original file, but we accept this
 	// as no less consistent than os mechanics.
-	w.bucket.pathToImmutableObject[w.path] = internal.NewImmutableObject(
+	w.bucket.putLocked(w.path, immutableObject)
+	return nil
+}
+
+func (w *writeObjectCloser) newImmutableObject() (*internal.ImmutableObject, error) {
+	// The memory limit is checked again in case other objects were
+	// written since the last call to Write.
+	if w.spillFile == nil && !w.bucket.hasMemoryBytes(int64(w.buffer.Len())) {
+		if err := w.spill(); err != nil {
+			return nil, err
+		}
+	}
+	if w.spillFile == nil {
+		return internal.NewImmutableObject(
+			w.path,
+			w.externalPath,
+			w.localPath,
+			w.buffer.Bytes(),
+		), nil
+	}
+	spillFilePath := w.spillFile.Name()
+	if err := w.spillFile.Close(); err != nil {
+		return nil, multierr.Combine(err, os.Remove(spillFilePath))
+	}
+	return internal.NewSpilledImmutableObject(
 		w.path,
 		w.externalPath,
 		w.localPath,
-		w.buffer.Bytes(),
-	)
+		spillFilePath,
+	), nil
+}
+
+// spill moves the data written so far to a new temporary file.
+func (w *writeObjectCloser) spill() error {
+	spillFile, err := os.CreateTemp(w.bucket.spillConfig.dirPath, "buf-storagemem-*")
+	if err != nil {
+		return err
+	}
+	if _, err := spillFile.Write(w.buffer.Bytes()); err != nil {
+		return multierr.Combine(err, spillFile.Close(), os.Remove(spillFile.Name()))
+	}
+	w.spillFile = spillFile
+	w.buffer = nil
 	return nil
 }
@@ -18,8 +18,10 @@ import (
 	"context"
 	"errors"
 	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/bufbuild/buf/private/pkg/filepathext"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
@@ -36,9 +38,11 @@ type bucket struct {
 	rootPath         string
 	absoluteRootPath string
 	symlinks         bool
+	atomicWrites     bool
+	syncWrites       bool
 }
 
-func newBucket(rootPath string, symlinks bool) (*bucket, error) {
+func newBucket(rootPath string, symlinks bool, atomicWrites bool, syncWrites bool) (*bucket, error) {
 	rootPath = normalpath.Unnormalize(rootPath)
 	if err := validateDirPathExists(rootPath, symlinks); err != nil {
 		return nil, err
@@ -54,6 +58,8 @@ func newBucket(rootPath string, symlinks bool) (*bucket, error) {
 		rootPath:         rootPath,
 		absoluteRootPath: absoluteRootPath,
 		symlinks:         symlinks,
+		atomicWrites:     atomicWrites,
+		syncWrites:       syncWrites,
 	}, nil
 }
 
@@ -192,8 +198,8 @@ func (b *bucket) Put(ctx context.Context, path string, options ...storage.PutOpt
 	}
 	var file *os.File
 	var finalPath string
-	if storage.NewPutOptions(options).Atomic() {
-		file, err = os.CreateTemp(externalDir, ".tmp"+filepath.Base(externalPath)+"*")
+	if b.atomicWrites || storage.NewPutOptions(options).Atomic() {
+		file, err = createTempFile(externalDir, ".tmp"+filepath.Base(externalPath))
 		finalPath = externalPath
 	} else {
 		file, err = os.Create(externalPath)
@@ -204,6 +210,7 @@ func (b *bucket) Put(ctx context.Context, path string, options ...storage.PutOpt
 	return newWriteObjectCloser(
 		file,
 		finalPath,
+		b.syncWrites,
 	), nil
 }
 
@@ -358,6 +365,9 @@ type writeObjectCloser struct {
 	// path is set during atomic writes to the final path where the file should be created.
 	// If set, the file is a temp file that needs to be renamed to this path if Write/Close are successful.
 	path string
+	// sync is set if the file, and for atomic writes the directory, should be flushed
+	// to stable storage in Close.
+	sync bool
 	// writeErr contains the first non-nil error caught by a call to Write.
 	// This is returned in Close for atomic writes to prevent writing an incomplete file.
 	writeErr atomic.Error
@@ -366,10 +376,12 @@ type writeObjectCloser struct {
 func newWriteObjectCloser(
 	file *os.File,
 	path string,
+	sync bool,
 ) *writeObjectCloser {
 	return &writeObjectCloser{
 		file: file,
 		path: path,
+		sync: sync,
 	}
 }
 
@@ -390,7 +402,11 @@ func (w *writeObjectCloser) SetLocalPath(string) error {
 }
 
 func (w *writeObjectCloser) Close() error {
-	err := toStorageError(w.file.Close())
+	var err error
+	if w.sync && w.writeErr.Load() == nil {
+		err = toStorageError(w.file.Sync())
+	}
+	err = multierr.Append(err, toStorageError(w.file.Close()))
 	// This is an atomic write operation - we need to rename to the final path
 	if w.path != "" {
 		atomicWriteErr := multierr.Append(w.writeErr.Load(), err)
@@ -401,10 +417,30 @@ func (w *writeObjectCloser) Close() error {
 		if err := os.Rename(w.file.Name(), w.path); err != nil {
 			return toStorageError(multierr.Append(err, os.Remove(w.file.Name())))
 		}
+		if w.sync {
+			return syncDir(filepath.Dir(w.path))
+		}
 	}
 	return err
 }
 
+// createTempFile creates a new file in the directory with the given prefix and a random
+// suffix, opened for writing.
+//
+// Unlike os.CreateTemp, the file is created with the same permissions as os.Create, so that
+// atomic writes result in the same permissions as non-atomic writes.
+func createTempFile(dirPath string, prefix string) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		path := filepath.Join(dirPath, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dirPath, prefix+"*"), Err: fs.ErrExist}
+}
+
 // newErrNotDir returns a new Error for a path not being a directory.
 func newErrNotDir(path string) *normalpath.Error {
 	return normalpath.NewError(path, errNotDir)
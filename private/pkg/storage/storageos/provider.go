@@ -37,6 +37,8 @@ func (p *provider) NewReadWriteBucket(rootPath string, options ...ReadWriteBucke
 	return newBucket(
 		rootPath,
 		p.symlinks && readWriteBucketOptions.symlinksIfSupported,
+		readWriteBucketOptions.atomicWrites,
+		readWriteBucketOptions.syncWrites,
 	)
 }
 
@@ -45,6 +47,8 @@ func (p *provider) NewReadWriteBucket(rootPath string, options ...ReadWriteBucke
 // so there's no potential issues in newBucket
 type readWriteBucketOptions struct {
 	symlinksIfSupported bool
+	atomicWrites        bool
+	syncWrites          bool
 }
 
 func newReadWriteBucketOptions() *readWriteBucketOptions {
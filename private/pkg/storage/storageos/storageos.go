@@ -30,6 +30,8 @@ type Provider interface {
 	// The root path is expected to be normalized, however the root path
 	// can be absolute or jump context.
 	//
+	// The returned ReadWriteBucket is also a storage.WatchReadBucket.
+	//
 	// Not thread-safe.
 	NewReadWriteBucket(rootPath string, options ...ReadWriteBucketOption) (storage.ReadWriteBucket, error)
 }
@@ -54,6 +56,29 @@ func ReadWriteBucketWithSymlinksIfSupported() ReadWriteBucketOption {
 	}
 }
 
+// ReadWriteBucketWithAtomicWrites returns a ReadWriteBucketOption that results in every
+// Put being atomic, as if storage.PutWithAtomic was passed.
+//
+// Files are written to a temporary file in the same directory, and renamed to the final path
+// when the WriteObjectCloser is closed without error, so that an interrupted write never
+// leaves a partially-written file at the final path.
+func ReadWriteBucketWithAtomicWrites() ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.atomicWrites = true
+	}
+}
+
+// ReadWriteBucketWithSyncWrites returns a ReadWriteBucketOption that results in every
+// file being flushed to stable storage with fsync before the WriteObjectCloser is closed.
+//
+// For atomic writes, the parent directory is also flushed after the rename, so that the
+// rename itself survives a crash.
+func ReadWriteBucketWithSyncWrites() ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.syncWrites = true
+	}
+}
+
 // ProviderWithSymlinks returns a ProviderOption that results in symlink support.
 //
 // Note that ReadWriteBucketWithSymlinksIfSupported still needs to be passed for a given
@@ -16,10 +16,12 @@ package storageos_test
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
@@ -70,6 +72,46 @@ func TestOS(t *testing.T) {
 		require.ErrorIs(t, err, fs.ErrNotExist)
 	})
 
+	t.Run("atomic_sync_writes", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		tempDir := t.TempDir()
+		bucket, err := storageos.NewProvider().NewReadWriteBucket(
+			tempDir,
+			storageos.ReadWriteBucketWithAtomicWrites(),
+			storageos.ReadWriteBucketWithSyncWrites(),
+		)
+		require.NoError(t, err)
+
+		writeObjectCloser, err := bucket.Put(ctx, "foo/bar.txt")
+		require.NoError(t, err)
+		_, err = writeObjectCloser.Write([]byte("hello"))
+		require.NoError(t, err)
+		// The file is not visible until the write is closed.
+		_, err = os.Stat(filepath.Join(tempDir, "foo", "bar.txt"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.NoError(t, writeObjectCloser.Close())
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "foo", "bar.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(data))
+		// No temporary files are left behind.
+		dirEntries, err := os.ReadDir(filepath.Join(tempDir, "foo"))
+		require.NoError(t, err)
+		require.Len(t, dirEntries, 1)
+
+		// Atomic writes have the same permissions as non-atomic writes.
+		nonAtomicFilePath := filepath.Join(tempDir, "baz.txt")
+		nonAtomicFile, err := os.Create(nonAtomicFilePath)
+		require.NoError(t, err)
+		require.NoError(t, nonAtomicFile.Close())
+		nonAtomicFileInfo, err := os.Stat(nonAtomicFilePath)
+		require.NoError(t, err)
+		fileInfo, err := os.Stat(filepath.Join(tempDir, "foo", "bar.txt"))
+		require.NoError(t, err)
+		require.Equal(t, nonAtomicFileInfo.Mode().Perm(), fileInfo.Mode().Perm())
+	})
+
 	t.Run("get_non_existent_file_symlink", func(t *testing.T) {
 		t.Parallel()
 		ctx := context.Background()
@@ -105,6 +147,120 @@ func TestOS(t *testing.T) {
 	})
 }
 
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tempDir := t.TempDir()
+	bucket, err := storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	require.NoError(t, storage.PutPath(ctx, bucket, "foo.txt", []byte("foo")))
+	watchEventsC, errC := testWatch(t, ctx, bucket, ".")
+
+	// Create a file in a directory that did not exist when Watch was called.
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/b/bar.txt", []byte("bar")))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/bar.txt", Type: storage.WatchEventTypeCreate})
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/b/bar.txt", []byte("barbar")))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/bar.txt", Type: storage.WatchEventTypeWrite})
+	// Files in the new directory are watched.
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/b/baz.txt", []byte("baz")))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/baz.txt", Type: storage.WatchEventTypeCreate})
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "a")))
+	requireWatchEvents(
+		t,
+		watchEventsC,
+		storage.WatchEvent{Path: "a/b/bar.txt", Type: storage.WatchEventTypeRemove},
+		storage.WatchEvent{Path: "a/b/baz.txt", Type: storage.WatchEventTypeRemove},
+	)
+	require.NoError(t, bucket.Delete(ctx, "foo.txt"))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "foo.txt", Type: storage.WatchEventTypeRemove})
+
+	cancel()
+	select {
+	case err := <-errC:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for Watch to return")
+	}
+}
+
+func TestWatchPrefixNotExist(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tempDir := t.TempDir()
+	bucket, err := storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	watchEventsC, _ := testWatch(t, ctx, bucket, "a/b")
+	// testWatch creates the prefix, remove it again.
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "a")))
+
+	// Changes outside of the prefix are not reported.
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/foo.txt", []byte("foo")))
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/b/c/bar.txt", []byte("bar")))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/c/bar.txt", Type: storage.WatchEventTypeCreate})
+	require.NoError(t, storage.PutPath(ctx, bucket, "a/b/c/bar.txt", []byte("barbar")))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/c/bar.txt", Type: storage.WatchEventTypeWrite})
+	require.NoError(t, bucket.Delete(ctx, "a/b/c/bar.txt"))
+	requireWatchEvents(t, watchEventsC, storage.WatchEvent{Path: "a/b/c/bar.txt", Type: storage.WatchEventTypeRemove})
+}
+
+// testWatch starts watching the bucket, and returns once the watch has started.
+func testWatch(
+	t *testing.T,
+	ctx context.Context,
+	bucket storage.ReadWriteBucket,
+	prefix string,
+) (<-chan []storage.WatchEvent, <-chan error) {
+	watchEventsC := make(chan []storage.WatchEvent, 16)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- storage.Watch(
+			ctx,
+			bucket,
+			prefix,
+			func(watchEvents []storage.WatchEvent) error {
+				watchEventsC <- watchEvents
+				return nil
+			},
+		)
+	}()
+	// Create files with the prefix until one is reported, as there is no other way to
+	// know that Watch has taken its initial snapshot. Files created before that are part
+	// of the initial snapshot, and are never reported.
+	var readyPaths []string
+	for i := 0; ; i++ {
+		require.Less(t, i, 10, "timed out waiting for Watch to start")
+		readyPath := normalpath.Join(prefix, fmt.Sprintf("ready%d.txt", i))
+		readyPaths = append(readyPaths, readyPath)
+		require.NoError(t, storage.PutPath(ctx, bucket, readyPath, nil))
+		select {
+		case watchEvents := <-watchEventsC:
+			require.Equal(t, []storage.WatchEvent{{Path: readyPath, Type: storage.WatchEventTypeCreate}}, watchEvents)
+			// Remove all the files, including the ones in the initial snapshot.
+			var expectedWatchEvents []storage.WatchEvent
+			for _, readyPath := range readyPaths {
+				require.NoError(t, bucket.Delete(ctx, readyPath))
+				expectedWatchEvents = append(expectedWatchEvents, storage.WatchEvent{Path: readyPath, Type: storage.WatchEventTypeRemove})
+			}
+			requireWatchEvents(t, watchEventsC, expectedWatchEvents...)
+			return watchEventsC, errC
+		case err := <-errC:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func requireWatchEvents(t *testing.T, watchEventsC <-chan []storage.WatchEvent, expected ...storage.WatchEvent) {
+	select {
+	case watchEvents := <-watchEventsC:
+		require.Equal(t, expected, watchEvents)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for watch events")
+	}
+}
+
 func testNewReadBucket(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
 	osBucket, err := storageosProvider.NewReadWriteBucket(
 		dirPath,
@@ -0,0 +1,226 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageos
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchBatchDelay is how long to wait after a file system event for further events
+// before the changes are reported, so that a burst of events, such as from saving a
+// file or checking out a branch, is reported as a single batch.
+const watchBatchDelay = 50 * time.Millisecond
+
+// Watch implements storage.WatchReadBucket.
+//
+// File system events are received with fsnotify, which only watches single directories,
+// so every directory with the prefix is watched, and directories created while watching
+// are added after each batch of events. If the prefix does not exist yet, its closest
+// existing parent directory is watched until it is created.
+//
+// The events from fsnotify only signal that something changed. The changes are computed
+// by comparing the modification time and size of every regular file with the prefix
+// before and after each batch of events, so that each batch has at most one WatchEvent
+// per path, and files created in a new directory before it was watched are not missed.
+func (b *bucket) Watch(ctx context.Context, prefix string, f func([]storage.WatchEvent) error) error {
+	externalPrefix, err := b.getExternalPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+	if err := b.addWatchDirPaths(watcher, externalPrefix); err != nil {
+		return err
+	}
+	previousWatchSnapshot, err := b.getWatchSnapshot(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	var batchC <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("file system watcher closed")
+			}
+			if batchC == nil {
+				batchC = time.After(watchBatchDelay)
+			}
+			continue
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("file system watcher closed")
+			}
+			// Events were dropped, the snapshot below picks up the changes.
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return err
+			}
+		case <-batchC:
+		}
+		batchC = nil
+		// Directories created since the last batch must be watched before the snapshot
+		// is taken, so that no changes within them are missed.
+		if err := b.addWatchDirPaths(watcher, externalPrefix); err != nil {
+			return err
+		}
+		watchSnapshot, err := b.getWatchSnapshot(ctx, prefix)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+		watchEvents := getWatchEvents(previousWatchSnapshot, watchSnapshot)
+		previousWatchSnapshot = watchSnapshot
+		if len(watchEvents) > 0 {
+			if err := f(watchEvents); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// addWatchDirPaths adds every directory within the external prefix to the watcher.
+//
+// If the external prefix does not exist, its closest existing parent directory within
+// the root of the bucket is added instead.
+func (b *bucket) addWatchDirPaths(watcher *fsnotify.Watcher, externalPrefix string) error {
+	dirPath := externalPrefix
+	for {
+		fileInfo, err := os.Stat(dirPath)
+		if err == nil && fileInfo.IsDir() {
+			break
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		parentDirPath := filepath.Dir(dirPath)
+		if dirPath == filepath.Clean(normalpath.Unnormalize(b.rootPath)) || parentDirPath == dirPath {
+			// The root of the bucket was removed.
+			return nil
+		}
+		dirPath = parentDirPath
+	}
+	if dirPath != externalPrefix {
+		return addWatchDirPath(watcher, dirPath)
+	}
+	return filepath.WalkDir(
+		dirPath,
+		func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				// The directory was removed while walking, it will be picked up as
+				// removed in the snapshot.
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !dirEntry.IsDir() {
+				return nil
+			}
+			return addWatchDirPath(watcher, path)
+		},
+	)
+}
+
+func addWatchDirPath(watcher *fsnotify.Watcher, dirPath string) error {
+	// Adding a directory that is already watched is a no-op.
+	if err := watcher.Add(dirPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// getWatchSnapshot returns a map from path to the state of each regular file with the prefix.
+func (b *bucket) getWatchSnapshot(ctx context.Context, prefix string) (map[string]watchFileState, error) {
+	watchSnapshot := make(map[string]watchFileState)
+	if err := b.Walk(
+		ctx,
+		prefix,
+		func(objectInfo storage.ObjectInfo) error {
+			// os.Stat follows symlinks, which is what we want if symlinks are enabled,
+			// and if symlinks are not enabled, Walk does not call f for symlinks.
+			fileInfo, err := os.Stat(objectInfo.ExternalPath())
+			if err != nil {
+				// The file was removed between the walk and the stat, it will be
+				// picked up as removed in this or the next batch of events.
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			watchSnapshot[objectInfo.Path()] = watchFileState{
+				modTime: fileInfo.ModTime(),
+				size:    fileInfo.Size(),
+			}
+			return nil
+		},
+	); err != nil {
+		// The prefix may not exist yet, or may have been removed.
+		if errors.Is(err, fs.ErrNotExist) {
+			return watchSnapshot, nil
+		}
+		return nil, err
+	}
+	return watchSnapshot, nil
+}
+
+type watchFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// getWatchEvents returns the WatchEvents between the two snapshots, sorted by path.
+func getWatchEvents(previous map[string]watchFileState, current map[string]watchFileState) []storage.WatchEvent {
+	var watchEvents []storage.WatchEvent
+	for path, currentState := range current {
+		previousState, ok := previous[path]
+		switch {
+		case !ok:
+			watchEvents = append(watchEvents, storage.WatchEvent{Path: path, Type: storage.WatchEventTypeCreate})
+		case !previousState.modTime.Equal(currentState.modTime) || previousState.size != currentState.size:
+			watchEvents = append(watchEvents, storage.WatchEvent{Path: path, Type: storage.WatchEventTypeWrite})
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			watchEvents = append(watchEvents, storage.WatchEvent{Path: path, Type: storage.WatchEventTypeRemove})
+		}
+	}
+	sort.Slice(
+		watchEvents,
+		func(i int, j int) bool {
+			return watchEvents[i].Path < watchEvents[j].Path
+		},
+	)
+	return watchEvents
+}
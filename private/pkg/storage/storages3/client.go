@@ -0,0 +1,370 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storages3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	signingService   = "s3"
+	amzDateFormat    = "20060102T150405Z"
+	// Only used for the body of error responses, the data of objects is never limited.
+	maxErrorResponseSize = 64 * 1024
+)
+
+// emptyPayloadHash is the SHA-256 of an empty payload.
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+type client struct {
+	httpClient  *http.Client
+	bucketName  string
+	endpoint    string
+	region      string
+	credentials *Credentials
+	// Replaced in tests.
+	now func() time.Time
+}
+
+func newClient(
+	httpClient *http.Client,
+	bucketName string,
+	endpoint string,
+	region string,
+	credentials *Credentials,
+) (*client, error) {
+	if bucketName == "" {
+		return nil, errors.New("S3 bucket name is required")
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	parsedEndpoint, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", endpoint, err)
+	}
+	if parsedEndpoint.Scheme != "http" && parsedEndpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: scheme must be http or https", endpoint)
+	}
+	return &client{
+		httpClient:  httpClient,
+		bucketName:  bucketName,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      region,
+		credentials: credentials,
+		now:         time.Now,
+	}, nil
+}
+
+func (c *client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	response, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, c.newResponseError(http.MethodGet, key, response)
+	}
+	return response.Body, nil
+}
+
+func (c *client) StatObject(ctx context.Context, key string) (retErr error) {
+	response, err := c.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return c.newResponseError(http.MethodHead, key, response)
+	}
+	return nil
+}
+
+func (c *client) ListObjects(ctx context.Context, prefix string, f func(key string) error) error {
+	var continuationToken string
+	for {
+		query := url.Values{
+			"list-type": []string{"2"},
+			"prefix":    []string{prefix},
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		listBucketResult, err := c.listObjectsV2(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, contents := range listBucketResult.Contents {
+			if err := f(contents.Key); err != nil {
+				return err
+			}
+		}
+		if !listBucketResult.IsTruncated || listBucketResult.NextContinuationToken == "" {
+			return nil
+		}
+		continuationToken = listBucketResult.NextContinuationToken
+	}
+}
+
+func (c *client) PutObject(ctx context.Context, key string, data []byte) (retErr error) {
+	response, err := c.do(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return c.newResponseError(http.MethodPut, key, response)
+	}
+	return nil
+}
+
+func (c *client) DeleteObject(ctx context.Context, key string) (retErr error) {
+	response, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return c.newResponseError(http.MethodDelete, key, response)
+	}
+}
+
+func (c *client) listObjectsV2(ctx context.Context, query url.Values) (_ *listBucketResult, retErr error) {
+	response, err := c.do(ctx, http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, c.newResponseError(http.MethodGet, "", response)
+	}
+	listBucketResult := &listBucketResult{}
+	if err := xml.NewDecoder(response.Body).Decode(listBucketResult); err != nil {
+		return nil, fmt.Errorf("could not parse S3 list response: %w", err)
+	}
+	return listBucketResult, nil
+}
+
+// do makes a request for the key, or for the bucket if the key is empty.
+func (c *client) do(
+	ctx context.Context,
+	method string,
+	key string,
+	query url.Values,
+	body []byte,
+) (*http.Response, error) {
+	// Path-style addressing, so that any endpoint works.
+	path := "/" + c.bucketName
+	if key != "" {
+		path += "/" + key
+	}
+	requestURL := c.endpoint + uriEncode(path, false)
+	if len(query) > 0 {
+		requestURL += "?" + canonicalQueryString(query)
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/octet-stream")
+	}
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = sha256Hex(body)
+	}
+	if c.credentials != nil {
+		signRequest(request, payloadHash, *c.credentials, c.region, c.now())
+	}
+	return c.httpClient.Do(request)
+}
+
+// signRequest signs the request with AWS Signature Version 4.
+//
+// The host and all headers set on the request are signed.
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func signRequest(request *http.Request, payloadHash string, credentials Credentials, region string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	date := amzDate[:8]
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	headerNameToValue := map[string]string{
+		"host": request.URL.Host,
+	}
+	for name, values := range request.Header {
+		headerNameToValue[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	headerNames := make([]string, 0, len(headerNameToValue))
+	for name := range headerNameToValue {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteString(":")
+		canonicalHeaders.WriteString(strings.TrimSpace(headerNameToValue[name]))
+		canonicalHeaders.WriteString("\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+	canonicalRequest := strings.Join(
+		[]string{
+			request.Method,
+			uriEncode(request.URL.Path, false),
+			canonicalQueryString(request.URL.Query()),
+			canonicalHeaders.String(),
+			signedHeaders,
+			payloadHash,
+		},
+		"\n",
+	)
+	scope := date + "/" + region + "/" + signingService + "/aws4_request"
+	stringToSign := strings.Join(
+		[]string{
+			signingAlgorithm,
+			amzDate,
+			scope,
+			sha256Hex([]byte(canonicalRequest)),
+		},
+		"\n",
+	)
+	signingKey := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, signingService)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	request.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			signingAlgorithm,
+			credentials.AccessKeyID,
+			scope,
+			signedHeaders,
+			signature,
+		),
+	)
+}
+
+// canonicalQueryString returns the query sorted by key, with keys and values URI-encoded.
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes every byte except unreserved characters, and slashes if encodeSlash is false.
+func uriEncode(s string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			builder.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// newResponseError returns an error for an unexpected response, and closes the body of the response.
+//
+// The error fulfills fs.ErrNotExist if the response is a 404.
+func (c *client) newResponseError(method string, key string, response *http.Response) error {
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxErrorResponseSize))
+	err = multierr.Append(err, response.Body.Close())
+	if response.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: "read", Path: key, Err: fs.ErrNotExist}
+	}
+	message := fmt.Sprintf("S3 returned unexpected status %q for %s s3://%s/%s", response.Status, method, c.bucketName, key)
+	errorResponse := &errorResponse{}
+	if xml.Unmarshal(data, errorResponse) == nil && errorResponse.Code != "" {
+		message = fmt.Sprintf("%s: %s: %s", message, errorResponse.Code, errorResponse.Message)
+	}
+	return multierr.Append(errors.New(message), err)
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
@@ -0,0 +1,139 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storages3 implements a storage Bucket backed by Amazon S3, or any object
+// store with an S3-compatible API.
+package storages3
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/internal/objectstore"
+)
+
+// DefaultRegion is the default region.
+const DefaultRegion = "us-east-1"
+
+// NewReadWriteBucket returns a new ReadWriteBucket for the S3 bucket with the given name.
+//
+// Requests are made with the given http.Client and signed with AWS Signature Version 4 if
+// credentials are given. Objects are only created when their WriteObjectCloser is closed, so
+// all writes are atomic. External paths are of the form s3://bucket/key.
+func NewReadWriteBucket(
+	httpClient *http.Client,
+	bucketName string,
+	options ...ReadWriteBucketOption,
+) (storage.ReadWriteBucket, error) {
+	readWriteBucketOptions := newReadWriteBucketOptions()
+	for _, option := range options {
+		option(readWriteBucketOptions)
+	}
+	client, err := newClient(
+		httpClient,
+		bucketName,
+		readWriteBucketOptions.endpoint,
+		readWriteBucketOptions.region,
+		readWriteBucketOptions.credentials,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return objectstore.NewReadWriteBucket(
+		client,
+		"s3://"+bucketName,
+		readWriteBucketOptions.prefix,
+		readWriteBucketOptions.maxConcurrency,
+	)
+}
+
+// Credentials are the credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials.
+	SessionToken string
+}
+
+// ReadWriteBucketOption is an option for a new ReadWriteBucket.
+type ReadWriteBucketOption func(*readWriteBucketOptions)
+
+// ReadWriteBucketWithPrefix returns a new ReadWriteBucketOption that scopes the bucket
+// to the given key prefix, as if it were a directory.
+func ReadWriteBucketWithPrefix(prefix string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.prefix = prefix
+	}
+}
+
+// ReadWriteBucketWithRegion returns a new ReadWriteBucketOption that sets the region
+// of the bucket.
+//
+// The default is DefaultRegion.
+func ReadWriteBucketWithRegion(region string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		if region != "" {
+			readWriteBucketOptions.region = region
+		}
+	}
+}
+
+// ReadWriteBucketWithEndpoint returns a new ReadWriteBucketOption that sets the endpoint,
+// such as https://storage.googleapis.com or http://localhost:9000, for S3-compatible object
+// stores.
+//
+// The default is https://s3.{region}.amazonaws.com. Buckets are always addressed with
+// path-style URLs, that is {endpoint}/{bucket}/{key}.
+func ReadWriteBucketWithEndpoint(endpoint string) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.endpoint = endpoint
+	}
+}
+
+// ReadWriteBucketWithCredentials returns a new ReadWriteBucketOption that signs requests
+// with the given Credentials.
+//
+// The default is to make anonymous requests.
+func ReadWriteBucketWithCredentials(credentials Credentials) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.credentials = &credentials
+	}
+}
+
+// ReadWriteBucketWithMaxConcurrency returns a new ReadWriteBucketOption that limits the
+// number of concurrent requests to S3.
+//
+// The default is 16.
+func ReadWriteBucketWithMaxConcurrency(maxConcurrency int) ReadWriteBucketOption {
+	return func(readWriteBucketOptions *readWriteBucketOptions) {
+		readWriteBucketOptions.maxConcurrency = maxConcurrency
+	}
+}
+
+// *** PRIVATE ***
+
+type readWriteBucketOptions struct {
+	prefix         string
+	region         string
+	endpoint       string
+	credentials    *Credentials
+	maxConcurrency int
+}
+
+func newReadWriteBucketOptions() *readWriteBucketOptions {
+	return &readWriteBucketOptions{
+		region:         DefaultRegion,
+		maxConcurrency: objectstore.DefaultMaxConcurrency,
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storages3_test
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storages3"
+	"github.com/bufbuild/buf/private/pkg/storage/storagetesting"
+	"github.com/stretchr/testify/require"
+)
+
+const testBucketName = "test-bucket"
+
+var storagetestingDirPath = filepath.Join("..", "storagetesting")
+
+func TestS3(t *testing.T) {
+	t.Parallel()
+	storagetesting.RunTestSuite(
+		t,
+		storagetestingDirPath,
+		testNewReadBucket,
+		testNewWriteBucket,
+		testWriteBucketToReadBucket,
+		false,
+	)
+}
+
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	server := newTestServer(t)
+	readWriteBucket := testNewReadWriteBucket(t, server, "prefix")
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a/b.proto", []byte("b")))
+	require.Equal(t, []string{"prefix/a/b.proto"}, server.keys())
+	objectInfo, err := readWriteBucket.Stat(ctx, "a/b.proto")
+	require.NoError(t, err)
+	require.Equal(t, "s3://test-bucket/prefix/a/b.proto", objectInfo.ExternalPath())
+	require.NoError(t, readWriteBucket.Delete(ctx, "a/b.proto"))
+	require.Empty(t, server.keys())
+	require.ErrorIs(t, readWriteBucket.Delete(ctx, "a/b.proto"), fs.ErrNotExist)
+}
+
+func testNewReadBucket(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+	osBucket, err := storageosProvider.NewReadWriteBucket(
+		dirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	require.NoError(t, err)
+	// The prefix is unique per directory so that external paths differ between buckets.
+	prefix := filepath.Base(dirPath)
+	readWriteBucket := testNewReadWriteBucket(t, newTestServer(t), prefix)
+	_, err = storage.Copy(context.Background(), osBucket, readWriteBucket)
+	require.NoError(t, err)
+	return readWriteBucket, func(t *testing.T, rootPath string, path string) string {
+		return "s3://" + testBucketName + "/" + prefix + "/" + normalpath.Normalize(path)
+	}
+}
+
+func testNewWriteBucket(t *testing.T, _ storageos.Provider) storage.WriteBucket {
+	return testNewReadWriteBucket(t, newTestServer(t), "prefix")
+}
+
+func testWriteBucketToReadBucket(t *testing.T, writeBucket storage.WriteBucket) storage.ReadBucket {
+	// hacky
+	readWriteBucket, ok := writeBucket.(storage.ReadWriteBucket)
+	require.True(t, ok)
+	return readWriteBucket
+}
+
+func testNewReadWriteBucket(t *testing.T, server *testServer, prefix string) storage.ReadWriteBucket {
+	readWriteBucket, err := storages3.NewReadWriteBucket(
+		server.httpServer.Client(),
+		testBucketName,
+		storages3.ReadWriteBucketWithEndpoint(server.httpServer.URL),
+		storages3.ReadWriteBucketWithPrefix(prefix),
+		storages3.ReadWriteBucketWithCredentials(
+			storages3.Credentials{
+				AccessKeyID:     "access-key-id",
+				SecretAccessKey: "secret-access-key",
+			},
+		),
+		storages3.ReadWriteBucketWithMaxConcurrency(4),
+	)
+	require.NoError(t, err)
+	return readWriteBucket
+}
+
+// testServer is a minimal in-memory S3 server that supports a single bucket.
+type testServer struct {
+	httpServer *httptest.Server
+
+	keyToData map[string][]byte
+	lock      sync.RWMutex
+}
+
+func newTestServer(t *testing.T) *testServer {
+	server := &testServer{
+		keyToData: make(map[string][]byte),
+	}
+	server.httpServer = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	t.Cleanup(server.httpServer.Close)
+	return server
+}
+
+func (s *testServer) keys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]string, 0, len(s.keyToData))
+	for key := range s.keyToData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *testServer) serveHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if !strings.HasPrefix(request.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access-key-id/") {
+		responseWriter.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(request.URL.Path, "/"+testBucketName)
+	if !ok {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+	if key == "" && request.Method == http.MethodGet {
+		s.list(responseWriter, request.URL.Query().Get("prefix"))
+		return
+	}
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		s.lock.RLock()
+		data, ok := s.keyToData[key]
+		s.lock.RUnlock()
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if request.Method == http.MethodGet {
+			_, _ = responseWriter.Write(data)
+		}
+	case http.MethodPut:
+		data, err := io.ReadAll(request.Body)
+		if err != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		s.keyToData[key] = data
+		s.lock.Unlock()
+	case http.MethodDelete:
+		s.lock.Lock()
+		delete(s.keyToData, key)
+		s.lock.Unlock()
+		responseWriter.WriteHeader(http.StatusNoContent)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *testServer) list(responseWriter http.ResponseWriter, prefix string) {
+	type contents struct {
+		Key string `xml:"Key"`
+	}
+	result := struct {
+		XMLName  xml.Name   `xml:"ListBucketResult"`
+		Contents []contents `xml:"Contents"`
+	}{}
+	for _, key := range s.keys() {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, contents{Key: key})
+		}
+	}
+	data, err := xml.Marshal(result)
+	if err != nil {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, _ = responseWriter.Write(data)
+}
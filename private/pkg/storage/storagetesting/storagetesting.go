@@ -1582,5 +1582,24 @@ func RunTestSuite(
 				"3": "three\n",
 			},
 		)
+		aObjectInfo, err := aReadBucket.Stat(context.Background(), "2")
+		require.NoError(t, err)
+		bObjectInfo, err := bReadBucket.Stat(context.Background(), "2")
+		require.NoError(t, err)
+		overlayConflicts, err := storage.GetOverlayConflicts(context.Background(), "", aReadBucket, bReadBucket)
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			[]storage.OverlayConflict{
+				{
+					Path:          "2",
+					ExternalPaths: []string{aObjectInfo.ExternalPath(), bObjectInfo.ExternalPath()},
+				},
+			},
+			overlayConflicts,
+		)
+		overlayConflicts, err = storage.GetOverlayConflicts(context.Background(), "", aReadBucket)
+		require.NoError(t, err)
+		require.Empty(t, overlayConflicts)
 	})
 }
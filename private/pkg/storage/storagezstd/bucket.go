@@ -0,0 +1,269 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagezstd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/multierr"
+)
+
+type bucket struct {
+	delegate           storage.ReadWriteBucket
+	uncompressedWrites bool
+}
+
+func newBucket(delegate storage.ReadWriteBucket, options ...ReadWriteBucketOption) *bucket {
+	bucket := &bucket{
+		delegate: delegate,
+	}
+	for _, option := range options {
+		option(bucket)
+	}
+	return bucket
+}
+
+func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	readObjectCloser, err := b.delegate.Get(ctx, path+CompressedPathSuffix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return b.delegate.Get(ctx, path)
+		}
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(readObjectCloser, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, multierr.Append(err, readObjectCloser.Close())
+	}
+	return newReadObjectCloser(
+		newCompressedObjectInfo(path, readObjectCloser),
+		decoder,
+		readObjectCloser,
+	), nil
+}
+
+func (b *bucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	objectInfo, err := b.delegate.Stat(ctx, path+CompressedPathSuffix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return b.delegate.Stat(ctx, path)
+		}
+		return nil, err
+	}
+	return newCompressedObjectInfo(path, objectInfo), nil
+}
+
+func (b *bucket) Walk(ctx context.Context, prefix string, f func(storage.ObjectInfo) error) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	if err := b.delegate.Walk(
+		ctx,
+		prefix,
+		func(objectInfo storage.ObjectInfo) error {
+			if path, ok := strings.CutSuffix(objectInfo.Path(), CompressedPathSuffix); ok {
+				return f(newCompressedObjectInfo(path, objectInfo))
+			}
+			return f(objectInfo)
+		},
+	); err != nil {
+		return err
+	}
+	if prefix == "." {
+		return nil
+	}
+	// The prefix may be the path of a compressed object, which the delegate
+	// does not consider to be contained in the prefix.
+	objectInfo, err := b.delegate.Stat(ctx, prefix+CompressedPathSuffix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return f(newCompressedObjectInfo(prefix, objectInfo))
+}
+
+func (b *bucket) Put(ctx context.Context, path string, options ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if b.uncompressedWrites {
+		writeObjectCloser, err := b.delegate.Put(ctx, path, options...)
+		if err != nil {
+			return nil, err
+		}
+		return newWriteObjectCloser(ctx, b.delegate, path+CompressedPathSuffix, writeObjectCloser, nil), nil
+	}
+	writeObjectCloser, err := b.delegate.Put(ctx, path+CompressedPathSuffix, options...)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := zstd.NewWriter(writeObjectCloser, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, multierr.Append(err, writeObjectCloser.Close())
+	}
+	return newWriteObjectCloser(ctx, b.delegate, path, writeObjectCloser, encoder), nil
+}
+
+func (b *bucket) Delete(ctx context.Context, path string) error {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return err
+	}
+	compressedErr := b.delegate.Delete(ctx, path+CompressedPathSuffix)
+	if compressedErr != nil && !errors.Is(compressedErr, fs.ErrNotExist) {
+		return compressedErr
+	}
+	err = b.delegate.Delete(ctx, path)
+	if err != nil && errors.Is(err, fs.ErrNotExist) && compressedErr == nil {
+		// The compressed object existed and was deleted.
+		return nil
+	}
+	return err
+}
+
+func (b *bucket) DeleteAll(ctx context.Context, prefix string) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	if err := b.delegate.DeleteAll(ctx, prefix); err != nil {
+		return err
+	}
+	if prefix == "." {
+		return nil
+	}
+	// The prefix may be the path of a compressed object.
+	if err := b.delegate.Delete(ctx, prefix+CompressedPathSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *bucket) SetExternalAndLocalPathsSupported() bool {
+	return b.delegate.SetExternalAndLocalPathsSupported()
+}
+
+type readObjectCloser struct {
+	storage.ObjectInfo
+
+	decoder *zstd.Decoder
+	closer  io.Closer
+}
+
+func newReadObjectCloser(objectInfo storage.ObjectInfo, decoder *zstd.Decoder, closer io.Closer) *readObjectCloser {
+	return &readObjectCloser{
+		ObjectInfo: objectInfo,
+		decoder:    decoder,
+		closer:     closer,
+	}
+}
+
+func (r *readObjectCloser) Read(p []byte) (int, error) {
+	return r.decoder.Read(p)
+}
+
+func (r *readObjectCloser) Close() error {
+	r.decoder.Close()
+	return r.closer.Close()
+}
+
+type writeObjectCloser struct {
+	ctx      context.Context
+	delegate storage.ReadWriteBucket
+	// otherPath is the path of the other representation of the object in the delegate,
+	// which is deleted once the object is written.
+	otherPath string
+	storage.WriteObjectCloser
+	// encoder is nil if the object is written uncompressed.
+	encoder *zstd.Encoder
+}
+
+func newWriteObjectCloser(
+	ctx context.Context,
+	delegate storage.ReadWriteBucket,
+	otherPath string,
+	delegateWriteObjectCloser storage.WriteObjectCloser,
+	encoder *zstd.Encoder,
+) *writeObjectCloser {
+	return &writeObjectCloser{
+		ctx:               ctx,
+		delegate:          delegate,
+		otherPath:         otherPath,
+		WriteObjectCloser: delegateWriteObjectCloser,
+		encoder:           encoder,
+	}
+}
+
+func (w *writeObjectCloser) Write(p []byte) (int, error) {
+	if w.encoder == nil {
+		return w.WriteObjectCloser.Write(p)
+	}
+	return w.encoder.Write(p)
+}
+
+func (w *writeObjectCloser) SetLocalPath(localPath string) error {
+	if w.encoder == nil {
+		return w.WriteObjectCloser.SetLocalPath(localPath)
+	}
+	// Compressed objects have no local path.
+	return nil
+}
+
+func (w *writeObjectCloser) Close() error {
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			return multierr.Append(err, w.WriteObjectCloser.Close())
+		}
+	}
+	if err := w.WriteObjectCloser.Close(); err != nil {
+		return err
+	}
+	if err := w.delegate.Delete(w.ctx, w.otherPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// newCompressedObjectInfo returns the ObjectInfo for a compressed object at path,
+// given the ObjectInfo of the compressed object in the delegate.
+//
+// The external path is the external path of the delegate without CompressedPathSuffix,
+// so that messages refer to the uncompressed object.
+func newCompressedObjectInfo(path string, delegateObjectInfo storage.ObjectInfo) storage.ObjectInfo {
+	return storageutil.NewObjectInfo(
+		path,
+		strings.TrimSuffix(delegateObjectInfo.ExternalPath(), CompressedPathSuffix),
+		"",
+	)
+}
@@ -0,0 +1,53 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagezstd implements a storage bucket that stores objects zstd-compressed.
+package storagezstd
+
+import (
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+// CompressedPathSuffix is the suffix added to the paths of compressed objects
+// in the delegate bucket.
+const CompressedPathSuffix = ".zst"
+
+// NewReadWriteBucket returns a new ReadWriteBucket that stores objects zstd-compressed
+// in the delegate ReadWriteBucket, and serves them uncompressed.
+//
+// An object at path is stored compressed at path + CompressedPathSuffix in the delegate.
+// Objects stored uncompressed in the delegate, for example by a previous version of a
+// cache, are still served as-is, so existing data does not need to be migrated. Writing
+// an object removes the other representation of the object from the delegate.
+//
+// Compressed objects have no local path, as the file at the delegate's local path is not
+// the content of the object. The external paths of compressed objects are the external
+// paths of the delegate without CompressedPathSuffix.
+func NewReadWriteBucket(delegate storage.ReadWriteBucket, options ...ReadWriteBucketOption) storage.ReadWriteBucket {
+	return newBucket(delegate, options...)
+}
+
+// ReadWriteBucketOption is an option for a new ReadWriteBucket.
+type ReadWriteBucketOption func(*bucket)
+
+// ReadWriteBucketWithUncompressedWrites returns a new ReadWriteBucketOption that results
+// in new objects being written uncompressed.
+//
+// Objects that are already compressed are still served uncompressed. This allows
+// compression to be turned off for a bucket that has compressed objects.
+func ReadWriteBucketWithUncompressedWrites() ReadWriteBucketOption {
+	return func(bucket *bucket) {
+		bucket.uncompressedWrites = true
+	}
+}
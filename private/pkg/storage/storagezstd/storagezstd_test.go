@@ -0,0 +1,130 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagezstd_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storagetesting"
+	"github.com/bufbuild/buf/private/pkg/storage/storagezstd"
+	"github.com/stretchr/testify/require"
+)
+
+var storagetestingDirPath = filepath.Join("..", "storagetesting")
+
+func TestZstd(t *testing.T) {
+	t.Parallel()
+	storagetesting.RunTestSuite(
+		t,
+		storagetestingDirPath,
+		testNewReadBucket,
+		testNewWriteBucket,
+		testWriteBucketToReadBucket,
+		false,
+	)
+}
+
+func TestCompressed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	delegate := storagemem.NewReadWriteBucket()
+	readWriteBucket := storagezstd.NewReadWriteBucket(delegate)
+	data := bytes.Repeat([]byte("syntax = \"proto3\";\n"), 100)
+
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a/b.proto", data))
+	compressedData, err := storage.ReadPath(ctx, delegate, "a/b.proto"+storagezstd.CompressedPathSuffix)
+	require.NoError(t, err)
+	require.Less(t, len(compressedData), len(data))
+	testRequirePathData(t, readWriteBucket, "a/b.proto", data)
+	objectInfo, err := readWriteBucket.Stat(ctx, "a/b.proto")
+	require.NoError(t, err)
+	require.Equal(t, "a/b.proto", objectInfo.Path())
+	require.Empty(t, objectInfo.LocalPath())
+
+	// Uncompressed objects in the delegate are served as-is.
+	require.NoError(t, storage.PutPath(ctx, delegate, "a/c.proto", []byte("c")))
+	testRequirePathData(t, readWriteBucket, "a/c.proto", []byte("c"))
+	paths, err := storage.AllPaths(ctx, readWriteBucket, "a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a/b.proto", "a/c.proto"}, paths)
+
+	// Writing an object replaces the uncompressed object.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a/c.proto", []byte("cc")))
+	paths, err = storage.AllPaths(ctx, delegate, "a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a/b.proto.zst", "a/c.proto.zst"}, paths)
+	testRequirePathData(t, readWriteBucket, "a/c.proto", []byte("cc"))
+
+	// Uncompressed writes replace the compressed object, and compressed objects are still served.
+	uncompressedReadWriteBucket := storagezstd.NewReadWriteBucket(
+		delegate,
+		storagezstd.ReadWriteBucketWithUncompressedWrites(),
+	)
+	require.NoError(t, storage.PutPath(ctx, uncompressedReadWriteBucket, "a/c.proto", []byte("ccc")))
+	paths, err = storage.AllPaths(ctx, delegate, "a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a/b.proto.zst", "a/c.proto"}, paths)
+	testRequirePathData(t, uncompressedReadWriteBucket, "a/b.proto", data)
+	testRequirePathData(t, uncompressedReadWriteBucket, "a/c.proto", []byte("ccc"))
+
+	require.NoError(t, readWriteBucket.Delete(ctx, "a/b.proto"))
+	_, err = readWriteBucket.Stat(ctx, "a/b.proto")
+	require.Error(t, err)
+	require.Error(t, readWriteBucket.Delete(ctx, "a/b.proto"))
+}
+
+func testRequirePathData(t *testing.T, readBucket storage.ReadBucket, path string, expected []byte) {
+	data, err := storage.ReadPath(context.Background(), readBucket, path)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+}
+
+func testNewReadBucket(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+	osBucket, err := storageosProvider.NewReadWriteBucket(
+		dirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	require.NoError(t, err)
+	readWriteBucket := storagezstd.NewReadWriteBucket(storagemem.NewReadWriteBucket())
+	_, err = storage.Copy(
+		context.Background(),
+		osBucket,
+		readWriteBucket,
+		storage.CopyWithExternalAndLocalPaths(),
+	)
+	require.NoError(t, err)
+	return readWriteBucket, func(t *testing.T, rootPath string, path string) string {
+		// Join calls Clean
+		return normalpath.Unnormalize(normalpath.Join(rootPath, path))
+	}
+}
+
+func testNewWriteBucket(*testing.T, storageos.Provider) storage.WriteBucket {
+	return storagezstd.NewReadWriteBucket(storagemem.NewReadWriteBucket())
+}
+
+func testWriteBucketToReadBucket(t *testing.T, writeBucket storage.WriteBucket) storage.ReadBucket {
+	// hacky
+	readWriteBucket, ok := writeBucket.(storage.ReadWriteBucket)
+	require.True(t, ok)
+	return readWriteBucket
+}
@@ -0,0 +1,90 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"strconv"
+)
+
+const (
+	// WatchEventTypeCreate is the WatchEventType for a path that was created.
+	WatchEventTypeCreate WatchEventType = iota + 1
+	// WatchEventTypeWrite is the WatchEventType for a path that was modified.
+	WatchEventTypeWrite
+	// WatchEventTypeRemove is the WatchEventType for a path that was removed.
+	WatchEventTypeRemove
+)
+
+// WatchEventType is the type of a WatchEvent.
+type WatchEventType int
+
+// String implements fmt.Stringer.
+func (w WatchEventType) String() string {
+	switch w {
+	case WatchEventTypeCreate:
+		return "create"
+	case WatchEventTypeWrite:
+		return "write"
+	case WatchEventTypeRemove:
+		return "remove"
+	default:
+		return strconv.Itoa(int(w))
+	}
+}
+
+// WatchEvent is a change to a path within a bucket.
+type WatchEvent struct {
+	// Path is the path that changed.
+	//
+	// This is the path within the bucket, not the external path.
+	Path string
+	// Type is the type of change.
+	Type WatchEventType
+}
+
+// WatchReadBucket is a ReadBucket that can watch for changes.
+type WatchReadBucket interface {
+	ReadBucket
+
+	// Watch watches for changes to the paths with the prefix, calling f with each batch
+	// of changes until the context is done or f returns an error.
+	//
+	// Directories are watched recursively, including directories created after Watch
+	// is called. Each batch of WatchEvents is sorted by path, and contains at most one
+	// WatchEvent per path.
+	//
+	// Returns the context error when the context is done.
+	Watch(ctx context.Context, prefix string, f func([]WatchEvent) error) error
+}
+
+// Watch watches the ReadBucket for changes to the paths with the prefix.
+//
+// If the ReadBucket is a WatchReadBucket, this calls Watch on the ReadBucket.
+// Otherwise, this is a no-op that blocks until the context is done, that is
+// f is never called.
+//
+// Note that ReadBuckets wrapped with functions such as MapReadBucket or
+// FilterReadBucket are not WatchReadBuckets, the underlying ReadBucket
+// should be watched instead.
+//
+// Returns the context error when the context is done.
+func Watch(ctx context.Context, readBucket ReadBucket, prefix string, f func([]WatchEvent) error) error {
+	if watchReadBucket, ok := readBucket.(WatchReadBucket); ok {
+		return watchReadBucket.Watch(ctx, prefix, f)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
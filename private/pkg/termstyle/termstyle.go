@@ -0,0 +1,234 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package termstyle styles text written to terminals.
+package termstyle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"golang.org/x/term"
+)
+
+const (
+	// ColorModeAuto styles text if the writer is a terminal and NO_COLOR is not set.
+	ColorModeAuto ColorMode = iota + 1
+	// ColorModeAlways always styles text.
+	ColorModeAlways
+	// ColorModeNever never styles text.
+	ColorModeNever
+)
+
+const (
+	// NoColorEnvKey is the environment variable that disables styling in ColorModeAuto.
+	//
+	// See https://no-color.org.
+	NoColorEnvKey = "NO_COLOR"
+)
+
+var (
+	// AllColorModeStrings are all the string values of ColorModes.
+	AllColorModeStrings = []string{
+		ColorModeAuto.String(),
+		ColorModeAlways.String(),
+		ColorModeNever.String(),
+	}
+
+	colorModeToString = map[ColorMode]string{
+		ColorModeAuto:   "auto",
+		ColorModeAlways: "always",
+		ColorModeNever:  "never",
+	}
+	stringToColorMode = map[string]ColorMode{
+		"auto":   ColorModeAuto,
+		"always": ColorModeAlways,
+		"never":  ColorModeNever,
+	}
+)
+
+// ColorMode determines when text is styled.
+type ColorMode int
+
+// String implements fmt.Stringer.
+func (c ColorMode) String() string {
+	s, ok := colorModeToString[c]
+	if !ok {
+		return fmt.Sprintf("%d", c)
+	}
+	return s
+}
+
+// ParseColorMode parses the ColorMode.
+//
+// If the empty string is given, this returns ColorModeAuto.
+func ParseColorMode(s string) (ColorMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ColorModeAuto, nil
+	}
+	c, ok := stringToColorMode[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown color mode %q, must be one of %s", s, strings.Join(AllColorModeStrings, ","))
+	}
+	return c, nil
+}
+
+// Styler styles text.
+//
+// If styling is disabled, all methods return the text unchanged.
+type Styler interface {
+	// Enabled returns true if the Styler styles text.
+	Enabled() bool
+	// Bold returns the text in bold.
+	Bold(text string) string
+	// Faint returns the text dimmed.
+	Faint(text string) string
+	// Red returns the text in red.
+	Red(text string) string
+	// Green returns the text in green.
+	Green(text string) string
+	// Yellow returns the text in yellow.
+	Yellow(text string) string
+	// Cyan returns the text in cyan.
+	Cyan(text string) string
+
+	isStyler()
+}
+
+// NewStyler returns a new Styler.
+//
+// If enabled is false, the Styler does not style text.
+func NewStyler(enabled bool) Styler {
+	return newStyler(enabled)
+}
+
+// NewStylerForWriter returns a new Styler for text written to the writer.
+//
+// See IsEnabled for when styling is enabled.
+func NewStylerForWriter(colorMode ColorMode, envContainer app.EnvContainer, writer io.Writer) Styler {
+	return newStyler(IsEnabled(colorMode, envContainer, writer))
+}
+
+// IsEnabled returns true if text written to the writer should be styled.
+//
+// ColorModeAlways always styles text. ColorModeAuto styles text if NO_COLOR is not set,
+// TERM is not dumb, and the writer is a terminal. Any other ColorMode never styles text.
+func IsEnabled(colorMode ColorMode, envContainer app.EnvContainer, writer io.Writer) bool {
+	switch colorMode {
+	case ColorModeAlways:
+		return true
+	case ColorModeAuto:
+		if envContainer.Env(NoColorEnvKey) != "" || envContainer.Env("TERM") == "dumb" {
+			return false
+		}
+		file, ok := writer.(*os.File)
+		return ok && term.IsTerminal(int(file.Fd()))
+	default:
+		return false
+	}
+}
+
+// StyleDiff styles the lines of the unified diff.
+//
+// Added lines are green, removed lines are red, hunk headers are cyan, and file headers are bold.
+func StyleDiff(styler Styler, diff []byte) []byte {
+	if !styler.Enabled() || len(diff) == 0 {
+		return diff
+	}
+	lines := bytes.SplitAfter(diff, []byte("\n"))
+	buffer := bytes.NewBuffer(nil)
+	for _, line := range lines {
+		text, newline := strings.CutSuffix(string(line), "\n")
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "+++"), strings.HasPrefix(text, "---"), strings.HasPrefix(text, "diff "):
+			text = styler.Bold(text)
+		case strings.HasPrefix(text, "@@"):
+			text = styler.Cyan(text)
+		case strings.HasPrefix(text, "+"):
+			text = styler.Green(text)
+		case strings.HasPrefix(text, "-"):
+			text = styler.Red(text)
+		}
+		_, _ = buffer.WriteString(text)
+		if newline {
+			_, _ = buffer.WriteString("\n")
+		}
+	}
+	return buffer.Bytes()
+}
+
+// *** PRIVATE ***
+
+const (
+	sgrReset  = "\x1b[0m"
+	sgrBold   = "\x1b[1m"
+	sgrFaint  = "\x1b[2m"
+	sgrRed    = "\x1b[31m"
+	sgrGreen  = "\x1b[32m"
+	sgrYellow = "\x1b[33m"
+	sgrCyan   = "\x1b[36m"
+)
+
+type styler struct {
+	enabled bool
+}
+
+func newStyler(enabled bool) *styler {
+	return &styler{
+		enabled: enabled,
+	}
+}
+
+func (s *styler) Enabled() bool {
+	return s.enabled
+}
+
+func (s *styler) Bold(text string) string {
+	return s.style(sgrBold, text)
+}
+
+func (s *styler) Faint(text string) string {
+	return s.style(sgrFaint, text)
+}
+
+func (s *styler) Red(text string) string {
+	return s.style(sgrRed, text)
+}
+
+func (s *styler) Green(text string) string {
+	return s.style(sgrGreen, text)
+}
+
+func (s *styler) Yellow(text string) string {
+	return s.style(sgrYellow, text)
+}
+
+func (s *styler) Cyan(text string) string {
+	return s.style(sgrCyan, text)
+}
+
+func (s *styler) style(sgr string, text string) string {
+	if !s.enabled || text == "" {
+		return text
+	}
+	return sgr + text + sgrReset
+}
+
+func (*styler) isStyler() {}
@@ -0,0 +1,74 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package termstyle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColorMode(t *testing.T) {
+	t.Parallel()
+	for s, expected := range map[string]ColorMode{
+		"":        ColorModeAuto,
+		"auto":    ColorModeAuto,
+		"ALWAYS":  ColorModeAlways,
+		" never ": ColorModeNever,
+	} {
+		colorMode, err := ParseColorMode(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, colorMode)
+	}
+	_, err := ParseColorMode("foo")
+	require.Error(t, err)
+}
+
+func TestIsEnabled(t *testing.T) {
+	t.Parallel()
+	emptyEnvContainer := app.NewEnvContainer(nil)
+	noColorEnvContainer := app.NewEnvContainer(map[string]string{NoColorEnvKey: "1"})
+	buffer := bytes.NewBuffer(nil)
+	require.True(t, IsEnabled(ColorModeAlways, noColorEnvContainer, buffer))
+	require.False(t, IsEnabled(ColorModeNever, emptyEnvContainer, buffer))
+	// A buffer is not a terminal.
+	require.False(t, IsEnabled(ColorModeAuto, emptyEnvContainer, buffer))
+	require.False(t, IsEnabled(ColorModeAuto, noColorEnvContainer, buffer))
+}
+
+func TestStyler(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "foo", NewStyler(false).Red("foo"))
+	require.Equal(t, "\x1b[31mfoo\x1b[0m", NewStyler(true).Red("foo"))
+	require.Equal(t, "", NewStyler(true).Bold(""))
+}
+
+func TestStyleDiff(t *testing.T) {
+	t.Parallel()
+	diff := []byte("--- a.proto\n+++ a.proto\n@@ -1 +1 @@\n-foo\n+bar\n baz\n")
+	require.Equal(t, diff, StyleDiff(NewStyler(false), diff))
+	require.Equal(
+		t,
+		"\x1b[1m--- a.proto\x1b[0m\n"+
+			"\x1b[1m+++ a.proto\x1b[0m\n"+
+			"\x1b[36m@@ -1 +1 @@\x1b[0m\n"+
+			"\x1b[31m-foo\x1b[0m\n"+
+			"\x1b[32m+bar\x1b[0m\n"+
+			" baz\n",
+		string(StyleDiff(NewStyler(true), diff)),
+	)
+}
@@ -68,12 +68,16 @@ func Parallelize(ctx context.Context, jobs []func(context.Context) error, option
 	if multiplier < 1 {
 		multiplier = 1
 	}
+	maxParallelism := Parallelism() * multiplier
+	if parallelizeOptions.maxParallelism > 0 {
+		maxParallelism = parallelizeOptions.maxParallelism
+	}
 	var cancel context.CancelFunc
 	if parallelizeOptions.cancelOnFailure {
 		ctx, cancel = context.WithCancel(ctx)
 		defer cancel()
 	}
-	semaphoreC := make(chan struct{}, Parallelism()*multiplier)
+	semaphoreC := make(chan struct{}, maxParallelism)
 	var retErr error
 	var wg sync.WaitGroup
 	var lock sync.Mutex
@@ -134,6 +138,17 @@ func ParallelizeWithMultiplier(multiplier int) ParallelizeOption {
 	}
 }
 
+// ParallelizeWithMaxParallelism returns a new ParallelizeOption that will run at most
+// maxParallelism jobs at once, regardless of Parallelism().
+//
+// This is useful for jobs that are bound by network latency rather than by CPU.
+// If set, the multiplier is ignored. A maxParallelism of <1 has no meaning.
+func ParallelizeWithMaxParallelism(maxParallelism int) ParallelizeOption {
+	return func(parallelizeOptions *parallelizeOptions) {
+		parallelizeOptions.maxParallelism = maxParallelism
+	}
+}
+
 // ParallelizeWithCancelOnFailure returns a new ParallelizeOption that will attempt
 // to cancel all other jobs via context cancellation if any job fails.
 func ParallelizeWithCancelOnFailure() ParallelizeOption {
@@ -144,6 +159,7 @@ func ParallelizeWithCancelOnFailure() ParallelizeOption {
 
 type parallelizeOptions struct {
 	multiplier      int
+	maxParallelism  int
 	cancelOnFailure bool
 }
 
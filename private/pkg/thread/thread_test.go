@@ -17,6 +17,7 @@ package thread
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
@@ -60,3 +61,30 @@ func TestParallelizeWithImmediateCancellation(t *testing.T) {
 		assert.Equal(t, int64(0), executed.Load(), "jobs executed")
 	})
 }
+
+func TestParallelizeWithMaxParallelism(t *testing.T) {
+	t.Parallel()
+	const maxParallelism = 3
+	var (
+		running    atomic.Int64
+		maxRunning atomic.Int64
+		jobs       []func(context.Context) error
+	)
+	for i := 0; i < 20; i++ {
+		jobs = append(jobs, func(_ context.Context) error {
+			current := running.Inc()
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Dec()
+			return nil
+		})
+	}
+	err := Parallelize(context.Background(), jobs, ParallelizeWithMaxParallelism(maxParallelism))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning.Load(), int64(maxParallelism))
+}
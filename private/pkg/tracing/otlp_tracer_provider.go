@@ -0,0 +1,488 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.uber.org/multierr"
+)
+
+const defaultServiceName = "buf"
+
+type otlpTracerProvider struct {
+	embedded.TracerProvider
+
+	httpClient  *http.Client
+	endpoint    string
+	serviceName string
+	header      http.Header
+
+	// scopeNameToEndedSpans are the ended spans for each instrumentation scope,
+	// that is the name of the Tracer.
+	scopeNameToEndedSpans map[string][]*otlpSpan
+	// scopeNames are the scope names in the order they were first seen.
+	scopeNames []string
+	shutdown   bool
+	lock       sync.Mutex
+}
+
+func newOTLPTracerProvider(
+	httpClient *http.Client,
+	endpoint string,
+	options ...OTLPTracerProviderOption,
+) *otlpTracerProvider {
+	otlpTracerProvider := &otlpTracerProvider{
+		httpClient:            httpClient,
+		endpoint:              endpoint,
+		serviceName:           defaultServiceName,
+		header:                make(http.Header),
+		scopeNameToEndedSpans: make(map[string][]*otlpSpan),
+	}
+	for _, option := range options {
+		option(otlpTracerProvider)
+	}
+	return otlpTracerProvider
+}
+
+func (p *otlpTracerProvider) Tracer(name string, _ ...trace.TracerOption) trace.Tracer {
+	return &otlpTracer{
+		provider:  p,
+		scopeName: name,
+	}
+}
+
+func (p *otlpTracerProvider) Shutdown(ctx context.Context) (retErr error) {
+	p.lock.Lock()
+	if p.shutdown {
+		p.lock.Unlock()
+		return nil
+	}
+	p.shutdown = true
+	data, err := json.Marshal(p.getExternalTracesData())
+	p.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range p.header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to export traces: %w", err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("failed to export traces: %s: %s", response.Status, string(bytes.TrimSpace(body)))
+	}
+	return nil
+}
+
+func (p *otlpTracerProvider) addEndedSpan(scopeName string, span *otlpSpan) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.shutdown {
+		return
+	}
+	if _, ok := p.scopeNameToEndedSpans[scopeName]; !ok {
+		p.scopeNames = append(p.scopeNames, scopeName)
+	}
+	p.scopeNameToEndedSpans[scopeName] = append(p.scopeNameToEndedSpans[scopeName], span)
+}
+
+// getExternalTracesData must be called with the lock held.
+func (p *otlpTracerProvider) getExternalTracesData() *externalTracesData {
+	resourceSpans := &externalResourceSpans{
+		Resource: externalResource{
+			Attributes: []externalKeyValue{
+				newExternalKeyValue(attribute.String("service.name", p.serviceName)),
+			},
+		},
+	}
+	for _, scopeName := range p.scopeNames {
+		scopeSpans := externalScopeSpans{
+			Scope: externalScope{
+				Name: scopeName,
+			},
+		}
+		for _, span := range p.scopeNameToEndedSpans[scopeName] {
+			scopeSpans.Spans = append(scopeSpans.Spans, span.toExternal())
+		}
+		resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scopeSpans)
+	}
+	return &externalTracesData{
+		ResourceSpans: []*externalResourceSpans{
+			resourceSpans,
+		},
+	}
+}
+
+type otlpTracer struct {
+	embedded.Tracer
+
+	provider  *otlpTracerProvider
+	scopeName string
+}
+
+func (t *otlpTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	spanConfig := trace.NewSpanStartConfig(options...)
+	var parentSpanID trace.SpanID
+	traceID := newTraceID()
+	if parentSpanContext := trace.SpanContextFromContext(ctx); parentSpanContext.IsValid() && !spanConfig.NewRoot() {
+		traceID = parentSpanContext.TraceID()
+		parentSpanID = parentSpanContext.SpanID()
+	}
+	startTime := spanConfig.Timestamp()
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+	span := &otlpSpan{
+		tracer: t,
+		spanContext: trace.NewSpanContext(
+			trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     newSpanID(),
+				TraceFlags: trace.FlagsSampled,
+			},
+		),
+		parentSpanID: parentSpanID,
+		name:         name,
+		kind:         spanConfig.SpanKind(),
+		startTime:    startTime,
+		attributes:   spanConfig.Attributes(),
+	}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type otlpSpan struct {
+	embedded.Span
+
+	tracer       *otlpTracer
+	spanContext  trace.SpanContext
+	parentSpanID trace.SpanID
+	kind         trace.SpanKind
+	startTime    time.Time
+
+	name              string
+	endTime           time.Time
+	attributes        []attribute.KeyValue
+	events            []otlpEvent
+	statusCode        codes.Code
+	statusDescription string
+	ended             bool
+	lock              sync.Mutex
+}
+
+type otlpEvent struct {
+	name       string
+	time       time.Time
+	attributes []attribute.KeyValue
+}
+
+func (s *otlpSpan) End(options ...trace.SpanEndOption) {
+	spanConfig := trace.NewSpanEndConfig(options...)
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.endTime = spanConfig.Timestamp()
+	if s.endTime.IsZero() {
+		s.endTime = time.Now()
+	}
+	s.lock.Unlock()
+	s.tracer.provider.addEndedSpan(s.tracer.scopeName, s)
+}
+
+func (s *otlpSpan) AddEvent(name string, options ...trace.EventOption) {
+	eventConfig := trace.NewEventConfig(options...)
+	eventTime := eventConfig.Timestamp()
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ended {
+		return
+	}
+	s.events = append(
+		s.events,
+		otlpEvent{
+			name:       name,
+			time:       eventTime,
+			attributes: eventConfig.Attributes(),
+		},
+	)
+}
+
+func (*otlpSpan) AddLink(trace.Link) {}
+
+func (s *otlpSpan) IsRecording() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.ended
+}
+
+func (s *otlpSpan) RecordError(err error, options ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	s.AddEvent(
+		"exception",
+		append(
+			options,
+			trace.WithAttributes(
+				attribute.String("exception.type", fmt.Sprintf("%T", err)),
+				attribute.String("exception.message", err.Error()),
+			),
+		)...,
+	)
+}
+
+func (s *otlpSpan) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+func (s *otlpSpan) SetStatus(code codes.Code, description string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// Ok is final, and Unset never overrides another status.
+	if s.ended || s.statusCode == codes.Ok || code == codes.Unset {
+		return
+	}
+	s.statusCode = code
+	s.statusDescription = ""
+	if code == codes.Error {
+		s.statusDescription = description
+	}
+}
+
+func (s *otlpSpan) SetName(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.ended {
+		s.name = name
+	}
+}
+
+func (s *otlpSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.ended {
+		s.attributes = append(s.attributes, attributes...)
+	}
+}
+
+func (s *otlpSpan) TracerProvider() trace.TracerProvider {
+	return s.tracer.provider
+}
+
+func (s *otlpSpan) toExternal() externalSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	externalSpan := externalSpan{
+		TraceID:           s.spanContext.TraceID().String(),
+		SpanID:            s.spanContext.SpanID().String(),
+		Name:              s.name,
+		Kind:              int(s.kind),
+		StartTimeUnixNano: strconv.FormatInt(s.startTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.endTime.UnixNano(), 10),
+		Attributes:        newExternalKeyValues(s.attributes),
+	}
+	if s.parentSpanID.IsValid() {
+		externalSpan.ParentSpanID = s.parentSpanID.String()
+	}
+	if externalSpan.Kind == int(trace.SpanKindUnspecified) {
+		externalSpan.Kind = int(trace.SpanKindInternal)
+	}
+	for _, event := range s.events {
+		externalSpan.Events = append(
+			externalSpan.Events,
+			externalEvent{
+				TimeUnixNano: strconv.FormatInt(event.time.UnixNano(), 10),
+				Name:         event.name,
+				Attributes:   newExternalKeyValues(event.attributes),
+			},
+		)
+	}
+	switch s.statusCode {
+	case codes.Ok:
+		externalSpan.Status = &externalStatus{Code: externalStatusCodeOk}
+	case codes.Error:
+		externalSpan.Status = &externalStatus{Code: externalStatusCodeError, Message: s.statusDescription}
+	}
+	return externalSpan
+}
+
+func newTraceID() trace.TraceID {
+	var traceID trace.TraceID
+	_, _ = rand.Read(traceID[:])
+	return traceID
+}
+
+func newSpanID() trace.SpanID {
+	var spanID trace.SpanID
+	_, _ = rand.Read(spanID[:])
+	return spanID
+}
+
+// The external types are the JSON encoding of the OTLP ExportTraceServiceRequest.
+//
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+const (
+	externalStatusCodeOk    = 1
+	externalStatusCodeError = 2
+)
+
+type externalTracesData struct {
+	ResourceSpans []*externalResourceSpans `json:"resourceSpans"`
+}
+
+type externalResourceSpans struct {
+	Resource   externalResource     `json:"resource"`
+	ScopeSpans []externalScopeSpans `json:"scopeSpans"`
+}
+
+type externalResource struct {
+	Attributes []externalKeyValue `json:"attributes,omitempty"`
+}
+
+type externalScopeSpans struct {
+	Scope externalScope  `json:"scope"`
+	Spans []externalSpan `json:"spans"`
+}
+
+type externalScope struct {
+	Name string `json:"name"`
+}
+
+type externalSpan struct {
+	TraceID           string             `json:"traceId"`
+	SpanID            string             `json:"spanId"`
+	ParentSpanID      string             `json:"parentSpanId,omitempty"`
+	Name              string             `json:"name"`
+	Kind              int                `json:"kind"`
+	StartTimeUnixNano string             `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string             `json:"endTimeUnixNano"`
+	Attributes        []externalKeyValue `json:"attributes,omitempty"`
+	Events            []externalEvent    `json:"events,omitempty"`
+	Status            *externalStatus    `json:"status,omitempty"`
+}
+
+type externalEvent struct {
+	TimeUnixNano string             `json:"timeUnixNano"`
+	Name         string             `json:"name"`
+	Attributes   []externalKeyValue `json:"attributes,omitempty"`
+}
+
+type externalStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type externalKeyValue struct {
+	Key   string           `json:"key"`
+	Value externalAnyValue `json:"value"`
+}
+
+type externalAnyValue struct {
+	StringValue *string             `json:"stringValue,omitempty"`
+	BoolValue   *bool               `json:"boolValue,omitempty"`
+	IntValue    *string             `json:"intValue,omitempty"`
+	DoubleValue *float64            `json:"doubleValue,omitempty"`
+	ArrayValue  *externalArrayValue `json:"arrayValue,omitempty"`
+}
+
+type externalArrayValue struct {
+	Values []externalAnyValue `json:"values"`
+}
+
+func newExternalKeyValues(keyValues []attribute.KeyValue) []externalKeyValue {
+	if len(keyValues) == 0 {
+		return nil
+	}
+	externalKeyValues := make([]externalKeyValue, 0, len(keyValues))
+	for _, keyValue := range keyValues {
+		externalKeyValues = append(externalKeyValues, newExternalKeyValue(keyValue))
+	}
+	return externalKeyValues
+}
+
+func newExternalKeyValue(keyValue attribute.KeyValue) externalKeyValue {
+	return externalKeyValue{
+		Key:   string(keyValue.Key),
+		Value: newExternalAnyValue(keyValue.Value),
+	}
+}
+
+func newExternalAnyValue(value attribute.Value) externalAnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		return externalAnyValue{BoolValue: pointer(value.AsBool())}
+	case attribute.INT64:
+		return externalAnyValue{IntValue: pointer(strconv.FormatInt(value.AsInt64(), 10))}
+	case attribute.FLOAT64:
+		return externalAnyValue{DoubleValue: pointer(value.AsFloat64())}
+	case attribute.BOOLSLICE:
+		return newExternalArrayValue(value.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return newExternalArrayValue(value.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return newExternalArrayValue(value.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return newExternalArrayValue(value.AsStringSlice(), attribute.StringValue)
+	default:
+		return externalAnyValue{StringValue: pointer(value.Emit())}
+	}
+}
+
+func newExternalArrayValue[T any](values []T, toValue func(T) attribute.Value) externalAnyValue {
+	externalArrayValue := &externalArrayValue{
+		Values: make([]externalAnyValue, 0, len(values)),
+	}
+	for _, value := range values {
+		externalArrayValue.Values = append(externalArrayValue.Values, newExternalAnyValue(toValue(value)))
+	}
+	return externalAnyValue{ArrayValue: externalArrayValue}
+}
+
+func pointer[T any](value T) *T {
+	return &value
+}
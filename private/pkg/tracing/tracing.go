@@ -0,0 +1,99 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing provides OpenTelemetry tracing.
+//
+// Spans are started with Start, and are only recorded if a TracerProvider was installed
+// with otel.SetTracerProvider, such as the TracerProvider returned by NewOTLPTracerProvider.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/bufbuild/buf"
+
+// Start starts a new span with the name and attributes, using the global TracerProvider.
+//
+// The returned span must be ended with End.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends the span, recording the error if it is not nil.
+//
+// This is typically deferred:
+//
+//	ctx, span := tracing.Start(ctx, "foo")
+//	defer func() { tracing.End(span, retErr) }()
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracerProvider is a trace.TracerProvider that must be shut down.
+type TracerProvider interface {
+	trace.TracerProvider
+
+	// Shutdown exports all ended spans and stops recording new spans.
+	//
+	// Spans that have not ended are not exported.
+	Shutdown(ctx context.Context) error
+}
+
+// NewOTLPTracerProvider returns a new TracerProvider that exports spans to the
+// OTLP/HTTP endpoint, such as "http://localhost:4318/v1/traces".
+//
+// Spans are exported using the JSON encoding of OTLP, in a single request when the
+// TracerProvider is shut down. The CLI is short-lived, so batching spans in the background
+// would gain nothing.
+func NewOTLPTracerProvider(
+	httpClient *http.Client,
+	endpoint string,
+	options ...OTLPTracerProviderOption,
+) TracerProvider {
+	return newOTLPTracerProvider(httpClient, endpoint, options...)
+}
+
+// OTLPTracerProviderOption is an option for a new OTLP TracerProvider.
+type OTLPTracerProviderOption func(*otlpTracerProvider)
+
+// OTLPTracerProviderWithServiceName returns a new OTLPTracerProviderOption that sets the
+// service.name resource attribute of all spans.
+//
+// The default is "buf".
+func OTLPTracerProviderWithServiceName(serviceName string) OTLPTracerProviderOption {
+	return func(otlpTracerProvider *otlpTracerProvider) {
+		otlpTracerProvider.serviceName = serviceName
+	}
+}
+
+// OTLPTracerProviderWithHeader returns a new OTLPTracerProviderOption that sets the
+// given header on the export request.
+//
+// This is typically used to pass credentials to the collector.
+func OTLPTracerProviderWithHeader(key string, value string) OTLPTracerProviderOption {
+	return func(otlpTracerProvider *otlpTracerProvider) {
+		otlpTracerProvider.header.Add(key, value)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestOTLPTracerProvider(t *testing.T) {
+	t.Parallel()
+	var requestBody []byte
+	var requestHeader http.Header
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				var err error
+				requestBody, err = io.ReadAll(request.Body)
+				assert.NoError(t, err)
+				requestHeader = request.Header
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+
+	tracerProvider := NewOTLPTracerProvider(
+		server.Client(),
+		server.URL+"/v1/traces",
+		OTLPTracerProviderWithServiceName("test"),
+		OTLPTracerProviderWithHeader("Authorization", "Bearer foo"),
+	)
+	tracer := tracerProvider.Tracer("scope")
+	ctx, parentSpan := tracer.Start(context.Background(), "parent")
+	_, childSpan := tracer.Start(ctx, "child")
+	childSpan.SetAttributes(
+		attribute.String("string", "foo"),
+		attribute.Int("int", 1),
+		attribute.Bool("bool", true),
+		attribute.StringSlice("strings", []string{"a", "b"}),
+	)
+	End(childSpan, errors.New("failed"))
+	End(parentSpan, nil)
+	// Not ended, so not exported.
+	_, _ = tracer.Start(ctx, "unended")
+	require.NoError(t, tracerProvider.Shutdown(context.Background()))
+
+	assert.Equal(t, "application/json", requestHeader.Get("Content-Type"))
+	assert.Equal(t, "Bearer foo", requestHeader.Get("Authorization"))
+	var externalTracesData externalTracesData
+	require.NoError(t, json.Unmarshal(requestBody, &externalTracesData))
+	require.Len(t, externalTracesData.ResourceSpans, 1)
+	resourceSpans := externalTracesData.ResourceSpans[0]
+	assert.Equal(t, "service.name", resourceSpans.Resource.Attributes[0].Key)
+	assert.Equal(t, "test", *resourceSpans.Resource.Attributes[0].Value.StringValue)
+	require.Len(t, resourceSpans.ScopeSpans, 1)
+	assert.Equal(t, "scope", resourceSpans.ScopeSpans[0].Scope.Name)
+	spans := resourceSpans.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "child", child.Name)
+	assert.Equal(t, "parent", parent.Name)
+	assert.Equal(t, parent.TraceID, child.TraceID)
+	assert.Equal(t, parent.SpanID, child.ParentSpanID)
+	assert.Empty(t, parent.ParentSpanID)
+	assert.Nil(t, parent.Status)
+	require.NotNil(t, child.Status)
+	assert.Equal(t, externalStatus{Code: externalStatusCodeError, Message: "failed"}, *child.Status)
+	require.Len(t, child.Events, 1)
+	assert.Equal(t, "exception", child.Events[0].Name)
+	data, err := json.Marshal(child.Attributes)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`[
+			{"key":"string","value":{"stringValue":"foo"}},
+			{"key":"int","value":{"intValue":"1"}},
+			{"key":"bool","value":{"boolValue":true}},
+			{"key":"strings","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}}
+		]`,
+		string(data),
+	)
+	// Shutdown is idempotent and spans ended after shutdown are dropped.
+	_, span := tracer.Start(context.Background(), "late")
+	span.End()
+	require.NoError(t, tracerProvider.Shutdown(context.Background()))
+}
+
+func TestOTLPTracerProviderExportError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				http.Error(responseWriter, "unavailable", http.StatusServiceUnavailable)
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	tracerProvider := NewOTLPTracerProvider(server.Client(), server.URL)
+	err := tracerProvider.Shutdown(context.Background())
+	require.ErrorContains(t, err, "503 Service Unavailable: unavailable")
+}
@@ -16,14 +16,93 @@ package httpclient
 
 import (
 	"crypto/tls"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"golang.org/x/net/http/httpproxy"
 )
 
-func newClient(clientTLSConfig *tls.Config) *http.Client {
+type clientOptions struct {
+	proxyEnvContainer app.EnvContainer
+	hostProxies       []*hostProxy
+	debugLogger       *slog.Logger
+}
+
+func newClient(clientTLSConfig *tls.Config, options ...ClientOption) *http.Client {
+	clientOptions := &clientOptions{}
+	for _, option := range options {
+		option(clientOptions)
+	}
+	proxy := http.ProxyFromEnvironment
+	if clientOptions.proxyEnvContainer != nil {
+		proxy = newProxyFunc(clientOptions.proxyEnvContainer)
+	}
+	if len(clientOptions.hostProxies) > 0 {
+		proxy = newHostProxyFunc(clientOptions.hostProxies, proxy)
+	}
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: clientTLSConfig,
+		Proxy:           proxy,
+	}
+	if clientOptions.debugLogger != nil {
+		transport = newDebugRoundTripper(clientOptions.debugLogger, transport)
+	}
 	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: clientTLSConfig,
-			Proxy:           http.ProxyFromEnvironment,
-		},
+		Transport: transport,
+	}
+}
+
+// newProxyFunc returns a proxy function for the environment variables of the
+// EnvContainer, with the same semantics as http.ProxyFromEnvironment.
+func newProxyFunc(envContainer app.EnvContainer) func(*http.Request) (*url.URL, error) {
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  getEnvAny(envContainer, "HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getEnvAny(envContainer, "HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getEnvAny(envContainer, "NO_PROXY", "no_proxy"),
+	}).ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return proxyFunc(request.URL)
+	}
+}
+
+// newHostProxyFunc returns a proxy function that uses the first of the hostProxies
+// that matches the hostname of the request, and the delegate otherwise.
+func newHostProxyFunc(
+	hostProxies []*hostProxy,
+	delegate func(*http.Request) (*url.URL, error),
+) func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		hostname := strings.ToLower(request.URL.Hostname())
+		for _, hostProxy := range hostProxies {
+			if hostProxy.matches(hostname) {
+				return hostProxy.proxyURL, nil
+			}
+		}
+		return delegate(request)
+	}
+}
+
+type hostProxy struct {
+	pattern  string
+	proxyURL *url.URL
+}
+
+func (h *hostProxy) matches(hostname string) bool {
+	pattern := strings.ToLower(h.pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+	}
+	return hostname == pattern
+}
+
+func getEnvAny(envContainer app.EnvContainer, keys ...string) string {
+	for _, key := range keys {
+		if value := envContainer.Env(key); value != "" {
+			return value
+		}
 	}
+	return ""
 }
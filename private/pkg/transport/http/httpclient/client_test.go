@@ -0,0 +1,99 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostProxy(t *testing.T) {
+	t.Parallel()
+	socksProxyURL, err := url.Parse("socks5://socks.example.com:1080")
+	require.NoError(t, err)
+	client := NewClient(
+		nil,
+		ClientWithProxyFromEnvContainer(
+			app.NewEnvContainer(
+				map[string]string{
+					"HTTPS_PROXY": "http://env.example.com:3128",
+					"NO_PROXY":    "internal.example.com",
+				},
+			),
+		),
+		ClientWithHostProxy("buf.internal.example.com", socksProxyURL),
+		ClientWithHostProxy("*.direct.example.com", nil),
+		ClientWithHostProxy("*.example.com", socksProxyURL),
+	)
+	proxy := client.Transport.(*http.Transport).Proxy
+	testProxy := func(requestURL string, expectedProxyURL string) {
+		request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+		require.NoError(t, err)
+		proxyURL, err := proxy(request)
+		require.NoError(t, err)
+		if expectedProxyURL == "" {
+			assert.Nil(t, proxyURL, requestURL)
+			return
+		}
+		require.NotNil(t, proxyURL, requestURL)
+		assert.Equal(t, expectedProxyURL, proxyURL.String(), requestURL)
+	}
+	testProxy("https://buf.internal.example.com:8443/foo", "socks5://socks.example.com:1080")
+	testProxy("https://BUF.Internal.example.com/foo", "socks5://socks.example.com:1080")
+	testProxy("https://internal.example.com/foo", "socks5://socks.example.com:1080")
+	testProxy("https://foo.direct.example.com/foo", "")
+	testProxy("https://example.com/foo", "http://env.example.com:3128")
+	testProxy("https://buf.build/foo", "http://env.example.com:3128")
+}
+
+func TestDebugLogger(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+		writer.Header().Set("Set-Cookie", "session=secret")
+		_, _ = writer.Write([]byte("goodbye!"))
+	}))
+	t.Cleanup(server.Close)
+	buffer := &bytes.Buffer{}
+	client := NewClient(nil, ClientWithDebugLogger(slog.New(slog.NewTextHandler(buffer, nil))))
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/foo", strings.NewReader("hello"))
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	data, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "goodbye!", string(data))
+	require.NoError(t, response.Body.Close())
+	output := buffer.String()
+	assert.NotContains(t, output, "secret")
+	assert.Contains(t, output, `msg="http request" method=POST url=`+server.URL+`/foo headers="Authorization: REDACTED"`)
+	assert.Contains(t, output, `msg="http response"`)
+	assert.Contains(t, output, `status="200 OK"`)
+	assert.Contains(t, output, "Set-Cookie: REDACTED")
+	assert.Contains(t, output, "request_bytes=5 response_bytes=8")
+}
@@ -0,0 +1,162 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/slogext"
+)
+
+// redactedHeaderKeys are the canonical keys of the headers whose values are never logged.
+var redactedHeaderKeys = map[string]struct{}{
+	"Authorization":       {},
+	"Cookie":              {},
+	"Proxy-Authorization": {},
+	"Set-Cookie":          {},
+}
+
+// debugRoundTripper logs every request and response.
+type debugRoundTripper struct {
+	logger   *slog.Logger
+	delegate http.RoundTripper
+}
+
+func newDebugRoundTripper(logger *slog.Logger, delegate http.RoundTripper) *debugRoundTripper {
+	return &debugRoundTripper{
+		logger:   logger,
+		delegate: delegate,
+	}
+}
+
+func (d *debugRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	start := time.Now()
+	var requestBody *countingReadCloser
+	if request.Body != nil && request.Body != http.NoBody {
+		// RoundTrippers must not modify the request.
+		request = request.Clone(ctx)
+		requestBody = newCountingReadCloser(request.Body, nil)
+		request.Body = requestBody
+	}
+	d.logger.InfoContext(
+		ctx,
+		"http request",
+		slog.String("method", request.Method),
+		slog.String("url", request.URL.Redacted()),
+		slog.String("headers", getRedactedHeaderString(request.Header)),
+	)
+	response, err := d.delegate.RoundTrip(request)
+	if err != nil {
+		d.logger.InfoContext(
+			ctx,
+			"http error",
+			slog.String("method", request.Method),
+			slog.String("url", request.URL.Redacted()),
+			slog.Duration("duration", time.Since(start)),
+			slogext.ErrorAttr(err),
+		)
+		return nil, err
+	}
+	responseAttrs := []any{
+		slog.String("method", request.Method),
+		slog.String("url", request.URL.Redacted()),
+		slog.String("status", response.Status),
+		slog.String("headers", getRedactedHeaderString(response.Header)),
+	}
+	// The response is logged once the body is closed, so that the size and timing
+	// include the whole body, which may be a long-lived stream.
+	response.Body = newCountingReadCloser(
+		response.Body,
+		func(responseBytes int64) {
+			var requestBytes int64
+			if requestBody != nil {
+				requestBytes = requestBody.count.Load()
+			}
+			d.logger.InfoContext(
+				ctx,
+				"http response",
+				append(
+					responseAttrs,
+					slog.Int64("request_bytes", requestBytes),
+					slog.Int64("response_bytes", responseBytes),
+					slog.Duration("duration", time.Since(start)),
+				)...,
+			)
+		},
+	)
+	return response, nil
+}
+
+// countingReadCloser counts the bytes read, and calls onClose with the count once closed.
+type countingReadCloser struct {
+	delegate io.ReadCloser
+	onClose  func(int64)
+	count    atomic.Int64
+	closed   atomic.Bool
+}
+
+func newCountingReadCloser(delegate io.ReadCloser, onClose func(int64)) *countingReadCloser {
+	return &countingReadCloser{
+		delegate: delegate,
+		onClose:  onClose,
+	}
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.delegate.Read(p)
+	c.count.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	err := c.delegate.Close()
+	if c.closed.CompareAndSwap(false, true) && c.onClose != nil {
+		c.onClose(c.count.Load())
+	}
+	return err
+}
+
+// getRedactedHeaderString returns the headers as a sorted, single-line string with the
+// values of sensitive headers redacted.
+func getRedactedHeaderString(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	for _, key := range keys {
+		values := header[key]
+		if _, ok := redactedHeaderKeys[http.CanonicalHeaderKey(key)]; ok {
+			values = []string{"REDACTED"}
+		}
+		for _, value := range values {
+			if builder.Len() > 0 {
+				_, _ = builder.WriteString("; ")
+			}
+			_, _ = builder.WriteString(key)
+			_, _ = builder.WriteString(": ")
+			_, _ = builder.WriteString(value)
+		}
+	}
+	return builder.String()
+}
@@ -16,10 +16,62 @@ package httpclient
 
 import (
 	"crypto/tls"
+	"log/slog"
 	"net/http"
+	"net/url"
+
+	"github.com/bufbuild/buf/private/pkg/app"
 )
 
 // NewClient returns a new Client.
-func NewClient(clientTLSConfig *tls.Config) *http.Client {
-	return newClient(clientTLSConfig)
+func NewClient(clientTLSConfig *tls.Config, options ...ClientOption) *http.Client {
+	return newClient(clientTLSConfig, options...)
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*clientOptions)
+
+// ClientWithProxyFromEnvContainer returns a new ClientOption that reads the proxy
+// configuration from the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment variables
+// (or the lowercase versions thereof) of the EnvContainer.
+//
+// The default is to read the proxy configuration from the environment of the operating system.
+func ClientWithProxyFromEnvContainer(envContainer app.EnvContainer) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.proxyEnvContainer = envContainer
+	}
+}
+
+// ClientWithHostProxy returns a new ClientOption that sends requests to hosts matching
+// the pattern through the proxy at the URL, instead of the proxy from the environment.
+//
+// The pattern is either a hostname, such as "buf.example.com", or a wildcard such as
+// "*.example.com" that matches all subdomains of a domain. Patterns are matched against
+// the hostname of the request, without the port, in the order the options are given,
+// and the first matching pattern wins.
+//
+// The URL may have the scheme http, https, socks5, or socks5h. If the URL is nil, requests
+// to matching hosts are sent directly, without a proxy.
+func ClientWithHostProxy(pattern string, proxyURL *url.URL) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.hostProxies = append(
+			clientOptions.hostProxies,
+			&hostProxy{
+				pattern:  pattern,
+				proxyURL: proxyURL,
+			},
+		)
+	}
+}
+
+// ClientWithDebugLogger returns a new ClientOption that logs every request and response
+// to the logger at info level.
+//
+// The method, URL, status, headers, sizes of the request and response bodies, and timing
+// are logged. The values of the Authorization, Proxy-Authorization, Cookie, and Set-Cookie
+// headers are redacted.
+func ClientWithDebugLogger(logger *slog.Logger) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.debugLogger = logger
+	}
 }
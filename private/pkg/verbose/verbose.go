@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/termstyle"
 )
 
 var (
@@ -49,8 +51,22 @@ type Printer interface {
 // The trimmed prefix is printed with a : before each line.
 //
 // This generally aligns with the --verbose flag being set and writer being stderr.
-func NewPrinter(writer io.Writer, prefix string) Printer {
-	return newWritePrinter(writer, prefix)
+func NewPrinter(writer io.Writer, prefix string, options ...PrinterOption) Printer {
+	return newWritePrinter(writer, prefix, options...)
+}
+
+// PrinterOption is an option for a new Printer.
+type PrinterOption func(*writePrinter)
+
+// PrinterWithStyler returns a new PrinterOption that styles messages with the given Styler.
+//
+// The prefix is dimmed. Messages that follow the conventions of curl are styled by their
+// first character: "*" for information is dimmed, ">" for data sent is cyan, and "<" for
+// data received is green.
+func PrinterWithStyler(styler termstyle.Styler) PrinterOption {
+	return func(writePrinter *writePrinter) {
+		writePrinter.styler = styler
+	}
 }
 
 // NewPrinterForFlagValue returns a new Printer for the given verboseValue flag value.
@@ -74,23 +90,37 @@ func (nopPrinter) isPrinter() {}
 type writePrinter struct {
 	writer io.Writer
 	prefix string
+	styler termstyle.Styler
 }
 
-func newWritePrinter(writer io.Writer, prefix string) *writePrinter {
+func newWritePrinter(writer io.Writer, prefix string, options ...PrinterOption) *writePrinter {
 	prefix = strings.TrimSpace(prefix)
 	if prefix != "" {
 		prefix = prefix + ": "
 	}
-	return &writePrinter{
+	writePrinter := &writePrinter{
 		writer: writer,
 		prefix: prefix,
+		styler: termstyle.NewStyler(false),
+	}
+	for _, option := range options {
+		option(writePrinter)
 	}
+	return writePrinter
 }
 
 func (w *writePrinter) Printf(format string, args ...interface{}) {
 	if value := strings.TrimSpace(fmt.Sprintf(format, args...)); value != "" {
+		switch {
+		case strings.HasPrefix(value, "*"):
+			value = w.styler.Faint(value)
+		case strings.HasPrefix(value, ">"):
+			value = w.styler.Cyan(value)
+		case strings.HasPrefix(value, "<"):
+			value = w.styler.Green(value)
+		}
 		// Errors are ignored per the interface spec.
-		_, _ = w.writer.Write([]byte(w.prefix + value + "\n"))
+		_, _ = w.writer.Write([]byte(w.styler.Faint(w.prefix) + value + "\n"))
 	}
 }
 